@@ -66,6 +66,10 @@ type App struct {
 	ctxMutex sync.RWMutex
 	trayIcon []byte
 
+	// Database waiting for its master key, see UnlockStorage
+	lockedStorage   *storage.SQLiteStorage
+	masterKeySource string
+
 	// Services
 	stats    *service.StatsService
 	endpoint *service.EndpointService
@@ -115,7 +119,31 @@ func (a *App) startup(ctx context.Context) {
 		logger.Error("Cannot start without storage")
 		return
 	}
+	master, source := loadMasterKey()
+	a.masterKeySource = source
+	if err := sqliteStorage.UnlockSecrets(master); err != nil {
+		// Keep the window usable so the key can be entered or the secrets reset
+		logger.Error("Cannot decrypt secrets: %v", err)
+		a.lockedStorage = sqliteStorage
+		a.config = config.DefaultConfig()
+		runtime.WindowShow(ctx)
+		return
+	}
 	a.storage = sqliteStorage
+	a.finishStartup()
+}
+
+// finishStartup loads the configuration from the unlocked storage and starts
+// the services and the proxy
+func (a *App) finishStartup() {
+	a.ctxMutex.RLock()
+	ctx := a.ctx
+	a.ctxMutex.RUnlock()
+
+	if a.lockedStorage != nil {
+		a.storage, a.lockedStorage = a.lockedStorage, nil
+	}
+	sqliteStorage := a.storage
 
 	configAdapter := storage.NewConfigStorageAdapter(sqliteStorage)
 	cfg, err := config.LoadFromStorage(configAdapter)
@@ -128,6 +156,12 @@ func (a *App) startup(ctx context.Context) {
 	}
 	a.config = cfg
 
+	// Keep the proxy off the network unless another address was chosen
+	if listen := cfg.GetListen(); listen.BindAddress == "" && listen.UnixSocket == "" {
+		listen.BindAddress = config.LoopbackAddress
+		cfg.UpdateListen(listen)
+	}
+
 	if cfg.GetLogLevel() >= 0 {
 		logger.GetLogger().SetMinLevel(logger.LogLevel(cfg.GetLogLevel()))
 	}
@@ -186,6 +220,9 @@ func (a *App) shutdown(ctx context.Context) {
 	if a.proxy != nil {
 		a.proxy.Stop()
 	}
+	if a.lockedStorage != nil {
+		a.lockedStorage.Close()
+	}
 	if a.storage != nil {
 		if err := a.storage.Close(); err != nil {
 			logger.Warn("Failed to close storage: %v", err)
@@ -234,6 +271,9 @@ func (a *App) HideWindow() {
 
 // beforeClose is called when the window is about to close
 func (a *App) beforeClose(ctx context.Context) bool {
+	if a.settings == nil {
+		return false
+	}
 	width, height := runtime.WindowGetSize(ctx)
 	a.settings.SaveWindowSize(width, height)
 
@@ -258,7 +298,7 @@ func (a *App) Quit() {
 	ctx := a.ctx
 	a.ctxMutex.RUnlock()
 
-	if ctx != nil {
+	if ctx != nil && a.settings != nil {
 		width, height := runtime.WindowGetSize(ctx)
 		a.settings.SaveWindowSize(width, height)
 	}
@@ -407,6 +447,10 @@ func (a *App) UpdateEndpoint(index int, name, apiUrl, apiKey, authMode, transfor
 func (a *App) ToggleEndpoint(index int, enabled bool) error {
 	return a.endpoint.ToggleEndpoint(index, enabled)
 }
+func (a *App) GetEndpointOptions(index int) string { return a.endpoint.GetEndpointOptions(index) }
+func (a *App) UpdateEndpointOptions(index int, optionsJSON string) error {
+	return a.endpoint.UpdateEndpointOptions(index, optionsJSON)
+}
 func (a *App) ReorderEndpoints(names []string) error { return a.endpoint.ReorderEndpoints(names) }
 func (a *App) GetCurrentEndpoint() string            { return a.endpoint.GetCurrentEndpoint() }
 func (a *App) SwitchToEndpoint(endpointName string) error {
@@ -320,6 +320,25 @@ export default {
         proxyUrl: 'Proxy URL',
         proxyUrlPlaceholder: 'e.g., http://127.0.0.1:7890 or socks5://127.0.0.1:1080',
         proxyHelp: 'Configure HTTP/SOCKS5 proxy, leave empty for direct connection',
+        listen: 'Listen Address',
+        listenHelp: 'Where ccNexus accepts connections, 0.0.0.0 allows LAN access',
+        bindAddressPlaceholder: 'Bind address, default 127.0.0.1',
+        tlsHelp: 'Serve HTTPS, with a self-signed certificate unless one is set',
+        tlsCertPlaceholder: 'Certificate file (PEM), empty for self-signed',
+        tlsKeyPlaceholder: 'Private key file (PEM)',
+        tlsClientCAPlaceholder: 'Client CA file (PEM), require client certificates',
+        unixSocketPlaceholder: 'Unix socket path, replaces the TCP port',
+        listenRestart: 'Listen settings take effect after restarting ccNexus',
+        masterKey: 'Master Key',
+        masterKeyHelp: 'Encrypts the API keys in the database. Devices that restore each other\'s backups need the same key',
+        masterKeyPlaceholder: 'Master key or passphrase of another device',
+        masterKeyImport: 'Import',
+        masterKeyExport: 'Copy',
+        masterKeyCopied: 'Master key copied, keep it somewhere safe',
+        masterKeyExportFailed: 'Failed to copy the master key',
+        masterKeyRequired: 'Please enter a master key or passphrase',
+        masterKeyImported: 'Master key imported, backups made with it can now be restored',
+        masterKeyImportFailed: 'Failed to import the master key',
         claudeNotification: 'Notification Method',
         notificationOptions: {
             disabled: 'Disabled',
@@ -626,6 +645,18 @@ export default {
         noMessages: 'No messages',
         loadDetailError: 'Failed to load session detail',
     },
+    unlock: {
+        title: 'Unlock ccNexus',
+        message: 'The API keys in the database are encrypted with a master key that is not on this device, for example after restoring from another device or losing the OS keyring entry. Enter the master key or passphrase they were encrypted with.',
+        placeholder: 'Master key or passphrase',
+        unlock: 'Unlock',
+        required: 'Please enter the master key or passphrase',
+        failed: 'Failed to unlock',
+        reset: 'Reset secrets',
+        resetConfirm: 'Confirm reset',
+        resetWarning: 'Resetting clears every stored API key, token and password and creates a new master key. Endpoints and settings are kept. Click again to confirm.',
+        resetFailed: 'Failed to reset secrets'
+    },
     festival: {
         toggle: 'Toggle switch'
     },
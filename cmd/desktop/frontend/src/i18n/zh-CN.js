@@ -320,6 +320,25 @@ export default {
         proxyUrl: '代理地址',
         proxyUrlPlaceholder: '例如：http://127.0.0.1:7890 或 socks5://127.0.0.1:1080',
         proxyHelp: '配置 HTTP/SOCKS5 代理，留空则直连',
+        listen: '监听地址',
+        listenHelp: 'ccNexus 接受连接的地址，0.0.0.0 允许局域网访问',
+        bindAddressPlaceholder: '绑定地址，默认 127.0.0.1',
+        tlsHelp: '启用 HTTPS，未设置证书时使用自签名证书',
+        tlsCertPlaceholder: '证书文件（PEM），留空使用自签名证书',
+        tlsKeyPlaceholder: '私钥文件（PEM）',
+        tlsClientCAPlaceholder: '客户端 CA 文件（PEM），要求客户端证书',
+        unixSocketPlaceholder: 'Unix 套接字路径，替代 TCP 端口',
+        listenRestart: '监听设置将在重启 ccNexus 后生效',
+        masterKey: '主密钥',
+        masterKeyHelp: '用于加密数据库中的 API 密钥，互相恢复备份的设备需使用同一主密钥',
+        masterKeyPlaceholder: '其他设备的主密钥或口令',
+        masterKeyImport: '导入',
+        masterKeyExport: '复制',
+        masterKeyCopied: '主密钥已复制，请妥善保管',
+        masterKeyExportFailed: '复制主密钥失败',
+        masterKeyRequired: '请输入主密钥或口令',
+        masterKeyImported: '主密钥已导入，现在可以恢复用它创建的备份',
+        masterKeyImportFailed: '导入主密钥失败',
         claudeNotification: '通知方式',
         notificationOptions: {
             disabled: '关闭通知',
@@ -627,6 +646,18 @@ export default {
         noMessages: '暂无消息',
         loadDetailError: '加载会话详情失败',
     },
+    unlock: {
+        title: '解锁 ccNexus',
+        message: '数据库中的 API 密钥由本设备上不存在的主密钥加密，例如从其他设备恢复或系统钥匙串中的条目丢失后。请输入加密时使用的主密钥或口令。',
+        placeholder: '主密钥或口令',
+        unlock: '解锁',
+        required: '请输入主密钥或口令',
+        failed: '解锁失败',
+        reset: '重置密钥',
+        resetConfirm: '确认重置',
+        resetWarning: '重置将清除所有已保存的 API 密钥、令牌和密码，并创建新的主密钥，端点与设置会保留。再次点击以确认。',
+        resetFailed: '重置密钥失败'
+    },
     festival: {
         toggle: '切换开关'
     },
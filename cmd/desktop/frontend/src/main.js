@@ -11,7 +11,8 @@ import { showDataSyncDialog } from './modules/webdav.js'
 import { initTips } from './modules/tips.js'
 import { initTerminal } from './modules/terminal.js'
 import { initSession } from './modules/session.js'
-import { showSettingsModal, closeSettingsModal, saveSettings, applyTheme, initTheme, showAutoThemeConfigModal, closeAutoThemeConfigModal, saveAutoThemeConfig } from './modules/settings.js'
+import { showSettingsModal, closeSettingsModal, saveSettings, applyTheme, initTheme, showAutoThemeConfigModal, closeAutoThemeConfigModal, saveAutoThemeConfig, exportMasterKey, importMasterKey } from './modules/settings.js'
+import { showUnlockScreen } from './modules/unlock.js'
 import { checkUpdatesOnStartup, checkForUpdates, initUpdateSettings } from './modules/updater.js'
 import { initBroadcast } from './modules/broadcast.js'
 import { initSponsor, showSponsorModal, closeSponsorModal, openSponsorLink } from './modules/sponsor.js'
@@ -99,6 +100,14 @@ window.addEventListener('DOMContentLoaded', async () => {
         await new Promise(resolve => setTimeout(resolve, 100));
     }
 
+    // The master key of the database is not on this machine: ask for it
+    // before anything reads the configuration
+    if (await window.go.main.App.IsStorageLocked()) {
+        setLanguage(navigator.language.startsWith('zh') ? 'zh-CN' : 'en');
+        showUnlockScreen();
+        return;
+    }
+
     // Initialize language
     const lang = await window.go.main.App.GetLanguage();
     setLanguage(lang);
@@ -265,6 +274,8 @@ window.clearAllFilters = clearAllFilters;
 window.showSettingsModal = showSettingsModal;
 window.closeSettingsModal = closeSettingsModal;
 window.saveSettings = saveSettings;
+window.exportMasterKey = exportMasterKey;
+window.importMasterKey = importMasterKey;
 window.showAutoThemeConfigModal = showAutoThemeConfigModal;
 window.closeAutoThemeConfigModal = closeAutoThemeConfigModal;
 window.saveAutoThemeConfig = saveAutoThemeConfig;
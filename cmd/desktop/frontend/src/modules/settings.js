@@ -214,6 +214,21 @@ async function loadCurrentSettings() {
             proxyInput.value = proxyUrl || '';
         }
 
+        // Load listener settings
+        const listen = config.listen || {};
+        document.getElementById('settingsBindAddress').value = listen.bindAddress || '';
+        document.getElementById('settingsUnixSocket').value = listen.unixSocket || '';
+        document.getElementById('settingsTLSCert').value = listen.certFile || '';
+        document.getElementById('settingsTLSKey').value = listen.keyFile || '';
+        document.getElementById('settingsTLSClientCA').value = listen.clientCAFile || '';
+        const tlsCheckbox = document.getElementById('settingsTLS');
+        const tlsFiles = document.getElementById('settingsTLSFiles');
+        tlsCheckbox.checked = listen.tls || false;
+        tlsFiles.style.display = tlsCheckbox.checked ? 'block' : 'none';
+        tlsCheckbox.onchange = function() {
+            tlsFiles.style.display = this.checked ? 'block' : 'none';
+        };
+
         // Load Claude notification settings
         const claudeNotificationType = config.claudeNotificationType || 'disabled';
 
@@ -300,6 +315,35 @@ export async function saveAutoThemeConfig() {
     }
 }
 
+// Copy the master key, so another device can import it and restore the
+// backups made on this one
+export async function exportMasterKey() {
+    try {
+        const key = await window.go.main.App.ExportMasterKey();
+        await navigator.clipboard.writeText(key);
+        showNotification(t('settings.masterKeyCopied'), 'success');
+    } catch (error) {
+        showNotification(t('settings.masterKeyExportFailed') + ': ' + error, 'error');
+    }
+}
+
+// Use the master key of another device, whose backups can be restored here
+export async function importMasterKey() {
+    const input = document.getElementById('settingsMasterKey');
+    const value = input.value.trim();
+    if (!value) {
+        showNotification(t('settings.masterKeyRequired'), 'error');
+        return;
+    }
+    try {
+        await window.go.main.App.ImportMasterKey(value);
+        input.value = '';
+        showNotification(t('settings.masterKeyImported'), 'success');
+    } catch (error) {
+        showNotification(t('settings.masterKeyImportFailed') + ': ' + error, 'error');
+    }
+}
+
 // Save settings
 export async function saveSettings() {
     try {
@@ -309,6 +353,15 @@ export async function saveSettings() {
         const theme = document.getElementById('settingsTheme').value;
         const themeAuto = document.getElementById('settingsThemeAuto').checked;
         const proxyUrl = document.getElementById('settingsProxyUrl').value.trim();
+        const tls = document.getElementById('settingsTLS').checked;
+        const listen = {
+            bindAddress: document.getElementById('settingsBindAddress').value.trim(),
+            unixSocket: document.getElementById('settingsUnixSocket').value.trim(),
+            tls: tls,
+            certFile: tls ? document.getElementById('settingsTLSCert').value.trim() : '',
+            keyFile: tls ? document.getElementById('settingsTLSKey').value.trim() : '',
+            clientCAFile: tls ? document.getElementById('settingsTLSClientCA').value.trim() : ''
+        };
 
         // Get Claude notification settings
         const claudeNotificationType = document.getElementById('settingsNotificationType').value;
@@ -322,6 +375,7 @@ export async function saveSettings() {
         const settings = {
             closeWindowBehavior: closeWindowBehavior,
             proxyUrl: proxyUrl,
+            listen: listen,
             theme: theme,
             themeAuto: themeAuto,
             claudeNotificationEnabled: claudeNotificationEnabled,
@@ -329,6 +383,13 @@ export async function saveSettings() {
         };
         await window.go.main.App.SaveSettings(JSON.stringify(settings));
 
+        const oldListen = config.listen || {};
+        const listenChanged = ['bindAddress', 'unixSocket', 'tls', 'certFile', 'keyFile', 'clientCAFile']
+            .some(key => (oldListen[key] || '') !== (listen[key] || ''));
+        if (listenChanged) {
+            showNotification(t('settings.listenRestart'), 'info');
+        }
+
         // Apply theme based on final settings
         stopAutoThemeCheck();
         if (themeAuto) {
@@ -755,6 +755,39 @@ export function initUI() {
                         </div>
                         <input type="text" id="settingsProxyUrl" placeholder="${t('settings.proxyUrlPlaceholder')}">
                     </div>
+                    <div class="form-group">
+                        <div class="form-label-row">
+                            <label>${t('settings.listen')}</label>
+                            <small class="form-help">${t('settings.listenHelp')}</small>
+                        </div>
+                        <div style="display: flex; gap: 10px; align-items: center;">
+                            <input type="text" id="settingsBindAddress" placeholder="${t('settings.bindAddressPlaceholder')}" style="flex: 1;">
+                            <div style="display: flex; align-items: center; gap: 8px; white-space: nowrap;" title="${t('settings.tlsHelp')}">
+                                <span style="font-size: 13px; color: var(--text-secondary);">HTTPS</span>
+                                <label class="toggle-switch" style="width: 40px; height: 20px; margin-top: 7px;">
+                                    <input type="checkbox" id="settingsTLS">
+                                    <span class="toggle-slider" style="border-radius: 20px;"></span>
+                                </label>
+                            </div>
+                        </div>
+                        <div id="settingsTLSFiles" style="display: none; margin-top: 8px;">
+                            <input type="text" id="settingsTLSCert" placeholder="${t('settings.tlsCertPlaceholder')}">
+                            <input type="text" id="settingsTLSKey" placeholder="${t('settings.tlsKeyPlaceholder')}" style="margin-top: 8px;">
+                            <input type="text" id="settingsTLSClientCA" placeholder="${t('settings.tlsClientCAPlaceholder')}" style="margin-top: 8px;">
+                        </div>
+                        <input type="text" id="settingsUnixSocket" placeholder="${t('settings.unixSocketPlaceholder')}" style="margin-top: 8px;">
+                    </div>
+                    <div class="form-group">
+                        <div class="form-label-row">
+                            <label>${t('settings.masterKey')}</label>
+                            <small class="form-help">${t('settings.masterKeyHelp')}</small>
+                        </div>
+                        <div style="display: flex; gap: 10px; align-items: center;">
+                            <input type="password" id="settingsMasterKey" placeholder="${t('settings.masterKeyPlaceholder')}" style="flex: 1;">
+                            <button class="btn btn-secondary" onclick="window.importMasterKey()">${t('settings.masterKeyImport')}</button>
+                            <button class="btn btn-secondary" onclick="window.exportMasterKey()">${t('settings.masterKeyExport')}</button>
+                        </div>
+                    </div>
                     <div class="form-group">
                         <div class="form-label-row">
                             <label><span class="required">*</span>${t('update.autoCheck')}</label>
@@ -0,0 +1,74 @@
+import { t } from '../i18n/index.js';
+
+// Show the unlock screen when the master key of the database is not on this
+// machine: enter the key or passphrase it was encrypted with, or reset the
+// secrets. Either way the page reloads into the normal UI.
+export function showUnlockScreen() {
+    const app = document.getElementById('app');
+    app.innerHTML = `
+        <div id="unlockModal" class="modal active">
+            <div class="modal-content">
+                <div class="modal-header">
+                    <h2>🔒 ${t('unlock.title')}</h2>
+                </div>
+                <div class="modal-body">
+                    <p>${t('unlock.message')}</p>
+                    <div class="form-group">
+                        <input type="password" id="unlockMasterKey" placeholder="${t('unlock.placeholder')}">
+                    </div>
+                    <p id="unlockError" style="color: #e74c3c; font-size: 13px; display: none;"></p>
+                </div>
+                <div class="modal-footer">
+                    <button class="btn btn-secondary" id="unlockResetBtn">${t('unlock.reset')}</button>
+                    <button class="btn btn-primary" id="unlockBtn">${t('unlock.unlock')}</button>
+                </div>
+            </div>
+        </div>
+    `;
+
+    const input = document.getElementById('unlockMasterKey');
+    const errorEl = document.getElementById('unlockError');
+    const resetBtn = document.getElementById('unlockResetBtn');
+    const showError = (message) => {
+        errorEl.textContent = message;
+        errorEl.style.display = 'block';
+    };
+
+    const unlock = async () => {
+        const value = input.value.trim();
+        if (!value) {
+            showError(t('unlock.required'));
+            return;
+        }
+        try {
+            await window.go.main.App.UnlockStorage(value);
+            window.location.reload();
+        } catch (error) {
+            showError(t('unlock.failed') + ': ' + error);
+        }
+    };
+
+    // Resetting discards every stored API key, so it takes a second click
+    let resetArmed = false;
+    const reset = async () => {
+        if (!resetArmed) {
+            resetArmed = true;
+            resetBtn.textContent = t('unlock.resetConfirm');
+            showError(t('unlock.resetWarning'));
+            return;
+        }
+        try {
+            await window.go.main.App.ResetSecrets();
+            window.location.reload();
+        } catch (error) {
+            showError(t('unlock.resetFailed') + ': ' + error);
+        }
+    };
+
+    document.getElementById('unlockBtn').addEventListener('click', unlock);
+    resetBtn.addEventListener('click', reset);
+    input.addEventListener('keydown', (e) => {
+        if (e.key === 'Enter') unlock();
+    });
+    input.focus();
+}
@@ -39,6 +39,8 @@ export function DetectWebDAVConflict(arg1:string):Promise<string>;
 
 export function DownloadUpdate(arg1:string,arg2:string):Promise<void>;
 
+export function ExportMasterKey():Promise<string>;
+
 export function FetchBroadcast(arg1:string):Promise<string>;
 
 export function FetchCodexRateLimits(arg1:number):Promise<string>;
@@ -75,6 +77,8 @@ export function GetDownloadProgress():Promise<string>;
 
 export function GetEndpointCredentials(arg1:number):Promise<string>;
 
+export function GetEndpointOptions(arg1:number):Promise<string>;
+
 export function GetLanguage():Promise<string>;
 
 export function GetLogLevel():Promise<number>;
@@ -121,8 +125,12 @@ export function ImportEndpointCredentials(arg1:number,arg2:string,arg3:boolean):
 
 export function ImportEndpointCredentialsFromFiles(arg1:number,arg2:boolean):Promise<string>;
 
+export function ImportMasterKey(arg1:string):Promise<void>;
+
 export function InstallUpdate(arg1:string):Promise<string>;
 
+export function IsStorageLocked():Promise<boolean>;
+
 export function LaunchCodexSessionTerminal(arg1:string,arg2:string):Promise<void>;
 
 export function LaunchCodexTerminal(arg1:string):Promise<void>;
@@ -153,6 +161,8 @@ export function RenameSession(arg1:string,arg2:string,arg3:string):Promise<void>
 
 export function ReorderEndpoints(arg1:Array<string>):Promise<void>;
 
+export function ResetSecrets():Promise<void>;
+
 export function RestoreFromProvider(arg1:string,arg2:string,arg3:string):Promise<void>;
 
 export function RestoreFromWebDAV(arg1:string,arg2:string):Promise<void>;
@@ -205,6 +215,8 @@ export function TestWebDAVConnection(arg1:string,arg2:string,arg3:string):Promis
 
 export function ToggleEndpoint(arg1:number,arg2:boolean):Promise<void>;
 
+export function UnlockStorage(arg1:string):Promise<void>;
+
 export function UpdateBackupProvider(arg1:string):Promise<void>;
 
 export function UpdateConfig(arg1:string):Promise<void>;
@@ -213,6 +225,8 @@ export function UpdateEndpoint(arg1:number,arg2:string,arg3:string,arg4:string,a
 
 export function UpdateEndpointCredentialToken(arg1:number,arg2:number,arg3:string,arg4:string):Promise<void>;
 
+export function UpdateEndpointOptions(arg1:number,arg2:string):Promise<void>;
+
 export function UpdateLocalBackupDir(arg1:string):Promise<void>;
 
 export function UpdatePort(arg1:number):Promise<void>;
@@ -78,6 +78,10 @@ export function DownloadUpdate(arg1, arg2) {
   return window['go']['main']['App']['DownloadUpdate'](arg1, arg2);
 }
 
+export function ExportMasterKey() {
+  return window['go']['main']['App']['ExportMasterKey']();
+}
+
 export function FetchBroadcast(arg1) {
   return window['go']['main']['App']['FetchBroadcast'](arg1);
 }
@@ -150,6 +154,10 @@ export function GetEndpointCredentials(arg1) {
   return window['go']['main']['App']['GetEndpointCredentials'](arg1);
 }
 
+export function GetEndpointOptions(arg1) {
+  return window['go']['main']['App']['GetEndpointOptions'](arg1);
+}
+
 export function GetLanguage() {
   return window['go']['main']['App']['GetLanguage']();
 }
@@ -242,10 +250,18 @@ export function ImportEndpointCredentialsFromFiles(arg1, arg2) {
   return window['go']['main']['App']['ImportEndpointCredentialsFromFiles'](arg1, arg2);
 }
 
+export function ImportMasterKey(arg1) {
+  return window['go']['main']['App']['ImportMasterKey'](arg1);
+}
+
 export function InstallUpdate(arg1) {
   return window['go']['main']['App']['InstallUpdate'](arg1);
 }
 
+export function IsStorageLocked() {
+  return window['go']['main']['App']['IsStorageLocked']();
+}
+
 export function LaunchCodexSessionTerminal(arg1, arg2) {
   return window['go']['main']['App']['LaunchCodexSessionTerminal'](arg1, arg2);
 }
@@ -306,6 +322,10 @@ export function ReorderEndpoints(arg1) {
   return window['go']['main']['App']['ReorderEndpoints'](arg1);
 }
 
+export function ResetSecrets() {
+  return window['go']['main']['App']['ResetSecrets']();
+}
+
 export function RestoreFromProvider(arg1, arg2, arg3) {
   return window['go']['main']['App']['RestoreFromProvider'](arg1, arg2, arg3);
 }
@@ -410,6 +430,10 @@ export function ToggleEndpoint(arg1, arg2) {
   return window['go']['main']['App']['ToggleEndpoint'](arg1, arg2);
 }
 
+export function UnlockStorage(arg1) {
+  return window['go']['main']['App']['UnlockStorage'](arg1);
+}
+
 export function UpdateBackupProvider(arg1) {
   return window['go']['main']['App']['UpdateBackupProvider'](arg1);
 }
@@ -426,6 +450,10 @@ export function UpdateEndpointCredentialToken(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['UpdateEndpointCredentialToken'](arg1, arg2, arg3, arg4);
 }
 
+export function UpdateEndpointOptions(arg1, arg2) {
+  return window['go']['main']['App']['UpdateEndpointOptions'](arg1, arg2);
+}
+
 export function UpdateLocalBackupDir(arg1) {
   return window['go']['main']['App']['UpdateLocalBackupDir'](arg1);
 }
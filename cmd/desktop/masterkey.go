@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/storage"
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	keyringService = "ccNexus"
+	keyringUser    = "master-key"
+)
+
+// masterKeySourceKeyring is the source of a master key kept in the OS keyring
+const masterKeySourceKeyring = "OS keyring"
+
+// loadMasterKey returns the key that encrypts secrets in the database and
+// where it came from. The CCNEXUS_MASTER_* variables take precedence;
+// otherwise the key is kept in the OS keyring and created on first start.
+// Without a usable keyring secrets stay in plain text.
+func loadMasterKey() (*storage.MasterKey, string) {
+	master, source, err := storage.MasterKeyFromEnv("CCNEXUS")
+	if err != nil {
+		logger.Warn("Ignoring master key from %s: %v", source, err)
+	} else if master != nil {
+		logger.Info("Using the master key from %s", source)
+		return master, source
+	}
+
+	encoded, err := keyring.Get(keyringService, keyringUser)
+	if errors.Is(err, keyring.ErrNotFound) {
+		if encoded, err = storage.GenerateMasterKey(); err == nil {
+			err = keyring.Set(keyringService, keyringUser, encoded)
+		}
+		if err == nil {
+			logger.Info("Created a master key in the OS keyring")
+		}
+	}
+	if err != nil {
+		logger.Warn("OS keyring unavailable, secrets in the database are stored in plain text: %v", err)
+		return nil, ""
+	}
+
+	master, err = parseMasterKey(encoded)
+	if err != nil {
+		logger.Warn("Invalid master key in the OS keyring, secrets in the database are stored in plain text: %v", err)
+		return nil, ""
+	}
+	return master, masterKeySourceKeyring
+}
+
+// parseMasterKey reads a master key as kept in the keyring or entered by the
+// user: 32 bytes encoded as base64 or hex, or else a passphrase
+func parseMasterKey(value string) (*storage.MasterKey, error) {
+	value = strings.TrimSpace(value)
+	if master, err := storage.ParseMasterKey(value); err == nil {
+		return master, nil
+	}
+	return storage.PassphraseMasterKey(value)
+}
+
+// keepMasterKey stores value in the OS keyring unless the master key is set
+// through environment variables, which the keyring would not override
+func (a *App) keepMasterKey(value string) error {
+	if a.masterKeySource != "" && a.masterKeySource != masterKeySourceKeyring {
+		return nil
+	}
+	if err := keyring.Set(keyringService, keyringUser, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("failed to save the master key in the OS keyring: %w", err)
+	}
+	a.masterKeySource = masterKeySourceKeyring
+	return nil
+}
+
+// IsStorageLocked reports whether startup is waiting for the master key of
+// the database, see UnlockStorage and ResetSecrets
+func (a *App) IsStorageLocked() bool {
+	return a.lockedStorage != nil
+}
+
+// UnlockStorage opens a database whose master key is missing from this
+// machine with the key or passphrase it was encrypted with, keeps the key in
+// the OS keyring and completes startup
+func (a *App) UnlockStorage(value string) error {
+	if a.lockedStorage == nil {
+		return fmt.Errorf("storage is not locked")
+	}
+	master, err := parseMasterKey(value)
+	if err != nil {
+		return err
+	}
+	if err := a.lockedStorage.UnlockSecrets(master); err != nil {
+		return err
+	}
+	if err := a.keepMasterKey(value); err != nil {
+		logger.Warn("%v, it must be entered again on the next start", err)
+	}
+	logger.Info("Secrets unlocked with the entered master key")
+	a.finishStartup()
+	return nil
+}
+
+// ResetSecrets recovers a database whose master key is lost: the encrypted
+// API keys, tokens and passwords are cleared and a new master key is created.
+// Endpoints and settings are kept.
+func (a *App) ResetSecrets() error {
+	if a.lockedStorage == nil {
+		return fmt.Errorf("storage is not locked")
+	}
+	master, _, _ := storage.MasterKeyFromEnv("CCNEXUS")
+	if master == nil {
+		encoded, err := storage.GenerateMasterKey()
+		if err != nil {
+			return err
+		}
+		if master, err = storage.ParseMasterKey(encoded); err != nil {
+			return err
+		}
+		if err := a.keepMasterKey(encoded); err != nil {
+			return err
+		}
+	}
+	if err := a.lockedStorage.ResetSecrets(master); err != nil {
+		return err
+	}
+	logger.Warn("Secrets were reset, API keys and passwords must be entered again")
+	a.finishStartup()
+	return nil
+}
+
+// ExportMasterKey returns the master key kept in the OS keyring, so another
+// machine can import it and restore this machine's backups
+func (a *App) ExportMasterKey() (string, error) {
+	if a.masterKeySource != masterKeySourceKeyring {
+		return "", a.masterKeyNotManaged()
+	}
+	return keyring.Get(keyringService, keyringUser)
+}
+
+// ImportMasterKey makes a key or passphrase, e.g. exported on another
+// machine, the master key of this one. The database is encrypted with it
+// from now on, and backups made under it can be restored.
+func (a *App) ImportMasterKey(value string) error {
+	if a.storage == nil {
+		return fmt.Errorf("storage is not available")
+	}
+	if a.masterKeySource != "" && a.masterKeySource != masterKeySourceKeyring {
+		return a.masterKeyNotManaged()
+	}
+	master, err := parseMasterKey(value)
+	if err != nil {
+		return err
+	}
+
+	previous, _ := keyring.Get(keyringService, keyringUser)
+	if err := a.keepMasterKey(value); err != nil {
+		return err
+	}
+	if a.storage.SecretsEncrypted() {
+		err = a.storage.RotateMasterKey(master, false)
+	} else {
+		err = a.storage.UnlockSecrets(master)
+	}
+	if err != nil {
+		if previous != "" {
+			keyring.Set(keyringService, keyringUser, previous)
+		}
+		return err
+	}
+	logger.Info("Imported a master key, secrets are now encrypted with it")
+	return nil
+}
+
+func (a *App) masterKeyNotManaged() error {
+	if a.masterKeySource == "" {
+		return fmt.Errorf("no master key: the OS keyring is unavailable and secrets are stored in plain text")
+	}
+	return fmt.Errorf("the master key is set by %s", a.masterKeySource)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/proxy"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+// configWatchInterval is how often a watched config file is checked for changes
+const configWatchInterval = 2 * time.Second
+
+// applyConfigFile reconciles storage with the config file and logs what changed
+func applyConfigFile(path string, adapter config.StorageAdapter) (*config.DeclarativeConfig, error) {
+	file, err := config.LoadDeclarativeConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	_, changes, err := config.ReconcileDeclarativeConfig(file, adapter)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		logger.Info("Config file %s is in sync", path)
+	}
+	for _, change := range changes {
+		logger.Info("Config file %s: %s", path, change)
+	}
+	return file, nil
+}
+
+// configFileDigest hashes a config file together with the files it
+// references through ${file:PATH}, so rotating a secret file counts as a change
+func configFileDigest(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	hash := sha256.New()
+	hash.Write(data)
+	for _, ref := range config.DeclarativeFileRefs(data, filepath.Dir(path)) {
+		// A missing file is reported by the reconcile it triggers
+		content, _ := os.ReadFile(ref)
+		hash.Write([]byte{0})
+		hash.Write(content)
+	}
+	var digest [sha256.Size]byte
+	hash.Sum(digest[:0])
+	return digest, nil
+}
+
+// watchConfigFile re-applies the config file whenever its content or that
+// of a file it references changes. A file that fails to parse or validate is
+// logged and leaves the running config untouched; the port only changes on
+// restart.
+func watchConfigFile(path string, cfg *config.Config, p *proxy.Proxy, adapter config.StorageAdapter) {
+	last, _ := configFileDigest(path)
+	for range time.Tick(configWatchInterval) {
+		digest, err := configFileDigest(path)
+		if err != nil {
+			logger.Warn("Failed to read config file %s: %v", path, err)
+			continue
+		}
+		if digest == last {
+			continue
+		}
+		last = digest
+
+		logger.Info("Config file %s changed, reconciling", path)
+		file, err := applyConfigFile(path, adapter)
+		if err != nil {
+			logger.Error("Config file %s not applied: %v", path, err)
+			continue
+		}
+		port := cfg.GetPort()
+		file.ApplyTo(cfg)
+		if cfg.GetPort() != port {
+			logger.Warn("Port change to %d takes effect after a restart", cfg.GetPort())
+			cfg.UpdatePort(port)
+		}
+		applyEnvOverrides(cfg)
+		setLogLevels(cfg.GetLogLevel())
+		if err := p.UpdateConfig(cfg); err != nil {
+			logger.Error("Failed to update proxy config: %v", err)
+		}
+	}
+}
+
+// runExport prints the stored config in the config file format
+func runExport(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "yaml", "Output format: yaml or json")
+	inlineSecrets := fs.Bool("inline-secrets", false, "Write API keys and passwords instead of ${env:...} references")
+	output := fs.String("o", "", "Write to a file instead of stdout")
+	fs.Parse(args)
+
+	sqliteStorage, _, err := openStorage(resolveDBPath(resolveDataDir()))
+	if err != nil {
+		logger.Error("Failed to open SQLite storage: %v", err)
+		return 1
+	}
+	defer sqliteStorage.Close()
+
+	cfg, err := config.LoadFromStorage(storage.NewConfigStorageAdapter(sqliteStorage))
+	if err != nil {
+		logger.Error("Unable to load configuration: %v", err)
+		return 1
+	}
+	data, err := config.ExportDeclarativeConfig(cfg, *inlineSecrets).Marshal(*format)
+	if err != nil {
+		logger.Error("Failed to export configuration: %v", err)
+		return 1
+	}
+
+	if *output != "" {
+		err = os.WriteFile(*output, data, 0600)
+	} else {
+		_, err = stdout.Write(data)
+	}
+	if err != nil {
+		logger.Error("Failed to write configuration: %v", err)
+		return 1
+	}
+	return 0
+}
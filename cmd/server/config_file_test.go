@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func TestApplyConfigFileAndExport(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "ccnexus.db")
+	t.Setenv("CCNEXUS_DB_PATH", dbPath)
+	t.Setenv("CCNEXUS_TEST_KEY", "sk-from-env")
+
+	configPath := filepath.Join(dir, "ccnexus.yaml")
+	os.WriteFile(configPath, []byte(`
+endpoints:
+  - name: Primary
+    apiUrl: https://api.anthropic.com
+    apiKey: ${env:CCNEXUS_TEST_KEY}
+  - name: Fallback
+    apiUrl: https://api.openai.com
+    apiKey: sk-fallback
+    transformer: openai
+    model: gpt-4o
+`), 0600)
+
+	store, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyConfigFile(configPath, storage.NewConfigStorageAdapter(store)); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := loadConfig(store)
+	store.Close()
+	if endpoints := cfg.GetEndpoints(); len(endpoints) != 2 || endpoints[0].APIKey != "sk-from-env" {
+		t.Fatalf("config file was not applied: %+v", endpoints)
+	}
+
+	var out bytes.Buffer
+	if code := runExport([]string{"-format", "yaml"}, &out); code != 0 {
+		t.Fatalf("export failed with exit code %d", code)
+	}
+	for _, want := range []string{"name: Primary", "apiKey: ${env:CCNEXUS_PRIMARY_API_KEY}", "model: gpt-4o"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("export lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "sk-") {
+		t.Fatalf("export leaks an API key:\n%s", out.String())
+	}
+}
+
+func TestConfigFileDigestCoversReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "ccnexus.yaml")
+	os.WriteFile(configPath, []byte("endpoints:\n  - name: Primary\n    apiUrl: https://api.anthropic.com\n    apiKey: ${file:primary.key}\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "primary.key"), []byte("sk-old\n"), 0600)
+
+	before, err := configFileDigest(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := configFileDigest(configPath); again != before {
+		t.Fatal("expected the digest of an unchanged config to stay the same")
+	}
+	os.WriteFile(filepath.Join(dir, "primary.key"), []byte("sk-new\n"), 0600)
+	if after, _ := configFileDigest(configPath); after == before {
+		t.Fatal("expected rotating a referenced secret file to change the digest")
+	}
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes of the ctl subcommand
+const (
+	ctlExitOK           = 0
+	ctlExitFailed       = 1 // Request rejected, endpoint test failed or server unreachable
+	ctlExitUsage        = 2
+	ctlExitUnauthorized = 3
+)
+
+const ctlUsage = `Usage: ccnexus-server ctl [flags] <command> [args]
+
+Commands:
+  endpoints list | get <name> | current
+  endpoints add -name N -url URL [-key K] [-auth-mode M] [-transformer T] [-model M] [-remark R] [-options JSON] [-disabled]
+  endpoints edit <name> [-name N] [-url URL] [-key K] [-auth-mode M] [-transformer T] [-model M] [-remark R] [-options JSON]
+  endpoints enable|disable|remove|switch|test <name>
+  endpoints reorder <name>...
+  credentials list <endpoint>
+  credentials import <endpoint> <file|-> [-overwrite] [-remark R]
+  keys list | rotate <id> | revoke <id>
+  keys create -name N [-endpoints a,b] [-groups g,h] [-models m,n] [-rpm N] [-daily-tokens N] [-expires T] [-remark R]
+  keys usage [-start YYYY-MM-DD] [-end YYYY-MM-DD]
+  stats [daily|weekly|monthly]
+  events [-n count]
+  config get
+  config set port|log-level <value>
+
+The server may also be unix:///path/to/socket when it listens on a Unix socket.
+
+Flags:
+`
+
+// ctlError is a failed API call; the status decides the exit code
+type ctlError struct {
+	status  int
+	message string
+}
+
+func (e *ctlError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("HTTP %d", e.status)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.status, e.message)
+}
+
+// errUsage reports a malformed command line
+var errUsage = errors.New("usage")
+
+// ctlClient talks to the management API of a running server
+type ctlClient struct {
+	server   string
+	user     string
+	password string
+	json     bool
+	http     *http.Client
+	out      io.Writer
+	stdin    io.Reader
+}
+
+// runCtl manages a running server through its REST API, see ctlUsage
+func runCtl(args []string, stdout, stderr io.Writer, stdin io.Reader) int {
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", envOr("CCNEXUS_SERVER", "http://127.0.0.1:3000"), "Server URL (env CCNEXUS_SERVER)")
+	user := fs.String("user", envOr("CCNEXUS_USER", "admin"), "Basic Auth username (env CCNEXUS_USER)")
+	password := fs.String("password", os.Getenv("CCNEXUS_PASSWORD"), "Basic Auth password (env CCNEXUS_PASSWORD)")
+	jsonOutput := fs.Bool("json", false, "Print JSON instead of tables")
+	timeout := fs.Int("timeout", 30, "Request timeout in seconds, endpoint tests and events excepted")
+	caFile := fs.String("ca", os.Getenv("CCNEXUS_CA"), "PEM CA file to verify an HTTPS server with (env CCNEXUS_CA)")
+	certFile := fs.String("cert", os.Getenv("CCNEXUS_CLIENT_CERT"), "PEM client certificate for mTLS (env CCNEXUS_CLIENT_CERT)")
+	keyFile := fs.String("key", os.Getenv("CCNEXUS_CLIENT_KEY"), "PEM client key for mTLS (env CCNEXUS_CLIENT_KEY)")
+	insecure := fs.Bool("insecure", false, "Skip verifying the server certificate, e.g. a self-signed one")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, ctlUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return ctlExitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return ctlExitUsage
+	}
+
+	serverURL, transport, err := ctlTransport(strings.TrimSuffix(*server, "/"), *caFile, *certFile, *keyFile, *insecure)
+	if err != nil {
+		fmt.Fprintf(stderr, "ctl: %v\n", err)
+		return ctlExitUsage
+	}
+	c := &ctlClient{
+		server:   serverURL,
+		user:     *user,
+		password: *password,
+		json:     *jsonOutput,
+		http:     &http.Client{Timeout: time.Duration(*timeout) * time.Second, Transport: transport},
+		out:      stdout,
+		stdin:    stdin,
+	}
+
+	err = c.dispatch(fs.Arg(0), fs.Args()[1:])
+	switch {
+	case err == nil:
+		return ctlExitOK
+	case errors.Is(err, errUsage):
+		fs.Usage()
+		return ctlExitUsage
+	}
+	fmt.Fprintf(stderr, "ctl: %v\n", err)
+	var apiErr *ctlError
+	if errors.As(err, &apiErr) && (apiErr.status == http.StatusUnauthorized || apiErr.status == http.StatusForbidden) {
+		return ctlExitUnauthorized
+	}
+	return ctlExitFailed
+}
+
+func (c *ctlClient) dispatch(command string, args []string) error {
+	switch command {
+	case "endpoints", "endpoint", "ep":
+		return c.endpoints(args)
+	case "credentials", "creds":
+		return c.credentials(args)
+	case "keys", "key":
+		return c.keys(args)
+	case "stats":
+		return c.stats(args)
+	case "events":
+		return c.events(args)
+	case "config":
+		return c.config(args)
+	}
+	return errUsage
+}
+
+// ctlTransport returns the base URL and transport for the server, which is an
+// http(s) URL, a host:port or unix:///path/to/socket
+func ctlTransport(server, caFile, certFile, keyFile string, insecure bool) (string, *http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if socket, ok := strings.CutPrefix(server, "unix://"); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		return "http://unix", transport, nil
+	}
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		server = "http://" + server
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return "", nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return "", nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return "", nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return server, transport, nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (c *ctlClient) newRequest(method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	return req, nil
+}
+
+// call sends a request and decodes the data of the API envelope into out.
+// Responses without an envelope are decoded whole.
+func (c *ctlClient) call(method, path string, body, out interface{}) error {
+	req, err := c.newRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(data, &apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(data))
+		}
+		return &ctlError{status: resp.StatusCode, message: apiErr.Error}
+	}
+	if out == nil {
+		return nil
+	}
+
+	var envelope struct {
+		Success *bool           `json:"success"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Success != nil && envelope.Data != nil {
+		data = envelope.Data
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unexpected response: %w", err)
+	}
+	return nil
+}
+
+// printJSON writes v as indented JSON
+func (c *ctlClient) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printTable writes tab-separated rows as aligned columns
+func (c *ctlClient) printTable(header []string, rows [][]string) {
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// printMessage prints a confirmation, or the response itself with -json
+func (c *ctlClient) printMessage(resp interface{}, format string, args ...interface{}) error {
+	if c.json {
+		return c.printJSON(resp)
+	}
+	fmt.Fprintf(c.out, format+"\n", args...)
+	return nil
+}
+
+// parseFlags parses subcommand flags that may come before or after the
+// positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func (c *ctlClient) stats(args []string) error {
+	period := "daily"
+	if len(args) > 1 {
+		return errUsage
+	}
+	if len(args) == 1 {
+		period = args[0]
+	}
+	switch period {
+	case "daily", "weekly", "monthly":
+	default:
+		return errUsage
+	}
+
+	var resp struct {
+		Period    string `json:"period"`
+		Date      string `json:"date,omitempty"`
+		StartDate string `json:"startDate,omitempty"`
+		EndDate   string `json:"endDate,omitempty"`
+		Stats     struct {
+			TotalRequests     int64 `json:"totalRequests"`
+			TotalErrors       int64 `json:"totalErrors"`
+			TotalSuccess      int64 `json:"totalSuccess"`
+			TotalInputTokens  int64 `json:"totalInputTokens"`
+			TotalOutputTokens int64 `json:"totalOutputTokens"`
+			Endpoints         map[string]struct {
+				Requests     int64 `json:"requests"`
+				Errors       int64 `json:"errors"`
+				InputTokens  int64 `json:"inputTokens"`
+				OutputTokens int64 `json:"outputTokens"`
+			} `json:"endpoints"`
+		} `json:"stats"`
+	}
+	if err := c.call(http.MethodGet, "/api/stats/"+period, nil, &resp); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+
+	names := make([]string, 0, len(resp.Stats.Endpoints))
+	for name := range resp.Stats.Endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var rows [][]string
+	for _, name := range names {
+		s := resp.Stats.Endpoints[name]
+		rows = append(rows, []string{name, fmt.Sprint(s.Requests), fmt.Sprint(s.Errors), fmt.Sprint(s.InputTokens), fmt.Sprint(s.OutputTokens)})
+	}
+	total := resp.Stats
+	rows = append(rows, []string{"TOTAL", fmt.Sprint(total.TotalRequests), fmt.Sprint(total.TotalErrors), fmt.Sprint(total.TotalInputTokens), fmt.Sprint(total.TotalOutputTokens)})
+	c.printTable([]string{"ENDPOINT", "REQUESTS", "ERRORS", "INPUT TOKENS", "OUTPUT TOKENS"}, rows)
+	return nil
+}
+
+// events prints the server's event stream, one JSON event per line
+func (c *ctlClient) events(args []string) error {
+	fs := flag.NewFlagSet("events", flag.ContinueOnError)
+	count := fs.Int("n", 0, "Stop after this many events (0 follows until interrupted)")
+	if rest, err := parseFlags(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+
+	req, err := c.newRequest(http.MethodGet, "/api/events", nil)
+	if err != nil {
+		return err
+	}
+	client := *c.http
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return &ctlError{status: resp.StatusCode, message: strings.TrimSpace(string(data))}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	seen := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		fmt.Fprintln(c.out, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		seen++
+		if *count > 0 && seen >= *count {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("event stream closed by the server")
+}
+
+func (c *ctlClient) config(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "get":
+		if len(args) != 1 {
+			return errUsage
+		}
+		var cfg map[string]interface{}
+		if err := c.call(http.MethodGet, "/api/config", nil, &cfg); err != nil {
+			return err
+		}
+		var port struct {
+			PortLocked bool `json:"portLocked"`
+		}
+		if err := c.call(http.MethodGet, "/api/config/port", nil, &port); err != nil {
+			return err
+		}
+		var auth map[string]interface{}
+		if err := c.call(http.MethodGet, "/api/config/basic-auth", nil, &auth); err != nil {
+			return err
+		}
+		cfg["portLocked"] = port.PortLocked
+		cfg["basicAuthEnabled"] = auth["enabled"]
+		cfg["basicAuthUsername"] = auth["username"]
+		if c.json {
+			return c.printJSON(cfg)
+		}
+		for _, key := range []string{"port", "portLocked", "logLevel", "basicAuthEnabled", "basicAuthUsername"} {
+			fmt.Fprintf(c.out, "%s=%v\n", key, cfg[key])
+		}
+		return nil
+
+	case "set":
+		if len(args) != 3 {
+			return errUsage
+		}
+		var value int
+		if _, err := fmt.Sscan(args[2], &value); err != nil {
+			return fmt.Errorf("%s must be a number: %q", args[1], args[2])
+		}
+		var path, field string
+		switch args[1] {
+		case "port":
+			path, field = "/api/config/port", "port"
+		case "log-level", "logLevel":
+			path, field = "/api/config/log-level", "logLevel"
+		default:
+			return errUsage
+		}
+		var resp map[string]interface{}
+		if err := c.call(http.MethodPut, path, map[string]int{field: value}, &resp); err != nil {
+			return err
+		}
+		return c.printMessage(resp, "%v", resp["message"])
+	}
+	return errUsage
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func endpointPath(name string, parts ...string) string {
+	path := "/api/endpoints/" + url.PathEscape(name)
+	for _, part := range parts {
+		path += "/" + part
+	}
+	return path
+}
+
+// endpointFields are the flags shared by endpoints add and edit
+type endpointFields struct {
+	name, url, key, authMode, transformer, model, remark, options *string
+}
+
+func newEndpointFields(fs *flag.FlagSet) endpointFields {
+	return endpointFields{
+		name:        fs.String("name", "", "Endpoint name"),
+		url:         fs.String("url", "", "API URL"),
+		key:         fs.String("key", "", "API key"),
+		authMode:    fs.String("auth-mode", "", "api_key or token_pool"),
+		transformer: fs.String("transformer", "", "claude, openai, openai2, gemini or ollama"),
+		model:       fs.String("model", "", "Model name"),
+		remark:      fs.String("remark", "", "Remark"),
+		options:     fs.String("options", "", "Options JSON object"),
+	}
+}
+
+func (c *ctlClient) endpoints(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "list", "ls":
+		if len(args) != 0 {
+			return errUsage
+		}
+		return c.listEndpoints()
+	case "current":
+		if len(args) != 0 {
+			return errUsage
+		}
+		var resp struct {
+			Name string `json:"name"`
+		}
+		if err := c.call(http.MethodGet, "/api/endpoints/current", nil, &resp); err != nil {
+			return err
+		}
+		return c.printMessage(resp, "%s", resp.Name)
+	case "add":
+		return c.addEndpoint(args)
+	case "edit":
+		return c.editEndpoint(args)
+	case "reorder":
+		if len(args) == 0 {
+			return errUsage
+		}
+		var resp map[string]interface{}
+		if err := c.call(http.MethodPost, "/api/endpoints/reorder", map[string][]string{"names": args}, &resp); err != nil {
+			return err
+		}
+		return c.printMessage(resp, "Reordered %d endpoints", len(args))
+	}
+
+	if len(args) != 1 {
+		return errUsage
+	}
+	name := args[0]
+	var resp map[string]interface{}
+	switch command {
+	case "get":
+		var endpoint storage.Endpoint
+		if err := c.call(http.MethodGet, endpointPath(name), nil, &endpoint); err != nil {
+			return err
+		}
+		if c.json {
+			return c.printJSON(endpoint)
+		}
+		c.printEndpoints([]storage.Endpoint{endpoint}, nil)
+		return nil
+	case "enable", "disable":
+		enabled := command == "enable"
+		if err := c.call(http.MethodPost, endpointPath(name, "toggle"), map[string]bool{"enabled": enabled}, &resp); err != nil {
+			return err
+		}
+		if enabled {
+			return c.printMessage(resp, "Enabled %s", name)
+		}
+		return c.printMessage(resp, "Disabled %s", name)
+	case "remove", "rm":
+		if err := c.call(http.MethodDelete, endpointPath(name), nil, &resp); err != nil {
+			return err
+		}
+		return c.printMessage(resp, "Removed %s", name)
+	case "switch":
+		if err := c.call(http.MethodPost, "/api/endpoints/switch", map[string]string{"name": name}, &resp); err != nil {
+			return err
+		}
+		return c.printMessage(resp, "Switched to %s", name)
+	case "test":
+		return c.testEndpoint(name)
+	}
+	return errUsage
+}
+
+func (c *ctlClient) listEndpoints() error {
+	var resp struct {
+		Endpoints  []storage.Endpoint                `json:"endpoints"`
+		TokenPools map[string]storage.TokenPoolStats `json:"tokenPools"`
+	}
+	if err := c.call(http.MethodGet, "/api/endpoints", nil, &resp); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+	c.printEndpoints(resp.Endpoints, resp.TokenPools)
+	return nil
+}
+
+func (c *ctlClient) printEndpoints(endpoints []storage.Endpoint, pools map[string]storage.TokenPoolStats) {
+	var rows [][]string
+	for _, ep := range endpoints {
+		auth := ep.AuthMode
+		if pool, ok := pools[ep.Name]; ok && pool.Total > 0 {
+			auth = fmt.Sprintf("%s (%d/%d active)", auth, pool.Active, pool.Total)
+		}
+		rows = append(rows, []string{ep.Name, fmt.Sprint(ep.Enabled), ep.Transformer, ep.Model, auth, ep.APIUrl})
+	}
+	c.printTable([]string{"NAME", "ENABLED", "TRANSFORMER", "MODEL", "AUTH", "URL"}, rows)
+}
+
+func (c *ctlClient) addEndpoint(args []string) error {
+	fs := flag.NewFlagSet("endpoints add", flag.ContinueOnError)
+	fields := newEndpointFields(fs)
+	disabled := fs.Bool("disabled", false, "Add the endpoint disabled")
+	if rest, err := parseFlags(fs, args); err != nil || len(rest) > 0 || *fields.name == "" || *fields.url == "" {
+		return errUsage
+	}
+
+	body := map[string]interface{}{
+		"name":        *fields.name,
+		"apiUrl":      *fields.url,
+		"apiKey":      *fields.key,
+		"authMode":    *fields.authMode,
+		"enabled":     !*disabled,
+		"transformer": *fields.transformer,
+		"model":       *fields.model,
+		"remark":      *fields.remark,
+	}
+	if *fields.options != "" {
+		body["options"] = json.RawMessage(*fields.options)
+	}
+	var resp map[string]interface{}
+	if err := c.call(http.MethodPost, "/api/endpoints", body, &resp); err != nil {
+		return err
+	}
+	return c.printMessage(resp, "Added %s", *fields.name)
+}
+
+// editEndpoint changes only the given fields; the update API replaces
+// enabled and remark, so their current values are sent back
+func (c *ctlClient) editEndpoint(args []string) error {
+	fs := flag.NewFlagSet("endpoints edit", flag.ContinueOnError)
+	newEndpointFields(fs)
+	rest, err := parseFlags(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	name := rest[0]
+
+	var existing storage.Endpoint
+	if err := c.call(http.MethodGet, endpointPath(name), nil, &existing); err != nil {
+		return err
+	}
+	body := map[string]interface{}{
+		"enabled": existing.Enabled,
+		"remark":  existing.Remark,
+	}
+	set := map[string]string{
+		"name": "name", "url": "apiUrl", "key": "apiKey", "auth-mode": "authMode",
+		"transformer": "transformer", "model": "model", "remark": "remark",
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "options" {
+			body["options"] = json.RawMessage(f.Value.String())
+		} else {
+			body[set[f.Name]] = f.Value.String()
+		}
+	})
+
+	var resp map[string]interface{}
+	if err := c.call(http.MethodPut, endpointPath(name), body, &resp); err != nil {
+		return err
+	}
+	return c.printMessage(resp, "Updated %s", name)
+}
+
+func (c *ctlClient) testEndpoint(name string) error {
+	var resp struct {
+		Success  bool   `json:"success"`
+		Latency  int64  `json:"latency"`
+		Response string `json:"response,omitempty"`
+		Error    string `json:"error,omitempty"`
+	}
+	// Tests wait on the upstream, which has its own timeout
+	client := *c.http
+	client.Timeout = 0
+	untimed := *c
+	untimed.http = &client
+	if err := untimed.call(http.MethodPost, endpointPath(name, "test"), nil, &resp); err != nil {
+		return err
+	}
+	if c.json {
+		if err := c.printJSON(resp); err != nil {
+			return err
+		}
+	} else if resp.Success {
+		fmt.Fprintf(c.out, "OK %s (%dms): %s\n", name, resp.Latency, resp.Response)
+	}
+	if !resp.Success {
+		return fmt.Errorf("test of %s failed after %dms: %s", name, resp.Latency, resp.Error)
+	}
+	return nil
+}
+
+func (c *ctlClient) credentials(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "list", "ls":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return c.listCredentials(args[1])
+	case "import":
+		return c.importCredentials(args[1:])
+	}
+	return errUsage
+}
+
+func (c *ctlClient) listCredentials(endpoint string) error {
+	var resp struct {
+		Credentials []storage.EndpointCredential `json:"credentials"`
+		Stats       *storage.TokenPoolStats      `json:"stats"`
+	}
+	if err := c.call(http.MethodGet, endpointPath(endpoint, "credentials"), nil, &resp); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Local().Format("2006-01-02 15:04")
+	}
+	var rows [][]string
+	for _, cred := range resp.Credentials {
+		account := cred.Email
+		if account == "" {
+			account = cred.AccountID
+		}
+		usage := "-"
+		if limits := cred.RateLimits; limits != nil && limits.Data != nil && limits.Data.Snapshot != nil && limits.Data.Snapshot.Primary != nil {
+			usage = fmt.Sprintf("%.0f%%", limits.Data.Snapshot.Primary.UsedPercent)
+		}
+		rows = append(rows, []string{fmt.Sprint(cred.ID), account, cred.Status, fmt.Sprint(cred.Enabled), formatTime(cred.ExpiresAt), formatTime(cred.LastUsedAt), usage})
+	}
+	c.printTable([]string{"ID", "ACCOUNT", "STATUS", "ENABLED", "EXPIRES", "LAST USED", "PRIMARY USED"}, rows)
+	return nil
+}
+
+// importCredentials accepts what the import API does: one credential, an
+// array of them or {items:[...]}
+func (c *ctlClient) importCredentials(args []string) error {
+	fs := flag.NewFlagSet("credentials import", flag.ContinueOnError)
+	overwrite := fs.Bool("overwrite", false, "Replace tokens of credentials that already exist")
+	remark := fs.String("remark", "", "Remark for imported credentials")
+	rest, err := parseFlags(fs, args)
+	if err != nil || len(rest) != 2 {
+		return errUsage
+	}
+	endpoint, source := rest[0], rest[1]
+
+	var data []byte
+	if source == "-" {
+		data, err = io.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return err
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return fmt.Errorf("parse %s: %w", source, err)
+	}
+	request, ok := payload.(map[string]interface{})
+	if !ok || request["items"] == nil {
+		request = map[string]interface{}{"items": payload}
+		if _, isArray := payload.([]interface{}); !isArray {
+			request["items"] = []interface{}{payload}
+		}
+	}
+	if *overwrite {
+		request["overwrite"] = true
+	}
+	if *remark != "" {
+		request["remark"] = *remark
+	}
+
+	var resp struct {
+		Created   int      `json:"created"`
+		Updated   int      `json:"updated"`
+		Skipped   int      `json:"skipped"`
+		Failed    int      `json:"failed"`
+		Processed int      `json:"processed"`
+		Errors    []string `json:"errors"`
+	}
+	if err := c.call(http.MethodPost, endpointPath(endpoint, "credentials", "import"), request, &resp); err != nil {
+		return err
+	}
+	if err := c.printMessage(resp, "Processed %d: %d created, %d updated, %d skipped, %d failed",
+		resp.Processed, resp.Created, resp.Updated, resp.Skipped, resp.Failed); err != nil {
+		return err
+	}
+	if resp.Failed > 0 {
+		if !c.json {
+			for _, msg := range resp.Errors {
+				fmt.Fprintln(c.out, msg)
+			}
+		}
+		return errors.New("some credentials were not imported")
+	}
+	return nil
+}
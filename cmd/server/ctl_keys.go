@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func clientKeyPath(id string, parts ...string) string {
+	path := "/api/client-keys/" + id
+	for _, part := range parts {
+		path += "/" + part
+	}
+	return path
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (c *ctlClient) keys(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "list", "ls":
+		if len(args) != 0 {
+			return errUsage
+		}
+		return c.listClientKeys()
+	case "create":
+		return c.createClientKey(args)
+	case "usage":
+		return c.clientKeyUsage(args)
+	}
+
+	if len(args) != 1 {
+		return errUsage
+	}
+	id := args[0]
+	switch command {
+	case "rotate":
+		var resp struct {
+			Key    storage.ClientKey `json:"key"`
+			Secret string            `json:"secret"`
+		}
+		if err := c.call(http.MethodPost, clientKeyPath(id, "rotate"), nil, &resp); err != nil {
+			return err
+		}
+		return c.printMessage(resp, "Rotated %s, new key: %s", resp.Key.Name, resp.Secret)
+	case "revoke":
+		var resp map[string]interface{}
+		if err := c.call(http.MethodDelete, clientKeyPath(id), nil, &resp); err != nil {
+			return err
+		}
+		return c.printMessage(resp, "Revoked key %s", id)
+	}
+	return errUsage
+}
+
+func (c *ctlClient) listClientKeys() error {
+	var resp struct {
+		Keys []storage.ClientKey `json:"keys"`
+	}
+	if err := c.call(http.MethodGet, "/api/client-keys", nil, &resp); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Local().Format("2006-01-02 15:04")
+	}
+	orAll := func(items []string) string {
+		if len(items) == 0 {
+			return "*"
+		}
+		return strings.Join(items, ",")
+	}
+	var rows [][]string
+	for _, key := range resp.Keys {
+		status := "active"
+		if key.RevokedAt != nil {
+			status = "revoked"
+		} else if key.ExpiresAt != nil && !time.Now().Before(*key.ExpiresAt) {
+			status = "expired"
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(key.ID), key.Name, key.Prefix + "…", status,
+			orAll(key.AllowedEndpoints), orAll(key.AllowedGroups), orAll(key.AllowedModels),
+			fmt.Sprint(key.RequestsPerMinute), fmt.Sprint(key.DailyTokenQuota),
+			formatTime(key.ExpiresAt), formatTime(key.LastUsedAt),
+		})
+	}
+	c.printTable([]string{"ID", "NAME", "KEY", "STATUS", "ENDPOINTS", "GROUPS", "MODELS", "RPM", "DAILY TOKENS", "EXPIRES", "LAST USED"}, rows)
+	return nil
+}
+
+func (c *ctlClient) createClientKey(args []string) error {
+	fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+	name := fs.String("name", "", "Key name")
+	endpoints := fs.String("endpoints", "", "Comma-separated endpoints the key may use")
+	groups := fs.String("groups", "", "Comma-separated endpoint groups the key may use")
+	models := fs.String("models", "", "Comma-separated models the key may request, globs allowed")
+	rpm := fs.Int("rpm", 0, "Requests per minute (0 is unlimited)")
+	dailyTokens := fs.Int64("daily-tokens", 0, "Tokens per day (0 is unlimited)")
+	expires := fs.String("expires", "", "Expiry as RFC 3339 time, YYYY-MM-DD or a duration such as 720h")
+	remark := fs.String("remark", "", "Remark")
+	if rest, err := parseFlags(fs, args); err != nil || len(rest) > 0 || *name == "" {
+		return errUsage
+	}
+
+	body := map[string]interface{}{
+		"name":              *name,
+		"allowedEndpoints":  splitList(*endpoints),
+		"allowedGroups":     splitList(*groups),
+		"allowedModels":     splitList(*models),
+		"requestsPerMinute": *rpm,
+		"dailyTokenQuota":   *dailyTokens,
+		"remark":            *remark,
+	}
+	if *expires != "" {
+		expiresAt, err := parseExpiry(*expires, time.Now())
+		if err != nil {
+			return err
+		}
+		body["expiresAt"] = expiresAt
+	}
+
+	var resp struct {
+		Key    storage.ClientKey `json:"key"`
+		Secret string            `json:"secret"`
+	}
+	if err := c.call(http.MethodPost, "/api/client-keys", body, &resp); err != nil {
+		return err
+	}
+	return c.printMessage(resp, "Created key %d (%s): %s", resp.Key.ID, resp.Key.Name, resp.Secret)
+}
+
+// parseExpiry reads an RFC 3339 time, a local date or a duration from now
+func parseExpiry(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q", value)
+}
+
+func (c *ctlClient) clientKeyUsage(args []string) error {
+	fs := flag.NewFlagSet("keys usage", flag.ContinueOnError)
+	start := fs.String("start", "", "First day, YYYY-MM-DD (default today)")
+	end := fs.String("end", "", "Last day, YYYY-MM-DD (default today)")
+	if rest, err := parseFlags(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+
+	path := "/api/stats/keys?start=" + *start + "&end=" + *end
+	var resp struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+		Keys  []struct {
+			Name         string                   `json:"name"`
+			Requests     int                      `json:"requests"`
+			Errors       int                      `json:"errors"`
+			InputTokens  int64                    `json:"inputTokens"`
+			OutputTokens int64                    `json:"outputTokens"`
+			Endpoints    []storage.ClientKeyUsage `json:"endpoints"`
+		} `json:"keys"`
+	}
+	if err := c.call(http.MethodGet, path, nil, &resp); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+
+	var rows [][]string
+	for _, key := range resp.Keys {
+		rows = append(rows, []string{key.Name, "*", fmt.Sprint(key.Requests), fmt.Sprint(key.Errors), fmt.Sprint(key.InputTokens), fmt.Sprint(key.OutputTokens)})
+		for _, u := range key.Endpoints {
+			rows = append(rows, []string{"", u.EndpointName, fmt.Sprint(u.Requests), fmt.Sprint(u.Errors), fmt.Sprint(u.InputTokens), fmt.Sprint(u.OutputTokens)})
+		}
+	}
+	c.printTable([]string{"KEY", "ENDPOINT", "REQUESTS", "ERRORS", "INPUT TOKENS", "OUTPUT TOKENS"}, rows)
+	return nil
+}
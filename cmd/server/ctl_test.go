@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/cmd/server/webui/api"
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/mockupstream"
+	"github.com/lich0821/ccNexus/internal/proxy"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+// newCtlTestServer serves the management API backed by a temporary database
+func newCtlTestServer(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(newCtlTestHandler(t))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func newCtlTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "ccnexus.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.BasicAuthEnabled = true
+	cfg.BasicAuthPassword = "secret"
+	p := proxy.New(cfg, storage.NewStatsStorageAdapter(store), store, "test")
+	return api.NewHandler(cfg, p, store)
+}
+
+type ctlResult struct {
+	code           int
+	stdout, stderr string
+}
+
+func ctl(server, stdin string, args ...string) ctlResult {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-server", server, "-password", "secret"}, args...)
+	code := runCtl(args, &stdout, &stderr, strings.NewReader(stdin))
+	return ctlResult{code, stdout.String(), stderr.String()}
+}
+
+func (r ctlResult) expect(t *testing.T, code int, contains ...string) {
+	t.Helper()
+	if r.code != code {
+		t.Fatalf("expected exit code %d, got %d\nstdout: %s\nstderr: %s", code, r.code, r.stdout, r.stderr)
+	}
+	for _, want := range contains {
+		if !strings.Contains(r.stdout, want) {
+			t.Fatalf("output lacks %q:\n%s", want, r.stdout)
+		}
+	}
+}
+
+func TestCtlManagesEndpoints(t *testing.T) {
+	mock := httptest.NewServer(mockupstream.New(mockupstream.Script{APIKey: "upstream-key"}))
+	defer mock.Close()
+	server := newCtlTestServer(t)
+
+	ctl(server, "", "endpoints", "add", "-name", "Mock", "-url", mock.URL, "-key", "upstream-key", "-remark", "offline").expect(t, ctlExitOK, "Added Mock")
+	ctl(server, "", "endpoints", "add", "-name", "Backup", "-url", mock.URL, "-key", "wrong-key", "-transformer", "openai", "-model", "gpt-4o").expect(t, ctlExitOK)
+	ctl(server, "", "endpoints", "list").expect(t, ctlExitOK, "NAME", "Mock", "Backup", "gpt-4o")
+
+	// Editing the model keeps the stored key, so the test still passes
+	ctl(server, "", "endpoints", "edit", "Mock", "-model", "claude-mock").expect(t, ctlExitOK, "Updated Mock")
+	ctl(server, "", "-json", "endpoints", "get", "Mock").expect(t, ctlExitOK, `"model": "claude-mock"`, `"remark": "offline"`, `"enabled": true`)
+	ctl(server, "", "endpoints", "test", "Mock").expect(t, ctlExitOK, "OK Mock", mockupstream.DefaultText)
+	ctl(server, "", "endpoints", "test", "Backup").expect(t, ctlExitFailed)
+
+	ctl(server, "", "endpoints", "reorder", "Backup", "Mock").expect(t, ctlExitOK)
+	ctl(server, "", "endpoints", "disable", "Backup").expect(t, ctlExitOK, "Disabled Backup")
+	ctl(server, "", "endpoints", "current").expect(t, ctlExitOK, "Mock")
+	ctl(server, "", "endpoints", "switch", "Backup").expect(t, ctlExitFailed)
+	ctl(server, "", "endpoints", "remove", "Backup").expect(t, ctlExitOK)
+	ctl(server, "", "endpoints", "get", "Backup").expect(t, ctlExitFailed)
+}
+
+func TestCtlCredentialsStatsAndConfig(t *testing.T) {
+	server := newCtlTestServer(t)
+	ctl(server, "", "endpoints", "add", "-name", "Codex", "-url", "https://chatgpt.com/backend-api/codex", "-auth-mode", "token_pool", "-transformer", "openai2").expect(t, ctlExitOK)
+
+	tokens := `[{"access_token":"a1","refresh_token":"r1","email":"one@example.com"},{"access_token":"a2","email":"two@example.com"}]`
+	ctl(server, tokens, "credentials", "import", "Codex", "-").expect(t, ctlExitOK, "Processed 2: 2 created")
+	ctl(server, `{"access_token":"a3","email":"one@example.com"}`, "credentials", "import", "-overwrite", "Codex", "-").expect(t, ctlExitOK, "1 updated")
+	ctl(server, "", "credentials", "list", "Codex").expect(t, ctlExitOK, "ACCOUNT", "one@example.com", "two@example.com")
+
+	ctl(server, "", "stats", "weekly").expect(t, ctlExitOK, "ENDPOINT", "TOTAL")
+	ctl(server, "", "-json", "stats").expect(t, ctlExitOK, `"period": "daily"`)
+
+	ctl(server, "", "events", "-n", "1").expect(t, ctlExitOK, `"type":"connected"`)
+
+	ctl(server, "", "config", "set", "log-level", "2").expect(t, ctlExitOK)
+	ctl(server, "", "config", "get").expect(t, ctlExitOK, "logLevel=2", "basicAuthEnabled=true")
+	ctl(server, "", "config", "set", "log-level", "9").expect(t, ctlExitFailed)
+}
+
+func TestCtlManagesClientKeys(t *testing.T) {
+	server := newCtlTestServer(t)
+	ctl(server, "", "endpoints", "add", "-name", "Mock", "-url", "http://127.0.0.1:1", "-key", "k").expect(t, ctlExitOK)
+
+	ctl(server, "", "keys", "create", "-name", "ci", "-endpoints", "Mock", "-models", "claude-*", "-rpm", "30", "-expires", "720h").expect(t, ctlExitOK, "Created key 1 (ci): ccn-")
+	ctl(server, "", "keys", "create", "-name", "bad", "-endpoints", "Missing").expect(t, ctlExitFailed)
+	ctl(server, "", "keys", "create", "-name", "bad", "-groups", "team-a").expect(t, ctlExitFailed)
+	ctl(server, "", "keys", "list").expect(t, ctlExitOK, "NAME", "ci", "active", "Mock", "claude-*")
+	ctl(server, "", "keys", "rotate", "1").expect(t, ctlExitOK, "Rotated ci, new key: ccn-")
+	ctl(server, "", "keys", "revoke", "1").expect(t, ctlExitOK, "Revoked key 1")
+	ctl(server, "", "keys", "list").expect(t, ctlExitOK, "revoked")
+	ctl(server, "", "keys", "rotate", "1").expect(t, ctlExitFailed)
+	ctl(server, "", "keys", "usage").expect(t, ctlExitOK, "KEY", "ci")
+	ctl(server, "", "keys", "create").expect(t, ctlExitUsage)
+}
+
+func TestCtlExitCodes(t *testing.T) {
+	server := newCtlTestServer(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCtl([]string{"-server", server, "-password", "wrong", "endpoints", "list"}, &stdout, &stderr, nil); code != ctlExitUnauthorized {
+		t.Fatalf("expected exit code %d for a wrong password, got %d", ctlExitUnauthorized, code)
+	}
+	ctl(server, "", "endpoints", "frobnicate").expect(t, ctlExitUsage)
+	ctl(server, "", "stats", "yearly").expect(t, ctlExitUsage)
+	ctl(server, "", "endpoints", "add", "-name", "NoURL").expect(t, ctlExitUsage)
+	ctl("127.0.0.1:1", "", "endpoints", "list").expect(t, ctlExitFailed)
+}
+
+func TestCtlOverUnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, shorter than many TempDirs
+	dir, err := os.MkdirTemp("", "ccnexus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "ccnexus.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(newCtlTestHandler(t))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	ctl("unix://"+socket, "", "config", "get").expect(t, ctlExitOK, "basicAuthEnabled=true")
+}
@@ -19,8 +19,30 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "mock":
+			os.Exit(runMock(os.Args[2:]))
+		case "ctl":
+			os.Exit(runCtl(os.Args[2:], os.Stdout, os.Stderr, os.Stdin))
+		case "export":
+			os.Exit(runExport(os.Args[2:], os.Stdout))
+		case "rotate-key":
+			os.Exit(runRotateKey(os.Args[2:], os.Stdout))
+		}
+	}
+
 	// Parse command line flags
 	portFlag := flag.Int("port", 0, "Force specific port (locked, cannot be changed via API)")
+	configFlag := flag.String("config", os.Getenv("CCNEXUS_CONFIG"), "YAML or JSON config file to reconcile into storage at startup")
+	watchFlag := flag.Bool("watch", os.Getenv("CCNEXUS_CONFIG_WATCH") == "true", "Re-apply the config file whenever it changes")
+	var listenFlags config.ListenConfig
+	flag.StringVar(&listenFlags.BindAddress, "bind", os.Getenv("CCNEXUS_BIND_ADDRESS"), "Address to listen on, e.g. 127.0.0.1 (default all interfaces)")
+	flag.StringVar(&listenFlags.UnixSocket, "unix-socket", os.Getenv("CCNEXUS_UNIX_SOCKET"), "Listen on this Unix domain socket instead of TCP")
+	flag.BoolVar(&listenFlags.TLS, "tls", envBool("CCNEXUS_TLS"), "Serve HTTPS, with a self-signed certificate unless -tls-cert is set")
+	flag.StringVar(&listenFlags.CertFile, "tls-cert", os.Getenv("CCNEXUS_TLS_CERT"), "PEM certificate file for HTTPS")
+	flag.StringVar(&listenFlags.KeyFile, "tls-key", os.Getenv("CCNEXUS_TLS_KEY"), "PEM private key file for HTTPS")
+	flag.StringVar(&listenFlags.ClientCAFile, "tls-client-ca", os.Getenv("CCNEXUS_TLS_CLIENT_CA"), "Require client certificates signed by the CAs in this PEM file")
 	flag.Parse()
 	dataDir := resolveDataDir()
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
@@ -28,17 +50,26 @@ func main() {
 		os.Exit(1)
 	}
 
-	dbPath := os.Getenv("CCNEXUS_DB_PATH")
-	if dbPath == "" {
-		dbPath = filepath.Join(dataDir, "ccnexus.db")
-	}
-
-	sqliteStorage, err := storage.NewSQLiteStorage(dbPath)
+	dbPath := resolveDBPath(dataDir)
+	sqliteStorage, keySource, err := openStorage(dbPath)
 	if err != nil {
 		logger.Error("Failed to open SQLite storage: %v", err)
 		os.Exit(1)
 	}
 	defer sqliteStorage.Close()
+	if keySource != "" {
+		logger.Info("Secrets in the database are encrypted with the master key from %s", keySource)
+	} else {
+		logger.Warn("No master key set (CCNEXUS_MASTER_KEY), secrets in the database are stored in plain text")
+	}
+
+	// The config file is reconciled first so an empty database is not seeded
+	if *configFlag != "" {
+		if _, err := applyConfigFile(*configFlag, storage.NewConfigStorageAdapter(sqliteStorage)); err != nil {
+			logger.Error("Unable to apply config file: %v", err)
+			os.Exit(1)
+		}
+	}
 
 	cfg, err := loadConfig(sqliteStorage)
 	if err != nil {
@@ -69,6 +100,7 @@ func main() {
 	}
 
 	applyEnvOverrides(cfg)
+	applyListenFlags(cfg, listenFlags)
 	setLogLevels(cfg.GetLogLevel())
 
 	if err := cfg.Validate(); err != nil {
@@ -96,12 +128,17 @@ func main() {
 		logger.Info("Web UI available at /ui/")
 	}
 
+	if *configFlag != "" && *watchFlag {
+		go watchConfigFile(*configFlag, cfg, p, storage.NewConfigStorageAdapter(sqliteStorage))
+		logger.Info("Watching config file %s for changes", *configFlag)
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
 		errCh <- p.StartWithMux(mux)
 	}()
 
-	logger.Info("ccNexus headless API listening on :%d (data dir: %s, db: %s)", cfg.GetPort(), dataDir, dbPath)
+	logger.Info("ccNexus headless API listening on %s (data dir: %s, db: %s)", cfg.GetListen().URL(cfg.GetPort()), dataDir, dbPath)
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -132,6 +169,13 @@ func resolveDataDir() string {
 	return "/data"
 }
 
+func resolveDBPath(dataDir string) string {
+	if dbPath := os.Getenv("CCNEXUS_DB_PATH"); dbPath != "" {
+		return dbPath
+	}
+	return filepath.Join(dataDir, "ccnexus.db")
+}
+
 func loadConfig(sqliteStorage *storage.SQLiteStorage) (*config.Config, error) {
 	adapter := storage.NewConfigStorageAdapter(sqliteStorage)
 	cfg, err := config.LoadFromStorage(adapter)
@@ -185,6 +229,41 @@ func applyEnvOverrides(cfg *config.Config) {
 	}
 }
 
+// applyListenFlags overrides the stored listener settings with the ones given
+// by flags or environment variables for this run, without saving them. A
+// certificate or client CA implies TLS.
+func applyListenFlags(cfg *config.Config, flags config.ListenConfig) {
+	if flags == (config.ListenConfig{}) {
+		return
+	}
+	listen := cfg.GetListen()
+	if flags.BindAddress != "" {
+		listen.BindAddress = flags.BindAddress
+	}
+	if flags.UnixSocket != "" {
+		listen.UnixSocket = flags.UnixSocket
+	}
+	if flags.CertFile != "" {
+		listen.CertFile = flags.CertFile
+	}
+	if flags.KeyFile != "" {
+		listen.KeyFile = flags.KeyFile
+	}
+	if flags.ClientCAFile != "" {
+		listen.ClientCAFile = flags.ClientCAFile
+	}
+	if flags.TLS || flags.CertFile != "" || flags.ClientCAFile != "" {
+		listen.TLS = true
+	}
+	cfg.OverrideListen(listen)
+}
+
+// envBool reports whether an environment variable is set to 1 or true
+func envBool(name string) bool {
+	value := os.Getenv(name)
+	return value == "1" || value == "true"
+}
+
 func setLogLevels(level int) {
 	if level < 0 {
 		return
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/mockupstream"
+)
+
+// runMock serves the scripted mock upstream so endpoints can be pointed at it
+// for offline testing
+func runMock(args []string) int {
+	fs := flag.NewFlagSet("mock", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:9099", "Address to listen on")
+	scriptPath := fs.String("script", "", "JSON script of scripted behaviors (default: canned text reply)")
+	fs.Parse(args)
+
+	var script mockupstream.Script
+	if *scriptPath != "" {
+		loaded, err := mockupstream.LoadScript(*scriptPath)
+		if err != nil {
+			logger.Error("Failed to load mock script: %v", err)
+			return 1
+		}
+		script = loaded
+	}
+
+	logger.Info("Mock upstream listening on http://%s", *addr)
+	if err := http.ListenAndServe(*addr, mockupstream.New(script)); err != nil {
+		logger.Error("Mock upstream stopped with error: %v", err)
+		return 1
+	}
+	return 0
+}
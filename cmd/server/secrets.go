@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+// openStorage opens the database and unlocks its secrets with the master key
+// from CCNEXUS_MASTER_KEY, CCNEXUS_MASTER_KEY_FILE, CCNEXUS_MASTER_PASSPHRASE
+// or CCNEXUS_MASTER_PASSPHRASE_FILE. It returns the variable the key came
+// from, or "" when secrets are stored in plain text.
+func openStorage(dbPath string) (*storage.SQLiteStorage, string, error) {
+	master, source, err := storage.MasterKeyFromEnv("CCNEXUS")
+	if err != nil {
+		return nil, "", err
+	}
+	sqliteStorage, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := sqliteStorage.UnlockSecrets(master); err != nil {
+		sqliteStorage.Close()
+		return nil, "", err
+	}
+	return sqliteStorage, source, nil
+}
+
+// runRotateKey wraps the data key with the master key from the
+// CCNEXUS_NEW_MASTER_* variables, or encrypts a plain-text database with it.
+// The server should be stopped first.
+func runRotateKey(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	dataKey := fs.Bool("data-key", false, "Also re-encrypt all secrets under a new data key")
+	fs.Parse(args)
+
+	newKey, newSource, err := storage.MasterKeyFromEnv("CCNEXUS_NEW")
+	if err != nil {
+		logger.Error("Invalid new master key: %v", err)
+		return 1
+	}
+	if newKey == nil {
+		logger.Error("Set the new master key in CCNEXUS_NEW_MASTER_KEY, CCNEXUS_NEW_MASTER_KEY_FILE, CCNEXUS_NEW_MASTER_PASSPHRASE or CCNEXUS_NEW_MASTER_PASSPHRASE_FILE")
+		return 1
+	}
+
+	sqliteStorage, source, err := openStorage(resolveDBPath(resolveDataDir()))
+	if err != nil {
+		logger.Error("Failed to open SQLite storage: %v", err)
+		return 1
+	}
+	defer sqliteStorage.Close()
+
+	if source == "" {
+		err = sqliteStorage.UnlockSecrets(newKey)
+	} else {
+		err = sqliteStorage.RotateMasterKey(newKey, *dataKey)
+	}
+	if err != nil {
+		logger.Error("Failed to rotate the master key: %v", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Secrets are now protected by the master key from %s\n", newSource)
+	return 0
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func TestRotateKeyEncryptsAndRotates(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CCNEXUS_DB_PATH", filepath.Join(dir, "ccnexus.db"))
+	passphraseFile := filepath.Join(dir, "passphrase")
+	os.WriteFile(passphraseFile, []byte("correct horse battery staple\n"), 0600)
+
+	store, source, err := openStorage(os.Getenv("CCNEXUS_DB_PATH"))
+	if err != nil || source != "" {
+		t.Fatalf("expected a plain-text database without a master key, got %q, %v", source, err)
+	}
+	store.SaveEndpoint(&storage.Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+	store.Close()
+
+	// Without a current key, rotate-key encrypts the database
+	t.Setenv("CCNEXUS_NEW_MASTER_PASSPHRASE_FILE", passphraseFile)
+	var out bytes.Buffer
+	if code := runRotateKey(nil, &out); code != 0 {
+		t.Fatalf("expected rotate-key to encrypt the database, got exit %d", code)
+	}
+	if _, _, err := openStorage(os.Getenv("CCNEXUS_DB_PATH")); !errors.Is(err, storage.ErrSecretsLocked) {
+		t.Fatalf("expected the database to need the master key, got %v", err)
+	}
+
+	newKey, _ := storage.GenerateMasterKey()
+	t.Setenv("CCNEXUS_MASTER_PASSPHRASE_FILE", passphraseFile)
+	t.Setenv("CCNEXUS_NEW_MASTER_PASSPHRASE_FILE", "")
+	t.Setenv("CCNEXUS_NEW_MASTER_KEY", newKey)
+	if code := runRotateKey([]string{"-data-key"}, &out); code != 0 {
+		t.Fatalf("expected rotate-key to succeed, got exit %d", code)
+	}
+
+	t.Setenv("CCNEXUS_MASTER_PASSPHRASE_FILE", "")
+	t.Setenv("CCNEXUS_MASTER_KEY", newKey)
+	store, source, err = openStorage(os.Getenv("CCNEXUS_DB_PATH"))
+	if err != nil || source != "CCNEXUS_MASTER_KEY" {
+		t.Fatalf("expected the new master key to unlock the database, got %q, %v", source, err)
+	}
+	defer store.Close()
+	if endpoints, err := store.GetEndpoints(); err != nil || endpoints[0].APIKey != "sk-main" {
+		t.Fatalf("expected the API key to survive rotation, got %+v, %v", endpoints, err)
+	}
+}
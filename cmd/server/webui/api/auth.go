@@ -0,0 +1,359 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+// Role is the access level of a web UI user. Each role may do everything the
+// roles below it may.
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // Reads endpoints, stats, settings and events
+	RoleOperator Role = "operator" // Also switches, toggles, tests and reorders endpoints
+	RoleAdmin    Role = "admin"    // Also edits endpoints, credentials, keys, users and settings
+)
+
+const (
+	sessionCookie = "ccnexus_session"
+	sessionTTL    = 24 * time.Hour
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Allows reports whether the role includes another
+func (r Role) Allows(other Role) bool {
+	return r.rank() >= other.rank() && r.rank() > 0
+}
+
+// principal is who made an API request
+type principal struct {
+	username string
+	role     Role
+}
+
+type principalContextKey struct{}
+
+func principalFromRequest(r *http.Request) *principal {
+	who, _ := r.Context().Value(principalContextKey{}).(*principal)
+	return who
+}
+
+// sessionToken returns the login session token of a request, from the
+// session cookie or an Authorization: Bearer header
+func sessionToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// isConfigAdmin reports whether the credentials are those of the Basic Auth
+// account in the configuration, which is always an admin
+func (h *Handler) isConfigAdmin(username, password string) bool {
+	return h.auth.Enabled &&
+		subtle.ConstantTimeCompare([]byte(h.auth.Username), []byte(username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(h.auth.Password), []byte(password)) == 1
+}
+
+// authRequired reports whether API requests need credentials, which is the
+// case once Basic Auth is enabled or any web UI user exists
+func (h *Handler) authRequired() (bool, error) {
+	if h.auth.Enabled {
+		return true, nil
+	}
+	return h.storage.HasWebUsers()
+}
+
+// authenticate returns who made a request, or nil when the credentials are
+// missing or wrong
+func (h *Handler) authenticate(r *http.Request) (*principal, error) {
+	required, err := h.authRequired()
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return &principal{username: "anonymous", role: RoleAdmin}, nil
+	}
+
+	if token := sessionToken(r); token != "" {
+		username, err := h.storage.GetWebSession(token)
+		if err != nil || username == "" {
+			return nil, err
+		}
+		return h.principalFor(username)
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	if h.isConfigAdmin(username, password) {
+		return &principal{username: username, role: RoleAdmin}, nil
+	}
+	user, err := h.storage.VerifyWebUser(username, password)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &principal{username: user.Username, role: Role(user.Role)}, nil
+}
+
+// principalFor returns the current role of a logged-in user, or nil if the
+// account no longer exists
+func (h *Handler) principalFor(username string) (*principal, error) {
+	if h.auth.Enabled && username == h.auth.Username {
+		return &principal{username: username, role: RoleAdmin}, nil
+	}
+	user, err := h.storage.GetWebUser(username)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &principal{username: user.Username, role: Role(user.Role)}, nil
+}
+
+// writeUnauthorized asks for credentials. Requests of the web UI, which sets
+// X-Requested-With, get no Basic Auth challenge so the browser shows the login
+// form instead of its own prompt.
+func writeUnauthorized(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Requested-With") == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="ccNexus"`)
+	}
+	WriteError(w, http.StatusUnauthorized, "Authentication required")
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// route serves an API route to callers whose role allows read for GET
+// requests and write for all others. Writes are recorded in the audit log.
+func (h *Handler) route(w http.ResponseWriter, r *http.Request, read, write Role, next http.HandlerFunc) {
+	who, err := h.authenticate(r)
+	if err != nil {
+		logger.Error("Failed to authenticate API request: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to authenticate")
+		return
+	}
+	if who == nil {
+		writeUnauthorized(w, r)
+		return
+	}
+
+	need := read
+	if !isReadMethod(r.Method) {
+		need = write
+	}
+	if !who.role.Allows(need) {
+		if !isReadMethod(r.Method) {
+			h.audit(r, who, http.StatusForbidden)
+		}
+		WriteError(w, http.StatusForbidden, "This requires the "+string(need)+" role")
+		return
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), principalContextKey{}, who))
+	if isReadMethod(r.Method) {
+		next(w, r)
+		return
+	}
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next(rec, r)
+	h.audit(r, who, rec.status)
+}
+
+// statusRecorder remembers the status code a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// audit records an API write. Request bodies are left out since they may
+// carry secrets.
+func (h *Handler) audit(r *http.Request, who *principal, status int) {
+	entry := &storage.AuditEntry{
+		Time:       time.Now(),
+		Username:   who.username,
+		Role:       string(who.role),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if err := h.storage.RecordAudit(entry); err != nil {
+		logger.Warn("Failed to record audit entry for %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+// handleLogin starts a login session for the web UI. The token is set as a
+// cookie and also returned for use as a Bearer token.
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var who *principal
+	if h.isConfigAdmin(req.Username, req.Password) {
+		who = &principal{username: req.Username, role: RoleAdmin}
+	} else if !(h.auth.Enabled && req.Username == h.auth.Username) {
+		user, err := h.storage.VerifyWebUser(req.Username, req.Password)
+		if err != nil {
+			logger.Error("Failed to verify user: %v", err)
+			WriteError(w, http.StatusInternalServerError, "Failed to log in")
+			return
+		}
+		if user != nil {
+			who = &principal{username: user.Username, role: Role(user.Role)}
+		}
+	}
+	if who == nil {
+		h.audit(r, &principal{username: req.Username}, http.StatusUnauthorized)
+		WriteError(w, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+
+	token, expiresAt, err := h.storage.CreateWebSession(who.username, sessionTTL)
+	if err != nil {
+		logger.Error("Failed to create session: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	h.audit(r, who, http.StatusOK)
+	logger.Info("Web UI login: %s (%s)", who.username, who.role)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	WriteSuccess(w, map[string]interface{}{
+		"token":     token,
+		"username":  who.username,
+		"role":      who.role,
+		"expiresAt": expiresAt,
+	})
+}
+
+// handleLogout ends the login session of the request, if any
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if token := sessionToken(r); token != "" {
+		if err := h.storage.DeleteWebSession(token); err != nil {
+			logger.Warn("Failed to delete session: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Logged out",
+	})
+}
+
+// handleMe returns who is logged in
+func (h *Handler) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	required, err := h.authRequired()
+	if err != nil {
+		logger.Error("Failed to check users: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to check users")
+		return
+	}
+	who := principalFromRequest(r)
+	WriteSuccess(w, map[string]interface{}{
+		"username":     who.username,
+		"role":         who.role,
+		"authRequired": required,
+	})
+}
+
+// handleChangePassword lets a web UI user change its own password
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	who := principalFromRequest(r)
+	user, err := h.storage.VerifyWebUser(who.username, req.CurrentPassword)
+	if err != nil {
+		logger.Error("Failed to verify user: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+	if user == nil {
+		WriteError(w, http.StatusForbidden, "Current password is wrong, or the account is not a web UI user")
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.storage.SetWebUserPassword(user.Username, req.NewPassword); err != nil {
+		logger.Error("Failed to set password: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+	logger.Info("Password changed: %s", user.Username)
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Password changed, please log in again",
+	})
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/proxy"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func newAuthTestHandler(t *testing.T, basicAuth bool) (*Handler, *storage.SQLiteStorage) {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "ccnexus.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	cfg := config.DefaultConfig()
+	cfg.BasicAuthEnabled = basicAuth
+	cfg.BasicAuthPassword = "secret"
+	p := proxy.New(cfg, storage.NewStatsStorageAdapter(store), store, "test")
+	return NewHandler(cfg, p, store), store
+}
+
+// apiCall sends a request as user (Basic Auth), as a session (Bearer token)
+// or anonymously when both are empty
+func apiCall(h *Handler, method, path, body, user, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if user != "" {
+		r.SetBasicAuth(user, "password-"+user)
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRolesGuardRoutes(t *testing.T) {
+	h, store := newAuthTestHandler(t, true)
+	for _, u := range []struct{ name, role string }{{"vera", "viewer"}, {"otto", "operator"}, {"ada", "admin"}} {
+		if err := store.CreateWebUser(&storage.WebUser{Username: u.name, Role: u.role}, "password-"+u.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.SaveEndpoint(&storage.Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+
+	for _, tc := range []struct {
+		method, path, body, user string
+		want                     int
+	}{
+		{"GET", "/api/endpoints", "", "", http.StatusUnauthorized},
+		{"GET", "/api/endpoints", "", "vera", http.StatusOK},
+		{"GET", "/api/stats/daily", "", "vera", http.StatusOK},
+		{"POST", "/api/endpoints/Main/toggle", `{"enabled":false}`, "vera", http.StatusForbidden},
+		{"POST", "/api/endpoints/Main/toggle", `{"enabled":true}`, "otto", http.StatusOK},
+		{"POST", "/api/endpoints/switch", `{"name":"Main"}`, "otto", http.StatusOK},
+		{"DELETE", "/api/endpoints/Main", "", "otto", http.StatusForbidden},
+		{"GET", "/api/endpoints/Main/credentials", "", "otto", http.StatusForbidden},
+		{"GET", "/api/client-keys", "", "otto", http.StatusForbidden},
+		{"PUT", "/api/config/log-level", `{"logLevel":2}`, "otto", http.StatusForbidden},
+		{"PUT", "/api/config/log-level", `{"logLevel":2}`, "ada", http.StatusOK},
+		{"GET", "/api/client-keys", "", "ada", http.StatusOK},
+		{"GET", "/api/users", "", "admin", http.StatusUnauthorized}, // wrong config password
+	} {
+		if rec := apiCall(h, tc.method, tc.path, tc.body, tc.user, ""); rec.Code != tc.want {
+			t.Errorf("%s %s as %q: expected %d, got %d: %s", tc.method, tc.path, tc.user, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/api/users", nil)
+	r.SetBasicAuth("admin", "secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"username":"vera"`) || strings.Contains(rec.Body.String(), "password") {
+		t.Fatalf("expected the Basic Auth account to list users without hashes, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Writes are audited, including refused ones, without request bodies
+	rec = apiCall(h, "GET", "/api/audit", "", "ada", "")
+	var audit struct {
+		Data struct {
+			Entries []storage.AuditEntry `json:"entries"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &audit)
+	if len(audit.Data.Entries) != 6 {
+		t.Fatalf("expected 6 audited writes, got %s", rec.Body.String())
+	}
+	latest := audit.Data.Entries[0]
+	if latest.Username != "ada" || latest.Role != "admin" || latest.Path != "/api/config/log-level" || latest.Status != http.StatusOK {
+		t.Fatalf("unexpected latest audit entry: %+v", latest)
+	}
+	if e := audit.Data.Entries[len(audit.Data.Entries)-1]; e.Username != "vera" || e.Status != http.StatusForbidden {
+		t.Fatalf("expected the refused toggle first, got %+v", e)
+	}
+}
+
+func TestSessionLogin(t *testing.T) {
+	h, store := newAuthTestHandler(t, false)
+
+	// Without Basic Auth and users the API stays open, and the first user
+	// must be an admin so it does not lock everyone out
+	if rec := apiCall(h, "POST", "/api/users", `{"username":"vera","password":"password-vera","role":"viewer"}`, "", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a lone viewer to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := apiCall(h, "POST", "/api/users", `{"username":"ada","password":"password-ada","role":"admin"}`, "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first admin to be created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := apiCall(h, "GET", "/api/endpoints", "", "", "")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected a login to be required once a user exists, got %d", rec.Code)
+	}
+
+	rec = apiCall(h, "POST", "/api/auth/login", `{"username":"ada","password":"wrong"}`, "", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong password to fail, got %d", rec.Code)
+	}
+	rec = apiCall(h, "POST", "/api/auth/login", `{"username":"ada","password":"password-ada"}`, "", "")
+	var login struct {
+		Data struct {
+			Token string `json:"token"`
+			Role  string `json:"role"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &login)
+	if rec.Code != http.StatusOK || login.Data.Token == "" || login.Data.Role != "admin" || len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("expected a session, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The session cookie works like the Bearer token
+	r := httptest.NewRequest("GET", "/api/auth/me", nil)
+	r.AddCookie(rec.Result().Cookies()[0])
+	me := httptest.NewRecorder()
+	h.ServeHTTP(me, r)
+	if me.Code != http.StatusOK || !strings.Contains(me.Body.String(), `"username":"ada"`) {
+		t.Fatalf("expected the cookie to authenticate, got %d: %s", me.Code, me.Body.String())
+	}
+
+	token := login.Data.Token
+	if rec := apiCall(h, "DELETE", "/api/users/ada", "", "", token); rec.Code != http.StatusOK {
+		t.Fatalf("expected the last user to be deletable, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if username, _ := store.GetWebSession(token); username != "" {
+		t.Fatal("expected deleting a user to end its sessions")
+	}
+
+	store.CreateWebUser(&storage.WebUser{Username: "ada", Role: "admin"}, "password-ada")
+	rec = apiCall(h, "POST", "/api/auth/login", `{"username":"ada","password":"password-ada"}`, "", "")
+	json.Unmarshal(rec.Body.Bytes(), &login)
+	if rec := apiCall(h, "PUT", "/api/users/ada", `{"role":"viewer"}`, "", login.Data.Token); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected demoting the last admin to be refused, got %d", rec.Code)
+	}
+	if rec := apiCall(h, "POST", "/api/auth/logout", "", "", login.Data.Token); rec.Code != http.StatusOK {
+		t.Fatalf("expected logout to succeed, got %d", rec.Code)
+	}
+	if rec := apiCall(h, "GET", "/api/auth/me", "", "", login.Data.Token); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the session to end on logout, got %d", rec.Code)
+	}
+}
+
+func TestViewerCannotReadEndpointSecrets(t *testing.T) {
+	h, store := newAuthTestHandler(t, false)
+	for _, u := range []struct{ name, role string }{{"vera", "viewer"}, {"ada", "admin"}} {
+		if err := store.CreateWebUser(&storage.WebUser{Username: u.name, Role: u.role}, "password-"+u.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.SaveEndpoint(&storage.Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main-secret", AuthMode: "api_key",
+		Enabled: true, Transformer: "claude", Options: json.RawMessage(secretOptions)})
+	secrets := append([]string{"sk-main-secret"}, optionSecrets...)
+
+	rec := apiCall(h, "POST", "/api/auth/login", `{"username":"vera","password":"password-vera"}`, "", "")
+	var login struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &login)
+	if login.Data.Token == "" {
+		t.Fatalf("viewer login failed: %s", rec.Body.String())
+	}
+	for _, path := range []string{"/api/endpoints", "/api/endpoints/Main"} {
+		rec := apiCall(h, "GET", path, "", "", login.Data.Token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s as viewer: expected 200, got %d", path, rec.Code)
+		}
+		for _, secret := range secrets {
+			if strings.Contains(rec.Body.String(), secret) {
+				t.Fatalf("GET %s leaks %q to a viewer: %s", path, secret, rec.Body.String())
+			}
+		}
+	}
+
+	// Admins may edit the secrets and see them in full
+	var read struct {
+		Data storage.Endpoint `json:"data"`
+	}
+	json.Unmarshal(apiCall(h, "GET", "/api/endpoints/Main", "", "ada", "").Body.Bytes(), &read)
+	if read.Data.APIKey != "sk-main-secret" {
+		t.Fatalf("expected an admin to read the API key, got %q", read.Data.APIKey)
+	}
+	for _, secret := range optionSecrets {
+		if !strings.Contains(string(read.Data.Options), secret) {
+			t.Fatalf("expected an admin to read option %q, got %s", secret, read.Data.Options)
+		}
+	}
+
+	// Saving the endpoint as a viewer read it keeps the stored secrets
+	read.Data.APIKey = maskAPIKey(read.Data.APIKey)
+	read.Data.Options = redactEndpointOptions(read.Data.Options)
+	body, _ := json.Marshal(read.Data)
+	if rec := apiCall(h, "PUT", "/api/endpoints/Main", string(body), "ada", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	endpoints, _ := store.GetEndpoints()
+	if endpoints[0].APIKey != "sk-main-secret" {
+		t.Fatalf("masked API key was written back: %s", endpoints[0].APIKey)
+	}
+	for _, secret := range optionSecrets {
+		if !strings.Contains(string(endpoints[0].Options), secret) {
+			t.Fatalf("masked option %q was written back: %s", secret, endpoints[0].Options)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+// clientKeyRequest is the body of creating or updating a client key
+type clientKeyRequest struct {
+	Name              string     `json:"name"`
+	AllowedEndpoints  []string   `json:"allowedEndpoints"`
+	AllowedGroups     []string   `json:"allowedGroups"`
+	AllowedModels     []string   `json:"allowedModels"`
+	RequestsPerMinute int        `json:"requestsPerMinute"`
+	DailyTokenQuota   int64      `json:"dailyTokenQuota"`
+	ExpiresAt         *time.Time `json:"expiresAt"`
+	Remark            string     `json:"remark"`
+}
+
+// handleClientKeys handles GET (list) and POST (create) for client keys
+func (h *Handler) handleClientKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listClientKeys(w, r)
+	case http.MethodPost:
+		h.createClientKey(w, r)
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleClientKeyByID handles GET, PUT and DELETE (revoke) for a client key
+// and POST /rotate
+func (h *Handler) handleClientKeyByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/client-keys/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		WriteError(w, http.StatusBadRequest, "Invalid client key id")
+		return
+	}
+
+	if len(parts) > 1 {
+		if parts[1] != "rotate" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		h.rotateClientKey(w, id)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		key, err := h.storage.GetClientKeyByID(id)
+		if err != nil {
+			logger.Error("Failed to get client key: %v", err)
+			WriteError(w, http.StatusInternalServerError, "Failed to get client key")
+			return
+		}
+		if key == nil {
+			WriteError(w, http.StatusNotFound, "Client key not found")
+			return
+		}
+		WriteSuccess(w, key)
+	case http.MethodPut:
+		h.updateClientKey(w, r, id)
+	case http.MethodDelete:
+		h.revokeClientKey(w, id)
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *Handler) listClientKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.storage.GetClientKeys()
+	if err != nil {
+		logger.Error("Failed to get client keys: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get client keys")
+		return
+	}
+	WriteSuccess(w, map[string]interface{}{
+		"keys": keys,
+	})
+}
+
+// decodeClientKeyRequest reads and checks a client key body
+func (h *Handler) decodeClientKeyRequest(r *http.Request) (*storage.ClientKey, error) {
+	var req clientKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.New("Invalid request body")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, errors.New("Name is required")
+	}
+	if req.RequestsPerMinute < 0 || req.DailyTokenQuota < 0 {
+		return nil, errors.New("Limits cannot be negative")
+	}
+	if len(req.AllowedEndpoints) > 0 || len(req.AllowedGroups) > 0 {
+		endpoints, err := h.storage.GetEndpoints()
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool, len(endpoints))
+		groups := make(map[string]bool)
+		for _, ep := range endpoints {
+			known[ep.Name] = true
+			if options, _ := config.ParseEndpointOptions(string(ep.Options)); options.GroupName() != "" {
+				groups[options.GroupName()] = true
+			}
+		}
+		for _, name := range req.AllowedEndpoints {
+			if !known[name] {
+				return nil, fmt.Errorf("Endpoint %s not found", name)
+			}
+		}
+		for _, group := range req.AllowedGroups {
+			if !groups[group] {
+				return nil, fmt.Errorf("No endpoint in group %s", group)
+			}
+		}
+	}
+
+	return &storage.ClientKey{
+		Name:              req.Name,
+		AllowedEndpoints:  req.AllowedEndpoints,
+		AllowedGroups:     req.AllowedGroups,
+		AllowedModels:     req.AllowedModels,
+		RequestsPerMinute: req.RequestsPerMinute,
+		DailyTokenQuota:   req.DailyTokenQuota,
+		ExpiresAt:         req.ExpiresAt,
+		Remark:            req.Remark,
+	}, nil
+}
+
+// createClientKey issues a client key. The secret is only returned here.
+func (h *Handler) createClientKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.decodeClientKeyRequest(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, err := h.storage.CreateClientKey(key)
+	if err != nil {
+		logger.Error("Failed to create client key: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to create client key")
+		return
+	}
+	logger.Info("Client key created: %s (%s)", key.Name, key.Prefix)
+	h.writeClientKeySecret(w, key.ID, secret)
+}
+
+func (h *Handler) updateClientKey(w http.ResponseWriter, r *http.Request, id int64) {
+	key, err := h.decodeClientKeyRequest(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key.ID = id
+
+	if err := h.storage.UpdateClientKey(key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			WriteError(w, http.StatusNotFound, "Client key not found")
+			return
+		}
+		logger.Error("Failed to update client key: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to update client key")
+		return
+	}
+	logger.Info("Client key updated: %s", key.Name)
+
+	updated, err := h.storage.GetClientKeyByID(id)
+	if err != nil {
+		logger.Error("Failed to get client key: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get client key")
+		return
+	}
+	WriteSuccess(w, updated)
+}
+
+// rotateClientKey replaces the secret of a client key, keeping its settings
+// and usage. The new secret is only returned here.
+func (h *Handler) rotateClientKey(w http.ResponseWriter, id int64) {
+	secret, err := h.storage.RotateClientKey(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			WriteError(w, http.StatusNotFound, "Client key not found or revoked")
+			return
+		}
+		logger.Error("Failed to rotate client key: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to rotate client key")
+		return
+	}
+	logger.Info("Client key rotated: id=%d", id)
+	h.writeClientKeySecret(w, id, secret)
+}
+
+func (h *Handler) revokeClientKey(w http.ResponseWriter, id int64) {
+	if err := h.storage.RevokeClientKey(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			WriteError(w, http.StatusNotFound, "Client key not found or already revoked")
+			return
+		}
+		logger.Error("Failed to revoke client key: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to revoke client key")
+		return
+	}
+	logger.Info("Client key revoked: id=%d", id)
+	WriteSuccess(w, map[string]interface{}{
+		"message": "Client key revoked successfully",
+	})
+}
+
+func (h *Handler) writeClientKeySecret(w http.ResponseWriter, id int64, secret string) {
+	key, err := h.storage.GetClientKeyByID(id)
+	if err != nil || key == nil {
+		logger.Error("Failed to get client key: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get client key")
+		return
+	}
+	WriteSuccess(w, map[string]interface{}{
+		"key":    key,
+		"secret": secret,
+	})
+}
+
+// handleStatsKeys returns usage per client key and endpoint. start and end
+// are inclusive dates and default to today.
+func (h *Handler) handleStatsKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	startDate, endDate := r.URL.Query().Get("start"), r.URL.Query().Get("end")
+	if startDate == "" {
+		startDate = today
+	}
+	if endDate == "" {
+		endDate = today
+	}
+	for _, date := range []string{startDate, endDate} {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			WriteError(w, http.StatusBadRequest, "Dates must be YYYY-MM-DD")
+			return
+		}
+	}
+
+	usage, err := h.storage.GetClientKeyUsage(startDate, endDate)
+	if err != nil {
+		logger.Error("Failed to get client key usage: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get client key usage")
+		return
+	}
+	keys, err := h.storage.GetClientKeys()
+	if err != nil {
+		logger.Error("Failed to get client keys: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get client keys")
+		return
+	}
+
+	type keyStats struct {
+		ID           int64                    `json:"id"`
+		Name         string                   `json:"name"`
+		Requests     int                      `json:"requests"`
+		Errors       int                      `json:"errors"`
+		InputTokens  int64                    `json:"inputTokens"`
+		OutputTokens int64                    `json:"outputTokens"`
+		Endpoints    []storage.ClientKeyUsage `json:"endpoints"`
+	}
+	stats := make([]*keyStats, 0, len(keys))
+	byID := make(map[int64]*keyStats, len(keys))
+	for _, key := range keys {
+		entry := &keyStats{ID: key.ID, Name: key.Name, Endpoints: []storage.ClientKeyUsage{}}
+		stats = append(stats, entry)
+		byID[key.ID] = entry
+	}
+	for _, u := range usage {
+		entry := byID[u.KeyID]
+		if entry == nil {
+			continue
+		}
+		entry.Requests += u.Requests
+		entry.Errors += u.Errors
+		entry.InputTokens += u.InputTokens
+		entry.OutputTokens += u.OutputTokens
+		entry.Endpoints = append(entry.Endpoints, u)
+	}
+
+	WriteSuccess(w, map[string]interface{}{
+		"start": startDate,
+		"end":   endDate,
+		"keys":  stats,
+	})
+}
@@ -43,6 +43,19 @@ func (h *Handler) handleBasicAuthConfig(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		if !req.Enabled {
+			users, err := h.storage.GetWebUsers()
+			if err != nil {
+				logger.Error("Failed to get users: %v", err)
+				WriteError(w, http.StatusInternalServerError, "Failed to get users")
+				return
+			}
+			if len(users) > 0 && !hasAdmin(users) {
+				WriteError(w, http.StatusBadRequest, "Create an admin user before disabling Basic Auth")
+				return
+			}
+		}
+
 		h.config.BasicAuthEnabled = req.Enabled
 		if req.Username != "" {
 			h.config.BasicAuthUsername = req.Username
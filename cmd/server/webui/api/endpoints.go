@@ -71,9 +71,8 @@ func (h *Handler) listEndpoints(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Mask API keys
 	for i := range endpoints {
-		endpoints[i].APIKey = maskAPIKey(endpoints[i].APIKey)
+		redactEndpointFor(r, &endpoints[i])
 	}
 
 	tokenPools, err := h.storage.GetAllTokenPoolStats()
@@ -99,7 +98,7 @@ func (h *Handler) getEndpoint(w http.ResponseWriter, r *http.Request, name strin
 
 	for _, ep := range endpoints {
 		if ep.Name == name {
-			ep.APIKey = maskAPIKey(ep.APIKey)
+			redactEndpointFor(r, &ep)
 			WriteSuccess(w, ep)
 			return
 		}
@@ -111,15 +110,16 @@ func (h *Handler) getEndpoint(w http.ResponseWriter, r *http.Request, name strin
 // createEndpoint creates a new endpoint
 func (h *Handler) createEndpoint(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name        string `json:"name"`
-		APIUrl      string `json:"apiUrl"`
-		APIKey      string `json:"apiKey"`
-		AuthMode    string `json:"authMode"`
-		Enabled     bool   `json:"enabled"`
-		Transformer string `json:"transformer"`
-		Model       string `json:"model"`
-		Remark      string `json:"remark"`
-		CloneFrom   string `json:"cloneFrom"` // Clone from existing endpoint name
+		Name        string          `json:"name"`
+		APIUrl      string          `json:"apiUrl"`
+		APIKey      string          `json:"apiKey"`
+		AuthMode    string          `json:"authMode"`
+		Enabled     bool            `json:"enabled"`
+		Transformer string          `json:"transformer"`
+		Model       string          `json:"model"`
+		Remark      string          `json:"remark"`
+		Options     json.RawMessage `json:"options"`
+		CloneFrom   string          `json:"cloneFrom"` // Clone from existing endpoint name
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -127,13 +127,16 @@ func (h *Handler) createEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If cloning, get API key from source endpoint
-	if req.CloneFrom != "" && req.APIKey == "" {
+	// If cloning, get API key and masked option secrets from source endpoint
+	if req.CloneFrom != "" {
 		endpoints, err := h.storage.GetEndpoints()
 		if err == nil {
 			for _, ep := range endpoints {
 				if ep.Name == req.CloneFrom {
-					req.APIKey = ep.APIKey
+					if req.APIKey == "" {
+						req.APIKey = ep.APIKey
+					}
+					req.Options = restoreEndpointOptionSecrets(req.Options, ep.Options)
 					break
 				}
 			}
@@ -141,6 +144,8 @@ func (h *Handler) createEndpoint(w http.ResponseWriter, r *http.Request) {
 	}
 
 	authMode := config.NormalizeAuthMode(req.AuthMode)
+	// Invalid options are reported below; they are only needed here for URL defaults
+	parsedOptions, _ := config.ParseEndpointOptions(string(req.Options))
 	normalizedEndpoint := config.Endpoint{
 		APIUrl:      normalizeAPIUrl(req.APIUrl),
 		APIKey:      req.APIKey,
@@ -148,6 +153,7 @@ func (h *Handler) createEndpoint(w http.ResponseWriter, r *http.Request) {
 		Transformer: req.Transformer,
 		Model:       req.Model,
 		Remark:      req.Remark,
+		Options:     parsedOptions,
 	}
 	if normalizedEndpoint.Transformer == "" {
 		normalizedEndpoint.Transformer = "claude"
@@ -170,6 +176,11 @@ func (h *Handler) createEndpoint(w http.ResponseWriter, r *http.Request) {
 	if config.IsTokenPoolAuthMode(authMode) {
 		req.APIKey = ""
 	}
+	options, err := normalizeEndpointOptions(req.Options)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Get current endpoints to determine sort order
 	endpoints, err := h.storage.GetEndpoints()
@@ -198,6 +209,7 @@ func (h *Handler) createEndpoint(w http.ResponseWriter, r *http.Request) {
 		Model:       req.Model,
 		Remark:      req.Remark,
 		SortOrder:   len(endpoints),
+		Options:     options,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -213,21 +225,22 @@ func (h *Handler) createEndpoint(w http.ResponseWriter, r *http.Request) {
 		logger.Error("Failed to reload config: %v", err)
 	}
 
-	endpoint.APIKey = maskAPIKey(endpoint.APIKey)
+	redactEndpointFor(r, endpoint)
 	WriteSuccess(w, endpoint)
 }
 
 // updateEndpoint updates an existing endpoint
 func (h *Handler) updateEndpoint(w http.ResponseWriter, r *http.Request, name string) {
 	var req struct {
-		Name        string `json:"name"`
-		APIUrl      string `json:"apiUrl"`
-		APIKey      string `json:"apiKey"`
-		AuthMode    string `json:"authMode"`
-		Enabled     bool   `json:"enabled"`
-		Transformer string `json:"transformer"`
-		Model       string `json:"model"`
-		Remark      string `json:"remark"`
+		Name        string          `json:"name"`
+		APIUrl      string          `json:"apiUrl"`
+		APIKey      string          `json:"apiKey"`
+		AuthMode    string          `json:"authMode"`
+		Enabled     bool            `json:"enabled"`
+		Transformer string          `json:"transformer"`
+		Model       string          `json:"model"`
+		Remark      string          `json:"remark"`
+		Options     json.RawMessage `json:"options"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -263,7 +276,7 @@ func (h *Handler) updateEndpoint(w http.ResponseWriter, r *http.Request, name st
 	if req.APIUrl != "" {
 		existing.APIUrl = normalizeAPIUrl(req.APIUrl)
 	}
-	if req.APIKey != "" {
+	if req.APIKey != "" && req.APIKey != maskAPIKey(existing.APIKey) {
 		existing.APIKey = req.APIKey
 	}
 	if req.AuthMode != "" {
@@ -302,6 +315,14 @@ func (h *Handler) updateEndpoint(w http.ResponseWriter, r *http.Request, name st
 		existing.Model = req.Model
 	}
 	existing.Remark = req.Remark
+	if req.Options != nil {
+		options, err := normalizeEndpointOptions(restoreEndpointOptionSecrets(req.Options, existing.Options))
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		existing.Options = options
+	}
 	existing.UpdatedAt = time.Now()
 
 	if err := h.storage.UpdateEndpoint(existing); err != nil {
@@ -315,7 +336,7 @@ func (h *Handler) updateEndpoint(w http.ResponseWriter, r *http.Request, name st
 		logger.Error("Failed to reload config: %v", err)
 	}
 
-	existing.APIKey = maskAPIKey(existing.APIKey)
+	redactEndpointFor(r, existing)
 	WriteSuccess(w, existing)
 }
 
@@ -525,6 +546,72 @@ func (h *Handler) reloadConfig() error {
 	return h.proxy.UpdateConfig(cfg)
 }
 
+// normalizeEndpointOptions validates endpoint options and returns their canonical JSON form
+func normalizeEndpointOptions(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	options, err := config.ParseEndpointOptions(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	encoded := config.EncodeEndpointOptions(options)
+	if encoded == "" {
+		return nil, nil
+	}
+	return json.RawMessage(encoded), nil
+}
+
+// redactEndpointFor masks the API key and option credentials of an endpoint
+// unless the caller is an admin, who may edit and therefore read them
+func redactEndpointFor(r *http.Request, ep *storage.Endpoint) {
+	if who := principalFromRequest(r); who != nil && who.role.Allows(RoleAdmin) {
+		return
+	}
+	ep.APIKey = maskAPIKey(ep.APIKey)
+	ep.Options = redactEndpointOptions(ep.Options)
+}
+
+// redactEndpointOptions masks the credentials in endpoint options the way
+// maskAPIKey masks the API key
+func redactEndpointOptions(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var options config.EndpointOptions
+	if err := json.Unmarshal(raw, &options); err != nil {
+		return nil
+	}
+	for _, field := range options.SecretFields() {
+		*field = maskAPIKey(*field)
+	}
+	data, err := json.Marshal(&options)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// restoreEndpointOptionSecrets keeps the stored credentials for option values
+// that come back masked, so clients can send back the options they read
+func restoreEndpointOptionSecrets(raw, stored json.RawMessage) json.RawMessage {
+	var options, current config.EndpointOptions
+	if len(raw) == 0 || len(stored) == 0 || json.Unmarshal(raw, &options) != nil || json.Unmarshal(stored, &current) != nil {
+		return raw
+	}
+	currentFields := current.SecretFields()
+	for path, field := range options.SecretFields() {
+		if secret, ok := currentFields[path]; ok && *field != "" && *field == maskAPIKey(*secret) {
+			*field = *secret
+		}
+	}
+	data, err := json.Marshal(&options)
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
 // maskAPIKey masks an API key, showing only the last 4 characters
 func maskAPIKey(key string) string {
 	if key == "" {
@@ -539,4 +626,4 @@ func maskAPIKey(key string) string {
 // normalizeAPIUrl ensures the API URL has the correct format
 func normalizeAPIUrl(apiUrl string) string {
 	return strings.TrimSuffix(apiUrl, "/")
-}
\ No newline at end of file
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const secretOptions = `{"bedrock":{"region":"us-east-1","accessKeyId":"AKIDEXAMPLE","secretAccessKey":"bedrock-secret-key","sessionToken":"bedrock-session-token"},` +
+	`"vertex":{"location":"us-east5","serviceAccountKey":"{\"private_key\":\"vertex-private-key\"}"},` +
+	`"azure":{"tenantId":"tenant","clientId":"client","clientSecret":"azure-client-secret"}}`
+
+var optionSecrets = []string{"bedrock-secret-key", "bedrock-session-token", "vertex-private-key", "azure-client-secret"}
+
+func TestRedactEndpointOptionsMasksSecrets(t *testing.T) {
+	redacted := string(redactEndpointOptions(json.RawMessage(secretOptions)))
+	for _, secret := range optionSecrets {
+		if strings.Contains(redacted, secret) {
+			t.Fatalf("secret %q not masked: %s", secret, redacted)
+		}
+	}
+	if !strings.Contains(redacted, `"region":"us-east-1"`) {
+		t.Fatalf("non-secret options must be kept: %s", redacted)
+	}
+}
+
+func TestRestoreEndpointOptionSecretsKeepsStoredValues(t *testing.T) {
+	redacted := redactEndpointOptions(json.RawMessage(secretOptions))
+	restored := string(restoreEndpointOptionSecrets(redacted, json.RawMessage(secretOptions)))
+	for _, secret := range optionSecrets {
+		if !strings.Contains(restored, secret) {
+			t.Fatalf("masked secret was not restored to %q: %s", secret, restored)
+		}
+	}
+
+	changed := strings.Replace(string(redacted), `"****-key"`, `"new-secret"`, 1)
+	if restored := string(restoreEndpointOptionSecrets(json.RawMessage(changed), json.RawMessage(secretOptions))); !strings.Contains(restored, `"new-secret"`) {
+		t.Fatalf("a new secret must replace the stored one: %s", restored)
+	}
+}
@@ -38,46 +38,87 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		path = "/" + path
 	}
 
-	authMiddleware := BasicAuthMiddleware(h.auth)
-
 	switch path {
+	case "/api/auth/login":
+		h.handleLogin(w, r)
+	case "/api/auth/logout":
+		h.handleLogout(w, r)
+	case "/api/auth/me":
+		h.route(w, r, RoleViewer, RoleViewer, h.handleMe)
+	case "/api/auth/password":
+		h.route(w, r, RoleViewer, RoleViewer, h.handleChangePassword)
+	case "/api/users":
+		h.route(w, r, RoleAdmin, RoleAdmin, h.handleUsers)
+	case "/api/audit":
+		h.route(w, r, RoleAdmin, RoleAdmin, h.handleAudit)
 	case "/api/endpoints":
-		authMiddleware(http.HandlerFunc(h.handleEndpoints)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleEndpoints)
 	case "/api/endpoints/current":
-		authMiddleware(http.HandlerFunc(h.handleCurrentEndpoint)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleOperator, h.handleCurrentEndpoint)
 	case "/api/endpoints/switch":
-		authMiddleware(http.HandlerFunc(h.handleSwitchEndpoint)).ServeHTTP(w, r)
+		h.route(w, r, RoleOperator, RoleOperator, h.handleSwitchEndpoint)
 	case "/api/endpoints/reorder":
-		authMiddleware(http.HandlerFunc(h.handleReorderEndpoints)).ServeHTTP(w, r)
+		h.route(w, r, RoleOperator, RoleOperator, h.handleReorderEndpoints)
 	case "/api/endpoints/fetch-models":
-		authMiddleware(http.HandlerFunc(h.handleFetchModels)).ServeHTTP(w, r)
+		h.route(w, r, RoleAdmin, RoleAdmin, h.handleFetchModels)
 	case "/api/stats/summary":
-		authMiddleware(http.HandlerFunc(h.handleStatsSummary)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleStatsSummary)
 	case "/api/stats/daily":
-		authMiddleware(http.HandlerFunc(h.handleStatsDaily)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleStatsDaily)
 	case "/api/stats/weekly":
-		authMiddleware(http.HandlerFunc(h.handleStatsWeekly)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleStatsWeekly)
 	case "/api/stats/monthly":
-		authMiddleware(http.HandlerFunc(h.handleStatsMonthly)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleStatsMonthly)
 	case "/api/stats/trends":
-		authMiddleware(http.HandlerFunc(h.handleStatsTrends)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleStatsTrends)
+	case "/api/stats/keys":
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleStatsKeys)
+	case "/api/client-keys":
+		h.route(w, r, RoleAdmin, RoleAdmin, h.handleClientKeys)
 	case "/api/config":
-		authMiddleware(http.HandlerFunc(h.handleConfig)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleConfig)
 	case "/api/config/port":
-		authMiddleware(http.HandlerFunc(h.handleConfigPort)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleConfigPort)
 	case "/api/config/log-level":
-		authMiddleware(http.HandlerFunc(h.handleConfigLogLevel)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleAdmin, h.handleConfigLogLevel)
 	case "/api/config/basic-auth":
-		authMiddleware(http.HandlerFunc(h.handleBasicAuthConfig)).ServeHTTP(w, r)
+		h.route(w, r, RoleAdmin, RoleAdmin, h.handleBasicAuthConfig)
 	case "/api/config/basic-auth/reset-password":
-		authMiddleware(http.HandlerFunc(h.handleResetBasicAuthPassword)).ServeHTTP(w, r)
+		h.route(w, r, RoleAdmin, RoleAdmin, h.handleResetBasicAuthPassword)
+	case "/api/plugins":
+		h.route(w, r, RoleAdmin, RoleAdmin, h.handlePlugins)
 	case "/api/events":
-		authMiddleware(http.HandlerFunc(h.handleEvents)).ServeHTTP(w, r)
+		h.route(w, r, RoleViewer, RoleViewer, h.handleEvents)
 	default:
 		if strings.HasPrefix(path, "/api/endpoints/") {
-			authMiddleware(http.HandlerFunc(h.handleEndpointByName)).ServeHTTP(w, r)
+			read, write := endpointRouteRoles(path)
+			h.route(w, r, read, write, h.handleEndpointByName)
+			return
+		}
+		if strings.HasPrefix(path, "/api/users/") {
+			h.route(w, r, RoleAdmin, RoleAdmin, h.handleUserByName)
+			return
+		}
+		if strings.HasPrefix(path, "/api/client-keys/") {
+			h.route(w, r, RoleAdmin, RoleAdmin, h.handleClientKeyByID)
 			return
 		}
 		http.NotFound(w, r)
 	}
-}
\ No newline at end of file
+}
+
+// endpointRouteRoles returns the roles for reading and writing below
+// /api/endpoints/{name}. Operators may test and toggle endpoints; credentials
+// are for admins only.
+func endpointRouteRoles(path string) (read, write Role) {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/endpoints/"), "/")
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "test", "toggle":
+			return RoleViewer, RoleOperator
+		case "credentials":
+			return RoleAdmin, RoleAdmin
+		}
+	}
+	return RoleViewer, RoleAdmin
+}
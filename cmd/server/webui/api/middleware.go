@@ -1,11 +1,8 @@
 package api
 
 import (
-	"crypto/subtle"
-	"encoding/base64"
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"github.com/lich0821/ccNexus/internal/logger"
 )
@@ -49,7 +46,7 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -81,61 +78,10 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// AuthConfig is the Basic Auth account of the configuration, which is always
+// an admin
 type AuthConfig struct {
 	Enabled  bool
 	Username string
 	Password string
 }
-
-func BasicAuthMiddleware(auth AuthConfig) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !auth.Enabled {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				w.Header().Set("WWW-Authenticate", `Basic realm="ccNexus"`)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			const prefix = "Basic "
-			if !strings.HasPrefix(authHeader, prefix) {
-				w.Header().Set("WWW-Authenticate", `Basic realm="ccNexus"`)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			encoded := strings.TrimPrefix(authHeader, prefix)
-			decoded, err := base64.StdEncoding.DecodeString(encoded)
-			if err != nil {
-				w.Header().Set("WWW-Authenticate", `Basic realm="ccNexus"`)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			credentials := string(decoded)
-			colonIndex := strings.Index(credentials, ":")
-			if colonIndex < 0 {
-				w.Header().Set("WWW-Authenticate", `Basic realm="ccNexus"`)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			username := credentials[:colonIndex]
-			password := credentials[colonIndex+1:]
-
-			if subtle.ConstantTimeCompare([]byte(auth.Username), []byte(username)) != 1 ||
-				subtle.ConstantTimeCompare([]byte(auth.Password), []byte(password)) != 1 {
-				w.Header().Set("WWW-Authenticate", `Basic realm="ccNexus"`)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
\ No newline at end of file
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+// handlePlugins handles GET and PUT for transformer plugins. Plugins are
+// declared in the config file only, since a declaration names a program the
+// proxy runs; PUT can merely enable or disable declared plugins.
+func (h *Handler) handlePlugins(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		WriteSuccess(w, map[string]interface{}{
+			"plugins": redactPlugins(h.config.GetPlugins()),
+			"status":  h.proxy.PluginStatus(),
+		})
+	case http.MethodPut:
+		var req struct {
+			Plugins []struct {
+				Name     string `json:"name"`
+				Disabled bool   `json:"disabled"`
+			} `json:"plugins"`
+		}
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "Invalid request body: plugins can only be enabled or disabled, declare them in the config file")
+			return
+		}
+
+		plugins := h.config.GetPlugins()
+		index := make(map[string]int, len(plugins))
+		for i, plugin := range plugins {
+			index[plugin.Name] = i
+		}
+		for _, change := range req.Plugins {
+			i, ok := index[change.Name]
+			if !ok {
+				WriteError(w, http.StatusBadRequest, fmt.Sprintf("Unknown plugin: %s", change.Name))
+				return
+			}
+			plugins[i].Disabled = change.Disabled
+		}
+
+		h.config.UpdatePlugins(plugins)
+		adapter := storage.NewConfigStorageAdapter(h.storage)
+		if err := h.config.SaveToStorage(adapter); err != nil {
+			logger.Error("Failed to save config: %v", err)
+			WriteError(w, http.StatusInternalServerError, "Failed to save configuration")
+			return
+		}
+
+		logger.Info("Plugins updated via API: %d changed", len(req.Plugins))
+		WriteSuccess(w, map[string]interface{}{
+			"plugins": redactPlugins(h.config.GetPlugins()),
+			"status":  h.proxy.PluginStatus(),
+		})
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// redactPlugins masks the environment values of plugin declarations, which
+// may hold credentials
+func redactPlugins(plugins []config.PluginConfig) []config.PluginConfig {
+	for i := range plugins {
+		if len(plugins[i].Env) == 0 {
+			continue
+		}
+		env := make(map[string]string, len(plugins[i].Env))
+		for key, value := range plugins[i].Env {
+			env[key] = maskAPIKey(value)
+		}
+		plugins[i].Env = env
+	}
+	return plugins
+}
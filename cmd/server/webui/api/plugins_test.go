@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func TestPluginsAreReadOnlyOverHTTP(t *testing.T) {
+	h, store := newAuthTestHandler(t, true)
+	if err := store.CreateWebUser(&storage.WebUser{Username: "ada", Role: "admin"}, "password-ada"); err != nil {
+		t.Fatal(err)
+	}
+	h.config.UpdatePlugins([]config.PluginConfig{{Name: "quirks", Command: "/usr/local/bin/quirks", Env: map[string]string{"TOKEN": "plugin-token"}}})
+
+	rec := apiCall(h, "GET", "/api/plugins", "", "ada", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "plugin-token") {
+		t.Fatalf("expected plugin env to be masked, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, body := range []string{
+		`{"plugins":[{"name":"quirks","command":"/bin/sh","args":["-c","id"]}]}`,
+		`{"plugins":[{"name":"shell","disabled":false}]}`,
+	} {
+		if rec := apiCall(h, "PUT", "/api/plugins", body, "ada", ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected the declaration change to be refused, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if plugins := h.config.GetPlugins(); len(plugins) != 1 || plugins[0].Command != "/usr/local/bin/quirks" {
+		t.Fatalf("declarations must be unchanged, got %+v", plugins)
+	}
+
+	if rec := apiCall(h, "PUT", "/api/plugins", `{"plugins":[{"name":"quirks","disabled":true}]}`, "ada", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected a declared plugin to be disabled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	plugins := h.config.GetPlugins()
+	if len(plugins) != 1 || !plugins[0].Disabled || plugins[0].Env["TOKEN"] != "plugin-token" {
+		t.Fatalf("expected the plugin disabled with its env kept, got %+v", plugins)
+	}
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+const minPasswordLength = 8
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return errors.New("Password must be at least " + strconv.Itoa(minPasswordLength) + " characters")
+	}
+	return nil
+}
+
+// checkAdminRemains refuses a change to the users that would leave nobody
+// able to administer the server. Users require a login, so unless the Basic
+// Auth account is enabled one of them must be an admin.
+func (h *Handler) checkAdminRemains(users []storage.WebUser) error {
+	if h.auth.Enabled || len(users) == 0 || hasAdmin(users) {
+		return nil
+	}
+	return errors.New("At least one admin user must remain unless Basic Auth is enabled")
+}
+
+func hasAdmin(users []storage.WebUser) bool {
+	for _, user := range users {
+		if user.Role == string(RoleAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleUsers handles GET (list) and POST (create) for web UI users
+func (h *Handler) handleUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		users, err := h.storage.GetWebUsers()
+		if err != nil {
+			logger.Error("Failed to get users: %v", err)
+			WriteError(w, http.StatusInternalServerError, "Failed to get users")
+			return
+		}
+		WriteSuccess(w, map[string]interface{}{
+			"users": users,
+		})
+	case http.MethodPost:
+		h.createUser(w, r)
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     Role   `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || strings.Contains(req.Username, "/") {
+		WriteError(w, http.StatusBadRequest, "Username is required and may not contain /")
+		return
+	}
+	if h.auth.Enabled && req.Username == h.auth.Username {
+		WriteError(w, http.StatusBadRequest, "Username is taken by the Basic Auth account")
+		return
+	}
+	if req.Role.rank() == 0 {
+		WriteError(w, http.StatusBadRequest, "Role must be viewer, operator or admin")
+		return
+	}
+	if err := validatePassword(req.Password); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := h.storage.GetWebUsers()
+	if err != nil {
+		logger.Error("Failed to get users: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get users")
+		return
+	}
+	for _, user := range users {
+		if user.Username == req.Username {
+			WriteError(w, http.StatusConflict, "User already exists")
+			return
+		}
+	}
+	user := storage.WebUser{Username: req.Username, Role: string(req.Role)}
+	if err := h.checkAdminRemains(append(users, user)); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.storage.CreateWebUser(&user, req.Password); err != nil {
+		logger.Error("Failed to create user: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+	logger.Info("Web UI user created: %s (%s)", user.Username, user.Role)
+
+	created, err := h.storage.GetWebUser(user.Username)
+	if err != nil || created == nil {
+		logger.Error("Failed to get user: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+	WriteSuccess(w, created)
+}
+
+// handleUserByName handles PUT (role or password) and DELETE for a web UI user
+func (h *Handler) handleUserByName(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimPrefix(r.URL.Path, "/api/users/")
+	if username == "" || strings.Contains(username, "/") {
+		WriteError(w, http.StatusBadRequest, "Username required")
+		return
+	}
+
+	users, err := h.storage.GetWebUsers()
+	if err != nil {
+		logger.Error("Failed to get users: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get users")
+		return
+	}
+	index := -1
+	for i, user := range users {
+		if user.Username == username {
+			index = i
+		}
+	}
+	if index < 0 {
+		WriteError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var req struct {
+			Role     Role   `json:"role"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.Role != "" {
+			if req.Role.rank() == 0 {
+				WriteError(w, http.StatusBadRequest, "Role must be viewer, operator or admin")
+				return
+			}
+			users[index].Role = string(req.Role)
+			if err := h.checkAdminRemains(users); err != nil {
+				WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.Password != "" {
+			if err := validatePassword(req.Password); err != nil {
+				WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		if req.Role != "" {
+			if err := h.storage.UpdateWebUserRole(username, string(req.Role)); err != nil {
+				logger.Error("Failed to update user: %v", err)
+				WriteError(w, http.StatusInternalServerError, "Failed to update user")
+				return
+			}
+		}
+		if req.Password != "" {
+			if err := h.storage.SetWebUserPassword(username, req.Password); err != nil {
+				logger.Error("Failed to set password: %v", err)
+				WriteError(w, http.StatusInternalServerError, "Failed to update user")
+				return
+			}
+		}
+		logger.Info("Web UI user updated: %s", username)
+
+		updated, err := h.storage.GetWebUser(username)
+		if err != nil || updated == nil {
+			logger.Error("Failed to get user: %v", err)
+			WriteError(w, http.StatusInternalServerError, "Failed to get user")
+			return
+		}
+		WriteSuccess(w, updated)
+
+	case http.MethodDelete:
+		remaining := append(users[:index:index], users[index+1:]...)
+		if err := h.checkAdminRemains(remaining); err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := h.storage.DeleteWebUser(username); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				WriteError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			logger.Error("Failed to delete user: %v", err)
+			WriteError(w, http.StatusInternalServerError, "Failed to delete user")
+			return
+		}
+		logger.Info("Web UI user deleted: %s", username)
+		WriteSuccess(w, map[string]interface{}{
+			"message": "User deleted successfully",
+		})
+
+	default:
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleAudit returns the audit log, newest first. limit defaults to 100.
+func (h *Handler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit, offset := 100, 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > 1000 {
+			WriteError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			WriteError(w, http.StatusBadRequest, "offset must not be negative")
+			return
+		}
+		offset = n
+	}
+
+	entries, err := h.storage.GetAuditLog(limit, offset)
+	if err != nil {
+		logger.Error("Failed to get audit log: %v", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to get audit log")
+		return
+	}
+	WriteSuccess(w, map[string]interface{}{
+		"entries": entries,
+	})
+}
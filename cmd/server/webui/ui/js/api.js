@@ -8,7 +8,9 @@ class APIClient {
         const options = {
             method,
             headers: {
-                'Content-Type': 'application/json'
+                'Content-Type': 'application/json',
+                // Asks the server for a plain 401 instead of a Basic Auth prompt
+                'X-Requested-With': 'XMLHttpRequest'
             }
         };
 
@@ -20,6 +22,9 @@ class APIClient {
             const response = await fetch(`${this.baseURL}${path}`, options);
             const result = await response.json();
 
+            if (response.status === 401 && !path.startsWith('/auth/login')) {
+                window.dispatchEvent(new CustomEvent('unauthorized'));
+            }
             if (!response.ok) {
                 throw new Error(result.error || 'Request failed');
             }
@@ -31,6 +36,19 @@ class APIClient {
         }
     }
 
+    // Login session
+    async login(username, password) {
+        return this.request('POST', '/auth/login', { username, password });
+    }
+
+    async logout() {
+        return this.request('POST', '/auth/logout');
+    }
+
+    async getMe() {
+        return this.request('GET', '/auth/me');
+    }
+
     // Endpoint management
     async getEndpoints() {
         return this.request('GET', '/endpoints');
@@ -187,6 +187,18 @@ export default {
         unknownError: 'Unknown error',
         failedToLoadEndpoints: 'Failed to load endpoints'
     },
+    auth: {
+        login: 'Log in',
+        logout: 'Log out',
+        username: 'Username',
+        password: 'Password',
+        loginFailed: 'Login failed: ',
+        roles: {
+            viewer: 'Viewer',
+            operator: 'Operator',
+            admin: 'Admin'
+        }
+    },
     notifications: {
         endpointsReordered: 'Endpoints reordered successfully',
         endpointCreated: 'Endpoint created successfully',
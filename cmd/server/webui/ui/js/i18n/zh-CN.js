@@ -187,6 +187,18 @@ export default {
         unknownError: '未知错误',
         failedToLoadEndpoints: '加载端点失败'
     },
+    auth: {
+        login: '登录',
+        logout: '退出登录',
+        username: '用户名',
+        password: '密码',
+        loginFailed: '登录失败：',
+        roles: {
+            viewer: '只读',
+            operator: '运维',
+            admin: '管理员'
+        }
+    },
     notifications: {
         endpointsReordered: '端点重新排序成功',
         endpointCreated: '端点创建成功',
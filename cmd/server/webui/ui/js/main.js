@@ -1,5 +1,8 @@
 import { router } from './router.js';
 import { state } from './state.js';
+import { api } from './api.js';
+import { notifications } from './utils/notifications.js';
+import { escapeHtml } from './utils/formatters.js';
 import { setLanguage, getLanguage, initLanguage, loadTranslations, t } from './utils/i18n.js';
 import { dashboard } from './components/dashboard.js';
 import { endpoints } from './components/endpoints.js';
@@ -63,6 +66,7 @@ function initLanguageToggle() {
         langToggle.querySelector('.icon').textContent = langLabels[newLang];
         // 更新侧边栏翻译
         updateSidebarTranslations();
+        updateSidebarUser();
         // 重新加载当前视图
         const currentView = state.get('currentView');
         if (currentView) {
@@ -104,6 +108,88 @@ function initRealtime() {
     };
 }
 
+// Show the logged-in user and the logout button when the API requires a login
+function updateSidebarUser() {
+    const user = state.get('user');
+    const container = document.getElementById('sidebar-user');
+    container.hidden = !user || !user.authRequired;
+    if (container.hidden) {
+        return;
+    }
+    document.getElementById('sidebar-username').textContent = `${user.username} (${t('auth.roles.' + user.role)})`;
+    document.getElementById('logout-btn').textContent = t('auth.logout');
+}
+
+// Show the login form; the app starts once the login succeeds
+function showLogin() {
+    const modalContainer = document.getElementById('modal-container');
+    if (document.getElementById('login-form')) {
+        return;
+    }
+
+    modalContainer.innerHTML = `
+        <div class="modal-overlay">
+            <div class="modal">
+                <div class="modal-header">
+                    <h3 class="modal-title">ccNexus ${t('auth.login')}</h3>
+                </div>
+                <div class="modal-body">
+                    <form id="login-form">
+                        <div class="form-group">
+                            <label class="form-label">${t('auth.username')}</label>
+                            <input type="text" class="form-input" name="username" autocomplete="username" required>
+                        </div>
+                        <div class="form-group">
+                            <label class="form-label">${t('auth.password')}</label>
+                            <input type="password" class="form-input" name="password" autocomplete="current-password" required>
+                        </div>
+                        <button type="submit" class="btn btn-primary">${t('auth.login')}</button>
+                    </form>
+                </div>
+            </div>
+        </div>
+    `;
+
+    const form = document.getElementById('login-form');
+    form.username.focus();
+    form.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        try {
+            await api.login(form.username.value, form.password.value);
+            modalContainer.innerHTML = '';
+            start();
+        } catch (error) {
+            notifications.error(t('auth.loginFailed') + escapeHtml(error.message));
+        }
+    });
+}
+
+// Load the current user, then the views and real-time updates
+let started = false;
+async function start() {
+    let user;
+    try {
+        user = await api.getMe();
+    } catch (error) {
+        showLogin();
+        return;
+    }
+    state.update('user', user);
+    updateSidebarUser();
+
+    if (started) {
+        router.navigate(state.get('currentView'));
+        return;
+    }
+    started = true;
+
+    // Initialize router
+    router.init();
+
+    // Initialize real-time updates
+    initRealtime();
+}
+
 // Initialize application
 function init() {
     // Register routes
@@ -121,11 +207,19 @@ function init() {
     // Initialize sidebar translations
     updateSidebarTranslations();
 
-    // Initialize router
-    router.init();
+    // Ask for a login whenever the session ends
+    window.addEventListener('unauthorized', showLogin);
+    document.getElementById('logout-btn').addEventListener('click', async () => {
+        try {
+            await api.logout();
+        } finally {
+            state.update('user', null);
+            updateSidebarUser();
+            showLogin();
+        }
+    });
 
-    // Initialize real-time updates
-    initRealtime();
+    start();
 
     console.log('ccNexus Admin initialized');
 }
@@ -42,6 +42,9 @@ export function getTransformerLabel(transformer) {
         'openai': 'OpenAI',
         'openai2': 'OpenAI Responses',
         'gemini': 'Gemini',
+        'bedrock': 'AWS Bedrock',
+        'vertex': 'Vertex AI',
+        'ollama': 'Ollama',
         'deepseek': 'DeepSeek'
     };
     return labels[transformer] || transformer;
@@ -32,19 +32,13 @@ func New(cfg *config.Config, p *proxy.Proxy, storage *storage.SQLiteStorage) *We
 func (w *WebUI) RegisterRoutes(mux *http.ServeMux) error {
 	mux.HandleFunc("/api/", w.apiHandler.ServeHTTP)
 
-	authConfig := api.AuthConfig{
-		Enabled:  w.cfg.BasicAuthEnabled,
-		Username: w.cfg.BasicAuthUsername,
-		Password: w.cfg.BasicAuthPassword,
-	}
-	authMiddleware := api.BasicAuthMiddleware(authConfig)
-
+	// The UI files hold no data; the UI logs in against the API
 	uiSubFS, err := fs.Sub(uiFS, "ui")
 	if err != nil {
 		return err
 	}
 
-	uiHandler := authMiddleware(http.FileServer(http.FS(uiSubFS)))
+	uiHandler := http.FileServer(http.FS(uiSubFS))
 	mux.Handle("/ui/", http.StripPrefix("/ui/", uiHandler))
 
 	mux.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
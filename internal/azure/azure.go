@@ -0,0 +1,59 @@
+package azure
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DefaultAPIVersion is used when an endpoint does not configure one. It is the
+// oldest version that serves both Chat Completions and the Responses API.
+const DefaultAPIVersion = "2025-04-01-preview"
+
+// IsAzureOpenAIURL reports whether a base URL points at an Azure OpenAI or
+// Azure AI Services resource
+func IsAzureOpenAIURL(raw string) bool {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	return strings.HasSuffix(host, ".openai.azure.com") || strings.HasSuffix(host, ".cognitiveservices.azure.com")
+}
+
+// BaseURL strips a trailing /openai from a resource URL so paths can be
+// appended uniformly
+func BaseURL(raw string) string {
+	raw = strings.TrimSuffix(strings.TrimSpace(raw), "/")
+	return strings.TrimSuffix(raw, "/openai")
+}
+
+// Deployment returns the deployment serving a model. Models without a mapping
+// are assumed to be deployed under their own name.
+func Deployment(model string, deployments map[string]string) string {
+	model = strings.TrimSpace(model)
+	if deployment, ok := deployments[model]; ok && strings.TrimSpace(deployment) != "" {
+		return strings.TrimSpace(deployment)
+	}
+	return model
+}
+
+// ChatCompletionsPath returns the Chat Completions path of a deployment
+func ChatCompletionsPath(deployment string) string {
+	return DeploymentPath(deployment, "chat/completions")
+}
+
+// DeploymentPath returns the path of an operation such as "embeddings" or
+// "audio/transcriptions" on a deployment
+func DeploymentPath(deployment, operation string) string {
+	return "/openai/deployments/" + url.PathEscape(deployment) + "/" + operation
+}
+
+// ResponsesPath is the Responses API path; the deployment goes in the body's model field
+const ResponsesPath = "/openai/responses"
+
+// DeploymentsPath lists the resource's deployments
+const DeploymentsPath = "/openai/deployments"
@@ -0,0 +1,78 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsAzureOpenAIURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://my-resource.openai.azure.com":                true,
+		"my-resource.openai.azure.com/openai":                 true,
+		"https://my-hub.cognitiveservices.azure.com/":         true,
+		"https://api.openai.com/v1":                           false,
+		"https://openai.azure.com.example.com/openai/v1/chat": false,
+	}
+	for raw, want := range tests {
+		if got := IsAzureOpenAIURL(raw); got != want {
+			t.Errorf("IsAzureOpenAIURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestDeploymentAndPaths(t *testing.T) {
+	deployments := map[string]string{"gpt-4o": "prod-4o"}
+	if got := Deployment("gpt-4o", deployments); got != "prod-4o" {
+		t.Fatalf("expected mapped deployment, got %q", got)
+	}
+	if got := Deployment("gpt-4.1", deployments); got != "gpt-4.1" {
+		t.Fatalf("expected model name as deployment, got %q", got)
+	}
+	if got := ChatCompletionsPath("prod-4o"); got != "/openai/deployments/prod-4o/chat/completions" {
+		t.Fatalf("unexpected chat path %q", got)
+	}
+	if got := BaseURL("https://r.openai.azure.com/openai/"); got != "https://r.openai.azure.com" {
+		t.Fatalf("unexpected base URL %q", got)
+	}
+}
+
+func TestTokenCacheClientCredentials(t *testing.T) {
+	minted := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("client_id") != "app" ||
+			r.PostForm.Get("client_secret") != "secret" || r.PostForm.Get("scope") != CognitiveServicesScope {
+			t.Errorf("unexpected token request %v", r.PostForm)
+		}
+		minted++
+		w.Write([]byte(`{"token_type":"Bearer","expires_in":3599,"access_token":"entra-token"}`))
+	}))
+	defer server.Close()
+
+	creds := ClientCredentials{TenantID: "tenant", ClientID: "app", ClientSecret: "secret", TokenURL: server.URL}
+	cache := NewTokenCache()
+	for i := 0; i < 2; i++ {
+		token, err := cache.Token(context.Background(), server.Client(), creds)
+		if err != nil || token != "entra-token" {
+			t.Fatalf("expected entra-token, got %q (%v)", token, err)
+		}
+	}
+	if minted != 1 {
+		t.Fatalf("expected one token request, got %d", minted)
+	}
+	cache.Invalidate(creds)
+	cache.Token(context.Background(), server.Client(), creds)
+	if minted != 2 {
+		t.Fatalf("expected a new token after invalidation, got %d requests", minted)
+	}
+
+	if got := (ClientCredentials{TenantID: "contoso"}).TokenEndpoint(); got != "https://login.microsoftonline.com/contoso/oauth2/v2.0/token" {
+		t.Fatalf("unexpected default token endpoint %q", got)
+	}
+	if _, err := MintToken(context.Background(), server.Client(), creds, time.Now()); err != nil {
+		t.Fatalf("MintToken failed: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CognitiveServicesScope grants access to Azure OpenAI data-plane APIs
+	CognitiveServicesScope = "https://cognitiveservices.azure.com/.default"
+
+	defaultTokenLifetime = time.Hour
+	// refreshWindow matches the token pool: tokens are renewed two minutes early
+	refreshWindow = 2 * time.Minute
+)
+
+// ClientCredentials identifies a Microsoft Entra ID application
+type ClientCredentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	TokenURL     string // Overrides the Entra ID token endpoint of the tenant
+}
+
+// TokenEndpoint returns the OAuth 2.0 token URL for the credentials
+func (c ClientCredentials) TokenEndpoint() string {
+	if tokenURL := strings.TrimSpace(c.TokenURL); tokenURL != "" {
+		return tokenURL
+	}
+	return "https://login.microsoftonline.com/" + url.PathEscape(strings.TrimSpace(c.TenantID)) + "/oauth2/v2.0/token"
+}
+
+// Token is an OAuth access token and its expiry
+type Token struct {
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+// MintToken requests an access token with the client credentials grant
+func MintToken(ctx context.Context, client *http.Client, creds ClientCredentials, now time.Time) (*Token, error) {
+	tokenURL := creds.TokenEndpoint()
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {strings.TrimSpace(creds.ClientID)},
+		"client_secret": {creds.ClientSecret},
+		"scope":         {CognitiveServicesScope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create token request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed (%s): %w", tokenURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read token response failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("parse token response failed: %w", err)
+	}
+	if strings.TrimSpace(tokenResp.AccessToken) == "" {
+		return nil, fmt.Errorf("token response missing access_token")
+	}
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = defaultTokenLifetime
+	}
+	return &Token{AccessToken: tokenResp.AccessToken, ExpiresAt: now.Add(expiresIn)}, nil
+}
+
+// TokenCache keeps one access token per application and mints a new one
+// shortly before the current token expires
+type TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*Token
+}
+
+// NewTokenCache creates an empty token cache
+func NewTokenCache() *TokenCache {
+	return &TokenCache{tokens: make(map[string]*Token)}
+}
+
+// Token returns a valid access token, minting one when the cached token is
+// missing or about to expire
+func (c *TokenCache) Token(ctx context.Context, client *http.Client, creds ClientCredentials) (string, error) {
+	key := cacheKey(creds)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if token, ok := c.tokens[key]; ok && now.Add(refreshWindow).Before(token.ExpiresAt) {
+		return token.AccessToken, nil
+	}
+	token, err := MintToken(ctx, client, creds, now)
+	if err != nil {
+		return "", err
+	}
+	c.tokens[key] = token
+	return token.AccessToken, nil
+}
+
+// Invalidate drops the cached token so the next call mints a new one
+func (c *TokenCache) Invalidate(creds ClientCredentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, cacheKey(creds))
+}
+
+func cacheKey(creds ClientCredentials) string {
+	return creds.TenantID + "|" + creds.ClientID + "|" + creds.TokenEndpoint()
+}
@@ -0,0 +1,50 @@
+package bedrock
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ResolveCredentials returns the credentials used to sign Bedrock requests.
+// Static keys win; otherwise the named profile is read from the shared
+// credentials file, and without a profile the AWS_* environment variables
+// and the default profile are tried in turn.
+func ResolveCredentials(accessKeyID, secretAccessKey, sessionToken, profile string) (Credentials, error) {
+	accessKeyID = strings.TrimSpace(accessKeyID)
+	secretAccessKey = strings.TrimSpace(secretAccessKey)
+	if accessKeyID != "" && secretAccessKey != "" {
+		return Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    strings.TrimSpace(sessionToken),
+		}, nil
+	}
+
+	var provider *credentials.Credentials
+	if profile = strings.TrimSpace(profile); profile != "" {
+		provider = credentials.NewFileAWSCredentials("", profile)
+	} else {
+		provider = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+		})
+	}
+
+	value, err := provider.Get()
+	if err != nil {
+		if profile != "" {
+			return Credentials{}, fmt.Errorf("failed to load AWS profile %s: %w", profile, err)
+		}
+		return Credentials{}, fmt.Errorf("failed to load AWS credentials: %w", err)
+	}
+	if value.AccessKeyID == "" || value.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("no AWS credentials configured")
+	}
+	return Credentials{
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+	}, nil
+}
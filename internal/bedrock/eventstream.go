@@ -0,0 +1,270 @@
+package bedrock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// EventStreamContentType is the content type of AWS event-stream responses
+const EventStreamContentType = "application/vnd.amazon.eventstream"
+
+const (
+	preludeLength  = 12
+	maxMessageSize = 16 * 1024 * 1024
+
+	headerTypeBoolTrue  = 0
+	headerTypeBoolFalse = 1
+	headerTypeByte      = 2
+	headerTypeShort     = 3
+	headerTypeInt       = 4
+	headerTypeLong      = 5
+	headerTypeBytes     = 6
+	headerTypeString    = 7
+	headerTypeTimestamp = 8
+	headerTypeUUID      = 9
+)
+
+// Message is a single AWS event-stream message. Only string header values are
+// kept; Bedrock does not use the other header types.
+type Message struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// EncodeMessage frames headers and payload as an event-stream message. It is
+// used by local stubs that stand in for Bedrock.
+func EncodeMessage(headers map[string]string, payload []byte) []byte {
+	var headerBuf bytes.Buffer
+	for name, value := range headers {
+		headerBuf.WriteByte(byte(len(name)))
+		headerBuf.WriteString(name)
+		headerBuf.WriteByte(headerTypeString)
+		binary.Write(&headerBuf, binary.BigEndian, uint16(len(value)))
+		headerBuf.WriteString(value)
+	}
+
+	total := preludeLength + headerBuf.Len() + len(payload) + 4
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.BigEndian, uint32(total))
+	binary.Write(&msg, binary.BigEndian, uint32(headerBuf.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(headerBuf.Bytes())
+	msg.Write(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+// EncodeChunk frames one Claude streaming event the way
+// InvokeModelWithResponseStream returns it
+func EncodeChunk(event []byte) []byte {
+	payload, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString(event)})
+	return EncodeMessage(map[string]string{
+		":message-type": "event",
+		":event-type":   "chunk",
+		":content-type": "application/json",
+	}, payload)
+}
+
+// Decoder reads event-stream messages from a reader
+type Decoder struct {
+	r io.Reader
+}
+
+// NewDecoder creates a decoder for r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next message. It returns io.EOF at a clean end of stream.
+func (d *Decoder) Decode() (*Message, error) {
+	prelude := make([]byte, preludeLength)
+	if _, err := io.ReadFull(d.r, prelude); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated event-stream prelude")
+		}
+		return nil, err
+	}
+
+	total := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, fmt.Errorf("event-stream prelude checksum mismatch")
+	}
+	if total > maxMessageSize || total < preludeLength+4 || headersLen > total-preludeLength-4 {
+		return nil, fmt.Errorf("invalid event-stream message length %d", total)
+	}
+
+	rest := make([]byte, total-preludeLength)
+	if _, err := io.ReadFull(d.r, rest); err != nil {
+		return nil, fmt.Errorf("truncated event-stream message: %w", err)
+	}
+	body := rest[:len(rest)-4]
+	crc := crc32.NewIEEE()
+	crc.Write(prelude)
+	crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(rest[len(rest)-4:]) {
+		return nil, fmt.Errorf("event-stream message checksum mismatch")
+	}
+
+	headers, err := decodeHeaders(body[:headersLen])
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Headers: headers, Payload: body[headersLen:]}, nil
+}
+
+func decodeHeaders(data []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	errTruncated := errors.New("truncated event-stream header")
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 1+nameLen+1 {
+			return nil, errTruncated
+		}
+		name := string(data[1 : 1+nameLen])
+		valueType := data[1+nameLen]
+		data = data[2+nameLen:]
+
+		var size int
+		switch valueType {
+		case headerTypeBoolTrue, headerTypeBoolFalse:
+			size = 0
+		case headerTypeByte:
+			size = 1
+		case headerTypeShort:
+			size = 2
+		case headerTypeInt:
+			size = 4
+		case headerTypeLong, headerTypeTimestamp:
+			size = 8
+		case headerTypeUUID:
+			size = 16
+		case headerTypeBytes, headerTypeString:
+			if len(data) < 2 {
+				return nil, errTruncated
+			}
+			size = int(binary.BigEndian.Uint16(data[:2]))
+			data = data[2:]
+		default:
+			return nil, fmt.Errorf("unknown event-stream header type %d", valueType)
+		}
+		if len(data) < size {
+			return nil, errTruncated
+		}
+		if valueType == headerTypeString {
+			headers[name] = string(data[:size])
+		}
+		data = data[size:]
+	}
+	return headers, nil
+}
+
+// sseReader converts a Bedrock response stream into Claude SSE text
+type sseReader struct {
+	body    io.ReadCloser
+	decoder *Decoder
+	pending bytes.Buffer
+	done    bool
+}
+
+// NewSSEReader wraps an InvokeModelWithResponseStream body so it reads as the
+// Claude Messages SSE stream carried inside it. Stream exceptions become
+// Claude error events.
+func NewSSEReader(body io.ReadCloser) io.ReadCloser {
+	return &sseReader{body: body, decoder: NewDecoder(body)}
+}
+
+func (s *sseReader) Read(p []byte) (int, error) {
+	for s.pending.Len() == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		msg, err := s.decoder.Decode()
+		if err == io.EOF {
+			s.done = true
+			continue
+		}
+		if err != nil {
+			s.done = true
+			s.pending.Write(sseError("api_error", err.Error()))
+			continue
+		}
+		s.pending.Write(messageToSSE(msg))
+	}
+	return s.pending.Read(p)
+}
+
+func (s *sseReader) Close() error {
+	return s.body.Close()
+}
+
+// messageToSSE renders one event-stream message as Claude SSE
+func messageToSSE(msg *Message) []byte {
+	switch msg.Headers[":message-type"] {
+	case "event":
+		if msg.Headers[":event-type"] != "chunk" {
+			return nil
+		}
+		var chunk struct {
+			Bytes string `json:"bytes"`
+		}
+		if err := json.Unmarshal(msg.Payload, &chunk); err != nil {
+			return sseError("api_error", "invalid Bedrock chunk: "+err.Error())
+		}
+		event, err := base64.StdEncoding.DecodeString(chunk.Bytes)
+		if err != nil {
+			return sseError("api_error", "invalid Bedrock chunk: "+err.Error())
+		}
+		var header struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal(event, &header)
+		return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", header.Type, event))
+	case "exception", "error":
+		exceptionType := msg.Headers[":exception-type"]
+		if exceptionType == "" {
+			exceptionType = msg.Headers[":error-code"]
+		}
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(msg.Payload, &body)
+		if body.Message == "" {
+			body.Message = msg.Headers[":error-message"]
+		}
+		return sseError(claudeErrorType(exceptionType), fmt.Sprintf("%s: %s", exceptionType, body.Message))
+	default:
+		return nil
+	}
+}
+
+// claudeErrorType maps Bedrock stream exceptions to Claude error types
+func claudeErrorType(exceptionType string) string {
+	switch exceptionType {
+	case "throttlingException":
+		return "rate_limit_error"
+	case "validationException":
+		return "invalid_request_error"
+	case "serviceUnavailableException", "modelNotReadyException":
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+func sseError(errorType, message string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errorType,
+			"message": message,
+		},
+	})
+	return []byte(fmt.Sprintf("event: error\ndata: %s\n\n", data))
+}
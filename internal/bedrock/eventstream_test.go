@@ -0,0 +1,49 @@
+package bedrock
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoderRoundTrip(t *testing.T) {
+	encoded := EncodeMessage(map[string]string{":message-type": "event", ":event-type": "chunk"}, []byte(`{"bytes":""}`))
+	msg, err := NewDecoder(bytes.NewReader(encoded)).Decode()
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if msg.Headers[":event-type"] != "chunk" || string(msg.Payload) != `{"bytes":""}` {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	encoded[len(encoded)-5] ^= 0xff
+	if _, err := NewDecoder(bytes.NewReader(encoded)).Decode(); err == nil {
+		t.Fatal("expected checksum mismatch to be reported")
+	}
+}
+
+func TestSSEReaderConvertsChunksAndExceptions(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(EncodeChunk([]byte(`{"type":"message_start","message":{"id":"msg_1"}}`)))
+	stream.Write(EncodeChunk([]byte(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`)))
+	stream.Write(EncodeMessage(map[string]string{
+		":message-type":   "exception",
+		":exception-type": "throttlingException",
+	}, []byte(`{"message":"Too many requests"}`)))
+
+	out, err := io.ReadAll(NewSSEReader(io.NopCloser(&stream)))
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	result := string(out)
+	for _, want := range []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\"",
+		"event: error\ndata: {\"error\":{\"message\":\"throttlingException: Too many requests\",\"type\":\"rate_limit_error\"},\"type\":\"error\"}\n\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in %s", want, result)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package bedrock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// anthropicModelIDs maps Anthropic model names to Bedrock foundation model IDs.
+// Undated aliases point at the snapshot Anthropic's API would resolve them to.
+var anthropicModelIDs = map[string]string{
+	"claude-3-haiku-20240307":    "anthropic.claude-3-haiku-20240307-v1:0",
+	"claude-3-5-haiku-20241022":  "anthropic.claude-3-5-haiku-20241022-v1:0",
+	"claude-3-5-sonnet-20240620": "anthropic.claude-3-5-sonnet-20240620-v1:0",
+	"claude-3-5-sonnet-20241022": "anthropic.claude-3-5-sonnet-20241022-v2:0",
+	"claude-3-7-sonnet-20250219": "anthropic.claude-3-7-sonnet-20250219-v1:0",
+	"claude-sonnet-4-20250514":   "anthropic.claude-sonnet-4-20250514-v1:0",
+	"claude-opus-4-20250514":     "anthropic.claude-opus-4-20250514-v1:0",
+	"claude-opus-4-1-20250805":   "anthropic.claude-opus-4-1-20250805-v1:0",
+	"claude-sonnet-4-5-20250929": "anthropic.claude-sonnet-4-5-20250929-v1:0",
+	"claude-haiku-4-5-20251001":  "anthropic.claude-haiku-4-5-20251001-v1:0",
+	"claude-opus-4-5-20251101":   "anthropic.claude-opus-4-5-20251101-v1:0",
+
+	"claude-3-5-haiku-latest":  "anthropic.claude-3-5-haiku-20241022-v1:0",
+	"claude-3-5-sonnet-latest": "anthropic.claude-3-5-sonnet-20241022-v2:0",
+	"claude-3-7-sonnet-latest": "anthropic.claude-3-7-sonnet-20250219-v1:0",
+	"claude-sonnet-4-0":        "anthropic.claude-sonnet-4-20250514-v1:0",
+	"claude-opus-4-0":          "anthropic.claude-opus-4-20250514-v1:0",
+	"claude-opus-4-1":          "anthropic.claude-opus-4-1-20250805-v1:0",
+	"claude-sonnet-4-5":        "anthropic.claude-sonnet-4-5-20250929-v1:0",
+	"claude-haiku-4-5":         "anthropic.claude-haiku-4-5-20251001-v1:0",
+	"claude-opus-4-5":          "anthropic.claude-opus-4-5-20251101-v1:0",
+}
+
+// ModelID resolves the Bedrock model ID for a model name. Explicit overrides
+// win, IDs and ARNs already in Bedrock form are kept, and known Anthropic
+// models are mapped to the cross-region inference profile of the region's
+// geography (for example us.anthropic.claude-sonnet-4-20250514-v1:0).
+func ModelID(model, region string, overrides map[string]string) string {
+	model = strings.TrimSpace(model)
+	if id, ok := overrides[model]; ok && strings.TrimSpace(id) != "" {
+		return strings.TrimSpace(id)
+	}
+	if strings.HasPrefix(model, "arn:") || strings.Contains(model, "anthropic.") {
+		return model
+	}
+
+	// Claude Code marks extended context models with a suffix Bedrock does not know
+	base := strings.TrimSuffix(model, "[1m]")
+	id, ok := anthropicModelIDs[base]
+	if !ok {
+		if !strings.HasPrefix(base, "claude-") {
+			return model
+		}
+		id = "anthropic." + base + "-v1:0"
+	}
+	if prefix := inferenceProfilePrefix(region); prefix != "" {
+		return prefix + "." + id
+	}
+	return id
+}
+
+// inferenceProfilePrefix returns the cross-region inference profile prefix for a region
+func inferenceProfilePrefix(region string) string {
+	region = strings.ToLower(strings.TrimSpace(region))
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "us-gov"
+	case strings.HasPrefix(region, "us-"), strings.HasPrefix(region, "ca-"):
+		return "us"
+	case strings.HasPrefix(region, "eu-"):
+		return "eu"
+	case strings.HasPrefix(region, "ap-"):
+		return "apac"
+	default:
+		return ""
+	}
+}
+
+// RuntimeURL returns the Bedrock runtime base URL of a region
+func RuntimeURL(region string) string {
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", strings.TrimSpace(region))
+}
+
+// InvokePath returns the escaped InvokeModel or InvokeModelWithResponseStream path
+func InvokePath(modelID string, stream bool) string {
+	action := "invoke"
+	if stream {
+		action = "invoke-with-response-stream"
+	}
+	return fmt.Sprintf("/model/%s/%s", uriEncode(modelID), action)
+}
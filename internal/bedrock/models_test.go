@@ -0,0 +1,25 @@
+package bedrock
+
+import "testing"
+
+func TestModelID(t *testing.T) {
+	tests := []struct {
+		model     string
+		region    string
+		overrides map[string]string
+		want      string
+	}{
+		{"claude-sonnet-4-20250514", "us-west-2", nil, "us.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"claude-3-5-sonnet-20241022", "eu-central-1", nil, "eu.anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		{"claude-sonnet-4-5", "ap-northeast-1", nil, "apac.anthropic.claude-sonnet-4-5-20250929-v1:0"},
+		{"claude-opus-4-1-20250805[1m]", "us-east-1", nil, "us.anthropic.claude-opus-4-1-20250805-v1:0"},
+		{"claude-future-9-20300101", "sa-east-1", nil, "anthropic.claude-future-9-20300101-v1:0"},
+		{"anthropic.claude-3-haiku-20240307-v1:0", "us-east-1", nil, "anthropic.claude-3-haiku-20240307-v1:0"},
+		{"claude-3-haiku-20240307", "us-east-1", map[string]string{"claude-3-haiku-20240307": "arn:aws:bedrock:us-east-1:123:inference-profile/x"}, "arn:aws:bedrock:us-east-1:123:inference-profile/x"},
+	}
+	for _, tt := range tests {
+		if got := ModelID(tt.model, tt.region, tt.overrides); got != tt.want {
+			t.Errorf("ModelID(%q, %q) = %q, want %q", tt.model, tt.region, got, tt.want)
+		}
+	}
+}
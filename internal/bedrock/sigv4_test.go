@@ -0,0 +1,40 @@
+package bedrock
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Vector "get-vanilla" from the AWS Signature Version 4 test suite
+func TestSignRequestMatchesAWSTestSuite(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	SignRequest(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("unexpected authorization\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestSignRequestIgnoresClientHeadersAndEncodesPath(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://bedrock-runtime.us-east-1.amazonaws.com"+InvokePath("anthropic.claude-3-haiku-20240307-v1:0", false), nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "claude-cli")
+	SignRequest(req, []byte(`{}`), Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}, "us-east-1", ServiceName, time.Now())
+
+	if req.Header.Get("X-Amz-Security-Token") != "token" {
+		t.Fatalf("expected session token header, got %v", req.Header)
+	}
+	if got := canonicalURI(req.URL); got != "/model/anthropic.claude-3-haiku-20240307-v1%253A0/invoke" {
+		t.Fatalf("expected double-encoded canonical path, got %s", got)
+	}
+	_, signed := canonicalHeaders(req)
+	if signed != "content-type;host;x-amz-date;x-amz-security-token" {
+		t.Fatalf("unexpected signed headers: %s", signed)
+	}
+}
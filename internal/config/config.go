@@ -8,15 +8,25 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/azure"
 )
 
 const (
 	AuthModeAPIKey         = "api_key"
 	AuthModeTokenPool      = "token_pool"
 	AuthModeCodexTokenPool = "codex_token_pool"
+	AuthModeAWSSigV4       = "aws_sigv4"
+	AuthModeServiceAccount = "service_account"
+	AuthModeEntraID        = "entra_id"
 
 	CodexTokenPoolAPIURL      = "https://chatgpt.com/backend-api/codex"
 	CodexTokenPoolTransformer = "openai2"
+
+	BedrockTransformer = "bedrock"
+	VertexTransformer  = "vertex"
+	OllamaTransformer  = "ollama"
 )
 
 func NormalizeAuthMode(mode string) string {
@@ -25,6 +35,12 @@ func NormalizeAuthMode(mode string) string {
 		return AuthModeTokenPool
 	case AuthModeCodexTokenPool:
 		return AuthModeCodexTokenPool
+	case AuthModeAWSSigV4:
+		return AuthModeAWSSigV4
+	case AuthModeServiceAccount:
+		return AuthModeServiceAccount
+	case AuthModeEntraID:
+		return AuthModeEntraID
 	default:
 		return AuthModeAPIKey
 	}
@@ -59,9 +75,28 @@ func ApplyEndpointAuthModeRules(ep *Endpoint) {
 		return
 	}
 
-	if ep.AuthMode == AuthModeTokenPool {
+	if ep.AuthMode == AuthModeTokenPool || ep.AuthMode == AuthModeAWSSigV4 ||
+		ep.AuthMode == AuthModeServiceAccount || ep.AuthMode == AuthModeEntraID {
 		ep.APIKey = ""
 	}
+
+	// Bedrock endpoints default to the regional runtime URL
+	if strings.EqualFold(ep.Transformer, BedrockTransformer) && ep.APIUrl == "" {
+		if region := ep.Options.BedrockRegion(); region != "" {
+			ep.APIUrl = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+		}
+	}
+
+	// Vertex AI endpoints default to the location's API host
+	if strings.EqualFold(ep.Transformer, VertexTransformer) && ep.APIUrl == "" {
+		switch location := ep.Options.VertexLocation(); location {
+		case "":
+		case "global":
+			ep.APIUrl = "https://aiplatform.googleapis.com"
+		default:
+			ep.APIUrl = fmt.Sprintf("https://%s-aiplatform.googleapis.com", location)
+		}
+	}
 }
 
 func isCodexBackendAPIURL(raw string) bool {
@@ -88,16 +123,125 @@ func isCodexBackendAPIURL(raw string) bool {
 	return strings.HasSuffix(cleanPath, "/backend-api/codex") || strings.HasSuffix(cleanPath, "/backend-api/codex/v1")
 }
 
+// Auxiliary OpenAI routes an endpoint can serve besides chat
+const (
+	CapabilityEmbeddings     = "embeddings"
+	CapabilityModerations    = "moderations"
+	CapabilityTranscriptions = "transcriptions"
+)
+
+// SupportsCapability reports whether the endpoint serves an auxiliary route.
+// Explicit flags in options.auxiliary win; otherwise OpenAI endpoints serve
+// all routes (Azure has no moderations, the Codex backend none), and Gemini
+// and Ollama endpoints serve embeddings.
+func (ep *Endpoint) SupportsCapability(capability string) bool {
+	if ep.Options != nil && ep.Options.Auxiliary != nil {
+		var flag *bool
+		switch capability {
+		case CapabilityEmbeddings:
+			flag = ep.Options.Auxiliary.Embeddings
+		case CapabilityModerations:
+			flag = ep.Options.Auxiliary.Moderations
+		case CapabilityTranscriptions:
+			flag = ep.Options.Auxiliary.Transcriptions
+		}
+		if flag != nil {
+			return *flag
+		}
+	}
+
+	switch strings.ToLower(ep.Transformer) {
+	case "openai", "openai2":
+		if NormalizeAuthMode(ep.AuthMode) == AuthModeCodexTokenPool || isCodexBackendAPIURL(ep.APIUrl) {
+			return false
+		}
+		if capability == CapabilityModerations && ep.IsAzureOpenAI() {
+			return false
+		}
+		return true
+	case "gemini", OllamaTransformer:
+		return capability == CapabilityEmbeddings
+	default:
+		return false
+	}
+}
+
 // Endpoint represents a single API endpoint configuration
 type Endpoint struct {
-	Name        string `json:"name"`
-	APIUrl      string `json:"apiUrl"`
-	APIKey      string `json:"apiKey"`
-	AuthMode    string `json:"authMode,omitempty"`
-	Enabled     bool   `json:"enabled"`
-	Transformer string `json:"transformer,omitempty"` // Transformer type: claude, openai, gemini, deepseek
-	Model       string `json:"model,omitempty"`       // Target model name for non-Claude APIs
-	Remark      string `json:"remark,omitempty"`      // Optional remark for the endpoint
+	Name        string           `json:"name"`
+	APIUrl      string           `json:"apiUrl"`
+	APIKey      string           `json:"apiKey"`
+	AuthMode    string           `json:"authMode,omitempty"`
+	Enabled     bool             `json:"enabled"`
+	Transformer string           `json:"transformer,omitempty"` // Transformer type: claude, openai, gemini, deepseek
+	Model       string           `json:"model,omitempty"`       // Target model name for non-Claude APIs
+	Remark      string           `json:"remark,omitempty"`      // Optional remark for the endpoint
+	Options     *EndpointOptions `json:"options,omitempty"`     // Optional per-endpoint tuning
+}
+
+// validateBedrock checks that Bedrock endpoints carry a region and that SigV4
+// auth is only used with them
+func (ep *Endpoint) validateBedrock() error {
+	isBedrock := strings.EqualFold(ep.Transformer, BedrockTransformer)
+	if ep.AuthMode == AuthModeAWSSigV4 && !isBedrock {
+		return fmt.Errorf("authMode %s requires the %s transformer", AuthModeAWSSigV4, BedrockTransformer)
+	}
+	if isBedrock && ep.Options.BedrockRegion() == "" {
+		return fmt.Errorf("bedrock region is required")
+	}
+	return nil
+}
+
+// validateVertex checks that Vertex AI endpoints carry a location and that
+// service account auth is only used with them
+func (ep *Endpoint) validateVertex() error {
+	isVertex := strings.EqualFold(ep.Transformer, VertexTransformer)
+	if ep.AuthMode == AuthModeServiceAccount && !isVertex {
+		return fmt.Errorf("authMode %s requires the %s transformer", AuthModeServiceAccount, VertexTransformer)
+	}
+	if !isVertex {
+		return nil
+	}
+	if ep.Options.VertexLocation() == "" {
+		return fmt.Errorf("vertex location is required")
+	}
+	if ep.AuthMode == AuthModeServiceAccount {
+		v := ep.Options.Vertex
+		if strings.TrimSpace(v.ServiceAccountKey) == "" && strings.TrimSpace(v.ServiceAccountFile) == "" {
+			return fmt.Errorf("vertex serviceAccountKey or serviceAccountFile is required")
+		}
+	}
+	return nil
+}
+
+// IsAzureOpenAI reports whether an OpenAI endpoint talks to Azure OpenAI, either
+// because Azure options are set or because the URL is an Azure resource
+func (ep *Endpoint) IsAzureOpenAI() bool {
+	switch strings.ToLower(ep.Transformer) {
+	case "openai", "openai2":
+	default:
+		return false
+	}
+	return (ep.Options != nil && ep.Options.Azure != nil) || azure.IsAzureOpenAIURL(ep.APIUrl)
+}
+
+// validateAzure checks that Entra ID auth is only used with Azure OpenAI
+// endpoints that carry client credentials
+func (ep *Endpoint) validateAzure() error {
+	if ep.Options != nil && ep.Options.Azure != nil && !ep.IsAzureOpenAI() {
+		return fmt.Errorf("azure options require the openai or openai2 transformer")
+	}
+	if ep.AuthMode != AuthModeEntraID {
+		return nil
+	}
+	if !ep.IsAzureOpenAI() {
+		return fmt.Errorf("authMode %s requires an Azure OpenAI endpoint", AuthModeEntraID)
+	}
+	a := ep.Options.Azure
+	if a == nil || strings.TrimSpace(a.TenantID) == "" || strings.TrimSpace(a.ClientID) == "" || a.ClientSecret == "" {
+		return fmt.Errorf("azure tenantId, clientId and clientSecret are required for authMode %s", AuthModeEntraID)
+	}
+	return nil
 }
 
 // WebDAVConfig represents WebDAV synchronization configuration
@@ -175,12 +319,17 @@ type Config struct {
 	ClaudeNotificationType    string          `json:"claudeNotificationType"`              // Notification type: toast, dialog, disabled
 	ModelsCacheTTL            int             `json:"modelsCacheTTL,omitempty"`            // /v1/models cache TTL in minutes, default 30
 	ModelsCacheRefreshEnabled bool            `json:"modelsCacheRefreshEnabled,omitempty"` // Enable ?refresh=true parameter, default false
+	StreamKeepAlive           int             `json:"streamKeepAlive,omitempty"`           // Seconds of upstream silence before a keep-alive is sent, default 15, -1 disables
+	StreamIdleTimeout         int             `json:"streamIdleTimeout,omitempty"`         // Seconds a response may go without a successful write, default 600
+	Plugins                   []PluginConfig  `json:"plugins,omitempty"`                   // Out-of-process transformer plugins
 	WebDAV                    *WebDAVConfig   `json:"webdav,omitempty"`                    // WebDAV synchronization config
 	Backup                    *BackupConfig   `json:"backup,omitempty"`                    // Backup/sync configuration
 	Update                    *UpdateConfig   `json:"update,omitempty"`                    // Update configuration
 	Terminal                  *TerminalConfig `json:"terminal,omitempty"`                  // Terminal launcher config
 	Proxy                     *ProxyConfig    `json:"proxy,omitempty"`                     // HTTP proxy config
 	CodexProxy                *ProxyConfig    `json:"codexProxy,omitempty"`                // Codex dedicated proxy config
+	Listen                    *ListenConfig   `json:"listen,omitempty"`                    // Bind address, TLS and Unix socket of the proxy
+	ListenOverride            *ListenConfig   `json:"-"`                                   // CLI forced listener settings, never saved
 	mu                        sync.RWMutex
 }
 
@@ -239,7 +388,32 @@ func (c *Config) Validate() error {
 		if c.Endpoints[i].AuthMode == AuthModeAPIKey && strings.TrimSpace(c.Endpoints[i].APIKey) == "" {
 			return fmt.Errorf("endpoint %d: apiKey is required", i+1)
 		}
+		if err := c.Endpoints[i].Options.Validate(); err != nil {
+			return fmt.Errorf("endpoint %d: %w", i+1, err)
+		}
+		if err := c.Endpoints[i].validateBedrock(); err != nil {
+			return fmt.Errorf("endpoint %d: %w", i+1, err)
+		}
+		if err := c.Endpoints[i].validateVertex(); err != nil {
+			return fmt.Errorf("endpoint %d: %w", i+1, err)
+		}
+		if err := c.Endpoints[i].validateAzure(); err != nil {
+			return fmt.Errorf("endpoint %d: %w", i+1, err)
+		}
+
+	}
+
+	if err := ValidatePlugins(c.Plugins, c.Endpoints); err != nil {
+		return err
+	}
 
+	for _, listen := range []*ListenConfig{c.Listen, c.ListenOverride} {
+		if listen == nil {
+			continue
+		}
+		if err := listen.Validate(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -262,6 +436,38 @@ func (c *Config) GetPort() int {
 	return c.Port
 }
 
+// Streaming defaults
+const (
+	DefaultStreamKeepAlive   = 15 * time.Second
+	DefaultStreamIdleTimeout = 10 * time.Minute
+)
+
+// GetStreamKeepAlive returns how long an upstream may stay silent before a
+// keep-alive is sent to the client, or 0 when keep-alives are disabled
+// (thread-safe)
+func (c *Config) GetStreamKeepAlive() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch {
+	case c.StreamKeepAlive < 0:
+		return 0
+	case c.StreamKeepAlive == 0:
+		return DefaultStreamKeepAlive
+	}
+	return time.Duration(c.StreamKeepAlive) * time.Second
+}
+
+// GetStreamIdleTimeout returns how long a response may go without a
+// successful write before the connection is closed (thread-safe)
+func (c *Config) GetStreamIdleTimeout() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.StreamIdleTimeout <= 0 {
+		return DefaultStreamIdleTimeout
+	}
+	return time.Duration(c.StreamIdleTimeout) * time.Second
+}
+
 // GetLogLevel returns the configured log level (thread-safe)
 func (c *Config) GetLogLevel() int {
 	c.mu.RLock()
@@ -574,6 +780,7 @@ type StorageEndpoint struct {
 	Model       string
 	Remark      string
 	SortOrder   int
+	Options     string // JSON-encoded EndpointOptions
 }
 
 // LoadFromStorage loads configuration from SQLite storage
@@ -588,6 +795,10 @@ func LoadFromStorage(storage StorageAdapter) (*Config, error) {
 	}
 
 	for _, ep := range endpoints {
+		options, err := ParseEndpointOptions(ep.Options)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", ep.Name, err)
+		}
 		endpoint := Endpoint{
 			Name:        ep.Name,
 			APIUrl:      ep.APIUrl,
@@ -597,6 +808,7 @@ func LoadFromStorage(storage StorageAdapter) (*Config, error) {
 			Transformer: ep.Transformer,
 			Model:       ep.Model,
 			Remark:      ep.Remark,
+			Options:     options,
 		}
 		if endpoint.Transformer == "" {
 			endpoint.Transformer = "claude"
@@ -634,6 +846,24 @@ func LoadFromStorage(storage StorageAdapter) (*Config, error) {
 		config.ModelsCacheRefreshEnabled = modelsCacheRefreshEnabledStr == "true"
 	}
 
+	if streamKeepAliveStr, err := storage.GetConfig("streamKeepAlive"); err == nil && streamKeepAliveStr != "" {
+		if streamKeepAlive, err := strconv.Atoi(streamKeepAliveStr); err == nil {
+			config.StreamKeepAlive = streamKeepAlive
+		}
+	}
+	if streamIdleTimeoutStr, err := storage.GetConfig("streamIdleTimeout"); err == nil && streamIdleTimeoutStr != "" {
+		if streamIdleTimeout, err := strconv.Atoi(streamIdleTimeoutStr); err == nil {
+			config.StreamIdleTimeout = streamIdleTimeout
+		}
+	}
+
+	if pluginsStr, err := storage.GetConfig("plugins"); err == nil && pluginsStr != "" {
+		var plugins []PluginConfig
+		if err := json.Unmarshal([]byte(pluginsStr), &plugins); err == nil {
+			config.Plugins = plugins
+		}
+	}
+
 	if lang, err := storage.GetConfig("language"); err == nil {
 		config.Language = lang
 	}
@@ -785,6 +1015,14 @@ func LoadFromStorage(storage StorageAdapter) (*Config, error) {
 		config.Terminal.ClaudeCommand = claudeCmd
 	}
 
+	// Load listener config
+	if listenStr, err := storage.GetConfig("listen"); err == nil && listenStr != "" {
+		var listen ListenConfig
+		if err := json.Unmarshal([]byte(listenStr), &listen); err == nil && listen != (ListenConfig{}) {
+			config.Listen = &listen
+		}
+	}
+
 	// Load Proxy config
 	if proxyURL, err := storage.GetConfig("proxy_url"); err == nil && proxyURL != "" {
 		config.Proxy = &ProxyConfig{URL: proxyURL}
@@ -863,6 +1101,7 @@ func (c *Config) SaveToStorage(storage StorageAdapter) error {
 		endpoint.Model = normalizedEndpoint.Model
 		endpoint.Remark = normalizedEndpoint.Remark
 		endpoint.SortOrder = i
+		endpoint.Options = EncodeEndpointOptions(ep.Options)
 
 		if existingNames[ep.Name] {
 			if err := storage.UpdateEndpoint(endpoint); err != nil {
@@ -896,6 +1135,19 @@ func (c *Config) SaveToStorage(storage StorageAdapter) error {
 	if err := storage.SetConfig("modelsCacheRefreshEnabled", strconv.FormatBool(c.ModelsCacheRefreshEnabled)); err != nil {
 		return fmt.Errorf("failed to save modelsCacheRefreshEnabled config: %w", err)
 	}
+	if err := storage.SetConfig("streamKeepAlive", strconv.Itoa(c.StreamKeepAlive)); err != nil {
+		return fmt.Errorf("failed to save streamKeepAlive config: %w", err)
+	}
+	if err := storage.SetConfig("streamIdleTimeout", strconv.Itoa(c.StreamIdleTimeout)); err != nil {
+		return fmt.Errorf("failed to save streamIdleTimeout config: %w", err)
+	}
+	pluginsJSON, err := json.Marshal(c.Plugins)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugins config: %w", err)
+	}
+	if err := storage.SetConfig("plugins", string(pluginsJSON)); err != nil {
+		return fmt.Errorf("failed to save plugins config: %w", err)
+	}
 	if err := storage.SetConfig("language", c.Language); err != nil {
 		return fmt.Errorf("failed to save language config: %w", err)
 	}
@@ -1013,6 +1265,19 @@ func (c *Config) SaveToStorage(storage StorageAdapter) error {
 		storage.SetConfig("terminal_claudeCommand", c.Terminal.ClaudeCommand)
 	}
 
+	// Save listener config
+	listenJSON := ""
+	if c.Listen != nil {
+		data, err := json.Marshal(c.Listen)
+		if err != nil {
+			return fmt.Errorf("failed to encode listen config: %w", err)
+		}
+		listenJSON = string(data)
+	}
+	if err := storage.SetConfig("listen", listenJSON); err != nil {
+		return fmt.Errorf("failed to save listen config: %w", err)
+	}
+
 	// Save Proxy config
 	proxyURL := ""
 	if c.Proxy != nil {
@@ -0,0 +1,492 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DeclarativeConfig is the config file format of server mode. Sections that
+// are left out stay as they are in storage; a present endpoints list is
+// authoritative, so endpoints missing from it are removed and its order is
+// the failover order. Any string may reference ${env:NAME} or ${file:PATH}.
+type DeclarativeConfig struct {
+	Settings  *DeclarativeSettings   `json:"settings,omitempty"`
+	Plugins   *[]PluginConfig        `json:"plugins,omitempty"`
+	Endpoints *[]DeclarativeEndpoint `json:"endpoints,omitempty"`
+}
+
+// DeclarativeSettings are the server settings a config file may manage
+type DeclarativeSettings struct {
+	Port                      *int                  `json:"port,omitempty"`
+	LogLevel                  *int                  `json:"logLevel,omitempty"`
+	ModelsCacheTTL            *int                  `json:"modelsCacheTTL,omitempty"`
+	ModelsCacheRefreshEnabled *bool                 `json:"modelsCacheRefreshEnabled,omitempty"`
+	StreamKeepAlive           *int                  `json:"streamKeepAlive,omitempty"`
+	StreamIdleTimeout         *int                  `json:"streamIdleTimeout,omitempty"`
+	BasicAuth                 *DeclarativeBasicAuth `json:"basicAuth,omitempty"`
+	Proxy                     *string               `json:"proxy,omitempty"`      // Proxy URL, empty for none
+	CodexProxy                *string               `json:"codexProxy,omitempty"` // Codex proxy URL, empty for none
+}
+
+// DeclarativeBasicAuth guards the web UI and management API
+type DeclarativeBasicAuth struct {
+	Enabled  *bool  `json:"enabled,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// DeclarativeEndpoint is an endpoint in a config file; it is enabled unless
+// it says otherwise
+type DeclarativeEndpoint struct {
+	Name        string           `json:"name"`
+	APIUrl      string           `json:"apiUrl"`
+	APIKey      string           `json:"apiKey,omitempty"`
+	AuthMode    string           `json:"authMode,omitempty"`
+	Enabled     *bool            `json:"enabled,omitempty"`
+	Transformer string           `json:"transformer,omitempty"`
+	Model       string           `json:"model,omitempty"`
+	Remark      string           `json:"remark,omitempty"`
+	Options     *EndpointOptions `json:"options,omitempty"`
+}
+
+var secretRefPattern = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// resolveSecretRefs replaces ${env:NAME} and ${file:PATH} in a string.
+// Relative file paths are resolved against the config file's directory.
+func resolveSecretRefs(value, baseDir string) (string, error) {
+	var resolveErr error
+	resolved := secretRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		match := secretRefPattern.FindStringSubmatch(ref)
+		kind, name := match[1], strings.TrimSpace(match[2])
+		if kind == "env" {
+			envValue, ok := os.LookupEnv(name)
+			if !ok && resolveErr == nil {
+				resolveErr = fmt.Errorf("environment variable %s is not set", name)
+			}
+			return envValue
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(baseDir, name)
+		}
+		data, err := os.ReadFile(name)
+		if err != nil && resolveErr == nil {
+			resolveErr = fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n")
+	})
+	return resolved, resolveErr
+}
+
+// DeclarativeFileRefs returns the files a config file references with
+// ${file:PATH}, resolved against the config file's directory
+func DeclarativeFileRefs(data []byte, baseDir string) []string {
+	var paths []string
+	for _, match := range secretRefPattern.FindAllSubmatch(data, -1) {
+		if string(match[1]) != "file" {
+			continue
+		}
+		name := strings.TrimSpace(string(match[2]))
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(baseDir, name)
+		}
+		paths = append(paths, name)
+	}
+	return paths
+}
+
+// resolveTree resolves secret references in every string of a decoded document
+func resolveTree(node interface{}, baseDir string) (interface{}, error) {
+	switch v := node.(type) {
+	case string:
+		return resolveSecretRefs(v, baseDir)
+	case map[string]interface{}:
+		for key, child := range v {
+			resolved, err := resolveTree(child, baseDir)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		for i, child := range v {
+			resolved, err := resolveTree(child, baseDir)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			v[i] = resolved
+		}
+	}
+	return node, nil
+}
+
+// ParseDeclarativeConfig parses a YAML or JSON config file and resolves its
+// secret references. Unknown fields are rejected so typos do not go unnoticed.
+func ParseDeclarativeConfig(data []byte, baseDir string) (*DeclarativeConfig, error) {
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		return &DeclarativeConfig{}, nil
+	}
+	tree, err := resolveTree(tree, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON so the json tags and option validation apply
+	encoded, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var file DeclarativeConfig
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	if file.Endpoints != nil {
+		seen := map[string]bool{}
+		for i, ep := range *file.Endpoints {
+			if strings.TrimSpace(ep.Name) == "" {
+				return nil, fmt.Errorf("endpoints[%d]: name is required", i)
+			}
+			if seen[ep.Name] {
+				return nil, fmt.Errorf("endpoints[%d]: duplicate name %q", i, ep.Name)
+			}
+			seen[ep.Name] = true
+		}
+	}
+	return &file, nil
+}
+
+// LoadDeclarativeConfig reads and parses a config file
+func LoadDeclarativeConfig(path string) (*DeclarativeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseDeclarativeConfig(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// ApplyTo overwrites the parts of cfg the file manages
+func (d *DeclarativeConfig) ApplyTo(cfg *Config) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	if s := d.Settings; s != nil {
+		if !cfg.PortLocked {
+			setIfPresent(&cfg.Port, s.Port)
+		}
+		setIfPresent(&cfg.LogLevel, s.LogLevel)
+		setIfPresent(&cfg.ModelsCacheTTL, s.ModelsCacheTTL)
+		setIfPresent(&cfg.ModelsCacheRefreshEnabled, s.ModelsCacheRefreshEnabled)
+		setIfPresent(&cfg.StreamKeepAlive, s.StreamKeepAlive)
+		setIfPresent(&cfg.StreamIdleTimeout, s.StreamIdleTimeout)
+		if auth := s.BasicAuth; auth != nil {
+			setIfPresent(&cfg.BasicAuthEnabled, auth.Enabled)
+			if auth.Username != "" {
+				cfg.BasicAuthUsername = auth.Username
+			}
+			if auth.Password != "" {
+				cfg.BasicAuthPassword = auth.Password
+			}
+		}
+		if s.Proxy != nil {
+			cfg.Proxy = &ProxyConfig{URL: *s.Proxy}
+		}
+		if s.CodexProxy != nil {
+			cfg.CodexProxy = &ProxyConfig{URL: *s.CodexProxy}
+		}
+	}
+	if d.Plugins != nil {
+		cfg.Plugins = append([]PluginConfig(nil), (*d.Plugins)...)
+	}
+	if d.Endpoints != nil {
+		endpoints := make([]Endpoint, 0, len(*d.Endpoints))
+		for _, ep := range *d.Endpoints {
+			endpoint := Endpoint{
+				Name:        ep.Name,
+				APIUrl:      strings.TrimSuffix(ep.APIUrl, "/"),
+				APIKey:      ep.APIKey,
+				AuthMode:    NormalizeAuthMode(ep.AuthMode),
+				Enabled:     ep.Enabled == nil || *ep.Enabled,
+				Transformer: ep.Transformer,
+				Model:       ep.Model,
+				Remark:      ep.Remark,
+				Options:     ep.Options,
+			}
+			if endpoint.Transformer == "" {
+				endpoint.Transformer = "claude"
+			}
+			ApplyEndpointAuthModeRules(&endpoint)
+			endpoints = append(endpoints, endpoint)
+		}
+		cfg.Endpoints = endpoints
+	}
+}
+
+func setIfPresent[T any](dst *T, value *T) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+// ReconcileDeclarativeConfig makes storage match the file and returns the
+// resulting config with a description of every change. Nothing is written
+// when the file already matches or the result does not validate.
+func ReconcileDeclarativeConfig(file *DeclarativeConfig, storage StorageAdapter) (*Config, []string, error) {
+	current, err := LoadFromStorage(storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	desired, err := LoadFromStorage(storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	file.ApplyTo(desired)
+
+	changes := DiffConfig(current, desired)
+	if len(changes) == 0 {
+		return current, nil, nil
+	}
+	if err := desired.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := desired.SaveToStorage(storage); err != nil {
+		return nil, nil, err
+	}
+	return desired, changes, nil
+}
+
+// DiffConfig describes how the managed parts of two configs differ. Secrets
+// are reported as changed without their values.
+func DiffConfig(before, after *Config) []string {
+	before.mu.RLock()
+	defer before.mu.RUnlock()
+	after.mu.RLock()
+	defer after.mu.RUnlock()
+
+	var changes []string
+	changed := func(format string, args ...interface{}) {
+		changes = append(changes, fmt.Sprintf(format, args...))
+	}
+	compare := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			changed("%s: %v -> %v", name, a, b)
+		}
+	}
+
+	compare("port", before.Port, after.Port)
+	compare("logLevel", before.LogLevel, after.LogLevel)
+	compare("modelsCacheTTL", before.ModelsCacheTTL, after.ModelsCacheTTL)
+	compare("modelsCacheRefreshEnabled", before.ModelsCacheRefreshEnabled, after.ModelsCacheRefreshEnabled)
+	compare("streamKeepAlive", before.StreamKeepAlive, after.StreamKeepAlive)
+	compare("streamIdleTimeout", before.StreamIdleTimeout, after.StreamIdleTimeout)
+	compare("basicAuth.enabled", before.BasicAuthEnabled, after.BasicAuthEnabled)
+	compare("basicAuth.username", before.BasicAuthUsername, after.BasicAuthUsername)
+	if before.BasicAuthPassword != after.BasicAuthPassword {
+		changed("basicAuth.password changed")
+	}
+	compare("proxy", proxyURL(before.Proxy), proxyURL(after.Proxy))
+	compare("codexProxy", proxyURL(before.CodexProxy), proxyURL(after.CodexProxy))
+
+	oldPlugins := map[string]PluginConfig{}
+	for _, plugin := range before.Plugins {
+		oldPlugins[plugin.Name] = plugin
+	}
+	for _, plugin := range after.Plugins {
+		previous, ok := oldPlugins[plugin.Name]
+		delete(oldPlugins, plugin.Name)
+		if !ok {
+			changed("plugin %q added", plugin.Name)
+		} else if !reflect.DeepEqual(previous, plugin) {
+			changed("plugin %q changed", plugin.Name)
+		}
+	}
+	for _, plugin := range before.Plugins {
+		if _, ok := oldPlugins[plugin.Name]; ok {
+			changed("plugin %q removed", plugin.Name)
+		}
+	}
+
+	oldEndpoints := map[string]Endpoint{}
+	for _, ep := range before.Endpoints {
+		oldEndpoints[ep.Name] = ep
+	}
+	newNames := map[string]bool{}
+	var keptOld, keptNew []string
+	for _, ep := range after.Endpoints {
+		newNames[ep.Name] = true
+		previous, ok := oldEndpoints[ep.Name]
+		if !ok {
+			changed("endpoint %q added", ep.Name)
+			continue
+		}
+		keptNew = append(keptNew, ep.Name)
+		prefix := fmt.Sprintf("endpoint %q: ", ep.Name)
+		compare(prefix+"apiUrl", previous.APIUrl, ep.APIUrl)
+		if previous.APIKey != ep.APIKey {
+			changed("%sapiKey changed", prefix)
+		}
+		compare(prefix+"authMode", previous.AuthMode, ep.AuthMode)
+		compare(prefix+"enabled", previous.Enabled, ep.Enabled)
+		compare(prefix+"transformer", previous.Transformer, ep.Transformer)
+		compare(prefix+"model", previous.Model, ep.Model)
+		compare(prefix+"remark", previous.Remark, ep.Remark)
+		if EncodeEndpointOptions(previous.Options) != EncodeEndpointOptions(ep.Options) {
+			changed("%soptions changed", prefix)
+		}
+	}
+	for _, ep := range before.Endpoints {
+		if !newNames[ep.Name] {
+			changed("endpoint %q removed", ep.Name)
+			continue
+		}
+		keptOld = append(keptOld, ep.Name)
+	}
+	if !reflect.DeepEqual(keptOld, keptNew) {
+		changed("endpoint order: %s -> %s", strings.Join(keptOld, ", "), strings.Join(keptNew, ", "))
+	}
+	return changes
+}
+
+func proxyURL(p *ProxyConfig) string {
+	if p == nil {
+		return ""
+	}
+	return p.URL
+}
+
+// ExportDeclarativeConfig describes cfg in the config file format. Unless
+// secrets are inlined they are replaced by ${env:...} references, named
+// after the endpoint, that have to be set when the file is applied.
+func ExportDeclarativeConfig(cfg *Config, inlineSecrets bool) *DeclarativeConfig {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+
+	secret := func(value, envName string) string {
+		if value == "" || inlineSecrets {
+			return value
+		}
+		return "${env:" + envName + "}"
+	}
+
+	basicAuthEnabled := cfg.BasicAuthEnabled
+	settings := &DeclarativeSettings{
+		Port:                      intPtr(cfg.Port),
+		LogLevel:                  intPtr(cfg.LogLevel),
+		ModelsCacheTTL:            intPtr(cfg.ModelsCacheTTL),
+		ModelsCacheRefreshEnabled: boolPtr(cfg.ModelsCacheRefreshEnabled),
+		StreamKeepAlive:           intPtr(cfg.StreamKeepAlive),
+		StreamIdleTimeout:         intPtr(cfg.StreamIdleTimeout),
+		BasicAuth: &DeclarativeBasicAuth{
+			Enabled:  &basicAuthEnabled,
+			Username: cfg.BasicAuthUsername,
+			Password: secret(cfg.BasicAuthPassword, "CCNEXUS_BASIC_AUTH_PASSWORD"),
+		},
+	}
+	proxy, codexProxy := proxyURL(cfg.Proxy), proxyURL(cfg.CodexProxy)
+	settings.Proxy, settings.CodexProxy = &proxy, &codexProxy
+
+	plugins := append([]PluginConfig{}, cfg.Plugins...)
+	endpoints := make([]DeclarativeEndpoint, 0, len(cfg.Endpoints))
+	for _, ep := range cfg.Endpoints {
+		envPrefix := "CCNEXUS_" + envName(ep.Name) + "_"
+		enabled := ep.Enabled
+		endpoint := DeclarativeEndpoint{
+			Name:        ep.Name,
+			APIUrl:      ep.APIUrl,
+			APIKey:      secret(ep.APIKey, envPrefix+"API_KEY"),
+			AuthMode:    ep.AuthMode,
+			Enabled:     &enabled,
+			Transformer: ep.Transformer,
+			Model:       ep.Model,
+			Remark:      ep.Remark,
+		}
+		if ep.Options != nil {
+			options, _ := ParseEndpointOptions(EncodeEndpointOptions(ep.Options))
+			if b := options.Bedrock; b != nil {
+				b.SecretAccessKey = secret(b.SecretAccessKey, envPrefix+"AWS_SECRET_ACCESS_KEY")
+				b.SessionToken = secret(b.SessionToken, envPrefix+"AWS_SESSION_TOKEN")
+			}
+			if v := options.Vertex; v != nil {
+				v.ServiceAccountKey = secret(v.ServiceAccountKey, envPrefix+"SERVICE_ACCOUNT_KEY")
+			}
+			if a := options.Azure; a != nil {
+				a.ClientSecret = secret(a.ClientSecret, envPrefix+"CLIENT_SECRET")
+			}
+			endpoint.Options = options
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return &DeclarativeConfig{Settings: settings, Plugins: &plugins, Endpoints: &endpoints}
+}
+
+func intPtr(v int) *int    { return &v }
+func boolPtr(v bool) *bool { return &v }
+
+// envName turns an endpoint name into an environment variable name fragment
+func envName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// Marshal encodes the config file as "yaml" or "json"
+func (d *DeclarativeConfig) Marshal(format string) ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil || format == "json" {
+		return append(data, '\n'), err
+	}
+	if format != "yaml" {
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	// JSON is YAML: decoding it into a node keeps the field order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle drops the flow style YAML nodes decoded from JSON carry
+func blockStyle(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+		node.Style = 0
+	} else {
+		node.Style &^= yaml.FlowStyle
+		if node.Style == yaml.DoubleQuotedStyle && !strings.ContainsAny(node.Value, "\n\"\\") {
+			node.Style = 0
+		}
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
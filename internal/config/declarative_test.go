@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memStorage is an in-memory StorageAdapter
+type memStorage struct {
+	endpoints map[string]StorageEndpoint
+	values    map[string]string
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{endpoints: map[string]StorageEndpoint{}, values: map[string]string{}}
+}
+
+func (m *memStorage) GetEndpoints() ([]StorageEndpoint, error) {
+	var endpoints []StorageEndpoint
+	for _, ep := range m.endpoints {
+		endpoints = append(endpoints, ep)
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].SortOrder < endpoints[j].SortOrder })
+	return endpoints, nil
+}
+
+func (m *memStorage) SaveEndpoint(ep *StorageEndpoint) error {
+	if _, ok := m.endpoints[ep.Name]; ok {
+		return fmt.Errorf("endpoint %s exists", ep.Name)
+	}
+	m.endpoints[ep.Name] = *ep
+	return nil
+}
+
+func (m *memStorage) UpdateEndpoint(ep *StorageEndpoint) error {
+	m.endpoints[ep.Name] = *ep
+	return nil
+}
+
+func (m *memStorage) DeleteEndpoint(name string) error {
+	delete(m.endpoints, name)
+	return nil
+}
+
+func (m *memStorage) GetConfig(key string) (string, error) { return m.values[key], nil }
+
+func (m *memStorage) SetConfig(key, value string) error {
+	m.values[key] = value
+	return nil
+}
+
+const testDeclarativeYAML = `
+settings:
+  logLevel: 2
+  basicAuth:
+    password: ${file:admin-password}
+endpoints:
+  - name: Primary
+    apiUrl: https://api.anthropic.com/
+    apiKey: ${env:CCNEXUS_TEST_PRIMARY_KEY}
+  - name: Backup
+    apiUrl: https://api.openai.com
+    apiKey: sk-backup
+    transformer: openai
+    model: gpt-4o
+    enabled: false
+`
+
+func TestParseDeclarativeConfigResolvesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "admin-password"), []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CCNEXUS_TEST_PRIMARY_KEY", "sk-primary")
+
+	file, err := ParseDeclarativeConfig([]byte(testDeclarativeYAML), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := *file.Endpoints
+	if endpoints[0].APIKey != "sk-primary" || file.Settings.BasicAuth.Password != "hunter2" {
+		t.Fatalf("secrets were not resolved: %+v, %+v", endpoints[0], file.Settings.BasicAuth)
+	}
+
+	for name, doc := range map[string]string{
+		"unset variable": `endpoints: [{name: A, apiUrl: x, apiKey: "${env:CCNEXUS_TEST_UNSET}"}]`,
+		"unknown field":  `endpoints: [{name: A, apiUrl: x, apiKey: k, modle: typo}]`,
+		"duplicate name": `endpoints: [{name: A, apiUrl: x, apiKey: k}, {name: A, apiUrl: y, apiKey: k}]`,
+	} {
+		if _, err := ParseDeclarativeConfig([]byte(doc), dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReconcileDeclarativeConfig(t *testing.T) {
+	storage := newMemStorage()
+	seed := DefaultConfig()
+	seed.Endpoints = []Endpoint{
+		{Name: "Backup", APIUrl: "https://api.openai.com", APIKey: "sk-old", Enabled: true, Transformer: "openai", Model: "gpt-4o"},
+		{Name: "Stale", APIUrl: "https://example.com", APIKey: "sk-stale", Enabled: true, Transformer: "claude"},
+	}
+	if err := seed.SaveToStorage(storage); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "admin-password"), []byte("hunter2"), 0600)
+	t.Setenv("CCNEXUS_TEST_PRIMARY_KEY", "sk-primary")
+	file, err := ParseDeclarativeConfig([]byte(testDeclarativeYAML), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, changes, err := ReconcileDeclarativeConfig(file, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := strings.Join(changes, "\n")
+	for _, want := range []string{
+		`logLevel: 1 -> 2`,
+		`basicAuth.password changed`,
+		`endpoint "Primary" added`,
+		`endpoint "Backup": apiKey changed`,
+		`endpoint "Backup": enabled: true -> false`,
+		`endpoint "Stale" removed`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("changes lack %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "sk-") || strings.Contains(log, "hunter2") {
+		t.Errorf("changes leak a secret:\n%s", log)
+	}
+
+	endpoints := cfg.GetEndpoints()
+	if len(endpoints) != 2 || endpoints[0].Name != "Primary" || endpoints[0].APIUrl != "https://api.anthropic.com" || endpoints[1].Enabled {
+		t.Fatalf("unexpected endpoints: %+v", endpoints)
+	}
+	stored, _ := LoadFromStorage(storage)
+	if stored.GetBasicAuthPassword() != "hunter2" || len(stored.GetEndpoints()) != 2 {
+		t.Fatalf("storage was not updated: %+v", stored.GetEndpoints())
+	}
+
+	if _, changes, err := ReconcileDeclarativeConfig(file, storage); err != nil || len(changes) != 0 {
+		t.Fatalf("expected a second reconcile to change nothing, got %v, %v", changes, err)
+	}
+
+	invalid, _ := ParseDeclarativeConfig([]byte(`endpoints: [{name: A, apiUrl: https://x}]`), dir)
+	if _, _, err := ReconcileDeclarativeConfig(invalid, storage); err == nil {
+		t.Fatal("expected an endpoint without apiKey to be rejected")
+	}
+	if len(storage.endpoints) != 2 {
+		t.Fatal("a rejected file must not touch storage")
+	}
+}
+
+func TestExportDeclarativeConfigRoundTrips(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Endpoints = []Endpoint{
+		{Name: "Claude Official", APIUrl: "https://api.anthropic.com", APIKey: "sk-secret", AuthMode: AuthModeAPIKey, Enabled: true, Transformer: "claude"},
+		{Name: "Local", APIUrl: "http://127.0.0.1:11434", APIKey: "none", AuthMode: AuthModeAPIKey, Enabled: false, Transformer: "openai", Model: "qwen3", Remark: "2024",
+			Options: &EndpointOptions{Context: &ContextOptions{Window: 32768}}},
+	}
+	cfg.BasicAuthPassword = "hunter2"
+
+	for _, format := range []string{"yaml", "json"} {
+		data, err := ExportDeclarativeConfig(cfg, false).Marshal(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := string(data)
+		if strings.Contains(text, "sk-secret") || strings.Contains(text, "hunter2") {
+			t.Fatalf("%s export leaks secrets:\n%s", format, text)
+		}
+		if !strings.Contains(text, "${env:CCNEXUS_CLAUDE_OFFICIAL_API_KEY}") {
+			t.Fatalf("%s export lacks the secret reference:\n%s", format, text)
+		}
+
+		t.Setenv("CCNEXUS_CLAUDE_OFFICIAL_API_KEY", "sk-secret")
+		t.Setenv("CCNEXUS_LOCAL_API_KEY", "none")
+		t.Setenv("CCNEXUS_BASIC_AUTH_PASSWORD", "hunter2")
+		file, err := ParseDeclarativeConfig(data, "")
+		if err != nil {
+			t.Fatalf("%s export does not parse: %v\n%s", format, err, text)
+		}
+		restored := DefaultConfig()
+		restored.Endpoints = nil
+		file.ApplyTo(restored)
+		if changes := DiffConfig(cfg, restored); len(changes) != 0 {
+			t.Fatalf("%s export does not round-trip: %v\n%s", format, changes, text)
+		}
+	}
+
+	data, _ := ExportDeclarativeConfig(cfg, true).Marshal("yaml")
+	if !strings.Contains(string(data), "apiKey: sk-secret") || !strings.HasPrefix(string(data), "settings:\n") {
+		t.Fatalf("unexpected inline export:\n%s", data)
+	}
+}
@@ -0,0 +1,346 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/azure"
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+// EndpointOptions holds optional per-endpoint tuning. It is persisted as a
+// single JSON column so new settings do not require schema migrations.
+type EndpointOptions struct {
+	Reasoning *ReasoningOptions `json:"reasoning,omitempty"`
+	Bedrock   *BedrockOptions   `json:"bedrock,omitempty"`
+	Vertex    *VertexOptions    `json:"vertex,omitempty"`
+	Azure     *AzureOptions     `json:"azure,omitempty"`
+	Ollama    *OllamaOptions    `json:"ollama,omitempty"`
+	Auxiliary *AuxiliaryOptions `json:"auxiliary,omitempty"`
+	Context   *ContextOptions   `json:"context,omitempty"`
+	Streaming string            `json:"streaming,omitempty"`
+	Plugin    string            `json:"plugin,omitempty"`
+	Quirks    *QuirkOptions     `json:"quirks,omitempty"`
+	Group     string            `json:"group,omitempty"` // Group client keys can be allowed by, see GroupName
+}
+
+// Upstream streaming support. By default an endpoint follows the client's
+// choice; otherwise the proxy aggregates or synthesizes the stream.
+const (
+	StreamingAlways = "always" // The upstream only streams
+	StreamingNever  = "never"  // The upstream cannot stream
+)
+
+// ReasoningOptions controls how thinking budgets and reasoning effort levels
+// are translated between providers. Zero values fall back to the defaults.
+type ReasoningOptions struct {
+	LowMaxBudget    int    `json:"lowMaxBudget,omitempty"`    // Budgets up to this value map to "low"
+	MediumMaxBudget int    `json:"mediumMaxBudget,omitempty"` // Budgets up to this value map to "medium", above is "high"
+	LowBudget       int    `json:"lowBudget,omitempty"`       // Budget used for "minimal"/"low" effort
+	MediumBudget    int    `json:"mediumBudget,omitempty"`    // Budget used for "medium" effort
+	HighBudget      int    `json:"highBudget,omitempty"`      // Budget used for "high" effort
+	Summary         string `json:"summary,omitempty"`         // Responses API reasoning.summary: auto, concise, detailed
+}
+
+// BedrockOptions configures AWS Bedrock endpoints. With authMode aws_sigv4 the
+// static keys are used when set, otherwise the profile, and without a profile
+// the AWS_* environment variables and the default profile.
+type BedrockOptions struct {
+	Region          string            `json:"region"`                    // AWS region, e.g. us-east-1
+	AccessKeyID     string            `json:"accessKeyId,omitempty"`     // Static access key ID
+	SecretAccessKey string            `json:"secretAccessKey,omitempty"` // Static secret access key
+	SessionToken    string            `json:"sessionToken,omitempty"`    // Optional session token for temporary keys
+	Profile         string            `json:"profile,omitempty"`         // Shared credentials file profile
+	ModelIDs        map[string]string `json:"modelIds,omitempty"`        // Model name -> Bedrock model ID or inference profile overrides
+}
+
+// BedrockRegion returns the configured Bedrock region, or "" when unset
+func (o *EndpointOptions) BedrockRegion() string {
+	if o == nil || o.Bedrock == nil {
+		return ""
+	}
+	return strings.TrimSpace(o.Bedrock.Region)
+}
+
+// VertexOptions configures Google Vertex AI endpoints. Claude models are sent
+// to the Anthropic publisher and all other models to the Google publisher.
+type VertexOptions struct {
+	ProjectID          string            `json:"projectId,omitempty"`          // Defaults to the service account's project
+	Location           string            `json:"location"`                     // Region such as us-east5, or "global"
+	ServiceAccountKey  string            `json:"serviceAccountKey,omitempty"`  // Service account JSON key content
+	ServiceAccountFile string            `json:"serviceAccountFile,omitempty"` // Path to a service account JSON key
+	TokenURL           string            `json:"tokenUrl,omitempty"`           // OAuth token URL, defaults to the key's token_uri
+	ModelIDs           map[string]string `json:"modelIds,omitempty"`           // Model name -> Vertex model ID overrides
+}
+
+// VertexLocation returns the configured Vertex AI location, or "" when unset
+func (o *EndpointOptions) VertexLocation() string {
+	if o == nil || o.Vertex == nil {
+		return ""
+	}
+	return strings.TrimSpace(o.Vertex.Location)
+}
+
+// AzureOptions configures Azure OpenAI for the openai and openai2
+// transformers. Requested models are sent to the deployment mapped here, or
+// to a deployment of the same name.
+type AzureOptions struct {
+	APIVersion   string            `json:"apiVersion,omitempty"`   // api-version query parameter
+	Deployments  map[string]string `json:"deployments,omitempty"`  // Model name -> deployment name
+	TenantID     string            `json:"tenantId,omitempty"`     // Entra ID tenant for authMode entra_id
+	ClientID     string            `json:"clientId,omitempty"`     // Entra ID application (client) ID
+	ClientSecret string            `json:"clientSecret,omitempty"` // Entra ID client secret
+	TokenURL     string            `json:"tokenUrl,omitempty"`     // Overrides the tenant's token endpoint
+}
+
+// AzureAPIVersion returns the configured api-version, or the default
+func (o *EndpointOptions) AzureAPIVersion() string {
+	if o == nil || o.Azure == nil || strings.TrimSpace(o.Azure.APIVersion) == "" {
+		return azure.DefaultAPIVersion
+	}
+	return strings.TrimSpace(o.Azure.APIVersion)
+}
+
+// AzureDeployment returns the deployment that serves a model
+func (o *EndpointOptions) AzureDeployment(model string) string {
+	var deployments map[string]string
+	if o != nil && o.Azure != nil {
+		deployments = o.Azure.Deployments
+	}
+	return azure.Deployment(model, deployments)
+}
+
+// AzureCredentials returns the Entra ID client credentials
+func (o *EndpointOptions) AzureCredentials() azure.ClientCredentials {
+	if o == nil || o.Azure == nil {
+		return azure.ClientCredentials{}
+	}
+	return azure.ClientCredentials{
+		TenantID:     o.Azure.TenantID,
+		ClientID:     o.Azure.ClientID,
+		ClientSecret: o.Azure.ClientSecret,
+		TokenURL:     o.Azure.TokenURL,
+	}
+}
+
+// OllamaOptions configures native Ollama endpoints. Zero values leave the
+// setting to the Ollama server.
+type OllamaOptions struct {
+	NumCtx    int    `json:"numCtx,omitempty"`    // Context window size in tokens (options.num_ctx)
+	KeepAlive string `json:"keepAlive,omitempty"` // How long the model stays loaded, e.g. "10m", "-1" for forever
+}
+
+// AuxiliaryOptions controls which of the OpenAI embeddings, moderations and
+// audio transcription routes an endpoint serves. Unset flags fall back to
+// what the transformer supports.
+type AuxiliaryOptions struct {
+	Embeddings     *bool             `json:"embeddings,omitempty"`
+	Moderations    *bool             `json:"moderations,omitempty"`
+	Transcriptions *bool             `json:"transcriptions,omitempty"`
+	Models         map[string]string `json:"models,omitempty"` // Requested model -> upstream model, "*" matches any model
+}
+
+// AuxiliaryModel returns the upstream model for a requested model on the
+// auxiliary routes
+func (o *EndpointOptions) AuxiliaryModel(model string) string {
+	if o == nil || o.Auxiliary == nil {
+		return model
+	}
+	if mapped := strings.TrimSpace(o.Auxiliary.Models[model]); mapped != "" {
+		return mapped
+	}
+	if mapped := strings.TrimSpace(o.Auxiliary.Models["*"]); mapped != "" {
+		return mapped
+	}
+	return model
+}
+
+// ContextOptions overrides the context window the proxy assumes for the
+// endpoint's models when deciding whether a request fits
+type ContextOptions struct {
+	Window int            `json:"window,omitempty"` // Context window in tokens for all models
+	Models map[string]int `json:"models,omitempty"` // Model name -> context window
+}
+
+// ContextWindow returns the configured context window of a model, or 0 when
+// the endpoint leaves it to the model capabilities table
+func (o *EndpointOptions) ContextWindow(model string) int {
+	if o == nil || o.Context == nil {
+		return 0
+	}
+	if window := o.Context.Models[model]; window > 0 {
+		return window
+	}
+	return o.Context.Window
+}
+
+// StreamingMode returns the upstream streaming support, or "" when the
+// endpoint follows the client
+func (o *EndpointOptions) StreamingMode() string {
+	if o == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(o.Streaming))
+}
+
+// PluginName returns the name of the endpoint's transformer plugin, or ""
+func (o *EndpointOptions) PluginName() string {
+	if o == nil {
+		return ""
+	}
+	return strings.TrimSpace(o.Plugin)
+}
+
+// GroupName returns the group the endpoint belongs to, or ""
+func (o *EndpointOptions) GroupName() string {
+	if o == nil {
+		return ""
+	}
+	return strings.TrimSpace(o.Group)
+}
+
+// QuirkOptions selects how an OpenAI-compatible provider deviates from the
+// OpenAI Chat API: a built-in profile, fields overriding it, or both
+type QuirkOptions struct {
+	Profile          string   `json:"profile,omitempty"`          // Built-in profile: deepseek, qwen, kimi, glm, minimax, openrouter, vllm
+	Reasoning        string   `json:"reasoning,omitempty"`        // Where reasoning is returned: reasoning_content, reasoning, think_tags, none
+	ReasoningRequest string   `json:"reasoningRequest,omitempty"` // How reasoning is requested: reasoning_effort, enable_thinking, thinking, reasoning, none
+	Usage            string   `json:"usage,omitempty"`            // How streams report usage: standard, choice, none
+	ToolCalls        string   `json:"toolCalls,omitempty"`        // How streamed tool calls are identified: standard, index, id
+	MaxTokens        int      `json:"maxTokens,omitempty"`        // Upper bound for the output token limit
+	MaxTokensField   string   `json:"maxTokensField,omitempty"`   // max_tokens or max_completion_tokens
+	DropFields       []string `json:"dropFields,omitempty"`       // Top-level request fields the provider rejects
+}
+
+// QuirkProfile returns the endpoint's quirk profile: the built-in profile
+// with the configured fields applied on top. Unknown profiles are ignored,
+// Validate rejects them.
+func (o *EndpointOptions) QuirkProfile() transformer.QuirkProfile {
+	if o == nil || o.Quirks == nil {
+		return transformer.QuirkProfile{}
+	}
+	q := o.Quirks
+	profile, _ := transformer.BuiltinQuirkProfile(strings.ToLower(strings.TrimSpace(q.Profile)))
+	if q.Reasoning != "" {
+		profile.Reasoning = q.Reasoning
+	}
+	if q.ReasoningRequest != "" {
+		profile.ReasoningRequest = q.ReasoningRequest
+	}
+	if q.Usage != "" {
+		profile.Usage = q.Usage
+	}
+	if q.ToolCalls != "" {
+		profile.ToolCalls = q.ToolCalls
+	}
+	if q.MaxTokens > 0 {
+		profile.MaxTokens = q.MaxTokens
+	}
+	if q.MaxTokensField != "" {
+		profile.MaxTokensField = q.MaxTokensField
+	}
+	profile.DropFields = append(profile.DropFields, q.DropFields...)
+	return profile
+}
+
+// ParseEndpointOptions decodes the stored JSON form of endpoint options.
+// An empty string yields nil options.
+func ParseEndpointOptions(raw string) (*EndpointOptions, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "null" {
+		return nil, nil
+	}
+	var opts EndpointOptions
+	if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+		return nil, fmt.Errorf("invalid endpoint options: %w", err)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+// EncodeEndpointOptions returns the stored JSON form of endpoint options.
+// Nil options encode to an empty string.
+func EncodeEndpointOptions(opts *EndpointOptions) string {
+	if opts == nil {
+		return ""
+	}
+	data, err := json.Marshal(opts)
+	if err != nil || string(data) == "{}" {
+		return ""
+	}
+	return string(data)
+}
+
+// SecretFields returns the credential fields of the options keyed by their
+// JSON path, so they can be masked wherever options are shown
+func (o *EndpointOptions) SecretFields() map[string]*string {
+	fields := map[string]*string{}
+	if o == nil {
+		return fields
+	}
+	if b := o.Bedrock; b != nil {
+		fields["bedrock.secretAccessKey"] = &b.SecretAccessKey
+		fields["bedrock.sessionToken"] = &b.SessionToken
+	}
+	if v := o.Vertex; v != nil {
+		fields["vertex.serviceAccountKey"] = &v.ServiceAccountKey
+	}
+	if a := o.Azure; a != nil {
+		fields["azure.clientSecret"] = &a.ClientSecret
+	}
+	return fields
+}
+
+// Validate checks option values for consistency
+func (o *EndpointOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if r := o.Reasoning; r != nil {
+		if r.LowMaxBudget < 0 || r.MediumMaxBudget < 0 || r.LowBudget < 0 || r.MediumBudget < 0 || r.HighBudget < 0 {
+			return fmt.Errorf("reasoning budgets must not be negative")
+		}
+		if r.LowMaxBudget > 0 && r.MediumMaxBudget > 0 && r.LowMaxBudget >= r.MediumMaxBudget {
+			return fmt.Errorf("reasoning lowMaxBudget must be less than mediumMaxBudget")
+		}
+		switch r.Summary {
+		case "", "auto", "concise", "detailed", "none":
+		default:
+			return fmt.Errorf("invalid reasoning summary: %s", r.Summary)
+		}
+	}
+	if b := o.Bedrock; b != nil {
+		if (strings.TrimSpace(b.AccessKeyID) == "") != (strings.TrimSpace(b.SecretAccessKey) == "") {
+			return fmt.Errorf("bedrock accessKeyId and secretAccessKey must be set together")
+		}
+	}
+	if c := o.Context; c != nil {
+		if c.Window < 0 {
+			return fmt.Errorf("context window must not be negative")
+		}
+		for model, window := range c.Models {
+			if window < 0 {
+				return fmt.Errorf("context window of %s must not be negative", model)
+			}
+		}
+	}
+	switch o.StreamingMode() {
+	case "", StreamingAlways, StreamingNever:
+	default:
+		return fmt.Errorf("invalid streaming mode: %s", o.Streaming)
+	}
+	if q := o.Quirks; q != nil {
+		if name := strings.ToLower(strings.TrimSpace(q.Profile)); name != "" {
+			if _, ok := transformer.BuiltinQuirkProfile(name); !ok {
+				return fmt.Errorf("unknown quirk profile %q, expected one of %v", q.Profile, transformer.BuiltinQuirkProfileNames())
+			}
+		}
+		if err := o.QuirkProfile().Validate(); err != nil {
+			return fmt.Errorf("quirks: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// LoopbackAddress is the bind address that keeps the proxy local to the
+// machine, which the desktop app uses unless another one is set
+const LoopbackAddress = "127.0.0.1"
+
+// ListenConfig controls where and how the proxy accepts connections
+type ListenConfig struct {
+	BindAddress  string `json:"bindAddress,omitempty"`  // Interface to listen on; empty is all interfaces
+	UnixSocket   string `json:"unixSocket,omitempty"`   // Listen on this Unix domain socket instead of TCP
+	TLS          bool   `json:"tls,omitempty"`          // Serve HTTPS
+	CertFile     string `json:"certFile,omitempty"`     // PEM certificate; a self-signed one is generated when empty
+	KeyFile      string `json:"keyFile,omitempty"`      // PEM private key of CertFile
+	ClientCAFile string `json:"clientCAFile,omitempty"` // PEM CAs that client certificates must be signed by (mTLS)
+}
+
+// Validate checks the listener settings
+func (l ListenConfig) Validate() error {
+	if l.BindAddress != "" {
+		if _, _, err := net.SplitHostPort(l.BindAddress); err == nil {
+			return fmt.Errorf("bind address %s must not include a port", l.BindAddress)
+		}
+		if strings.ContainsAny(l.BindAddress, " /") {
+			return fmt.Errorf("invalid bind address: %s", l.BindAddress)
+		}
+	}
+	if (l.CertFile == "") != (l.KeyFile == "") {
+		return fmt.Errorf("TLS certificate and key files must be set together")
+	}
+	if !l.TLS && (l.CertFile != "" || l.ClientCAFile != "") {
+		return fmt.Errorf("TLS certificate files are set but TLS is disabled")
+	}
+	return nil
+}
+
+// URL returns where clients reach the proxy, e.g. https://127.0.0.1:3000
+func (l ListenConfig) URL(port int) string {
+	if l.UnixSocket != "" {
+		return "unix:" + l.UnixSocket
+	}
+	scheme := "http"
+	if l.TLS {
+		scheme = "https"
+	}
+	host := l.BindAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// GetListen returns the listener settings in effect: the CLI override if
+// there is one, otherwise the stored ones (thread-safe)
+func (c *Config) GetListen() ListenConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ListenOverride != nil {
+		return *c.ListenOverride
+	}
+	if c.Listen == nil {
+		return ListenConfig{}
+	}
+	return *c.Listen
+}
+
+// UpdateListen updates the listener settings, which apply on the next start
+// of the proxy (thread-safe)
+func (c *Config) UpdateListen(listen ListenConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if listen == (ListenConfig{}) {
+		c.Listen = nil
+		return
+	}
+	c.Listen = &listen
+}
+
+// OverrideListen replaces the listener settings for this run only; the stored
+// settings stay as they are and are what SaveToStorage writes (thread-safe)
+func (c *Config) OverrideListen(listen ListenConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ListenOverride = &listen
+}
@@ -0,0 +1,54 @@
+package config
+
+import "testing"
+
+func TestListenConfigValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		listen ListenConfig
+		valid  bool
+	}{
+		{"default", ListenConfig{}, true},
+		{"loopback", ListenConfig{BindAddress: LoopbackAddress}, true},
+		{"ipv6", ListenConfig{BindAddress: "::1"}, true},
+		{"with port", ListenConfig{BindAddress: "127.0.0.1:3000"}, false},
+		{"self-signed", ListenConfig{TLS: true}, true},
+		{"cert without key", ListenConfig{TLS: true, CertFile: "server.crt"}, false},
+		{"cert without TLS", ListenConfig{CertFile: "server.crt", KeyFile: "server.key"}, false},
+		{"mTLS", ListenConfig{TLS: true, ClientCAFile: "ca.crt"}, true},
+	}
+	for _, tc := range cases {
+		if err := tc.listen.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestListenConfigURL(t *testing.T) {
+	if got := (ListenConfig{}).URL(3000); got != "http://localhost:3000" {
+		t.Errorf("got %s", got)
+	}
+	if got := (ListenConfig{BindAddress: "::1", TLS: true}).URL(3000); got != "https://[::1]:3000" {
+		t.Errorf("got %s", got)
+	}
+	if got := (ListenConfig{UnixSocket: "/run/ccnexus.sock"}).URL(3000); got != "unix:/run/ccnexus.sock" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestOverrideListenIsNotSaved(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.UpdateListen(ListenConfig{BindAddress: LoopbackAddress})
+	cfg.OverrideListen(ListenConfig{BindAddress: "0.0.0.0", TLS: true})
+	if got := cfg.GetListen(); got.BindAddress != "0.0.0.0" || !got.TLS {
+		t.Fatalf("override must be in effect, got %+v", got)
+	}
+
+	storage := newMemStorage()
+	if err := cfg.SaveToStorage(storage); err != nil {
+		t.Fatal(err)
+	}
+	if saved := storage.values["listen"]; saved != `{"bindAddress":"127.0.0.1"}` {
+		t.Fatalf("only the stored settings must be saved, got %s", saved)
+	}
+}
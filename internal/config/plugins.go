@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultPluginTimeout bounds a single plugin call
+const DefaultPluginTimeout = 10 * time.Second
+
+// PluginConfig declares an out-of-process transformer plugin. Exactly one of
+// Command and WASM is set. Endpoints use a plugin through options.plugin.
+type PluginConfig struct {
+	Name    string            `json:"name"`
+	Command string            `json:"command,omitempty"` // Executable speaking JSON-RPC over stdio
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`     // Added to the proxy's environment
+	Dir     string            `json:"dir,omitempty"`     // Working directory
+	WASM    string            `json:"wasm,omitempty"`    // Path to a WASI module
+	Timeout int               `json:"timeout,omitempty"` // Seconds per call, default 10
+	Config  json.RawMessage   `json:"config,omitempty"`  // Sent to the plugin on initialize
+
+	Disabled bool `json:"disabled,omitempty"` // Not started; endpoints using it fail their attempts
+}
+
+// CallTimeout returns how long a single call may take
+func (p PluginConfig) CallTimeout() time.Duration {
+	if p.Timeout <= 0 {
+		return DefaultPluginTimeout
+	}
+	return time.Duration(p.Timeout) * time.Second
+}
+
+// Validate checks the plugin declaration
+func (p PluginConfig) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("plugin name is required")
+	}
+	hasCommand := strings.TrimSpace(p.Command) != ""
+	hasWASM := strings.TrimSpace(p.WASM) != ""
+	if hasCommand == hasWASM {
+		return fmt.Errorf("plugin %s: exactly one of command and wasm must be set", p.Name)
+	}
+	if p.Timeout < 0 {
+		return fmt.Errorf("plugin %s: timeout must not be negative", p.Name)
+	}
+	if len(p.Config) > 0 && !json.Valid(p.Config) {
+		return fmt.Errorf("plugin %s: config is not valid JSON", p.Name)
+	}
+	return nil
+}
+
+// ValidatePlugins checks plugin declarations and that every endpoint refers
+// to a declared plugin
+func ValidatePlugins(plugins []PluginConfig, endpoints []Endpoint) error {
+	names := make(map[string]bool, len(plugins))
+	for _, plugin := range plugins {
+		if err := plugin.Validate(); err != nil {
+			return err
+		}
+		if names[plugin.Name] {
+			return fmt.Errorf("duplicate plugin name: %s", plugin.Name)
+		}
+		names[plugin.Name] = true
+	}
+	for _, endpoint := range endpoints {
+		if name := endpoint.Options.PluginName(); name != "" && !names[name] {
+			return fmt.Errorf("endpoint %s uses unknown plugin %s", endpoint.Name, name)
+		}
+	}
+	return nil
+}
+
+// GetPlugins returns a copy of the plugin declarations (thread-safe)
+func (c *Config) GetPlugins() []PluginConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	plugins := make([]PluginConfig, len(c.Plugins))
+	copy(plugins, c.Plugins)
+	return plugins
+}
+
+// UpdatePlugins replaces the plugin declarations (thread-safe)
+func (c *Config) UpdatePlugins(plugins []PluginConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Plugins = plugins
+}
@@ -0,0 +1,85 @@
+package mockupstream
+
+// claudeMessage renders a behavior as a Claude Messages response
+func claudeMessage(b Behavior, model string) map[string]interface{} {
+	content := []map[string]interface{}{}
+	if b.Thinking != "" {
+		content = append(content, map[string]interface{}{"type": "thinking", "thinking": b.Thinking, "signature": "mock-signature"})
+	}
+	if text := b.text(); text != "" {
+		content = append(content, map[string]interface{}{"type": "text", "text": text})
+	}
+	for _, call := range b.toolCalls() {
+		content = append(content, map[string]interface{}{"type": "tool_use", "id": call.ID, "name": call.Name, "input": call.Arguments})
+	}
+	input, output := b.usage()
+	return map[string]interface{}{
+		"id":            "msg_mock",
+		"type":          "message",
+		"role":          "assistant",
+		"model":         model,
+		"content":       content,
+		"stop_reason":   b.stopReason(),
+		"stop_sequence": nil,
+		"usage":         map[string]interface{}{"input_tokens": input, "output_tokens": output},
+	}
+}
+
+// claudeStream renders a behavior as a Claude Messages event stream
+func claudeStream(b Behavior, model string) []string {
+	input, output := b.usage()
+	events := []string{sse("message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id": "msg_mock", "type": "message", "role": "assistant", "model": model, "content": []interface{}{},
+			"stop_reason": nil, "stop_sequence": nil,
+			"usage": map[string]interface{}{"input_tokens": input, "output_tokens": 0},
+		},
+	})}
+
+	index := 0
+	block := func(start map[string]interface{}, deltas []map[string]interface{}) {
+		events = append(events, sse("content_block_start", map[string]interface{}{
+			"type": "content_block_start", "index": index, "content_block": start,
+		}))
+		for _, delta := range deltas {
+			events = append(events, sse("content_block_delta", map[string]interface{}{
+				"type": "content_block_delta", "index": index, "delta": delta,
+			}))
+		}
+		events = append(events, sse("content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": index}))
+		index++
+	}
+
+	if b.Thinking != "" {
+		var deltas []map[string]interface{}
+		for _, word := range words(b.Thinking) {
+			deltas = append(deltas, map[string]interface{}{"type": "thinking_delta", "thinking": word})
+		}
+		deltas = append(deltas, map[string]interface{}{"type": "signature_delta", "signature": "mock-signature"})
+		block(map[string]interface{}{"type": "thinking", "thinking": ""}, deltas)
+	}
+	if text := b.text(); text != "" {
+		var deltas []map[string]interface{}
+		for _, word := range words(text) {
+			deltas = append(deltas, map[string]interface{}{"type": "text_delta", "text": word})
+		}
+		block(map[string]interface{}{"type": "text", "text": ""}, deltas)
+	}
+	for _, call := range b.toolCalls() {
+		block(
+			map[string]interface{}{"type": "tool_use", "id": call.ID, "name": call.Name, "input": map[string]interface{}{}},
+			[]map[string]interface{}{{"type": "input_json_delta", "partial_json": call.arguments()}},
+		)
+	}
+
+	events = append(events,
+		sse("message_delta", map[string]interface{}{
+			"type":  "message_delta",
+			"delta": map[string]interface{}{"stop_reason": b.stopReason(), "stop_sequence": nil},
+			"usage": map[string]interface{}{"output_tokens": output},
+		}),
+		sse("message_stop", map[string]interface{}{"type": "message_stop"}),
+	)
+	return events
+}
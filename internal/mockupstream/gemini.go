@@ -0,0 +1,94 @@
+package mockupstream
+
+import "strings"
+
+// geminiModel reads the model from a .../models/<model>:<method> path
+func geminiModel(path string) string {
+	idx := strings.LastIndex(path, "/models/")
+	if idx < 0 {
+		return ""
+	}
+	model := path[idx+len("/models/"):]
+	if colon := strings.Index(model, ":"); colon >= 0 {
+		model = model[:colon]
+	}
+	return model
+}
+
+func geminiFinishReason(stopReason string) string {
+	if stopReason == "max_tokens" {
+		return "MAX_TOKENS"
+	}
+	return "STOP"
+}
+
+func geminiUsage(b Behavior) map[string]interface{} {
+	input, output := b.usage()
+	return map[string]interface{}{"promptTokenCount": input, "candidatesTokenCount": output, "totalTokenCount": input + output}
+}
+
+func geminiCandidate(parts []map[string]interface{}, finishReason string) map[string]interface{} {
+	candidate := map[string]interface{}{
+		"content": map[string]interface{}{"role": "model", "parts": parts},
+		"index":   0,
+	}
+	if finishReason != "" {
+		candidate["finishReason"] = finishReason
+	}
+	return candidate
+}
+
+func geminiCallParts(b Behavior) []map[string]interface{} {
+	var parts []map[string]interface{}
+	for _, call := range b.toolCalls() {
+		parts = append(parts, map[string]interface{}{
+			"functionCall": map[string]interface{}{"id": call.ID, "name": call.Name, "args": call.Arguments},
+		})
+	}
+	return parts
+}
+
+// geminiResponse renders a behavior as a generateContent response
+func geminiResponse(b Behavior, model string) map[string]interface{} {
+	parts := []map[string]interface{}{}
+	if b.Thinking != "" {
+		parts = append(parts, map[string]interface{}{"text": b.Thinking, "thought": true})
+	}
+	if text := b.text(); text != "" {
+		parts = append(parts, map[string]interface{}{"text": text})
+	}
+	parts = append(parts, geminiCallParts(b)...)
+	return map[string]interface{}{
+		"candidates":    []map[string]interface{}{geminiCandidate(parts, geminiFinishReason(b.stopReason()))},
+		"usageMetadata": geminiUsage(b),
+		"modelVersion":  model,
+	}
+}
+
+// geminiStream renders a behavior as a streamGenerateContent?alt=sse stream.
+// The last chunk carries the function calls, finish reason and usage.
+func geminiStream(b Behavior, model string) []string {
+	var events []string
+	chunk := func(parts []map[string]interface{}) {
+		events = append(events, dataEvent(map[string]interface{}{
+			"candidates":   []map[string]interface{}{geminiCandidate(parts, "")},
+			"modelVersion": model,
+		}))
+	}
+	for _, word := range words(b.Thinking) {
+		chunk([]map[string]interface{}{{"text": word, "thought": true}})
+	}
+	for _, word := range words(b.text()) {
+		chunk([]map[string]interface{}{{"text": word}})
+	}
+
+	parts := geminiCallParts(b)
+	if len(parts) == 0 {
+		parts = []map[string]interface{}{{"text": ""}}
+	}
+	return append(events, dataEvent(map[string]interface{}{
+		"candidates":    []map[string]interface{}{geminiCandidate(parts, geminiFinishReason(b.stopReason()))},
+		"usageMetadata": geminiUsage(b),
+		"modelVersion":  model,
+	}))
+}
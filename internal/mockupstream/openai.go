@@ -0,0 +1,81 @@
+package mockupstream
+
+import "time"
+
+// chatFinishReason maps a Claude stop reason to Chat Completions
+func chatFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func chatToolCalls(calls []ToolCall) []map[string]interface{} {
+	var out []map[string]interface{}
+	for i, call := range calls {
+		out = append(out, map[string]interface{}{
+			"index": i, "id": call.ID, "type": "function",
+			"function": map[string]interface{}{"name": call.Name, "arguments": call.arguments()},
+		})
+	}
+	return out
+}
+
+// chatCompletion renders a behavior as a Chat Completions response
+func chatCompletion(b Behavior, model string) map[string]interface{} {
+	message := map[string]interface{}{"role": "assistant", "content": nil}
+	if text := b.text(); text != "" {
+		message["content"] = text
+	}
+	if b.Thinking != "" {
+		message["reasoning_content"] = b.Thinking
+	}
+	if calls := b.toolCalls(); len(calls) > 0 {
+		message["tool_calls"] = chatToolCalls(calls)
+	}
+	input, output := b.usage()
+	return map[string]interface{}{
+		"id":      "chatcmpl-mock",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]interface{}{{"index": 0, "message": message, "finish_reason": chatFinishReason(b.stopReason())}},
+		"usage":   map[string]interface{}{"prompt_tokens": input, "completion_tokens": output, "total_tokens": input + output},
+	}
+}
+
+// chatStream renders a behavior as a Chat Completions chunk stream. Usage
+// comes in a chunk of its own when the client asked for it.
+func chatStream(b Behavior, model string, includeUsage bool) []string {
+	created := time.Now().Unix()
+	chunk := func(delta map[string]interface{}, finishReason interface{}) string {
+		return dataEvent(map[string]interface{}{
+			"id": "chatcmpl-mock", "object": "chat.completion.chunk", "created": created, "model": model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		})
+	}
+
+	events := []string{chunk(map[string]interface{}{"role": "assistant", "content": ""}, nil)}
+	for _, word := range words(b.Thinking) {
+		events = append(events, chunk(map[string]interface{}{"reasoning_content": word}, nil))
+	}
+	for _, word := range words(b.text()) {
+		events = append(events, chunk(map[string]interface{}{"content": word}, nil))
+	}
+	for _, call := range chatToolCalls(b.toolCalls()) {
+		events = append(events, chunk(map[string]interface{}{"tool_calls": []map[string]interface{}{call}}, nil))
+	}
+	events = append(events, chunk(map[string]interface{}{}, chatFinishReason(b.stopReason())))
+	if includeUsage {
+		input, output := b.usage()
+		events = append(events, dataEvent(map[string]interface{}{
+			"id": "chatcmpl-mock", "object": "chat.completion.chunk", "created": created, "model": model,
+			"choices": []interface{}{},
+			"usage":   map[string]interface{}{"prompt_tokens": input, "completion_tokens": output, "total_tokens": input + output},
+		}))
+	}
+	return append(events, "data: [DONE]\n\n")
+}
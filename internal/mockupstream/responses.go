@@ -0,0 +1,181 @@
+package mockupstream
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// responsesOutput renders a behavior as Responses API output items
+func responsesOutput(b Behavior) []map[string]interface{} {
+	var output []map[string]interface{}
+	if b.Thinking != "" {
+		output = append(output, map[string]interface{}{
+			"type": "reasoning", "id": "rs_mock",
+			"summary": []map[string]interface{}{{"type": "summary_text", "text": b.Thinking}},
+		})
+	}
+	if text := b.text(); text != "" {
+		output = append(output, map[string]interface{}{
+			"type": "message", "id": "msg_mock", "status": "completed", "role": "assistant",
+			"content": []map[string]interface{}{{"type": "output_text", "text": text, "annotations": []interface{}{}}},
+		})
+	}
+	for i, call := range b.toolCalls() {
+		output = append(output, map[string]interface{}{
+			"type": "function_call", "id": fmt.Sprintf("fc_mock_%d", i), "call_id": call.ID,
+			"name": call.Name, "arguments": call.arguments(), "status": "completed",
+		})
+	}
+	return output
+}
+
+// responsesResponse renders a behavior as a Responses API response
+func responsesResponse(b Behavior, model string) map[string]interface{} {
+	input, output := b.usage()
+	resp := map[string]interface{}{
+		"id":         "resp_mock",
+		"object":     "response",
+		"created_at": time.Now().Unix(),
+		"status":     "completed",
+		"model":      model,
+		"output":     responsesOutput(b),
+		"usage":      map[string]interface{}{"input_tokens": input, "output_tokens": output, "total_tokens": input + output},
+	}
+	if b.stopReason() == "max_tokens" {
+		resp["status"] = "incomplete"
+		resp["incomplete_details"] = map[string]interface{}{"reason": "max_output_tokens"}
+	}
+	return resp
+}
+
+// responsesStream renders a behavior as a Responses API event stream. The
+// Codex backend also reports its rate limits in a codex.rate_limits event.
+func responsesStream(b Behavior, model string, codex bool) []string {
+	final := responsesResponse(b, model)
+	sequence := 0
+	var events []string
+	emit := func(data map[string]interface{}) {
+		data["sequence_number"] = sequence
+		sequence++
+		events = append(events, sse(data["type"].(string), data))
+	}
+
+	emit(map[string]interface{}{"type": "response.created", "response": map[string]interface{}{
+		"id": "resp_mock", "object": "response", "created_at": final["created_at"], "status": "in_progress",
+		"model": model, "output": []interface{}{},
+	}})
+	if codex && b.RateLimits != nil {
+		emit(codexRateLimitEvent(b.RateLimits))
+	}
+
+	for index, item := range final["output"].([]map[string]interface{}) {
+		added := map[string]interface{}{}
+		for key, value := range item {
+			added[key] = value
+		}
+		added["status"] = "in_progress"
+		switch item["type"] {
+		case "reasoning":
+			added["summary"] = []interface{}{}
+		case "message":
+			added["content"] = []interface{}{}
+		case "function_call":
+			added["arguments"] = ""
+		}
+		emit(map[string]interface{}{"type": "response.output_item.added", "output_index": index, "item": added})
+
+		itemID := item["id"]
+		switch item["type"] {
+		case "reasoning":
+			for _, word := range words(b.Thinking) {
+				emit(map[string]interface{}{"type": "response.reasoning_summary_text.delta", "item_id": itemID, "output_index": index, "summary_index": 0, "delta": word})
+			}
+			emit(map[string]interface{}{"type": "response.reasoning_summary_text.done", "item_id": itemID, "output_index": index, "summary_index": 0, "text": b.Thinking})
+		case "message":
+			text := b.text()
+			emit(map[string]interface{}{"type": "response.content_part.added", "item_id": itemID, "output_index": index, "content_index": 0,
+				"part": map[string]interface{}{"type": "output_text", "text": "", "annotations": []interface{}{}}})
+			for _, word := range words(text) {
+				emit(map[string]interface{}{"type": "response.output_text.delta", "item_id": itemID, "output_index": index, "content_index": 0, "delta": word})
+			}
+			emit(map[string]interface{}{"type": "response.output_text.done", "item_id": itemID, "output_index": index, "content_index": 0, "text": text})
+			emit(map[string]interface{}{"type": "response.content_part.done", "item_id": itemID, "output_index": index, "content_index": 0,
+				"part": map[string]interface{}{"type": "output_text", "text": text, "annotations": []interface{}{}}})
+		case "function_call":
+			emit(map[string]interface{}{"type": "response.function_call_arguments.delta", "item_id": itemID, "output_index": index, "delta": item["arguments"]})
+			emit(map[string]interface{}{"type": "response.function_call_arguments.done", "item_id": itemID, "output_index": index, "arguments": item["arguments"]})
+		}
+		emit(map[string]interface{}{"type": "response.output_item.done", "output_index": index, "item": item})
+	}
+
+	terminal := "response.completed"
+	if final["status"] == "incomplete" {
+		terminal = "response.incomplete"
+	}
+	emit(map[string]interface{}{"type": terminal, "response": final})
+	return events
+}
+
+// setRateLimitHeaders reports Codex rate limits the way the Codex backend
+// does on every response
+func setRateLimitHeaders(h http.Header, limits *RateLimits) {
+	h.Set("x-codex-primary-used-percent", fmt.Sprint(limits.PrimaryUsedPercent))
+	h.Set("x-codex-secondary-used-percent", fmt.Sprint(limits.SecondaryUsedPercent))
+	if limits.PrimaryWindowMinutes > 0 {
+		h.Set("x-codex-primary-window-minutes", fmt.Sprint(limits.PrimaryWindowMinutes))
+	}
+	if limits.PrimaryResetAt > 0 {
+		h.Set("x-codex-primary-reset-at", fmt.Sprint(limits.PrimaryResetAt))
+	}
+	if limits.SecondaryWindowMinutes > 0 {
+		h.Set("x-codex-secondary-window-minutes", fmt.Sprint(limits.SecondaryWindowMinutes))
+	}
+	if limits.SecondaryResetAt > 0 {
+		h.Set("x-codex-secondary-reset-at", fmt.Sprint(limits.SecondaryResetAt))
+	}
+}
+
+func codexRateLimitEvent(limits *RateLimits) map[string]interface{} {
+	window := func(used float64, minutes, resetAt int64) map[string]interface{} {
+		w := map[string]interface{}{"used_percent": used}
+		if minutes > 0 {
+			w["window_minutes"] = minutes
+		}
+		if resetAt > 0 {
+			w["reset_at"] = resetAt
+		}
+		return w
+	}
+	return map[string]interface{}{
+		"type":      "codex.rate_limits",
+		"plan_type": limits.PlanType,
+		"rate_limits": map[string]interface{}{
+			"primary":   window(limits.PrimaryUsedPercent, limits.PrimaryWindowMinutes, limits.PrimaryResetAt),
+			"secondary": window(limits.SecondaryUsedPercent, limits.SecondaryWindowMinutes, limits.SecondaryResetAt),
+		},
+	}
+}
+
+// serveCodexUsage answers the Codex usage API with the default behavior's
+// rate limits
+func serveCodexUsage(w http.ResponseWriter, limits *RateLimits) {
+	if limits == nil {
+		limits = &RateLimits{}
+	}
+	window := func(used float64, minutes, resetAt int64) map[string]interface{} {
+		return map[string]interface{}{
+			"used_percent":         used,
+			"limit_window_seconds": minutes * 60,
+			"reset_at":             resetAt,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"plan_type": limits.PlanType,
+		"rate_limit": map[string]interface{}{
+			"primary_window":   window(limits.PrimaryUsedPercent, limits.PrimaryWindowMinutes, limits.PrimaryResetAt),
+			"secondary_window": window(limits.SecondaryUsedPercent, limits.SecondaryWindowMinutes, limits.SecondaryResetAt),
+		},
+		"credits": map[string]interface{}{"has_credits": true, "unlimited": false},
+	})
+}
@@ -0,0 +1,131 @@
+// Package mockupstream serves fake Claude Messages, OpenAI Chat Completions,
+// OpenAI Responses, Codex backend and Gemini APIs for offline testing. What
+// each request gets back is scripted: canned replies, tool calls, latency,
+// mid-stream disconnects, error statuses and Codex rate-limit headers.
+package mockupstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// DefaultText is the reply of a behavior without text or tool calls
+const DefaultText = "Hello from the mock upstream."
+
+// Script tells the mock how to answer generation requests. Steps are used
+// once each, in order; once they run out every request gets Default.
+type Script struct {
+	Default Behavior   `json:"default"`
+	Steps   []Behavior `json:"steps,omitempty"`
+	APIKey  string     `json:"apiKey,omitempty"` // Only key accepted when set, other requests get 401
+	Models  []string   `json:"models,omitempty"` // Served by the model listing APIs, default mock-model
+}
+
+// Behavior is one scripted answer
+type Behavior struct {
+	Text         string     `json:"text,omitempty"`         // Reply text
+	Thinking     string     `json:"thinking,omitempty"`     // Reasoning sent before the reply
+	ToolCalls    []ToolCall `json:"toolCalls,omitempty"`    // Parallel tool calls after the reply
+	StopReason   string     `json:"stopReason,omitempty"`   // end_turn, max_tokens or tool_use; derived when empty
+	InputTokens  int        `json:"inputTokens,omitempty"`  // Reported usage, default 10
+	OutputTokens int        `json:"outputTokens,omitempty"` // Reported usage, default 5
+
+	Status     int               `json:"status,omitempty"`     // Error status answered in the API's error format
+	Message    string            `json:"message,omitempty"`    // Error message
+	RetryAfter int               `json:"retryAfter,omitempty"` // Retry-After seconds sent with the error
+	Headers    map[string]string `json:"headers,omitempty"`    // Extra response headers
+
+	LatencyMs       int `json:"latencyMs,omitempty"`       // Delay before the response headers
+	ChunkDelayMs    int `json:"chunkDelayMs,omitempty"`    // Delay between stream events
+	DisconnectAfter int `json:"disconnectAfter,omitempty"` // Stream events sent before the connection is dropped
+
+	RateLimits *RateLimits `json:"rateLimits,omitempty"` // Codex rate-limit headers and event
+}
+
+// ToolCall is a function call the mock model makes
+type ToolCall struct {
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// RateLimits is the Codex rate-limit state reported in x-codex-* headers, the
+// codex.rate_limits stream event and the usage API
+type RateLimits struct {
+	PlanType               string  `json:"planType,omitempty"`
+	PrimaryUsedPercent     float64 `json:"primaryUsedPercent"`
+	PrimaryWindowMinutes   int64   `json:"primaryWindowMinutes,omitempty"`
+	PrimaryResetAt         int64   `json:"primaryResetAt,omitempty"`
+	SecondaryUsedPercent   float64 `json:"secondaryUsedPercent"`
+	SecondaryWindowMinutes int64   `json:"secondaryWindowMinutes,omitempty"`
+	SecondaryResetAt       int64   `json:"secondaryResetAt,omitempty"`
+}
+
+// LoadScript reads a script from a JSON file
+func LoadScript(path string) (Script, error) {
+	var script Script
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return script, err
+	}
+	if err := json.Unmarshal(data, &script); err != nil {
+		return script, fmt.Errorf("parse script %s: %w", path, err)
+	}
+	return script, nil
+}
+
+func (b Behavior) text() string {
+	if b.Text == "" && b.Thinking == "" && len(b.ToolCalls) == 0 {
+		return DefaultText
+	}
+	return b.Text
+}
+
+func (b Behavior) stopReason() string {
+	if b.StopReason != "" {
+		return b.StopReason
+	}
+	if len(b.ToolCalls) > 0 {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+func (b Behavior) usage() (input, output int) {
+	input, output = b.InputTokens, b.OutputTokens
+	if input == 0 {
+		input = 10
+	}
+	if output == 0 {
+		output = 5
+	}
+	return input, output
+}
+
+// toolCalls returns the tool calls with IDs filled in
+func (b Behavior) toolCalls() []ToolCall {
+	calls := make([]ToolCall, len(b.ToolCalls))
+	for i, call := range b.ToolCalls {
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_mock_%d", i)
+		}
+		if call.Arguments == nil {
+			call.Arguments = map[string]interface{}{}
+		}
+		calls[i] = call
+	}
+	return calls
+}
+
+func (c ToolCall) arguments() string {
+	data, _ := json.Marshal(c.Arguments)
+	return string(data)
+}
+
+func (s Script) models() []string {
+	if len(s.Models) == 0 {
+		return []string{"mock-model"}
+	}
+	return s.Models
+}
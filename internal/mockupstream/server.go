@@ -0,0 +1,429 @@
+package mockupstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIs the mock serves
+const (
+	APIClaude    = "claude"
+	APIOpenAI    = "openai"
+	APIResponses = "responses"
+	APICodex     = "codex"
+	APIGemini    = "gemini"
+)
+
+// Operations within an API
+const (
+	opGenerate    = "generate"
+	opCountTokens = "count_tokens"
+	opModels      = "models"
+	opToken       = "token"
+	opUsage       = "usage"
+)
+
+// Request is a request the mock received
+type Request struct {
+	API    string
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Server is an http.Handler serving the fake APIs
+type Server struct {
+	mu       sync.Mutex
+	script   Script
+	next     int
+	requests []Request
+	tokens   map[string]bool // Access tokens issued by the OAuth endpoint
+}
+
+// New creates a mock upstream following a script
+func New(script Script) *Server {
+	return &Server{script: script, tokens: make(map[string]bool)}
+}
+
+// SetScript replaces the script and restarts its steps
+func (s *Server) SetScript(script Script) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = script
+	s.next = 0
+}
+
+// Enqueue appends steps to the script
+func (s *Server) Enqueue(steps ...Behavior) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script.Steps = append(s.script.Steps, steps...)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// nextBehavior returns the behavior for the next generation request
+func (s *Server) nextBehavior() Behavior {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next < len(s.script.Steps) {
+		s.next++
+		return s.script.Steps[s.next-1]
+	}
+	return s.script.Default
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	api, op := route(r)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		API: api, Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: body,
+	})
+	script := s.script
+	s.mu.Unlock()
+
+	switch op {
+	case "":
+		writeError(w, api, http.StatusNotFound, "no mock for "+r.Method+" "+r.URL.Path)
+		return
+	case opToken:
+		s.serveToken(w, r, body)
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, api, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+
+	switch op {
+	case opModels:
+		serveModels(w, api, script.models())
+	case opUsage:
+		serveCodexUsage(w, script.Default.RateLimits)
+	case opCountTokens:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"input_tokens": len(body)/4 + 1})
+	case opGenerate:
+		s.generate(w, r, api, body, s.nextBehavior())
+	}
+}
+
+// route maps a request path to the API and operation it belongs to. Paths are
+// matched by suffix so base URLs with a prefix work too.
+func route(r *http.Request) (api, op string) {
+	p := r.URL.Path
+	switch {
+	case strings.HasSuffix(p, "/oauth/token"):
+		return "", opToken
+	case strings.HasSuffix(p, "/wham/usage") || strings.HasSuffix(p, "/api/codex/usage"):
+		return APICodex, opUsage
+	case strings.Contains(p, ":generateContent") || strings.Contains(p, ":streamGenerateContent"):
+		return APIGemini, opGenerate
+	case strings.HasSuffix(p, "/messages/count_tokens"):
+		return APIClaude, opCountTokens
+	case strings.HasSuffix(p, "/messages"):
+		return APIClaude, opGenerate
+	case strings.HasSuffix(p, "/chat/completions"):
+		return APIOpenAI, opGenerate
+	case strings.Contains(p, "/backend-api/codex/") && strings.HasSuffix(p, "/responses"):
+		return APICodex, opGenerate
+	case strings.HasSuffix(p, "/responses"):
+		return APIResponses, opGenerate
+	case r.Method == http.MethodGet && strings.Contains(p, "/v1beta/models"):
+		return APIGemini, opModels
+	case r.Method == http.MethodGet && strings.HasSuffix(p, "/models"):
+		return APIOpenAI, opModels
+	}
+	return "", ""
+}
+
+// authorized checks the request's key against the script's API key and the
+// tokens issued by the OAuth endpoint
+func (s *Server) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.script.APIKey == "" {
+		return true
+	}
+	for _, key := range []string{
+		r.Header.Get("x-api-key"),
+		r.Header.Get("api-key"),
+		r.Header.Get("x-goog-api-key"),
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		r.URL.Query().Get("key"),
+	} {
+		if key != "" && (key == s.script.APIKey || s.tokens[key]) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveToken answers OAuth refresh-token grants with a fresh access token
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request, body []byte) {
+	form, _ := url.ParseQuery(string(body))
+	if form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid_grant"})
+		return
+	}
+	s.mu.Lock()
+	token := fmt.Sprintf("mock-access-%d", len(s.tokens)+1)
+	s.tokens[token] = true
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  token,
+		"refresh_token": "mock-refresh-" + token,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+// request is what the mock reads from a generation request
+type request struct {
+	Model         string `json:"model"`
+	Stream        bool   `json:"stream"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request, api string, body []byte, b Behavior) {
+	if !sleep(r, b.LatencyMs) {
+		return
+	}
+	for key, value := range b.Headers {
+		w.Header().Set(key, value)
+	}
+	if b.RateLimits != nil {
+		setRateLimitHeaders(w.Header(), b.RateLimits)
+	}
+	if b.Status != 0 && b.Status != http.StatusOK {
+		if b.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(b.RetryAfter))
+		}
+		writeError(w, api, b.Status, b.Message)
+		return
+	}
+
+	var req request
+	json.Unmarshal(body, &req)
+	if api == APIGemini {
+		req.Model = geminiModel(r.URL.Path)
+		req.Stream = strings.Contains(r.URL.Path, ":streamGenerateContent")
+	}
+	if req.Model == "" {
+		req.Model = "mock-model"
+	}
+	if api == APICodex {
+		// The Codex backend only streams
+		req.Stream = true
+	}
+
+	if !req.Stream {
+		var resp interface{}
+		switch api {
+		case APIClaude:
+			resp = claudeMessage(b, req.Model)
+		case APIOpenAI:
+			resp = chatCompletion(b, req.Model)
+		case APIResponses:
+			resp = responsesResponse(b, req.Model)
+		case APIGemini:
+			resp = geminiResponse(b, req.Model)
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	var events []string
+	switch api {
+	case APIClaude:
+		events = claudeStream(b, req.Model)
+	case APIOpenAI:
+		events = chatStream(b, req.Model, req.StreamOptions.IncludeUsage)
+	case APIResponses:
+		events = responsesStream(b, req.Model, false)
+	case APICodex:
+		events = responsesStream(b, req.Model, true)
+	case APIGemini:
+		events = geminiStream(b, req.Model)
+	}
+	writeStream(w, r, events, b)
+}
+
+// writeStream sends SSE events, pausing between them and dropping the
+// connection after DisconnectAfter events when scripted
+func writeStream(w http.ResponseWriter, r *http.Request, events []string, b Behavior) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for i, event := range events {
+		if b.DisconnectAfter > 0 && i == b.DisconnectAfter {
+			// Aborts the response without terminating the chunked body, as a
+			// dropped upstream connection does
+			panic(http.ErrAbortHandler)
+		}
+		if i > 0 && !sleep(r, b.ChunkDelayMs) {
+			return
+		}
+		io.WriteString(w, event)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// sleep waits unless the client goes away first
+func sleep(r *http.Request, ms int) bool {
+	if ms <= 0 {
+		return true
+	}
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers in the error format of the API
+func writeError(w http.ResponseWriter, api string, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	switch api {
+	case APIClaude:
+		writeJSON(w, status, map[string]interface{}{
+			"type":  "error",
+			"error": map[string]interface{}{"type": claudeErrorType(status), "message": message},
+		})
+	case APIGemini:
+		writeJSON(w, status, map[string]interface{}{
+			"error": map[string]interface{}{"code": status, "message": message, "status": geminiErrorStatus(status)},
+		})
+	default:
+		writeJSON(w, status, map[string]interface{}{
+			"error": map[string]interface{}{"message": message, "type": openAIErrorType(status), "code": nil},
+		})
+	}
+}
+
+func claudeErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	}
+	return "api_error"
+}
+
+func openAIErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusNotFound:
+		return "invalid_request_error"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "authentication_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	}
+	return "server_error"
+}
+
+func geminiErrorStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	}
+	return "INTERNAL"
+}
+
+func serveModels(w http.ResponseWriter, api string, models []string) {
+	if api == APIGemini {
+		var list []map[string]interface{}
+		for _, model := range models {
+			list = append(list, map[string]interface{}{
+				"name":                       "models/" + model,
+				"displayName":                model,
+				"supportedGenerationMethods": []string{"generateContent", "streamGenerateContent", "countTokens"},
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"models": list})
+		return
+	}
+	// One list both OpenAI and Claude clients can read
+	var list []map[string]interface{}
+	for _, model := range models {
+		list = append(list, map[string]interface{}{
+			"id": model, "object": "model", "type": "model", "display_name": model, "created": 0, "owned_by": "mock",
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": list, "has_more": false})
+}
+
+// sse formats an event with an event line, as Claude and the Responses API do
+func sse(event string, data interface{}) string {
+	encoded, _ := json.Marshal(data)
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", event, encoded)
+}
+
+// dataEvent formats an event with only a data line, as Chat Completions and
+// Gemini do
+func dataEvent(data interface{}) string {
+	encoded, _ := json.Marshal(data)
+	return fmt.Sprintf("data: %s\n\n", encoded)
+}
+
+// words splits text into stream deltas
+func words(text string) []string {
+	var out []string
+	for _, word := range strings.SplitAfter(text, " ") {
+		if word != "" {
+			out = append(out, word)
+		}
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package mockupstream
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, srv *httptest.Server, path, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestServesEveryAPI(t *testing.T) {
+	mock := New(Script{Default: Behavior{
+		Text:      "Hi there",
+		Thinking:  "Greeting",
+		ToolCalls: []ToolCall{{Name: "read", Arguments: map[string]interface{}{"path": "a.go"}}, {Name: "ls"}},
+	}})
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	tests := []struct {
+		name, path, body string
+		want             []string
+	}{
+		{"claude", "/v1/messages", `{"model":"m"}`, []string{`"type":"thinking"`, `"text":"Hi there"`, `"name":"ls"`, `"stop_reason":"tool_use"`}},
+		{"claude stream", "/v1/messages", `{"model":"m","stream":true}`, []string{"event: message_start", `"text_delta"`, `"input_json_delta"`, "event: message_stop"}},
+		{"openai", "/v1/chat/completions", `{"model":"m"}`, []string{`"content":"Hi there"`, `"reasoning_content":"Greeting"`, `"finish_reason":"tool_calls"`}},
+		{"openai stream", "/v1/chat/completions", `{"model":"m","stream":true,"stream_options":{"include_usage":true}}`, []string{`"content":"Hi "`, `"tool_calls"`, `"prompt_tokens":10`, "data: [DONE]"}},
+		{"responses", "/v1/responses", `{"model":"m"}`, []string{`"type":"reasoning"`, `"type":"function_call"`, `"status":"completed"`}},
+		{"responses stream", "/v1/responses", `{"model":"m","stream":true}`, []string{"event: response.created", "event: response.output_text.delta", "event: response.function_call_arguments.done", "event: response.completed"}},
+		{"codex", "/backend-api/codex/responses", `{"model":"m"}`, []string{"event: response.created", "event: response.completed"}},
+		{"gemini", "/v1beta/models/gemini-2.5-pro:generateContent", `{}`, []string{`"thought":true`, `"functionCall"`, `"modelVersion":"gemini-2.5-pro"`}},
+		{"gemini stream", "/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", `{}`, []string{`"text":"Hi "`, `"finishReason":"STOP"`, `"usageMetadata"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post(t, srv, tt.path, tt.body, nil)
+			body := readAll(t, resp)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d: %s", resp.StatusCode, body)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("response lacks %s:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestStepsThenDefault(t *testing.T) {
+	mock := New(Script{
+		Default: Behavior{Text: "ok"},
+		Steps:   []Behavior{{Status: http.StatusTooManyRequests, RetryAfter: 7}},
+	})
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/messages", `{}`, nil)
+	body := readAll(t, resp)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "7" || !strings.Contains(body, "rate_limit_error") {
+		t.Fatalf("expected a Claude 429 with Retry-After, got %d %q: %s", resp.StatusCode, resp.Header.Get("Retry-After"), body)
+	}
+	resp = post(t, srv, "/v1/messages", `{}`, nil)
+	if body := readAll(t, resp); resp.StatusCode != http.StatusOK || !strings.Contains(body, `"text":"ok"`) {
+		t.Fatalf("expected the default reply after the steps, got %d: %s", resp.StatusCode, body)
+	}
+	if got := len(mock.Requests()); got != 2 {
+		t.Fatalf("expected 2 recorded requests, got %d", got)
+	}
+}
+
+func TestDisconnectMidStream(t *testing.T) {
+	srv := httptest.NewServer(New(Script{Default: Behavior{Text: "one two three four", DisconnectAfter: 3}}))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/messages", `{"stream":true}`, nil)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("expected the stream to break, read %s", body)
+	}
+	if !strings.Contains(string(body), "event: message_start") || strings.Contains(string(body), "message_stop") {
+		t.Fatalf("expected a partial stream, got %s", body)
+	}
+}
+
+func TestAPIKeyAndTokenRefresh(t *testing.T) {
+	srv := httptest.NewServer(New(Script{APIKey: "secret"}))
+	defer srv.Close()
+
+	resp := post(t, srv, "/v1/chat/completions", `{}`, map[string]string{"Authorization": "Bearer stale"})
+	if readAll(t, resp); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong key, got %d", resp.StatusCode)
+	}
+
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"r"}}.Encode()
+	resp, err := http.Post(srv.URL+"/oauth/token", "application/x-www-form-urlencoded", strings.NewReader(form))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&token)
+	resp.Body.Close()
+	if token.AccessToken == "" {
+		t.Fatal("no access token issued")
+	}
+
+	resp = post(t, srv, "/v1/chat/completions", `{}`, map[string]string{"Authorization": "Bearer " + token.AccessToken})
+	if readAll(t, resp); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the refreshed token to be accepted, got %d", resp.StatusCode)
+	}
+	resp = post(t, srv, "/v1beta/models/m:generateContent?key=secret", `{}`, nil)
+	if readAll(t, resp); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the key query parameter to be accepted, got %d", resp.StatusCode)
+	}
+}
+
+func TestCodexRateLimits(t *testing.T) {
+	limits := &RateLimits{PrimaryUsedPercent: 42, PrimaryWindowMinutes: 300, SecondaryUsedPercent: 7}
+	srv := httptest.NewServer(New(Script{Default: Behavior{RateLimits: limits}}))
+	defer srv.Close()
+
+	resp := post(t, srv, "/backend-api/codex/responses", `{}`, nil)
+	body := readAll(t, resp)
+	if resp.Header.Get("x-codex-primary-used-percent") != "42" || resp.Header.Get("x-codex-primary-window-minutes") != "300" {
+		t.Fatalf("missing rate-limit headers: %v", resp.Header)
+	}
+	if !strings.Contains(body, "event: codex.rate_limits") {
+		t.Fatalf("missing codex.rate_limits event:\n%s", body)
+	}
+
+	resp, err := http.Get(srv.URL + "/backend-api/wham/usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readAll(t, resp); !strings.Contains(body, `"used_percent":42`) {
+		t.Fatalf("usage API lacks the rate limits: %s", body)
+	}
+}
@@ -0,0 +1,76 @@
+// Package modelcaps records known capabilities of upstream models, such as
+// their context windows. Models are matched by the longest known prefix.
+package modelcaps
+
+import "strings"
+
+// Capabilities describes what a model family supports
+type Capabilities struct {
+	ContextWindow int // Maximum input plus output tokens
+}
+
+// table maps model name prefixes to their capabilities. More specific
+// prefixes win, so dated and suffixed variants share their family's entry.
+var table = map[string]Capabilities{
+	// Anthropic
+	"claude-": {ContextWindow: 200000},
+
+	// OpenAI
+	"gpt-3.5-turbo": {ContextWindow: 16385},
+	"gpt-4":         {ContextWindow: 8192},
+	"gpt-4-32k":     {ContextWindow: 32768},
+	"gpt-4-turbo":   {ContextWindow: 128000},
+	"gpt-4o":        {ContextWindow: 128000},
+	"gpt-4.1":       {ContextWindow: 1047576},
+	"gpt-5":         {ContextWindow: 400000},
+	"gpt-5-chat":    {ContextWindow: 128000},
+	"gpt-oss":       {ContextWindow: 131072},
+	"o1":            {ContextWindow: 200000},
+	"o1-mini":       {ContextWindow: 128000},
+	"o3":            {ContextWindow: 200000},
+	"o4-mini":       {ContextWindow: 200000},
+	"codex-mini":    {ContextWindow: 200000},
+
+	// Google
+	"gemini-1.5-pro":   {ContextWindow: 2097152},
+	"gemini-1.5-flash": {ContextWindow: 1048576},
+	"gemini-2.0-flash": {ContextWindow: 1048576},
+	"gemini-2.5":       {ContextWindow: 1048576},
+
+	// DeepSeek
+	"deepseek-chat":     {ContextWindow: 128000},
+	"deepseek-reasoner": {ContextWindow: 128000},
+}
+
+// Lookup returns the capabilities of a model. Provider prefixes such as
+// "openai/" and Bedrock's "us.anthropic." are ignored.
+func Lookup(model string) (Capabilities, bool) {
+	name := normalize(model)
+	best := ""
+	for prefix := range table {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return Capabilities{}, false
+	}
+	return table[best], true
+}
+
+// ContextWindow returns the context window of a model, or 0 when unknown
+func ContextWindow(model string) int {
+	caps, _ := Lookup(model)
+	return caps.ContextWindow
+}
+
+func normalize(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "anthropic."); i >= 0 {
+		name = name[i+len("anthropic."):]
+	}
+	return name
+}
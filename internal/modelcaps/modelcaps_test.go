@@ -0,0 +1,27 @@
+package modelcaps
+
+import "testing"
+
+func TestContextWindow(t *testing.T) {
+	cases := map[string]int{
+		"claude-sonnet-4-5-20250929":                 200000,
+		"us.anthropic.claude-opus-4-1-20250805-v1:0": 200000,
+		"claude-sonnet-4@20250514":                   200000,
+		"gpt-4":                                      8192,
+		"gpt-4-turbo-2024-04-09":                     128000,
+		"openai/gpt-4o-mini":                         128000,
+		"gpt-4.1-mini":                               1047576,
+		"gpt-5-codex":                                400000,
+		"gpt-5-chat-latest":                          128000,
+		"o1-mini":                                    128000,
+		"gemini-2.5-pro":                             1048576,
+		"deepseek-chat":                              128000,
+		"llama3.1:8b":                                0,
+		"":                                           0,
+	}
+	for model, want := range cases {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("%q: expected %d, got %d", model, want, got)
+		}
+	}
+}
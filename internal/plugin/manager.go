@@ -0,0 +1,206 @@
+// Package plugin runs transformer plugins declared in the configuration and
+// wraps endpoint transformers with them. Plugins are either executables
+// speaking the pluginsdk protocol over stdio, or WASI modules that get one
+// exchange per call.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/pluginsdk"
+)
+
+// Plugin kinds
+const (
+	KindCommand = "command"
+	KindWASM    = "wasm"
+)
+
+// Status describes a configured plugin
+type Status struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Disabled  bool     `json:"disabled,omitempty"`
+	Running   bool     `json:"running"`           // Process alive or module compiled
+	Restarts  int      `json:"restarts"`          // Starts within the last minute
+	Methods   []string `json:"methods,omitempty"` // Transform methods the plugin implements
+	LastError string   `json:"lastError,omitempty"`
+}
+
+// host runs one plugin
+type host interface {
+	// call sends one request and decodes the result
+	call(ctx context.Context, method string, params, result interface{}) error
+	// supports reports whether the plugin implements a transform method,
+	// starting the plugin if needed
+	supports(ctx context.Context, method string) (bool, error)
+	status() Status
+	close()
+}
+
+type entry struct {
+	fingerprint string
+	host        host
+}
+
+// Manager starts plugins on first use and restarts them when their
+// declaration changes
+type Manager struct {
+	plugins func() []config.PluginConfig
+
+	mu     sync.Mutex
+	hosts  map[string]*entry
+	closed bool
+}
+
+// NewManager creates a manager reading declarations from plugins on every use
+func NewManager(plugins func() []config.PluginConfig) *Manager {
+	return &Manager{plugins: plugins, hosts: make(map[string]*entry)}
+}
+
+// Wrap returns a transformer that passes traffic of base through the named
+// plugin. The plugin is started on the first transform call.
+func (m *Manager) Wrap(base transformer.Transformer, name string, call pluginsdk.CallContext) (transformer.Transformer, error) {
+	h, cfg, err := m.host(name)
+	if err != nil {
+		return nil, err
+	}
+	if call.Transformer == "" {
+		call.Transformer = base.Name()
+	}
+	return &Transformer{base: base, plugin: name, host: h, call: call, timeout: cfg.CallTimeout()}, nil
+}
+
+// Status reports every configured plugin, sorted by name
+func (m *Manager) Status() []Status {
+	plugins := m.plugins()
+	m.mu.Lock()
+	m.syncLocked(plugins)
+	statuses := make([]Status, 0, len(plugins))
+	for _, cfg := range plugins {
+		if e, ok := m.hosts[cfg.Name]; ok {
+			statuses = append(statuses, e.host.status())
+			continue
+		}
+		statuses = append(statuses, Status{Name: cfg.Name, Kind: kindOf(cfg), Disabled: cfg.Disabled})
+	}
+	m.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Close stops all plugins. Later calls to Wrap fail.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	hosts := m.hosts
+	m.hosts = make(map[string]*entry)
+	m.closed = true
+	m.mu.Unlock()
+
+	for _, e := range hosts {
+		e.host.close()
+	}
+}
+
+func (m *Manager) host(name string) (host, config.PluginConfig, error) {
+	plugins := m.plugins()
+	var cfg config.PluginConfig
+	found := false
+	for _, p := range plugins {
+		if p.Name == name {
+			cfg, found = p, true
+			break
+		}
+	}
+	if !found {
+		return nil, cfg, fmt.Errorf("unknown plugin: %s", name)
+	}
+	if cfg.Disabled {
+		return nil, cfg, fmt.Errorf("plugin %s is disabled", name)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, cfg, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, cfg, fmt.Errorf("plugin manager is closed")
+	}
+	m.syncLocked(plugins)
+	fingerprint := fingerprintOf(cfg)
+	if e, ok := m.hosts[name]; ok && e.fingerprint == fingerprint {
+		return e.host, cfg, nil
+	}
+
+	var h host
+	if kindOf(cfg) == KindWASM {
+		h = newWASMHost(cfg)
+	} else {
+		h = newProcessHost(cfg)
+	}
+	m.hosts[name] = &entry{fingerprint: fingerprint, host: h}
+	return h, cfg, nil
+}
+
+// syncLocked stops plugins that were removed or whose declaration changed
+func (m *Manager) syncLocked(plugins []config.PluginConfig) {
+	current := make(map[string]string, len(plugins))
+	for _, cfg := range plugins {
+		current[cfg.Name] = fingerprintOf(cfg)
+	}
+	for name, e := range m.hosts {
+		if fingerprint, ok := current[name]; ok && fingerprint == e.fingerprint {
+			continue
+		}
+		delete(m.hosts, name)
+		logger.Info("[PLUGIN] %s: declaration changed or removed, stopping", name)
+		go e.host.close()
+	}
+}
+
+func kindOf(cfg config.PluginConfig) string {
+	if cfg.WASM != "" {
+		return KindWASM
+	}
+	return KindCommand
+}
+
+func fingerprintOf(cfg config.PluginConfig) string {
+	data, _ := json.Marshal(cfg)
+	return string(data)
+}
+
+// initializeParams builds the initialize request for a plugin
+func initializeParams(cfg config.PluginConfig) pluginsdk.InitializeParams {
+	return pluginsdk.InitializeParams{
+		ProtocolVersion: pluginsdk.ProtocolVersion,
+		Name:            cfg.Name,
+		Config:          cfg.Config,
+	}
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[method] = true
+	}
+	return set
+}
+
+func sortedMethods(set map[string]bool) []string {
+	methods := make([]string, 0, len(set))
+	for method := range set {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
@@ -0,0 +1,348 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/pluginsdk"
+)
+
+// The test binary doubles as a plugin when started with this variable set
+const testPluginEnv = "CCNEXUS_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		if err := pluginsdk.Serve(testPlugin()); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testPlugin tags requests and responses, numbers stream events and splits
+// the second one in two. Requests with "crash" exit the plugin, requests
+// with "hang" never return.
+func testPlugin() *pluginsdk.Plugin {
+	p := &pluginsdk.Plugin{Name: "test", Version: "1"}
+	p.Init = func(raw json.RawMessage) error {
+		var cfg struct {
+			RequestOnly bool `json:"requestOnly"`
+		}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &cfg); err != nil {
+				return err
+			}
+		}
+		if cfg.RequestOnly {
+			p.TransformResponse = nil
+			p.TransformStreamEvent = nil
+		}
+		return nil
+	}
+	p.TransformRequest = func(ctx pluginsdk.CallContext, body json.RawMessage) (json.RawMessage, error) {
+		var req map[string]interface{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		if req["crash"] == true {
+			os.Exit(3)
+		}
+		if req["hang"] == true {
+			time.Sleep(time.Hour)
+		}
+		req["plugin"] = ctx.Endpoint + "/" + ctx.Transformer
+		return json.Marshal(req)
+	}
+	p.TransformResponse = func(ctx pluginsdk.CallContext, body json.RawMessage) (json.RawMessage, error) {
+		var resp map[string]interface{}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		resp["seen"] = true
+		return json.Marshal(resp)
+	}
+	p.TransformStreamEvent = func(ctx pluginsdk.CallContext, event string, state json.RawMessage) (string, json.RawMessage, error) {
+		count := 0
+		if len(state) > 0 {
+			if err := json.Unmarshal(state, &count); err != nil {
+				return "", nil, err
+			}
+		}
+		count++
+		out := "data: " + strconv.Itoa(count) + "\n\n"
+		if count == 2 {
+			out += "data: extra\n\n"
+		}
+		next, _ := json.Marshal(count)
+		return out, next, nil
+	}
+	return p
+}
+
+// echoTransformer stands in for a built-in transformer
+type echoTransformer struct{}
+
+func (echoTransformer) TransformRequest(req []byte) ([]byte, error) { return req, nil }
+func (echoTransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
+	return resp, nil
+}
+func (echoTransformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
+	return append([]byte("> "), resp...), nil
+}
+func (echoTransformer) Name() string { return "cx_chat_openai" }
+
+func testPluginConfig(t *testing.T, name string) config.PluginConfig {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.PluginConfig{Name: name, Command: executable, Env: map[string]string{testPluginEnv: "1"}}
+}
+
+func newTestManager(t *testing.T, plugins ...config.PluginConfig) *Manager {
+	t.Helper()
+	m := NewManager(func() []config.PluginConfig { return plugins })
+	t.Cleanup(m.Close)
+	return m
+}
+
+func wrap(t *testing.T, m *Manager, name string) transformer.Transformer {
+	t.Helper()
+	trans, err := m.Wrap(echoTransformer{}, name, pluginsdk.CallContext{Endpoint: "ep", Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trans
+}
+
+func TestTransformerRoundTrip(t *testing.T) {
+	m := newTestManager(t, testPluginConfig(t, "test"))
+	trans := wrap(t, m, "test")
+
+	if trans.Name() != "cx_chat_openai" {
+		t.Fatalf("name = %q, want the built-in transformer's", trans.Name())
+	}
+	if _, ok := trans.(transformer.Wrapper); !ok {
+		t.Fatal("plugin transformer should implement transformer.Wrapper")
+	}
+
+	req, err := trans.TransformRequest([]byte(`{"model":"m"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(req), `"plugin":"ep/cx_chat_openai"`) {
+		t.Fatalf("request = %s", req)
+	}
+
+	resp, err := trans.TransformResponse([]byte(`{"id":"1"}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resp), `"seen":true`) {
+		t.Fatalf("response = %s", resp)
+	}
+
+	ctx := transformer.NewStreamContext()
+	var events []string
+	for i := 0; i < 3; i++ {
+		out, err := trans.TransformResponseWithContext([]byte("data: {}\n\n"), true, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, string(out))
+	}
+	want := []string{"> data: 1\n\n", "> data: 2\n\n> data: extra\n\n", "> data: 3\n\n"}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("event %d = %q, want %q", i, events[i], want[i])
+		}
+	}
+
+	status := m.Status()
+	if len(status) != 1 || !status[0].Running || status[0].Restarts != 1 || len(status[0].Methods) != 3 {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestUnsupportedMethodsPassThrough(t *testing.T) {
+	cfg := testPluginConfig(t, "test")
+	cfg.Config = json.RawMessage(`{"requestOnly":true}`)
+	trans := wrap(t, newTestManager(t, cfg), "test")
+
+	resp, err := trans.TransformResponse([]byte(`{"id":"1"}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != `{"id":"1"}` {
+		t.Fatalf("response = %s, want it untouched", resp)
+	}
+	out, err := trans.TransformResponseWithContext([]byte("data: {}\n\n"), true, transformer.NewStreamContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "> data: {}\n\n" {
+		t.Fatalf("event = %q, want it untouched", out)
+	}
+}
+
+func TestCrashedPluginIsRestarted(t *testing.T) {
+	m := newTestManager(t, testPluginConfig(t, "test"))
+	trans := wrap(t, m, "test")
+
+	if _, err := trans.TransformRequest([]byte(`{"crash":true}`)); err == nil {
+		t.Fatal("expected an error from a crashing plugin")
+	}
+	req, err := trans.TransformRequest([]byte(`{}`))
+	if err != nil {
+		t.Fatalf("plugin was not restarted: %v", err)
+	}
+	if !strings.Contains(string(req), `"plugin"`) {
+		t.Fatalf("request = %s", req)
+	}
+	if status := m.Status(); status[0].Restarts != 2 || status[0].LastError == "" {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestCrashLoopFailsFast(t *testing.T) {
+	trans := wrap(t, newTestManager(t, testPluginConfig(t, "test")), "test")
+
+	for i := 0; i < maxStarts; i++ {
+		if _, err := trans.TransformRequest([]byte(`{"crash":true}`)); err == nil {
+			t.Fatal("expected an error from a crashing plugin")
+		}
+	}
+	_, err := trans.TransformRequest([]byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "started 5 times") {
+		t.Fatalf("err = %v, want the plugin to stay down", err)
+	}
+}
+
+func TestTimedOutPluginIsKilled(t *testing.T) {
+	cfg := testPluginConfig(t, "test")
+	cfg.Timeout = 1
+	trans := wrap(t, newTestManager(t, cfg), "test")
+
+	start := time.Now()
+	_, err := trans.TransformRequest([]byte(`{"hang":true}`))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("timeout took %v", elapsed)
+	}
+	if _, err := trans.TransformRequest([]byte(`{}`)); err != nil {
+		t.Fatalf("plugin was not restarted after the timeout: %v", err)
+	}
+}
+
+func TestManagerRestartsChangedPlugin(t *testing.T) {
+	plugins := []config.PluginConfig{testPluginConfig(t, "test")}
+	m := NewManager(func() []config.PluginConfig { return plugins })
+	defer m.Close()
+
+	first, err := m.Wrap(echoTransformer{}, "test", pluginsdk.CallContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.TransformRequest([]byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins = []config.PluginConfig{testPluginConfig(t, "test")}
+	plugins[0].Config = json.RawMessage(`{"requestOnly":true}`)
+	second, err := m.Wrap(echoTransformer{}, "test", pluginsdk.CallContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := second.TransformResponse([]byte(`{}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != `{}` {
+		t.Fatalf("response = %s, want the new configuration to apply", resp)
+	}
+
+	plugins[0].Disabled = true
+	if _, err := m.Wrap(echoTransformer{}, "test", pluginsdk.CallContext{}); err == nil {
+		t.Fatal("expected an error for a disabled plugin")
+	}
+	if status := m.Status(); len(status) != 1 || !status[0].Disabled || status[0].Running {
+		t.Fatalf("status = %+v, want the plugin stopped and disabled", status)
+	}
+
+	plugins = nil
+	if _, err := m.Wrap(echoTransformer{}, "test", pluginsdk.CallContext{}); err == nil {
+		t.Fatal("expected an error for a removed plugin")
+	}
+	if status := m.Status(); len(status) != 0 {
+		t.Fatalf("status = %+v, want no plugins", status)
+	}
+}
+
+func TestWASMPlugin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a WASI module")
+	}
+	wasm := filepath.Join(t.TempDir(), "quirks.wasm")
+	build := exec.Command("go", "build", "-o", wasm, "github.com/lich0821/ccNexus/pluginsdk/examples/quirks")
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Skipf("cannot build the example plugin: %v\n%s", err, out)
+	}
+
+	cfg := config.PluginConfig{
+		Name:   "quirks",
+		WASM:   wasm,
+		Config: json.RawMessage(`{"dropFields":["user"],"maxTokens":100,"cumulativeDeltas":true}`),
+	}
+	m := newTestManager(t, cfg)
+	trans := wrap(t, m, "quirks")
+
+	req, err := trans.TransformRequest([]byte(`{"max_tokens":4096,"user":"u"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(req) != `{"max_tokens":100}` {
+		t.Fatalf("request = %s", req)
+	}
+
+	ctx := transformer.NewStreamContext()
+	var deltas []string
+	for _, content := range []string{"Hel", "Hello", "Hello!"} {
+		event := `data: {"choices":[{"index":0,"delta":{"content":"` + content + `"}}]}` + "\n\n"
+		out, err := trans.TransformResponseWithContext([]byte(event), true, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(string(out), "> data: "))
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("event = %q: %v", out, err)
+		}
+		deltas = append(deltas, chunk.Choices[0].Delta.Content)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo|!" {
+		t.Fatalf("deltas = %q", deltas)
+	}
+
+	if status := m.Status(); !status[0].Running || status[0].Kind != KindWASM {
+		t.Fatalf("status = %+v", status)
+	}
+}
@@ -0,0 +1,329 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/pluginsdk"
+)
+
+const (
+	maxStarts     = 5                // Starts allowed within startWindow before giving up
+	startWindow   = time.Minute      // Window for counting starts
+	shutdownGrace = 2 * time.Second  // Time a plugin gets to exit after shutdown
+	maxLineSize   = 64 * 1024 * 1024 // Largest protocol line accepted from a plugin
+)
+
+// processHost runs a plugin executable and restarts it after it exits. A
+// plugin that keeps dying is not restarted more than maxStarts times per
+// startWindow so that requests fail fast instead of waiting on it.
+type processHost struct {
+	cfg config.PluginConfig
+
+	mu        sync.Mutex
+	proc      *process
+	starts    []time.Time
+	methods   map[string]bool
+	lastError string
+	closed    bool
+}
+
+func newProcessHost(cfg config.PluginConfig) *processHost {
+	return &processHost{cfg: cfg}
+}
+
+func (h *processHost) call(ctx context.Context, method string, params, result interface{}) error {
+	proc, err := h.running(ctx)
+	if err != nil {
+		return err
+	}
+	err = proc.call(ctx, method, params, result)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// A plugin that misses the deadline may be stuck, the next call gets a new process
+		h.discard(proc)
+		err = fmt.Errorf("%s timed out after %v", method, h.cfg.CallTimeout())
+	}
+	h.recordError(err)
+	return fmt.Errorf("plugin %s: %w", h.cfg.Name, err)
+}
+
+func (h *processHost) supports(ctx context.Context, method string) (bool, error) {
+	if _, err := h.running(ctx); err != nil {
+		return false, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.methods[method], nil
+}
+
+// running returns the live process, starting and initializing one if needed
+func (h *processHost) running(ctx context.Context) (*process, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, fmt.Errorf("plugin %s is stopped", h.cfg.Name)
+	}
+	if h.proc != nil && !h.proc.exited() {
+		return h.proc, nil
+	}
+	h.proc = nil
+
+	now := time.Now()
+	h.starts = recentStarts(h.starts, now)
+	if len(h.starts) >= maxStarts {
+		return nil, fmt.Errorf("plugin %s: started %d times within %v, last error: %s", h.cfg.Name, len(h.starts), startWindow, h.lastError)
+	}
+	h.starts = append(h.starts, now)
+
+	proc, err := startProcess(h.cfg)
+	if err != nil {
+		h.lastError = err.Error()
+		return nil, fmt.Errorf("plugin %s: %w", h.cfg.Name, err)
+	}
+	var result pluginsdk.InitializeResult
+	if err := proc.call(ctx, pluginsdk.MethodInitialize, initializeParams(h.cfg), &result); err != nil {
+		proc.kill()
+		h.lastError = "initialize: " + err.Error()
+		return nil, fmt.Errorf("plugin %s: initialize: %w", h.cfg.Name, err)
+	}
+	h.proc = proc
+	h.methods = methodSet(result.Methods)
+	logger.Info("[PLUGIN] %s: started (pid %d, methods %v)", h.cfg.Name, proc.cmd.Process.Pid, result.Methods)
+	return proc, nil
+}
+
+// discard kills proc and stops handing it out
+func (h *processHost) discard(proc *process) {
+	h.mu.Lock()
+	if h.proc == proc {
+		h.proc = nil
+	}
+	h.mu.Unlock()
+	proc.kill()
+}
+
+func (h *processHost) recordError(err error) {
+	h.mu.Lock()
+	h.lastError = err.Error()
+	h.mu.Unlock()
+	logger.Warn("[PLUGIN] %s: %v", h.cfg.Name, err)
+}
+
+func (h *processHost) status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return Status{
+		Name:      h.cfg.Name,
+		Kind:      KindCommand,
+		Running:   h.proc != nil && !h.proc.exited(),
+		Restarts:  len(recentStarts(h.starts, time.Now())),
+		Methods:   sortedMethods(h.methods),
+		LastError: h.lastError,
+	}
+}
+
+func (h *processHost) close() {
+	h.mu.Lock()
+	proc := h.proc
+	h.proc = nil
+	h.closed = true
+	h.mu.Unlock()
+
+	if proc != nil {
+		proc.shutdown()
+	}
+}
+
+func recentStarts(starts []time.Time, now time.Time) []time.Time {
+	recent := starts[:0]
+	for _, start := range starts {
+		if now.Sub(start) < startWindow {
+			recent = append(recent, start)
+		}
+	}
+	return recent
+}
+
+// process is one running plugin executable. Calls may be concurrent, their
+// responses are matched by id.
+type process struct {
+	name    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	nextID  atomic.Int64
+
+	pendingMu sync.Mutex
+	pending   map[int64]chan pluginsdk.Response
+
+	done chan struct{} // Closed after the process exited
+	err  error         // Why the process exited, set before done is closed
+}
+
+func startProcess(cfg config.PluginConfig) (*process, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = os.Environ()
+	for key, value := range cfg.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		name:    cfg.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan pluginsdk.Response),
+		done:    make(chan struct{}),
+	}
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		logStderr(cfg.Name, stderr)
+	}()
+	go p.readLoop(stdout, stderrDone)
+	return p, nil
+}
+
+func (p *process) readLoop(stdout io.Reader, stderrDone <-chan struct{}) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var resp pluginsdk.Response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || resp.ID == nil {
+			logger.Warn("[PLUGIN] %s: ignoring unexpected output: %.200s", p.name, scanner.Text())
+			continue
+		}
+		p.pendingMu.Lock()
+		ch, ok := p.pending[*resp.ID]
+		delete(p.pending, *resp.ID)
+		p.pendingMu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Warn("[PLUGIN] %s: reading output: %v", p.name, err)
+		p.kill()
+	}
+
+	<-stderrDone
+	if err := p.cmd.Wait(); err != nil {
+		p.err = fmt.Errorf("plugin exited: %w", err)
+	} else {
+		p.err = errors.New("plugin exited")
+	}
+	close(p.done)
+}
+
+func (p *process) call(ctx context.Context, method string, params, result interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := p.nextID.Add(1)
+	line, err := json.Marshal(pluginsdk.Request{JSONRPC: "2.0", ID: &id, Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan pluginsdk.Response, 1)
+	p.pendingMu.Lock()
+	p.pending[id] = ch
+	p.pendingMu.Unlock()
+	defer func() {
+		p.pendingMu.Lock()
+		delete(p.pending, id)
+		p.pendingMu.Unlock()
+	}()
+
+	if err := p.write(line); err != nil {
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-p.done:
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *process) write(line []byte) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	_, err := p.stdin.Write(append(line, '\n'))
+	return err
+}
+
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *process) kill() {
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+}
+
+// shutdown asks the plugin to exit and kills it if it does not in time
+func (p *process) shutdown() {
+	if line, err := json.Marshal(pluginsdk.Request{JSONRPC: "2.0", Method: pluginsdk.MethodShutdown}); err == nil {
+		_ = p.write(line)
+	}
+	_ = p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(shutdownGrace):
+		p.kill()
+		<-p.done
+	}
+}
+
+func logStderr(name string, stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		logger.Debug("[PLUGIN] %s: %s", name, scanner.Text())
+	}
+}
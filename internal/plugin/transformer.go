@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/pluginsdk"
+)
+
+// Transformer passes an endpoint's traffic through a plugin around its
+// built-in transformer: requests after the built-in conversion, responses
+// before it. Methods the plugin does not implement leave traffic untouched.
+type Transformer struct {
+	base    transformer.Transformer
+	plugin  string
+	host    host
+	call    pluginsdk.CallContext
+	timeout time.Duration
+}
+
+// Name returns the built-in transformer's name so routing is unchanged
+func (t *Transformer) Name() string {
+	return t.base.Name()
+}
+
+// Unwrap returns the built-in transformer
+func (t *Transformer) Unwrap() transformer.Transformer {
+	return t.base
+}
+
+// TransformRequest converts the request and hands it to the plugin
+func (t *Transformer) TransformRequest(claudeReq []byte) ([]byte, error) {
+	body, err := t.base.TransformRequest(claudeReq)
+	if err != nil {
+		return nil, err
+	}
+	return t.transformBody(pluginsdk.MethodTransformRequest, body)
+}
+
+// TransformResponse hands the response to the plugin and converts the result
+func (t *Transformer) TransformResponse(targetResp []byte, isStreaming bool) ([]byte, error) {
+	return t.transformResponse(targetResp, isStreaming, nil, func(resp []byte) ([]byte, error) {
+		return t.base.TransformResponse(resp, isStreaming)
+	})
+}
+
+// TransformResponseWithContext is TransformResponse for streams. The plugin
+// state lives in the stream context.
+func (t *Transformer) TransformResponseWithContext(targetResp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
+	return t.transformResponse(targetResp, isStreaming, ctx, func(resp []byte) ([]byte, error) {
+		return t.base.TransformResponseWithContext(resp, isStreaming, ctx)
+	})
+}
+
+func (t *Transformer) transformResponse(targetResp []byte, isStreaming bool, streamCtx *transformer.StreamContext, convert func([]byte) ([]byte, error)) ([]byte, error) {
+	if !isStreaming {
+		body, err := t.transformBody(pluginsdk.MethodTransformResponse, targetResp)
+		if err != nil {
+			return nil, err
+		}
+		return convert(body)
+	}
+
+	events, err := t.transformEvent(targetResp, streamCtx)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	for _, event := range events {
+		converted, err := convert([]byte(event))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, converted...)
+	}
+	return out, nil
+}
+
+func (t *Transformer) transformBody(method string, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	if ok, err := t.host.supports(ctx, method); err != nil || !ok {
+		return body, err
+	}
+
+	var params interface{}
+	if method == pluginsdk.MethodTransformRequest {
+		params = pluginsdk.TransformRequestParams{CallContext: t.call, Body: body}
+	} else {
+		params = pluginsdk.TransformResponseParams{CallContext: t.call, Body: body}
+	}
+	var result pluginsdk.BodyResult
+	if err := t.host.call(ctx, method, params, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Body) == 0 {
+		return nil, fmt.Errorf("plugin %s: %s returned an empty body", t.plugin, method)
+	}
+	return result.Body, nil
+}
+
+// transformEvent passes one SSE event through the plugin and splits what it
+// returns into events
+func (t *Transformer) transformEvent(event []byte, streamCtx *transformer.StreamContext) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	ok, err := t.host.supports(ctx, pluginsdk.MethodTransformStreamEvent)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []string{string(event)}, nil
+	}
+
+	params := pluginsdk.TransformStreamEventParams{CallContext: t.call, Event: string(event)}
+	if streamCtx != nil {
+		params.State = streamCtx.PluginState
+	}
+	var result pluginsdk.TransformStreamEventResult
+	if err := t.host.call(ctx, pluginsdk.MethodTransformStreamEvent, params, &result); err != nil {
+		return nil, err
+	}
+	if streamCtx != nil {
+		streamCtx.PluginState = result.State
+	}
+	return splitEvents(result.Event), nil
+}
+
+// splitEvents splits SSE text at blank lines, keeping each event's
+// terminating blank line
+func splitEvents(data string) []string {
+	var events []string
+	for {
+		i := strings.Index(data, "\n\n")
+		if i < 0 {
+			break
+		}
+		if event := data[:i+2]; strings.TrimSpace(event) != "" {
+			events = append(events, event)
+		}
+		data = data[i+2:]
+	}
+	if strings.TrimSpace(data) != "" {
+		events = append(events, data)
+	}
+	return events
+}
@@ -0,0 +1,241 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/pluginsdk"
+)
+
+// wasmHost runs a WASI plugin. The module is compiled once and instantiated
+// for every call with stdin holding initialize and the call, so an instance
+// keeps no state between calls and a crash only fails that call. The module
+// sees the plugin environment and, when dir is set, that directory as its
+// root file system, nothing else of the host.
+type wasmHost struct {
+	cfg config.PluginConfig
+
+	mu        sync.Mutex
+	runtime   wazero.Runtime
+	module    wazero.CompiledModule
+	methods   map[string]bool
+	lastError string
+	closed    bool
+}
+
+func newWASMHost(cfg config.PluginConfig) *wasmHost {
+	return &wasmHost{cfg: cfg}
+}
+
+func (h *wasmHost) call(ctx context.Context, method string, params, result interface{}) error {
+	runtime, module, err := h.load(ctx)
+	if err != nil {
+		return err
+	}
+	err = h.exchangeResult(ctx, runtime, module, method, params, result)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%s timed out after %v", method, h.cfg.CallTimeout())
+	}
+	h.recordError(err)
+	return fmt.Errorf("plugin %s: %w", h.cfg.Name, err)
+}
+
+func (h *wasmHost) supports(ctx context.Context, method string) (bool, error) {
+	if _, _, err := h.load(ctx); err != nil {
+		return false, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.methods[method], nil
+}
+
+// load compiles the module and asks it which methods it implements
+func (h *wasmHost) load(ctx context.Context) (wazero.Runtime, wazero.CompiledModule, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil, fmt.Errorf("plugin %s is stopped", h.cfg.Name)
+	}
+	if h.module != nil {
+		return h.runtime, h.module, nil
+	}
+
+	data, err := os.ReadFile(h.cfg.WASM)
+	if err != nil {
+		h.lastError = err.Error()
+		return nil, nil, fmt.Errorf("plugin %s: %w", h.cfg.Name, err)
+	}
+	// Compilation is not bounded by the call timeout, it happens once
+	runtime := wazero.NewRuntimeWithConfig(context.Background(), wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(context.Background(), runtime); err != nil {
+		runtime.Close(context.Background())
+		h.lastError = err.Error()
+		return nil, nil, fmt.Errorf("plugin %s: %w", h.cfg.Name, err)
+	}
+	module, err := runtime.CompileModule(context.Background(), data)
+	if err != nil {
+		runtime.Close(context.Background())
+		h.lastError = "compile: " + err.Error()
+		return nil, nil, fmt.Errorf("plugin %s: compile: %w", h.cfg.Name, err)
+	}
+
+	responses, err := h.exchange(ctx, runtime, module, nil)
+	if err == nil {
+		err = responseError(responses, 1)
+	}
+	var result pluginsdk.InitializeResult
+	if err == nil {
+		err = json.Unmarshal(responses[1].Result, &result)
+	}
+	if err != nil {
+		runtime.Close(context.Background())
+		h.lastError = "initialize: " + err.Error()
+		return nil, nil, fmt.Errorf("plugin %s: initialize: %w", h.cfg.Name, err)
+	}
+	h.runtime = runtime
+	h.module = module
+	h.methods = methodSet(result.Methods)
+	logger.Info("[PLUGIN] %s: loaded %s (methods %v)", h.cfg.Name, h.cfg.WASM, result.Methods)
+	return runtime, module, nil
+}
+
+func (h *wasmHost) exchangeResult(ctx context.Context, runtime wazero.Runtime, module wazero.CompiledModule, method string, params, result interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := int64(2)
+	responses, err := h.exchange(ctx, runtime, module, &pluginsdk.Request{JSONRPC: "2.0", ID: &id, Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+	if err := responseError(responses, 1); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if err := responseError(responses, id); err != nil {
+		return err
+	}
+	if result == nil || len(responses[id].Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(responses[id].Result, result)
+}
+
+// exchange runs one instance with initialize and, if given, req on stdin
+// and returns the responses by id
+func (h *wasmHost) exchange(ctx context.Context, runtime wazero.Runtime, module wazero.CompiledModule, req *pluginsdk.Request) (map[int64]pluginsdk.Response, error) {
+	initID := int64(1)
+	initParams, err := json.Marshal(initializeParams(h.cfg))
+	if err != nil {
+		return nil, err
+	}
+	var stdin bytes.Buffer
+	encoder := json.NewEncoder(&stdin)
+	if err := encoder.Encode(pluginsdk.Request{JSONRPC: "2.0", ID: &initID, Method: pluginsdk.MethodInitialize, Params: initParams}); err != nil {
+		return nil, err
+	}
+	if req != nil {
+		if err := encoder.Encode(req); err != nil {
+			return nil, err
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(h.cfg.Name).
+		WithStdin(&stdin).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	for key, value := range h.cfg.Env {
+		moduleConfig = moduleConfig.WithEnv(key, value)
+	}
+	if h.cfg.Dir != "" {
+		moduleConfig = moduleConfig.WithFSConfig(wazero.NewFSConfig().WithDirMount(h.cfg.Dir, "/"))
+	}
+
+	instance, err := runtime.InstantiateModule(ctx, module, moduleConfig)
+	if instance != nil {
+		instance.Close(context.Background())
+	}
+	logStderr(h.cfg.Name, &stderr)
+	var exitErr *sys.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 0) {
+		return nil, err
+	}
+
+	responses := make(map[int64]pluginsdk.Response)
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var resp pluginsdk.Response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || resp.ID == nil {
+			logger.Warn("[PLUGIN] %s: ignoring unexpected output: %.200s", h.cfg.Name, scanner.Text())
+			continue
+		}
+		responses[*resp.ID] = resp
+	}
+	return responses, scanner.Err()
+}
+
+func responseError(responses map[int64]pluginsdk.Response, id int64) error {
+	resp, ok := responses[id]
+	if !ok {
+		return errors.New("plugin exited without responding")
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (h *wasmHost) recordError(err error) {
+	h.mu.Lock()
+	h.lastError = err.Error()
+	h.mu.Unlock()
+	logger.Warn("[PLUGIN] %s: %v", h.cfg.Name, err)
+}
+
+func (h *wasmHost) status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return Status{
+		Name:      h.cfg.Name,
+		Kind:      KindWASM,
+		Running:   h.module != nil,
+		Methods:   sortedMethods(h.methods),
+		LastError: h.lastError,
+	}
+}
+
+func (h *wasmHost) close() {
+	h.mu.Lock()
+	runtime := h.runtime
+	h.runtime = nil
+	h.module = nil
+	h.closed = true
+	h.mu.Unlock()
+
+	if runtime != nil {
+		runtime.Close(context.Background())
+	}
+}
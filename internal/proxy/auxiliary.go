@@ -0,0 +1,377 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/azure"
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/tokencount"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+)
+
+// auxiliaryRoutes maps the auxiliary OpenAI paths to the capability an
+// endpoint needs to serve them
+var auxiliaryRoutes = map[string]string{
+	"/v1/embeddings":           config.CapabilityEmbeddings,
+	"/v1/moderations":          config.CapabilityModerations,
+	"/v1/audio/transcriptions": config.CapabilityTranscriptions,
+}
+
+// auxiliaryOperations is the upstream operation of each capability, relative
+// to /v1 or an Azure deployment
+var auxiliaryOperations = map[string]string{
+	config.CapabilityEmbeddings:     "embeddings",
+	config.CapabilityModerations:    "moderations",
+	config.CapabilityTranscriptions: "audio/transcriptions",
+}
+
+// auxiliaryRequest is an auxiliary request as received from the client
+type auxiliaryRequest struct {
+	capability  string
+	body        []byte
+	contentType string
+	bodyModel   string // Model named in the body
+	model       string // Requested model after endpoint resolution
+}
+
+// handleAuxiliary serves /v1/embeddings, /v1/moderations and
+// /v1/audio/transcriptions. Only endpoints with the matching capability take
+// part, and failover walks them in order starting at the current endpoint.
+func (p *Proxy) handleAuxiliary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	capability := auxiliaryRoutes[r.URL.Path]
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("Failed to read request body: %v", err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	auxReq := &auxiliaryRequest{
+		capability:  capability,
+		body:        bodyBytes,
+		contentType: r.Header.Get("Content-Type"),
+	}
+	auxReq.bodyModel = auxReq.requestModel()
+	auxReq.model = auxReq.bodyModel
+	logger.DebugLog("=== Auxiliary Request ===")
+	logger.DebugLog("Path: %s, Model: %s, Bytes: %d", r.URL.Path, auxReq.model, len(bodyBytes))
+
+	specifiedEndpoint, modelOverride, resolveErr := p.resolver.ResolveEndpoint(r, bodyBytes)
+	if resolveErr != nil {
+		logger.Warn("端点解析失败: %v", resolveErr)
+		writeInvalidRequestError(w, resolveErr.Error())
+		return
+	}
+	if modelOverride != "" {
+		auxReq.model = modelOverride
+	}
+
+	endpoints := p.auxiliaryEndpoints(capability, specifiedEndpoint)
+	if len(endpoints) == 0 {
+		http.Error(w, fmt.Sprintf("No enabled endpoints support %s", capability), http.StatusServiceUnavailable)
+		return
+	}
+	clientKey := clientKeyFromRequest(r)
+	endpoints, keyErr := authorizeClientKeyRequest(clientKey, endpoints, specifiedEndpoint, auxReq.model)
+	if keyErr != nil {
+		logger.Warn("Client key rejected: %s", keyErr.message)
+		writeClientKeyError(w, keyErr)
+		return
+	}
+
+	reqCtx := &proxyRequestContext{
+		httpRequest:                 r,
+		bodyBytes:                   bodyBytes,
+		requestModel:                auxReq.model,
+		requestStart:                time.Now(),
+		requestBytes:                len(bodyBytes),
+		endpoints:                   endpoints,
+		refreshedCredentialAttempts: make(map[int64]bool),
+		mintedTokenRetried:          make(map[string]bool),
+		clientKey:                   clientKey,
+	}
+
+	index := 0
+	maxRetries := p.computeMaxRetries(endpoints)
+	for retry := 0; retry < maxRetries && index < len(endpoints); retry++ {
+		attempt := &endpointAttempt{endpoint: endpoints[index]}
+		switch p.runAuxiliaryAttempt(w, reqCtx, attempt, auxReq) {
+		case attemptResultDone:
+			return
+		case attemptResultRetryNextEndpoint:
+			index++
+		}
+	}
+
+	p.recordClientKeyUsage(clientKey, endpoints[min(index, len(endpoints)-1)].Name, 0, 1, 0, 0)
+	http.Error(w, "All endpoints failed", http.StatusServiceUnavailable)
+}
+
+// auxiliaryEndpoints returns the enabled endpoints that serve a capability,
+// starting at the current endpoint
+func (p *Proxy) auxiliaryEndpoints(capability string, specified *config.Endpoint) []config.Endpoint {
+	if specified != nil {
+		if !specified.SupportsCapability(capability) {
+			return nil
+		}
+		return []config.Endpoint{*specified}
+	}
+
+	enabled := p.getEnabledEndpoints()
+	current := p.getCurrentEndpoint().Name
+	start := 0
+	for i, ep := range enabled {
+		if ep.Name == current {
+			start = i
+			break
+		}
+	}
+
+	var endpoints []config.Endpoint
+	for i := range enabled {
+		ep := enabled[(start+i)%len(enabled)]
+		if ep.SupportsCapability(capability) {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints
+}
+
+func (p *Proxy) runAuxiliaryAttempt(w http.ResponseWriter, reqCtx *proxyRequestContext, attempt *endpointAttempt, auxReq *auxiliaryRequest) attemptResult {
+	p.markRequestActive(attempt.endpoint.Name)
+	if result := p.resolveAttemptAuth(reqCtx, attempt); result != attemptResultDone {
+		p.markRequestInactive(attempt.endpoint.Name)
+		return result
+	}
+	attempt.modelName = attempt.endpoint.Options.AuxiliaryModel(auxReq.model)
+
+	proxyReq, err := buildAuxiliaryRequest(reqCtx.httpRequest, attempt.endpoint, attempt.apiKey, auxReq, attempt.modelName)
+	if err != nil {
+		logger.Error("[%s] Failed to build %s request: %v", attempt.endpoint.Name, auxReq.capability, err)
+		p.markRequestInactive(attempt.endpoint.Name)
+		writeInvalidRequestError(w, err.Error())
+		return attemptResultDone
+	}
+	attempt.proxyRequest = proxyReq
+	logger.Debug("[%s] Requesting %s %s %d", attempt.endpoint.Name, auxReq.capability, attempt.modelName, reqCtx.requestBytes)
+
+	resp, err := sendRequest(p.getEndpointContext(attempt.endpoint.Name), proxyReq, p.httpClient, p.config)
+	if err != nil {
+		return p.handleSendError(err, attempt)
+	}
+	attempt.response = resp
+
+	if resp.StatusCode == http.StatusOK {
+		p.finishAuxiliaryResponse(w, reqCtx, attempt, auxReq)
+		return attemptResultDone
+	}
+	if shouldRetry(resp.StatusCode) {
+		return p.handleRetryableStatus(resp, attempt)
+	}
+	return p.handleFinalStatus(w, reqCtx, attempt)
+}
+
+// finishAuxiliaryResponse relays a successful response, converting Gemini
+// embeddings to the OpenAI format, and records usage
+func (p *Proxy) finishAuxiliaryResponse(w http.ResponseWriter, reqCtx *proxyRequestContext, attempt *endpointAttempt, auxReq *auxiliaryRequest) {
+	resp := attempt.response
+	respBody := readResponseBody(resp)
+	inputTokens, outputTokens := 0, 0
+
+	if isGeminiEmbeddings(attempt.endpoint, auxReq.capability) {
+		texts := convert.EmbeddingInputTexts(auxReq.body)
+		inputTokens = tokencount.EstimateOutputTokens(strings.Join(texts, "\n"))
+		var req struct {
+			EncodingFormat string `json:"encoding_format"`
+		}
+		json.Unmarshal(auxReq.body, &req)
+		converted, err := convert.GeminiEmbeddingsToOpenAI(respBody, attempt.modelName, req.EncodingFormat, inputTokens)
+		if err != nil {
+			logger.Error("[%s] Failed to convert Gemini embeddings: %v", attempt.endpoint.Name, err)
+		} else {
+			respBody = converted
+			resp.Header.Set("Content-Type", "application/json")
+		}
+	} else {
+		var usage struct {
+			Usage struct {
+				PromptTokens int `json:"prompt_tokens"`
+				InputTokens  int `json:"input_tokens"`
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+		}
+		if json.Unmarshal(respBody, &usage) == nil {
+			inputTokens = usage.Usage.PromptTokens + usage.Usage.InputTokens
+			outputTokens = usage.Usage.OutputTokens
+		}
+	}
+
+	copyResponseHeaders(w, resp)
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(respBody)
+
+	p.stats.RecordRequest(attempt.endpoint.Name)
+	p.stats.RecordTokens(attempt.endpoint.Name, inputTokens, outputTokens)
+	p.recordCredentialUsage(attempt.credentialID, attempt.endpoint.Name, 1, 0, inputTokens, outputTokens)
+	p.recordClientKeyUsage(reqCtx.clientKey, attempt.endpoint.Name, 1, 0, inputTokens, outputTokens)
+	p.markCredentialSuccess(attempt.credentialID)
+	p.markRequestInactive(attempt.endpoint.Name)
+	if p.onEndpointSuccess != nil {
+		p.onEndpointSuccess(attempt.endpoint.Name)
+	}
+	logger.Debug("[%s] Requested %s tokens=%d/%d latency=%s key=%s", attempt.endpoint.Name, auxReq.capability, inputTokens, outputTokens, time.Since(reqCtx.requestStart).Round(time.Millisecond), clientKeyLabel(reqCtx.clientKey))
+}
+
+func isGeminiEmbeddings(endpoint config.Endpoint, capability string) bool {
+	return strings.EqualFold(endpoint.Transformer, "gemini") && capability == config.CapabilityEmbeddings
+}
+
+// buildAuxiliaryRequest creates the upstream request for an auxiliary route
+func buildAuxiliaryRequest(r *http.Request, endpoint config.Endpoint, apiKey string, auxReq *auxiliaryRequest, model string) (*http.Request, error) {
+	baseURL := strings.TrimSuffix(normalizeAPIUrl(endpoint.APIUrl), "/")
+	operation := auxiliaryOperations[auxReq.capability]
+	body, contentType := auxReq.body, auxReq.contentType
+	query := url.Values{}
+
+	isAzure := endpoint.IsAzureOpenAI()
+	var targetURL string
+	switch {
+	case isGeminiEmbeddings(endpoint, auxReq.capability):
+		geminiBody, method, err := convert.OpenAIEmbeddingsToGemini(body, model)
+		if err != nil {
+			return nil, err
+		}
+		body, contentType = geminiBody, "application/json"
+		targetURL = fmt.Sprintf("%s/v1beta/models/%s%s", baseURL, url.PathEscape(model), method)
+		query.Set("key", apiKey)
+	case isAzure:
+		deployment := endpoint.Options.AzureDeployment(model)
+		targetURL = azure.BaseURL(baseURL) + azure.DeploymentPath(deployment, operation)
+		query.Set("api-version", endpoint.Options.AzureAPIVersion())
+	default:
+		targetURL = baseURL + "/v1/" + operation
+	}
+
+	if model != "" && model != auxReq.bodyModel && !isGeminiEmbeddings(endpoint, auxReq.capability) {
+		var err error
+		if body, contentType, err = auxReq.withModel(model); err != nil {
+			return nil, err
+		}
+	}
+	if encoded := query.Encode(); encoded != "" {
+		targetURL += "?" + encoded
+	}
+
+	proxyReq, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		switch key {
+		case "Host", "Accept-Encoding", "Content-Length", "Authorization", "X-Api-Key", "X-Goog-Api-Key":
+			continue
+		}
+		for _, value := range values {
+			proxyReq.Header.Add(key, value)
+		}
+	}
+	proxyReq.Header.Set("Accept-Encoding", "gzip, identity")
+	proxyReq.Header.Set("Content-Type", contentType)
+
+	switch {
+	case isGeminiEmbeddings(endpoint, auxReq.capability):
+		// Key is sent as a query parameter
+	case isAzure:
+		applyAzureAuth(proxyReq, endpoint, apiKey)
+	default:
+		proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	return proxyReq, nil
+}
+
+// requestModel returns the model of a JSON or multipart request
+func (a *auxiliaryRequest) requestModel() string {
+	if boundary, ok := multipartBoundary(a.contentType); ok {
+		reader := multipart.NewReader(bytes.NewReader(a.body), boundary)
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return ""
+			}
+			if part.FormName() == "model" && part.FileName() == "" {
+				value, _ := io.ReadAll(io.LimitReader(part, 1024))
+				return strings.TrimSpace(string(value))
+			}
+		}
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(a.body, &req)
+	return strings.TrimSpace(req.Model)
+}
+
+// withModel returns the request body with its model replaced, keeping the
+// multipart boundary of form uploads
+func (a *auxiliaryRequest) withModel(model string) ([]byte, string, error) {
+	boundary, ok := multipartBoundary(a.contentType)
+	if !ok {
+		return setPayloadField(a.body, "model", model), a.contentType, nil
+	}
+
+	var out bytes.Buffer
+	writer := multipart.NewWriter(&out)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, "", err
+	}
+	reader := multipart.NewReader(bytes.NewReader(a.body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid multipart body: %w", err)
+		}
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "model" && part.FileName() == "" {
+			_, err = io.WriteString(dst, model)
+		} else {
+			_, err = io.Copy(dst, part)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), a.contentType, nil
+}
+
+func multipartBoundary(contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+type recordingStatsStorage struct {
+	mu      sync.Mutex
+	records []*StatRecord
+}
+
+func (s *recordingStatsStorage) RecordDailyStat(stat interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, stat.(*StatRecord))
+	return nil
+}
+
+func (s *recordingStatsStorage) GetTotalStats() (int, map[string]interface{}, error) {
+	return 0, nil, nil
+}
+
+func (s *recordingStatsStorage) GetDailyStats(endpointName, startDate, endDate string) ([]interface{}, error) {
+	return nil, nil
+}
+
+func (s *recordingStatsStorage) GetPeriodStatsAggregated(startDate, endDate string) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// totals sums the recorded requests, errors and input tokens of an endpoint
+func (s *recordingStatsStorage) totals(endpointName string) (requests, errors, inputTokens int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.records {
+		if r.EndpointName == endpointName {
+			requests += r.Requests
+			errors += r.Errors
+			inputTokens += r.InputTokens
+		}
+	}
+	return
+}
+
+func newAuxiliaryTestProxy(endpoints []config.Endpoint) (*Proxy, *recordingStatsStorage) {
+	cfg := config.DefaultConfig()
+	cfg.UpdateEndpoints(endpoints)
+	statsStorage := &recordingStatsStorage{}
+	return New(cfg, statsStorage, nil, "test"), statsStorage
+}
+
+func TestEmbeddingsFailOverToCapableEndpoint(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key-2" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "bge-m3" {
+			t.Errorf("expected mapped model, got %v", body["model"])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1]}],"model":"bge-m3","usage":{"prompt_tokens":5,"total_tokens":5}}`))
+	}))
+	defer healthy.Close()
+
+	p, statsStorage := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Claude", APIUrl: failing.URL, APIKey: "k", Enabled: true, Transformer: "claude"},
+		{Name: "Primary", APIUrl: failing.URL, APIKey: "key-1", Enabled: true, Transformer: "openai"},
+		{Name: "Backup", APIUrl: healthy.URL, APIKey: "key-2", Enabled: true, Transformer: "openai",
+			Options: &config.EndpointOptions{Auxiliary: &config.AuxiliaryOptions{Models: map[string]string{"*": "bge-m3"}}}},
+	})
+
+	r := httptest.NewRequest("POST", "/v1/embeddings", strings.NewReader(`{"model":"text-embedding-3-small","input":"hello"}`))
+	r.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	p.handleAuxiliary(rec, r)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"embedding":[0.1]`) {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+	if requests, errors, _ := statsStorage.totals("Primary"); requests != 0 || errors != 1 {
+		t.Fatalf("expected one error on Primary, got %d requests %d errors", requests, errors)
+	}
+	if requests, _, tokens := statsStorage.totals("Backup"); requests != 1 || tokens != 5 {
+		t.Fatalf("expected one request with 5 tokens on Backup, got %d/%d", requests, tokens)
+	}
+	if requests, errors, _ := statsStorage.totals("Claude"); requests+errors != 0 {
+		t.Fatal("endpoints without the capability must not be tried")
+	}
+}
+
+func TestGeminiBatchEmbeddings(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-embedding-001:batchEmbedContents" || r.URL.Query().Get("key") != "gkey" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		var body struct {
+			Requests []struct {
+				Model                string `json:"model"`
+				OutputDimensionality int    `json:"outputDimensionality"`
+			} `json:"requests"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Requests) != 2 || body.Requests[0].Model != "models/gemini-embedding-001" || body.Requests[0].OutputDimensionality != 2 {
+			t.Errorf("unexpected batch body: %+v", body)
+		}
+		w.Write([]byte(`{"embeddings":[{"values":[0.5,-1]},{"values":[0.25,1]}]}`))
+	}))
+	defer upstream.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Gemini", APIUrl: upstream.URL, APIKey: "gkey", Enabled: true, Transformer: "gemini"},
+	})
+	r := httptest.NewRequest("POST", "/v1/embeddings", strings.NewReader(`{"model":"gemini-embedding-001","input":["a","b"],"dimensions":2,"encoding_format":"base64"}`))
+	rec := httptest.NewRecorder()
+	p.handleAuxiliary(rec, r)
+
+	var resp struct {
+		Data []struct {
+			Index     int    `json:"index"`
+			Embedding string `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+	}
+	// 0.5 and -1 as little-endian float32
+	if len(resp.Data) != 2 || resp.Data[0].Embedding != "AAAAPwAAgL8=" || resp.Data[1].Index != 1 {
+		t.Fatalf("unexpected embeddings: %s", rec.Body.String())
+	}
+	if resp.Usage.PromptTokens == 0 {
+		t.Fatal("expected estimated prompt tokens")
+	}
+}
+
+func TestTranscriptionsRewriteMultipartModel(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/whisper-prod/audio/transcriptions" || r.URL.Query().Get("api-version") == "" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		if r.Header.Get("api-key") != "azure-key" {
+			t.Errorf("expected api-key header, got %v", r.Header)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("invalid multipart body: %v", err)
+		}
+		if r.FormValue("model") != "whisper" || r.FormValue("language") != "en" {
+			t.Errorf("unexpected form: %v", r.MultipartForm.Value)
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("missing file: %v", err)
+		}
+		if data, _ := io.ReadAll(file); string(data) != "RIFF-audio" {
+			t.Errorf("file not preserved: %q", data)
+		}
+		w.Write([]byte(`{"text":"hello"}`))
+	}))
+	defer upstream.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{{
+		Name: "Azure", APIUrl: upstream.URL, APIKey: "azure-key", Enabled: true, Transformer: "openai",
+		Options: &config.EndpointOptions{
+			Azure:     &config.AzureOptions{Deployments: map[string]string{"whisper": "whisper-prod"}},
+			Auxiliary: &config.AuxiliaryOptions{Models: map[string]string{"whisper-1": "whisper"}},
+		},
+	}})
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("model", "whisper-1")
+	part, _ := writer.CreateFormFile("file", "a.wav")
+	part.Write([]byte("RIFF-audio"))
+	writer.WriteField("language", "en")
+	writer.Close()
+
+	r := httptest.NewRequest("POST", "/v1/audio/transcriptions", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	p.handleAuxiliary(rec, r)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"text":"hello"}` {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestModerationsWithoutCapableEndpoint(t *testing.T) {
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Gemini", APIUrl: "https://example.com", APIKey: "k", Enabled: true, Transformer: "gemini"},
+	})
+	r := httptest.NewRequest("POST", "/v1/moderations", strings.NewReader(`{"input":"hi"}`))
+	rec := httptest.NewRecorder()
+	p.handleAuxiliary(rec, r)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/azure"
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+const azureTokenTimeout = 20 * time.Second
+
+// isAzureOpenAIRequest reports whether the request goes to an Azure OpenAI
+// deployment through the Chat Completions or Responses API
+func isAzureOpenAIRequest(endpoint config.Endpoint, transformerName string) bool {
+	if !strings.HasSuffix(transformerName, "_openai") && !strings.HasSuffix(transformerName, "_openai2") {
+		return false
+	}
+	return endpoint.IsAzureOpenAI()
+}
+
+// azureDeploymentForRequest maps the request's model to its deployment
+func azureDeploymentForRequest(endpoint config.Endpoint, transformedBody []byte, modelName string) string {
+	model := strings.TrimSpace(modelName)
+	if model == "" {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.Unmarshal(transformedBody, &req)
+		model = strings.TrimSpace(req.Model)
+	}
+	return endpoint.Options.AzureDeployment(model)
+}
+
+// azureTargetPath returns the deployment path for Chat Completions, or the
+// resource-level Responses path
+func azureTargetPath(endpoint config.Endpoint, transformedBody []byte, transformerName string, modelName string) string {
+	if strings.HasSuffix(transformerName, "_openai2") {
+		return azure.ResponsesPath
+	}
+	return azure.ChatCompletionsPath(azureDeploymentForRequest(endpoint, transformedBody, modelName))
+}
+
+// azureRequestBody names the deployment in the model field, which is how the
+// Responses API selects it; Chat Completions ignores the field
+func azureRequestBody(endpoint config.Endpoint, transformedBody []byte, modelName string) []byte {
+	deployment := azureDeploymentForRequest(endpoint, transformedBody, modelName)
+	if deployment == "" {
+		return transformedBody
+	}
+	return setPayloadField(transformedBody, "model", deployment)
+}
+
+// azureQuery returns the query Azure expects: only the api-version
+func azureQuery(endpoint config.Endpoint) string {
+	return url.Values{"api-version": {endpoint.Options.AzureAPIVersion()}}.Encode()
+}
+
+// applyAzureAuth replaces the client's headers with an api-key header, or
+// an Entra ID bearer token for authMode entra_id
+func applyAzureAuth(req *http.Request, endpoint config.Endpoint, apiKey string) {
+	for _, key := range []string{"Authorization", "X-Api-Key", "Api-Key", "Anthropic-Version", "Anthropic-Beta"} {
+		req.Header.Del(key)
+	}
+	if config.NormalizeAuthMode(endpoint.AuthMode) == config.AuthModeEntraID {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return
+	}
+	req.Header.Set("api-key", apiKey)
+}
+
+// azureAccessToken returns a cached or newly minted Entra ID access token
+func (p *Proxy) azureAccessToken(endpoint config.Endpoint) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), azureTokenTimeout)
+	defer cancel()
+	return p.azureTokens.Token(ctx, p.tokenMintHTTPClient(azureTokenTimeout), endpoint.Options.AzureCredentials())
+}
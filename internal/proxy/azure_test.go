@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/azure"
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+func TestAzureChatCompletionsUsesDeploymentPath(t *testing.T) {
+	endpoint := config.Endpoint{
+		Name:        "Azure",
+		APIUrl:      "https://my-resource.openai.azure.com/openai",
+		APIKey:      "azure-key",
+		AuthMode:    config.AuthModeAPIKey,
+		Transformer: "openai",
+		Options: &config.EndpointOptions{Azure: &config.AzureOptions{
+			APIVersion:  "2024-10-21",
+			Deployments: map[string]string{"gpt-4o": "prod-4o"},
+		}},
+	}
+	trans, err := prepareTransformerForClient(ClientFormatClaude, endpoint, "gpt-4o")
+	if err != nil {
+		t.Fatalf("prepareTransformerForClient failed: %v", err)
+	}
+	transformed, err := trans.TransformRequest([]byte(`{"model":"gpt-4o","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("TransformRequest failed: %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/v1/messages?beta=true", nil)
+	r.Header.Set("x-api-key", "client-key")
+	proxyReq, err := buildProxyRequest(r, endpoint, endpoint.APIKey, transformed, trans.Name(), "gpt-4o", nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	if got := proxyReq.URL.String(); got != "https://my-resource.openai.azure.com/openai/deployments/prod-4o/chat/completions?api-version=2024-10-21" {
+		t.Fatalf("unexpected URL %s", got)
+	}
+	if proxyReq.Header.Get("api-key") != "azure-key" || proxyReq.Header.Get("Authorization") != "" || proxyReq.Header.Get("X-Api-Key") != "" {
+		t.Fatalf("unexpected auth headers: %v", proxyReq.Header)
+	}
+}
+
+func TestAzureResponsesWithEntraID(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("client_id") != "app" {
+			t.Errorf("unexpected client_id %q", r.PostForm.Get("client_id"))
+		}
+		fmt.Fprint(w, `{"access_token":"entra-token","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	endpoint := config.Endpoint{
+		Name:        "Azure",
+		APIUrl:      "https://my-resource.openai.azure.com",
+		AuthMode:    config.AuthModeEntraID,
+		Transformer: "openai2",
+		Options: &config.EndpointOptions{Azure: &config.AzureOptions{
+			Deployments:  map[string]string{"gpt-5": "gpt5-eastus"},
+			TenantID:     "tenant",
+			ClientID:     "app",
+			ClientSecret: "secret",
+			TokenURL:     tokenServer.URL,
+		}},
+	}
+	p := &Proxy{config: config.DefaultConfig(), azureTokens: azure.NewTokenCache()}
+	reqCtx := &proxyRequestContext{}
+	attempt := &endpointAttempt{endpoint: endpoint}
+	if result := p.resolveAttemptAuth(reqCtx, attempt); result != attemptResultDone || attempt.apiKey != "entra-token" {
+		t.Fatalf("expected minted Entra ID token, got %q (%v)", attempt.apiKey, result)
+	}
+
+	r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	r.Header.Set("Authorization", "Bearer client-key")
+	body := []byte(`{"model":"gpt-5","input":[]}`)
+	proxyReq, err := buildProxyRequest(r, endpoint, attempt.apiKey, body, "cx_chat_openai2", "gpt-5", nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	if got := proxyReq.URL.String(); got != "https://my-resource.openai.azure.com/openai/responses?api-version="+azure.DefaultAPIVersion {
+		t.Fatalf("unexpected URL %s", got)
+	}
+	if proxyReq.Header.Get("Authorization") != "Bearer entra-token" || proxyReq.Header.Get("api-key") != "" {
+		t.Fatalf("unexpected auth headers: %v", proxyReq.Header)
+	}
+	sent, _ := io.ReadAll(proxyReq.Body)
+	if !strings.Contains(string(sent), `"model":"gpt5-eastus"`) {
+		t.Fatalf("expected the deployment as model, got %s", sent)
+	}
+}
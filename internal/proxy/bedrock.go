@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/bedrock"
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// isBedrockTransformer reports whether the transformer targets AWS Bedrock
+func isBedrockTransformer(transformerName string) bool {
+	return strings.HasSuffix(transformerName, "_bedrock")
+}
+
+// bedrockTargetPath returns the InvokeModel path for the request's model
+func bedrockTargetPath(endpoint config.Endpoint, transformedBody []byte, modelName string) string {
+	var claudeReq struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	json.Unmarshal(transformedBody, &claudeReq)
+
+	model := strings.TrimSpace(modelName)
+	if model == "" {
+		model = strings.TrimSpace(claudeReq.Model)
+	}
+	var overrides map[string]string
+	if endpoint.Options != nil && endpoint.Options.Bedrock != nil {
+		overrides = endpoint.Options.Bedrock.ModelIDs
+	}
+	modelID := bedrock.ModelID(model, endpoint.Options.BedrockRegion(), overrides)
+	return bedrock.InvokePath(modelID, claudeReq.Stream)
+}
+
+// applyBedrockAuth replaces the client's Anthropic headers with Bedrock
+// authentication: a Bedrock API key as bearer token, or a SigV4 signature
+func applyBedrockAuth(req *http.Request, endpoint config.Endpoint, apiKey string, body []byte) error {
+	for _, key := range []string{"Authorization", "X-Api-Key", "Anthropic-Version", "Anthropic-Beta"} {
+		req.Header.Del(key)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if strings.HasSuffix(req.URL.Path, "/invoke-with-response-stream") {
+		req.Header.Set("Accept", bedrock.EventStreamContentType)
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	if config.NormalizeAuthMode(endpoint.AuthMode) != config.AuthModeAWSSigV4 {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		return nil
+	}
+
+	var opts config.BedrockOptions
+	if endpoint.Options != nil && endpoint.Options.Bedrock != nil {
+		opts = *endpoint.Options.Bedrock
+	}
+	creds, err := bedrock.ResolveCredentials(opts.AccessKeyID, opts.SecretAccessKey, opts.SessionToken, opts.Profile)
+	if err != nil {
+		return err
+	}
+	region := endpoint.Options.BedrockRegion()
+	if region == "" {
+		return fmt.Errorf("bedrock region is not configured")
+	}
+	bedrock.SignRequest(req, body, creds, region, bedrock.ServiceName, time.Now())
+	return nil
+}
+
+// adaptBedrockStreamResponse turns an event-stream response body into the
+// Claude SSE stream it carries, so it is handled like any Claude stream
+func adaptBedrockStreamResponse(resp *http.Response) error {
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(contentType, bedrock.EventStreamContentType) {
+		return nil
+	}
+
+	body := resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		body = struct {
+			io.Reader
+			io.Closer
+		}{gzipReader, resp.Body}
+		resp.Header.Del("Content-Encoding")
+	}
+	resp.Body = bedrock.NewSSEReader(body)
+	resp.Header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return nil
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/bedrock"
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+func TestBedrockStreamingAgainstEventStreamStub(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/model/us.anthropic.claude-sonnet-4-20250514-v1%3A0/invoke-with-response-stream" {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/us-east-1/bedrock/aws4_request") {
+			t.Errorf("expected SigV4 authorization, got %q", auth)
+		}
+		if r.Header.Get("X-Api-Key") != "" || r.Header.Get("Anthropic-Version") != "" {
+			t.Errorf("client Anthropic headers must not be forwarded: %v", r.Header)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["model"]; ok {
+			t.Errorf("model must move to the URL, got %v", body)
+		}
+		if body["anthropic_version"] != "bedrock-2023-05-31" {
+			t.Errorf("expected bedrock anthropic_version, got %v", body["anthropic_version"])
+		}
+		if betas, _ := body["anthropic_beta"].([]interface{}); len(betas) != 1 || betas[0] != "interleaved-thinking-2025-05-14" {
+			t.Errorf("expected only supported betas, got %v", body["anthropic_beta"])
+		}
+
+		w.Header().Set("Content-Type", bedrock.EventStreamContentType)
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":11,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`,
+			`{"type":"message_stop"}`,
+		} {
+			w.Write(bedrock.EncodeChunk([]byte(event)))
+		}
+	}))
+	defer upstream.Close()
+
+	endpoint := config.Endpoint{
+		Name:        "Bedrock",
+		APIUrl:      upstream.URL,
+		AuthMode:    config.AuthModeAWSSigV4,
+		Enabled:     true,
+		Transformer: config.BedrockTransformer,
+		Options: &config.EndpointOptions{Bedrock: &config.BedrockOptions{
+			Region:          "us-east-1",
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "secret",
+		}},
+	}
+	cfg := config.DefaultConfig()
+	cfg.UpdateEndpoints([]config.Endpoint{endpoint})
+	p := &Proxy{config: cfg}
+
+	trans, err := prepareTransformerForClient(ClientFormatClaude, endpoint, "claude-sonnet-4-20250514")
+	if err != nil {
+		t.Fatalf("prepareTransformerForClient failed: %v", err)
+	}
+	clientBody := []byte(`{"model":"claude-sonnet-4-20250514","stream":true,"max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`)
+	transformed, err := trans.TransformRequest(clientBody)
+	if err != nil {
+		t.Fatalf("TransformRequest failed: %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/v1/messages", nil)
+	r.Header.Set("x-api-key", "client-key")
+	r.Header.Set("anthropic-version", "2023-06-01")
+	r.Header.Set("anthropic-beta", "claude-code-20250219,interleaved-thinking-2025-05-14")
+	proxyReq, err := buildProxyRequest(r, endpoint, "", transformed, trans.Name(), "claude-sonnet-4-20250514", nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(proxyReq)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if err := adaptBedrockStreamResponse(resp); err != nil {
+		t.Fatalf("adaptBedrockStreamResponse failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	in, out, text := p.handleStreamingResponse(rec, resp, endpoint, trans, trans.Name(), false, "claude-sonnet-4-20250514", clientBody, 0)
+	if in != 11 || out != 3 || text != "Hello" {
+		t.Fatalf("expected usage 11/3 and text Hello, got %d/%d %q", in, out, text)
+	}
+	result := rec.Body.String()
+	if !strings.Contains(result, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\"") {
+		t.Fatalf("expected Claude SSE output, got %s", result)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("expected SSE content type, got %s", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestBedrockAPIKeyAuthUsesBearerToken(t *testing.T) {
+	endpoint := config.Endpoint{
+		Name:        "Bedrock",
+		APIUrl:      "https://bedrock-runtime.eu-west-1.amazonaws.com",
+		APIKey:      "bedrock-api-key",
+		AuthMode:    config.AuthModeAPIKey,
+		Transformer: config.BedrockTransformer,
+		Options:     &config.EndpointOptions{Bedrock: &config.BedrockOptions{Region: "eu-west-1"}},
+	}
+	r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	body := []byte(`{"model":"claude-3-5-sonnet-20241022","max_tokens":64,"messages":[]}`)
+	proxyReq, err := buildProxyRequest(r, endpoint, endpoint.APIKey, body, "cx_chat_bedrock", "", nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	if proxyReq.URL.EscapedPath() != "/model/eu.anthropic.claude-3-5-sonnet-20241022-v2%3A0/invoke" {
+		t.Fatalf("unexpected path %s", proxyReq.URL.EscapedPath())
+	}
+	if proxyReq.Header.Get("Authorization") != "Bearer bedrock-api-key" {
+		t.Fatalf("expected bearer auth, got %q", proxyReq.Header.Get("Authorization"))
+	}
+	sent, _ := io.ReadAll(proxyReq.Body)
+	if strings.Contains(string(sent), `"model"`) || strings.Contains(string(sent), `"stream"`) {
+		t.Fatalf("model and stream must not be sent to Bedrock, got %s", sent)
+	}
+}
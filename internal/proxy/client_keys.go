@@ -0,0 +1,266 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+type clientKeyContextKey struct{}
+
+// clientKeyFromRequest returns the client key a request was authorized with,
+// or nil when client keys are not in use
+func clientKeyFromRequest(r *http.Request) *storage.ClientKey {
+	key, _ := r.Context().Value(clientKeyContextKey{}).(*storage.ClientKey)
+	return key
+}
+
+// clientSecret returns the key the client presented, in the places Claude,
+// OpenAI and Gemini clients put their API key
+func clientSecret(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("x-api-key")); key != "" {
+		return key
+	}
+	if auth := strings.TrimSpace(r.Header.Get("Authorization")); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	if key := strings.TrimSpace(r.Header.Get("x-goog-api-key")); key != "" {
+		return key
+	}
+	return strings.TrimSpace(r.URL.Query().Get("key"))
+}
+
+// clientKeyError is a request refused on behalf of a client key
+type clientKeyError struct {
+	status     int
+	errType    string
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *clientKeyError) Error() string { return e.message }
+
+func writeClientKeyError(w http.ResponseWriter, err *clientKeyError) {
+	w.Header().Set("Content-Type", "application/json")
+	if err.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((err.retryAfter+time.Second-1)/time.Second)))
+	}
+	w.WriteHeader(err.status)
+	body, _ := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    err.errType,
+			"message": err.message,
+		},
+	})
+	_, _ = w.Write(body)
+}
+
+func clientKeyForbidden(format string, args ...interface{}) *clientKeyError {
+	return &clientKeyError{status: http.StatusForbidden, errType: "permission_error", message: fmt.Sprintf(format, args...)}
+}
+
+// clientKeyLimiter counts the requests of each client key in fixed
+// one-minute windows
+type clientKeyLimiter struct {
+	mu      sync.Mutex
+	windows map[int64]*clientKeyWindow
+}
+
+type clientKeyWindow struct {
+	start time.Time
+	count int
+}
+
+func newClientKeyLimiter() *clientKeyLimiter {
+	return &clientKeyLimiter{windows: make(map[int64]*clientKeyWindow)}
+}
+
+// allow counts a request and returns how long to wait when the key is over
+// its limit
+func (l *clientKeyLimiter) allow(key *storage.ClientKey, now time.Time) (bool, time.Duration) {
+	if key.RequestsPerMinute <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window := l.windows[key.ID]
+	if window == nil || now.Sub(window.start) >= time.Minute {
+		window = &clientKeyWindow{start: now}
+		l.windows[key.ID] = window
+	}
+	if window.count >= key.RequestsPerMinute {
+		return false, window.start.Add(time.Minute).Sub(now)
+	}
+	window.count++
+	return true, 0
+}
+
+// withClientKey guards a proxy route with client keys. Once any key has been
+// issued every request needs a valid one; before that the routes stay open.
+func (p *Proxy) withClientKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := p.authorizeClientKey(r)
+		if err != nil {
+			logger.Warn("Client key rejected for %s: %s", r.URL.Path, err.message)
+			writeClientKeyError(w, err)
+			return
+		}
+		if key != nil {
+			r = r.WithContext(context.WithValue(r.Context(), clientKeyContextKey{}, key))
+		}
+		next(w, r)
+	}
+}
+
+func (p *Proxy) authorizeClientKey(r *http.Request) (*storage.ClientKey, *clientKeyError) {
+	if p.storage == nil {
+		return nil, nil
+	}
+	inUse, err := p.storage.HasClientKeys()
+	if err != nil {
+		logger.Error("Failed to check client keys: %v", err)
+		return nil, &clientKeyError{status: http.StatusInternalServerError, errType: "api_error", message: "Failed to verify client key"}
+	}
+	if !inUse {
+		return nil, nil
+	}
+
+	unauthorized := func(message string) *clientKeyError {
+		return &clientKeyError{status: http.StatusUnauthorized, errType: "authentication_error", message: message}
+	}
+	secret := clientSecret(r)
+	if secret == "" {
+		return nil, unauthorized("Missing client API key")
+	}
+	key, err := p.storage.GetClientKeyBySecret(secret)
+	if err != nil {
+		logger.Error("Failed to look up client key: %v", err)
+		return nil, &clientKeyError{status: http.StatusInternalServerError, errType: "api_error", message: "Failed to verify client key"}
+	}
+	if key == nil {
+		return nil, unauthorized("Invalid client API key")
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, unauthorized(fmt.Sprintf("Client API key %s has been revoked", key.Name))
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, unauthorized(fmt.Sprintf("Client API key %s has expired", key.Name))
+	}
+	if key.DailyTokenQuota > 0 {
+		used, err := p.storage.GetClientKeyTokens(key.ID, now.Format("2006-01-02"))
+		if err != nil {
+			logger.Warn("Failed to read token usage of client key %s: %v", key.Name, err)
+		} else if used >= key.DailyTokenQuota {
+			midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			return nil, &clientKeyError{
+				status:     http.StatusTooManyRequests,
+				errType:    "rate_limit_error",
+				message:    fmt.Sprintf("Client API key %s used its daily quota of %d tokens", key.Name, key.DailyTokenQuota),
+				retryAfter: midnight.Sub(now),
+			}
+		}
+	}
+	if ok, wait := p.clientKeyLimiter.allow(key, now); !ok {
+		return nil, &clientKeyError{
+			status:     http.StatusTooManyRequests,
+			errType:    "rate_limit_error",
+			message:    fmt.Sprintf("Client API key %s is limited to %d requests per minute", key.Name, key.RequestsPerMinute),
+			retryAfter: wait,
+		}
+	}
+	return key, nil
+}
+
+// clientKeyAllowsEndpoint reports whether a client key may use an endpoint,
+// being allowed either by name or by the endpoint's group. Without allowed
+// endpoints and groups every endpoint is allowed.
+func clientKeyAllowsEndpoint(key *storage.ClientKey, endpoint config.Endpoint) bool {
+	if key == nil || len(key.AllowedEndpoints) == 0 && len(key.AllowedGroups) == 0 {
+		return true
+	}
+	for _, allowed := range key.AllowedEndpoints {
+		if allowed == endpoint.Name {
+			return true
+		}
+	}
+	if group := endpoint.Options.GroupName(); group != "" {
+		for _, allowed := range key.AllowedGroups {
+			if allowed == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clientKeyAllowsModel reports whether a client key may request a model.
+// Allowed models may be glob patterns such as claude-sonnet-*.
+func clientKeyAllowsModel(key *storage.ClientKey, model string) bool {
+	if key == nil || len(key.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range key.AllowedModels {
+		if matched, _ := path.Match(pattern, model); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeClientKeyRequest applies the endpoint and model allowlists of the
+// request's client key, narrowing the candidate endpoints
+func authorizeClientKeyRequest(key *storage.ClientKey, endpoints []config.Endpoint, specified *config.Endpoint, model string) ([]config.Endpoint, *clientKeyError) {
+	if key == nil {
+		return endpoints, nil
+	}
+	if !clientKeyAllowsModel(key, model) {
+		return nil, clientKeyForbidden("Client API key %s may not use model %q", key.Name, model)
+	}
+	if specified != nil && !clientKeyAllowsEndpoint(key, *specified) {
+		return nil, clientKeyForbidden("Client API key %s may not use endpoint %s", key.Name, specified.Name)
+	}
+
+	allowed := make([]config.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if clientKeyAllowsEndpoint(key, ep) {
+			allowed = append(allowed, ep)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, clientKeyForbidden("Client API key %s may not use any enabled endpoint", key.Name)
+	}
+	return allowed, nil
+}
+
+// recordClientKeyUsage attributes a request's outcome to its client key
+func (p *Proxy) recordClientKeyUsage(key *storage.ClientKey, endpointName string, requests, errors, inputTokens, outputTokens int) {
+	if key == nil || p.storage == nil {
+		return
+	}
+	date := time.Now().Format("2006-01-02")
+	if err := p.storage.RecordClientKeyUsage(key.ID, endpointName, date, requests, errors, inputTokens, outputTokens); err != nil {
+		logger.Warn("Failed to record usage of client key %s: %v", key.Name, err)
+	}
+}
+
+// clientKeyLabel names the client key of a request in log lines
+func clientKeyLabel(key *storage.ClientKey) string {
+	if key == nil {
+		return "-"
+	}
+	return key.Name
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/mockupstream"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func newClientKeyTestProxy(t *testing.T, endpoints []config.Endpoint) (*Proxy, *storage.SQLiteStorage) {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "ccnexus.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	cfg := config.DefaultConfig()
+	cfg.UpdateEndpoints(endpoints)
+	return New(cfg, &recordingStatsStorage{}, store, "test"), store
+}
+
+func sendKeyedRequest(p *Proxy, secret, model string) *httptest.ResponseRecorder {
+	body := `{"model":"` + model + `","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	if secret != "" {
+		r.Header.Set("x-api-key", secret)
+	}
+	rec := httptest.NewRecorder()
+	p.withClientKey(p.handleProxy)(rec, r)
+	return rec
+}
+
+func TestClientKeysGuardProxyRoutes(t *testing.T) {
+	_, firstURL := newMockUpstream(t, mockupstream.Script{Default: mockupstream.Behavior{Text: "from first"}})
+	second, secondURL := newMockUpstream(t, mockupstream.Script{Default: mockupstream.Behavior{Text: "from second"}})
+	p, store := newClientKeyTestProxy(t, []config.Endpoint{
+		{Name: "First", APIUrl: firstURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+		{Name: "Second", APIUrl: secondURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+	})
+
+	// Without keys the proxy stays open
+	if rec := sendKeyedRequest(p, "", "claude-sonnet-4-5"); rec.Code != http.StatusOK {
+		t.Fatalf("expected an open proxy before keys are issued, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	team := &storage.ClientKey{Name: "team", AllowedEndpoints: []string{"Second"}, AllowedModels: []string{"claude-sonnet-*"}, RequestsPerMinute: 2}
+	secret, err := store.CreateClientKey(team)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := sendKeyedRequest(p, "", "claude-sonnet-4-5"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a missing key to be rejected, got %d", rec.Code)
+	}
+	if rec := sendKeyedRequest(p, "ccn-unknown", "claude-sonnet-4-5"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an unknown key to be rejected, got %d", rec.Code)
+	}
+	if rec := sendKeyedRequest(p, secret, "claude-opus-4-1"); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "permission_error") {
+		t.Fatalf("expected a disallowed model to be forbidden, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The current endpoint is First, which the key may not use
+	rec := sendKeyedRequest(p, secret, "claude-sonnet-4-5")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "from second") {
+		t.Fatalf("expected the allowed endpoint to answer, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, req := range second.Requests() {
+		if req.Header.Get("x-api-key") == secret || strings.Contains(req.Header.Get("Authorization"), secret) {
+			t.Fatal("the client key was forwarded upstream")
+		}
+	}
+
+	// The forbidden request counted toward the limit of two per minute
+	rec = sendKeyedRequest(p, secret, "claude-sonnet-4-5")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected the rate limit to apply, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	usage, err := store.GetClientKeyUsage(time.Now().Format("2006-01-02"), time.Now().Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].EndpointName != "Second" || usage[0].Requests != 1 || usage[0].InputTokens == 0 {
+		t.Fatalf("expected usage attributed to the key on Second, got %+v", usage)
+	}
+
+	// Rotation retires the old secret; revocation keeps the proxy closed
+	rotated, err := store.RotateClientKey(team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.clientKeyLimiter = newClientKeyLimiter()
+	if rec := sendKeyedRequest(p, secret, "claude-sonnet-4-5"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the rotated-out secret to be rejected, got %d", rec.Code)
+	}
+	if rec := sendKeyedRequest(p, rotated, "claude-sonnet-4-5"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the new secret to work, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := store.RevokeClientKey(team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rec := sendKeyedRequest(p, rotated, "claude-sonnet-4-5"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked key to be rejected, got %d", rec.Code)
+	}
+	if rec := sendKeyedRequest(p, "", "claude-sonnet-4-5"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the proxy to stay closed after revoking the last key, got %d", rec.Code)
+	}
+}
+
+func TestClientKeyAllowedGroups(t *testing.T) {
+	_, personalURL := newMockUpstream(t, mockupstream.Script{Default: mockupstream.Behavior{Text: "from personal"}})
+	_, teamURL := newMockUpstream(t, mockupstream.Script{Default: mockupstream.Behavior{Text: "from team"}})
+	p, store := newClientKeyTestProxy(t, []config.Endpoint{
+		{Name: "Personal", APIUrl: personalURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+		{Name: "Team", APIUrl: teamURL, APIKey: "k", Enabled: true, Transformer: "claude", Options: &config.EndpointOptions{Group: "team-a"}},
+	})
+	secret, err := store.CreateClientKey(&storage.ClientKey{Name: "team", AllowedGroups: []string{"team-a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.GetClientKeyByID(1)
+	if err != nil || len(stored.AllowedGroups) != 1 || stored.AllowedGroups[0] != "team-a" {
+		t.Fatalf("expected the allowed groups to be stored, got %+v (%v)", stored, err)
+	}
+
+	rec := sendKeyedRequest(p, secret, "claude-sonnet-4-5")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "from team") {
+		t.Fatalf("expected the endpoint in the allowed group to answer, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Leaving the group takes the endpoint away from the key
+	p.config.UpdateEndpoints([]config.Endpoint{
+		{Name: "Personal", APIUrl: personalURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+		{Name: "Team", APIUrl: teamURL, APIKey: "k", Enabled: true, Transformer: "claude", Options: &config.EndpointOptions{Group: "team-b"}},
+	})
+	if rec := sendKeyedRequest(p, secret, "claude-sonnet-4-5"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected no endpoint to be allowed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRestrictedClientKeyFailuresKeepCurrentEndpoint(t *testing.T) {
+	unavailable := mockupstream.Script{Default: mockupstream.Behavior{Status: http.StatusServiceUnavailable}}
+	a, aURL := newMockUpstream(t, unavailable)
+	b, bURL := newMockUpstream(t, unavailable)
+	c, cURL := newMockUpstream(t, unavailable)
+	p, store := newClientKeyTestProxy(t, []config.Endpoint{
+		{Name: "A", APIUrl: aURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+		{Name: "B", APIUrl: bURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+		{Name: "C", APIUrl: cURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+	})
+	secret, err := store.CreateClientKey(&storage.ClientKey{Name: "only-c", AllowedEndpoints: []string{"C"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if rec := sendKeyedRequest(p, secret, "claude-sonnet-4-5"); rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected the failure to reach the client, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if p.currentIndex != 0 {
+			t.Fatalf("request %d: failures on an endpoint only the key uses must not rotate the current one, got %s", i+1, p.getCurrentEndpoint().Name)
+		}
+	}
+	if len(a.Requests())+len(b.Requests()) != 0 || len(c.Requests()) == 0 {
+		t.Fatalf("expected only C to be tried, got A=%d B=%d C=%d", len(a.Requests()), len(b.Requests()), len(c.Requests()))
+	}
+}
+
+func TestClientKeyQuotaAndExpiry(t *testing.T) {
+	_, url := newMockUpstream(t, mockupstream.Script{Default: mockupstream.Behavior{Text: "hello"}})
+	p, store := newClientKeyTestProxy(t, []config.Endpoint{
+		{Name: "Mock", APIUrl: url, APIKey: "k", Enabled: true, Transformer: "claude"},
+	})
+
+	quota := &storage.ClientKey{Name: "quota", DailyTokenQuota: 1}
+	secret, err := store.CreateClientKey(quota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := sendKeyedRequest(p, secret, "claude-sonnet-4-5"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first request to pass, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := sendKeyedRequest(p, secret, "claude-sonnet-4-5"); rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "daily quota") {
+		t.Fatalf("expected the token quota to apply, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	past := time.Now().Add(-time.Minute)
+	expired := &storage.ClientKey{Name: "expired", ExpiresAt: &past}
+	secret, err = store.CreateClientKey(expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"m","messages":[]}`))
+	r.Header.Set("Authorization", "Bearer "+secret)
+	rec := httptest.NewRecorder()
+	p.withClientKey(p.handleProxy)(rec, r)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "expired") {
+		t.Fatalf("expected an expired key to be rejected, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
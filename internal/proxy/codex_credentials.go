@@ -17,11 +17,14 @@ import (
 )
 
 const (
-	codexOAuthTokenURL  = "https://auth.openai.com/oauth/token"
 	codexOAuthClientID  = "app_EMoamEEZ73f0CkXaXp7hrann"
 	codexRefreshTimeout = 45 * time.Second
 )
 
+// codexOAuthTokenURL is where Codex refresh tokens are exchanged; tests point
+// it at a mock upstream
+var codexOAuthTokenURL = "https://auth.openai.com/oauth/token"
+
 type codexRefreshTokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/modelcaps"
+	"github.com/lich0821/ccNexus/internal/tokencount"
+)
+
+// contextLengthError reports that the request does not fit the context window
+// of any endpoint it could be sent to
+type contextLengthError struct {
+	inputTokens int
+	limit       int // Largest context window among the candidate endpoints
+}
+
+func (e *contextLengthError) Error() string {
+	return fmt.Sprintf("prompt is too long: %d tokens > %d maximum", e.inputTokens, e.limit)
+}
+
+// estimatedInputTokens estimates the request's input tokens on first use
+func (reqCtx *proxyRequestContext) estimatedInputTokens() int {
+	if reqCtx.inputTokens > 0 {
+		return reqCtx.inputTokens
+	}
+	if reqCtx.clientFormat == ClientFormatClaude {
+		var req tokencount.CountTokensRequest
+		if json.Unmarshal(reqCtx.bodyBytes, &req) == nil {
+			reqCtx.inputTokens = tokencount.EstimateInputTokens(&req)
+		}
+	} else {
+		reqCtx.inputTokens = tokencount.EstimateRequestTokens(reqCtx.bodyBytes)
+	}
+	return reqCtx.inputTokens
+}
+
+// contextWindow returns the context window of the model the endpoint would
+// serve the request with, or 0 when it is unknown. Endpoint options win over
+// Ollama's num_ctx, which wins over the model capabilities table.
+func contextWindow(reqCtx *proxyRequestContext, endpoint config.Endpoint) int {
+	model := resolveAttemptModelName(reqCtx, endpoint)
+	if window := endpoint.Options.ContextWindow(model); window > 0 {
+		return window
+	}
+	if endpoint.Transformer == config.OllamaTransformer && endpoint.Options != nil && endpoint.Options.Ollama != nil && endpoint.Options.Ollama.NumCtx > 0 {
+		return endpoint.Options.Ollama.NumCtx
+	}
+	return modelcaps.ContextWindow(model)
+}
+
+// fitsContextWindow reports whether the estimated input fits the endpoint.
+// Endpoints whose window is unknown are always tried.
+func fitsContextWindow(reqCtx *proxyRequestContext, endpoint config.Endpoint) (bool, int) {
+	window := contextWindow(reqCtx, endpoint)
+	return window == 0 || reqCtx.estimatedInputTokens() <= window, window
+}
+
+// writeContextLengthError writes a context length error in the client's
+// format. The wording matches each provider so clients start compaction.
+func writeContextLengthError(w http.ResponseWriter, clientFormat ClientFormat, err *contextLengthError) {
+	var body map[string]interface{}
+	switch clientFormat {
+	case ClientFormatOpenAIChat, ClientFormatOpenAIResponses:
+		body = map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": fmt.Sprintf("This model's maximum context length is %d tokens. However, your messages resulted in %d tokens.", err.limit, err.inputTokens),
+				"type":    "invalid_request_error",
+				"param":   "messages",
+				"code":    "context_length_exceeded",
+			},
+		}
+	case ClientFormatGemini:
+		body = map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("The input token count (%d) exceeds the maximum number of tokens allowed (%d).", err.inputTokens, err.limit),
+				"status":  "INVALID_ARGUMENT",
+			},
+		}
+	default:
+		body = map[string]interface{}{
+			"type": "error",
+			"error": map[string]interface{}{
+				"type":    "invalid_request_error",
+				"message": err.Error(),
+			},
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(body)
+}
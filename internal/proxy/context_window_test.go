@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+// longClaudeRequest returns a Claude request of roughly 5000 estimated tokens
+func longClaudeRequest() string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-5",
+		"max_tokens": 100,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": strings.Repeat("word ", 4000)},
+		},
+	})
+	return string(body)
+}
+
+func TestContextWindowSkipsEndpointsThatCannotFit(t *testing.T) {
+	small := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach the endpoint with the small window")
+	}))
+	defer small.Close()
+	large := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":5000,"output_tokens":1}}`))
+	}))
+	defer large.Close()
+
+	p, statsStorage := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Small", APIUrl: small.URL, APIKey: "k1", Enabled: true, Transformer: "claude",
+			Options: &config.EndpointOptions{Context: &config.ContextOptions{Window: 1000}}},
+		{Name: "Large", APIUrl: large.URL, APIKey: "k2", Enabled: true, Transformer: "claude"},
+	})
+
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(longClaudeRequest()))
+	rec := httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	if requests, errors, _ := statsStorage.totals("Small"); requests+errors != 0 {
+		t.Fatalf("skipped endpoint must not be counted, got %d requests %d errors", requests, errors)
+	}
+	if current := p.getCurrentEndpoint().Name; current != "Small" {
+		t.Fatalf("skipping must not rotate the current endpoint, got %s", current)
+	}
+}
+
+func TestContextWindowExceededOnAllEndpoints(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not be sent")
+	}))
+	defer upstream.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "A", APIUrl: upstream.URL, APIKey: "k1", Enabled: true, Transformer: "claude",
+			Options: &config.EndpointOptions{Context: &config.ContextOptions{Window: 1000}}},
+		{Name: "B", APIUrl: upstream.URL, APIKey: "k2", Enabled: true, Transformer: "openai", Model: "gpt-4",
+			Options: &config.EndpointOptions{Context: &config.ContextOptions{Models: map[string]int{"gpt-4": 2000}}}},
+	})
+
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(longClaudeRequest()))
+	rec := httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "prompt is too long") || !strings.Contains(rec.Body.String(), "> 2000 maximum") {
+		t.Fatalf("expected a Claude context error, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	chatBody := `{"model":"gpt-4","messages":[{"role":"user","content":"` + strings.Repeat("word ", 4000) + `"}]}`
+	r = httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(chatBody))
+	rec = httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+
+	var resp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusBadRequest || resp.Error.Code != "context_length_exceeded" {
+		t.Fatalf("expected context_length_exceeded, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestContextWindowSkippedEndpointStreamsToCompletion(t *testing.T) {
+	large := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":5000}}}\n\n"))
+		w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"ok\"}}\n\n"))
+		w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer large.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Small", APIUrl: large.URL, APIKey: "k1", Enabled: true, Transformer: "claude",
+			Options: &config.EndpointOptions{Context: &config.ContextOptions{Window: 1000}}},
+		{Name: "Large", APIUrl: large.URL, APIKey: "k2", Enabled: true, Transformer: "claude"},
+	})
+
+	body := strings.Replace(longClaudeRequest(), `"max_tokens":100`, `"max_tokens":100,"stream":true`, 1)
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+
+	if !strings.Contains(rec.Body.String(), "message_stop") {
+		t.Fatalf("stream from a non-current endpoint must not be cut off, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestContextWindowFailoverKeepsCurrentEndpoint(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":5000,"output_tokens":1}}`))
+	}))
+	defer healthy.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Small", APIUrl: healthy.URL, APIKey: "k1", Enabled: true, Transformer: "claude",
+			Options: &config.EndpointOptions{Context: &config.ContextOptions{Window: 1000}}},
+		{Name: "Failing", APIUrl: failing.URL, APIKey: "k2", Enabled: true, Transformer: "claude"},
+		{Name: "Large", APIUrl: healthy.URL, APIKey: "k3", Enabled: true, Transformer: "claude"},
+	})
+
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(longClaudeRequest()))
+	rec := httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the request to fail over to Large, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if current := p.getCurrentEndpoint().Name; current != "Small" {
+		t.Fatalf("a failing endpoint picked for size must not rotate the current endpoint, got %s", current)
+	}
+}
+
+func TestContextWindowErrorOnlyWhenSkippedForSize(t *testing.T) {
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Small", APIUrl: "http://small", APIKey: "k1", Enabled: true, Transformer: "claude",
+			Options: &config.EndpointOptions{Context: &config.ContextOptions{Window: 1000}}},
+		{Name: "Other", APIUrl: "http://other", APIKey: "k2", Enabled: true, Transformer: "claude"},
+	})
+	newReqCtx := func(allowed ...string) *proxyRequestContext {
+		return &proxyRequestContext{
+			bodyBytes:    []byte(longClaudeRequest()),
+			clientFormat: ClientFormatClaude,
+			clientKey:    &storage.ClientKey{Name: "ci", AllowedEndpoints: allowed},
+		}
+	}
+
+	// The key may use the small endpoint only: it was skipped for size
+	_, err := p.nextEndpointForRequest(newReqCtx("Small"))
+	var ctxErr *contextLengthError
+	if !errors.As(err, &ctxErr) || ctxErr.limit != 1000 {
+		t.Fatalf("expected a context length error with limit 1000, got %v", err)
+	}
+
+	// The key may use no enabled endpoint: the allowlist, not the size, is why
+	_, err = p.nextEndpointForRequest(newReqCtx("Removed"))
+	var keyErr *clientKeyError
+	if !errors.As(err, &keyErr) || keyErr.status != http.StatusForbidden {
+		t.Fatalf("expected the key to be refused, got %v", err)
+	}
+}
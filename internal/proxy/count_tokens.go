@@ -0,0 +1,263 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/tokencount"
+	"github.com/lich0821/ccNexus/internal/transformer/cc"
+)
+
+// tokenCountMethodHeader tells the client how input_tokens was obtained:
+// "upstream", "bpe:<encoding>" or "estimate"
+const tokenCountMethodHeader = "X-CCN-Token-Count-Method"
+
+const (
+	tokenCountMethodUpstream = "upstream"
+	tokenCountMethodEstimate = "estimate"
+)
+
+// responsesInputTokenFields are the Responses request fields accepted by
+// /v1/responses/input_tokens
+var responsesInputTokenFields = []string{"model", "input", "instructions", "tools", "tool_choice", "parallel_tool_calls", "reasoning", "text", "truncation"}
+
+// handleCountTokens serves /v1/messages/count_tokens. The request is counted
+// by the upstream of the selected endpoint when it has a native count API;
+// otherwise the model family's BPE vocabulary is used, and the heuristic
+// estimate only when no vocabulary can be loaded.
+func (p *Proxy) handleCountTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("Failed to read count_tokens request: %v", err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var req tokencount.CountTokensRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		logger.Error("Failed to decode count_tokens request: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	specifiedEndpoint, modelOverride, resolveErr := p.resolver.ResolveEndpoint(r, bodyBytes)
+	if resolveErr != nil {
+		logger.Warn("端点解析失败: %v", resolveErr)
+		writeInvalidRequestError(w, resolveErr.Error())
+		return
+	}
+	endpoint := p.getCurrentEndpoint()
+	if specifiedEndpoint != nil {
+		endpoint = *specifiedEndpoint
+	}
+	model := modelOverride
+	if model == "" {
+		model = req.Model
+	}
+	clientKey := clientKeyFromRequest(r)
+	if _, keyErr := authorizeClientKeyRequest(clientKey, p.getEnabledEndpoints(), specifiedEndpoint, model); keyErr != nil {
+		writeClientKeyError(w, keyErr)
+		return
+	}
+	if !clientKeyAllowsEndpoint(clientKey, endpoint) {
+		// Count locally rather than through an endpoint the key may not use
+		endpoint = config.Endpoint{}
+	}
+
+	reqCtx := &proxyRequestContext{
+		httpRequest:                 r,
+		bodyBytes:                   bodyBytes,
+		requestModel:                req.Model,
+		modelOverride:               modelOverride,
+		refreshedCredentialAttempts: make(map[int64]bool),
+		mintedTokenRetried:          make(map[string]bool),
+	}
+	if endpoint.Name != "" && supportsUpstreamTokenCount(endpoint) {
+		tokens, err := p.countTokensUpstream(reqCtx, endpoint)
+		if err == nil {
+			writeTokenCount(w, tokens, tokenCountMethodUpstream)
+			return
+		}
+		logger.Debug("[%s] Upstream token count failed, counting locally: %v", endpoint.Name, err)
+	}
+
+	// Pick the vocabulary by the model the endpoint would actually serve
+	req.Model = resolveAttemptModelName(reqCtx, endpoint)
+	tokens, encoding := tokencount.CountInputTokens(&req)
+	method := tokenCountMethodEstimate
+	if encoding != "" {
+		method = "bpe:" + encoding
+	}
+	writeTokenCount(w, tokens, method)
+}
+
+func writeTokenCount(w http.ResponseWriter, tokens int, method string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(tokenCountMethodHeader, method)
+	json.NewEncoder(w).Encode(tokencount.CountTokensResponse{InputTokens: tokens})
+}
+
+// supportsUpstreamTokenCount reports whether the endpoint's upstream has a
+// native count API: Claude count_tokens, Gemini countTokens or Responses
+// input_tokens. The Codex backend and Azure have none.
+func supportsUpstreamTokenCount(endpoint config.Endpoint) bool {
+	switch strings.ToLower(endpoint.Transformer) {
+	case "claude", "gemini":
+		return true
+	case "openai2":
+		if endpoint.IsAzureOpenAI() || config.NormalizeAuthMode(endpoint.AuthMode) == config.AuthModeCodexTokenPool {
+			return false
+		}
+		return !isCodexBackendBaseURL(normalizeAPIUrl(endpoint.APIUrl))
+	default:
+		return false
+	}
+}
+
+// countTokensUpstream asks the endpoint's upstream to count the request. Any
+// failure is returned so the caller can count locally instead.
+func (p *Proxy) countTokensUpstream(reqCtx *proxyRequestContext, endpoint config.Endpoint) (int, error) {
+	attempt := &endpointAttempt{endpoint: endpoint}
+	if result := p.resolveAttemptAuth(reqCtx, attempt); result != attemptResultDone {
+		return 0, fmt.Errorf("no usable credential")
+	}
+	model := resolveAttemptModelName(reqCtx, endpoint)
+
+	proxyReq, err := buildCountTokensRequest(reqCtx.httpRequest, endpoint, attempt.apiKey, reqCtx.bodyBytes, model)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := sendRequest(p.getEndpointContext(endpoint.Name), proxyReq, p.httpClient, p.config)
+	if err != nil {
+		return 0, err
+	}
+	respBody := readResponseBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d: %s", resp.StatusCode, truncateString(string(respBody), 200))
+	}
+
+	var count struct {
+		InputTokens *int `json:"input_tokens"`
+		TotalTokens *int `json:"totalTokens"`
+	}
+	if err := json.Unmarshal(respBody, &count); err != nil {
+		return 0, err
+	}
+	switch {
+	case count.InputTokens != nil:
+		return *count.InputTokens, nil
+	case count.TotalTokens != nil:
+		return *count.TotalTokens, nil
+	}
+	return 0, fmt.Errorf("response has no token count")
+}
+
+// buildCountTokensRequest creates the native count request for the
+// endpoint's upstream from a Claude count_tokens body
+func buildCountTokensRequest(r *http.Request, endpoint config.Endpoint, apiKey string, claudeBody []byte, model string) (*http.Request, error) {
+	baseURL := strings.TrimSuffix(normalizeAPIUrl(endpoint.APIUrl), "/")
+	var targetURL string
+	var body []byte
+
+	switch strings.ToLower(endpoint.Transformer) {
+	case "gemini":
+		geminiBody, err := geminiCountTokensBody(claudeBody, model)
+		if err != nil {
+			return nil, err
+		}
+		body = geminiBody
+		targetURL = fmt.Sprintf("%s/v1beta/models/%s:countTokens?%s", baseURL, url.PathEscape(model), url.Values{"key": {apiKey}}.Encode())
+	case "openai2":
+		responsesBody, err := responsesCountTokensBody(claudeBody, model)
+		if err != nil {
+			return nil, err
+		}
+		body = responsesBody
+		targetURL = baseURL + "/v1/responses/input_tokens"
+	default:
+		body = overrideModelInPayload(claudeBody, model)
+		targetURL = baseURL + "/v1/messages/count_tokens"
+		if r.URL.RawQuery != "" {
+			targetURL += "?" + r.URL.RawQuery
+		}
+	}
+
+	proxyReq, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		switch key {
+		case "Host", "Accept-Encoding", "Content-Length", "Authorization", "X-Api-Key", "X-Goog-Api-Key":
+			continue
+		}
+		for _, value := range values {
+			proxyReq.Header.Add(key, value)
+		}
+	}
+	proxyReq.Header.Set("Accept-Encoding", "gzip, identity")
+	proxyReq.Header.Set("Content-Type", "application/json")
+
+	switch strings.ToLower(endpoint.Transformer) {
+	case "gemini":
+		// Key is sent as a query parameter
+	case "openai2":
+		proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
+	default:
+		proxyReq.Header.Set("x-api-key", apiKey)
+		proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	return proxyReq, nil
+}
+
+// geminiCountTokensBody wraps the converted generateContent request so that
+// the system instruction and tools are counted too
+func geminiCountTokensBody(claudeBody []byte, model string) ([]byte, error) {
+	converted, err := cc.NewGeminiTransformer(model).TransformRequest(claudeBody)
+	if err != nil {
+		return nil, err
+	}
+	var generateReq map[string]interface{}
+	if err := json.Unmarshal(converted, &generateReq); err != nil {
+		return nil, err
+	}
+	delete(generateReq, "stream")
+	generateReq["model"] = "models/" + model
+	return json.Marshal(map[string]interface{}{"generateContentRequest": generateReq})
+}
+
+// responsesCountTokensBody converts the request to the Responses format and
+// keeps only the fields the input_tokens endpoint accepts
+func responsesCountTokensBody(claudeBody []byte, model string) ([]byte, error) {
+	converted, err := cc.NewOpenAI2Transformer(model).TransformRequest(claudeBody)
+	if err != nil {
+		return nil, err
+	}
+	var responsesReq map[string]interface{}
+	if err := json.Unmarshal(converted, &responsesReq); err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(responsesInputTokenFields))
+	for _, field := range responsesInputTokenFields {
+		if v, ok := responsesReq[field]; ok {
+			out[field] = v
+		}
+	}
+	if model != "" {
+		out["model"] = model
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+const countTokensBody = `{"model":"claude-sonnet-4-5","system":"Be brief.","messages":[{"role":"user","content":"hello world"}],"tools":[{"name":"get_weather","description":"Get the weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}]}`
+
+func countTokens(t *testing.T, p *Proxy) (int, string) {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/v1/messages/count_tokens?beta=true", strings.NewReader(countTokensBody))
+	rec := httptest.NewRecorder()
+	p.handleCountTokens(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	return resp.InputTokens, rec.Header().Get(tokenCountMethodHeader)
+}
+
+func TestCountTokensForwardsToClaude(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages/count_tokens" || r.URL.RawQuery != "beta=true" || r.Header.Get("x-api-key") != "ckey" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.String(), r.Header.Get("x-api-key"))
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "claude-opus-4-1" || body["tools"] == nil {
+			t.Errorf("unexpected body: %v", body)
+		}
+		w.Write([]byte(`{"input_tokens":421}`))
+	}))
+	defer upstream.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Claude", APIUrl: upstream.URL, APIKey: "ckey", Enabled: true, Transformer: "claude", Model: "claude-opus-4-1"},
+	})
+	if tokens, method := countTokens(t, p); tokens != 421 || method != tokenCountMethodUpstream {
+		t.Fatalf("expected 421 from upstream, got %d via %q", tokens, method)
+	}
+}
+
+func TestCountTokensForwardsToGemini(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-pro:countTokens" || r.URL.Query().Get("key") != "gkey" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		var body struct {
+			GenerateContentRequest map[string]interface{} `json:"generateContentRequest"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		req := body.GenerateContentRequest
+		if req["model"] != "models/gemini-2.5-pro" || req["contents"] == nil || req["systemInstruction"] == nil || req["tools"] == nil {
+			t.Errorf("unexpected body: %v", req)
+		}
+		w.Write([]byte(`{"totalTokens":77}`))
+	}))
+	defer upstream.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Gemini", APIUrl: upstream.URL, APIKey: "gkey", Enabled: true, Transformer: "gemini", Model: "gemini-2.5-pro"},
+	})
+	if tokens, method := countTokens(t, p); tokens != 77 || method != tokenCountMethodUpstream {
+		t.Fatalf("expected 77 from upstream, got %d via %q", tokens, method)
+	}
+}
+
+func TestCountTokensForwardsToResponses(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/responses/input_tokens" || r.Header.Get("Authorization") != "Bearer okey" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "gpt-5" || body["input"] == nil {
+			t.Errorf("unexpected body: %v", body)
+		}
+		for _, field := range []string{"stream", "max_output_tokens", "store"} {
+			if _, ok := body[field]; ok {
+				t.Errorf("field %s must not be sent", field)
+			}
+		}
+		w.Write([]byte(`{"object":"response.input_tokens","input_tokens":12}`))
+	}))
+	defer upstream.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "OpenAI", APIUrl: upstream.URL, APIKey: "okey", Enabled: true, Transformer: "openai2", Model: "gpt-5"},
+	})
+	if tokens, method := countTokens(t, p); tokens != 12 || method != tokenCountMethodUpstream {
+		t.Fatalf("expected 12 from upstream, got %d via %q", tokens, method)
+	}
+}
+
+func TestCountTokensFallsBackToBPE(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer failing.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Claude", APIUrl: failing.URL, APIKey: "ckey", Enabled: true, Transformer: "claude"},
+	})
+	tokens, method := countTokens(t, p)
+	if method != "bpe:cl100k_base" {
+		t.Fatalf("expected cl100k fallback, got %q", method)
+	}
+	if tokens <= 0 {
+		t.Fatalf("expected a positive count, got %d", tokens)
+	}
+
+	p, _ = newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Chat", APIUrl: failing.URL, APIKey: "okey", Enabled: true, Transformer: "openai", Model: "gpt-4o"},
+	})
+	r := httptest.NewRequest("POST", "/v1/messages/count_tokens", strings.NewReader(`{"model":"@Chat/gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`))
+	rec := httptest.NewRecorder()
+	p.handleCountTokens(rec, r)
+	if method := rec.Header().Get(tokenCountMethodHeader); method != "bpe:o200k_base" {
+		t.Fatalf("expected o200k for gpt-4o models, got %q", method)
+	}
+}
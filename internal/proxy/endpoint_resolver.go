@@ -59,6 +59,10 @@ func (r *EndpointResolver) ResolveEndpoint(req *http.Request, bodyBytes []byte)
 		json.Unmarshal(bodyBytes, &streamReq)
 	}
 	modelName := strings.TrimSpace(streamReq.Model)
+	if modelName == "" {
+		// Gemini 客户端在 URL 路径中携带模型名
+		modelName, _ = parseGeminiPath(req.URL.Path)
+	}
 
 	if modelName != "" && strings.HasPrefix(modelName, "@") {
 		endpointName, modelOverride := r.parseEndpointFromModel(modelName)
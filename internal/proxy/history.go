@@ -0,0 +1,536 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// Providers that issue tool-call IDs and thinking signatures
+const (
+	providerAnthropic = "anthropic"
+	providerOpenAI    = "openai"
+	providerGemini    = "gemini"
+)
+
+const (
+	provenanceStoreLimit           = 20000
+	signatureProvenancePrefix      = "sig:"
+	openAIEncryptedReasoningPrefix = "gAAAAA"
+	maxClaudeToolIDLength          = 64
+	maxOpenAIToolIDLength          = 40
+	thinkingPlaceholderText        = "(thinking...)"
+)
+
+var claudeToolIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// providerForTransformer returns the provider family behind an endpoint
+// transformer. Vertex AI serves both families, so the client transformer
+// chosen for the model decides.
+func providerForTransformer(endpointTransformer, transformerName string) string {
+	switch endpointTransformer {
+	case "openai", "openai2", config.OllamaTransformer:
+		return providerOpenAI
+	case "gemini":
+		return providerGemini
+	case config.VertexTransformer:
+		if isVertexGeminiTransformer(transformerName) {
+			return providerGemini
+		}
+		return providerAnthropic
+	default:
+		return providerAnthropic
+	}
+}
+
+// provenanceStore remembers which provider produced the tool-call IDs and
+// thinking signatures returned to clients, so a conversation that later moves
+// to another provider can be normalized. Oldest entries are evicted first.
+type provenanceStore struct {
+	mu      sync.Mutex
+	entries map[string]string
+	order   []string
+	limit   int
+}
+
+func newProvenanceStore(limit int) *provenanceStore {
+	return &provenanceStore{entries: make(map[string]string), limit: limit}
+}
+
+// record merges key -> provider entries collected from a response
+func (s *provenanceStore) record(entries map[string]string) {
+	if s == nil || len(entries) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, provider := range entries {
+		if _, exists := s.entries[key]; !exists {
+			s.order = append(s.order, key)
+		}
+		s.entries[key] = provider
+	}
+	for len(s.order) > s.limit {
+		delete(s.entries, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// lookup returns the provider that produced a key, or "" if unknown
+func (s *provenanceStore) lookup(key string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key]
+}
+
+// signatureKey returns the provenance key for a thinking signature
+func signatureKey(signature string) string {
+	sum := sha256.Sum256([]byte(signature))
+	return signatureProvenancePrefix + hex.EncodeToString(sum[:16])
+}
+
+// collectProvenance walks a client-facing payload and records every tool-call
+// ID and thinking signature it carries as produced by provider
+func collectProvenance(dst map[string]string, v interface{}, provider string) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		blockType, _ := node["type"].(string)
+		switch blockType {
+		case "tool_use", "server_tool_use":
+			if id, _ := node["id"].(string); id != "" {
+				dst[id] = provider
+			}
+		case "thinking", "signature_delta":
+			if sig, _ := node["signature"].(string); sig != "" {
+				dst[signatureKey(sig)] = provider
+			}
+		case "function_call", "custom_tool_call":
+			if id, _ := node["call_id"].(string); id != "" {
+				dst[id] = provider
+			}
+		case "reasoning":
+			if enc, _ := node["encrypted_content"].(string); enc != "" {
+				dst[signatureKey(enc)] = provider
+			}
+		}
+		// OpenAI Chat tool_calls entries
+		if _, ok := node["function"].(map[string]interface{}); ok {
+			if id, _ := node["id"].(string); id != "" {
+				dst[id] = provider
+			}
+		}
+		for _, child := range node {
+			collectProvenance(dst, child, provider)
+		}
+	case []interface{}:
+		for _, child := range node {
+			collectProvenance(dst, child, provider)
+		}
+	}
+}
+
+// collectProvenanceFromEvent records provenance from the data lines of an SSE event
+func collectProvenanceFromEvent(dst map[string]string, eventData []byte, provider string) {
+	scanner := bufio.NewScanner(bytes.NewReader(eventData))
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		jsonData := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if jsonData == "" || jsonData == "[DONE]" {
+			continue
+		}
+		var event interface{}
+		if err := json.Unmarshal([]byte(jsonData), &event); err != nil {
+			continue
+		}
+		collectProvenance(dst, event, provider)
+	}
+}
+
+// collectProvenanceFromResponse records provenance from a non-streaming response body
+func collectProvenanceFromResponse(dst map[string]string, body []byte, provider string) {
+	var resp interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return
+	}
+	collectProvenance(dst, resp, provider)
+}
+
+// historyNormalizer rewrites conversation history so the target provider only
+// receives signatures it can verify and tool-call IDs in a format it accepts
+type historyNormalizer struct {
+	target           string
+	lookup           func(key string) string
+	idMap            map[string]string // original tool-call ID -> ID sent upstream
+	seen             map[string]int    // occurrences of each tool-call ID definition
+	changed          bool
+	droppedThinking  int
+	remappedToolIDs  int
+	strippedItemIDs  int
+	droppedReasoning int
+}
+
+func newHistoryNormalizer(target string, lookup func(key string) string) *historyNormalizer {
+	if lookup == nil {
+		lookup = func(string) string { return "" }
+	}
+	return &historyNormalizer{
+		target: target,
+		lookup: lookup,
+		idMap:  make(map[string]string),
+		seen:   make(map[string]int),
+	}
+}
+
+// normalizeHistory applies the history normalization pass to a client request.
+// The body is returned unchanged when nothing needs rewriting.
+func normalizeHistory(body []byte, clientFormat ClientFormat, target string, lookup func(key string) string) ([]byte, *historyNormalizer) {
+	n := newHistoryNormalizer(target, lookup)
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body, n
+	}
+
+	switch clientFormat {
+	case ClientFormatClaude:
+		n.normalizeClaudeMessages(req)
+	case ClientFormatOpenAIChat:
+		n.normalizeChatMessages(req)
+	case ClientFormatOpenAIResponses:
+		n.normalizeResponsesInput(req)
+	}
+
+	if !n.changed {
+		return body, n
+	}
+	updated, err := json.Marshal(req)
+	if err != nil {
+		return body, n
+	}
+	return updated, n
+}
+
+func (n *historyNormalizer) summary() string {
+	return fmt.Sprintf("dropped_thinking=%d remapped_tool_ids=%d stripped_item_ids=%d dropped_reasoning=%d",
+		n.droppedThinking, n.remappedToolIDs, n.strippedItemIDs, n.droppedReasoning)
+}
+
+// toolIDProvider returns the recorded or inferred provider of a tool-call ID
+func (n *historyNormalizer) toolIDProvider(id string) string {
+	if provider := n.lookup(id); provider != "" {
+		return provider
+	}
+	switch {
+	case strings.HasPrefix(id, "toolu_"), strings.HasPrefix(id, "srvtoolu_"):
+		return providerAnthropic
+	case strings.HasPrefix(id, "call_"), strings.HasPrefix(id, "fc_"):
+		return providerOpenAI
+	}
+	return ""
+}
+
+// signatureProvider returns the recorded or inferred provider of a signature
+func (n *historyNormalizer) signatureProvider(signature string) string {
+	if provider := n.lookup(signatureKey(signature)); provider != "" {
+		return provider
+	}
+	if strings.HasPrefix(signature, openAIEncryptedReasoningPrefix) {
+		return providerOpenAI
+	}
+	return ""
+}
+
+// canVerifySignature reports whether the target accepts a signature. Unknown
+// signatures are assumed to be Claude's own, since only Claude requires them.
+func (n *historyNormalizer) canVerifySignature(signature string) bool {
+	if signature == "" {
+		return false
+	}
+	provider := n.signatureProvider(signature)
+	if provider == "" {
+		return n.target == providerAnthropic
+	}
+	return provider == n.target
+}
+
+func (n *historyNormalizer) needsToolIDRemap(id string) bool {
+	if provider := n.toolIDProvider(id); provider != "" && provider != n.target {
+		return true
+	}
+	switch n.target {
+	case providerAnthropic:
+		return len(id) > maxClaudeToolIDLength || !claudeToolIDPattern.MatchString(id)
+	case providerOpenAI:
+		return len(id) > maxOpenAIToolIDLength
+	}
+	return false
+}
+
+func (n *historyNormalizer) remappedToolID(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	prefix := "call_"
+	if n.target == providerAnthropic {
+		prefix = "toolu_"
+	}
+	return prefix + hex.EncodeToString(sum[:12])
+}
+
+// toolCallID maps the ID of a tool call definition. Duplicate IDs (Gemini
+// derives IDs from function names) get a distinct ID per occurrence.
+func (n *historyNormalizer) toolCallID(id string) string {
+	if id == "" || n.target == providerGemini {
+		return id
+	}
+	n.seen[id]++
+	occurrence := n.seen[id]
+	if occurrence == 1 && !n.needsToolIDRemap(id) {
+		n.idMap[id] = id
+		return id
+	}
+	seed := id
+	if occurrence > 1 {
+		seed = fmt.Sprintf("%s#%d", id, occurrence)
+	}
+	mapped := n.remappedToolID(seed)
+	n.idMap[id] = mapped
+	n.changed = true
+	n.remappedToolIDs++
+	return mapped
+}
+
+// toolResultID maps an ID that references an earlier tool call
+func (n *historyNormalizer) toolResultID(id string) string {
+	if id == "" || n.target == providerGemini {
+		return id
+	}
+	if mapped, ok := n.idMap[id]; ok {
+		if mapped != id {
+			n.changed = true
+		}
+		return mapped
+	}
+	if !n.needsToolIDRemap(id) {
+		return id
+	}
+	n.changed = true
+	return n.remappedToolID(id)
+}
+
+func (n *historyNormalizer) normalizeClaudeMessages(req map[string]interface{}) {
+	messages, ok := req["messages"].([]interface{})
+	if !ok {
+		return
+	}
+	for _, msg := range messages {
+		msgMap, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, ok := msgMap["content"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		filtered := make([]interface{}, 0, len(content))
+		for _, block := range content {
+			m, ok := block.(map[string]interface{})
+			if !ok {
+				filtered = append(filtered, block)
+				continue
+			}
+			switch m["type"] {
+			case "thinking":
+				signature, _ := m["signature"].(string)
+				if n.canVerifySignature(signature) {
+					break
+				}
+				if n.target == providerGemini {
+					// Gemini accepts unsigned thoughts, only the signature must go
+					if signature != "" {
+						delete(m, "signature")
+						n.changed = true
+					}
+					break
+				}
+				n.changed = true
+				n.droppedThinking++
+				continue
+			case "redacted_thinking":
+				if n.target != providerAnthropic {
+					n.changed = true
+					n.droppedThinking++
+					continue
+				}
+			case "tool_use":
+				if id, _ := m["id"].(string); id != "" {
+					m["id"] = n.toolCallID(id)
+				}
+			case "tool_result":
+				if id, _ := m["tool_use_id"].(string); id != "" {
+					m["tool_use_id"] = n.toolResultID(id)
+				}
+			}
+			filtered = append(filtered, m)
+		}
+
+		if len(filtered) == 0 && len(content) > 0 {
+			filtered = append(filtered, map[string]interface{}{"type": "text", "text": thinkingPlaceholderText})
+		}
+		msgMap["content"] = filtered
+	}
+}
+
+func (n *historyNormalizer) normalizeChatMessages(req map[string]interface{}) {
+	messages, ok := req["messages"].([]interface{})
+	if !ok {
+		return
+	}
+	for _, msg := range messages {
+		msgMap, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if toolCalls, ok := msgMap["tool_calls"].([]interface{}); ok {
+			for _, tc := range toolCalls {
+				if tcMap, ok := tc.(map[string]interface{}); ok {
+					if id, _ := tcMap["id"].(string); id != "" {
+						tcMap["id"] = n.toolCallID(id)
+					}
+				}
+			}
+		}
+		if id, _ := msgMap["tool_call_id"].(string); id != "" {
+			msgMap["tool_call_id"] = n.toolResultID(id)
+		}
+	}
+}
+
+func (n *historyNormalizer) normalizeResponsesInput(req map[string]interface{}) {
+	input, ok := req["input"].([]interface{})
+	if !ok {
+		return
+	}
+	filtered := make([]interface{}, 0, len(input))
+	for _, item := range input {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			filtered = append(filtered, item)
+			continue
+		}
+		switch m["type"] {
+		case "function_call", "custom_tool_call":
+			if id, _ := m["call_id"].(string); id != "" {
+				m["call_id"] = n.toolCallID(id)
+			}
+			n.stripForeignItemID(m, "fc")
+		case "function_call_output", "custom_tool_call_output":
+			if id, _ := m["call_id"].(string); id != "" {
+				m["call_id"] = n.toolResultID(id)
+			}
+		case "reasoning":
+			if !n.keepReasoningItem(m) {
+				n.changed = true
+				n.droppedReasoning++
+				continue
+			}
+		}
+		filtered = append(filtered, m)
+	}
+	req["input"] = filtered
+}
+
+// keepReasoningItem decides whether a Responses API reasoning item can be
+// replayed to the target provider
+func (n *historyNormalizer) keepReasoningItem(item map[string]interface{}) bool {
+	encrypted, _ := item["encrypted_content"].(string)
+	if encrypted == "" {
+		// Items without content are references to server-side state
+		if n.target != providerOpenAI {
+			return true
+		}
+		id, _ := item["id"].(string)
+		return strings.HasPrefix(id, "rs_")
+	}
+	if n.target == providerGemini {
+		return true
+	}
+	if provider := n.signatureProvider(encrypted); provider != "" {
+		return provider == n.target
+	}
+	return n.target == providerAnthropic
+}
+
+// stripForeignItemID removes Responses API item IDs that the OpenAI backend
+// would reject because they were not issued by it
+func (n *historyNormalizer) stripForeignItemID(item map[string]interface{}, prefix string) {
+	if n.target != providerOpenAI {
+		return
+	}
+	id, ok := item["id"].(string)
+	if !ok || strings.HasPrefix(id, prefix) {
+		return
+	}
+	delete(item, "id")
+	n.changed = true
+	n.strippedItemIDs++
+}
+
+// ensureClaudeThinkingContinuity disables thinking when the final assistant
+// turn uses tools without a leading thinking block, which Claude rejects.
+// This happens after thinking from another provider was dropped.
+func ensureClaudeThinkingContinuity(body []byte) ([]byte, bool) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body, false
+	}
+	thinking, ok := req["thinking"].(map[string]interface{})
+	if !ok || thinking["type"] != "enabled" {
+		return body, false
+	}
+	messages, _ := req["messages"].([]interface{})
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg, ok := messages[i].(map[string]interface{})
+		if !ok || msg["role"] != "assistant" {
+			continue
+		}
+		content, ok := msg["content"].([]interface{})
+		if !ok || len(content) == 0 || !claudeContentHasToolUse(content) {
+			return body, false
+		}
+		if first, ok := content[0].(map[string]interface{}); ok {
+			if first["type"] == "thinking" || first["type"] == "redacted_thinking" {
+				return body, false
+			}
+		}
+		delete(req, "thinking")
+		updated, err := json.Marshal(req)
+		if err != nil {
+			return body, false
+		}
+		return updated, true
+	}
+	return body, false
+}
+
+func claudeContentHasToolUse(content []interface{}) bool {
+	for _, block := range content {
+		if m, ok := block.(map[string]interface{}); ok && m["type"] == "tool_use" {
+			return true
+		}
+	}
+	return false
+}
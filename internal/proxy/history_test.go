@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHistoryDropsForeignThinkingForClaude(t *testing.T) {
+	store := newProvenanceStore(100)
+	store.record(map[string]string{signatureKey("gemini-sig"): providerGemini})
+
+	body := []byte(`{"messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":[
+			{"type":"thinking","thinking":"from openai","signature":"gAAAAABenc"},
+			{"type":"thinking","thinking":"from gemini","signature":"gemini-sig"},
+			{"type":"thinking","thinking":"native","signature":"EqQBClaudeSig"},
+			{"type":"text","text":"Hello"}
+		]},
+		{"role":"assistant","content":[{"type":"thinking","thinking":"unsigned","signature":""}]}
+	]}`)
+
+	out, n := normalizeHistory(body, ClientFormatClaude, providerAnthropic, store.lookup)
+	if n.droppedThinking != 3 {
+		t.Fatalf("expected 3 dropped thinking blocks, got %d", n.droppedThinking)
+	}
+	result := string(out)
+	for _, foreign := range []string{"gAAAAABenc", "gemini-sig", "unsigned"} {
+		if strings.Contains(result, foreign) {
+			t.Fatalf("expected %q to be dropped, got %s", foreign, result)
+		}
+	}
+	if !strings.Contains(result, "EqQBClaudeSig") {
+		t.Fatalf("expected Claude signature to be kept, got %s", result)
+	}
+	if !strings.Contains(result, `{"text":"(thinking...)","type":"text"}`) {
+		t.Fatalf("expected placeholder for emptied message, got %s", result)
+	}
+}
+
+func TestNormalizeHistoryRemapsToolIDsConsistently(t *testing.T) {
+	// Gemini derives tool IDs from function names, so repeated calls collide
+	body := []byte(`{"messages":[
+		{"role":"assistant","content":[{"type":"tool_use","id":"call_read","name":"read","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_read","content":"a"}]},
+		{"role":"assistant","content":[{"type":"tool_use","id":"call_read","name":"read","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_read","content":"b"}]},
+		{"role":"assistant","content":[{"type":"tool_use","id":"toolu_01","name":"read","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_01","content":"c"}]}
+	]}`)
+
+	out, _ := normalizeHistory(body, ClientFormatClaude, providerAnthropic, nil)
+	var req struct {
+		Messages []struct {
+			Content []map[string]interface{} `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	var useIDs []string
+	for i := 0; i < len(req.Messages); i += 2 {
+		useID, _ := req.Messages[i].Content[0]["id"].(string)
+		resultID, _ := req.Messages[i+1].Content[0]["tool_use_id"].(string)
+		if useID != resultID {
+			t.Fatalf("tool_use %q and tool_result %q must match", useID, resultID)
+		}
+		useIDs = append(useIDs, useID)
+	}
+	if !strings.HasPrefix(useIDs[0], "toolu_") || useIDs[0] == useIDs[1] {
+		t.Fatalf("expected distinct Claude-style IDs for repeated calls, got %v", useIDs)
+	}
+	if useIDs[2] != "toolu_01" {
+		t.Fatalf("expected native Claude ID to be kept, got %q", useIDs[2])
+	}
+}
+
+func TestNormalizeHistoryResponsesForOpenAI(t *testing.T) {
+	body := []byte(`{"input":[
+		{"type":"reasoning","id":"rs_claude_1","summary":[],"encrypted_content":"EqQBClaudeSig"},
+		{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"gAAAAABenc"},
+		{"type":"function_call","id":"toolu_01","call_id":"toolu_01","name":"read","arguments":"{}"},
+		{"type":"function_call_output","call_id":"toolu_01","output":"ok"}
+	]}`)
+
+	out, n := normalizeHistory(body, ClientFormatOpenAIResponses, providerOpenAI, nil)
+	var req struct {
+		Input []map[string]interface{} `json:"input"`
+	}
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(req.Input) != 3 || n.droppedReasoning != 1 {
+		t.Fatalf("expected Claude reasoning item to be dropped, got %s", out)
+	}
+	call := req.Input[1]
+	if _, ok := call["id"]; ok {
+		t.Fatalf("expected foreign item id to be removed, got %v", call)
+	}
+	callID, _ := call["call_id"].(string)
+	if !strings.HasPrefix(callID, "call_") || req.Input[2]["call_id"] != callID {
+		t.Fatalf("expected matching remapped call IDs, got %v and %v", callID, req.Input[2]["call_id"])
+	}
+}
+
+func TestNormalizeHistoryUnchangedForSameProvider(t *testing.T) {
+	body := []byte(`{"messages":[{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{}"}}]},{"role":"tool","tool_call_id":"call_1","content":"ok"}]}`)
+	out, n := normalizeHistory(body, ClientFormatOpenAIChat, providerOpenAI, nil)
+	if n.changed || string(out) != string(body) {
+		t.Fatalf("expected body to be untouched, got %s", out)
+	}
+}
+
+func TestCollectProvenanceFromEvent(t *testing.T) {
+	entries := make(map[string]string)
+	collectProvenanceFromEvent(entries, []byte("event: content_block_start\n"+
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"call_read","name":"read","input":{}}}`+"\n\n"), providerGemini)
+	collectProvenanceFromEvent(entries, []byte(`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"gemini-sig"}}`+"\n\n"), providerGemini)
+
+	store := newProvenanceStore(100)
+	store.record(entries)
+	if store.lookup("call_read") != providerGemini {
+		t.Fatalf("expected tool ID to be attributed to gemini, got %v", entries)
+	}
+	if store.lookup(signatureKey("gemini-sig")) != providerGemini {
+		t.Fatalf("expected signature to be attributed to gemini, got %v", entries)
+	}
+}
+
+func TestProvenanceStoreEvictsOldest(t *testing.T) {
+	store := newProvenanceStore(2)
+	store.record(map[string]string{"a": providerOpenAI})
+	store.record(map[string]string{"b": providerOpenAI})
+	store.record(map[string]string{"c": providerOpenAI})
+	if store.lookup("a") != "" || store.lookup("c") != providerOpenAI {
+		t.Fatalf("expected oldest entry to be evicted")
+	}
+}
+
+func TestEnsureClaudeThinkingContinuity(t *testing.T) {
+	body := []byte(`{"thinking":{"type":"enabled","budget_tokens":4096},"messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"read","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"ok"}]}
+	]}`)
+	out, disabled := ensureClaudeThinkingContinuity(body)
+	if !disabled || strings.Contains(string(out), `"thinking"`) {
+		t.Fatalf("expected thinking to be disabled, got %s", out)
+	}
+
+	withThinking := []byte(`{"thinking":{"type":"enabled","budget_tokens":4096},"messages":[
+		{"role":"assistant","content":[{"type":"thinking","thinking":"t","signature":"s"},{"type":"tool_use","id":"toolu_1","name":"read","input":{}}]}
+	]}`)
+	if _, disabled := ensureClaudeThinkingContinuity(withThinking); disabled {
+		t.Fatalf("expected thinking to stay enabled when the tool turn starts with thinking")
+	}
+}
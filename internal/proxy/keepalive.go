@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Keep-alive events per client protocol. Claude clients understand ping
+// events; OpenAI and Gemini clients ignore SSE comments.
+var (
+	claudeKeepAliveEvent  = []byte("event: ping\ndata: {\"type\": \"ping\"}\n\n")
+	commentKeepAliveEvent = []byte(": keep-alive\n\n")
+)
+
+// keepAliveEvent returns the keep-alive event for the client side of a
+// transformer
+func keepAliveEvent(transformerName string) []byte {
+	if strings.HasPrefix(transformerName, "cc_") {
+		return claudeKeepAliveEvent
+	}
+	return commentKeepAliveEvent
+}
+
+// withIdleWriteDeadline gives every response an idle-based write deadline
+// instead of the server's absolute WriteTimeout. Streaming responses extend
+// it on each write, so only a stalled stream is cut off.
+func withIdleWriteDeadline(next http.Handler, idleTimeout func() time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(idleTimeout()))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// streamWriter serializes writes to a streaming response so keep-alives can
+// be sent from another goroutine. Each write is flushed and moves the write
+// deadline forward by the idle timeout.
+type streamWriter struct {
+	mu           sync.Mutex
+	w            http.ResponseWriter
+	flusher      http.Flusher
+	controller   *http.ResponseController
+	idleTimeout  time.Duration
+	lastActivity time.Time
+}
+
+func newStreamWriter(w http.ResponseWriter, flusher http.Flusher, idleTimeout time.Duration) *streamWriter {
+	return &streamWriter{
+		w:            w,
+		flusher:      flusher,
+		controller:   http.NewResponseController(w),
+		idleTimeout:  idleTimeout,
+		lastActivity: time.Now(),
+	}
+}
+
+// Write writes and flushes one complete event
+func (s *streamWriter) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idleTimeout > 0 {
+		_ = s.controller.SetWriteDeadline(time.Now().Add(s.idleTimeout))
+	}
+	n, err := s.w.Write(data)
+	if err == nil {
+		s.flusher.Flush()
+		s.lastActivity = time.Now()
+	}
+	return n, err
+}
+
+// touch records that the upstream sent something
+func (s *streamWriter) touch() {
+	s.mu.Lock()
+	s.lastActivity = time.Now()
+	s.mu.Unlock()
+}
+
+// keepAlive writes event whenever neither the upstream nor the proxy has been
+// active for interval, until done is closed
+func (s *streamWriter) keepAlive(interval time.Duration, event []byte, done <-chan struct{}) {
+	for {
+		s.mu.Lock()
+		wait := interval - time.Since(s.lastActivity)
+		s.mu.Unlock()
+		if wait <= 0 {
+			if _, err := s.Write(event); err != nil {
+				return
+			}
+			wait = interval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/transformer/cc"
+)
+
+func TestStreamingSendsKeepAliveDuringUpstreamSilence(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.StreamKeepAlive = 1
+	cfg.UpdateEndpoints([]config.Endpoint{
+		{Name: "Claude", APIUrl: "https://example.com", APIKey: "x", Enabled: true, Transformer: "claude"},
+	})
+	p := &Proxy{config: cfg}
+
+	body, upstream := io.Pipe()
+	go func() {
+		upstream.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":3}}}\n\n"))
+		time.Sleep(1500 * time.Millisecond)
+		upstream.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+		upstream.Close()
+	}()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       body,
+	}
+	rec := httptest.NewRecorder()
+
+	p.handleStreamingResponse(rec, resp, cfg.GetEndpoints()[0], cc.NewClaudeTransformer(), "cc_claude", false, "claude-sonnet-4-5", nil, 0)
+
+	out := rec.Body.String()
+	ping := strings.Index(out, "event: ping\n")
+	start := strings.Index(out, "message_start")
+	stop := strings.Index(out, "message_stop")
+	if ping < 0 || !(start < ping && ping < stop) {
+		t.Fatalf("expected a ping between upstream events, got:\n%s", out)
+	}
+}
+
+func TestKeepAliveEventMatchesClientProtocol(t *testing.T) {
+	if string(keepAliveEvent("cc_openai")) != string(claudeKeepAliveEvent) {
+		t.Fatal("Claude clients must receive ping events")
+	}
+	for _, name := range []string{"cx_chat_openai", "cx_resp_claude", "ge_gemini"} {
+		if string(keepAliveEvent(name)) != ": keep-alive\n\n" {
+			t.Fatalf("%s clients must receive SSE comments", name)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+const (
+	selfSignedValidity = 365 * 24 * time.Hour
+	selfSignedRenewal  = 30 * 24 * time.Hour // Regenerate when less than this is left
+)
+
+// listen opens the listener of the proxy server: a Unix domain socket when
+// one is configured, otherwise TCP on the bind address and port
+func listen(settings config.ListenConfig, port int) (net.Listener, error) {
+	if settings.UnixSocket == "" {
+		return net.Listen("tcp", net.JoinHostPort(settings.BindAddress, strconv.Itoa(port)))
+	}
+
+	// A socket file left behind by a crash would make the listen fail
+	if info, err := os.Lstat(settings.UnixSocket); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		if err := os.Remove(settings.UnixSocket); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", settings.UnixSocket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(settings.UnixSocket, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// tlsConfig returns the TLS settings of the proxy server. Without a
+// certificate file, a self-signed certificate kept in certDir is used; with an
+// empty certDir it is generated for this run only.
+func tlsConfig(settings config.ListenConfig, certDir string) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if settings.CertFile != "" {
+		cert, err = tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+	} else {
+		cert, err = selfSignedCertificate(certDir, settings.BindAddress)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if settings.ClientCAFile != "" {
+		data, err := os.ReadFile(settings.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", settings.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// selfSignedCertificate loads the self-signed certificate in dir, generating
+// a new one when there is none, it is about to expire or it was made for
+// another host name or bind address
+func selfSignedCertificate(dir, bindAddress string) (tls.Certificate, error) {
+	certPath := filepath.Join(dir, "selfsigned.crt")
+	keyPath := filepath.Join(dir, "selfsigned.key")
+	dnsNames, ips := selfSignedNames(bindAddress)
+	if dir != "" {
+		if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil && cert.Leaf != nil &&
+			time.Until(cert.Leaf.NotAfter) > selfSignedRenewal && sameNames(cert.Leaf, dnsNames, ips) {
+			return cert, nil
+		}
+	}
+
+	certPEM, keyPEM, err := generateSelfSigned(dnsNames, ips, time.Now())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	logger.Info("Generated a self-signed TLS certificate, SHA-256 fingerprint %x", sha256.Sum256(cert.Certificate[0]))
+
+	if dir != "" {
+		err = os.MkdirAll(dir, 0700)
+		if err == nil {
+			err = os.WriteFile(keyPath, keyPEM, 0600)
+		}
+		if err == nil {
+			err = os.WriteFile(certPath, certPEM, 0644)
+		}
+		if err != nil {
+			logger.Warn("Failed to save the self-signed certificate in %s: %v", dir, err)
+		}
+	}
+	return cert, nil
+}
+
+// selfSignedNames returns the names a self-signed certificate is made for:
+// localhost, the loopback addresses, the host name and the bind address
+func selfSignedNames(bindAddress string) (dnsNames []string, ips []net.IP) {
+	dnsNames = []string{"localhost"}
+	ips = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		dnsNames = append(dnsNames, hostname)
+	}
+	if ip := net.ParseIP(bindAddress); ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() {
+		ips = append(ips, ip)
+	} else if ip == nil && bindAddress != "" && bindAddress != "localhost" {
+		dnsNames = append(dnsNames, bindAddress)
+	}
+	return dnsNames, ips
+}
+
+// sameNames reports whether a certificate was made for exactly these names
+func sameNames(cert *x509.Certificate, dnsNames []string, ips []net.IP) bool {
+	if len(cert.DNSNames) != len(dnsNames) || len(cert.IPAddresses) != len(ips) {
+		return false
+	}
+	for i, name := range dnsNames {
+		if cert.DNSNames[i] != name {
+			return false
+		}
+	}
+	for i, ip := range ips {
+		if !cert.IPAddresses[i].Equal(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// generateSelfSigned creates a certificate for the given names
+func generateSelfSigned(dnsNames []string, ips []net.IP, now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"ccNexus"}, CommonName: "ccNexus self-signed"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
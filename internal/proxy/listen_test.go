@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+// writeClientCertificate writes a self-signed client certificate, which also
+// serves as the client CA
+func writeClientCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ccnexus-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestTLSListenerRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, clientKey := writeClientCertificate(t, dir)
+	settings := config.ListenConfig{BindAddress: config.LoopbackAddress, TLS: true, ClientCAFile: clientCert}
+
+	tlsCfg, err := tlsConfig(settings, filepath.Join(dir, "tls"))
+	if err != nil {
+		t.Fatalf("tlsConfig: %v", err)
+	}
+	ln, err := listen(settings, 0)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: tlsCfg,
+	}
+	go server.ServeTLS(ln, "", "")
+	defer server.Close()
+
+	// The self-signed certificate is kept and reused
+	again, err := selfSignedCertificate(filepath.Join(dir, "tls"), settings.BindAddress)
+	if err != nil || !bytes.Equal(again.Certificate[0], tlsCfg.Certificates[0].Certificate[0]) {
+		t.Fatalf("expected the saved self-signed certificate to be reused, got %v", err)
+	}
+
+	// A new bind address needs a certificate naming it
+	moved, err := selfSignedCertificate(filepath.Join(dir, "tls"), "192.0.2.10")
+	if err != nil || bytes.Equal(moved.Certificate[0], again.Certificate[0]) || moved.Leaf.VerifyHostname("192.0.2.10") != nil {
+		t.Fatalf("expected a certificate for the new bind address, got %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsCfg.Certificates[0].Leaf)
+	url := "https://" + ln.Addr().String()
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+	}
+
+	if resp, err := client().Get(url); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected a client without a certificate to be rejected")
+	}
+
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client(cert).Get(url)
+	if err != nil {
+		t.Fatalf("expected the client certificate to be accepted: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestUnixSocketListenerReplacesStaleSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, shorter than many TempDirs
+	dir, err := os.MkdirTemp("", "ccnexus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	settings := config.ListenConfig{UnixSocket: filepath.Join(dir, "ccnexus.sock")}
+
+	stale, err := net.Listen("unix", settings.UnixSocket)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listen(settings, 3000)
+	if err != nil {
+		t.Fatalf("expected the stale socket to be replaced: %v", err)
+	}
+	defer ln.Close()
+	if info, err := os.Stat(settings.UnixSocket); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the socket to be private, got %v, %v", info.Mode(), err)
+	}
+}
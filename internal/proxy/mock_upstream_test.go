@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/mockupstream"
+	"github.com/lich0821/ccNexus/internal/storage"
+)
+
+func newMockUpstream(t *testing.T, script mockupstream.Script) (*mockupstream.Server, string) {
+	t.Helper()
+	mock := mockupstream.New(script)
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	return mock, srv.URL
+}
+
+func sendClaudeRequest(p *Proxy, stream bool) *httptest.ResponseRecorder {
+	body := `{"model":"claude-sonnet-4-5","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`
+	if stream {
+		body = `{"model":"claude-sonnet-4-5","max_tokens":64,"stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	}
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+	return rec
+}
+
+func TestMockUpstreamServesEveryProvider(t *testing.T) {
+	behavior := mockupstream.Behavior{
+		Text:      "Paris is sunny",
+		ToolCalls: []mockupstream.ToolCall{{Name: "get_weather", Arguments: map[string]interface{}{"city": "Paris"}}},
+	}
+	for _, transformer := range []string{"claude", "openai", "openai2", "gemini"} {
+		for _, stream := range []bool{false, true} {
+			name := transformer
+			if stream {
+				name += "/stream"
+			}
+			t.Run(name, func(t *testing.T) {
+				_, url := newMockUpstream(t, mockupstream.Script{Default: behavior})
+				p, statsStorage := newAuxiliaryTestProxy([]config.Endpoint{
+					{Name: "Mock", APIUrl: url, APIKey: "k", Enabled: true, Transformer: transformer, Model: "mock-model"},
+				})
+
+				rec := sendClaudeRequest(p, stream)
+				out := rec.Body.String()
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status %d: %s", rec.Code, out)
+				}
+				for _, want := range []string{"Paris ", "get_weather", `tool_use`} {
+					if !strings.Contains(out, want) {
+						t.Fatalf("response lacks %s:\n%s", want, out)
+					}
+				}
+				if stream && !strings.Contains(out, "event: message_stop") {
+					t.Fatalf("stream was not finished:\n%s", out)
+				}
+				if requests, errors, _ := statsStorage.totals("Mock"); requests != 1 || errors != 0 {
+					t.Fatalf("expected one successful request, got %d requests and %d errors", requests, errors)
+				}
+			})
+		}
+	}
+}
+
+func TestMockUpstreamRateLimitFailsOver(t *testing.T) {
+	limited, limitedURL := newMockUpstream(t, mockupstream.Script{
+		Default: mockupstream.Behavior{Status: http.StatusTooManyRequests, RetryAfter: 30, Message: "slow down"},
+	})
+	_, healthyURL := newMockUpstream(t, mockupstream.Script{Default: mockupstream.Behavior{Text: "from backup"}})
+	p, statsStorage := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Limited", APIUrl: limitedURL, APIKey: "k", Enabled: true, Transformer: "claude"},
+		{Name: "Backup", APIUrl: healthyURL, APIKey: "k", Enabled: true, Transformer: "openai", Model: "mock-model"},
+	})
+
+	rec := sendClaudeRequest(p, false)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "from backup") {
+		t.Fatalf("expected the backup endpoint to answer, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(limited.Requests()) == 0 {
+		t.Fatal("the rate-limited endpoint was never tried")
+	}
+	if requests, _, _ := statsStorage.totals("Backup"); requests != 1 {
+		t.Fatalf("expected one request on the backup endpoint, got %d", requests)
+	}
+}
+
+func TestMockUpstreamDisconnectMidStream(t *testing.T) {
+	_, url := newMockUpstream(t, mockupstream.Script{
+		Default: mockupstream.Behavior{Text: "one two three four five", DisconnectAfter: 4},
+	})
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "Flaky", APIUrl: url, APIKey: "k", Enabled: true, Transformer: "claude"},
+	})
+
+	rec := sendClaudeRequest(p, true)
+	out := rec.Body.String()
+	if !strings.Contains(out, "event: message_start") || !strings.Contains(out, "one ") {
+		t.Fatalf("expected the events sent before the disconnect, got:\n%s", out)
+	}
+	if strings.Contains(out, "five") || strings.Contains(out, "event: message_stop") {
+		t.Fatalf("expected the stream to end at the disconnect, got:\n%s", out)
+	}
+}
+
+func TestMockUpstreamCodexRefreshAndRateLimits(t *testing.T) {
+	mock, url := newMockUpstream(t, mockupstream.Script{
+		APIKey: "never-issued",
+		Default: mockupstream.Behavior{
+			Text:       "codex says hi",
+			RateLimits: &mockupstream.RateLimits{PrimaryUsedPercent: 42, PrimaryWindowMinutes: 300, SecondaryUsedPercent: 12},
+		},
+	})
+	previousTokenURL := codexOAuthTokenURL
+	codexOAuthTokenURL = url + "/oauth/token"
+	t.Cleanup(func() { codexOAuthTokenURL = previousTokenURL })
+
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "ccnexus.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	credential := &storage.EndpointCredential{
+		EndpointName: "Codex", ProviderType: "codex", AccessToken: "stale", RefreshToken: "refresh", Enabled: true,
+	}
+	if err := store.SaveEndpointCredential(credential); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.UpdateEndpoints([]config.Endpoint{
+		{Name: "Codex", APIUrl: url + "/backend-api/codex", AuthMode: config.AuthModeCodexTokenPool, Enabled: true, Transformer: "openai2", Model: "gpt-5-codex"},
+	})
+	p := New(cfg, &recordingStatsStorage{}, store, "test")
+
+	rec := sendClaudeRequest(p, true)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "codex ") {
+		t.Fatalf("expected the retried request to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var paths []string
+	for _, r := range mock.Requests() {
+		paths = append(paths, r.Path)
+	}
+	if strings.Join(paths, " ") != "/backend-api/codex/responses /oauth/token /backend-api/codex/responses" {
+		t.Fatalf("expected a 401, a refresh and a retry, got %v", paths)
+	}
+	refreshed, err := store.GetCredentialByID(credential.ID)
+	if err != nil || refreshed.AccessToken == "stale" {
+		t.Fatalf("credential was not refreshed: %+v, %v", refreshed, err)
+	}
+
+	limits, err := store.GetCredentialRateLimits(credential.ID)
+	if err != nil || limits == nil || limits.Data == nil || limits.Data.Snapshot == nil || limits.Data.Snapshot.Primary == nil {
+		data, _ := json.Marshal(limits)
+		t.Fatalf("rate limits were not captured: %s, %v", data, err)
+	}
+	if used := limits.Data.Snapshot.Primary.UsedPercent; used != 42 {
+		t.Fatalf("expected 42%% primary usage, got %v", used)
+	}
+}
@@ -111,6 +111,10 @@ func (p *Proxy) fetchModelsFromEndpoint(ep config.Endpoint) ([]ModelInfo, error)
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
+	case config.OllamaTransformer:
+		// Ollama lists local models at /api/tags
+		return p.fetchOllamaModels(ep)
+
 	default:
 		// For transformers without /v1/models support (claude, codex)
 		return nil, fmt.Errorf("transformer %s does not support /v1/models", ep.Transformer)
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+)
+
+// ollamaStreamContentType is the content type of Ollama's streaming responses
+const ollamaStreamContentType = "application/x-ndjson"
+
+// isOllamaTransformer reports whether the transformer targets the native Ollama API
+func isOllamaTransformer(transformerName string) bool {
+	return strings.HasSuffix(transformerName, "_ollama")
+}
+
+// adaptOllamaStreamResponse rewrites a successful NDJSON stream from Ollama as
+// an OpenAI Chat SSE stream so the regular streaming path can handle it
+func adaptOllamaStreamResponse(resp *http.Response) error {
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(contentType, ollamaStreamContentType) {
+		return nil
+	}
+
+	body := resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		body = struct {
+			io.Reader
+			io.Closer
+		}{gzipReader, resp.Body}
+		resp.Header.Del("Content-Encoding")
+	}
+	resp.Body = newOllamaSSEReader(body)
+	resp.Header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return nil
+}
+
+// ollamaSSEReader converts NDJSON lines to SSE events as they are read
+type ollamaSSEReader struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	state   *convert.OllamaStreamState
+	pending bytes.Buffer
+	done    bool
+}
+
+func newOllamaSSEReader(body io.ReadCloser) io.ReadCloser {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return &ollamaSSEReader{body: body, scanner: scanner, state: convert.NewOllamaStreamState()}
+}
+
+func (r *ollamaSSEReader) Read(p []byte) (int, error) {
+	for r.pending.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if !r.scanner.Scan() {
+			r.done = true
+			if err := r.scanner.Err(); err != nil {
+				r.pending.Write(r.state.Error(err.Error()))
+			}
+			continue
+		}
+		r.pending.Write(r.state.Convert(r.scanner.Bytes()))
+	}
+	return r.pending.Read(p)
+}
+
+func (r *ollamaSSEReader) Close() error {
+	return r.body.Close()
+}
+
+// fetchOllamaModels lists the models pulled on an Ollama server
+func (p *Proxy) fetchOllamaModels(ep config.Endpoint) ([]ModelInfo, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(ep.APIUrl, "/")+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "ccNexus/1.0")
+	if ep.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+ep.APIKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			ModifiedAt time.Time `json:"modified_at"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]ModelInfo, len(result.Models))
+	for i, m := range result.Models {
+		models[i] = ModelInfo{
+			ID:         m.Name,
+			Object:     "model",
+			Created:    m.ModifiedAt.Unix(),
+			OwnedBy:    "ollama",
+			EndpointID: ep.Name,
+		}
+	}
+	return models, nil
+}
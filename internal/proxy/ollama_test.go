@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+func TestOllamaStreamingAgainstNDJSONStub(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer ollama" {
+			t.Errorf("expected bearer auth, got %q", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Model     string                 `json:"model"`
+			Stream    bool                   `json:"stream"`
+			KeepAlive string                 `json:"keep_alive"`
+			Options   map[string]interface{} `json:"options"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "qwen3:32b" || !body.Stream || body.KeepAlive != "-1" || body.Options["num_ctx"] != float64(65536) {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range []string{
+			`{"model":"qwen3:32b","message":{"role":"assistant","content":"Hel"},"done":false}`,
+			`{"model":"qwen3:32b","message":{"role":"assistant","content":"lo"},"done":false}`,
+			`{"model":"qwen3:32b","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":4}`,
+		} {
+			w.Write([]byte(line + "\n"))
+		}
+	}))
+	defer upstream.Close()
+
+	endpoint := config.Endpoint{
+		Name:        "Ollama",
+		APIUrl:      upstream.URL,
+		APIKey:      "ollama",
+		AuthMode:    config.AuthModeAPIKey,
+		Enabled:     true,
+		Transformer: config.OllamaTransformer,
+		Model:       "qwen3:32b",
+		Options:     &config.EndpointOptions{Ollama: &config.OllamaOptions{NumCtx: 65536, KeepAlive: "-1"}},
+	}
+	cfg := config.DefaultConfig()
+	cfg.UpdateEndpoints([]config.Endpoint{endpoint})
+	p := &Proxy{config: cfg}
+
+	trans, err := prepareTransformerForClient(ClientFormatClaude, endpoint, "qwen3:32b")
+	if err != nil {
+		t.Fatalf("prepareTransformerForClient failed: %v", err)
+	}
+	clientBody := []byte(`{"model":"claude-sonnet-4-20250514","stream":true,"max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`)
+	transformed, err := trans.TransformRequest(clientBody)
+	if err != nil {
+		t.Fatalf("TransformRequest failed: %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/v1/messages", nil)
+	proxyReq, err := buildProxyRequest(r, endpoint, endpoint.APIKey, transformed, trans.Name(), "qwen3:32b", nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(proxyReq)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if err := adaptOllamaStreamResponse(resp); err != nil {
+		t.Fatalf("adaptOllamaStreamResponse failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	in, out, text := p.handleStreamingResponse(rec, resp, endpoint, trans, trans.Name(), false, "qwen3:32b", clientBody, 0)
+	if in != 12 || out != 4 || text != "Hello" {
+		t.Fatalf("expected usage 12/4 and text Hello, got %d/%d %q", in, out, text)
+	}
+	if !strings.Contains(rec.Body.String(), "event: message_stop") {
+		t.Fatalf("expected Claude SSE output, got %s", rec.Body.String())
+	}
+}
+
+func TestFetchOllamaModels(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"models":[{"name":"qwen3:32b","modified_at":"2025-05-01T10:00:00Z"},{"name":"llama3.2:latest"}]}`))
+	}))
+	defer upstream.Close()
+
+	p := &Proxy{httpClient: http.DefaultClient}
+	models, err := p.fetchModelsFromEndpoint(config.Endpoint{Name: "Ollama", APIUrl: upstream.URL + "/", Transformer: config.OllamaTransformer})
+	if err != nil {
+		t.Fatalf("fetchModelsFromEndpoint failed: %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "qwen3:32b" || models[1].ID != "llama3.2:latest" || models[0].OwnedBy != "ollama" || models[0].EndpointID != "Ollama" {
+		t.Fatalf("unexpected models: %+v", models)
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/lich0821/ccNexus/internal/azure"
 	"github.com/lich0821/ccNexus/internal/config"
 	"github.com/lich0821/ccNexus/internal/logger"
+	"github.com/lich0821/ccNexus/internal/plugin"
 	"github.com/lich0821/ccNexus/internal/storage"
+	"github.com/lich0821/ccNexus/internal/vertex"
 )
 
 // SSEEvent represents a Server-Sent Event
@@ -47,6 +51,11 @@ type Proxy struct {
 	onEndpointSuccess func(endpointName string)     // callback when endpoint request succeeds
 	modelsCache       *ModelsCache                  // Cache for /v1/models endpoint
 	resolver          *EndpointResolver             // 端点解析器，用于解析客户端指定的端点
+	provenance        *provenanceStore              // Provider that produced each tool-call ID and signature
+	vertexTokens      *vertex.TokenCache            // Access tokens minted for Vertex AI service accounts
+	azureTokens       *azure.TokenCache             // Access tokens minted for Entra ID applications
+	plugins           *plugin.Manager               // Transformer plugins used by endpoints
+	clientKeyLimiter  *clientKeyLimiter             // Request rate of each client key
 }
 
 // New creates a new Proxy instance
@@ -71,16 +80,21 @@ func New(cfg *config.Config, statsStorage StatsStorage, sqliteStorage *storage.S
 	}
 
 	return &Proxy{
-		config:         cfg,
-		storage:        sqliteStorage,
-		stats:          stats,
-		currentIndex:   0,
-		httpClient:     httpClient,
-		activeRequests: make(map[string]bool),
-		endpointCtx:    make(map[string]context.Context),
-		endpointCancel: make(map[string]context.CancelFunc),
-		modelsCache:    NewModelsCache(cfg.ModelsCacheTTL),
-		resolver:       NewEndpointResolverWithFunc(cfg.GetEndpoints),
+		config:           cfg,
+		storage:          sqliteStorage,
+		stats:            stats,
+		currentIndex:     0,
+		httpClient:       httpClient,
+		activeRequests:   make(map[string]bool),
+		endpointCtx:      make(map[string]context.Context),
+		endpointCancel:   make(map[string]context.CancelFunc),
+		modelsCache:      NewModelsCache(cfg.ModelsCacheTTL),
+		resolver:         NewEndpointResolverWithFunc(cfg.GetEndpoints),
+		provenance:       newProvenanceStore(provenanceStoreLimit),
+		vertexTokens:     vertex.NewTokenCache(),
+		azureTokens:      azure.NewTokenCache(),
+		plugins:          plugin.NewManager(cfg.GetPlugins),
+		clientKeyLimiter: newClientKeyLimiter(),
 	}
 }
 
@@ -106,35 +120,62 @@ func (p *Proxy) StartWithMux(customMux *http.ServeMux) error {
 	}
 
 	// Register proxy routes
-	mux.HandleFunc("/", p.handleProxy)
-	mux.HandleFunc("/v1/messages/count_tokens", p.handleCountTokens)
-	mux.HandleFunc("/v1/models", p.handleModels)
+	mux.HandleFunc("/", p.withClientKey(p.handleProxy))
+	mux.HandleFunc("/v1/messages/count_tokens", p.withClientKey(p.handleCountTokens))
+	mux.HandleFunc("/v1/models", p.withClientKey(p.handleModels))
+	for path := range auxiliaryRoutes {
+		mux.HandleFunc(path, p.withClientKey(p.handleAuxiliary))
+	}
 	mux.HandleFunc("/health", p.handleHealth)
 	mux.HandleFunc("/stats", p.handleStats)
 
 	p.server = &http.Server{
-		Addr:              fmt.Sprintf(":%d", port),
-		Handler:           mux,
+		Handler:           withIdleWriteDeadline(mux, p.config.GetStreamIdleTimeout),
 		ReadHeaderTimeout: 10 * time.Second,
 		ReadTimeout:       5 * time.Minute,
-		WriteTimeout:      10 * time.Minute,
-		IdleTimeout:       120 * time.Second,
+		IdleTimeout:       120 * time.Second, // Write deadlines are set per response, see withIdleWriteDeadline
 	}
 
-	logger.Info("ccNexus starting on port %d", port)
+	settings := p.config.GetListen()
+	if settings.TLS {
+		certDir := ""
+		if p.storage != nil {
+			certDir = filepath.Join(filepath.Dir(p.storage.GetDBPath()), "tls")
+		}
+		tlsCfg, err := tlsConfig(settings, certDir)
+		if err != nil {
+			return fmt.Errorf("failed to set up TLS: %w", err)
+		}
+		p.server.TLSConfig = tlsCfg
+	}
+	ln, err := listen(settings, port)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("ccNexus listening on %s", settings.URL(port))
 	logger.Info("Configured %d endpoints", len(p.config.GetEndpoints()))
 
-	return p.server.ListenAndServe()
+	if settings.TLS {
+		return p.server.ServeTLS(ln, "", "")
+	}
+	return p.server.Serve(ln)
 }
 
 // Stop stops the proxy server
 func (p *Proxy) Stop() error {
+	p.plugins.Close()
 	if p.server != nil {
 		return p.server.Close()
 	}
 	return nil
 }
 
+// PluginStatus reports the configured transformer plugins
+func (p *Proxy) PluginStatus() []plugin.Status {
+	return p.plugins.Status()
+}
+
 // getEnabledEndpoints returns only the enabled endpoints
 func (p *Proxy) getEnabledEndpoints() []config.Endpoint {
 	allEndpoints := p.config.GetEndpoints()
@@ -298,11 +339,19 @@ const (
 	ClientFormatClaude          ClientFormat = "claude"           // Claude Code: /v1/messages
 	ClientFormatOpenAIChat      ClientFormat = "openai_chat"      // Codex (chat): /v1/chat/completions
 	ClientFormatOpenAIResponses ClientFormat = "openai_responses" // Codex (responses): /v1/responses
+	ClientFormatGemini          ClientFormat = "gemini"           // Gemini CLI: /v1beta/models/{model}:generateContent
+)
+
+const (
+	geminiGenerateMethod       = ":generateContent"
+	geminiStreamGenerateMethod = ":streamGenerateContent"
 )
 
 // detectClientFormat identifies the client format based on request path
 func detectClientFormat(path string) ClientFormat {
 	switch {
+	case strings.HasSuffix(path, geminiGenerateMethod) || strings.HasSuffix(path, geminiStreamGenerateMethod):
+		return ClientFormatGemini
 	case strings.HasPrefix(path, "/v1/chat/completions") || strings.HasPrefix(path, "/chat/completions"):
 		return ClientFormatOpenAIChat
 	case strings.HasPrefix(path, "/v1/responses") || strings.HasPrefix(path, "/responses"):
@@ -312,6 +361,21 @@ func detectClientFormat(path string) ClientFormat {
 	}
 }
 
+// parseGeminiPath extracts the model and streaming mode from a Gemini
+// /v1beta/models/{model}:generateContent or :streamGenerateContent path
+func parseGeminiPath(path string) (model string, stream bool) {
+	idx := strings.Index(path, "/models/")
+	if idx == -1 {
+		return "", false
+	}
+	rest := path[idx+len("/models/"):]
+	colon := strings.LastIndex(rest, ":")
+	if colon == -1 {
+		return "", false
+	}
+	return strings.TrimSpace(rest[:colon]), rest[colon:] == geminiStreamGenerateMethod
+}
+
 // handleProxy handles the main proxy logic
 func (p *Proxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 	p.handleProxyRequest(w, r)
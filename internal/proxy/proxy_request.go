@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/lich0821/ccNexus/internal/logger"
 	"github.com/lich0821/ccNexus/internal/storage"
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/pluginsdk"
 )
 
 type proxyRequestContext struct {
@@ -26,6 +28,10 @@ type proxyRequestContext struct {
 	modelOverride               string
 	useSpecificEndpoint         bool
 	refreshedCredentialAttempts map[int64]bool
+	mintedTokenRetried          map[string]bool
+	inputTokens                 int                // Estimated on first use, see estimatedInputTokens
+	clientKey                   *storage.ClientKey // Key the client authorized with, if client keys are in use
+	failedOver                  string             // Last endpoint this request left that was not the current one, see nextEndpointForRequest
 }
 
 type endpointAttempt struct {
@@ -39,6 +45,7 @@ type endpointAttempt struct {
 	transformedBody    []byte
 	modelName          string
 	thinkingEnabled    bool
+	upstreamStream     bool // Differs from the client's choice when the endpoint only streams or cannot stream
 	proxyRequest       *http.Request
 	response           *http.Response
 }
@@ -62,7 +69,18 @@ func (p *Proxy) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 	lastEndpointName := ""
 
 	for retry := 0; retry < maxRetries; retry++ {
-		endpoint := p.nextEndpointForRequest(reqCtx)
+		endpoint, err := p.nextEndpointForRequest(reqCtx)
+		var ctxErr *contextLengthError
+		if errors.As(err, &ctxErr) {
+			logger.Warn("No endpoint fits the request: %v", ctxErr)
+			writeContextLengthError(w, reqCtx.clientFormat, ctxErr)
+			return
+		}
+		var keyErr *clientKeyError
+		if errors.As(err, &keyErr) {
+			writeClientKeyError(w, keyErr)
+			return
+		}
 		if endpoint.Name == "" {
 			http.Error(w, "No enabled endpoints available", http.StatusServiceUnavailable)
 			return
@@ -86,11 +104,18 @@ func (p *Proxy) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if endpointAttempts >= 2 && !reqCtx.useSpecificEndpoint {
-			p.rotateEndpoint()
+			// Only a failing current endpoint moves the shared rotation; one the
+			// request picked for itself is left for the next of its candidates
+			if reqCtx.failedOver == "" && p.isCurrentEndpoint(endpoint.Name) {
+				p.rotateEndpoint()
+			} else {
+				reqCtx.failedOver = endpoint.Name
+			}
 			endpointAttempts = 0
 		}
 	}
 
+	p.recordClientKeyUsage(reqCtx.clientKey, lastEndpointName, 0, 1, 0, 0)
 	http.Error(w, "All endpoints failed", http.StatusServiceUnavailable)
 }
 
@@ -113,6 +138,10 @@ func (p *Proxy) newProxyRequestContext(w http.ResponseWriter, r *http.Request) (
 		Stream bool   `json:"stream"`
 	}
 	_ = json.Unmarshal(bodyBytes, &streamReq)
+	if clientFormat == ClientFormatGemini {
+		// Gemini carries the model and streaming mode in the URL
+		streamReq.Model, streamReq.Stream = parseGeminiPath(r.URL.Path)
+	}
 
 	endpoints := p.getEnabledEndpoints()
 	if len(endpoints) == 0 {
@@ -128,6 +157,18 @@ func (p *Proxy) newProxyRequestContext(w http.ResponseWriter, r *http.Request) (
 		return nil, resolveErr
 	}
 
+	clientKey := clientKeyFromRequest(r)
+	model := modelOverride
+	if model == "" {
+		model = strings.TrimSpace(streamReq.Model)
+	}
+	endpoints, keyErr := authorizeClientKeyRequest(clientKey, endpoints, specifiedEndpoint, model)
+	if keyErr != nil {
+		logger.Warn("Client key rejected: %s", keyErr.message)
+		writeClientKeyError(w, keyErr)
+		return nil, keyErr
+	}
+
 	useSpecificEndpoint := specifiedEndpoint != nil
 	if useSpecificEndpoint {
 		logger.Debug("[Resolver] 使用指定端点: %s", specifiedEndpoint.Name)
@@ -146,14 +187,74 @@ func (p *Proxy) newProxyRequestContext(w http.ResponseWriter, r *http.Request) (
 		modelOverride:               modelOverride,
 		useSpecificEndpoint:         useSpecificEndpoint,
 		refreshedCredentialAttempts: make(map[int64]bool),
+		mintedTokenRetried:          make(map[string]bool),
+		clientKey:                   clientKey,
 	}, nil
 }
 
-func (p *Proxy) nextEndpointForRequest(reqCtx *proxyRequestContext) config.Endpoint {
+// nextEndpointForRequest returns the endpoint for the next attempt: the
+// specified endpoint, or the current one. Endpoints whose context window the
+// request cannot fit, or that the client key may not use, are skipped without
+// rotating or counting a failure. Once the request has failed over from an
+// endpoint it picked this way, it continues after that endpoint instead of
+// rotating the current one for every client. A contextLengthError is returned
+// only when an endpoint the key may use was skipped for size; if the
+// allowlist alone left no endpoint, the key is refused instead.
+func (p *Proxy) nextEndpointForRequest(reqCtx *proxyRequestContext) (config.Endpoint, error) {
 	if reqCtx.useSpecificEndpoint && reqCtx.specifiedEndpoint != nil {
-		return *reqCtx.specifiedEndpoint
+		endpoint := *reqCtx.specifiedEndpoint
+		if fits, window := fitsContextWindow(reqCtx, endpoint); !fits {
+			return config.Endpoint{}, &contextLengthError{inputTokens: reqCtx.estimatedInputTokens(), limit: window}
+		}
+		return endpoint, nil
+	}
+
+	current := p.getCurrentEndpoint()
+	if current.Name == "" {
+		return current, nil
+	}
+	if reqCtx.failedOver == "" && clientKeyAllowsEndpoint(reqCtx.clientKey, current) {
+		if fits, _ := fitsContextWindow(reqCtx, current); fits {
+			return current, nil
+		}
+	}
+
+	endpoints := p.getEnabledEndpoints()
+	start := 0
+	for i, ep := range endpoints {
+		if reqCtx.failedOver != "" && ep.Name == reqCtx.failedOver {
+			start = i + 1
+			break
+		}
+		if reqCtx.failedOver == "" && ep.Name == current.Name {
+			start = i
+			break
+		}
 	}
-	return p.getCurrentEndpoint()
+	largest := 0
+	skippedForSize := false
+	for i := range endpoints {
+		endpoint := endpoints[(start+i)%len(endpoints)]
+		if !clientKeyAllowsEndpoint(reqCtx.clientKey, endpoint) {
+			continue
+		}
+		fits, window := fitsContextWindow(reqCtx, endpoint)
+		if fits {
+			logger.Debug("[%s] Skipped endpoints that cannot fit %d input tokens or key=%s may not use", endpoint.Name, reqCtx.estimatedInputTokens(), clientKeyLabel(reqCtx.clientKey))
+			return endpoint, nil
+		}
+		skippedForSize = true
+		if window > largest {
+			largest = window
+		}
+	}
+	if skippedForSize {
+		return config.Endpoint{}, &contextLengthError{inputTokens: reqCtx.estimatedInputTokens(), limit: largest}
+	}
+	if reqCtx.clientKey != nil {
+		return config.Endpoint{}, clientKeyForbidden("Client API key %s may not use any enabled endpoint", reqCtx.clientKey.Name)
+	}
+	return config.Endpoint{}, nil
 }
 
 func (p *Proxy) runEndpointAttempt(w http.ResponseWriter, reqCtx *proxyRequestContext, attempt *endpointAttempt) attemptResult {
@@ -186,15 +287,45 @@ func (p *Proxy) prepareEndpointAttempt(reqCtx *proxyRequestContext, attempt *end
 		p.stats.RecordError(attempt.endpoint.Name)
 		return attemptResultRetryNextEndpoint
 	}
+	if name := attempt.endpoint.Options.PluginName(); name != "" {
+		trans, err = p.plugins.Wrap(trans, name, pluginsdk.CallContext{Endpoint: attempt.endpoint.Name, Model: attempt.modelName})
+		if err != nil {
+			logger.Error("[%s] %v", attempt.endpoint.Name, err)
+			p.stats.RecordError(attempt.endpoint.Name)
+			return attemptResultRetryNextEndpoint
+		}
+	}
 	attempt.transformer = trans
 	attempt.transformerName = trans.Name()
+	attempt.upstreamStream = upstreamStreaming(attempt.endpoint, attempt.transformerName, reqCtx.streamRequested)
+
+	// Normalize history per attempt: failover may target a different provider
+	targetProvider := providerForTransformer(attempt.endpoint.Transformer, attempt.transformerName)
+	requestBody, normalizer := normalizeHistory(reqCtx.bodyBytes, reqCtx.clientFormat, targetProvider, p.provenance.lookup)
+	if normalizer.changed {
+		logger.DebugLog("[%s] Normalized history for %s: %s", attempt.endpoint.Name, targetProvider, normalizer.summary())
+	}
+
+	if reqCtx.clientFormat == ClientFormatGemini {
+		if attempt.upstreamStream && targetProvider != providerGemini {
+			requestBody = setPayloadField(requestBody, "stream", true)
+		}
+	} else if attempt.upstreamStream != reqCtx.streamRequested {
+		requestBody = setPayloadField(requestBody, "stream", attempt.upstreamStream)
+	}
 
-	transformedBody, err := trans.TransformRequest(reqCtx.bodyBytes)
+	transformedBody, err := trans.TransformRequest(requestBody)
 	if err != nil {
 		logger.Error("[%s] Failed to transform request: %v", attempt.endpoint.Name, err)
 		p.stats.RecordError(attempt.endpoint.Name)
 		return attemptResultRetryNextEndpoint
 	}
+	if targetProvider == providerAnthropic {
+		var disabled bool
+		if transformedBody, disabled = ensureClaudeThinkingContinuity(transformedBody); disabled {
+			logger.DebugLog("[%s] Disabled thinking: last tool call has no verifiable thinking block", attempt.endpoint.Name)
+		}
+	}
 
 	logger.DebugLog("[%s] Transformer: %s", attempt.endpoint.Name, attempt.transformerName)
 	logger.DebugLog("[%s] Transformed Request: %s", attempt.endpoint.Name, string(transformedBody))
@@ -212,6 +343,14 @@ func (p *Proxy) prepareEndpointAttempt(reqCtx *proxyRequestContext, attempt *end
 	if shouldOverridePayloadModel(attempt.transformerName) && attempt.modelName != "" {
 		cleanedBody = overrideModelInPayload(cleanedBody, attempt.modelName)
 	}
+	if isVertexTransformer(attempt.transformerName) && attempt.upstreamStream {
+		// Vertex selects streaming by URL method, which is derived from the body
+		cleanedBody = setPayloadField(cleanedBody, "stream", true)
+	}
+	if attempt.upstreamStream && !reqCtx.streamRequested && isOpenAIChatUpstreamTransformer(attempt.transformerName) &&
+		attempt.endpoint.Options.QuirkProfile().Usage != transformer.UsageNone {
+		cleanedBody = setPayloadField(cleanedBody, "stream_options", map[string]interface{}{"include_usage": true})
+	}
 	attempt.transformedBody = cleanedBody
 	attempt.thinkingEnabled = detectThinkingEnabled(attempt.transformerName, attempt.transformedBody)
 
@@ -221,6 +360,9 @@ func (p *Proxy) prepareEndpointAttempt(reqCtx *proxyRequestContext, attempt *end
 		p.stats.RecordError(attempt.endpoint.Name)
 		return attemptResultRetryNextEndpoint
 	}
+	if attempt.upstreamStream != reqCtx.streamRequested && isGeminiUpstreamTransformer(attempt.transformerName) {
+		applyGeminiStreamMethod(proxyReq, attempt.upstreamStream)
+	}
 	attempt.proxyRequest = proxyReq
 
 	return attemptResultDone
@@ -262,6 +404,33 @@ func (p *Proxy) resolveAttemptAuth(reqCtx *proxyRequestContext, attempt *endpoin
 		return attemptResultDone
 	}
 
+	if attempt.authMode == config.AuthModeAWSSigV4 {
+		// Credentials are resolved when the request is signed
+		return attemptResultDone
+	}
+
+	if attempt.authMode == config.AuthModeServiceAccount {
+		token, err := p.vertexAccessToken(attempt.endpoint)
+		if err != nil {
+			logger.Warn("[%s] Failed to mint Vertex access token: %v", attempt.endpoint.Name, err)
+			p.stats.RecordError(attempt.endpoint.Name)
+			return attemptResultRetryNextEndpoint
+		}
+		attempt.apiKey = token
+		return attemptResultDone
+	}
+
+	if attempt.authMode == config.AuthModeEntraID {
+		token, err := p.azureAccessToken(attempt.endpoint)
+		if err != nil {
+			logger.Warn("[%s] Failed to get Entra ID access token: %v", attempt.endpoint.Name, err)
+			p.stats.RecordError(attempt.endpoint.Name)
+			return attemptResultRetryNextEndpoint
+		}
+		attempt.apiKey = token
+		return attemptResultDone
+	}
+
 	if attempt.apiKey == "" {
 		logger.Warn("[%s] API key mode but apiKey is empty", attempt.endpoint.Name)
 		p.stats.RecordError(attempt.endpoint.Name)
@@ -303,19 +472,37 @@ func (p *Proxy) handleAttemptResponse(w http.ResponseWriter, reqCtx *proxyReques
 	if resp.StatusCode == http.StatusOK {
 		p.captureCodexRateLimitsFromHeaders(attempt.endpoint, attempt.credentialID, resp.Header)
 	}
+	if isBedrockTransformer(attempt.transformerName) {
+		if err := adaptBedrockStreamResponse(resp); err != nil {
+			logger.Warn("[%s] Failed to decode Bedrock stream: %v", attempt.endpoint.Name, err)
+		}
+	}
+	if isOllamaTransformer(attempt.transformerName) {
+		if err := adaptOllamaStreamResponse(resp); err != nil {
+			logger.Warn("[%s] Failed to decode Ollama stream: %v", attempt.endpoint.Name, err)
+		}
+	}
 
-	if resp.StatusCode == http.StatusOK && !reqCtx.streamRequested && shouldAggregateCodexStreaming(attempt.endpoint, attempt.transformerName) {
+	isStreaming := shouldHandleAsStreamingResponse(resp.Header.Get("Content-Type"), attempt.upstreamStream, attempt.endpoint, attempt.transformerName)
+	if resp.StatusCode == http.StatusOK && isStreaming && !reqCtx.streamRequested {
 		return p.handleAggregatedStreamingSuccess(w, reqCtx, attempt)
 	}
 
-	isStreaming := shouldHandleAsStreamingResponse(resp.Header.Get("Content-Type"), reqCtx.streamRequested, attempt.endpoint, attempt.transformerName)
 	if resp.StatusCode == http.StatusOK && isStreaming {
 		inputTokens, outputTokens, outputText := p.handleStreamingResponse(w, resp, attempt.endpoint, attempt.transformer, attempt.transformerName, attempt.thinkingEnabled, attempt.modelName, reqCtx.bodyBytes, attempt.credentialID)
 		p.finishSuccessfulAttempt(reqCtx, attempt, inputTokens, outputTokens, outputText)
 		return attemptResultDone
 	}
 
-	if resp.StatusCode == http.StatusOK {
+	if resp.StatusCode == http.StatusOK && reqCtx.streamRequested {
+		inputTokens, outputTokens, outputText, err := p.handleSynthesizedStreamingResponse(w, reqCtx, attempt)
+		if err == nil {
+			p.finishSuccessfulAttempt(reqCtx, attempt, inputTokens, outputTokens, outputText)
+			return attemptResultDone
+		}
+	}
+
+	if resp.StatusCode == http.StatusOK && !reqCtx.streamRequested {
 		inputTokens, outputTokens, err := p.handleNonStreamingResponse(w, resp, attempt.endpoint, attempt.transformer)
 		if err == nil {
 			p.finishSuccessfulAttempt(reqCtx, attempt, inputTokens, outputTokens, "")
@@ -330,8 +517,18 @@ func (p *Proxy) handleAttemptResponse(w http.ResponseWriter, reqCtx *proxyReques
 	return p.handleFinalStatus(w, reqCtx, attempt)
 }
 
+// handleAggregatedStreamingSuccess answers a non-streaming client from a
+// streaming upstream. Responses API streams carry the final response in
+// response.completed; other streams are folded event by event.
 func (p *Proxy) handleAggregatedStreamingSuccess(w http.ResponseWriter, reqCtx *proxyRequestContext, attempt *endpointAttempt) attemptResult {
-	inputTokens, outputTokens, outputText, err := p.handleStreamingAsNonStreaming(w, attempt.response, attempt.endpoint, attempt.transformer, attempt.credentialID)
+	var inputTokens, outputTokens int
+	var outputText string
+	var err error
+	if strings.Contains(attempt.transformerName, "openai2") {
+		inputTokens, outputTokens, outputText, err = p.handleStreamingAsNonStreaming(w, attempt.response, attempt.endpoint, attempt.transformer, attempt.credentialID)
+	} else {
+		inputTokens, outputTokens, outputText, err = p.handleAggregatedStreamingResponse(w, reqCtx, attempt)
+	}
 	if err == nil {
 		p.finishSuccessfulAttempt(reqCtx, attempt, inputTokens, outputTokens, outputText)
 		return attemptResultDone
@@ -352,13 +549,14 @@ func (p *Proxy) finishSuccessfulAttempt(reqCtx *proxyRequestContext, attempt *en
 	p.stats.RecordRequest(attempt.endpoint.Name)
 	p.stats.RecordTokens(attempt.endpoint.Name, inputTokens, outputTokens)
 	p.recordCredentialUsage(attempt.credentialID, attempt.endpoint.Name, 1, 0, inputTokens, outputTokens)
+	p.recordClientKeyUsage(reqCtx.clientKey, attempt.endpoint.Name, 1, 0, inputTokens, outputTokens)
 	p.markCredentialSuccess(attempt.credentialID)
 	p.markRequestInactive(attempt.endpoint.Name)
 	if p.onEndpointSuccess != nil {
 		p.onEndpointSuccess(attempt.endpoint.Name)
 	}
 	totalElapsed := time.Since(reqCtx.requestStart).Round(time.Millisecond)
-	logger.Debug("[%s] Requested tokens=%d/%d latency=%s cred_id=%d", attempt.endpoint.Name, inputTokens, outputTokens, totalElapsed, attempt.credentialID)
+	logger.Debug("[%s] Requested tokens=%d/%d latency=%s cred_id=%d key=%s", attempt.endpoint.Name, inputTokens, outputTokens, totalElapsed, attempt.credentialID, clientKeyLabel(reqCtx.clientKey))
 }
 
 func (p *Proxy) handleRetryableStatus(resp *http.Response, attempt *endpointAttempt) attemptResult {
@@ -378,6 +576,11 @@ func (p *Proxy) handleFinalStatus(w http.ResponseWriter, reqCtx *proxyRequestCon
 	respBody := readResponseBody(resp)
 	skipCredentialPenalty := false
 
+	if resp.StatusCode == http.StatusUnauthorized && p.tryMintedTokenRetry(reqCtx, attempt) {
+		p.markRequestInactive(attempt.endpoint.Name)
+		return attemptResultRetrySameEndpoint
+	}
+
 	if (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) && attempt.credentialID > 0 {
 		errMsg := truncateString(string(respBody), 500)
 		if !shouldTreatCredentialAuthFailure(resp.StatusCode, errMsg) {
@@ -413,6 +616,7 @@ func (p *Proxy) handleFinalStatus(w http.ResponseWriter, reqCtx *proxyRequestCon
 			p.markCredentialFailure(attempt.credentialID, resp.StatusCode, errMsg)
 		}
 		p.recordCredentialUsage(attempt.credentialID, attempt.endpoint.Name, 0, 1, 0, 0)
+		p.recordClientKeyUsage(reqCtx.clientKey, attempt.endpoint.Name, 0, 1, 0, 0)
 		logger.Warn("[%s] Response %d: %s", attempt.endpoint.Name, resp.StatusCode, errMsg)
 		logger.DebugLog("[%s] Response %d: %s", attempt.endpoint.Name, resp.StatusCode, errMsg)
 	}
@@ -458,7 +662,8 @@ func resolveAttemptModelName(reqCtx *proxyRequestContext, endpoint config.Endpoi
 
 func shouldOverridePayloadModel(transformerName string) bool {
 	return strings.Contains(transformerName, "claude") ||
-		strings.Contains(transformerName, "openai")
+		strings.Contains(transformerName, "openai") ||
+		isOllamaTransformer(transformerName)
 }
 
 func detectThinkingEnabled(transformerName string, transformedBody []byte) bool {
@@ -469,8 +674,17 @@ func detectThinkingEnabled(transformerName string, transformedBody []byte) bool
 	if err := json.Unmarshal(transformedBody, &openaiReq); err != nil {
 		return false
 	}
-	enable, _ := openaiReq["enable_thinking"].(bool)
-	return enable
+	if enable, _ := openaiReq["enable_thinking"].(bool); enable {
+		return true
+	}
+	if effort, _ := openaiReq["reasoning_effort"].(string); effort != "" && effort != "none" {
+		return true
+	}
+	if reasoning, ok := openaiReq["reasoning"].(map[string]interface{}); ok {
+		effort, _ := reasoning["effort"].(string)
+		return effort != "none"
+	}
+	return false
 }
 
 func writeInvalidRequestError(w http.ResponseWriter, message string) {
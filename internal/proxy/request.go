@@ -14,18 +14,24 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/net/proxy"
 
+	"github.com/lich0821/ccNexus/internal/azure"
 	"github.com/lich0821/ccNexus/internal/config"
 	"github.com/lich0821/ccNexus/internal/logger"
 	"github.com/lich0821/ccNexus/internal/storage"
 	"github.com/lich0821/ccNexus/internal/transformer"
 	"github.com/lich0821/ccNexus/internal/transformer/cc"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
 	"github.com/lich0821/ccNexus/internal/transformer/cx/chat"
 	"github.com/lich0821/ccNexus/internal/transformer/cx/responses"
+	"github.com/lich0821/ccNexus/internal/transformer/ge"
+	"github.com/lich0821/ccNexus/internal/vertex"
 )
 
 const (
 	codexClientVersion = "0.101.0"
 	codexUserAgent     = "codex_cli_rs/0.101.0 (Mac OS 26.0.1; arm64) Apple_Terminal/464"
+
+	codexEncryptedReasoningInclude = "reasoning.encrypted_content"
 )
 
 // prepareTransformerForClient creates transformer based on client format and endpoint.
@@ -37,16 +43,52 @@ func prepareTransformerForClient(clientFormat ClientFormat, endpoint config.Endp
 		endpointTransformer = "claude"
 	}
 
+	var trans transformer.Transformer
+	var err error
 	switch clientFormat {
 	case ClientFormatClaude:
-		return prepareCCTransformer(endpoint, endpointTransformer, effectiveModel)
+		trans, err = prepareCCTransformer(endpoint, endpointTransformer, effectiveModel)
 	case ClientFormatOpenAIChat:
-		return prepareCxChatTransformer(endpoint, endpointTransformer, effectiveModel)
+		trans, err = prepareCxChatTransformer(endpoint, endpointTransformer, effectiveModel)
 	case ClientFormatOpenAIResponses:
-		return prepareCxRespTransformer(endpoint, endpointTransformer, effectiveModel)
+		trans, err = prepareCxRespTransformer(endpoint, endpointTransformer, effectiveModel)
+	case ClientFormatGemini:
+		trans, err = prepareGeTransformer(endpoint, endpointTransformer, effectiveModel)
+	default:
+		return nil, fmt.Errorf("unsupported client format: %s", clientFormat)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("unsupported client format: %s", clientFormat)
+	if configurable, ok := trans.(transformer.Configurable); ok {
+		configurable.SetOptions(transformerOptionsForEndpoint(endpoint))
+	}
+	return trans, nil
+}
+
+// transformerOptionsForEndpoint maps per-endpoint options to conversion options
+func transformerOptionsForEndpoint(endpoint config.Endpoint) transformer.Options {
+	var opts transformer.Options
+	if endpoint.Options != nil && endpoint.Options.Reasoning != nil {
+		r := endpoint.Options.Reasoning
+		opts.Reasoning = transformer.ReasoningConfig{
+			LowMaxBudget:    r.LowMaxBudget,
+			MediumMaxBudget: r.MediumMaxBudget,
+			LowBudget:       r.LowBudget,
+			MediumBudget:    r.MediumBudget,
+			HighBudget:      r.HighBudget,
+			Summary:         r.Summary,
+		}
+	}
+	if endpoint.Options != nil && endpoint.Options.Ollama != nil {
+		opts.Ollama = transformer.OllamaConfig{
+			NumCtx:    endpoint.Options.Ollama.NumCtx,
+			KeepAlive: endpoint.Options.Ollama.KeepAlive,
+		}
+	}
+	opts.Quirks = endpoint.Options.QuirkProfile()
+	return opts
 }
 
 // prepareCCTransformer creates transformer for Claude Code client
@@ -64,6 +106,15 @@ func prepareCCTransformer(endpoint config.Endpoint, endpointTransformer string,
 		return cc.NewOpenAI2Transformer(effectiveModel), nil
 	case "gemini":
 		return cc.NewGeminiTransformer(effectiveModel), nil
+	case config.BedrockTransformer:
+		return cc.NewBedrockTransformer(effectiveModel), nil
+	case config.VertexTransformer:
+		if vertex.IsClaudeModel(effectiveModel) {
+			return cc.NewVertexClaudeTransformer(effectiveModel), nil
+		}
+		return cc.NewVertexGeminiTransformer(effectiveModel), nil
+	case config.OllamaTransformer:
+		return cc.NewOllamaTransformer(effectiveModel), nil
 	default:
 		return nil, fmt.Errorf("unsupported endpoint transformer: %s", endpointTransformer)
 	}
@@ -80,6 +131,15 @@ func prepareCxChatTransformer(endpoint config.Endpoint, endpointTransformer stri
 		return chat.NewOpenAI2Transformer(effectiveModel), nil
 	case "gemini":
 		return chat.NewGeminiTransformer(effectiveModel), nil
+	case config.BedrockTransformer:
+		return chat.NewBedrockTransformer(effectiveModel), nil
+	case config.VertexTransformer:
+		if vertex.IsClaudeModel(effectiveModel) {
+			return chat.NewVertexClaudeTransformer(effectiveModel), nil
+		}
+		return chat.NewVertexGeminiTransformer(effectiveModel), nil
+	case config.OllamaTransformer:
+		return chat.NewOllamaTransformer(effectiveModel), nil
 	default:
 		return nil, fmt.Errorf("unsupported endpoint transformer for Codex Chat: %s", endpointTransformer)
 	}
@@ -96,20 +156,75 @@ func prepareCxRespTransformer(endpoint config.Endpoint, endpointTransformer stri
 		return responses.NewOpenAI2Transformer(effectiveModel), nil
 	case "gemini":
 		return responses.NewGeminiTransformer(effectiveModel), nil
+	case config.BedrockTransformer:
+		return responses.NewBedrockTransformer(effectiveModel), nil
+	case config.VertexTransformer:
+		if vertex.IsClaudeModel(effectiveModel) {
+			return responses.NewVertexClaudeTransformer(effectiveModel), nil
+		}
+		return responses.NewVertexGeminiTransformer(effectiveModel), nil
+	case config.OllamaTransformer:
+		return responses.NewOllamaTransformer(effectiveModel), nil
 	default:
 		return nil, fmt.Errorf("unsupported endpoint transformer for Codex Responses: %s", endpointTransformer)
 	}
 }
 
+// prepareGeTransformer creates transformer for Gemini CLI client
+func prepareGeTransformer(endpoint config.Endpoint, endpointTransformer string, effectiveModel string) (transformer.Transformer, error) {
+	switch endpointTransformer {
+	case "claude":
+		return ge.NewClaudeTransformer(effectiveModel), nil
+	case "openai":
+		return ge.NewOpenAITransformer(effectiveModel), nil
+	case "openai2":
+		return ge.NewOpenAI2Transformer(effectiveModel), nil
+	case "gemini":
+		return ge.NewGeminiTransformer(effectiveModel), nil
+	case config.BedrockTransformer:
+		return ge.NewBedrockTransformer(effectiveModel), nil
+	case config.VertexTransformer:
+		if vertex.IsClaudeModel(effectiveModel) {
+			return ge.NewVertexClaudeTransformer(effectiveModel), nil
+		}
+		return ge.NewVertexGeminiTransformer(effectiveModel), nil
+	case config.OllamaTransformer:
+		return ge.NewOllamaTransformer(effectiveModel), nil
+	default:
+		return nil, fmt.Errorf("unsupported endpoint transformer for Gemini: %s", endpointTransformer)
+	}
+}
+
 // getTargetPath determines the target API path based on transformer name
 func getTargetPath(originalPath string, endpoint config.Endpoint, transformedBody []byte, transformerName string, modelName string) string {
+	if isAzureOpenAIRequest(endpoint, transformerName) {
+		return azureTargetPath(endpoint, transformedBody, transformerName, modelName)
+	}
 	switch transformerName {
-	case "cc_claude", "cx_chat_claude", "cx_resp_claude":
+	case "cc_claude", "cx_chat_claude", "cx_resp_claude", "ge_claude":
 		return "/v1/messages"
-	case "cc_openai", "cx_chat_openai", "cx_resp_openai":
+	case "cc_openai", "cx_chat_openai", "cx_resp_openai", "ge_openai":
 		return "/v1/chat/completions"
-	case "cc_openai2", "cx_resp_openai2", "cx_chat_openai2":
+	case "cc_openai2", "cx_resp_openai2", "cx_chat_openai2", "ge_openai2":
 		return "/v1/responses"
+	case "cc_ollama", "cx_chat_ollama", "cx_resp_ollama", "ge_ollama":
+		return "/api/chat"
+	case "cc_bedrock", "cx_chat_bedrock", "cx_resp_bedrock", "ge_bedrock":
+		return bedrockTargetPath(endpoint, transformedBody, modelName)
+	case "cc_vertex_claude", "cx_chat_vertex_claude", "cx_resp_vertex_claude", "ge_vertex_claude",
+		"cc_vertex_gemini", "cx_chat_vertex_gemini", "cx_resp_vertex_gemini", "ge_vertex_gemini":
+		return vertexTargetPath(endpoint, transformedBody, transformerName, modelName)
+	case "ge_gemini":
+		// Keep the client's generate method, only the model may change
+		originalModel, stream := parseGeminiPath(originalPath)
+		model := strings.TrimSpace(modelName)
+		if model == "" {
+			model = originalModel
+		}
+		if stream {
+			return fmt.Sprintf("/v1beta/models/%s%s", model, geminiStreamGenerateMethod)
+		}
+		return fmt.Sprintf("/v1beta/models/%s%s", model, geminiGenerateMethod)
 	case "cc_gemini", "cx_chat_gemini", "cx_resp_gemini":
 		var geminiReq struct {
 			Stream bool `json:"stream"`
@@ -134,15 +249,47 @@ func buildProxyRequest(r *http.Request, endpoint config.Endpoint, apiKey string,
 		targetPath = r.URL.Path
 	}
 
+	isAzure := isAzureOpenAIRequest(endpoint, transformerName)
 	normalizedAPIUrl := normalizeAPIUrl(endpoint.APIUrl)
+	if isAzure {
+		normalizedAPIUrl = azure.BaseURL(normalizedAPIUrl)
+	}
 	targetPath = normalizeTargetPathForBaseURL(normalizedAPIUrl, targetPath)
 	requestBody := transformedBody
+	if isAzure {
+		requestBody = azureRequestBody(endpoint, requestBody, modelName)
+	}
 	if isCodexBackendBaseURL(normalizedAPIUrl) && isResponsesPath(targetPath) {
 		requestBody = ensureCodexResponsesPayload(requestBody)
 	}
+	if isBedrockTransformer(transformerName) {
+		bedrockBody, _, err := convert.ClaudeReqToBedrock(requestBody, r.Header.Get("anthropic-beta"))
+		if err != nil {
+			return nil, err
+		}
+		requestBody = bedrockBody
+	}
+	if isVertexTransformer(transformerName) {
+		vertexBody, err := vertexRequestBody(requestBody, transformerName)
+		if err != nil {
+			return nil, err
+		}
+		requestBody = vertexBody
+	}
 	targetURL := fmt.Sprintf("%s%s", normalizedAPIUrl, targetPath)
-	if r.URL.RawQuery != "" {
-		targetURL += "?" + r.URL.RawQuery
+	rawQuery := r.URL.RawQuery
+	if isGeminiClientTransformer(transformerName) {
+		rawQuery = stripGeminiClientQuery(r.URL.Query())
+	}
+	if isVertexTransformer(transformerName) {
+		// Google APIs reject unknown query parameters such as Claude Code's beta=true
+		rawQuery = ""
+	}
+	if isAzure {
+		rawQuery = azureQuery(endpoint)
+	}
+	if rawQuery != "" {
+		targetURL += "?" + rawQuery
 	}
 
 	proxyReq, err := http.NewRequest(r.Method, targetURL, bytes.NewReader(requestBody))
@@ -150,9 +297,10 @@ func buildProxyRequest(r *http.Request, endpoint config.Endpoint, apiKey string,
 		return nil, err
 	}
 
-	// Copy headers (except Host and Accept-Encoding)
+	// Copy headers (except Host, Accept-Encoding and the client's own key)
 	for key, values := range r.Header {
-		if key == "Host" || key == "Accept-Encoding" {
+		switch key {
+		case "Host", "Accept-Encoding", "Authorization", "X-Api-Key", "X-Goog-Api-Key":
 			continue
 		}
 		for _, value := range values {
@@ -165,13 +313,28 @@ func buildProxyRequest(r *http.Request, endpoint config.Endpoint, apiKey string,
 
 	// Set authentication based on transformer type
 	switch transformerName {
-	case "cc_openai", "cc_openai2", "cx_chat_openai", "cx_chat_openai2", "cx_resp_openai", "cx_resp_openai2":
+	case "cc_openai", "cc_openai2", "cx_chat_openai", "cx_chat_openai2", "cx_resp_openai", "cx_resp_openai2", "ge_openai", "ge_openai2":
+		proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
+	case "cc_ollama", "cx_chat_ollama", "cx_resp_ollama", "ge_ollama":
+		// Ollama ignores the key; it matters only behind an authenticating proxy
 		proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
 	case "cc_gemini", "cx_chat_gemini", "cx_resp_gemini":
 		q := proxyReq.URL.Query()
 		q.Set("key", apiKey)
 		q.Set("alt", "sse")
 		proxyReq.URL.RawQuery = q.Encode()
+	case "cc_bedrock", "cx_chat_bedrock", "cx_resp_bedrock", "ge_bedrock":
+		// Signed last, once all headers are final
+	case "cc_vertex_claude", "cx_chat_vertex_claude", "cx_resp_vertex_claude", "ge_vertex_claude",
+		"cc_vertex_gemini", "cx_chat_vertex_gemini", "cx_resp_vertex_gemini", "ge_vertex_gemini":
+		applyVertexAuth(proxyReq, apiKey, transformerName)
+	case "ge_gemini":
+		q := proxyReq.URL.Query()
+		q.Set("key", apiKey)
+		if strings.HasSuffix(targetPath, geminiStreamGenerateMethod) {
+			q.Set("alt", "sse")
+		}
+		proxyReq.URL.RawQuery = q.Encode()
 	default:
 		// Claude endpoints
 		proxyReq.Header.Set("x-api-key", apiKey)
@@ -183,10 +346,31 @@ func buildProxyRequest(r *http.Request, endpoint config.Endpoint, apiKey string,
 		proxyReq.Header.Set("Host", parsedBase.Host)
 	}
 	applyCodexCredentialHeaders(proxyReq, credential, requestBody)
+	if isAzure {
+		applyAzureAuth(proxyReq, endpoint, apiKey)
+	}
+	if isBedrockTransformer(transformerName) {
+		if err := applyBedrockAuth(proxyReq, endpoint, apiKey, requestBody); err != nil {
+			return nil, err
+		}
+	}
 
 	return proxyReq, nil
 }
 
+// isGeminiClientTransformer reports whether the transformer serves a Gemini CLI client
+func isGeminiClientTransformer(transformerName string) bool {
+	return strings.HasPrefix(transformerName, "ge_")
+}
+
+// stripGeminiClientQuery removes the Gemini client's own API key and response
+// format from the query so neither is forwarded upstream
+func stripGeminiClientQuery(query url.Values) string {
+	query.Del("key")
+	query.Del("alt")
+	return query.Encode()
+}
+
 func applyCodexCredentialHeaders(req *http.Request, credential *storage.EndpointCredential, payload []byte) {
 	if req == nil || credential == nil {
 		return
@@ -292,6 +476,7 @@ func ensureCodexResponsesPayload(payload []byte) []byte {
 	if _, ok := body["instructions"]; !ok {
 		body["instructions"] = ""
 	}
+	preserveCodexReasoning(body)
 	updated, err := json.Marshal(body)
 	if err != nil {
 		return payload
@@ -299,10 +484,51 @@ func ensureCodexResponsesPayload(payload []byte) []byte {
 	return updated
 }
 
+// preserveCodexReasoning keeps reasoning usable across turns with store=false:
+// encrypted_content is requested in the response, and input reasoning items are
+// replayed by content since their IDs are not persisted upstream.
+func preserveCodexReasoning(body map[string]interface{}) {
+	include, _ := body["include"].([]interface{})
+	hasEncrypted := false
+	for _, v := range include {
+		if s, _ := v.(string); s == codexEncryptedReasoningInclude {
+			hasEncrypted = true
+			break
+		}
+	}
+	if !hasEncrypted {
+		body["include"] = append(include, codexEncryptedReasoningInclude)
+	}
+
+	input, ok := body["input"].([]interface{})
+	if !ok {
+		return
+	}
+	filtered := make([]interface{}, 0, len(input))
+	for _, item := range input {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok || itemMap["type"] != "reasoning" {
+			filtered = append(filtered, item)
+			continue
+		}
+		if encrypted, _ := itemMap["encrypted_content"].(string); encrypted == "" {
+			continue
+		}
+		delete(itemMap, "id")
+		filtered = append(filtered, itemMap)
+	}
+	body["input"] = filtered
+}
+
 func overrideModelInPayload(payload []byte, model string) []byte {
 	if strings.TrimSpace(model) == "" {
 		return payload
 	}
+	return setPayloadField(payload, "model", model)
+}
+
+// setPayloadField sets a top-level field of a JSON object payload
+func setPayloadField(payload []byte, key string, value interface{}) []byte {
 	trimmed := strings.TrimSpace(string(payload))
 	if trimmed == "" || strings.HasPrefix(trimmed, "[") {
 		return payload
@@ -312,7 +538,24 @@ func overrideModelInPayload(payload []byte, model string) []byte {
 	if err := json.Unmarshal(payload, &body); err != nil {
 		return payload
 	}
-	body["model"] = model
+	body[key] = value
+	updated, err := json.Marshal(body)
+	if err != nil {
+		return payload
+	}
+	return updated
+}
+
+// removePayloadField deletes a top-level field from a JSON object payload
+func removePayloadField(payload []byte, key string) []byte {
+	var body map[string]interface{}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return payload
+	}
+	if _, ok := body[key]; !ok {
+		return payload
+	}
+	delete(body, key)
 	updated, err := json.Marshal(body)
 	if err != nil {
 		return payload
@@ -2,9 +2,11 @@ package proxy
 
 import (
 	"encoding/json"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/transformer"
 	"github.com/lich0821/ccNexus/internal/transformer/convert"
 )
 
@@ -49,6 +51,100 @@ func TestEnsureCodexResponsesPayloadOverridesStoreAndStream(t *testing.T) {
 	}
 }
 
+func TestEnsureCodexResponsesPayloadPreservesEncryptedReasoning(t *testing.T) {
+	raw := []byte(`{"model":"gpt-5","include":["file_search_call.results"],"input":[
+		{"type":"message","role":"user","content":"hi"},
+		{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"gAAAAABenc"},
+		{"type":"reasoning","id":"rs_2","summary":[]}
+	]}`)
+	out := ensureCodexResponsesPayload(raw)
+
+	var payload struct {
+		Include []string                 `json:"include"`
+		Input   []map[string]interface{} `json:"input"`
+	}
+	if err := json.Unmarshal(out, &payload); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if len(payload.Include) != 2 || payload.Include[1] != "reasoning.encrypted_content" {
+		t.Fatalf("expected encrypted reasoning to be included, got %v", payload.Include)
+	}
+	if len(payload.Input) != 2 {
+		t.Fatalf("expected reasoning item without encrypted_content to be dropped, got %v", payload.Input)
+	}
+	reasoning := payload.Input[1]
+	if reasoning["encrypted_content"] != "gAAAAABenc" {
+		t.Fatalf("expected encrypted_content to be preserved, got %v", reasoning)
+	}
+	if _, ok := reasoning["id"]; ok {
+		t.Fatalf("expected reasoning item id to be removed with store=false, got %v", reasoning)
+	}
+}
+
+func TestPrepareTransformerAppliesEndpointReasoningOptions(t *testing.T) {
+	endpoint := config.Endpoint{
+		Name:        "openai",
+		Transformer: "openai",
+		Options: &config.EndpointOptions{
+			Reasoning: &config.ReasoningOptions{LowMaxBudget: 1000, MediumMaxBudget: 2000},
+		},
+	}
+	trans, err := prepareTransformerForClient(ClientFormatClaude, endpoint, "gpt-5")
+	if err != nil {
+		t.Fatalf("prepareTransformerForClient failed: %v", err)
+	}
+
+	out, err := trans.TransformRequest([]byte(`{"max_tokens":8000,"thinking":{"type":"enabled","budget_tokens":4000},"messages":[]}`))
+	if err != nil {
+		t.Fatalf("TransformRequest failed: %v", err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(out, &payload); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if payload["reasoning_effort"] != "high" {
+		t.Fatalf("expected endpoint thresholds to map budget to high, got %#v", payload["reasoning_effort"])
+	}
+}
+
+func TestPrepareTransformerAppliesEndpointQuirks(t *testing.T) {
+	endpoint := config.Endpoint{
+		Name:        "qwen",
+		Transformer: "openai",
+		Options: &config.EndpointOptions{
+			Quirks: &config.QuirkOptions{Profile: "qwen", MaxTokens: 4000, DropFields: []string{"user"}},
+		},
+	}
+	for _, client := range []ClientFormat{ClientFormatClaude, ClientFormatOpenAIChat} {
+		trans, err := prepareTransformerForClient(client, endpoint, "qwen3")
+		if err != nil {
+			t.Fatalf("prepareTransformerForClient failed: %v", err)
+		}
+		body := `{"model":"qwen3","max_tokens":8000,"thinking":{"type":"enabled","budget_tokens":9000},"messages":[]}`
+		if client == ClientFormatOpenAIChat {
+			body = `{"model":"qwen3","max_completion_tokens":8000,"reasoning_effort":"high","user":"u","messages":[]}`
+		}
+		out, err := trans.TransformRequest([]byte(body))
+		if err != nil {
+			t.Fatalf("TransformRequest failed: %v", err)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(out, &payload); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		if payload["enable_thinking"] != true || payload["max_tokens"] != float64(4000) {
+			t.Fatalf("%s: expected the qwen profile with a clamped limit, got %v", client, payload)
+		}
+		if _, ok := payload["reasoning_effort"]; ok {
+			t.Fatalf("%s: reasoning_effort should be replaced, got %v", client, payload)
+		}
+		if _, ok := payload["user"]; ok {
+			t.Fatalf("%s: user should be dropped, got %v", client, payload)
+		}
+	}
+}
+
 func TestNormalizeTargetPathForBaseURLOnCodexBackend(t *testing.T) {
 	got := normalizeTargetPathForBaseURL("https://chatgpt.com/backend-api/codex", "/v1/responses")
 	if got != "/responses" {
@@ -156,7 +252,7 @@ func TestResolveAttemptModelNameFallsBackToRequestOverride(t *testing.T) {
 func TestClaudeToOpenAIUsesRequestModelWhenEndpointModelEmpty(t *testing.T) {
 	body := []byte(`{"model":"glm-5.1","messages":[{"role":"user","content":"hello"}]}`)
 
-	out, err := convert.OpenAIReqToClaude(body, "glm-5.1")
+	out, err := convert.OpenAIReqToClaude(body, "glm-5.1", transformer.Options{})
 	if err != nil {
 		t.Fatalf("transform failed: %v", err)
 	}
@@ -186,3 +282,57 @@ func TestShouldHandleAsStreamingResponseForCodexWithoutContentType(t *testing.T)
 		t.Fatal("expected text/event-stream content-type to be treated as streaming")
 	}
 }
+
+func TestDetectGeminiClientFormat(t *testing.T) {
+	path := "/v1beta/models/gemini-2.5-pro:streamGenerateContent"
+	if got := detectClientFormat(path); got != ClientFormatGemini {
+		t.Fatalf("expected gemini client format, got %s", got)
+	}
+	model, stream := parseGeminiPath(path)
+	if model != "gemini-2.5-pro" || !stream {
+		t.Fatalf("expected gemini-2.5-pro streaming, got %q %v", model, stream)
+	}
+	model, stream = parseGeminiPath("/v1beta/models/gemini-2.5-flash:generateContent")
+	if model != "gemini-2.5-flash" || stream {
+		t.Fatalf("expected gemini-2.5-flash non-streaming, got %q %v", model, stream)
+	}
+}
+
+func TestGeminiClientRequestToClaudeEndpoint(t *testing.T) {
+	endpoint := config.Endpoint{Name: "claude", APIUrl: "https://api.anthropic.com", Transformer: "claude", Model: "claude-sonnet-4"}
+	trans, err := prepareTransformerForClient(ClientFormatGemini, endpoint, endpoint.Model)
+	if err != nil {
+		t.Fatalf("prepareTransformerForClient failed: %v", err)
+	}
+	if trans.Name() != "ge_claude" {
+		t.Fatalf("expected ge_claude transformer, got %s", trans.Name())
+	}
+
+	r := httptest.NewRequest("POST", "/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse&key=client-key", nil)
+	r.Header.Set("X-Goog-Api-Key", "client-key")
+	proxyReq, err := buildProxyRequest(r, endpoint, "upstream-key", []byte(`{}`), trans.Name(), endpoint.Model, nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	if proxyReq.URL.String() != "https://api.anthropic.com/v1/messages" {
+		t.Fatalf("expected client query to be dropped, got %s", proxyReq.URL)
+	}
+	if proxyReq.Header.Get("X-Goog-Api-Key") != "" || proxyReq.Header.Get("x-api-key") != "upstream-key" {
+		t.Fatalf("expected only the upstream key to be sent, got %v", proxyReq.Header)
+	}
+}
+
+func TestGeminiClientRequestToGeminiEndpoint(t *testing.T) {
+	endpoint := config.Endpoint{Name: "gemini", APIUrl: "https://generativelanguage.googleapis.com", Transformer: "gemini", Model: "gemini-2.5-flash"}
+	r := httptest.NewRequest("POST", "/v1beta/models/gemini-2.5-pro:generateContent?key=client-key", nil)
+	proxyReq, err := buildProxyRequest(r, endpoint, "upstream-key", []byte(`{}`), "ge_gemini", endpoint.Model, nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	if proxyReq.URL.Path != "/v1beta/models/gemini-2.5-flash:generateContent" {
+		t.Fatalf("expected endpoint model in path, got %s", proxyReq.URL.Path)
+	}
+	if proxyReq.URL.RawQuery != "key=upstream-key" {
+		t.Fatalf("expected upstream key without alt=sse, got %s", proxyReq.URL.RawQuery)
+	}
+}
@@ -43,6 +43,10 @@ func (p *Proxy) handleNonStreamingResponse(w http.ResponseWriter, resp *http.Res
 
 	logger.DebugLog("[%s] Transformed Response: %s", endpoint.Name, string(transformedResp))
 
+	provenance := make(map[string]string)
+	collectProvenanceFromResponse(provenance, transformedResp, providerForTransformer(endpoint.Transformer, trans.Name()))
+	p.provenance.record(provenance)
+
 	// Extract token usage
 	inputTokens, outputTokens := extractTokenUsage(transformedResp)
 	if inputTokens == 0 && outputTokens == 0 {
@@ -77,6 +81,9 @@ func extractTokenUsage(responseBody []byte) (int, int) {
 	if usage, ok := resp["usage"].(map[string]interface{}); ok {
 		return extractInputOutputTokens(usage)
 	}
+	if usage, ok := resp["usageMetadata"].(map[string]interface{}); ok {
+		return extractInputOutputTokens(usage)
+	}
 
 	return 0, 0
 }
@@ -85,6 +92,7 @@ func extractTokenUsage(responseBody []byte) (int, int) {
 // Supports:
 // - Claude/OpenAI Responses: input_tokens/output_tokens
 // - OpenAI Chat: prompt_tokens/completion_tokens
+// - Gemini usageMetadata: promptTokenCount/candidatesTokenCount
 func extractInputOutputTokens(usage map[string]interface{}) (int, int) {
 	var inputTokens, outputTokens int
 
@@ -92,12 +100,16 @@ func extractInputOutputTokens(usage map[string]interface{}) (int, int) {
 		inputTokens = parseTokenNumber(input)
 	} else if input, ok := usage["prompt_tokens"]; ok {
 		inputTokens = parseTokenNumber(input)
+	} else if input, ok := usage["promptTokenCount"]; ok {
+		inputTokens = parseTokenNumber(input)
 	}
 
 	if output, ok := usage["output_tokens"]; ok {
 		outputTokens = parseTokenNumber(output)
 	} else if output, ok := usage["completion_tokens"]; ok {
 		outputTokens = parseTokenNumber(output)
+	} else if output, ok := usage["candidatesTokenCount"]; ok {
+		outputTokens = parseTokenNumber(output)
 	}
 
 	return inputTokens, outputTokens
@@ -177,6 +189,9 @@ func extractResponseOutputText(responseBody []byte) string {
 		}
 	}
 
+	// Gemini style: candidates[].content.parts[].text
+	appendGeminiCandidateText(payload, &builder)
+
 	// OpenAI Responses style: output[].content[].text
 	if output, ok := payload["output"].([]interface{}); ok {
 		for _, outVal := range output {
@@ -200,3 +215,25 @@ func extractResponseOutputText(responseBody []byte) string {
 
 	return builder.String()
 }
+
+// appendGeminiCandidateText appends the non-thought text of Gemini candidates
+func appendGeminiCandidateText(payload map[string]interface{}, builder *strings.Builder) {
+	candidates, ok := payload["candidates"].([]interface{})
+	if !ok {
+		return
+	}
+	for _, candidateVal := range candidates {
+		candidate, _ := candidateVal.(map[string]interface{})
+		content, _ := candidate["content"].(map[string]interface{})
+		parts, _ := content["parts"].([]interface{})
+		for _, partVal := range parts {
+			part, _ := partVal.(map[string]interface{})
+			if thought, _ := part["thought"].(bool); thought {
+				continue
+			}
+			if text, ok := part["text"].(string); ok {
+				builder.WriteString(text)
+			}
+		}
+	}
+}
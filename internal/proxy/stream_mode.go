@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// upstreamStreaming decides whether the upstream request streams. Endpoints
+// follow the client unless their streaming option says otherwise; the Codex
+// backend only accepts streaming requests.
+func upstreamStreaming(endpoint config.Endpoint, transformerName string, clientStream bool) bool {
+	switch endpoint.Options.StreamingMode() {
+	case config.StreamingAlways:
+		return true
+	case config.StreamingNever:
+		return false
+	}
+	if shouldAggregateCodexStreaming(endpoint, transformerName) {
+		return true
+	}
+	return clientStream
+}
+
+// isGeminiUpstreamTransformer reports whether the transformer calls the
+// Gemini API directly, which selects streaming by URL method
+func isGeminiUpstreamTransformer(transformerName string) bool {
+	switch transformerName {
+	case "cc_gemini", "cx_chat_gemini", "cx_resp_gemini", "ge_gemini":
+		return true
+	}
+	return false
+}
+
+// isOpenAIChatUpstreamTransformer reports whether the transformer calls an
+// OpenAI Chat Completions upstream
+func isOpenAIChatUpstreamTransformer(transformerName string) bool {
+	return strings.HasSuffix(transformerName, "_openai")
+}
+
+// applyGeminiStreamMethod points a Gemini request at the streaming or the
+// non-streaming generate method
+func applyGeminiStreamMethod(req *http.Request, stream bool) {
+	path := req.URL.Path
+	if colon := strings.LastIndex(path, ":"); colon >= 0 {
+		path = path[:colon]
+	}
+	q := req.URL.Query()
+	if stream {
+		req.URL.Path = path + geminiStreamGenerateMethod
+		q.Set("alt", "sse")
+	} else {
+		req.URL.Path = path + geminiGenerateMethod
+		q.Del("alt")
+	}
+	req.URL.RawPath = ""
+	req.URL.RawQuery = q.Encode()
+}
+
+// handleSynthesizedStreamingResponse serves a streaming client from a
+// non-streaming upstream response: the response is transformed as usual and
+// replayed as SSE events in the client's format
+func (p *Proxy) handleSynthesizedStreamingResponse(w http.ResponseWriter, reqCtx *proxyRequestContext, attempt *endpointAttempt) (int, int, string, error) {
+	buffered := newResponseBuffer()
+	inputTokens, outputTokens, err := p.handleNonStreamingResponse(buffered, attempt.response, attempt.endpoint, attempt.transformer)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	events, err := synthesizeStream(reqCtx.clientFormat, buffered.body.Bytes())
+	if err != nil {
+		// Still answer with the response rather than nothing
+		logger.Warn("[%s] Failed to synthesize stream, sending response as is: %v", attempt.endpoint.Name, err)
+		buffered.copyHeadersTo(w)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(buffered.status)
+		w.Write(buffered.body.Bytes())
+		return inputTokens, outputTokens, "", nil
+	}
+	logger.DebugLog("[%s] Synthesized stream: %s", attempt.endpoint.Name, string(events))
+
+	buffered.copyHeadersTo(w)
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(events)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return inputTokens, outputTokens, extractResponseOutputText(buffered.body.Bytes()), nil
+}
+
+// handleAggregatedStreamingResponse serves a non-streaming client from a
+// streaming upstream response: the stream is transformed as usual and the
+// client-format events are folded into a single response
+func (p *Proxy) handleAggregatedStreamingResponse(w http.ResponseWriter, reqCtx *proxyRequestContext, attempt *endpointAttempt) (int, int, string, error) {
+	buffered := newResponseBuffer()
+	inputTokens, outputTokens, outputText := p.handleStreamingResponse(buffered, attempt.response, attempt.endpoint, attempt.transformer, attempt.transformerName, attempt.thinkingEnabled, attempt.modelName, reqCtx.bodyBytes, attempt.credentialID)
+	body, err := aggregateStream(reqCtx.clientFormat, buffered.body.Bytes())
+	if err != nil {
+		return 0, 0, "", err
+	}
+	logger.DebugLog("[%s] Aggregated stream: %s", attempt.endpoint.Name, string(body))
+
+	if in, out := extractTokenUsage(body); in > 0 || out > 0 {
+		inputTokens, outputTokens = in, out
+	}
+	buffered.copyHeadersTo(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	return inputTokens, outputTokens, outputText, nil
+}
+
+// responseBuffer captures a response so it can be converted before it is
+// sent to the client
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header), status: http.StatusOK}
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) Write(data []byte) (int, error) { return b.body.Write(data) }
+
+func (b *responseBuffer) WriteHeader(status int) { b.status = status }
+
+func (b *responseBuffer) Flush() {}
+
+// copyHeadersTo copies the captured headers that still apply once the body
+// has been converted
+func (b *responseBuffer) copyHeadersTo(w http.ResponseWriter) {
+	for key, values := range b.header {
+		switch key {
+		case "Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+}
+
+// errIncompleteStream reports a stream that ended before its final event
+func errIncompleteStream(format ClientFormat) error {
+	return fmt.Errorf("%s stream ended before the response was complete", format)
+}
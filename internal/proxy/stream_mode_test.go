@@ -0,0 +1,226 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+)
+
+func TestClaudeStreamRoundTrip(t *testing.T) {
+	message := `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"thinking","thinking":"hmm","signature":"sig"},{"type":"text","text":"Hello"},{"type":"tool_use","id":"toolu_1","name":"read","input":{"path":"a.go"}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":12,"output_tokens":7}}`
+
+	stream, err := synthesizeStream(ClientFormatClaude, []byte(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"event: message_start", "\"thinking_delta\"", "\"signature_delta\"", "\"text_delta\"", "\"input_json_delta\"", "event: message_delta", "event: message_stop"} {
+		if !strings.Contains(string(stream), want) {
+			t.Fatalf("synthesized stream lacks %s:\n%s", want, stream)
+		}
+	}
+
+	aggregated, err := aggregateStream(ClientFormatClaude, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want map[string]interface{}
+	json.Unmarshal(aggregated, &got)
+	json.Unmarshal([]byte(message), &want)
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Fatalf("round trip changed the message:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+}
+
+func TestOpenAIChatStreamRoundTrip(t *testing.T) {
+	completion := `{"id":"chatcmpl-1","object":"chat.completion","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"read","arguments":"{\"path\":\"a.go\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":9,"completion_tokens":4,"total_tokens":13}}`
+
+	stream, err := synthesizeStream(ClientFormatOpenAIChat, []byte(completion))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(stream), "data: [DONE]\n\n") {
+		t.Fatalf("stream must end with [DONE]:\n%s", stream)
+	}
+
+	aggregated, err := aggregateStream(ClientFormatOpenAIChat, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Choices []struct {
+			Message struct {
+				Content   *string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	json.Unmarshal(aggregated, &got)
+	if len(got.Choices) != 1 || got.Choices[0].FinishReason != "tool_calls" || got.Choices[0].Message.Content != nil {
+		t.Fatalf("unexpected aggregate: %s", aggregated)
+	}
+	if calls := got.Choices[0].Message.ToolCalls; len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"path":"a.go"}` {
+		t.Fatalf("tool call lost: %s", aggregated)
+	}
+	if got.Usage.CompletionTokens != 4 {
+		t.Fatalf("usage lost: %s", aggregated)
+	}
+}
+
+func TestResponsesStreamRoundTrip(t *testing.T) {
+	response := `{"id":"resp_1","object":"response","status":"completed","model":"gpt-5","output":[{"id":"msg_1","type":"message","role":"assistant","status":"completed","content":[{"type":"output_text","text":"Hi","annotations":[]}]},{"id":"fc_1","type":"function_call","call_id":"call_1","name":"read","arguments":"{}","status":"completed"}],"usage":{"input_tokens":5,"output_tokens":2}}`
+
+	stream, err := synthesizeStream(ClientFormatOpenAIResponses, []byte(response))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"event: response.created", "event: response.output_text.delta", "event: response.function_call_arguments.done", "event: response.completed"} {
+		if !strings.Contains(string(stream), want) {
+			t.Fatalf("synthesized stream lacks %s:\n%s", want, stream)
+		}
+	}
+	events := parseSSEEvents(stream)
+	for i, event := range events {
+		if jsonInt(event.data["sequence_number"]) != i {
+			t.Fatalf("event %d has sequence_number %v", i, event.data["sequence_number"])
+		}
+	}
+
+	aggregated, err := aggregateStream(ClientFormatOpenAIResponses, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in, out := extractTokenUsage(aggregated); in != 5 || out != 2 {
+		t.Fatalf("unexpected aggregate: %s", aggregated)
+	}
+}
+
+func TestGeminiStreamAggregation(t *testing.T) {
+	stream := "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Hel\"}]},\"index\":0}]}\n\n" +
+		"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"lo\"},{\"functionCall\":{\"name\":\"read\",\"args\":{}}}]},\"finishReason\":\"STOP\",\"index\":0}],\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":2}}\n\n"
+
+	aggregated, err := aggregateStream(ClientFormatGemini, []byte(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Candidates []struct {
+			Content struct {
+				Parts []map[string]interface{} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+	}
+	json.Unmarshal(aggregated, &got)
+	if len(got.Candidates) != 1 || got.Candidates[0].FinishReason != "STOP" {
+		t.Fatalf("unexpected aggregate: %s", aggregated)
+	}
+	parts := got.Candidates[0].Content.Parts
+	if len(parts) != 2 || parts[0]["text"] != "Hello" || parts[1]["functionCall"] == nil {
+		t.Fatalf("parts not merged: %s", aggregated)
+	}
+	if in, out := extractTokenUsage(aggregated); in != 3 || out != 2 {
+		t.Fatalf("usage lost: %s", aggregated)
+	}
+}
+
+func TestStreamOnlyEndpointServesNonStreamingClient(t *testing.T) {
+	var upstreamBody map[string]interface{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &upstreamBody)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-sonnet-4-5\",\"content\":[],\"usage\":{\"input_tokens\":8,\"output_tokens\":0}}}\n\n"))
+		w.Write([]byte("event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n"))
+		w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"ok\"}}\n\n"))
+		w.Write([]byte("event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n"))
+		w.Write([]byte("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":1}}\n\n"))
+		w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer upstream.Close()
+
+	p, statsStorage := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "StreamOnly", APIUrl: upstream.URL, APIKey: "k", Enabled: true, Transformer: "claude",
+			Options: &config.EndpointOptions{Streaming: config.StreamingAlways}},
+	})
+
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4-5","max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`))
+	rec := httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+
+	if stream, _ := upstreamBody["stream"].(bool); !stream {
+		t.Fatalf("upstream request must stream, got %v", upstreamBody)
+	}
+	var got struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.Content) != 1 || got.Content[0].Text != "ok" || got.StopReason != "end_turn" {
+		t.Fatalf("expected an aggregated message, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if requests, _, inputTokens := statsStorage.totals("StreamOnly"); requests != 1 || inputTokens != 8 {
+		t.Fatalf("expected one request with 8 input tokens, got %d with %d", requests, inputTokens)
+	}
+}
+
+func TestNonStreamingEndpointServesStreamingClient(t *testing.T) {
+	var upstreamBody map[string]interface{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &upstreamBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1700000000,"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":8,"completion_tokens":1,"total_tokens":9}}`))
+	}))
+	defer upstream.Close()
+
+	p, _ := newAuxiliaryTestProxy([]config.Endpoint{
+		{Name: "NoStream", APIUrl: upstream.URL, APIKey: "k", Enabled: true, Transformer: "openai", Model: "gpt-4o",
+			Options: &config.EndpointOptions{Streaming: config.StreamingNever}},
+	})
+
+	r := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4-5","max_tokens":10,"stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	rec := httptest.NewRecorder()
+	p.handleProxyRequest(rec, r)
+
+	if stream, _ := upstreamBody["stream"].(bool); stream {
+		t.Fatalf("upstream request must not stream, got %v", upstreamBody)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("expected an event stream, got %q", rec.Header().Get("Content-Type"))
+	}
+	out := rec.Body.String()
+	for _, want := range []string{"event: message_start", "\"text\":\"ok\"", "\"output_tokens\":1", "event: message_stop"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("synthesized stream lacks %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestApplyGeminiStreamMethod(t *testing.T) {
+	r := httptest.NewRequest("POST", "https://example.com/v1beta/models/gemini-2.5-pro:generateContent?key=k&alt=sse", nil)
+	applyGeminiStreamMethod(r, false)
+	if r.URL.Path != "/v1beta/models/gemini-2.5-pro:generateContent" || r.URL.Query().Get("alt") != "" || r.URL.Query().Get("key") != "k" {
+		t.Fatalf("unexpected non-streaming URL: %s", r.URL)
+	}
+	applyGeminiStreamMethod(r, true)
+	if r.URL.Path != "/v1beta/models/gemini-2.5-pro:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+		t.Fatalf("unexpected streaming URL: %s", r.URL)
+	}
+}
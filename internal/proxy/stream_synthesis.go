@@ -0,0 +1,693 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sseEvent is one parsed server-sent event
+type sseEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// parseSSEEvents parses the JSON events of an SSE stream. Comments, keep-alive
+// pings and [DONE] markers are skipped.
+func parseSSEEvents(stream []byte) []sseEvent {
+	var events []sseEvent
+	var name string
+	var data strings.Builder
+	flush := func() {
+		if data.Len() > 0 {
+			var payload map[string]interface{}
+			if json.Unmarshal([]byte(data.String()), &payload) == nil {
+				events = append(events, sseEvent{name: name, data: payload})
+			}
+		}
+		name = ""
+		data.Reset()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Buffer(make([]byte, 0, 128*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			value := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if value == "[DONE]" {
+				continue
+			}
+			if data.Len() > 0 {
+				data.WriteString("\n")
+			}
+			data.WriteString(value)
+		}
+	}
+	flush()
+	return events
+}
+
+// writeSSEEvent appends an event; an empty name writes a data-only event
+func writeSSEEvent(buf *bytes.Buffer, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		buf.WriteString("event: " + name + "\n")
+	}
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	return nil
+}
+
+// aggregateStream folds a client-format SSE stream into the equivalent
+// non-streaming response body
+func aggregateStream(format ClientFormat, stream []byte) ([]byte, error) {
+	events := parseSSEEvents(stream)
+	for _, event := range events {
+		if errObj := event.data["error"]; errObj != nil && event.data["choices"] == nil && event.data["candidates"] == nil {
+			return nil, fmt.Errorf("upstream stream error: %v", errObj)
+		}
+	}
+
+	var resp map[string]interface{}
+	var err error
+	switch format {
+	case ClientFormatOpenAIChat:
+		resp, err = aggregateOpenAIChatStream(events)
+	case ClientFormatOpenAIResponses:
+		resp, err = aggregateResponsesStream(events)
+	case ClientFormatGemini:
+		resp, err = aggregateGeminiStream(events)
+	default:
+		resp, err = aggregateClaudeStream(events)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
+
+// synthesizeStream replays a client-format response as the SSE stream a
+// streaming upstream would have produced
+func synthesizeStream(format ClientFormat, body []byte) ([]byte, error) {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response to stream: %w", err)
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case ClientFormatOpenAIChat:
+		err = synthesizeOpenAIChatStream(&buf, resp)
+	case ClientFormatOpenAIResponses:
+		err = synthesizeResponsesStream(&buf, resp)
+	case ClientFormatGemini:
+		err = writeSSEEvent(&buf, "", resp)
+	default:
+		err = synthesizeClaudeStream(&buf, resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// aggregateClaudeStream rebuilds a Claude message from its stream events
+func aggregateClaudeStream(events []sseEvent) (map[string]interface{}, error) {
+	var message map[string]interface{}
+	blocks := make(map[int]map[string]interface{})
+	partialJSON := make(map[int]*strings.Builder)
+
+	for _, event := range events {
+		eventType, _ := event.data["type"].(string)
+		switch eventType {
+		case "message_start":
+			message, _ = event.data["message"].(map[string]interface{})
+		case "content_block_start":
+			index := jsonInt(event.data["index"])
+			if block, ok := event.data["content_block"].(map[string]interface{}); ok {
+				blocks[index] = block
+			}
+		case "content_block_delta":
+			index := jsonInt(event.data["index"])
+			block := blocks[index]
+			delta, _ := event.data["delta"].(map[string]interface{})
+			if block == nil || delta == nil {
+				continue
+			}
+			switch delta["type"] {
+			case "text_delta":
+				block["text"] = jsonString(block["text"]) + jsonString(delta["text"])
+			case "thinking_delta":
+				block["thinking"] = jsonString(block["thinking"]) + jsonString(delta["thinking"])
+			case "signature_delta":
+				block["signature"] = jsonString(block["signature"]) + jsonString(delta["signature"])
+			case "input_json_delta":
+				if partialJSON[index] == nil {
+					partialJSON[index] = &strings.Builder{}
+				}
+				partialJSON[index].WriteString(jsonString(delta["partial_json"]))
+			}
+		case "message_delta":
+			if message == nil {
+				continue
+			}
+			if delta, ok := event.data["delta"].(map[string]interface{}); ok {
+				for key, value := range delta {
+					message[key] = value
+				}
+			}
+			if usage, ok := event.data["usage"].(map[string]interface{}); ok {
+				merged, _ := message["usage"].(map[string]interface{})
+				if merged == nil {
+					merged = make(map[string]interface{})
+				}
+				for key, value := range usage {
+					merged[key] = value
+				}
+				message["usage"] = merged
+			}
+		}
+	}
+	if message == nil {
+		return nil, errIncompleteStream(ClientFormatClaude)
+	}
+
+	for index, partial := range partialJSON {
+		input := map[string]interface{}{}
+		if partial.Len() > 0 {
+			if err := json.Unmarshal([]byte(partial.String()), &input); err != nil {
+				return nil, fmt.Errorf("invalid tool input in stream: %w", err)
+			}
+		}
+		if block := blocks[index]; block != nil {
+			block["input"] = input
+		}
+	}
+	content := make([]interface{}, 0, len(blocks))
+	for _, index := range sortedKeys(blocks) {
+		content = append(content, blocks[index])
+	}
+	message["content"] = content
+	return message, nil
+}
+
+// synthesizeClaudeStream emits message_start, a start/delta/stop sequence per
+// content block, message_delta with the stop reason and usage, and
+// message_stop
+func synthesizeClaudeStream(buf *bytes.Buffer, message map[string]interface{}) error {
+	usage, _ := message["usage"].(map[string]interface{})
+	startUsage := map[string]interface{}{"output_tokens": 0}
+	for key, value := range usage {
+		if key != "output_tokens" {
+			startUsage[key] = value
+		}
+	}
+	start := map[string]interface{}{
+		"id":            message["id"],
+		"type":          "message",
+		"role":          "assistant",
+		"model":         message["model"],
+		"content":       []interface{}{},
+		"stop_reason":   nil,
+		"stop_sequence": nil,
+		"usage":         startUsage,
+	}
+	if err := writeSSEEvent(buf, "message_start", map[string]interface{}{"type": "message_start", "message": start}); err != nil {
+		return err
+	}
+
+	content, _ := message["content"].([]interface{})
+	for index, item := range content {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		startBlock := block
+		var deltas []map[string]interface{}
+		switch block["type"] {
+		case "text":
+			startBlock = map[string]interface{}{"type": "text", "text": ""}
+			deltas = append(deltas, map[string]interface{}{"type": "text_delta", "text": jsonString(block["text"])})
+		case "thinking":
+			startBlock = map[string]interface{}{"type": "thinking", "thinking": ""}
+			deltas = append(deltas, map[string]interface{}{"type": "thinking_delta", "thinking": jsonString(block["thinking"])})
+			if signature := jsonString(block["signature"]); signature != "" {
+				deltas = append(deltas, map[string]interface{}{"type": "signature_delta", "signature": signature})
+			}
+		case "tool_use", "server_tool_use":
+			startBlock = map[string]interface{}{"type": block["type"], "id": block["id"], "name": block["name"], "input": map[string]interface{}{}}
+			input, err := json.Marshal(block["input"])
+			if err != nil {
+				return err
+			}
+			if string(input) != "null" && string(input) != "{}" {
+				deltas = append(deltas, map[string]interface{}{"type": "input_json_delta", "partial_json": string(input)})
+			}
+		}
+
+		if err := writeSSEEvent(buf, "content_block_start", map[string]interface{}{"type": "content_block_start", "index": index, "content_block": startBlock}); err != nil {
+			return err
+		}
+		for _, delta := range deltas {
+			if err := writeSSEEvent(buf, "content_block_delta", map[string]interface{}{"type": "content_block_delta", "index": index, "delta": delta}); err != nil {
+				return err
+			}
+		}
+		if err := writeSSEEvent(buf, "content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": index}); err != nil {
+			return err
+		}
+	}
+
+	deltaUsage := map[string]interface{}{"output_tokens": 0}
+	if usage != nil {
+		deltaUsage = usage
+	}
+	if err := writeSSEEvent(buf, "message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": message["stop_reason"], "stop_sequence": message["stop_sequence"]},
+		"usage": deltaUsage,
+	}); err != nil {
+		return err
+	}
+	return writeSSEEvent(buf, "message_stop", map[string]interface{}{"type": "message_stop"})
+}
+
+// aggregateOpenAIChatStream rebuilds a chat completion from its chunks
+func aggregateOpenAIChatStream(events []sseEvent) (map[string]interface{}, error) {
+	type toolCall struct {
+		id, name  string
+		arguments strings.Builder
+	}
+	type choice struct {
+		content, reasoning strings.Builder
+		role               string
+		finishReason       interface{}
+		toolCalls          map[int]*toolCall
+	}
+
+	var resp map[string]interface{}
+	choices := make(map[int]*choice)
+	for _, event := range events {
+		if resp == nil {
+			resp = map[string]interface{}{"id": event.data["id"], "created": event.data["created"], "model": event.data["model"]}
+		}
+		if usage, ok := event.data["usage"].(map[string]interface{}); ok {
+			resp["usage"] = usage
+		}
+		if fingerprint, ok := event.data["system_fingerprint"]; ok {
+			resp["system_fingerprint"] = fingerprint
+		}
+		items, _ := event.data["choices"].([]interface{})
+		for _, item := range items {
+			chunk, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			index := jsonInt(chunk["index"])
+			c := choices[index]
+			if c == nil {
+				c = &choice{role: "assistant", toolCalls: make(map[int]*toolCall)}
+				choices[index] = c
+			}
+			if reason, ok := chunk["finish_reason"]; ok && reason != nil {
+				c.finishReason = reason
+			}
+			delta, _ := chunk["delta"].(map[string]interface{})
+			if role := jsonString(delta["role"]); role != "" {
+				c.role = role
+			}
+			c.content.WriteString(jsonString(delta["content"]))
+			c.reasoning.WriteString(jsonString(delta["reasoning_content"]))
+			calls, _ := delta["tool_calls"].([]interface{})
+			for _, callItem := range calls {
+				call, ok := callItem.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				callIndex := jsonInt(call["index"])
+				tc := c.toolCalls[callIndex]
+				if tc == nil {
+					tc = &toolCall{}
+					c.toolCalls[callIndex] = tc
+				}
+				if id := jsonString(call["id"]); id != "" {
+					tc.id = id
+				}
+				function, _ := call["function"].(map[string]interface{})
+				if name := jsonString(function["name"]); name != "" {
+					tc.name = name
+				}
+				tc.arguments.WriteString(jsonString(function["arguments"]))
+			}
+		}
+	}
+	if resp == nil {
+		return nil, errIncompleteStream(ClientFormatOpenAIChat)
+	}
+
+	resultChoices := make([]interface{}, 0, len(choices))
+	for _, index := range sortedKeys(choices) {
+		c := choices[index]
+		message := map[string]interface{}{"role": c.role, "content": c.content.String()}
+		if c.reasoning.Len() > 0 {
+			message["reasoning_content"] = c.reasoning.String()
+		}
+		if len(c.toolCalls) > 0 {
+			calls := make([]interface{}, 0, len(c.toolCalls))
+			for _, callIndex := range sortedKeys(c.toolCalls) {
+				tc := c.toolCalls[callIndex]
+				calls = append(calls, map[string]interface{}{
+					"id":       tc.id,
+					"type":     "function",
+					"function": map[string]interface{}{"name": tc.name, "arguments": tc.arguments.String()},
+				})
+			}
+			message["tool_calls"] = calls
+			if c.content.Len() == 0 {
+				message["content"] = nil
+			}
+		}
+		resultChoices = append(resultChoices, map[string]interface{}{"index": index, "message": message, "finish_reason": c.finishReason})
+	}
+	resp["object"] = "chat.completion"
+	resp["choices"] = resultChoices
+	return resp, nil
+}
+
+// synthesizeOpenAIChatStream emits one content chunk per choice, a chunk with
+// each finish reason, a usage chunk and the [DONE] marker
+func synthesizeOpenAIChatStream(buf *bytes.Buffer, resp map[string]interface{}) error {
+	chunk := func(choices []interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      resp["id"],
+			"object":  "chat.completion.chunk",
+			"created": resp["created"],
+			"model":   resp["model"],
+			"choices": choices,
+		}
+	}
+
+	choices, _ := resp["choices"].([]interface{})
+	for position, item := range choices {
+		choice, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index := choice["index"]
+		if index == nil {
+			index = position
+		}
+		message, _ := choice["message"].(map[string]interface{})
+		delta := map[string]interface{}{"role": "assistant"}
+		for _, key := range []string{"content", "reasoning_content", "refusal"} {
+			if value, ok := message[key].(string); ok && value != "" {
+				delta[key] = value
+			}
+		}
+		if calls, ok := message["tool_calls"].([]interface{}); ok && len(calls) > 0 {
+			indexed := make([]interface{}, 0, len(calls))
+			for callIndex, callItem := range calls {
+				call, ok := callItem.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				withIndex := map[string]interface{}{"index": callIndex}
+				for key, value := range call {
+					withIndex[key] = value
+				}
+				indexed = append(indexed, withIndex)
+			}
+			delta["tool_calls"] = indexed
+		}
+
+		if err := writeSSEEvent(buf, "", chunk([]interface{}{map[string]interface{}{"index": index, "delta": delta, "finish_reason": nil}})); err != nil {
+			return err
+		}
+		if err := writeSSEEvent(buf, "", chunk([]interface{}{map[string]interface{}{"index": index, "delta": map[string]interface{}{}, "finish_reason": choice["finish_reason"]}})); err != nil {
+			return err
+		}
+	}
+	if usage, ok := resp["usage"]; ok && usage != nil {
+		usageChunk := chunk([]interface{}{})
+		usageChunk["usage"] = usage
+		if err := writeSSEEvent(buf, "", usageChunk); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("data: [DONE]\n\n")
+	return nil
+}
+
+// aggregateResponsesStream returns the final response carried by the
+// terminal event of a Responses stream
+func aggregateResponsesStream(events []sseEvent) (map[string]interface{}, error) {
+	for i := len(events) - 1; i >= 0; i-- {
+		switch events[i].data["type"] {
+		case "response.completed", "response.incomplete":
+			if resp, ok := events[i].data["response"].(map[string]interface{}); ok {
+				return resp, nil
+			}
+		case "response.failed":
+			resp, _ := events[i].data["response"].(map[string]interface{})
+			return nil, fmt.Errorf("upstream response failed: %v", resp["error"])
+		}
+	}
+	return nil, errIncompleteStream(ClientFormatOpenAIResponses)
+}
+
+// synthesizeResponsesStream emits response.created, the added/delta/done
+// events of each output item and a terminal response event
+func synthesizeResponsesStream(buf *bytes.Buffer, resp map[string]interface{}) error {
+	sequence := 0
+	emit := func(eventType string, fields map[string]interface{}) error {
+		fields["type"] = eventType
+		fields["sequence_number"] = sequence
+		sequence++
+		return writeSSEEvent(buf, eventType, fields)
+	}
+
+	created := make(map[string]interface{}, len(resp))
+	for key, value := range resp {
+		created[key] = value
+	}
+	created["status"] = "in_progress"
+	created["output"] = []interface{}{}
+	delete(created, "usage")
+	if err := emit("response.created", map[string]interface{}{"response": created}); err != nil {
+		return err
+	}
+
+	output, _ := resp["output"].([]interface{})
+	for outputIndex, entry := range output {
+		item, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		itemID := item["id"]
+		added := make(map[string]interface{}, len(item))
+		for key, value := range item {
+			added[key] = value
+		}
+		added["status"] = "in_progress"
+		switch item["type"] {
+		case "message":
+			added["content"] = []interface{}{}
+		case "function_call":
+			added["arguments"] = ""
+		}
+		if err := emit("response.output_item.added", map[string]interface{}{"output_index": outputIndex, "item": added}); err != nil {
+			return err
+		}
+
+		switch item["type"] {
+		case "message":
+			parts, _ := item["content"].([]interface{})
+			for contentIndex, partItem := range parts {
+				part, ok := partItem.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				position := map[string]interface{}{"item_id": itemID, "output_index": outputIndex, "content_index": contentIndex}
+				with := func(extra map[string]interface{}) map[string]interface{} {
+					fields := make(map[string]interface{}, len(position)+len(extra))
+					for key, value := range position {
+						fields[key] = value
+					}
+					for key, value := range extra {
+						fields[key] = value
+					}
+					return fields
+				}
+				emptyPart := map[string]interface{}{"type": part["type"]}
+				textKey := "text"
+				if part["type"] == "refusal" {
+					textKey = "refusal"
+				} else {
+					emptyPart["annotations"] = []interface{}{}
+				}
+				emptyPart[textKey] = ""
+				if err := emit("response.content_part.added", with(map[string]interface{}{"part": emptyPart})); err != nil {
+					return err
+				}
+				text := jsonString(part[textKey])
+				deltaType, doneType := "response.output_text.delta", "response.output_text.done"
+				if textKey == "refusal" {
+					deltaType, doneType = "response.refusal.delta", "response.refusal.done"
+				}
+				if err := emit(deltaType, with(map[string]interface{}{"delta": text})); err != nil {
+					return err
+				}
+				if err := emit(doneType, with(map[string]interface{}{textKey: text})); err != nil {
+					return err
+				}
+				if err := emit("response.content_part.done", with(map[string]interface{}{"part": part})); err != nil {
+					return err
+				}
+			}
+		case "function_call":
+			arguments := jsonString(item["arguments"])
+			if err := emit("response.function_call_arguments.delta", map[string]interface{}{"item_id": itemID, "output_index": outputIndex, "delta": arguments}); err != nil {
+				return err
+			}
+			if err := emit("response.function_call_arguments.done", map[string]interface{}{"item_id": itemID, "output_index": outputIndex, "arguments": arguments}); err != nil {
+				return err
+			}
+		}
+
+		if err := emit("response.output_item.done", map[string]interface{}{"output_index": outputIndex, "item": item}); err != nil {
+			return err
+		}
+	}
+
+	terminal := "response.completed"
+	if resp["status"] == "incomplete" {
+		terminal = "response.incomplete"
+	}
+	return emit(terminal, map[string]interface{}{"response": resp})
+}
+
+// aggregateGeminiStream merges Gemini stream chunks into one response.
+// Adjacent text parts of the same kind are joined.
+func aggregateGeminiStream(events []sseEvent) (map[string]interface{}, error) {
+	if len(events) == 0 {
+		return nil, errIncompleteStream(ClientFormatGemini)
+	}
+
+	resp := make(map[string]interface{})
+	candidates := make(map[int]map[string]interface{})
+	parts := make(map[int][]interface{})
+	for _, event := range events {
+		for key, value := range event.data {
+			if key != "candidates" {
+				resp[key] = value
+			}
+		}
+		items, _ := event.data["candidates"].([]interface{})
+		for position, item := range items {
+			chunk, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			index := position
+			if _, ok := chunk["index"]; ok {
+				index = jsonInt(chunk["index"])
+			}
+			candidate := candidates[index]
+			if candidate == nil {
+				candidate = make(map[string]interface{})
+				candidates[index] = candidate
+			}
+			for key, value := range chunk {
+				if key != "content" {
+					candidate[key] = value
+				}
+			}
+			content, _ := chunk["content"].(map[string]interface{})
+			if role := jsonString(content["role"]); role != "" {
+				candidate["role"] = role
+			}
+			chunkParts, _ := content["parts"].([]interface{})
+			for _, partItem := range chunkParts {
+				parts[index] = appendGeminiPart(parts[index], partItem)
+			}
+		}
+	}
+
+	merged := make([]interface{}, 0, len(candidates))
+	for _, index := range sortedKeys(candidates) {
+		candidate := candidates[index]
+		role := jsonString(candidate["role"])
+		if role == "" {
+			role = "model"
+		}
+		delete(candidate, "role")
+		candidate["content"] = map[string]interface{}{"role": role, "parts": parts[index]}
+		merged = append(merged, candidate)
+	}
+	resp["candidates"] = merged
+	return resp, nil
+}
+
+// appendGeminiPart appends a part, joining text onto a preceding text part
+// of the same kind
+func appendGeminiPart(parts []interface{}, item interface{}) []interface{} {
+	part, ok := item.(map[string]interface{})
+	if !ok {
+		return parts
+	}
+	text, isText := part["text"].(string)
+	if isText && len(parts) > 0 {
+		if last, ok := parts[len(parts)-1].(map[string]interface{}); ok {
+			lastText, lastIsText := last["text"].(string)
+			_, lastSigned := last["thoughtSignature"]
+			if lastIsText && !lastSigned && last["thought"] == part["thought"] {
+				joined := make(map[string]interface{}, len(part))
+				for key, value := range part {
+					joined[key] = value
+				}
+				joined["text"] = lastText + text
+				parts[len(parts)-1] = joined
+				return parts
+			}
+		}
+	}
+	return append(parts, part)
+}
+
+func jsonString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func jsonInt(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -52,11 +52,30 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 		reader = gzipReader
 	}
 
+	// Keep the client connection alive while the upstream is silent
+	out := newStreamWriter(w, flusher, p.config.GetStreamIdleTimeout())
+	if interval := p.config.GetStreamKeepAlive(); interval > 0 {
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			out.keepAlive(interval, keepAliveEvent(transformerName), done)
+		}()
+		// The writer must not be used once the handler returns
+		defer func() {
+			close(done)
+			<-stopped
+		}()
+	}
+
 	// Create stream context for all transformers except pure passthrough
 	var streamCtx *transformer.StreamContext
 	switch transformerName {
-	case "cx_chat_openai", "cx_resp_openai2":
-		// Pure passthrough - no context needed
+	case "cx_chat_openai", "cx_chat_ollama", "cx_resp_openai2", "ge_gemini", "ge_vertex_gemini":
+		// Pure passthrough - no context needed, except to carry plugin state
+		if _, wrapped := trans.(transformer.Wrapper); wrapped {
+			streamCtx = transformer.NewStreamContext()
+		}
 	default:
 		// cc_claude needs context for input_tokens fallback
 		streamCtx = transformer.NewStreamContext()
@@ -67,6 +86,14 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 		}
 	}
 
+	// Record which provider produced the tool-call IDs and signatures sent to the client
+	provider := providerForTransformer(endpoint.Transformer, transformerName)
+	provenance := make(map[string]string)
+	if streamCtx != nil {
+		provenance = streamCtx.ToolCallIDMap
+	}
+	defer func() { p.provenance.record(provenance) }()
+
 	scanner := bufio.NewScanner(reader)
 	// Increase buffer sizes to handle large SSE events (e.g., large file reads in tool calls)
 	buf := make([]byte, 0, 128*1024) // 128KB initial buffer (was 64KB)
@@ -78,10 +105,16 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 	eventCount := 0
 	streamDone := false
 
+	// Only a manual switch away from the endpoint ends the stream; requests
+	// routed to another endpoint (specified, or skipped to for context) are
+	// never current
+	startedOnCurrent := p.isCurrentEndpoint(endpoint.Name)
+
 	for scanner.Scan() && !streamDone {
 		line := scanner.Text()
+		out.touch()
 
-		if !p.isCurrentEndpoint(endpoint.Name) {
+		if startedOnCurrent && !p.isCurrentEndpoint(endpoint.Name) {
 			logger.Warn("[%s] Endpoint switched during streaming, terminating stream gracefully", endpoint.Name)
 			streamDone = true
 			break
@@ -100,10 +133,10 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 					streamCtx.OutputTokens = outputTokens
 				}
 
-				// Inject message_delta event with usage
-				deltaEvent := fmt.Sprintf("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\",\"stop_sequence\":null},\"usage\":{\"output_tokens\":%d}}\n\n", outputTokens)
-				if _, writeErr := w.Write([]byte(deltaEvent)); writeErr == nil {
-					flusher.Flush()
+				// Inject message_delta event with usage; Gemini clients get usage in the final chunk
+				if !isGeminiClientTransformer(transformerName) {
+					deltaEvent := fmt.Sprintf("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\",\"stop_sequence\":null},\"usage\":{\"output_tokens\":%d}}\n\n", outputTokens)
+					out.Write([]byte(deltaEvent))
 				}
 			}
 
@@ -114,8 +147,7 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 			transformedEvent, err := p.transformStreamEvent(eventData, trans, transformerName, streamCtx)
 			if err == nil && len(transformedEvent) > 0 {
 				logger.DebugLog("[%s] SSE Event #%d (Transformed): %s", endpoint.Name, eventCount+1, string(transformedEvent))
-				w.Write(transformedEvent)
-				flusher.Flush()
+				out.Write(transformedEvent)
 			}
 			break
 		}
@@ -145,9 +177,9 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 				}
 
 				// Inject message_delta event with usage before message_stop
-				deltaEvent := fmt.Sprintf("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\",\"stop_sequence\":null},\"usage\":{\"output_tokens\":%d}}\n\n", outputTokens)
-				if _, writeErr := w.Write([]byte(deltaEvent)); writeErr == nil {
-					flusher.Flush()
+				if !isGeminiClientTransformer(transformerName) {
+					deltaEvent := fmt.Sprintf("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\",\"stop_sequence\":null},\"usage\":{\"output_tokens\":%d}}\n\n", outputTokens)
+					out.Write([]byte(deltaEvent))
 				}
 			}
 
@@ -159,8 +191,9 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 
 				p.extractTokensFromEvent(transformedEvent, &inputTokens, &outputTokens)
 				p.extractTextFromEvent(transformedEvent, &outputText)
+				collectProvenanceFromEvent(provenance, transformedEvent, provider)
 
-				if _, writeErr := w.Write(transformedEvent); writeErr != nil {
+				if _, writeErr := out.Write(transformedEvent); writeErr != nil {
 					// Client disconnected (broken pipe) is normal for cancelled requests
 					if strings.Contains(writeErr.Error(), "broken pipe") || strings.Contains(writeErr.Error(), "connection reset") {
 						logger.Debug("[%s] Client disconnected: %v", endpoint.Name, writeErr)
@@ -170,7 +203,6 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 					streamDone = true
 					break
 				}
-				flusher.Flush()
 			}
 			buffer.Reset()
 		}
@@ -202,7 +234,8 @@ func (p *Proxy) handleStreamingResponse(w http.ResponseWriter, resp *http.Respon
 }
 
 // handleStreamingAsNonStreaming aggregates SSE and returns a single non-stream response.
-// This is used for Codex endpoints that require stream=true upstream while client requested non-stream.
+// This is used for Responses API upstreams that stream, such as the Codex backend,
+// while the client requested non-stream.
 func (p *Proxy) handleStreamingAsNonStreaming(w http.ResponseWriter, resp *http.Response, endpoint config.Endpoint, trans transformer.Transformer, credentialID int64) (int, int, string, error) {
 	var reader io.Reader = resp.Body
 	if resp.Header.Get("Content-Encoding") == "gzip" {
@@ -269,6 +302,9 @@ func (p *Proxy) handleStreamingAsNonStreaming(w http.ResponseWriter, resp *http.
 	if err != nil {
 		return 0, 0, "", err
 	}
+	provenance := make(map[string]string)
+	collectProvenanceFromResponse(provenance, transformedResp, providerForTransformer(endpoint.Transformer, trans.Name()))
+	p.provenance.record(provenance)
 
 	for key, values := range resp.Header {
 		if key == "Content-Length" || key == "Content-Encoding" || key == "Content-Type" {
@@ -379,6 +415,11 @@ func (p *Proxy) extractTokensFromEvent(eventData []byte, inputTokens, outputToke
 			applyUsage(usage)
 		}
 
+		// Gemini chunk-style usage
+		if usage, ok := event["usageMetadata"].(map[string]interface{}); ok {
+			applyUsage(usage)
+		}
+
 		// Some providers wrap payloads with object=...
 		if obj, ok := event["object"].(string); ok && strings.Contains(obj, "chat.completion") {
 			if usage, ok := event["usage"].(map[string]interface{}); ok {
@@ -443,6 +484,9 @@ func (p *Proxy) extractTextFromEvent(transformedEvent []byte, outputText *string
 				}
 			}
 		}
+
+		// Handle Gemini stream chunk format (candidates[].content.parts[].text)
+		appendGeminiCandidateText(event, outputText)
 	}
 }
 
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/logger"
+)
+
+// tryMintedTokenRetry retries an endpoint whose access token is minted by
+// ccNexus (Vertex AI service accounts, Entra ID applications) once with a
+// fresh token after the upstream rejected the cached one
+func (p *Proxy) tryMintedTokenRetry(reqCtx *proxyRequestContext, attempt *endpointAttempt) bool {
+	if reqCtx.mintedTokenRetried[attempt.endpoint.Name] {
+		return false
+	}
+	switch attempt.authMode {
+	case config.AuthModeServiceAccount:
+		p.invalidateVertexToken(attempt.endpoint)
+	case config.AuthModeEntraID:
+		p.azureTokens.Invalidate(attempt.endpoint.Options.AzureCredentials())
+	default:
+		return false
+	}
+	reqCtx.mintedTokenRetried[attempt.endpoint.Name] = true
+	logger.Info("[%s] Access token rejected, retrying with a new token", attempt.endpoint.Name)
+	return true
+}
+
+// tokenMintHTTPClient returns a client for token endpoints that honours the
+// general proxy setting
+func (p *Proxy) tokenMintHTTPClient(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if p.httpClient != nil {
+		client.Transport = p.httpClient.Transport
+	}
+	if p.config == nil {
+		return client
+	}
+	proxyCfg := p.config.GetProxy()
+	if proxyCfg == nil || strings.TrimSpace(proxyCfg.URL) == "" {
+		return client
+	}
+	transport, err := CreateProxyTransport(proxyCfg.URL)
+	if err != nil {
+		logger.Warn("Failed to create proxy transport for token request: %v", err)
+		return client
+	}
+	client.Transport = transport
+	return client
+}
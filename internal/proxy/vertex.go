@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+	"github.com/lich0821/ccNexus/internal/vertex"
+)
+
+const vertexTokenTimeout = 20 * time.Second
+
+// isVertexTransformer reports whether the transformer targets Google Vertex AI
+func isVertexTransformer(transformerName string) bool {
+	return strings.Contains(transformerName, "_vertex_")
+}
+
+// isVertexGeminiTransformer reports whether the transformer targets a Gemini
+// model on Vertex AI
+func isVertexGeminiTransformer(transformerName string) bool {
+	return strings.HasSuffix(transformerName, "_vertex_gemini")
+}
+
+func vertexOptions(endpoint config.Endpoint) config.VertexOptions {
+	if endpoint.Options == nil || endpoint.Options.Vertex == nil {
+		return config.VertexOptions{}
+	}
+	return *endpoint.Options.Vertex
+}
+
+func loadVertexServiceAccount(endpoint config.Endpoint) (*vertex.ServiceAccount, error) {
+	opts := vertexOptions(endpoint)
+	return vertex.LoadServiceAccount(opts.ServiceAccountKey, opts.ServiceAccountFile)
+}
+
+// vertexProjectID returns the configured project, falling back to the
+// project of the service account key
+func vertexProjectID(endpoint config.Endpoint) string {
+	if project := strings.TrimSpace(vertexOptions(endpoint).ProjectID); project != "" {
+		return project
+	}
+	if sa, err := loadVertexServiceAccount(endpoint); err == nil {
+		return sa.ProjectID
+	}
+	return ""
+}
+
+// vertexTargetPath returns the publisher model method path for the request.
+// Streaming is read from the transformed body's stream field.
+func vertexTargetPath(endpoint config.Endpoint, transformedBody []byte, transformerName string, modelName string) string {
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	json.Unmarshal(transformedBody, &req)
+
+	model := strings.TrimSpace(modelName)
+	if model == "" {
+		model = strings.TrimSpace(req.Model)
+	}
+	if model == "" {
+		model = strings.TrimSpace(endpoint.Model)
+	}
+
+	publisher, method := vertex.PublisherAnthropic, "rawPredict"
+	if req.Stream {
+		method = "streamRawPredict"
+	}
+	if isVertexGeminiTransformer(transformerName) {
+		publisher, method = vertex.PublisherGoogle, "generateContent"
+		if req.Stream {
+			method = "streamGenerateContent"
+		}
+	}
+
+	opts := vertexOptions(endpoint)
+	modelID := vertex.ModelID(model, opts.ModelIDs)
+	return vertex.ModelPath(vertexProjectID(endpoint), endpoint.Options.VertexLocation(), publisher, modelID, method)
+}
+
+// vertexRequestBody adapts the transformed body for Vertex AI: Claude requests
+// get the Vertex anthropic_version, Gemini requests drop the stream marker
+// that only selected the URL method
+func vertexRequestBody(transformedBody []byte, transformerName string) ([]byte, error) {
+	if isVertexGeminiTransformer(transformerName) {
+		return removePayloadField(transformedBody, "stream"), nil
+	}
+	return convert.ClaudeReqToVertex(transformedBody)
+}
+
+// applyVertexAuth replaces the client's headers with a Google access token
+func applyVertexAuth(req *http.Request, accessToken string, transformerName string) {
+	betas := convert.FilterVertexBetas(req.Header.Get("Anthropic-Beta"))
+	for _, key := range []string{"Authorization", "X-Api-Key", "Anthropic-Version", "Anthropic-Beta"} {
+		req.Header.Del(key)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if betas != "" && !isVertexGeminiTransformer(transformerName) {
+		req.Header.Set("Anthropic-Beta", betas)
+	}
+	if isVertexGeminiTransformer(transformerName) && strings.HasSuffix(req.URL.Path, ":streamGenerateContent") {
+		q := req.URL.Query()
+		q.Set("alt", "sse")
+		req.URL.RawQuery = q.Encode()
+	}
+}
+
+// vertexAccessToken returns a cached or newly minted access token for the
+// endpoint's service account
+func (p *Proxy) vertexAccessToken(endpoint config.Endpoint) (string, error) {
+	sa, err := loadVertexServiceAccount(endpoint)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vertexTokenTimeout)
+	defer cancel()
+	return p.vertexTokens.Token(ctx, p.tokenMintHTTPClient(vertexTokenTimeout), sa, vertexTokenURL(endpoint, sa))
+}
+
+// invalidateVertexToken drops the endpoint's cached token so the next attempt
+// mints a new one
+func (p *Proxy) invalidateVertexToken(endpoint config.Endpoint) {
+	sa, err := loadVertexServiceAccount(endpoint)
+	if err != nil {
+		return
+	}
+	p.vertexTokens.Invalidate(sa, vertexTokenURL(endpoint, sa))
+}
+
+func vertexTokenURL(endpoint config.Endpoint, sa *vertex.ServiceAccount) string {
+	if tokenURL := strings.TrimSpace(vertexOptions(endpoint).TokenURL); tokenURL != "" {
+		return tokenURL
+	}
+	if tokenURL := strings.TrimSpace(sa.TokenURI); tokenURL != "" {
+		return tokenURL
+	}
+	return vertex.DefaultTokenURL
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/vertex"
+)
+
+func newVertexServiceAccountKey(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyJSON, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "key-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email": "svc@key-project.iam.gserviceaccount.com",
+	})
+	return string(keyJSON)
+}
+
+func TestVertexClaudeStreamingWithServiceAccount(t *testing.T) {
+	var minted int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("assertion") == "" {
+			t.Errorf("token request without assertion")
+		}
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, atomic.AddInt32(&minted, 1))
+	}))
+	defer tokenServer.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/key-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.RawQuery != "" {
+			t.Errorf("client query must not be forwarded, got %q", r.URL.RawQuery)
+		}
+		if r.Header.Get("Authorization") != "Bearer token-1" || r.Header.Get("X-Api-Key") != "" {
+			t.Errorf("unexpected auth headers: %v", r.Header)
+		}
+		if r.Header.Get("Anthropic-Beta") != "interleaved-thinking-2025-05-14" {
+			t.Errorf("expected only supported betas, got %q", r.Header.Get("Anthropic-Beta"))
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["model"]; ok {
+			t.Errorf("model must move to the URL, got %v", body)
+		}
+		if body["anthropic_version"] != "vertex-2023-10-16" || body["stream"] != true {
+			t.Errorf("unexpected body %v", body)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":7,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+			`{"type":"message_stop"}`,
+		} {
+			var header struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal([]byte(event), &header)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", header.Type, event)
+		}
+	}))
+	defer upstream.Close()
+
+	endpoint := config.Endpoint{
+		Name:        "Vertex",
+		APIUrl:      upstream.URL,
+		AuthMode:    config.AuthModeServiceAccount,
+		Enabled:     true,
+		Transformer: config.VertexTransformer,
+		Options: &config.EndpointOptions{Vertex: &config.VertexOptions{
+			Location:          "us-east5",
+			ServiceAccountKey: newVertexServiceAccountKey(t),
+			TokenURL:          tokenServer.URL,
+		}},
+	}
+	cfg := config.DefaultConfig()
+	cfg.UpdateEndpoints([]config.Endpoint{endpoint})
+	p := &Proxy{config: cfg, vertexTokens: vertex.NewTokenCache()}
+
+	token, err := p.vertexAccessToken(endpoint)
+	if err != nil || token != "token-1" {
+		t.Fatalf("expected token-1, got %q (%v)", token, err)
+	}
+	trans, err := prepareTransformerForClient(ClientFormatClaude, endpoint, "claude-sonnet-4-20250514")
+	if err != nil {
+		t.Fatalf("prepareTransformerForClient failed: %v", err)
+	}
+	clientBody := []byte(`{"model":"claude-sonnet-4-20250514","stream":true,"max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`)
+	transformed, err := trans.TransformRequest(clientBody)
+	if err != nil {
+		t.Fatalf("TransformRequest failed: %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/v1/messages?beta=true", nil)
+	r.Header.Set("x-api-key", "client-key")
+	r.Header.Set("anthropic-version", "2023-06-01")
+	r.Header.Set("anthropic-beta", "claude-code-20250219,interleaved-thinking-2025-05-14")
+	proxyReq, err := buildProxyRequest(r, endpoint, token, transformed, trans.Name(), "claude-sonnet-4-20250514", nil)
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(proxyReq)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	in, out, text := p.handleStreamingResponse(rec, resp, endpoint, trans, trans.Name(), false, "claude-sonnet-4-20250514", clientBody, 0)
+	if in != 7 || out != 2 || text != "Hi" {
+		t.Fatalf("expected usage 7/2 and text Hi, got %d/%d %q", in, out, text)
+	}
+
+	// A rejected token is replaced once per request
+	reqCtx := &proxyRequestContext{mintedTokenRetried: make(map[string]bool)}
+	attempt := &endpointAttempt{endpoint: endpoint, authMode: config.AuthModeServiceAccount}
+	if !p.tryMintedTokenRetry(reqCtx, attempt) {
+		t.Fatalf("expected a retry after the first 401")
+	}
+	if p.tryMintedTokenRetry(reqCtx, attempt) {
+		t.Fatalf("expected no second retry")
+	}
+	if token, _ := p.vertexAccessToken(endpoint); token != "token-2" {
+		t.Fatalf("expected a new token after invalidation, got %q", token)
+	}
+}
+
+func TestVertexGeminiRequestFromClaudeClient(t *testing.T) {
+	endpoint := config.Endpoint{
+		Name:        "Vertex",
+		APIUrl:      "https://europe-west1-aiplatform.googleapis.com",
+		APIKey:      "ya29.access-token",
+		AuthMode:    config.AuthModeAPIKey,
+		Enabled:     true,
+		Transformer: config.VertexTransformer,
+		Model:       "gemini-2.5-pro",
+		Options: &config.EndpointOptions{Vertex: &config.VertexOptions{
+			ProjectID: "proj",
+			Location:  "europe-west1",
+		}},
+	}
+	p := &Proxy{config: config.DefaultConfig(), provenance: newProvenanceStore(provenanceStoreLimit)}
+
+	body := []byte(`{"model":"claude-sonnet-4-20250514","stream":true,"max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`)
+	r := httptest.NewRequest("POST", "/v1/messages?beta=true", nil)
+	r.Header.Set("x-api-key", "client-key")
+	reqCtx := &proxyRequestContext{
+		httpRequest:     r,
+		bodyBytes:       body,
+		clientFormat:    ClientFormatClaude,
+		streamRequested: true,
+		requestModel:    "claude-sonnet-4-20250514",
+	}
+	attempt := &endpointAttempt{endpoint: endpoint}
+	if result := p.prepareEndpointAttempt(reqCtx, attempt); result != attemptResultDone {
+		t.Fatalf("prepareEndpointAttempt failed: %v", result)
+	}
+	if attempt.transformerName != "cc_vertex_gemini" {
+		t.Fatalf("expected cc_vertex_gemini, got %s", attempt.transformerName)
+	}
+
+	proxyReq := attempt.proxyRequest
+	if proxyReq.URL.Path != "/v1/projects/proj/locations/europe-west1/publishers/google/models/gemini-2.5-pro:streamGenerateContent" {
+		t.Fatalf("unexpected path %s", proxyReq.URL.Path)
+	}
+	if proxyReq.URL.RawQuery != "alt=sse" {
+		t.Fatalf("expected alt=sse only, got %q", proxyReq.URL.RawQuery)
+	}
+	if proxyReq.Header.Get("Authorization") != "Bearer ya29.access-token" || proxyReq.Header.Get("X-Api-Key") != "" {
+		t.Fatalf("unexpected auth headers: %v", proxyReq.Header)
+	}
+	sent, _ := io.ReadAll(proxyReq.Body)
+	if strings.Contains(string(sent), `"stream"`) || !strings.Contains(string(sent), `"contents"`) {
+		t.Fatalf("expected a Gemini body without stream, got %s", sent)
+	}
+	if provider := providerForTransformer(endpoint.Transformer, attempt.transformerName); provider != providerGemini {
+		t.Fatalf("expected gemini provider, got %s", provider)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,10 +15,12 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/lich0821/ccNexus/internal/azure"
 	"github.com/lich0821/ccNexus/internal/config"
 	"github.com/lich0821/ccNexus/internal/logger"
 	"github.com/lich0821/ccNexus/internal/proxy"
 	"github.com/lich0821/ccNexus/internal/storage"
+	transformerpkg "github.com/lich0821/ccNexus/internal/transformer"
 	"github.com/lich0821/ccNexus/internal/transformer/convert"
 )
 
@@ -122,6 +125,16 @@ func (e *EndpointService) resolveEndpointAuth(endpoint config.Endpoint) (string,
 		}
 		return strings.TrimSpace(cred.AccessToken), cred, nil
 	}
+	if authMode == config.AuthModeEntraID {
+		creds := endpoint.Options.AzureCredentials()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		token, err := azure.MintToken(ctx, e.createHTTPClient(20*time.Second, creds.TokenEndpoint()), creds, time.Now())
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get Entra ID access token: %w", err)
+		}
+		return token.AccessToken, nil, nil
+	}
 
 	apiKey := strings.TrimSpace(endpoint.APIKey)
 	if apiKey == "" {
@@ -265,6 +278,7 @@ func (e *EndpointService) UpdateEndpoint(index int, name, apiUrl, apiKey, authMo
 		Transformer: transformer,
 		Model:       model,
 		Remark:      remark,
+		Options:     endpoints[index].Options,
 	}
 	config.ApplyEndpointAuthModeRules(&updatedEndpoint)
 	endpoints[index] = updatedEndpoint
@@ -303,6 +317,49 @@ func (e *EndpointService) UpdateEndpoint(index int, name, apiUrl, apiKey, authMo
 	return nil
 }
 
+// GetEndpointOptions returns the per-endpoint options as JSON
+func (e *EndpointService) GetEndpointOptions(index int) string {
+	endpoints := e.config.GetEndpoints()
+	if index < 0 || index >= len(endpoints) {
+		return "{}"
+	}
+	encoded := config.EncodeEndpointOptions(endpoints[index].Options)
+	if encoded == "" {
+		return "{}"
+	}
+	return encoded
+}
+
+// UpdateEndpointOptions replaces the per-endpoint options of an endpoint
+func (e *EndpointService) UpdateEndpointOptions(index int, optionsJSON string) error {
+	endpoints := e.config.GetEndpoints()
+
+	if index < 0 || index >= len(endpoints) {
+		return fmt.Errorf("invalid endpoint index: %d", index)
+	}
+
+	options, err := config.ParseEndpointOptions(optionsJSON)
+	if err != nil {
+		return err
+	}
+	endpoints[index].Options = options
+	e.config.UpdateEndpoints(endpoints)
+
+	if err := e.proxy.UpdateConfig(e.config); err != nil {
+		return err
+	}
+
+	if e.storage != nil {
+		configAdapter := storage.NewConfigStorageAdapter(e.storage)
+		if err := e.config.SaveToStorage(configAdapter); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+
+	logger.Info("Endpoint options updated: %s", endpoints[index].Name)
+	return nil
+}
+
 // ToggleEndpoint toggles the enabled state of an endpoint
 func (e *EndpointService) ToggleEndpoint(index int, enabled bool) error {
 	endpoints := e.config.GetEndpoints()
@@ -523,6 +580,23 @@ func (e *EndpointService) TestEndpoint(index int) string {
 	if transformer == "openai2" && isCodexBackendAPIURL(normalizedAPIUrl) {
 		requestBody = ensureCodexResponsesProbePayload(requestBody)
 	}
+	isAzure := endpoint.IsAzureOpenAI()
+	if isAzure {
+		// Azure serves deployments: chat goes to the deployment path, Responses names it in the body
+		var probe map[string]interface{}
+		json.Unmarshal(requestBody, &probe)
+		model, _ := probe["model"].(string)
+		deployment := endpoint.Options.AzureDeployment(model)
+		probe["model"] = deployment
+		requestBody, _ = json.Marshal(probe)
+		normalizedAPIUrl = azure.BaseURL(normalizedAPIUrl)
+		if transformer == "openai2" {
+			apiPath = azure.ResponsesPath
+		} else {
+			apiPath = azure.ChatCompletionsPath(deployment)
+		}
+		apiPath += "?" + url.Values{"api-version": {endpoint.Options.AzureAPIVersion()}}.Encode()
+	}
 	url := fmt.Sprintf("%s%s", normalizedAPIUrl, normalizeEndpointPathForBaseURL(normalizedAPIUrl, apiPath))
 
 	req, err := http.NewRequest("POST", url, bytes.NewReader(requestBody))
@@ -541,7 +615,11 @@ func (e *EndpointService) TestEndpoint(index int) string {
 		req.Header.Set("x-api-key", apiKey)
 		req.Header.Set("anthropic-version", "2023-06-01")
 	case "openai", "openai2":
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+		if isAzure && config.NormalizeAuthMode(endpoint.AuthMode) != config.AuthModeEntraID {
+			req.Header.Set("api-key", apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
 	case "gemini":
 		q := req.URL.Query()
 		q.Add("key", apiKey)
@@ -1035,7 +1113,7 @@ func (e *EndpointService) testMinimalRequest(apiUrl, apiKey, transformer, model
 				"messages":   []map[string]interface{}{{"role": "user", "content": "ping"}},
 				"max_tokens": 1,
 			})
-			converted, convErr := convert.OpenAIReqToOpenAI2(probeOpenAIReq, model)
+			converted, convErr := convert.OpenAIReqToOpenAI2(probeOpenAIReq, model, transformerpkg.Options{})
 			if convErr != nil {
 				return 0, fmt.Errorf("failed to build codex probe payload: %w", convErr)
 			}
@@ -1335,7 +1413,8 @@ func (e *EndpointService) FetchModels(apiUrl, apiKey, transformer string) string
 	}
 	var resolvedCredential *storage.EndpointCredential
 	resolvedAPIKey := strings.TrimSpace(apiKey)
-	if resolvedAPIKey == "" {
+	// Ollama servers do not require a key
+	if resolvedAPIKey == "" && transformer != config.OllamaTransformer {
 		var resolveErr error
 		resolvedAPIKey, resolvedCredential, resolveErr = e.resolveTokenPoolAuthForAPI(normalizedAPIUrl, transformer)
 		if resolveErr != nil {
@@ -1356,6 +1435,10 @@ func (e *EndpointService) FetchModels(apiUrl, apiKey, transformer string) string
 	case "claude":
 		models, err = e.fetchOpenAIModels(normalizedAPIUrl, resolvedAPIKey, transformer, resolvedCredential)
 	case "openai", "openai2":
+		if azure.IsAzureOpenAIURL(normalizedAPIUrl) {
+			models, err = e.fetchAzureDeployments(normalizedAPIUrl, resolvedAPIKey)
+			break
+		}
 		if transformer == "openai2" && isCodexBackendAPIURL(normalizedAPIUrl) {
 			models, err = e.fetchCodexModels(normalizedAPIUrl, resolvedAPIKey, resolvedCredential)
 			break
@@ -1363,6 +1446,8 @@ func (e *EndpointService) FetchModels(apiUrl, apiKey, transformer string) string
 		models, err = e.fetchOpenAIModels(normalizedAPIUrl, resolvedAPIKey, transformer, resolvedCredential)
 	case "gemini":
 		models, err = e.fetchGeminiModels(normalizedAPIUrl, resolvedAPIKey)
+	case config.OllamaTransformer:
+		models, err = e.fetchOllamaModels(normalizedAPIUrl, resolvedAPIKey)
 	default:
 		result := map[string]interface{}{
 			"success": false,
@@ -1459,6 +1544,63 @@ func (e *EndpointService) fetchOpenAIModels(apiUrl, apiKey, transformer string,
 	return models, nil
 }
 
+// azureDeploymentsAPIVersion is the last api-version that serves the
+// deployments listing on the data plane
+const azureDeploymentsAPIVersion = "2022-12-01"
+
+// fetchAzureDeployments lists the deployments of an Azure OpenAI resource.
+// Requests are routed by deployment, so deployment names are offered as models.
+func (e *EndpointService) fetchAzureDeployments(apiUrl, apiKey string) ([]string, error) {
+	url := azure.BaseURL(apiUrl) + azure.DeploymentsPath + "?api-version=" + azureDeploymentsAPIVersion
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	// Entra ID access tokens are JWTs; anything else is a resource key
+	if strings.HasPrefix(apiKey, "eyJ") {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	} else {
+		req.Header.Set("api-key", apiKey)
+	}
+	req.Header.Set("Accept", "application/json")
+	logger.Debug("Fetching Azure deployments from: %s", url)
+
+	client := e.createHTTPClient(30*time.Second, req.URL.String())
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		errMsg := string(body)
+		if len(errMsg) > 200 {
+			errMsg = errMsg[:200] + "..."
+		}
+		logger.Error("Azure deployments API failed for %s: HTTP %d - %s", url, resp.StatusCode, errMsg)
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, errMsg)
+	}
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	models := make([]string, 0, len(result.Data))
+	for _, d := range result.Data {
+		if id := strings.TrimSpace(d.ID); id != "" {
+			models = append(models, id)
+		}
+	}
+	return models, nil
+}
+
 func (e *EndpointService) fetchCodexModels(apiURL, apiKey string, credential *storage.EndpointCredential) ([]string, error) {
 	// Keep signature for compatibility with existing callers.
 	_ = apiURL
@@ -1491,6 +1633,56 @@ func codexRegistryModels() []string {
 	}
 }
 
+func (e *EndpointService) fetchOllamaModels(apiUrl, apiKey string) ([]string, error) {
+	url := fmt.Sprintf("%s/api/tags", strings.TrimSuffix(apiUrl, "/"))
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		logger.Error("Failed to create request for %s: %v", url, err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	logger.Debug("Fetching Ollama models from: %s", url)
+
+	client := e.createHTTPClient(30*time.Second, req.URL.String())
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Error("Request failed for %s: %v", url, err)
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		errMsg := string(body)
+		if len(errMsg) > 200 {
+			errMsg = errMsg[:200] + "..."
+		}
+		logger.Error("Ollama tags API failed for %s: HTTP %d - %s", url, resp.StatusCode, errMsg)
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, errMsg)
+	}
+
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		logger.Error("Failed to parse Ollama tags response from %s: %v", url, err)
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		models = append(models, m.Name)
+	}
+	logger.Debug("Successfully fetched %d Ollama models from %s", len(models), url)
+	return models, nil
+}
+
 func (e *EndpointService) fetchGeminiModels(apiUrl, apiKey string) ([]string, error) {
 	url := fmt.Sprintf("%s/v1beta/models?key=%s", apiUrl, apiKey)
 
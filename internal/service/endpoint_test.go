@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/config"
+	"github.com/lich0821/ccNexus/internal/mockupstream"
+)
+
+type endpointTestResult struct {
+	Success    bool     `json:"success"`
+	Status     string   `json:"status"`
+	StatusCode int      `json:"statusCode"`
+	Message    string   `json:"message"`
+	Models     []string `json:"models"`
+}
+
+func newMockEndpointService(t *testing.T, script mockupstream.Script, transformer string) (*EndpointService, *mockupstream.Server, string) {
+	t.Helper()
+	mock := mockupstream.New(script)
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.UpdateEndpoints([]config.Endpoint{
+		{Name: "Mock", APIUrl: srv.URL, APIKey: "test-key", Enabled: true, Transformer: transformer, Model: "mock-model"},
+	})
+	return NewEndpointService(cfg, nil, nil), mock, srv.URL
+}
+
+func decodeEndpointTestResult(t *testing.T, data string) endpointTestResult {
+	t.Helper()
+	var result endpointTestResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		t.Fatalf("invalid result %q: %v", data, err)
+	}
+	return result
+}
+
+func TestTestEndpointAgainstMockUpstream(t *testing.T) {
+	for _, transformer := range []string{"claude", "openai", "openai2", "gemini"} {
+		t.Run(transformer, func(t *testing.T) {
+			e, mock, _ := newMockEndpointService(t, mockupstream.Script{APIKey: "test-key"}, transformer)
+
+			result := decodeEndpointTestResult(t, e.TestEndpoint(0))
+			if !result.Success || !strings.Contains(result.Message, mockupstream.DefaultText) {
+				t.Fatalf("expected the mock reply, got %+v", result)
+			}
+			if requests := mock.Requests(); len(requests) != 1 || requests[0].API != transformerAPI(transformer) {
+				t.Fatalf("expected one %s request, got %+v", transformer, requests)
+			}
+		})
+	}
+}
+
+func transformerAPI(transformer string) string {
+	switch transformer {
+	case "openai":
+		return mockupstream.APIOpenAI
+	case "openai2":
+		return mockupstream.APIResponses
+	case "gemini":
+		return mockupstream.APIGemini
+	}
+	return mockupstream.APIClaude
+}
+
+func TestTestEndpointReportsUpstreamErrors(t *testing.T) {
+	e, _, _ := newMockEndpointService(t, mockupstream.Script{APIKey: "another-key"}, "openai")
+	if result := decodeEndpointTestResult(t, e.TestEndpoint(0)); result.Success || result.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 result, got %+v", result)
+	}
+
+	e, _, _ = newMockEndpointService(t, mockupstream.Script{
+		Default: mockupstream.Behavior{Status: http.StatusTooManyRequests, RetryAfter: 5, Message: "slow down"},
+	}, "claude")
+	result := decodeEndpointTestResult(t, e.TestEndpoint(0))
+	if result.Success || result.StatusCode != http.StatusTooManyRequests || !strings.Contains(result.Message, "slow down") {
+		t.Fatalf("expected a 429 result, got %+v", result)
+	}
+}
+
+func TestTestEndpointLightAgainstMockUpstream(t *testing.T) {
+	e, _, _ := newMockEndpointService(t, mockupstream.Script{APIKey: "test-key"}, "claude")
+	if result := decodeEndpointTestResult(t, e.TestEndpointLight(0)); !result.Success || result.Status != "ok" {
+		t.Fatalf("expected a passing light test, got %+v", result)
+	}
+
+	e, _, _ = newMockEndpointService(t, mockupstream.Script{APIKey: "another-key"}, "openai")
+	if result := decodeEndpointTestResult(t, e.TestEndpointLight(0)); result.Success || result.Status != "invalid_key" {
+		t.Fatalf("expected an invalid key, got %+v", result)
+	}
+}
+
+func TestFetchModelsAgainstMockUpstream(t *testing.T) {
+	script := mockupstream.Script{APIKey: "test-key", Models: []string{"alpha", "beta"}}
+	for _, transformer := range []string{"openai", "gemini"} {
+		t.Run(transformer, func(t *testing.T) {
+			e, _, url := newMockEndpointService(t, script, transformer)
+			result := decodeEndpointTestResult(t, e.FetchModels(url, "test-key", transformer))
+			if !result.Success || strings.Join(result.Models, ",") != "alpha,beta" {
+				t.Fatalf("expected the scripted models, got %+v", result)
+			}
+		})
+	}
+}
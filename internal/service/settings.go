@@ -339,14 +339,15 @@ func (s *SettingsService) SetCodexProxyURL(proxyURL string) error {
 
 // SettingsData represents the settings data for batch save
 type SettingsData struct {
-	CloseWindowBehavior       string `json:"closeWindowBehavior"`
-	ProxyURL                  string `json:"proxyUrl"`
-	Theme                     string `json:"theme"`
-	ThemeAuto                 bool   `json:"themeAuto"`
-	AutoLightTheme            string `json:"autoLightTheme"`
-	AutoDarkTheme             string `json:"autoDarkTheme"`
-	ClaudeNotificationEnabled bool   `json:"claudeNotificationEnabled"`
-	ClaudeNotificationType    string `json:"claudeNotificationType"`
+	CloseWindowBehavior       string               `json:"closeWindowBehavior"`
+	ProxyURL                  string               `json:"proxyUrl"`
+	Listen                    *config.ListenConfig `json:"listen,omitempty"` // Applies on restart; nil leaves it unchanged
+	Theme                     string               `json:"theme"`
+	ThemeAuto                 bool                 `json:"themeAuto"`
+	AutoLightTheme            string               `json:"autoLightTheme"`
+	AutoDarkTheme             string               `json:"autoDarkTheme"`
+	ClaudeNotificationEnabled bool                 `json:"claudeNotificationEnabled"`
+	ClaudeNotificationType    string               `json:"claudeNotificationType"`
 }
 
 // SaveSettings saves all settings in a single operation to avoid database lock issues
@@ -363,6 +364,11 @@ func (s *SettingsService) SaveSettings(settingsJSON string) error {
 		settings.CloseWindowBehavior != "ask" {
 		return fmt.Errorf("invalid close window behavior: %s", settings.CloseWindowBehavior)
 	}
+	if settings.Listen != nil {
+		if err := settings.Listen.Validate(); err != nil {
+			return fmt.Errorf("invalid listen settings: %w", err)
+		}
+	}
 
 	// Update all settings in memory
 	if settings.CloseWindowBehavior != "" {
@@ -390,6 +396,10 @@ func (s *SettingsService) SaveSettings(settingsJSON string) error {
 	}
 	s.config.UpdateProxy(proxyCfg)
 
+	if settings.Listen != nil {
+		s.config.UpdateListen(*settings.Listen)
+	}
+
 	// Update Claude notification config
 	// Validate notification type
 	if settings.ClaudeNotificationType != "" &&
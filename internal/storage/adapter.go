@@ -31,6 +31,7 @@ func (a *ConfigStorageAdapter) GetEndpoints() ([]config.StorageEndpoint, error)
 			Model:       ep.Model,
 			Remark:      ep.Remark,
 			SortOrder:   ep.SortOrder,
+			Options:     string(ep.Options),
 		}
 	}
 	return result, nil
@@ -48,6 +49,7 @@ func (a *ConfigStorageAdapter) SaveEndpoint(ep *config.StorageEndpoint) error {
 		Model:       ep.Model,
 		Remark:      ep.Remark,
 		SortOrder:   ep.SortOrder,
+		Options:     optionsFromString(ep.Options),
 	}
 	return a.storage.SaveEndpoint(endpoint)
 }
@@ -64,6 +66,7 @@ func (a *ConfigStorageAdapter) UpdateEndpoint(ep *config.StorageEndpoint) error
 		Model:       ep.Model,
 		Remark:      ep.Remark,
 		SortOrder:   ep.SortOrder,
+		Options:     optionsFromString(ep.Options),
 	}
 	return a.storage.UpdateEndpoint(endpoint)
 }
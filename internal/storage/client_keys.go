@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	clientKeySecretPrefix = "ccn-"
+	clientKeyPrefixLength = 12 // Characters of the secret kept to tell keys apart
+)
+
+const clientKeyColumns = `
+	id, name, prefix, allowed_endpoints, allowed_groups, allowed_models, requests_per_minute, daily_token_quota,
+	expires_at, revoked_at, last_used_at, remark, created_at, updated_at`
+
+// hashSecret returns the stored form of a client key secret or session token
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newClientKeySecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return clientKeySecretPrefix + hex.EncodeToString(buf), nil
+}
+
+func namesToColumn(names []string) sql.NullString {
+	if len(names) == 0 {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(names)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+func namesFromColumn(value sql.NullString) []string {
+	names := []string{}
+	if value.Valid {
+		_ = json.Unmarshal([]byte(value.String), &names)
+	}
+	return names
+}
+
+func scanClientKey(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*ClientKey, error) {
+	var key ClientKey
+	var allowedEndpoints, allowedGroups, allowedModels, remark sql.NullString
+	var expiresAt, revokedAt, lastUsedAt sql.NullTime
+
+	if err := scanner.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&allowedEndpoints,
+		&allowedGroups,
+		&allowedModels,
+		&key.RequestsPerMinute,
+		&key.DailyTokenQuota,
+		&expiresAt,
+		&revokedAt,
+		&lastUsedAt,
+		&remark,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	key.AllowedEndpoints = namesFromColumn(allowedEndpoints)
+	key.AllowedGroups = namesFromColumn(allowedGroups)
+	key.AllowedModels = namesFromColumn(allowedModels)
+	key.ExpiresAt = fromNullTime(expiresAt)
+	key.RevokedAt = fromNullTime(revokedAt)
+	key.LastUsedAt = fromNullTime(lastUsedAt)
+	key.Remark = remark.String
+	return &key, nil
+}
+
+// CreateClientKey stores a new client key and returns its secret
+func (s *SQLiteStorage) CreateClientKey(key *ClientKey) (string, error) {
+	secret, err := newClientKeySecret()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key.Prefix = secret[:clientKeyPrefixLength]
+	result, err := s.db.Exec(`
+		INSERT INTO client_keys (
+			name, key_hash, prefix, allowed_endpoints, allowed_groups, allowed_models,
+			requests_per_minute, daily_token_quota, expires_at, remark
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		key.Name,
+		hashSecret(secret),
+		key.Prefix,
+		namesToColumn(key.AllowedEndpoints),
+		namesToColumn(key.AllowedGroups),
+		namesToColumn(key.AllowedModels),
+		key.RequestsPerMinute,
+		key.DailyTokenQuota,
+		toNullTime(key.ExpiresAt),
+		toNullString(key.Remark),
+	)
+	if err != nil {
+		return "", err
+	}
+	if key.ID, err = result.LastInsertId(); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// UpdateClientKey saves the name, restrictions and remark of a client key
+func (s *SQLiteStorage) UpdateClientKey(key *ClientKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`
+		UPDATE client_keys SET
+			name=?, allowed_endpoints=?, allowed_groups=?, allowed_models=?, requests_per_minute=?, daily_token_quota=?,
+			expires_at=?, remark=?, updated_at=CURRENT_TIMESTAMP
+		WHERE id=?
+	`,
+		key.Name,
+		namesToColumn(key.AllowedEndpoints),
+		namesToColumn(key.AllowedGroups),
+		namesToColumn(key.AllowedModels),
+		key.RequestsPerMinute,
+		key.DailyTokenQuota,
+		toNullTime(key.ExpiresAt),
+		toNullString(key.Remark),
+		key.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// RotateClientKey replaces the secret of a client key and returns the new one.
+// The old secret stops working immediately.
+func (s *SQLiteStorage) RotateClientKey(id int64) (string, error) {
+	secret, err := newClientKeySecret()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`
+		UPDATE client_keys SET key_hash=?, prefix=?, updated_at=CURRENT_TIMESTAMP
+		WHERE id=? AND revoked_at IS NULL
+	`, hashSecret(secret), secret[:clientKeyPrefixLength], id)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAffected(result); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// RevokeClientKey disables a client key for good. Revoked keys are kept so
+// their usage stays attributed.
+func (s *SQLiteStorage) RevokeClientKey(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`
+		UPDATE client_keys SET revoked_at=?, updated_at=CURRENT_TIMESTAMP
+		WHERE id=? AND revoked_at IS NULL
+	`, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetClientKeys returns all client keys, revoked ones included
+func (s *SQLiteStorage) GetClientKeys() ([]ClientKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT ` + clientKeyColumns + ` FROM client_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]ClientKey, 0)
+	for rows.Next() {
+		key, err := scanClientKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// GetClientKeyByID returns a client key, or nil if there is none with that ID
+func (s *SQLiteStorage) GetClientKeyByID(id int64) (*ClientKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, err := scanClientKey(s.db.QueryRow(`SELECT `+clientKeyColumns+` FROM client_keys WHERE id=?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetClientKeyBySecret returns the client key with the given secret, or nil
+// if the secret is unknown
+func (s *SQLiteStorage) GetClientKeyBySecret(secret string) (*ClientKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, err := scanClientKey(s.db.QueryRow(`SELECT `+clientKeyColumns+` FROM client_keys WHERE key_hash=?`, hashSecret(secret)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// HasClientKeys reports whether any client key was ever issued. Revoked keys
+// count, so revoking the last key does not open the proxy routes again.
+func (s *SQLiteStorage) HasClientKeys() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM client_keys)`).Scan(&exists)
+	return exists, err
+}
+
+// RecordClientKeyUsage adds to what a client key used of an endpoint on a day
+func (s *SQLiteStorage) RecordClientKeyUsage(keyID int64, endpointName, date string, requests, errors, inputTokens, outputTokens int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO client_key_usage (key_id, endpoint_name, date, requests, errors, input_tokens, output_tokens)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(key_id, endpoint_name, date) DO UPDATE SET
+			requests=requests + excluded.requests,
+			errors=errors + excluded.errors,
+			input_tokens=input_tokens + excluded.input_tokens,
+			output_tokens=output_tokens + excluded.output_tokens
+	`, keyID, endpointName, date, requests, errors, inputTokens, outputTokens); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE client_keys SET last_used_at=? WHERE id=?`, time.Now().UTC(), keyID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetClientKeyTokens returns the tokens a client key used on a day
+func (s *SQLiteStorage) GetClientKeyTokens(keyID int64, date string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tokens int64
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(input_tokens + output_tokens), 0)
+		FROM client_key_usage
+		WHERE key_id=? AND date=?
+	`, keyID, date).Scan(&tokens)
+	return tokens, err
+}
+
+// GetClientKeyUsage returns client key usage per key and endpoint between two
+// dates, both inclusive
+func (s *SQLiteStorage) GetClientKeyUsage(startDate, endDate string) ([]ClientKeyUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT key_id, endpoint_name, SUM(requests), SUM(errors), SUM(input_tokens), SUM(output_tokens)
+		FROM client_key_usage
+		WHERE date >= ? AND date <= ?
+		GROUP BY key_id, endpoint_name
+		ORDER BY key_id, endpoint_name
+	`, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make([]ClientKeyUsage, 0)
+	for rows.Next() {
+		var u ClientKeyUsage
+		if err := rows.Scan(&u.KeyID, &u.EndpointName, &u.Requests, &u.Errors, &u.InputTokens, &u.OutputTokens); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
@@ -90,7 +90,7 @@ func classifyCredentialForStats(stats *TokenPoolStats, cred *EndpointCredential,
 	}
 }
 
-func scanCredential(scanner interface {
+func scanCredential(box *secretBox, scanner interface {
 	Scan(dest ...interface{}) error
 }) (*EndpointCredential, error) {
 	var cred EndpointCredential
@@ -122,11 +122,22 @@ func scanCredential(scanner interface {
 		return nil, err
 	}
 
+	for _, token := range []struct {
+		dst *string
+		src string
+	}{
+		{&cred.AccessToken, accessToken.String},
+		{&cred.RefreshToken, refreshToken.String},
+		{&cred.IDToken, idToken.String},
+	} {
+		value, err := box.open(token.src)
+		if err != nil {
+			return nil, fmt.Errorf("credential %d: %w", cred.ID, err)
+		}
+		*token.dst = value
+	}
 	cred.AccountID = accountID.String
 	cred.Email = email.String
-	cred.AccessToken = accessToken.String
-	cred.RefreshToken = refreshToken.String
-	cred.IDToken = idToken.String
 	cred.LastRefresh = fromNullTime(lastRefresh)
 	cred.ExpiresAt = fromNullTime(expiresAt)
 	cred.Status = status.String
@@ -139,6 +150,18 @@ func scanCredential(scanner interface {
 	return &cred, nil
 }
 
+// sealCredentialTokens returns the tokens of a credential as stored
+func (s *SQLiteStorage) sealCredentialTokens(cred *EndpointCredential) (accessToken, refreshToken, idToken string, err error) {
+	if accessToken, err = s.secrets.seal(cred.AccessToken); err != nil {
+		return
+	}
+	if refreshToken, err = s.secrets.seal(cred.RefreshToken); err != nil {
+		return
+	}
+	idToken, err = s.secrets.seal(cred.IDToken)
+	return
+}
+
 func (s *SQLiteStorage) GetEndpointCredentials(endpointName string) ([]EndpointCredential, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -160,7 +183,7 @@ func (s *SQLiteStorage) GetEndpointCredentials(endpointName string) ([]EndpointC
 	credentials := make([]EndpointCredential, 0)
 	now := time.Now().UTC()
 	for rows.Next() {
-		cred, err := scanCredential(rows)
+		cred, err := scanCredential(s.secrets, rows)
 		if err != nil {
 			return nil, err
 		}
@@ -184,7 +207,7 @@ func (s *SQLiteStorage) GetCredentialByID(id int64) (*EndpointCredential, error)
 		WHERE id=?
 	`, id)
 
-	cred, err := scanCredential(row)
+	cred, err := scanCredential(s.secrets, row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -206,6 +229,10 @@ func (s *SQLiteStorage) SaveEndpointCredential(cred *EndpointCredential) error {
 	if cred.Status == "" {
 		cred.Status = credentialStatusActive
 	}
+	accessToken, refreshToken, idToken, err := s.sealCredentialTokens(cred)
+	if err != nil {
+		return err
+	}
 
 	result, err := s.db.Exec(`
 		INSERT INTO endpoint_credentials (
@@ -218,9 +245,9 @@ func (s *SQLiteStorage) SaveEndpointCredential(cred *EndpointCredential) error {
 		cred.ProviderType,
 		toNullString(cred.AccountID),
 		toNullString(cred.Email),
-		cred.AccessToken,
-		toNullString(cred.RefreshToken),
-		toNullString(cred.IDToken),
+		accessToken,
+		toNullString(refreshToken),
+		toNullString(idToken),
 		toNullTime(cred.LastRefresh),
 		toNullTime(cred.ExpiresAt),
 		cred.Status,
@@ -257,6 +284,10 @@ func (s *SQLiteStorage) UpdateEndpointCredential(cred *EndpointCredential) error
 	if cred.Status == "" {
 		cred.Status = credentialStatusActive
 	}
+	accessToken, refreshToken, idToken, err := s.sealCredentialTokens(cred)
+	if err != nil {
+		return err
+	}
 
 	result, err := s.db.Exec(`
 		UPDATE endpoint_credentials SET
@@ -282,9 +313,9 @@ func (s *SQLiteStorage) UpdateEndpointCredential(cred *EndpointCredential) error
 		cred.ProviderType,
 		toNullString(cred.AccountID),
 		toNullString(cred.Email),
-		cred.AccessToken,
-		toNullString(cred.RefreshToken),
-		toNullString(cred.IDToken),
+		accessToken,
+		toNullString(refreshToken),
+		toNullString(idToken),
 		toNullTime(cred.LastRefresh),
 		toNullTime(cred.ExpiresAt),
 		cred.Status,
@@ -381,7 +412,7 @@ func (s *SQLiteStorage) GetAllTokenPoolStats() (map[string]TokenPoolStats, error
 	now := time.Now().UTC()
 	stats := make(map[string]TokenPoolStats)
 	for rows.Next() {
-		cred, err := scanCredential(rows)
+		cred, err := scanCredential(s.secrets, rows)
 		if err != nil {
 			return nil, err
 		}
@@ -1,20 +1,24 @@
 package storage
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Endpoint struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	APIUrl      string    `json:"apiUrl"`
-	APIKey      string    `json:"apiKey"`
-	AuthMode    string    `json:"authMode"`
-	Enabled     bool      `json:"enabled"`
-	Transformer string    `json:"transformer"`
-	Model       string    `json:"model"`
-	Remark      string    `json:"remark"`
-	SortOrder   int       `json:"sortOrder"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	APIUrl      string          `json:"apiUrl"`
+	APIKey      string          `json:"apiKey"`
+	AuthMode    string          `json:"authMode"`
+	Enabled     bool            `json:"enabled"`
+	Transformer string          `json:"transformer"`
+	Model       string          `json:"model"`
+	Remark      string          `json:"remark"`
+	SortOrder   int             `json:"sortOrder"`
+	Options     json.RawMessage `json:"options,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
 }
 
 type EndpointCredential struct {
@@ -97,6 +101,59 @@ type TokenPoolStats struct {
 	NeedRefresh int `json:"needRefresh"`
 }
 
+// ClientKey is a key issued to a client of the proxy routes. Only a hash of
+// the secret is stored; the secret itself is returned once, when the key is
+// created or rotated.
+type ClientKey struct {
+	ID                int64      `json:"id"`
+	Name              string     `json:"name"`
+	Prefix            string     `json:"prefix"`
+	AllowedEndpoints  []string   `json:"allowedEndpoints"`
+	AllowedGroups     []string   `json:"allowedGroups"`
+	AllowedModels     []string   `json:"allowedModels"`
+	RequestsPerMinute int        `json:"requestsPerMinute"`
+	DailyTokenQuota   int64      `json:"dailyTokenQuota"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt         *time.Time `json:"revokedAt,omitempty"`
+	LastUsedAt        *time.Time `json:"lastUsedAt,omitempty"`
+	Remark            string     `json:"remark,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+// ClientKeyUsage is what one client key used of one endpoint
+type ClientKeyUsage struct {
+	KeyID        int64  `json:"keyId"`
+	EndpointName string `json:"endpointName"`
+	Requests     int    `json:"requests"`
+	Errors       int    `json:"errors"`
+	InputTokens  int64  `json:"inputTokens"`
+	OutputTokens int64  `json:"outputTokens"`
+}
+
+// WebUser is an account of the server's web UI API. Only a hash of the
+// password is stored.
+type WebUser struct {
+	ID          int64      `json:"id"`
+	Username    string     `json:"username"`
+	Role        string     `json:"role"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// AuditEntry is a change made through the web UI API
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Username   string    `json:"username"`
+	Role       string    `json:"role"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	RemoteAddr string    `json:"remoteAddr"`
+}
+
 type DailyStat struct {
 	ID           int64
 	EndpointName string
@@ -0,0 +1,548 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Secret columns are encrypted with AES-256-GCM under a random data key.
+// The data key is stored in secret_keys, wrapped by a master key that never
+// touches the database, so a copied database or backup stays encrypted.
+// Encrypted values look like enc:v1:<data key id>:<base64 nonce+ciphertext>.
+const sealedPrefix = "enc:v1:"
+
+var (
+	// ErrSecretsLocked is returned for encrypted secrets when no master key
+	// was given
+	ErrSecretsLocked = errors.New("secrets in the database are encrypted, a master key is required")
+	// ErrWrongMasterKey is returned when the master key cannot unwrap the
+	// data key of a database
+	ErrWrongMasterKey = errors.New("master key does not match the one the secrets were encrypted with")
+)
+
+// secretConfigKeys are the app_config entries that hold secrets
+var secretConfigKeys = []string{
+	"webdav_password",
+	"backup_s3_accessKey", "backup_s3_secretKey", "backup_s3_sessionToken",
+	"basicAuthPassword",
+	"plugins", // Plugin env may carry credentials
+}
+
+func isSecretConfigKey(key string) bool {
+	for _, k := range secretConfigKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// MasterKey protects the data key of a database. It is either 32 random
+// bytes or a passphrase, which is stretched with scrypt.
+type MasterKey struct {
+	key        []byte
+	passphrase string
+}
+
+// ParseMasterKey reads a 32-byte master key encoded as base64 or hex
+func ParseMasterKey(encoded string) (*MasterKey, error) {
+	encoded = strings.TrimSpace(encoded)
+	for _, decode := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if key, err := decode(encoded); err == nil && len(key) == 32 {
+			return &MasterKey{key: key}, nil
+		}
+	}
+	return nil, errors.New("master key must be 32 bytes encoded as base64 or hex")
+}
+
+// PassphraseMasterKey derives the master key from a passphrase
+func PassphraseMasterKey(passphrase string) (*MasterKey, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is empty")
+	}
+	return &MasterKey{passphrase: passphrase}, nil
+}
+
+// GenerateMasterKey returns a new random master key, base64 encoded
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// MasterKeyFromEnv reads the master key from <prefix>_MASTER_KEY,
+// <prefix>_MASTER_KEY_FILE, <prefix>_MASTER_PASSPHRASE or
+// <prefix>_MASTER_PASSPHRASE_FILE, whichever is set first. It returns the
+// name of the variable used, or a nil key if none is set.
+func MasterKeyFromEnv(prefix string) (*MasterKey, string, error) {
+	for _, source := range []struct {
+		name       string
+		file       bool
+		passphrase bool
+	}{
+		{prefix + "_MASTER_KEY", false, false},
+		{prefix + "_MASTER_KEY_FILE", true, false},
+		{prefix + "_MASTER_PASSPHRASE", false, true},
+		{prefix + "_MASTER_PASSPHRASE_FILE", true, true},
+	} {
+		value := os.Getenv(source.name)
+		if value == "" {
+			continue
+		}
+		if source.file {
+			data, err := os.ReadFile(value)
+			if err != nil {
+				return nil, source.name, err
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+
+		var key *MasterKey
+		var err error
+		if source.passphrase {
+			key, err = PassphraseMasterKey(value)
+		} else {
+			key, err = ParseMasterKey(value)
+		}
+		if err != nil {
+			return nil, source.name, fmt.Errorf("%s: %w", source.name, err)
+		}
+		return key, source.name, nil
+	}
+	return nil, "", nil
+}
+
+// kek returns the key that wraps a data key stored with salt
+func (k *MasterKey) kek(salt []byte) ([]byte, error) {
+	if k.passphrase == "" {
+		return k.key, nil
+	}
+	return scrypt.Key([]byte(k.passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+func gcmSeal(key, plaintext, additional []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func gcmOpen(key, sealed, additional []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+}
+
+// secretBox seals values with the active data key and opens values sealed
+// with any known one. A nil box stores secrets in plain text.
+type secretBox struct {
+	keys   map[string][]byte
+	active string
+}
+
+func (b *secretBox) seal(value string) (string, error) {
+	if b == nil || value == "" {
+		return value, nil
+	}
+	sealed, err := gcmSeal(b.keys[b.active], []byte(value), []byte(b.active))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + b.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *secretBox) open(value string) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	if b == nil {
+		return "", ErrSecretsLocked
+	}
+	key, ok := b.keys[id]
+	if !ok {
+		return "", fmt.Errorf("unknown data key %s", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	plain, err := gcmOpen(key, sealed, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plain), nil
+}
+
+// current reports whether a value is stored the way the box would store it
+func (b *secretBox) current(value string) bool {
+	if b == nil {
+		return !strings.HasPrefix(value, sealedPrefix)
+	}
+	return value == "" || strings.HasPrefix(value, sealedPrefix+b.active+":")
+}
+
+// with returns a box that also opens values sealed with keys
+func (b *secretBox) with(keys map[string][]byte) *secretBox {
+	if len(keys) == 0 {
+		return b
+	}
+	merged := &secretBox{keys: make(map[string][]byte)}
+	if b != nil {
+		merged.active = b.active
+		for id, key := range b.keys {
+			merged.keys[id] = key
+		}
+	}
+	for id, key := range keys {
+		merged.keys[id] = key
+	}
+	return merged
+}
+
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// loadDataKeys unwraps the data keys of a database (main or attached) and
+// returns them with the id of the newest
+func loadDataKeys(db querier, dbName string, master *MasterKey) (map[string][]byte, string, error) {
+	var tables int
+	if err := db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s.sqlite_master WHERE type='table' AND name='secret_keys'`, dbName)).Scan(&tables); err != nil {
+		return nil, "", err
+	}
+	if tables == 0 {
+		return nil, "", nil
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT id, wrapped_key, salt FROM %s.secret_keys ORDER BY created_at, rowid`, dbName))
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	keys := make(map[string][]byte)
+	var newest string
+	for rows.Next() {
+		var id, wrapped, salt string
+		if err := rows.Scan(&id, &wrapped, &salt); err != nil {
+			return nil, "", err
+		}
+		if master == nil {
+			return nil, "", ErrSecretsLocked
+		}
+		key, err := unwrapDataKey(master, id, wrapped, salt)
+		if err != nil {
+			return nil, "", err
+		}
+		keys[id] = key
+		newest = id
+	}
+	return keys, newest, rows.Err()
+}
+
+func unwrapDataKey(master *MasterKey, id, wrapped, salt string) ([]byte, error) {
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := master.kek(saltBytes)
+	if err != nil {
+		return nil, err
+	}
+	key, err := gcmOpen(kek, sealed, []byte(id))
+	if err != nil {
+		return nil, ErrWrongMasterKey
+	}
+	return key, nil
+}
+
+// wrapDataKey stores a data key wrapped by the master key, under a new salt
+func wrapDataKey(tx *sql.Tx, master *MasterKey, id string, key []byte) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	kek, err := master.kek(salt)
+	if err != nil {
+		return err
+	}
+	wrapped, err := gcmSeal(kek, key, []byte(id))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO secret_keys (id, wrapped_key, salt) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET wrapped_key=excluded.wrapped_key, salt=excluded.salt
+	`, id, base64.StdEncoding.EncodeToString(wrapped), base64.StdEncoding.EncodeToString(salt))
+	return err
+}
+
+// newDataKey creates a random data key and stores it wrapped by master
+func newDataKey(tx *sql.Tx, master *MasterKey) (string, []byte, error) {
+	buf := make([]byte, 8+32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	id, key := hex.EncodeToString(buf[:8]), buf[8:]
+	if err := wrapDataKey(tx, master, id, key); err != nil {
+		return "", nil, err
+	}
+	return id, key, nil
+}
+
+// secretColumn is a column holding secrets, limited to the rows matching
+// filter
+type secretColumn struct {
+	table, column, filter string
+	args                  []interface{}
+}
+
+// secretColumns lists every column that holds secrets
+func secretColumns() []secretColumn {
+	placeholders := make([]string, len(secretConfigKeys))
+	configKeys := make([]interface{}, len(secretConfigKeys))
+	for i, key := range secretConfigKeys {
+		placeholders[i] = "?"
+		configKeys[i] = key
+	}
+	return []secretColumn{
+		{"endpoints", "api_key", "", nil},
+		{"endpoints", "options", "", nil},
+		{"endpoint_credentials", "access_token", "", nil},
+		{"endpoint_credentials", "refresh_token", "", nil},
+		{"endpoint_credentials", "id_token", "", nil},
+		{"app_config", "value", " AND key IN (" + strings.Join(placeholders, ",") + ")", configKeys},
+	}
+}
+
+// resealSecrets rewrites every secret column that is not stored the way to
+// stores it, opening the old values with from
+func resealSecrets(tx *sql.Tx, from, to *secretBox) error {
+	for _, c := range secretColumns() {
+		rows, err := tx.Query(fmt.Sprintf(`SELECT rowid, %s FROM %s WHERE COALESCE(%s, '') != ''%s`, c.column, c.table, c.column, c.filter), c.args...)
+		if err != nil {
+			return err
+		}
+		updates := make(map[int64]string)
+		for rows.Next() {
+			var rowid int64
+			var value string
+			if err := rows.Scan(&rowid, &value); err != nil {
+				rows.Close()
+				return err
+			}
+			if !to.current(value) {
+				updates[rowid] = value
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for rowid, value := range updates {
+			plain, err := from.open(value)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", c.table, c.column, err)
+			}
+			sealed, err := to.seal(plain)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET %s=? WHERE rowid=?`, c.table, c.column), sealed, rowid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UnlockSecrets encrypts secret columns with a data key protected by master.
+// The first call on a database creates the data key and later calls unwrap
+// it; either way, secrets still stored in plain text get encrypted. Without
+// a master key secrets stay in plain text, which fails with ErrSecretsLocked
+// once the database has a data key.
+func (s *SQLiteStorage) UnlockSecrets(master *MasterKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, active, err := loadDataKeys(s.db, "main", master)
+	if err != nil {
+		return err
+	}
+	if master == nil {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(keys) == 0 {
+		id, key, err := newDataKey(tx, master)
+		if err != nil {
+			return err
+		}
+		keys, active = map[string][]byte{id: key}, id
+	}
+	box := &secretBox{keys: keys, active: active}
+	if err := resealSecrets(tx, box, box); err != nil {
+		return fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.master, s.secrets = master, box
+	return nil
+}
+
+// RotateMasterKey wraps the data key with a new master key. With
+// rotateDataKey, all secrets are also encrypted again under a new data key.
+// Other processes using the database must be restarted after a new data key.
+func (s *SQLiteStorage) RotateMasterKey(master *MasterKey, rotateDataKey bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secrets == nil {
+		return ErrSecretsLocked
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	box := s.secrets
+	if rotateDataKey {
+		id, key, err := newDataKey(tx, master)
+		if err != nil {
+			return err
+		}
+		box = &secretBox{keys: map[string][]byte{id: key}, active: id}
+		if err := resealSecrets(tx, s.secrets.with(box.keys), box); err != nil {
+			return fmt.Errorf("failed to encrypt secrets: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM secret_keys WHERE id != ?`, id); err != nil {
+			return err
+		}
+	} else {
+		for id, key := range box.keys {
+			if err := wrapDataKey(tx, master, id, key); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.master, s.secrets = master, box
+	return nil
+}
+
+// ResetSecrets recovers a database whose master key was lost. Encrypted
+// values are cleared and the data keys deleted, then the remaining secrets
+// are encrypted under a new data key wrapped by master. Endpoints and
+// settings are kept, but their API keys, tokens and passwords must be entered
+// again.
+func (s *SQLiteStorage) ResetSecrets(master *MasterKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range secretColumns() {
+		args := append([]interface{}{sealedPrefix + "%"}, c.args...)
+		if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET %s='' WHERE %s LIKE ?%s`, c.table, c.column, c.column, c.filter), args...); err != nil {
+			return fmt.Errorf("failed to clear %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM secret_keys`); err != nil {
+		return err
+	}
+
+	var box *secretBox
+	if master != nil {
+		id, key, err := newDataKey(tx, master)
+		if err != nil {
+			return err
+		}
+		box = &secretBox{keys: map[string][]byte{id: key}, active: id}
+		if err := resealSecrets(tx, nil, box); err != nil {
+			return fmt.Errorf("failed to encrypt secrets: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.master, s.secrets = master, box
+	return nil
+}
+
+// SecretsEncrypted reports whether secrets are encrypted with a master key
+func (s *SQLiteStorage) SecretsEncrypted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.secrets != nil
+}
+
+// backupSecrets returns a box that opens the secrets of both the local
+// database and an attached backup
+func (s *SQLiteStorage) backupSecrets(db querier, dbName string) (*secretBox, error) {
+	keys, _, err := loadDataKeys(db, dbName, s.master)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	return s.secrets.with(keys), nil
+}
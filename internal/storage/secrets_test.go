@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestStorage(t *testing.T, path string, master *MasterKey) *SQLiteStorage {
+	t.Helper()
+	s, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.UnlockSecrets(master); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testMasterKey(t *testing.T) *MasterKey {
+	t.Helper()
+	encoded, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseMasterKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// rawColumn reads a column as stored, bypassing decryption
+func rawColumn(t *testing.T, s *SQLiteStorage, query string) string {
+	t.Helper()
+	var value string
+	if err := s.db.QueryRow(query).Scan(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestUnlockSecretsEncryptsExistingRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccnexus.db")
+	plain := openTestStorage(t, path, nil)
+	plain.SaveEndpoint(&Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main", AuthMode: "api_key", Enabled: true, Transformer: "claude", Options: json.RawMessage(`{"region":"us-east-1"}`)})
+	plain.SaveEndpointCredential(&EndpointCredential{EndpointName: "Main", AccessToken: "access", RefreshToken: "refresh", Enabled: true})
+	plain.SetConfig("webdav_password", "hunter2")
+	plain.SetConfig("plugins", `[{"name":"quirks","command":"quirks","env":{"TOKEN":"plugin-token"}}]`)
+	plain.SetConfig("language", "en")
+	if got := rawColumn(t, plain, `SELECT api_key FROM endpoints`); got != "sk-main" {
+		t.Fatalf("expected plain text without a master key, got %q", got)
+	}
+	plain.Close()
+
+	master, err := PassphraseMasterKey("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := openTestStorage(t, path, master)
+	for _, query := range []string{
+		`SELECT api_key FROM endpoints`,
+		`SELECT options FROM endpoints`,
+		`SELECT access_token FROM endpoint_credentials`,
+		`SELECT refresh_token FROM endpoint_credentials`,
+		`SELECT value FROM app_config WHERE key='webdav_password'`,
+		`SELECT value FROM app_config WHERE key='plugins'`,
+	} {
+		if got := rawColumn(t, s, query); !strings.HasPrefix(got, sealedPrefix) {
+			t.Errorf("%s: expected an encrypted value, got %q", query, got)
+		}
+	}
+	if got := rawColumn(t, s, `SELECT value FROM app_config WHERE key='language'`); got != "en" {
+		t.Errorf("expected settings to stay readable, got %q", got)
+	}
+
+	endpoints, err := s.GetEndpoints()
+	if err != nil || len(endpoints) != 1 || endpoints[0].APIKey != "sk-main" || string(endpoints[0].Options) != `{"region":"us-east-1"}` {
+		t.Fatalf("expected decrypted endpoints, got %+v, %v", endpoints, err)
+	}
+	creds, err := s.GetEndpointCredentials("Main")
+	if err != nil || len(creds) != 1 || creds[0].AccessToken != "access" || creds[0].RefreshToken != "refresh" || creds[0].IDToken != "" {
+		t.Fatalf("expected decrypted credentials, got %+v, %v", creds, err)
+	}
+	if password, _ := s.GetConfig("webdav_password"); password != "hunter2" {
+		t.Fatalf("expected decrypted password, got %q", password)
+	}
+	s.Close()
+
+	locked, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer locked.Close()
+	if err := locked.UnlockSecrets(nil); !errors.Is(err, ErrSecretsLocked) {
+		t.Fatalf("expected the database to require a master key, got %v", err)
+	}
+	if err := locked.UnlockSecrets(testMasterKey(t)); !errors.Is(err, ErrWrongMasterKey) {
+		t.Fatalf("expected a wrong master key to be refused, got %v", err)
+	}
+	if _, err := locked.GetEndpoints(); !errors.Is(err, ErrSecretsLocked) {
+		t.Fatalf("expected reads to fail while locked, got %v", err)
+	}
+}
+
+func TestRotateMasterKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccnexus.db")
+	oldKey, newKey := testMasterKey(t), testMasterKey(t)
+	s := openTestStorage(t, path, oldKey)
+	s.SaveEndpoint(&Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+	before := rawColumn(t, s, `SELECT api_key FROM endpoints`)
+
+	if err := s.RotateMasterKey(newKey, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := rawColumn(t, s, `SELECT api_key FROM endpoints`); got != before {
+		t.Fatal("expected rewrapping the data key to leave secrets untouched")
+	}
+	if err := s.RotateMasterKey(newKey, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := rawColumn(t, s, `SELECT api_key FROM endpoints`); got == before {
+		t.Fatal("expected a new data key to re-encrypt secrets")
+	}
+	if got := rawColumn(t, s, `SELECT COUNT(*) FROM secret_keys`); got != "1" {
+		t.Fatalf("expected the old data key to be dropped, got %s keys", got)
+	}
+	s.Close()
+
+	reopened, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if err := reopened.UnlockSecrets(oldKey); !errors.Is(err, ErrWrongMasterKey) {
+		t.Fatalf("expected the old master key to stop working, got %v", err)
+	}
+	if err := reopened.UnlockSecrets(newKey); err != nil {
+		t.Fatal(err)
+	}
+	if endpoints, err := reopened.GetEndpoints(); err != nil || endpoints[0].APIKey != "sk-main" {
+		t.Fatalf("expected secrets to survive rotation, got %+v, %v", endpoints, err)
+	}
+}
+
+func TestBackupsStayEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	master := testMasterKey(t)
+	source := openTestStorage(t, filepath.Join(dir, "source.db"), master)
+	source.SaveEndpoint(&Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+	source.SetConfig("backup_s3_secretKey", "s3-secret")
+
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := source.CreateBackupCopy(backupPath); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := NewSQLiteStorage(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backup.GetEndpoints(); !errors.Is(err, ErrSecretsLocked) {
+		t.Fatalf("expected the backup to stay encrypted, got %v", err)
+	}
+	backup.Close()
+
+	// The target has its own data key under the same master key
+	target := openTestStorage(t, filepath.Join(dir, "target.db"), master)
+	target.SaveEndpoint(&Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+	conflicts, err := target.DetectEndpointConflicts(backupPath)
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("expected equal secrets not to conflict, got %+v, %v", conflicts, err)
+	}
+
+	target.UpdateEndpoint(&Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-old", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+	if err := target.MergeFromBackup(backupPath, MergeStrategyOverwriteLocal); err != nil {
+		t.Fatal(err)
+	}
+	endpoints, err := target.GetEndpoints()
+	if err != nil || endpoints[0].APIKey != "sk-main" {
+		t.Fatalf("expected the restored key to be readable, got %+v, %v", endpoints, err)
+	}
+	if secret, err := target.GetConfig("backup_s3_secretKey"); err != nil || secret != "s3-secret" {
+		t.Fatalf("expected the restored S3 secret, got %q, %v", secret, err)
+	}
+
+	other := openTestStorage(t, filepath.Join(dir, "other.db"), testMasterKey(t))
+	if err := other.MergeFromBackup(backupPath, MergeStrategyKeepLocal); !errors.Is(err, ErrWrongMasterKey) {
+		t.Fatalf("expected a backup under another master key to be refused, got %v", err)
+	}
+
+	// Taking over the master key of the machine that made the backup lets
+	// another machine restore it
+	if err := other.RotateMasterKey(master, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.MergeFromBackup(backupPath, MergeStrategyKeepLocal); err != nil {
+		t.Fatalf("expected the backup to restore under the shared master key, got %v", err)
+	}
+	if endpoints, err := other.GetEndpoints(); err != nil || len(endpoints) != 1 || endpoints[0].APIKey != "sk-main" {
+		t.Fatalf("expected the restored key to be readable, got %+v, %v", endpoints, err)
+	}
+}
+
+func TestResetSecretsAfterLostMasterKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccnexus.db")
+	s := openTestStorage(t, path, testMasterKey(t))
+	s.SaveEndpoint(&Endpoint{Name: "Main", APIUrl: "https://example.com", APIKey: "sk-main", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+	s.SetConfig("webdav_password", "hunter2")
+	s.SetConfig("language", "en")
+	s.Close()
+
+	lost, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lost.Close()
+	replacement := testMasterKey(t)
+	if err := lost.UnlockSecrets(replacement); !errors.Is(err, ErrWrongMasterKey) {
+		t.Fatalf("expected the lost key to be missed, got %v", err)
+	}
+	if err := lost.ResetSecrets(replacement); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := lost.GetEndpoints()
+	if err != nil || len(endpoints) != 1 || endpoints[0].Name != "Main" || endpoints[0].APIKey != "" {
+		t.Fatalf("expected the endpoint kept without its key, got %+v, %v", endpoints, err)
+	}
+	if password, err := lost.GetConfig("webdav_password"); err != nil || password != "" {
+		t.Fatalf("expected the password cleared, got %q, %v", password, err)
+	}
+	if language, _ := lost.GetConfig("language"); language != "en" {
+		t.Fatalf("expected settings to be kept, got %q", language)
+	}
+	lost.SaveEndpoint(&Endpoint{Name: "New", APIUrl: "https://example.com", APIKey: "sk-new", AuthMode: "api_key", Enabled: true, Transformer: "claude"})
+	lost.Close()
+
+	reopened := openTestStorage(t, path, replacement)
+	if endpoints, err := reopened.GetEndpoints(); err != nil || len(endpoints) != 2 || endpoints[1].APIKey != "sk-new" {
+		t.Fatalf("expected the database to open with the new key, got %+v, %v", endpoints, err)
+	}
+}
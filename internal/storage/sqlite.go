@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -40,9 +41,11 @@ var safeConfigKeys = []string{
 }
 
 type SQLiteStorage struct {
-	db     *sql.DB
-	dbPath string
-	mu     sync.RWMutex
+	db      *sql.DB
+	dbPath  string
+	mu      sync.RWMutex
+	master  *MasterKey
+	secrets *secretBox // nil while secrets are stored in plain text
 }
 
 func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
@@ -95,6 +98,7 @@ func (s *SQLiteStorage) initSchema() error {
 		model TEXT,
 		remark TEXT,
 		sort_order INTEGER DEFAULT 0,
+		options TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -159,6 +163,70 @@ func (s *SQLiteStorage) initSchema() error {
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS client_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		key_hash TEXT UNIQUE NOT NULL,
+		prefix TEXT NOT NULL,
+		allowed_endpoints TEXT,
+		allowed_groups TEXT,
+		allowed_models TEXT,
+		requests_per_minute INTEGER DEFAULT 0,
+		daily_token_quota INTEGER DEFAULT 0,
+		expires_at DATETIME,
+		revoked_at DATETIME,
+		last_used_at DATETIME,
+		remark TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS client_key_usage (
+		key_id INTEGER NOT NULL,
+		endpoint_name TEXT NOT NULL,
+		date TEXT NOT NULL,
+		requests INTEGER DEFAULT 0,
+		errors INTEGER DEFAULT 0,
+		input_tokens INTEGER DEFAULT 0,
+		output_tokens INTEGER DEFAULT 0,
+		PRIMARY KEY (key_id, endpoint_name, date)
+	);
+
+	CREATE TABLE IF NOT EXISTS web_users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		role TEXT NOT NULL,
+		last_login_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS web_sessions (
+		token_hash TEXT PRIMARY KEY,
+		username TEXT NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		time DATETIME NOT NULL,
+		username TEXT NOT NULL,
+		role TEXT NOT NULL,
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		remote_addr TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS secret_keys (
+		id TEXT PRIMARY KEY,
+		wrapped_key TEXT NOT NULL,
+		salt TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_daily_stats_date ON daily_stats(date);
 	CREATE INDEX IF NOT EXISTS idx_daily_stats_endpoint ON daily_stats(endpoint_name);
 	CREATE INDEX IF NOT EXISTS idx_daily_stats_device ON daily_stats(device_id);
@@ -167,6 +235,9 @@ func (s *SQLiteStorage) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_endpoint_credentials_expires_at ON endpoint_credentials(expires_at);
 	CREATE INDEX IF NOT EXISTS idx_credential_rate_limits_updated ON credential_rate_limits(updated_at);
 	CREATE INDEX IF NOT EXISTS idx_credential_usage_endpoint ON credential_usage(endpoint_name);
+	CREATE INDEX IF NOT EXISTS idx_client_key_usage_date ON client_key_usage(date);
+	CREATE INDEX IF NOT EXISTS idx_web_sessions_username ON web_sessions(username);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_time ON audit_log(time);
 	`
 
 	if _, err := s.db.Exec(schema); err != nil {
@@ -180,6 +251,12 @@ func (s *SQLiteStorage) initSchema() error {
 	if err := s.migrateAuthMode(); err != nil {
 		return err
 	}
+	if err := s.migrateEndpointOptions(); err != nil {
+		return err
+	}
+	if err := s.migrateClientKeyGroups(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -226,20 +303,87 @@ func (s *SQLiteStorage) migrateAuthMode() error {
 	return err
 }
 
+// migrateEndpointOptions adds the options column to existing databases
+func (s *SQLiteStorage) migrateEndpointOptions() error {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('endpoints') WHERE name='options'`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := s.db.Exec(`ALTER TABLE endpoints ADD COLUMN options TEXT`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateClientKeyGroups adds the allowed_groups column to existing databases
+func (s *SQLiteStorage) migrateClientKeyGroups() error {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('client_keys') WHERE name='allowed_groups'`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := s.db.Exec(`ALTER TABLE client_keys ADD COLUMN allowed_groups TEXT`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// optionsFromString converts a stored options value into raw JSON
+func optionsFromString(value string) json.RawMessage {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "null" {
+		return nil
+	}
+	return json.RawMessage(value)
+}
+
+// optionsColumnValue returns the value written to the options column,
+// which is encrypted since options may hold cloud credentials
+func (s *SQLiteStorage) optionsColumnValue(options json.RawMessage) (interface{}, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	return s.secrets.seal(string(options))
+}
+
+// openEndpoint decrypts the secrets of an endpoint read from the database
+func openEndpoint(box *secretBox, ep *Endpoint, options string) error {
+	apiKey, err := box.open(ep.APIKey)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", ep.Name, err)
+	}
+	if options, err = box.open(options); err != nil {
+		return fmt.Errorf("endpoint %s: %w", ep.Name, err)
+	}
+	ep.APIKey = apiKey
+	ep.Options = optionsFromString(options)
+	return nil
+}
+
 func (s *SQLiteStorage) GetEndpoints() ([]Endpoint, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	rows, err := s.db.Query(`SELECT id, name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order, created_at, updated_at FROM endpoints ORDER BY sort_order ASC`)
+	rows, err := s.db.Query(`SELECT id, name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order, COALESCE(options, ''), created_at, updated_at FROM endpoints ORDER BY sort_order ASC`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	box := s.secrets
 	var endpoints []Endpoint
 	for rows.Next() {
 		var ep Endpoint
-		if err := rows.Scan(&ep.ID, &ep.Name, &ep.APIUrl, &ep.APIKey, &ep.AuthMode, &ep.Enabled, &ep.Transformer, &ep.Model, &ep.Remark, &ep.SortOrder, &ep.CreatedAt, &ep.UpdatedAt); err != nil {
+		var options string
+		if err := rows.Scan(&ep.ID, &ep.Name, &ep.APIUrl, &ep.APIKey, &ep.AuthMode, &ep.Enabled, &ep.Transformer, &ep.Model, &ep.Remark, &ep.SortOrder, &options, &ep.CreatedAt, &ep.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := openEndpoint(box, &ep, options); err != nil {
 			return nil, err
 		}
 		normalizeEndpointAuthMode(&ep)
@@ -254,9 +398,17 @@ func (s *SQLiteStorage) SaveEndpoint(ep *Endpoint) error {
 	defer s.mu.Unlock()
 
 	normalizeEndpointAuthMode(ep)
+	apiKey, err := s.secrets.seal(ep.APIKey)
+	if err != nil {
+		return err
+	}
+	options, err := s.optionsColumnValue(ep.Options)
+	if err != nil {
+		return err
+	}
 
-	result, err := s.db.Exec(`INSERT INTO endpoints (name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		ep.Name, ep.APIUrl, ep.APIKey, ep.AuthMode, ep.Enabled, ep.Transformer, ep.Model, ep.Remark, ep.SortOrder)
+	result, err := s.db.Exec(`INSERT INTO endpoints (name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order, options) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ep.Name, ep.APIUrl, apiKey, ep.AuthMode, ep.Enabled, ep.Transformer, ep.Model, ep.Remark, ep.SortOrder, options)
 	if err != nil {
 		return err
 	}
@@ -274,9 +426,17 @@ func (s *SQLiteStorage) UpdateEndpoint(ep *Endpoint) error {
 	defer s.mu.Unlock()
 
 	normalizeEndpointAuthMode(ep)
+	apiKey, err := s.secrets.seal(ep.APIKey)
+	if err != nil {
+		return err
+	}
+	options, err := s.optionsColumnValue(ep.Options)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.Exec(`UPDATE endpoints SET api_url=?, api_key=?, auth_mode=?, enabled=?, transformer=?, model=?, remark=?, sort_order=?, updated_at=CURRENT_TIMESTAMP WHERE name=?`,
-		ep.APIUrl, ep.APIKey, ep.AuthMode, ep.Enabled, ep.Transformer, ep.Model, ep.Remark, ep.SortOrder, ep.Name)
+	_, err = s.db.Exec(`UPDATE endpoints SET api_url=?, api_key=?, auth_mode=?, enabled=?, transformer=?, model=?, remark=?, sort_order=?, options=?, updated_at=CURRENT_TIMESTAMP WHERE name=?`,
+		ep.APIUrl, apiKey, ep.AuthMode, ep.Enabled, ep.Transformer, ep.Model, ep.Remark, ep.SortOrder, options, ep.Name)
 	return err
 }
 
@@ -374,13 +534,22 @@ func (s *SQLiteStorage) GetConfig(key string) (string, error) {
 	if err == sql.ErrNoRows {
 		return "", nil
 	}
-	return value, err
+	if err != nil {
+		return "", err
+	}
+	return s.secrets.open(value)
 }
 
 func (s *SQLiteStorage) SetConfig(key, value string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if isSecretConfigKey(key) {
+		var err error
+		if value, err = s.secrets.seal(value); err != nil {
+			return err
+		}
+	}
 	_, err := s.db.Exec(`INSERT INTO app_config (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value, updated_at=CURRENT_TIMESTAMP`, key, value)
 	return err
 }
@@ -608,6 +777,7 @@ func (s *SQLiteStorage) DeleteMonthlyStats(month string) error {
 
 // CreateBackupCopy 创建数据库备份副本，只保留安全的 app_config 配置项。
 // 设备特定的配置（device_id、终端设置、本地路径等）会被排除。
+// 加密的密钥字段连同包装后的数据密钥一起复制，备份保持加密状态。
 func (s *SQLiteStorage) CreateBackupCopy(backupPath string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -662,14 +832,20 @@ func (s *SQLiteStorage) DetectEndpointConflicts(remoteDBPath string) ([]MergeCon
 	}
 	defer s.db.Exec("DETACH DATABASE remote")
 
+	// Secrets of the remote database are encrypted with its own data key
+	box, err := s.backupSecrets(s.db, "remote")
+	if err != nil {
+		return nil, err
+	}
+
 	// Get local endpoints
-	localEndpoints, err := s.getEndpointsFromDB(s.db, "main")
+	localEndpoints, err := s.getEndpointsFromDB(s.db, "main", box)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get remote endpoints
-	remoteEndpoints, err := s.getEndpointsFromDB(s.db, "remote")
+	remoteEndpoints, err := s.getEndpointsFromDB(s.db, "remote", box)
 	if err != nil {
 		return nil, err
 	}
@@ -701,18 +877,28 @@ func (s *SQLiteStorage) DetectEndpointConflicts(remoteDBPath string) ([]MergeCon
 }
 
 // getEndpointsFromDB gets endpoints from a specific database (main or attached)
-func (s *SQLiteStorage) getEndpointsFromDB(db *sql.DB, dbName string) ([]Endpoint, error) {
+func (s *SQLiteStorage) getEndpointsFromDB(db *sql.DB, dbName string, box *secretBox) ([]Endpoint, error) {
 	var authModeColumnCount int
 	columnCheck := fmt.Sprintf(`SELECT COUNT(*) FROM %s.pragma_table_info('endpoints') WHERE name='auth_mode'`, dbName)
 	if err := db.QueryRow(columnCheck).Scan(&authModeColumnCount); err != nil {
 		return nil, err
 	}
 
+	var optionsColumnCount int
+	optionsCheck := fmt.Sprintf(`SELECT COUNT(*) FROM %s.pragma_table_info('endpoints') WHERE name='options'`, dbName)
+	if err := db.QueryRow(optionsCheck).Scan(&optionsColumnCount); err != nil {
+		return nil, err
+	}
+	selectOptions := "''"
+	if optionsColumnCount > 0 {
+		selectOptions = "COALESCE(options, '')"
+	}
+
 	query := ""
 	if authModeColumnCount > 0 {
-		query = fmt.Sprintf(`SELECT id, name, api_url, api_key, COALESCE(auth_mode, 'api_key') as auth_mode, enabled, transformer, model, remark, COALESCE(sort_order, 0) as sort_order, created_at, updated_at FROM %s.endpoints`, dbName)
+		query = fmt.Sprintf(`SELECT id, name, api_url, api_key, COALESCE(auth_mode, 'api_key') as auth_mode, enabled, transformer, model, remark, COALESCE(sort_order, 0) as sort_order, %s as options, created_at, updated_at FROM %s.endpoints`, selectOptions, dbName)
 	} else {
-		query = fmt.Sprintf(`SELECT id, name, api_url, api_key, 'api_key' as auth_mode, enabled, transformer, model, remark, COALESCE(sort_order, 0) as sort_order, created_at, updated_at FROM %s.endpoints`, dbName)
+		query = fmt.Sprintf(`SELECT id, name, api_url, api_key, 'api_key' as auth_mode, enabled, transformer, model, remark, COALESCE(sort_order, 0) as sort_order, %s as options, created_at, updated_at FROM %s.endpoints`, selectOptions, dbName)
 	}
 
 	rows, err := db.Query(query)
@@ -724,7 +910,11 @@ func (s *SQLiteStorage) getEndpointsFromDB(db *sql.DB, dbName string) ([]Endpoin
 	var endpoints []Endpoint
 	for rows.Next() {
 		var ep Endpoint
-		if err := rows.Scan(&ep.ID, &ep.Name, &ep.APIUrl, &ep.APIKey, &ep.AuthMode, &ep.Enabled, &ep.Transformer, &ep.Model, &ep.Remark, &ep.SortOrder, &ep.CreatedAt, &ep.UpdatedAt); err != nil {
+		var options string
+		if err := rows.Scan(&ep.ID, &ep.Name, &ep.APIUrl, &ep.APIKey, &ep.AuthMode, &ep.Enabled, &ep.Transformer, &ep.Model, &ep.Remark, &ep.SortOrder, &options, &ep.CreatedAt, &ep.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := openEndpoint(box, &ep, options); err != nil {
 			return nil, err
 		}
 		normalizeEndpointAuthMode(&ep)
@@ -785,6 +975,9 @@ func compareEndpoints(local, remote Endpoint) []string {
 	if local.Remark != remote.Remark {
 		conflicts = append(conflicts, "remark")
 	}
+	if string(local.Options) != string(remote.Options) {
+		conflicts = append(conflicts, "options")
+	}
 
 	return conflicts
 }
@@ -831,6 +1024,15 @@ func (s *SQLiteStorage) MergeFromBackup(backupDBPath string, strategy MergeStrat
 		return fmt.Errorf("failed to merge app config: %w", err)
 	}
 
+	// 4. 备份中的密钥字段由备份自己的数据密钥加密，用本地数据密钥重新加密
+	box, err := s.backupSecrets(tx, "backup")
+	if err != nil {
+		return err
+	}
+	if err := resealSecrets(tx, box, s.secrets); err != nil {
+		return fmt.Errorf("failed to re-encrypt secrets: %w", err)
+	}
+
 	// 提交事务
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -851,24 +1053,33 @@ func (s *SQLiteStorage) mergeEndpoints(tx *sql.Tx, strategy MergeStrategy) error
 		selectAuthMode = "COALESCE(auth_mode, 'api_key')"
 	}
 
+	var backupHasOptions int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM backup.pragma_table_info('endpoints') WHERE name='options'`).Scan(&backupHasOptions); err != nil {
+		return err
+	}
+	selectOptions := "NULL"
+	if backupHasOptions > 0 {
+		selectOptions = "options"
+	}
+
 	switch strategy {
 	case MergeStrategyKeepLocal:
 		// 只插入新端点（忽略冲突）
 		_, err := tx.Exec(fmt.Sprintf(`
 			INSERT OR IGNORE INTO endpoints
-			(name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order)
-			SELECT name, api_url, api_key, %s, enabled, transformer, model, remark, COALESCE(sort_order, 0)
+			(name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order, options)
+			SELECT name, api_url, api_key, %s, enabled, transformer, model, remark, COALESCE(sort_order, 0), %s
 			FROM backup.endpoints
-		`, selectAuthMode))
+		`, selectAuthMode, selectOptions))
 		return err
 	case MergeStrategyOverwriteLocal:
 		// 替换已存在的端点
 		_, err := tx.Exec(fmt.Sprintf(`
 			INSERT OR REPLACE INTO endpoints
-			(name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order)
-			SELECT name, api_url, api_key, %s, enabled, transformer, model, remark, COALESCE(sort_order, 0)
+			(name, api_url, api_key, auth_mode, enabled, transformer, model, remark, sort_order, options)
+			SELECT name, api_url, api_key, %s, enabled, transformer, model, remark, COALESCE(sort_order, 0), %s
 			FROM backup.endpoints
-		`, selectAuthMode))
+		`, selectAuthMode, selectOptions))
 		return err
 	default:
 		return fmt.Errorf("unknown merge strategy: %s", strategy)
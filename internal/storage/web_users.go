@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const webUserColumns = `id, username, role, last_login_at, created_at, updated_at`
+
+func scanWebUser(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*WebUser, error) {
+	var user WebUser
+	var lastLoginAt sql.NullTime
+	if err := scanner.Scan(&user.ID, &user.Username, &user.Role, &lastLoginAt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	user.LastLoginAt = fromNullTime(lastLoginAt)
+	return &user, nil
+}
+
+// CreateWebUser stores a new web UI user with a hashed password
+func (s *SQLiteStorage) CreateWebUser(user *WebUser, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`INSERT INTO web_users (username, password_hash, role) VALUES (?, ?, ?)`,
+		user.Username, string(hash), user.Role)
+	if err != nil {
+		return err
+	}
+	user.ID, err = result.LastInsertId()
+	return err
+}
+
+// UpdateWebUserRole changes the role of a web UI user
+func (s *SQLiteStorage) UpdateWebUserRole(username, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`UPDATE web_users SET role=?, updated_at=CURRENT_TIMESTAMP WHERE username=?`, role, username)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// SetWebUserPassword replaces the password of a web UI user and ends its
+// sessions
+func (s *SQLiteStorage) SetWebUserPassword(username, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE web_users SET password_hash=?, updated_at=CURRENT_TIMESTAMP WHERE username=?`, string(hash), username)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM web_sessions WHERE username=?`, username); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteWebUser removes a web UI user and ends its sessions
+func (s *SQLiteStorage) DeleteWebUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM web_users WHERE username=?`, username)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM web_sessions WHERE username=?`, username); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetWebUsers returns all web UI users
+func (s *SQLiteStorage) GetWebUsers() ([]WebUser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT ` + webUserColumns + ` FROM web_users ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]WebUser, 0)
+	for rows.Next() {
+		user, err := scanWebUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// GetWebUser returns a web UI user, or nil if there is none with that name
+func (s *SQLiteStorage) GetWebUser(username string) (*WebUser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, err := scanWebUser(s.db.QueryRow(`SELECT `+webUserColumns+` FROM web_users WHERE username=?`, username))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// HasWebUsers reports whether any web UI user exists
+func (s *SQLiteStorage) HasWebUsers() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM web_users)`).Scan(&exists)
+	return exists, err
+}
+
+// VerifyWebUser returns the user if the password matches, or nil
+func (s *SQLiteStorage) VerifyWebUser(username, password string) (*WebUser, error) {
+	s.mu.RLock()
+	var hash string
+	err := s.db.QueryRow(`SELECT password_hash FROM web_users WHERE username=?`, username).Scan(&hash)
+	s.mu.RUnlock()
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s.GetWebUser(username)
+}
+
+// CreateWebSession starts a login session and returns its token. username
+// may also name the Basic Auth account of the configuration.
+func (s *SQLiteStorage) CreateWebSession(username string, ttl time.Duration) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM web_sessions WHERE expires_at <= ?`, now); err != nil {
+		return "", time.Time{}, err
+	}
+	if _, err := tx.Exec(`INSERT INTO web_sessions (token_hash, username, expires_at) VALUES (?, ?, ?)`,
+		hashSecret(token), username, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	if _, err := tx.Exec(`UPDATE web_users SET last_login_at=? WHERE username=?`, now, username); err != nil {
+		return "", time.Time{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// GetWebSession returns the user name of a session that has not expired, or
+// "" if the token is unknown
+func (s *SQLiteStorage) GetWebSession(token string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var username string
+	err := s.db.QueryRow(`SELECT username FROM web_sessions WHERE token_hash=? AND expires_at > ?`,
+		hashSecret(token), time.Now().UTC()).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return username, err
+}
+
+// DeleteWebSession ends a login session
+func (s *SQLiteStorage) DeleteWebSession(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`DELETE FROM web_sessions WHERE token_hash=?`, hashSecret(token))
+	return err
+}
+
+// RecordAudit appends an entry to the audit log
+func (s *SQLiteStorage) RecordAudit(entry *AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO audit_log (time, username, role, method, path, status, remote_addr)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entry.Time.UTC(), entry.Username, entry.Role, entry.Method, entry.Path, entry.Status, toNullString(entry.RemoteAddr))
+	return err
+}
+
+// GetAuditLog returns audit entries, newest first
+func (s *SQLiteStorage) GetAuditLog(limit, offset int) ([]AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, time, username, role, method, path, status, remote_addr
+		FROM audit_log
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var entry AuditEntry
+		var remoteAddr sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Time, &entry.Username, &entry.Role, &entry.Method, &entry.Path, &entry.Status, &remoteAddr); err != nil {
+			return nil, err
+		}
+		entry.RemoteAddr = remoteAddr.String
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,96 @@
+package tokencount
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// BPE encodings embedded in the binary
+const (
+	EncodingO200k  = "o200k_base"
+	EncodingCl100k = "cl100k_base"
+)
+
+// o200kModelPrefixes are the model families tokenized with o200k_base
+var o200kModelPrefixes = []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "gpt-oss", "chatgpt-", "codex-", "o1", "o3", "o4"}
+
+func init() {
+	// Vocabularies ship with the binary, never fetched at runtime
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+var (
+	encodersMu sync.Mutex
+	encoders   = make(map[string]*tiktoken.Tiktoken)
+)
+
+// EncodingForModel returns the BPE encoding for a model family. OpenAI's
+// current models use o200k_base; everything else, including Claude and
+// Gemini whose tokenizers are not public, is approximated with cl100k_base.
+func EncodingForModel(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, prefix := range o200kModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return EncodingO200k
+		}
+	}
+	return EncodingCl100k
+}
+
+// CountInputTokens counts the input tokens of a request with the BPE
+// vocabulary of its model family. Images and tool overhead are still
+// estimated. If the vocabulary cannot be loaded it falls back to
+// EstimateInputTokens and returns an empty encoding.
+func CountInputTokens(req *CountTokensRequest) (tokens int, encoding string) {
+	encoding = EncodingForModel(req.Model)
+	enc, err := loadEncoder(encoding)
+	if err != nil {
+		return EstimateInputTokens(req), ""
+	}
+
+	countText := func(text string) int {
+		return len(enc.EncodeOrdinary(text))
+	}
+	countJSON := func(v any) (int, bool) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return 0, false
+		}
+		return countText(string(data)), true
+	}
+	c := counter{
+		text: countText,
+		json: countJSON,
+		schema: func(schema any, _ int) int {
+			if schema == nil {
+				return 0
+			}
+			tokens, _ := countJSON(schema)
+			return tokens
+		},
+	}
+	return countInputTokens(req, c), encoding
+}
+
+// loadEncoder returns a cached encoder, parsing its vocabulary on first use
+func loadEncoder(encoding string) (*tiktoken.Tiktoken, error) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if enc, ok := encoders[encoding]; ok {
+		return enc, nil
+	}
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	encoders[encoding] = enc
+	return enc, nil
+}
@@ -0,0 +1,51 @@
+package tokencount
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodingForModel(t *testing.T) {
+	cases := map[string]string{
+		"gpt-4o-mini":       EncodingO200k,
+		"openai/gpt-5":      EncodingO200k,
+		"o3-mini":           EncodingO200k,
+		"gpt-4-turbo":       EncodingCl100k,
+		"claude-sonnet-4-5": EncodingCl100k,
+		"gemini-2.5-pro":    EncodingCl100k,
+	}
+	for model, want := range cases {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("%s: expected %s, got %s", model, want, got)
+		}
+	}
+}
+
+func TestCountInputTokensUsesBPE(t *testing.T) {
+	req := &CountTokensRequest{
+		Model:    "gpt-4o",
+		Messages: []MessageParam{{Role: "user", Content: "hello world"}},
+	}
+	tokens, encoding := CountInputTokens(req)
+	if encoding != EncodingO200k {
+		t.Fatalf("expected o200k_base, got %q", encoding)
+	}
+	// 10 request overhead + 10 message overhead + 2 tokens for "hello world"
+	if tokens != 22 {
+		t.Fatalf("expected 22 tokens, got %d", tokens)
+	}
+}
+
+func TestUnmarshalableBlockKeepsFallbackEstimate(t *testing.T) {
+	req := &CountTokensRequest{
+		Model:    "gpt-4o",
+		Messages: []MessageParam{{Role: "user", Content: []any{map[string]any{"type": "tool_use", "input": math.Inf(1)}}}},
+	}
+	// 10 request overhead + 10 message overhead + 10 for the block
+	if tokens := EstimateInputTokens(req); tokens != 30 {
+		t.Fatalf("expected the estimate to count 10 for the block, got %d", tokens)
+	}
+	if tokens, _ := CountInputTokens(req); tokens != 30 {
+		t.Fatalf("expected the BPE count to count 10 for the block, got %d", tokens)
+	}
+}
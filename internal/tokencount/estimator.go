@@ -30,21 +30,35 @@ type CountTokensResponse struct {
 
 // EstimateInputTokens estimates input tokens for a request
 func EstimateInputTokens(req *CountTokensRequest) int {
+	return countInputTokens(req, heuristicCounter)
+}
+
+// counter measures the parts of a request in tokens. The heuristic counter
+// works from character counts; a BPE counter encodes text exactly.
+type counter struct {
+	text   func(string) int
+	json   func(any) (int, bool) // False when the value cannot be marshaled
+	schema func(schema any, toolCount int) int
+}
+
+var heuristicCounter = counter{text: estimateText, json: estimateJSON, schema: estimateSchema}
+
+func countInputTokens(req *CountTokensRequest, c counter) int {
 	tokens := 10 // Base request overhead
 
 	// System prompt
 	if req.System != nil {
-		tokens += estimateAny(req.System) + 5
+		tokens += c.estimateAny(req.System) + 5
 	}
 
 	// Messages
 	for _, msg := range req.Messages {
-		tokens += 10 + estimateAny(msg.Content)
+		tokens += 10 + c.estimateAny(msg.Content)
 	}
 
 	// Tools
 	if len(req.Tools) > 0 {
-		tokens += estimateTools(req.Tools)
+		tokens += c.estimateTools(req.Tools)
 	}
 
 	return tokens
@@ -55,25 +69,23 @@ func EstimateOutputTokens(text string) int {
 	return estimateText(text)
 }
 
-func estimateAny(v any) int {
+func (c counter) estimateAny(v any) int {
 	switch val := v.(type) {
 	case string:
-		return estimateText(val)
+		return c.text(val)
 	case []any:
 		tokens := 0
 		for _, item := range val {
-			tokens += estimateBlock(item)
+			tokens += c.estimateBlock(item)
 		}
 		return tokens
 	default:
-		if data, err := json.Marshal(v); err == nil {
-			return len(data) / 4
-		}
-		return 0
+		tokens, _ := c.json(v)
+		return tokens
 	}
 }
 
-func estimateBlock(block any) int {
+func (c counter) estimateBlock(block any) int {
 	m, ok := block.(map[string]any)
 	if !ok {
 		return 10
@@ -83,7 +95,7 @@ func estimateBlock(block any) int {
 	switch blockType {
 	case "text":
 		if text, ok := m["text"].(string); ok {
-			return estimateText(text)
+			return c.text(text)
 		}
 	case "image":
 		return estimateImageBlock(m)
@@ -91,20 +103,28 @@ func estimateBlock(block any) int {
 		return 500
 	case "tool_use":
 		if input, ok := m["input"]; ok {
-			if data, err := json.Marshal(input); err == nil {
-				return len(data) / 4
+			if tokens, ok := c.json(input); ok {
+				return tokens
 			}
 		}
 	case "tool_result":
-		return estimateAny(m["content"])
+		return c.estimateAny(m["content"])
 	}
 
-	if data, err := json.Marshal(block); err == nil {
-		return len(data) / 4
+	if tokens, ok := c.json(block); ok {
+		return tokens
 	}
 	return 10
 }
 
+func estimateJSON(v any) (int, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, false
+	}
+	return len(data) / 4, true
+}
+
 func estimateText(text string) int {
 	if text == "" {
 		return 0
@@ -139,15 +159,15 @@ func estimateText(text string) int {
 	return tokens
 }
 
-func estimateTools(tools []Tool) int {
+func (c counter) estimateTools(tools []Tool) int {
 	n := len(tools)
 	base, perTool := getToolOverhead(n)
 	tokens := base
 
 	for _, tool := range tools {
 		tokens += estimateToolName(tool.Name)
-		tokens += estimateText(tool.Description)
-		tokens += estimateSchema(tool.InputSchema, n)
+		tokens += c.text(tool.Description)
+		tokens += c.schema(tool.InputSchema, n)
 		tokens += perTool
 	}
 
@@ -26,7 +26,11 @@ func estimateImageBlock(m map[string]any) int {
 	if !ok || data == "" {
 		return 1500
 	}
+	return estimateImageData(data)
+}
 
+// estimateImageData estimates tokens for base64 image data
+func estimateImageData(data string) int {
 	// Decode base64 and get image dimensions
 	width, height := getImageDimensions(data)
 	if width == 0 || height == 0 {
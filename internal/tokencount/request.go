@@ -0,0 +1,73 @@
+package tokencount
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// structuralKeys hold identifiers and enum values rather than prompt text
+var structuralKeys = map[string]bool{
+	"model":        true,
+	"role":         true,
+	"type":         true,
+	"id":           true,
+	"call_id":      true,
+	"tool_call_id": true,
+	"mimeType":     true,
+	"mime_type":    true,
+	"detail":       true,
+}
+
+// EstimateRequestTokens estimates the input tokens of a request in any
+// provider format (OpenAI Chat, Responses, Gemini) by walking its JSON.
+// Strings count as text and inline images by resolution. Claude requests
+// should use EstimateInputTokens, which also models tool overhead.
+func EstimateRequestTokens(body []byte) int {
+	var v any
+	if json.Unmarshal(body, &v) != nil {
+		return 0
+	}
+	return 10 + estimateValue("", v)
+}
+
+func estimateValue(key string, v any) int {
+	switch val := v.(type) {
+	case string:
+		if structuralKeys[key] {
+			return 0
+		}
+		if data, ok := strings.CutPrefix(val, "data:image/"); ok {
+			if comma := strings.Index(data, ","); comma >= 0 {
+				return estimateImageData(data[comma+1:])
+			}
+		}
+		return estimateText(val)
+	case []any:
+		tokens := 0
+		for _, item := range val {
+			tokens += estimateValue(key, item)
+		}
+		return tokens
+	case map[string]any:
+		// Gemini inline data: {"mimeType": "image/png", "data": "<base64>"}
+		if data, ok := val["data"].(string); ok && isImageMimeType(val) {
+			return estimateImageData(data)
+		}
+		tokens := 0
+		for k, item := range val {
+			tokens += estimateValue(k, item)
+		}
+		return tokens
+	default:
+		return 0
+	}
+}
+
+func isImageMimeType(m map[string]any) bool {
+	for _, key := range []string{"mimeType", "mime_type"} {
+		if mimeType, ok := m[key].(string); ok && strings.HasPrefix(mimeType, "image/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package cc
+
+// BedrockTransformer sends Claude Code requests to AWS Bedrock. Bedrock speaks
+// the Claude Messages format, so it behaves like the Claude passthrough; the
+// proxy moves the model into the URL and signs the request.
+type BedrockTransformer struct {
+	*ClaudeTransformer
+}
+
+// NewBedrockTransformer creates a new transformer
+func NewBedrockTransformer(model string) *BedrockTransformer {
+	return &BedrockTransformer{ClaudeTransformer: NewClaudeTransformerWithModel(model)}
+}
+
+func (t *BedrockTransformer) Name() string {
+	return "cc_bedrock"
+}
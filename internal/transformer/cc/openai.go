@@ -8,6 +8,7 @@ import (
 // OpenAITransformer transforms Claude Code requests to OpenAI Chat format
 type OpenAITransformer struct {
 	model string
+	opts  transformer.Options
 }
 
 // NewOpenAITransformer creates a new transformer
@@ -19,20 +20,25 @@ func (t *OpenAITransformer) Name() string {
 	return "cc_openai"
 }
 
+// SetOptions applies per-endpoint conversion options
+func (t *OpenAITransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
 func (t *OpenAITransformer) TransformRequest(req []byte) ([]byte, error) {
-	return convert.ClaudeReqToOpenAI(req, t.model)
+	return convert.ClaudeReqToOpenAI(req, t.model, t.opts)
 }
 
 func (t *OpenAITransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
 	if isStreaming {
 		return nil, nil
 	}
-	return convert.OpenAIRespToClaude(resp)
+	return convert.OpenAIRespToClaude(resp, t.opts)
 }
 
 func (t *OpenAITransformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
 	if isStreaming {
-		return convert.OpenAIStreamToClaude(resp, ctx)
+		return convert.OpenAIStreamToClaude(resp, ctx, t.opts)
 	}
-	return convert.OpenAIRespToClaude(resp)
+	return convert.OpenAIRespToClaude(resp, t.opts)
 }
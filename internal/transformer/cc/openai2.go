@@ -8,6 +8,7 @@ import (
 // OpenAI2Transformer transforms Claude Code requests to OpenAI Responses API format
 type OpenAI2Transformer struct {
 	model string
+	opts  transformer.Options
 }
 
 // NewOpenAI2Transformer creates a new transformer
@@ -19,8 +20,13 @@ func (t *OpenAI2Transformer) Name() string {
 	return "cc_openai2"
 }
 
+// SetOptions applies per-endpoint conversion options
+func (t *OpenAI2Transformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
 func (t *OpenAI2Transformer) TransformRequest(req []byte) ([]byte, error) {
-	return convert.ClaudeReqToOpenAI2(req, t.model)
+	return convert.ClaudeReqToOpenAI2(req, t.model, t.opts)
 }
 
 func (t *OpenAI2Transformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
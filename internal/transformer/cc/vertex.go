@@ -0,0 +1,32 @@
+package cc
+
+// VertexClaudeTransformer transforms Claude Code requests to Claude models on Google
+// Vertex AI, which use the Claude Messages format; the proxy moves the model
+// into the URL and authenticates with a Google access token
+type VertexClaudeTransformer struct {
+	*ClaudeTransformer
+}
+
+// NewVertexClaudeTransformer creates a new transformer
+func NewVertexClaudeTransformer(model string) *VertexClaudeTransformer {
+	return &VertexClaudeTransformer{ClaudeTransformer: NewClaudeTransformerWithModel(model)}
+}
+
+func (t *VertexClaudeTransformer) Name() string {
+	return "cc_vertex_claude"
+}
+
+// VertexGeminiTransformer transforms Claude Code requests to Gemini models on Google
+// Vertex AI, which use the Gemini generateContent format
+type VertexGeminiTransformer struct {
+	*GeminiTransformer
+}
+
+// NewVertexGeminiTransformer creates a new transformer
+func NewVertexGeminiTransformer(model string) *VertexGeminiTransformer {
+	return &VertexGeminiTransformer{GeminiTransformer: NewGeminiTransformer(model)}
+}
+
+func (t *VertexGeminiTransformer) Name() string {
+	return "cc_vertex_gemini"
+}
@@ -0,0 +1,285 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/cc"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+	"github.com/lich0821/ccNexus/internal/transformer/cx/chat"
+	"github.com/lich0821/ccNexus/internal/transformer/cx/responses"
+	"github.com/lich0821/ccNexus/internal/transformer/ge"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the current transformers")
+
+// model is the endpoint model every transformer is created with
+const model = "conformance-model"
+
+// Cases every client and every provider must have fixtures for
+var (
+	requestCases  = []string{"text", "thinking", "tools", "image"}
+	upstreamCases = []string{"text", "thinking", "parallel_tools", "max_tokens"}
+)
+
+// pair is a transformer with the fixtures it reads: client requests from
+// testdata/requests/<client> and upstream responses from
+// testdata/upstream/<upstream>
+type pair struct {
+	client   string
+	upstream string
+	new      func() transformer.Transformer
+}
+
+var pairs = []pair{
+	{"claude", "claude", func() transformer.Transformer { return cc.NewClaudeTransformerWithModel(model) }},
+	{"claude", "openai", func() transformer.Transformer { return cc.NewOpenAITransformer(model) }},
+	{"claude", "openai2", func() transformer.Transformer { return cc.NewOpenAI2Transformer(model) }},
+	{"claude", "gemini", func() transformer.Transformer { return cc.NewGeminiTransformer(model) }},
+	{"claude", "ollama", func() transformer.Transformer { return cc.NewOllamaTransformer(model) }},
+	{"claude", "claude", func() transformer.Transformer { return cc.NewBedrockTransformer(model) }},
+	{"claude", "claude", func() transformer.Transformer { return cc.NewVertexClaudeTransformer(model) }},
+	{"claude", "gemini", func() transformer.Transformer { return cc.NewVertexGeminiTransformer(model) }},
+
+	{"openai_chat", "claude", func() transformer.Transformer { return chat.NewClaudeTransformer(model) }},
+	{"openai_chat", "openai", func() transformer.Transformer { return chat.NewOpenAITransformer(model) }},
+	{"openai_chat", "openai2", func() transformer.Transformer { return chat.NewOpenAI2Transformer(model) }},
+	{"openai_chat", "gemini", func() transformer.Transformer { return chat.NewGeminiTransformer(model) }},
+	{"openai_chat", "ollama", func() transformer.Transformer { return chat.NewOllamaTransformer(model) }},
+	{"openai_chat", "claude", func() transformer.Transformer { return chat.NewBedrockTransformer(model) }},
+	{"openai_chat", "claude", func() transformer.Transformer { return chat.NewVertexClaudeTransformer(model) }},
+	{"openai_chat", "gemini", func() transformer.Transformer { return chat.NewVertexGeminiTransformer(model) }},
+
+	{"openai_responses", "claude", func() transformer.Transformer { return responses.NewClaudeTransformer(model) }},
+	{"openai_responses", "openai", func() transformer.Transformer { return responses.NewOpenAITransformer(model) }},
+	{"openai_responses", "openai2", func() transformer.Transformer { return responses.NewOpenAI2Transformer(model) }},
+	{"openai_responses", "gemini", func() transformer.Transformer { return responses.NewGeminiTransformer(model) }},
+	{"openai_responses", "ollama", func() transformer.Transformer { return responses.NewOllamaTransformer(model) }},
+	{"openai_responses", "claude", func() transformer.Transformer { return responses.NewBedrockTransformer(model) }},
+	{"openai_responses", "claude", func() transformer.Transformer { return responses.NewVertexClaudeTransformer(model) }},
+	{"openai_responses", "gemini", func() transformer.Transformer { return responses.NewVertexGeminiTransformer(model) }},
+
+	{"gemini", "claude", func() transformer.Transformer { return ge.NewClaudeTransformer(model) }},
+	{"gemini", "openai", func() transformer.Transformer { return ge.NewOpenAITransformer(model) }},
+	{"gemini", "openai2", func() transformer.Transformer { return ge.NewOpenAI2Transformer(model) }},
+	{"gemini", "gemini", func() transformer.Transformer { return ge.NewGeminiTransformer(model) }},
+	{"gemini", "ollama", func() transformer.Transformer { return ge.NewOllamaTransformer(model) }},
+	{"gemini", "claude", func() transformer.Transformer { return ge.NewBedrockTransformer(model) }},
+	{"gemini", "claude", func() transformer.Transformer { return ge.NewVertexClaudeTransformer(model) }},
+	{"gemini", "gemini", func() transformer.Transformer { return ge.NewVertexGeminiTransformer(model) }},
+}
+
+func TestConformance(t *testing.T) {
+	for _, p := range pairs {
+		p := p
+		name := p.new().Name()
+		t.Run(name, func(t *testing.T) {
+			golden := filepath.Join("testdata", "golden", name)
+			got := make(map[string][]byte)
+
+			for _, file := range fixtures(t, filepath.Join("testdata", "requests", p.client)) {
+				out, err := p.new().TransformRequest(readFile(t, file))
+				got["request_"+filepath.Base(file)] = result(out, err, true)
+			}
+			for _, file := range fixtures(t, filepath.Join("testdata", "upstream", p.upstream)) {
+				base := filepath.Base(file)
+				ext := filepath.Ext(base)
+				caseName := strings.TrimSuffix(base, ext)
+				if ext == ".json" {
+					out, err := p.new().TransformResponse(readFile(t, file), false)
+					got["response_"+base] = result(out, err, true)
+					continue
+				}
+				out, err := replayStream(p.new(), readFile(t, file), ext == ".ndjson")
+				got["stream_"+caseName+".sse"] = result(out, err, false)
+			}
+
+			if *update {
+				writeGolden(t, golden, got)
+				return
+			}
+			compareGolden(t, golden, got)
+		})
+	}
+}
+
+// TestFixtureCoverage keeps every client and provider covering every case
+func TestFixtureCoverage(t *testing.T) {
+	clients := make(map[string]bool)
+	upstreams := make(map[string]bool)
+	for _, p := range pairs {
+		clients[p.client] = true
+		upstreams[p.upstream] = true
+	}
+	for client := range clients {
+		for _, c := range requestCases {
+			if _, err := os.Stat(filepath.Join("testdata", "requests", client, c+".json")); err != nil {
+				t.Errorf("client %s has no %s request: %v", client, c, err)
+			}
+		}
+	}
+	for upstream := range upstreams {
+		dir := filepath.Join("testdata", "upstream", upstream)
+		for _, c := range upstreamCases {
+			if _, err := os.Stat(filepath.Join(dir, c+".json")); err != nil {
+				t.Errorf("provider %s has no %s response: %v", upstream, c, err)
+			}
+			sse, sseErr := os.Stat(filepath.Join(dir, c+".sse"))
+			ndjson, ndjsonErr := os.Stat(filepath.Join(dir, c+".ndjson"))
+			if (sseErr == nil && sse != nil) == (ndjsonErr == nil && ndjson != nil) {
+				t.Errorf("provider %s needs exactly one %s stream (.sse or .ndjson)", upstream, c)
+			}
+		}
+	}
+}
+
+// replayStream feeds a recorded stream to the transformer one SSE event at a
+// time, as the proxy's streaming loop does
+func replayStream(trans transformer.Transformer, stream []byte, ndjson bool) ([]byte, error) {
+	if ndjson {
+		state := convert.NewOllamaStreamState()
+		var sse bytes.Buffer
+		for _, line := range bytes.Split(stream, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				sse.Write(state.Convert(line))
+			}
+		}
+		stream = sse.Bytes()
+	}
+
+	ctx := transformer.NewStreamContext()
+	ctx.ModelName = model
+	var out bytes.Buffer
+	for _, event := range strings.SplitAfter(string(stream), "\n\n") {
+		if strings.TrimSpace(event) == "" {
+			continue
+		}
+		converted, err := trans.TransformResponseWithContext([]byte(event), true, ctx)
+		if err != nil {
+			return out.Bytes(), err
+		}
+		out.Write(converted)
+	}
+	return out.Bytes(), nil
+}
+
+// Values that differ between runs
+var volatile = regexp.MustCompile(`"created":\s*\d+`)
+
+// result renders a transformer's output for a golden file. JSON is indented
+// so golden diffs stay readable; errors are part of the contract too.
+func result(out []byte, err error, indent bool) []byte {
+	if err != nil {
+		return []byte("error: " + err.Error() + "\n")
+	}
+	out = volatile.ReplaceAll(out, []byte(`"created":0`))
+	if indent {
+		var buf bytes.Buffer
+		if json.Indent(&buf, out, "", "  ") == nil {
+			out = buf.Bytes()
+		}
+	}
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return out
+}
+
+func writeGolden(t *testing.T, dir string, got map[string][]byte) {
+	t.Helper()
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range got {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func compareGolden(t *testing.T, dir string, got map[string][]byte) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("no golden files, run go generate ./internal/transformer/conformance: %v", err)
+	}
+	for _, entry := range entries {
+		if _, ok := got[entry.Name()]; !ok {
+			t.Errorf("%s: golden file has no fixture, regenerate the golden files", entry.Name())
+		}
+	}
+	for _, name := range sortedKeys(got) {
+		want, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s: missing golden file, regenerate the golden files", name)
+			continue
+		}
+		if !bytes.Equal(got[name], want) {
+			t.Errorf("%s differs from the golden file:\n%s", name, diff(string(want), string(got[name])))
+		}
+	}
+}
+
+// diff shows the first differing line with some context
+func diff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, w, g)
+		}
+	}
+	return ""
+}
+
+func fixtures(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
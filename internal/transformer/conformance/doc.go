@@ -0,0 +1,18 @@
+// Package conformance holds the golden-file suite every transformer pair
+// must pass. It has no code of its own; the suite lives in its tests.
+//
+// testdata/requests/<client> holds client requests and
+// testdata/upstream/<provider> recorded upstream responses: <case>.json for
+// non-streaming responses, <case>.sse for SSE streams and <case>.ndjson for
+// NDJSON streams, which are adapted to SSE the way the proxy does. For each
+// transformer the suite converts every request and replays every response of
+// its provider, comparing the results with testdata/golden/<transformer>.
+//
+// Adding a provider means adding its upstream fixtures for the cases in
+// upstreamCases and its transformers to the pairs table, then regenerating
+// the golden files and reviewing them:
+//
+//	go generate ./internal/transformer/conformance
+package conformance
+
+//go:generate go test -run TestConformance -update .
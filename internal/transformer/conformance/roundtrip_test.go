@@ -0,0 +1,527 @@
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/cc"
+	"github.com/lich0821/ccNexus/internal/transformer/cx/chat"
+	"github.com/lich0821/ccNexus/internal/transformer/cx/responses"
+	"github.com/lich0821/ccNexus/internal/transformer/ge"
+)
+
+// format is one wire format with the transformers leaving it for the others:
+// out[B] takes this format's requests to B, and converts B's responses back
+type format struct {
+	requests string // testdata/requests/<requests>
+	upstream string // testdata/upstream/<upstream>
+	request  func(t *testing.T, body []byte) semantics
+	response func(t *testing.T, body []byte) semantics
+	out      map[string]func() transformer.Transformer
+	// reasoning is false when responses can't carry reasoning back to the
+	// client; Chat Completions only has it as a vendor extension
+	reasoning bool
+}
+
+var formats = map[string]format{
+	"claude": {
+		requests: "claude", upstream: "claude",
+		request: claudeRequest, response: claudeResponse,
+		reasoning: true,
+		out: map[string]func() transformer.Transformer{
+			"openai":  func() transformer.Transformer { return cc.NewOpenAITransformer(model) },
+			"openai2": func() transformer.Transformer { return cc.NewOpenAI2Transformer(model) },
+			"gemini":  func() transformer.Transformer { return cc.NewGeminiTransformer(model) },
+		},
+	},
+	"openai": {
+		requests: "openai_chat", upstream: "openai",
+		request: openAIRequest, response: openAIResponse,
+		out: map[string]func() transformer.Transformer{
+			"claude":  func() transformer.Transformer { return chat.NewClaudeTransformer(model) },
+			"openai2": func() transformer.Transformer { return chat.NewOpenAI2Transformer(model) },
+			"gemini":  func() transformer.Transformer { return chat.NewGeminiTransformer(model) },
+		},
+	},
+	"openai2": {
+		requests: "openai_responses", upstream: "openai2",
+		request: responsesRequest, response: responsesResponse,
+		reasoning: true,
+		out: map[string]func() transformer.Transformer{
+			"claude": func() transformer.Transformer { return responses.NewClaudeTransformer(model) },
+			"openai": func() transformer.Transformer { return responses.NewOpenAITransformer(model) },
+			"gemini": func() transformer.Transformer { return responses.NewGeminiTransformer(model) },
+		},
+	},
+	"gemini": {
+		requests: "gemini", upstream: "gemini",
+		request: geminiRequest, response: geminiResponse,
+		reasoning: true,
+		out: map[string]func() transformer.Transformer{
+			"claude":  func() transformer.Transformer { return ge.NewClaudeTransformer(model) },
+			"openai":  func() transformer.Transformer { return ge.NewOpenAITransformer(model) },
+			"openai2": func() transformer.Transformer { return ge.NewOpenAI2Transformer(model) },
+		},
+	},
+}
+
+// TestRequestRoundTrip converts each client request A→B and back B→A and
+// checks the conversation means the same: system prompt, text, images, tool
+// calls and which call each tool result answers.
+func TestRequestRoundTrip(t *testing.T) {
+	for _, a := range sortedFormats() {
+		for _, b := range sortedFormats() {
+			if a == b {
+				continue
+			}
+			fa, fb := formats[a], formats[b]
+			t.Run(a+"->"+b, func(t *testing.T) {
+				for _, file := range fixtures(t, filepath.Join("testdata", "requests", fa.requests)) {
+					original := readFile(t, file)
+					there, err := fa.out[b]().TransformRequest(original)
+					if err != nil {
+						t.Fatalf("%s: %s→%s: %v", filepath.Base(file), a, b, err)
+					}
+					back, err := fb.out[a]().TransformRequest(there)
+					if err != nil {
+						t.Fatalf("%s: %s→%s: %v", filepath.Base(file), b, a, err)
+					}
+					assertSameSemantics(t, filepath.Base(file), fa.request(t, original), fa.request(t, back))
+				}
+			})
+		}
+	}
+}
+
+// TestResponseRoundTrip converts each upstream response A→B and back B→A and
+// checks it means the same: reasoning, text, tool calls, stop reason and usage.
+func TestResponseRoundTrip(t *testing.T) {
+	for _, a := range sortedFormats() {
+		for _, b := range sortedFormats() {
+			if a == b {
+				continue
+			}
+			fa, fb := formats[a], formats[b]
+			t.Run(a+"->"+b, func(t *testing.T) {
+				for _, file := range fixtures(t, filepath.Join("testdata", "upstream", fa.upstream)) {
+					if filepath.Ext(file) != ".json" {
+						continue
+					}
+					original := readFile(t, file)
+					// A response of format A reaches a client of format B
+					// through B's transformer to A, and back the other way
+					there, err := fb.out[a]().TransformResponse(original, false)
+					if err != nil {
+						t.Fatalf("%s: %s→%s: %v", filepath.Base(file), a, b, err)
+					}
+					back, err := fa.out[b]().TransformResponse(there, false)
+					if err != nil {
+						t.Fatalf("%s: %s→%s: %v", filepath.Base(file), b, a, err)
+					}
+					want := fa.response(t, original)
+					if !fa.reasoning || !fb.reasoning {
+						want = want.without("thinking")
+					}
+					assertSameSemantics(t, filepath.Base(file), want, fa.response(t, back))
+				}
+			})
+		}
+	}
+}
+
+// semantics is what a request or response means, independent of how its
+// format splits it into messages and blocks
+type semantics struct {
+	System string
+	Items  []item
+	Stop   string
+	Usage  [2]int // Input and output tokens
+}
+
+// item is one piece of content with the role that produced it
+type item struct {
+	Role string
+	Kind string // text, thinking, image, call, result
+	Text string // Text, image data URL, call name or the result's call name
+	Data string // Canonical call arguments or result content
+}
+
+func (s *semantics) add(role, kind, text, data string) {
+	if kind == "text" || kind == "thinking" {
+		if text == "" {
+			return
+		}
+		// Formats differ in how they split text into blocks
+		if n := len(s.Items); n > 0 && s.Items[n-1].Role == role && s.Items[n-1].Kind == kind {
+			s.Items[n-1].Text += text
+			return
+		}
+	}
+	s.Items = append(s.Items, item{Role: role, Kind: kind, Text: text, Data: data})
+}
+
+// without returns the semantics with items of a kind left out
+func (s semantics) without(kind string) semantics {
+	out := semantics{System: s.System, Stop: s.Stop, Usage: s.Usage}
+	for _, it := range s.Items {
+		if it.Kind != kind {
+			out.add(it.Role, it.Kind, it.Text, it.Data)
+		}
+	}
+	return out
+}
+
+func assertSameSemantics(t *testing.T, name string, want, got semantics) {
+	t.Helper()
+	if !reflect.DeepEqual(want, got) {
+		wantJSON, _ := json.MarshalIndent(want, "", "  ")
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("%s: round trip changed the meaning\nwant %s\n got %s", name, wantJSON, gotJSON)
+	}
+}
+
+func claudeRequest(t *testing.T, body []byte) semantics {
+	var req struct {
+		System   interface{} `json:"system"`
+		Messages []struct {
+			Role    string      `json:"role"`
+			Content interface{} `json:"content"`
+		} `json:"messages"`
+	}
+	decode(t, body, &req)
+
+	var s semantics
+	s.System = joinText(req.System)
+	calls := make(map[string]string)
+	for _, msg := range req.Messages {
+		for _, block := range blocks(msg.Content) {
+			switch block["type"] {
+			case "text":
+				s.add(msg.Role, "text", str(block["text"]), "")
+			case "image":
+				source, _ := block["source"].(map[string]interface{})
+				s.add(msg.Role, "image", "data:"+str(source["media_type"])+";base64,"+str(source["data"]), "")
+			case "tool_use":
+				calls[str(block["id"])] = str(block["name"])
+				s.add(msg.Role, "call", str(block["name"]), canonical(block["input"]))
+			case "tool_result":
+				s.add(msg.Role, "result", calls[str(block["tool_use_id"])], joinText(block["content"]))
+			}
+		}
+	}
+	return s
+}
+
+func claudeResponse(t *testing.T, body []byte) semantics {
+	var resp struct {
+		Content    []map[string]interface{} `json:"content"`
+		StopReason string                   `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	decode(t, body, &resp)
+
+	s := semantics{Stop: resp.StopReason, Usage: [2]int{resp.Usage.InputTokens, resp.Usage.OutputTokens}}
+	for _, block := range resp.Content {
+		switch block["type"] {
+		case "thinking":
+			s.add("assistant", "thinking", str(block["thinking"]), "")
+		case "text":
+			s.add("assistant", "text", str(block["text"]), "")
+		case "tool_use":
+			s.add("assistant", "call", str(block["name"]), canonical(block["input"]))
+		}
+	}
+	return s
+}
+
+func openAIRequest(t *testing.T, body []byte) semantics {
+	var req struct {
+		Messages []struct {
+			Role       string      `json:"role"`
+			Content    interface{} `json:"content"`
+			ToolCallID string      `json:"tool_call_id"`
+			ToolCalls  []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"messages"`
+	}
+	decode(t, body, &req)
+
+	var s semantics
+	calls := make(map[string]string)
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			s.System += joinText(msg.Content)
+			continue
+		case "tool":
+			s.add("tool", "result", calls[msg.ToolCallID], joinText(msg.Content))
+			continue
+		}
+		for _, block := range blocks(msg.Content) {
+			switch block["type"] {
+			case "text":
+				s.add(msg.Role, "text", str(block["text"]), "")
+			case "image_url":
+				image, _ := block["image_url"].(map[string]interface{})
+				s.add(msg.Role, "image", str(image["url"]), "")
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			calls[call.ID] = call.Function.Name
+			s.add(msg.Role, "call", call.Function.Name, canonicalString(call.Function.Arguments))
+		}
+	}
+	return s
+}
+
+func openAIResponse(t *testing.T, body []byte) semantics {
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content          interface{} `json:"content"`
+				ReasoningContent string      `json:"reasoning_content"`
+				ToolCalls        []struct {
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	decode(t, body, &resp)
+	if len(resp.Choices) == 0 {
+		t.Fatalf("response has no choices: %s", body)
+	}
+
+	choice := resp.Choices[0]
+	s := semantics{Stop: choice.FinishReason, Usage: [2]int{resp.Usage.PromptTokens, resp.Usage.CompletionTokens}}
+	s.add("assistant", "thinking", choice.Message.ReasoningContent, "")
+	s.add("assistant", "text", joinText(choice.Message.Content), "")
+	for _, call := range choice.Message.ToolCalls {
+		s.add("assistant", "call", call.Function.Name, canonicalString(call.Function.Arguments))
+	}
+	return s
+}
+
+func responsesRequest(t *testing.T, body []byte) semantics {
+	var req struct {
+		Instructions string        `json:"instructions"`
+		Input        []interface{} `json:"input"`
+	}
+	decode(t, body, &req)
+
+	s := semantics{System: req.Instructions}
+	calls := make(map[string]string)
+	for _, raw := range req.Input {
+		entry, _ := raw.(map[string]interface{})
+		switch str(entry["type"]) {
+		case "function_call":
+			calls[str(entry["call_id"])] = str(entry["name"])
+			s.add("assistant", "call", str(entry["name"]), canonicalString(str(entry["arguments"])))
+		case "function_call_output":
+			s.add("tool", "result", calls[str(entry["call_id"])], joinText(entry["output"]))
+		case "", "message":
+			role := str(entry["role"])
+			if role == "system" || role == "developer" {
+				s.System += joinText(entry["content"])
+				continue
+			}
+			for _, block := range blocks(entry["content"]) {
+				switch block["type"] {
+				case "text", "input_text", "output_text":
+					s.add(role, "text", str(block["text"]), "")
+				case "input_image":
+					s.add(role, "image", str(block["image_url"]), "")
+				}
+			}
+		}
+	}
+	return s
+}
+
+func responsesResponse(t *testing.T, body []byte) semantics {
+	var resp struct {
+		Status            string                   `json:"status"`
+		Output            []map[string]interface{} `json:"output"`
+		IncompleteDetails *struct {
+			Reason string `json:"reason"`
+		} `json:"incomplete_details"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	decode(t, body, &resp)
+
+	s := semantics{Stop: resp.Status, Usage: [2]int{resp.Usage.InputTokens, resp.Usage.OutputTokens}}
+	if resp.IncompleteDetails != nil {
+		s.Stop += ":" + resp.IncompleteDetails.Reason
+	}
+	for _, entry := range resp.Output {
+		switch entry["type"] {
+		case "reasoning":
+			s.add("assistant", "thinking", joinText(entry["summary"]), "")
+		case "message":
+			s.add("assistant", "text", joinText(entry["content"]), "")
+		case "function_call":
+			s.add("assistant", "call", str(entry["name"]), canonicalString(str(entry["arguments"])))
+		}
+	}
+	return s
+}
+
+func geminiRequest(t *testing.T, body []byte) semantics {
+	var req transformer.GeminiRequest
+	decode(t, body, &req)
+
+	var s semantics
+	if req.SystemInstruction != nil {
+		for _, part := range req.SystemInstruction.Parts {
+			s.System += part.Text
+		}
+	}
+	for _, content := range req.Contents {
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				s.add(content.Role, "call", part.FunctionCall.Name, canonical(part.FunctionCall.Args))
+			case part.FunctionResponse != nil:
+				s.add(content.Role, "result", part.FunctionResponse.Name, joinText(part.FunctionResponse.Response))
+			case part.InlineData != nil:
+				s.add(content.Role, "image", "data:"+part.InlineData.MimeType+";base64,"+part.InlineData.Data, "")
+			case !part.Thought:
+				s.add(content.Role, "text", part.Text, "")
+			}
+		}
+	}
+	return s
+}
+
+func geminiResponse(t *testing.T, body []byte) semantics {
+	var resp transformer.GeminiResponse
+	decode(t, body, &resp)
+	if len(resp.Candidates) == 0 {
+		t.Fatalf("response has no candidates: %s", body)
+	}
+
+	candidate := resp.Candidates[0]
+	s := semantics{Stop: candidate.FinishReason}
+	if resp.UsageMetadata != nil {
+		s.Usage = [2]int{resp.UsageMetadata.PromptTokenCount, resp.UsageMetadata.CandidatesTokenCount}
+	}
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			s.add("model", "call", part.FunctionCall.Name, canonical(part.FunctionCall.Args))
+		case part.Thought:
+			s.add("model", "thinking", part.Text, "")
+		default:
+			s.add("model", "text", part.Text, "")
+		}
+	}
+	return s
+}
+
+func decode(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, body)
+	}
+}
+
+// blocks returns content as blocks, a plain string being one text block
+func blocks(content interface{}) []map[string]interface{} {
+	switch c := content.(type) {
+	case string:
+		return []map[string]interface{}{{"type": "text", "text": c}}
+	case []interface{}:
+		var out []map[string]interface{}
+		for _, raw := range c {
+			if block, ok := raw.(map[string]interface{}); ok {
+				out = append(out, block)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// joinText concatenates the strings inside content, whatever its shape
+func joinText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []interface{}:
+		var parts []string
+		for _, v := range c {
+			parts = append(parts, joinText(v))
+		}
+		return strings.Join(parts, "")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(c))
+		for key := range c {
+			if key != "type" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		var parts []string
+		for _, key := range keys {
+			parts = append(parts, joinText(c[key]))
+		}
+		return strings.Join(parts, "")
+	}
+	return ""
+}
+
+func canonical(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func canonicalString(raw string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return raw
+	}
+	return canonical(v)
+}
+
+func str(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedFormats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
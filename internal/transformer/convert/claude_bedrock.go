@@ -0,0 +1,54 @@
+package convert
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// BedrockAnthropicVersion is the anthropic_version Bedrock expects in request bodies
+const BedrockAnthropicVersion = "bedrock-2023-05-31"
+
+// bedrockBetas lists the anthropic-beta flags Bedrock accepts; unknown flags
+// are rejected with a validation error, so everything else is dropped
+var bedrockBetas = map[string]bool{
+	"computer-use-2024-10-22":                true,
+	"computer-use-2025-01-24":                true,
+	"token-efficient-tools-2025-02-19":       true,
+	"interleaved-thinking-2025-05-14":        true,
+	"output-128k-2025-02-19":                 true,
+	"context-1m-2025-08-07":                  true,
+	"context-management-2025-06-27":          true,
+	"fine-grained-tool-streaming-2025-05-14": true,
+}
+
+// ClaudeReqToBedrock adapts a Claude Messages request for Bedrock InvokeModel.
+// Bedrock takes the model from the URL and selects streaming by operation, so
+// both fields are removed; supported beta flags move from the anthropic-beta
+// header into the body. It reports whether the request asked for streaming.
+func ClaudeReqToBedrock(claudeReq []byte, betaHeader string) ([]byte, bool, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(claudeReq, &req); err != nil {
+		return nil, false, err
+	}
+
+	stream, _ := req["stream"].(bool)
+	delete(req, "model")
+	delete(req, "stream")
+	if _, ok := req["anthropic_version"]; !ok {
+		req["anthropic_version"] = BedrockAnthropicVersion
+	}
+
+	var betas []string
+	for _, beta := range strings.Split(betaHeader, ",") {
+		beta = strings.TrimSpace(beta)
+		if bedrockBetas[beta] {
+			betas = append(betas, beta)
+		}
+	}
+	if len(betas) > 0 {
+		req["anthropic_beta"] = betas
+	}
+
+	out, err := json.Marshal(req)
+	return out, stream, err
+}
@@ -6,10 +6,11 @@ import (
 	"strings"
 
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/schema"
 )
 
 // ClaudeReqToGemini converts Claude request to Gemini request
-func ClaudeReqToGemini(claudeReq []byte, model string) ([]byte, error) {
+func ClaudeReqToGemini(claudeReq []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.ClaudeRequest
 	if err := json.Unmarshal(claudeReq, &req); err != nil {
 		return nil, err
@@ -41,7 +42,11 @@ func ClaudeReqToGemini(claudeReq []byte, model string) ([]byte, error) {
 		case string:
 			parts = append(parts, map[string]interface{}{"text": content})
 		case []interface{}:
-			parts = convertClaudeContentToGeminiParts(content, toolUseIDToName)
+			converted, err := convertClaudeContentToGeminiParts(content, toolUseIDToName)
+			if err != nil {
+				return nil, err
+			}
+			parts = converted
 		}
 
 		contents = append(contents, map[string]interface{}{"role": role, "parts": parts})
@@ -56,6 +61,10 @@ func ClaudeReqToGemini(claudeReq []byte, model string) ([]byte, error) {
 	if req.Temperature > 0 {
 		genConfig["temperature"] = req.Temperature
 	}
+	if thinkingConfig := geminiThinkingConfig(reasoningFromClaude(req.Thinking), opts.Reasoning); thinkingConfig != nil {
+		genConfig["thinkingConfig"] = thinkingConfig
+	}
+	applyOutputFormatToGemini(genConfig, outputFormatFromClaude(req.OutputFormat))
 	if len(genConfig) > 0 {
 		geminiReq["generationConfig"] = genConfig
 	}
@@ -67,23 +76,19 @@ func ClaudeReqToGemini(claudeReq []byte, model string) ([]byte, error) {
 			funcDecls = append(funcDecls, map[string]interface{}{
 				"name":        tool.Name,
 				"description": tool.Description,
-				"parameters":  cleanSchemaForGemini(tool.InputSchema),
+				"parameters":  normalizeToolSchema(tool.Name, tool.InputSchema, schema.ProfileGemini),
 			})
 		}
 		geminiReq["tools"] = []map[string]interface{}{{"functionDeclarations": funcDecls}}
 		// Add toolConfig to enable function calling
-		geminiReq["toolConfig"] = map[string]interface{}{
-			"functionCallingConfig": map[string]interface{}{
-				"mode": "AUTO",
-			},
-		}
+		geminiReq["toolConfig"] = toolChoiceFromClaude(req.ToolChoice).geminiToolConfig()
 	}
 
 	return json.Marshal(geminiReq)
 }
 
 // GeminiReqToClaude converts Gemini request to Claude request
-func GeminiReqToClaude(geminiReq []byte, model string) ([]byte, error) {
+func GeminiReqToClaude(geminiReq []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.GeminiRequest
 	if err := json.Unmarshal(geminiReq, &req); err != nil {
 		return nil, err
@@ -93,6 +98,9 @@ func GeminiReqToClaude(geminiReq []byte, model string) ([]byte, error) {
 		"model":      model,
 		"max_tokens": 8192,
 	}
+	if req.Stream {
+		claudeReq["stream"] = true
+	}
 
 	// Convert system instruction
 	if req.SystemInstruction != nil && len(req.SystemInstruction.Parts) > 0 {
@@ -109,6 +117,7 @@ func GeminiReqToClaude(geminiReq []byte, model string) ([]byte, error) {
 
 	// Convert contents to messages
 	var messages []map[string]interface{}
+	callIDs := newGeminiCallIDs()
 	for _, content := range req.Contents {
 		role := content.Role
 		if role == "model" {
@@ -131,19 +140,42 @@ func GeminiReqToClaude(geminiReq []byte, model string) ([]byte, error) {
 				contentBlocks = append(contentBlocks, map[string]interface{}{"type": "text", "text": part.Text})
 			}
 			if part.FunctionCall != nil {
+				input := part.FunctionCall.Args
+				if input == nil {
+					input = map[string]interface{}{}
+				}
 				contentBlocks = append(contentBlocks, map[string]interface{}{
 					"type":  "tool_use",
-					"id":    fmt.Sprintf("call_%s", part.FunctionCall.Name),
+					"id":    callIDs.call(part.FunctionCall),
 					"name":  part.FunctionCall.Name,
-					"input": part.FunctionCall.Args,
+					"input": input,
 				})
 			}
 			if part.FunctionResponse != nil {
-				contentBlocks = append(contentBlocks, map[string]interface{}{
+				block := map[string]interface{}{
 					"type":        "tool_result",
-					"tool_use_id": fmt.Sprintf("call_%s", part.FunctionResponse.Name),
-					"content":     part.FunctionResponse.Response,
-				})
+					"tool_use_id": callIDs.response(part.FunctionResponse),
+					"content":     geminiFunctionResponseText(part.FunctionResponse.Response),
+				}
+				// Gemini reports failed calls as {"error": ...}
+				if errText, ok := part.FunctionResponse.Response["error"].(string); ok && len(part.FunctionResponse.Response) == 1 {
+					block["content"] = errText
+					block["is_error"] = true
+				}
+				contentBlocks = append(contentBlocks, block)
+			}
+			if part.InlineData != nil || part.FileData != nil {
+				var media *mediaPart
+				if part.InlineData != nil {
+					media = mediaFromGemini(part.InlineData.MimeType, part.InlineData.Data, "")
+				} else {
+					media = mediaFromGemini(part.FileData.MimeType, "", part.FileData.FileURI)
+				}
+				block, err := media.toClaude()
+				if err != nil {
+					return nil, err
+				}
+				contentBlocks = append(contentBlocks, block)
 			}
 		}
 
@@ -164,6 +196,7 @@ func GeminiReqToClaude(geminiReq []byte, model string) ([]byte, error) {
 			claudeReq["temperature"] = *req.GenerationConfig.Temperature
 		}
 	}
+	applyReasoningToClaude(claudeReq, reasoningFromGemini(req.GenerationConfig), opts.Reasoning)
 
 	// Convert tools
 	if len(req.Tools) > 0 {
@@ -173,14 +206,18 @@ func GeminiReqToClaude(geminiReq []byte, model string) ([]byte, error) {
 				tools = append(tools, map[string]interface{}{
 					"name":         fd.Name,
 					"description":  fd.Description,
-					"input_schema": fd.Parameters,
+					"input_schema": normalizeToolSchema(fd.Name, geminiDeclarationSchema(fd), schema.ProfileClaude),
 				})
 			}
 		}
 		if len(tools) > 0 {
 			claudeReq["tools"] = tools
+			if choice := toolChoiceFromGemini(req.ToolConfig).claude(); choice != nil {
+				claudeReq["tool_choice"] = choice
+			}
 		}
 	}
+	applyOutputFormatToClaude(claudeReq, outputFormatFromGemini(req.GenerationConfig))
 
 	return json.Marshal(claudeReq)
 }
@@ -188,7 +225,7 @@ func GeminiReqToClaude(geminiReq []byte, model string) ([]byte, error) {
 // ClaudeRespToGemini converts Claude response to Gemini response
 func ClaudeRespToGemini(claudeResp []byte) ([]byte, error) {
 	var resp transformer.ClaudeResponse
-	if err := json.Unmarshal(claudeResp, &resp); err != nil {
+	if err := json.Unmarshal(unwrapStructuredOutputResponse(claudeResp), &resp); err != nil {
 		return nil, err
 	}
 
@@ -214,6 +251,7 @@ func ClaudeRespToGemini(claudeResp []byte) ([]byte, error) {
 		case "tool_use":
 			parts = append(parts, map[string]interface{}{
 				"functionCall": map[string]interface{}{
+					"id":   blockMap["id"],
 					"name": blockMap["name"],
 					"args": blockMap["input"],
 				},
@@ -221,23 +259,18 @@ func ClaudeRespToGemini(claudeResp []byte) ([]byte, error) {
 		}
 	}
 
-	finishReason := "STOP"
-	if resp.StopReason == "tool_use" {
-		finishReason = "TOOL_CODE"
-	}
-
 	geminiResp := map[string]interface{}{
 		"candidates": []map[string]interface{}{
 			{
 				"content":      map[string]interface{}{"role": "model", "parts": parts},
-				"finishReason": finishReason,
+				"finishReason": geminiFinishReason(resp.StopReason),
+				"index":        0,
 			},
 		},
-		"usageMetadata": map[string]interface{}{
-			"promptTokenCount":     resp.Usage.InputTokens,
-			"candidatesTokenCount": resp.Usage.OutputTokens,
-			"totalTokenCount":      resp.Usage.InputTokens + resp.Usage.OutputTokens,
-		},
+		"usageMetadata": geminiUsageMetadata(resp.Usage.InputTokens, resp.Usage.OutputTokens),
+	}
+	if resp.Model != "" {
+		geminiResp["modelVersion"] = resp.Model
 	}
 
 	return json.Marshal(geminiResp)
@@ -279,6 +312,7 @@ func GeminiRespToClaude(geminiResp []byte) ([]byte, error) {
 				stopReason = "tool_use"
 			}
 		}
+		stopReason = claudeStopReason(candidate.FinishReason, stopReason == "tool_use")
 	}
 
 	var inputTokens, outputTokens int
@@ -302,8 +336,11 @@ func GeminiRespToClaude(geminiResp []byte) ([]byte, error) {
 	return json.Marshal(claudeResp)
 }
 
-// ClaudeStreamToGemini converts Claude SSE event to Gemini stream format
+// ClaudeStreamToGemini converts Claude SSE event to Gemini stream format.
+// Tool calls are emitted whole once their input is complete, and the final
+// chunk carries the finish reason and usage as Gemini does.
 func ClaudeStreamToGemini(event []byte, ctx *transformer.StreamContext) ([]byte, error) {
+	event = unwrapStructuredOutputEvent(event, ctx)
 	eventType, jsonData := parseSSE(event)
 	if jsonData == "" {
 		return nil, nil
@@ -315,24 +352,77 @@ func ClaudeStreamToGemini(event []byte, ctx *transformer.StreamContext) ([]byte,
 	}
 
 	switch eventType {
+	case "message_start":
+		if msg, ok := data["message"].(map[string]interface{}); ok {
+			if usage, ok := msg["usage"].(map[string]interface{}); ok {
+				if input, _ := usage["input_tokens"].(float64); input > 0 {
+					ctx.InputTokens = int(input)
+				}
+			}
+		}
+
+	case "content_block_start":
+		block, _ := data["content_block"].(map[string]interface{})
+		if block["type"] == "tool_use" {
+			ctx.ToolBlockStarted = true
+			ctx.CurrentToolID, _ = block["id"].(string)
+			ctx.CurrentToolName, _ = block["name"].(string)
+			ctx.ToolArguments = ""
+		}
+
 	case "content_block_delta":
 		delta, ok := data["delta"].(map[string]interface{})
 		if !ok {
 			return nil, nil
 		}
-		if delta["type"] == "text_delta" {
+		switch delta["type"] {
+		case "text_delta":
 			text, _ := delta["text"].(string)
-			chunk := map[string]interface{}{
-				"candidates": []map[string]interface{}{
-					{"content": map[string]interface{}{"role": "model", "parts": []map[string]interface{}{{"text": text}}}},
-				},
+			return buildGeminiChunk([]map[string]interface{}{{"text": text}}, "", nil), nil
+		case "thinking_delta":
+			thinking, _ := delta["thinking"].(string)
+			return buildGeminiChunk([]map[string]interface{}{{"text": thinking, "thought": true}}, "", nil), nil
+		case "input_json_delta":
+			partial, _ := delta["partial_json"].(string)
+			ctx.ToolArguments += partial
+		}
+
+	case "content_block_stop":
+		if !ctx.ToolBlockStarted {
+			return nil, nil
+		}
+		ctx.ToolBlockStarted = false
+		args := map[string]interface{}{}
+		if ctx.ToolArguments != "" {
+			json.Unmarshal([]byte(ctx.ToolArguments), &args)
+		}
+		return buildGeminiChunk([]map[string]interface{}{{
+			"functionCall": map[string]interface{}{"id": ctx.CurrentToolID, "name": ctx.CurrentToolName, "args": args},
+		}}, "", nil), nil
+
+	case "message_delta":
+		if usage, ok := data["usage"].(map[string]interface{}); ok {
+			if input, _ := usage["input_tokens"].(float64); input > 0 {
+				ctx.InputTokens = int(input)
+			}
+			if output, _ := usage["output_tokens"].(float64); output > 0 {
+				ctx.OutputTokens = int(output)
+			}
+		}
+		if delta, ok := data["delta"].(map[string]interface{}); ok {
+			if stopReason, _ := delta["stop_reason"].(string); stopReason != "" {
+				ctx.PendingFinishReason = geminiFinishReason(stopReason)
 			}
-			d, _ := json.Marshal(chunk)
-			return []byte(fmt.Sprintf("data: %s\n\n", d)), nil
 		}
 
 	case "message_stop":
-		return []byte("data: [DONE]\n\n"), nil
+		return finishGeminiStream(ctx), nil
+
+	case "error":
+		if errObj, ok := data["error"].(map[string]interface{}); ok {
+			msg, _ := errObj["message"].(string)
+			return nil, fmt.Errorf("upstream error: %s", msg)
+		}
 	}
 
 	return nil, nil
@@ -431,10 +521,7 @@ func GeminiStreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte,
 			result = append(result, buildClaudeEvent("content_block_stop", map[string]interface{}{"index": ctx.ContentIndex})...)
 			ctx.ContentBlockStarted = false
 		}
-		stopReason := "end_turn"
-		if hasFunctionCall || candidate.FinishReason == "TOOL_CODE" {
-			stopReason = "tool_use"
-		}
+		stopReason := claudeStopReason(candidate.FinishReason, hasFunctionCall || candidate.FinishReason == "TOOL_CODE")
 		result = append(result, buildClaudeEvent("message_delta", map[string]interface{}{
 			"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
 			"usage": currentClaudeUsage(ctx),
@@ -447,7 +534,7 @@ func GeminiStreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte,
 }
 
 // Helper function
-func convertClaudeContentToGeminiParts(content []interface{}, toolUseIDToName map[string]string) []map[string]interface{} {
+func convertClaudeContentToGeminiParts(content []interface{}, toolUseIDToName map[string]string) ([]map[string]interface{}, error) {
 	var parts []map[string]interface{}
 	for _, block := range content {
 		m, ok := block.(map[string]interface{})
@@ -479,24 +566,28 @@ func convertClaudeContentToGeminiParts(content []interface{}, toolUseIDToName ma
 		case "tool_result":
 			toolUseID, _ := m["tool_use_id"].(string)
 			funcName := toolUseIDToName[toolUseID]
+			text := toolResultToString(m["content"])
+			response := geminiFunctionResponse(text)
+			if isError, _ := m["is_error"].(bool); isError {
+				response = map[string]interface{}{"error": text}
+			}
 			parts = append(parts, map[string]interface{}{
 				"functionResponse": map[string]interface{}{
 					"name":     funcName,
-					"response": map[string]interface{}{"result": m["content"]},
+					"response": response,
 				},
 			})
-		case "image":
-			if source, ok := m["source"].(map[string]interface{}); ok {
-				if source["type"] == "base64" {
-					parts = append(parts, map[string]interface{}{
-						"inlineData": map[string]interface{}{
-							"mimeType": source["media_type"],
-							"data":     source["data"],
-						},
-					})
-				}
+		case "image", "document":
+			media, err := mediaFromClaude(m)
+			if err != nil {
+				return nil, err
 			}
+			part, err := media.toGemini()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		}
 	}
-	return parts
+	return parts, nil
 }
@@ -5,10 +5,11 @@ import (
 	"strings"
 
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/schema"
 )
 
 // ClaudeReqToOpenAI converts Claude request to OpenAI Chat request
-func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
+func ClaudeReqToOpenAI(claudeReq []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.ClaudeRequest
 	if err := json.Unmarshal(claudeReq, &req); err != nil {
 		return nil, err
@@ -35,9 +36,11 @@ func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
 		case []interface{}:
 			// Check for tool_result blocks
 			var textParts []string
+			var contentParts []map[string]interface{}
 			var toolCalls []transformer.OpenAIToolCall
 			var toolResults []transformer.OpenAIMessage
 			hasThinking := false
+			hasMedia := false
 
 			for _, block := range content {
 				m, ok := block.(map[string]interface{})
@@ -48,7 +51,19 @@ func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
 				case "text":
 					if text, ok := m["text"].(string); ok {
 						textParts = append(textParts, text)
+						contentParts = append(contentParts, map[string]interface{}{"type": "text", "text": text})
 					}
+				case "image", "document":
+					media, err := mediaFromClaude(m)
+					if err != nil {
+						return nil, err
+					}
+					part, err := media.toOpenAI()
+					if err != nil {
+						return nil, err
+					}
+					contentParts = append(contentParts, part)
+					hasMedia = true
 				case "thinking":
 					// Skip thinking blocks - they are Claude's internal reasoning
 					// and should not be forwarded to other APIs
@@ -85,10 +100,12 @@ func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
 				}
 			}
 
-			// Add main message if has text or tool_calls
-			if len(textParts) > 0 || len(toolCalls) > 0 {
+			// Add main message if has text, media or tool_calls
+			if len(textParts) > 0 || hasMedia || len(toolCalls) > 0 {
 				openaiMsg := transformer.OpenAIMessage{Role: msg.Role}
-				if len(textParts) > 0 {
+				if hasMedia {
+					openaiMsg.Content = contentParts
+				} else if len(textParts) > 0 {
 					openaiMsg.Content = strings.Join(textParts, "")
 				}
 				if len(toolCalls) > 0 {
@@ -119,6 +136,7 @@ func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
 	if req.Temperature > 0 {
 		openaiReq.Temperature = &req.Temperature
 	}
+	openaiReq.ReasoningEffort = openAIReasoningEffort(reasoningFromClaude(req.Thinking), opts.Reasoning)
 
 	// Convert tools
 	if len(req.Tools) > 0 {
@@ -129,10 +147,11 @@ func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
 					Name        string                 `json:"name"`
 					Description string                 `json:"description,omitempty"`
 					Parameters  map[string]interface{} `json:"parameters"`
+					Strict      bool                   `json:"strict,omitempty"`
 				}{
 					Name:        tool.Name,
 					Description: tool.Description,
-					Parameters:  tool.InputSchema,
+					Parameters:  normalizeToolSchema(tool.Name, tool.InputSchema, schema.ProfileOpenAI),
 				},
 			})
 		}
@@ -155,6 +174,10 @@ func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
 		} else {
 			openaiReq.ToolChoice = "auto"
 		}
+		openaiReq.ParallelToolCalls = toolChoiceFromClaude(req.ToolChoice).openAIParallelToolCalls()
+	}
+	if format := outputFormatFromClaude(req.OutputFormat); format != nil {
+		openaiReq.ResponseFormat = openAIResponseFormat(format)
 	}
 
 	// Enable usage tracking for streaming
@@ -162,11 +185,15 @@ func ClaudeReqToOpenAI(claudeReq []byte, model string) ([]byte, error) {
 		openaiReq.StreamOptions = &transformer.StreamOptions{IncludeUsage: true}
 	}
 
-	return json.Marshal(openaiReq)
+	body, err := json.Marshal(openaiReq)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyOpenAIRequestQuirks(body, opts)
 }
 
 // OpenAIReqToClaude converts OpenAI Chat request to Claude request
-func OpenAIReqToClaude(openaiReq []byte, model string) ([]byte, error) {
+func OpenAIReqToClaude(openaiReq []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.OpenAIRequest
 	if err := json.Unmarshal(openaiReq, &req); err != nil {
 		return nil, err
@@ -186,6 +213,7 @@ func OpenAIReqToClaude(openaiReq []byte, model string) ([]byte, error) {
 	if req.Temperature != nil {
 		claudeReq["temperature"] = *req.Temperature
 	}
+	applyReasoningToClaude(claudeReq, reasoningFromOpenAI(req), opts.Reasoning)
 
 	// Convert messages
 	var systemPrompt string
@@ -206,7 +234,11 @@ func OpenAIReqToClaude(openaiReq []byte, model string) ([]byte, error) {
 		case string:
 			claudeMsg["content"] = content
 		case []interface{}:
-			claudeMsg["content"] = convertOpenAIContentToClaude(content)
+			blocks, err := convertOpenAIContentToClaude(content)
+			if err != nil {
+				return nil, err
+			}
+			claudeMsg["content"] = blocks
 		}
 
 		// Handle tool_calls
@@ -252,14 +284,18 @@ func OpenAIReqToClaude(openaiReq []byte, model string) ([]byte, error) {
 				tools = append(tools, map[string]interface{}{
 					"name":         tool.Function.Name,
 					"description":  tool.Function.Description,
-					"input_schema": tool.Function.Parameters,
+					"input_schema": normalizeToolSchema(tool.Function.Name, tool.Function.Parameters, schema.ProfileClaude),
 				})
 			}
 		}
 		if len(tools) > 0 {
 			claudeReq["tools"] = tools
+			if choice := toolChoiceFromOpenAI(req.ToolChoice, req.ParallelToolCalls).claude(); choice != nil {
+				claudeReq["tool_choice"] = choice
+			}
 		}
 	}
+	applyOutputFormatToClaude(claudeReq, outputFormatFromOpenAI(req.ResponseFormat))
 
 	return json.Marshal(claudeReq)
 }
@@ -267,7 +303,7 @@ func OpenAIReqToClaude(openaiReq []byte, model string) ([]byte, error) {
 // ClaudeRespToOpenAI converts Claude response to OpenAI Chat response
 func ClaudeRespToOpenAI(claudeResp []byte, model string) ([]byte, error) {
 	var resp transformer.ClaudeResponse
-	if err := json.Unmarshal(claudeResp, &resp); err != nil {
+	if err := json.Unmarshal(unwrapStructuredOutputResponse(claudeResp), &resp); err != nil {
 		return nil, err
 	}
 
@@ -303,10 +339,7 @@ func ClaudeRespToOpenAI(claudeResp []byte, model string) ([]byte, error) {
 		message["tool_calls"] = toolCalls
 	}
 
-	finishReason := "stop"
-	if resp.StopReason == "tool_use" {
-		finishReason = "tool_calls"
-	}
+	finishReason := openAIFinishReason(resp.StopReason, resp.StopReason == "tool_use")
 
 	openaiResp := map[string]interface{}{
 		"id":      resp.ID,
@@ -324,9 +357,9 @@ func ClaudeRespToOpenAI(claudeResp []byte, model string) ([]byte, error) {
 }
 
 // OpenAIRespToClaude converts OpenAI Chat response to Claude response
-func OpenAIRespToClaude(openaiResp []byte) ([]byte, error) {
+func OpenAIRespToClaude(openaiResp []byte, opts transformer.Options) ([]byte, error) {
 	var resp transformer.OpenAIResponse
-	if err := json.Unmarshal(openaiResp, &resp); err != nil {
+	if err := json.Unmarshal(normalizeOpenAIResponse(openaiResp, opts.Quirks), &resp); err != nil {
 		return nil, err
 	}
 
@@ -335,8 +368,15 @@ func OpenAIRespToClaude(openaiResp []byte) ([]byte, error) {
 
 	if len(resp.Choices) > 0 {
 		choice := resp.Choices[0]
+		if choice.Message.ReasoningContent != "" {
+			content = append(content, map[string]interface{}{"type": "thinking", "thinking": choice.Message.ReasoningContent})
+		}
 		if choice.Message.Content != "" {
-			content = append(content, splitThinkTaggedText(choice.Message.Content)...)
+			if opts.Quirks.ParseThinkTags() {
+				content = append(content, splitThinkTaggedText(choice.Message.Content)...)
+			} else {
+				content = append(content, map[string]interface{}{"type": "text", "text": choice.Message.Content})
+			}
 		}
 		for _, tc := range choice.Message.ToolCalls {
 			var args map[string]interface{}
@@ -347,8 +387,8 @@ func OpenAIRespToClaude(openaiResp []byte) ([]byte, error) {
 				"name":  tc.Function.Name,
 				"input": args,
 			})
-			stopReason = "tool_use"
 		}
+		stopReason = claudeStopReason(choice.FinishReason, len(choice.Message.ToolCalls) > 0)
 	}
 
 	claudeResp := map[string]interface{}{
@@ -369,6 +409,7 @@ func OpenAIRespToClaude(openaiResp []byte) ([]byte, error) {
 
 // ClaudeStreamToOpenAI converts Claude SSE event to OpenAI Chat stream chunk
 func ClaudeStreamToOpenAI(event []byte, ctx *transformer.StreamContext, model string) ([]byte, error) {
+	event = unwrapStructuredOutputEvent(event, ctx)
 	eventType, jsonData := parseSSE(event)
 	if jsonData == "" {
 		return nil, nil
@@ -426,10 +467,7 @@ func ClaudeStreamToOpenAI(event []byte, ctx *transformer.StreamContext, model st
 	case "message_delta":
 		if delta, ok := data["delta"].(map[string]interface{}); ok {
 			stopReason, _ := delta["stop_reason"].(string)
-			finish := "stop"
-			if stopReason == "tool_use" {
-				finish = "tool_calls"
-			}
+			finish := openAIFinishReason(stopReason, stopReason == "tool_use")
 			return buildOpenAIChunk(ctx.MessageID, model, "", nil, finish)
 		}
 		return nil, nil
@@ -442,7 +480,7 @@ func ClaudeStreamToOpenAI(event []byte, ctx *transformer.StreamContext, model st
 }
 
 // OpenAIStreamToClaude converts OpenAI Chat stream chunk to Claude SSE event
-func OpenAIStreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte, error) {
+func OpenAIStreamToClaude(event []byte, ctx *transformer.StreamContext, opts transformer.Options) ([]byte, error) {
 	_, jsonData := parseSSE(event)
 	if jsonData == "" || jsonData == "[DONE]" {
 		if jsonData == "[DONE]" {
@@ -476,7 +514,7 @@ func OpenAIStreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte,
 	}
 
 	var chunk transformer.OpenAIStreamChunk
-	if err := json.Unmarshal([]byte(jsonData), &chunk); err != nil {
+	if err := json.Unmarshal([]byte(normalizeOpenAIStreamChunk(jsonData, ctx, opts.Quirks)), &chunk); err != nil {
 		return nil, nil
 	}
 
@@ -568,7 +606,11 @@ func OpenAIStreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte,
 			}
 		}
 
-		consumeThinkTaggedStream(content, ctx, emitTextWithClose, emitThinkingWithClose)
+		if opts.Quirks.ParseThinkTags() {
+			consumeThinkTaggedStream(content, ctx, emitTextWithClose, emitThinkingWithClose)
+		} else {
+			emitTextWithClose(content)
+		}
 	}
 
 	// Tool calls
@@ -623,13 +665,15 @@ func OpenAIStreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte,
 			result = append(result, buildClaudeEvent("content_block_stop", map[string]interface{}{"index": ctx.ToolIndex})...)
 			ctx.ToolBlockStarted = false
 		}
-		stopReason := "end_turn"
-		if *choice.FinishReason == "tool_calls" {
-			stopReason = "tool_use"
+		stopReason := claudeStopReason(*choice.FinishReason, *choice.FinishReason == "tool_calls")
+		// Some providers report usage on the finish chunk instead of a chunk of its own
+		usage := map[string]interface{}{"output_tokens": 0}
+		if chunk.Usage != nil {
+			usage = map[string]interface{}{"input_tokens": chunk.Usage.PromptTokens, "output_tokens": chunk.Usage.CompletionTokens}
 		}
 		result = append(result, buildClaudeEvent("message_delta", map[string]interface{}{
 			"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
-			"usage": map[string]interface{}{"output_tokens": 0},
+			"usage": usage,
 		})...)
 		ctx.FinishReasonSent = true
 	}
@@ -673,7 +717,7 @@ func convertClaudeContentToOpenAI(content []interface{}) (interface{}, []transfo
 	return "", toolCalls
 }
 
-func convertOpenAIContentToClaude(content []interface{}) []map[string]interface{} {
+func convertOpenAIContentToClaude(content []interface{}) ([]map[string]interface{}, error) {
 	var result []map[string]interface{}
 	for _, item := range content {
 		m, ok := item.(map[string]interface{})
@@ -683,22 +727,19 @@ func convertOpenAIContentToClaude(content []interface{}) []map[string]interface{
 		switch m["type"] {
 		case "text":
 			result = append(result, map[string]interface{}{"type": "text", "text": m["text"]})
-		case "image_url":
-			if urlObj, ok := m["image_url"].(map[string]interface{}); ok {
-				if url, ok := urlObj["url"].(string); ok && strings.HasPrefix(url, "data:") {
-					parts := strings.SplitN(url, ",", 2)
-					if len(parts) == 2 {
-						mediaType := strings.TrimPrefix(strings.Split(parts[0], ";")[0], "data:")
-						result = append(result, map[string]interface{}{
-							"type":   "image",
-							"source": map[string]interface{}{"type": "base64", "media_type": mediaType, "data": parts[1]},
-						})
-					}
-				}
+		case "image_url", "file", "input_audio":
+			media, err := mediaFromOpenAI(m)
+			if err != nil {
+				return nil, err
 			}
+			block, err := media.toClaude()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, block)
 		}
 	}
-	return result
+	return result, nil
 }
 
 func extractToolResultContent(content interface{}) string {
@@ -6,10 +6,11 @@ import (
 	"strings"
 
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/schema"
 )
 
 // ClaudeReqToOpenAI2 converts Claude request to OpenAI Responses API request
-func ClaudeReqToOpenAI2(claudeReq []byte, model string) ([]byte, error) {
+func ClaudeReqToOpenAI2(claudeReq []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.ClaudeRequest
 	if err := json.Unmarshal(claudeReq, &req); err != nil {
 		return nil, err
@@ -45,11 +46,20 @@ func ClaudeReqToOpenAI2(claudeReq []byte, model string) ([]byte, error) {
 				},
 			})
 		case []interface{}:
-			input = append(input, convertClaudeMessageToOpenAI2Items(content, msg.Role)...)
+			items, err := convertClaudeMessageToOpenAI2Items(content, msg.Role)
+			if err != nil {
+				return nil, err
+			}
+			input = append(input, items...)
 		}
 	}
 	openai2Req["input"] = input
 
+	if reasoning := openAI2Reasoning(reasoningFromClaude(req.Thinking), opts.Reasoning); reasoning != nil {
+		openai2Req["reasoning"] = reasoning
+		openai2Req["include"] = []string{"reasoning.encrypted_content"}
+	}
+
 	// TODO: max_output_tokens is standard OpenAI Responses API param but some
 	// third-party endpoints (e.g. SiliconFlow) don't support it. Skipping for compatibility.
 
@@ -61,7 +71,7 @@ func ClaudeReqToOpenAI2(claudeReq []byte, model string) ([]byte, error) {
 				"type":        "function",
 				"name":        tool.Name,
 				"description": tool.Description,
-				"parameters":  tool.InputSchema,
+				"parameters":  normalizeToolSchema(tool.Name, tool.InputSchema, schema.ProfileOpenAI),
 			})
 		}
 		openai2Req["tools"] = tools
@@ -79,6 +89,12 @@ func ClaudeReqToOpenAI2(claudeReq []byte, model string) ([]byte, error) {
 				openai2Req["tool_choice"] = "required"
 			}
 		}
+		if parallel := toolChoiceFromClaude(req.ToolChoice).openAIParallelToolCalls(); parallel != nil {
+			openai2Req["parallel_tool_calls"] = *parallel
+		}
+	}
+	if format := outputFormatFromClaude(req.OutputFormat); format != nil {
+		openai2Req["text"] = openAI2TextFormat(format)
 	}
 
 	return json.Marshal(openai2Req)
@@ -139,7 +155,7 @@ func hasClaudeToolResult(messages []transformer.ClaudeMessage) bool {
 }
 
 // OpenAI2ReqToClaude converts OpenAI Responses API request to Claude request
-func OpenAI2ReqToClaude(openai2Req []byte, model string) ([]byte, error) {
+func OpenAI2ReqToClaude(openai2Req []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.OpenAI2Request
 	if err := json.Unmarshal(openai2Req, &req); err != nil {
 		return nil, err
@@ -160,9 +176,13 @@ func OpenAI2ReqToClaude(openai2Req []byte, model string) ([]byte, error) {
 	if req.Temperature != nil {
 		claudeReq["temperature"] = *req.Temperature
 	}
+	applyReasoningToClaude(claudeReq, reasoningFromOpenAI2(req.Reasoning), opts.Reasoning)
 
 	// Convert input to messages
-	messages := convertOpenAI2InputToClaude(req.Input)
+	messages, err := convertOpenAI2InputToClaude(req.Input)
+	if err != nil {
+		return nil, err
+	}
 	claudeReq["messages"] = messages
 
 	// Convert tools
@@ -187,13 +207,17 @@ func OpenAI2ReqToClaude(openai2Req []byte, model string) ([]byte, error) {
 			tools = append(tools, map[string]interface{}{
 				"name":         tool.Name,
 				"description":  tool.Description,
-				"input_schema": inputSchema,
+				"input_schema": normalizeToolSchema(tool.Name, inputSchema, schema.ProfileClaude),
 			})
 		}
 		if len(tools) > 0 {
 			claudeReq["tools"] = tools
+			if choice := toolChoiceFromOpenAI(req.ToolChoice, req.ParallelToolCalls).claude(); choice != nil {
+				claudeReq["tool_choice"] = choice
+			}
 		}
 	}
+	applyOutputFormatToClaude(claudeReq, outputFormatFromOpenAI2(req.Text))
 
 	return json.Marshal(claudeReq)
 }
@@ -201,10 +225,11 @@ func OpenAI2ReqToClaude(openai2Req []byte, model string) ([]byte, error) {
 // ClaudeRespToOpenAI2 converts Claude response to OpenAI Responses API response
 func ClaudeRespToOpenAI2(claudeResp []byte) ([]byte, error) {
 	var resp transformer.ClaudeResponse
-	if err := json.Unmarshal(claudeResp, &resp); err != nil {
+	if err := json.Unmarshal(unwrapStructuredOutputResponse(claudeResp), &resp); err != nil {
 		return nil, err
 	}
 
+	var reasoningItems []map[string]interface{}
 	var outputContent []map[string]interface{}
 	var functionCalls []map[string]interface{}
 
@@ -220,8 +245,10 @@ func ClaudeRespToOpenAI2(claudeResp []byte) ([]byte, error) {
 				"text": blockMap["text"],
 			})
 		case "thinking":
-			// Skip thinking blocks in response
-			continue
+			thinking, _ := blockMap["thinking"].(string)
+			signature, _ := blockMap["signature"].(string)
+			itemID := fmt.Sprintf("rs_%s_%d", resp.ID, len(reasoningItems))
+			reasoningItems = append(reasoningItems, openAI2ReasoningOutput(itemID, thinking, signature))
 		case "tool_use":
 			args, _ := json.Marshal(blockMap["input"])
 			functionCalls = append(functionCalls, map[string]interface{}{
@@ -234,7 +261,7 @@ func ClaudeRespToOpenAI2(claudeResp []byte) ([]byte, error) {
 		}
 	}
 
-	var output []map[string]interface{}
+	output := reasoningItems
 	if len(outputContent) > 0 {
 		output = append(output, map[string]interface{}{
 			"type":    "message",
@@ -247,7 +274,6 @@ func ClaudeRespToOpenAI2(claudeResp []byte) ([]byte, error) {
 	openai2Resp := map[string]interface{}{
 		"id":     resp.ID,
 		"object": "response",
-		"status": "completed",
 		"output": output,
 		"usage": map[string]interface{}{
 			"input_tokens":  resp.Usage.InputTokens,
@@ -255,6 +281,7 @@ func ClaudeRespToOpenAI2(claudeResp []byte) ([]byte, error) {
 			"total_tokens":  resp.Usage.InputTokens + resp.Usage.OutputTokens,
 		},
 	}
+	setOpenAI2Status(openai2Resp, resp.StopReason)
 
 	return json.Marshal(openai2Resp)
 }
@@ -267,10 +294,14 @@ func OpenAI2RespToClaude(openai2Resp []byte) ([]byte, error) {
 	}
 
 	var content []map[string]interface{}
-	stopReason := "end_turn"
+	toolUse := false
 
 	for _, item := range resp.Output {
 		switch item.Type {
+		case "reasoning":
+			if block := claudeThinkingFromOpenAI2Output(item); block != nil {
+				content = append(content, block)
+			}
 		case "message":
 			for _, part := range item.Content {
 				if part.Type == "output_text" {
@@ -290,7 +321,7 @@ func OpenAI2RespToClaude(openai2Resp []byte) ([]byte, error) {
 				"name":  item.Name,
 				"input": args,
 			})
-			stopReason = "tool_use"
+			toolUse = true
 		}
 	}
 
@@ -299,7 +330,7 @@ func OpenAI2RespToClaude(openai2Resp []byte) ([]byte, error) {
 		"type":        "message",
 		"role":        "assistant",
 		"content":     content,
-		"stop_reason": stopReason,
+		"stop_reason": claudeStopReason(resp.IncompleteReason(), toolUse),
 		"usage": map[string]interface{}{
 			"input_tokens":  resp.Usage.InputTokens,
 			"output_tokens": resp.Usage.OutputTokens,
@@ -311,6 +342,7 @@ func OpenAI2RespToClaude(openai2Resp []byte) ([]byte, error) {
 
 // ClaudeStreamToOpenAI2 converts Claude SSE event to OpenAI Responses stream event
 func ClaudeStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte, error) {
+	event = unwrapStructuredOutputEvent(event, ctx)
 	eventType, jsonData := parseSSE(event)
 	if jsonData == "" {
 		return nil, nil
@@ -362,6 +394,21 @@ func ClaudeStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 		blockIdx := int(idx)
 
 		switch block["type"] {
+		case "thinking":
+			ctx.ThinkingBlockStarted = true
+			ctx.ThinkingIndex = blockIdx
+			ctx.ReasoningText = ""
+			ctx.ReasoningSignature = ""
+			itemID := fmt.Sprintf("rs_%s_%d", ctx.MessageID, blockIdx)
+			writeEvent(map[string]interface{}{
+				"type": "response.output_item.added", "output_index": blockIdx,
+				"item": map[string]interface{}{"type": "reasoning", "id": itemID, "summary": []interface{}{}},
+			})
+			writeEvent(map[string]interface{}{
+				"type": "response.reasoning_summary_part.added", "item_id": itemID,
+				"output_index": blockIdx, "summary_index": 0,
+				"part": map[string]interface{}{"type": "summary_text", "text": ""},
+			})
 		case "text":
 			ctx.ContentBlockStarted = true
 			ctx.ContentIndex = blockIdx
@@ -400,6 +447,16 @@ func ClaudeStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 			return nil, nil
 		}
 		switch delta["type"] {
+		case "thinking_delta":
+			text, _ := delta["thinking"].(string)
+			ctx.ReasoningText += text
+			writeEvent(map[string]interface{}{
+				"type": "response.reasoning_summary_text.delta", "item_id": fmt.Sprintf("rs_%s_%d", ctx.MessageID, ctx.ThinkingIndex),
+				"output_index": ctx.ThinkingIndex, "summary_index": 0, "delta": text,
+			})
+		case "signature_delta":
+			signature, _ := delta["signature"].(string)
+			ctx.ReasoningSignature += signature
 		case "text_delta":
 			writeEvent(map[string]interface{}{
 				"type": "response.output_text.delta", "output_index": ctx.ContentIndex,
@@ -418,7 +475,25 @@ func ClaudeStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 		idx, _ := data["index"].(float64)
 		blockIdx := int(idx)
 
-		if ctx.ToolBlockStarted && blockIdx == ctx.ToolIndex {
+		if ctx.ThinkingBlockStarted && blockIdx == ctx.ThinkingIndex {
+			itemID := fmt.Sprintf("rs_%s_%d", ctx.MessageID, blockIdx)
+			writeEvent(map[string]interface{}{
+				"type": "response.reasoning_summary_text.done", "item_id": itemID,
+				"output_index": blockIdx, "summary_index": 0, "text": ctx.ReasoningText,
+			})
+			writeEvent(map[string]interface{}{
+				"type": "response.reasoning_summary_part.done", "item_id": itemID,
+				"output_index": blockIdx, "summary_index": 0,
+				"part": map[string]interface{}{"type": "summary_text", "text": ctx.ReasoningText},
+			})
+			writeEvent(map[string]interface{}{
+				"type": "response.output_item.done", "output_index": blockIdx,
+				"item": openAI2ReasoningOutput(itemID, ctx.ReasoningText, ctx.ReasoningSignature),
+			})
+			ctx.ThinkingBlockStarted = false
+			ctx.ReasoningText = ""
+			ctx.ReasoningSignature = ""
+		} else if ctx.ToolBlockStarted && blockIdx == ctx.ToolIndex {
 			// function_call_arguments.done
 			writeEvent(map[string]interface{}{
 				"type":         "response.function_call_arguments.done",
@@ -462,17 +537,21 @@ func ClaudeStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 				ctx.OutputTokens = int(out)
 			}
 		}
+		if delta, ok := data["delta"].(map[string]interface{}); ok {
+			ctx.PendingFinishReason, _ = delta["stop_reason"].(string)
+		}
 
 	case "message_stop":
-		writeEvent(map[string]interface{}{
-			"type": "response.completed",
-			"response": map[string]interface{}{
-				"id": ctx.MessageID, "object": "response", "status": "completed",
-				"usage": map[string]interface{}{
-					"input_tokens": ctx.InputTokens, "output_tokens": ctx.OutputTokens,
-					"total_tokens": ctx.InputTokens + ctx.OutputTokens,
-				},
+		response := map[string]interface{}{
+			"id": ctx.MessageID, "object": "response",
+			"usage": map[string]interface{}{
+				"input_tokens": ctx.InputTokens, "output_tokens": ctx.OutputTokens,
+				"total_tokens": ctx.InputTokens + ctx.OutputTokens,
 			},
+		}
+		writeEvent(map[string]interface{}{
+			"type":     setOpenAI2Status(response, ctx.PendingFinishReason),
+			"response": response,
 		})
 		result.WriteString("data: [DONE]\n\n")
 	}
@@ -566,6 +645,17 @@ func OpenAI2StreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte
 
 		consumeThinkTaggedStream(content, ctx, emitTextWithClose, emitThinkingWithClose)
 
+	case "response.reasoning_summary_part.added":
+		// Separate multiple summary parts inside a single thinking block
+		if evt.SummaryIndex > 0 && ctx.ThinkingBlockStarted {
+			_, emitThinking := makeThinkEmitters(ctx, &result)
+			emitThinking("\n\n")
+		}
+
+	case "response.reasoning_summary_text.delta":
+		_, emitThinking := makeThinkEmitters(ctx, &result)
+		emitThinking(evt.Delta)
+
 	case "response.output_item.added":
 		if evt.Item != nil && evt.Item.Type == "function_call" {
 			if ctx.ThinkingBlockStarted {
@@ -602,13 +692,16 @@ func OpenAI2StreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte
 		}
 
 	case "response.output_item.done":
+		if evt.Item != nil && evt.Item.Type == "reasoning" {
+			result = append(result, finishClaudeThinkingBlock(ctx, evt.Item.EncryptedContent)...)
+		}
 		if evt.Item != nil && evt.Item.Type == "function_call" && ctx.ToolBlockStarted {
 			result = append(result, buildClaudeEvent("content_block_stop", map[string]interface{}{"index": ctx.ToolIndex})...)
 			ctx.ToolBlockStarted = false
 			ctx.ContentIndex++
 		}
 
-	case "response.completed":
+	case "response.completed", "response.incomplete":
 		if evt.Response != nil {
 			if evt.Response.Usage.InputTokens > 0 {
 				ctx.InputTokens = evt.Response.Usage.InputTokens
@@ -627,10 +720,11 @@ func OpenAI2StreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte
 			result = append(result, buildClaudeEvent("content_block_stop", map[string]interface{}{"index": ctx.ContentIndex})...)
 			ctx.ContentBlockStarted = false
 		}
-		stopReason := "end_turn"
-		if ctx.ToolIndex > 0 || ctx.CurrentToolID != "" {
-			stopReason = "tool_use"
+		incompleteReason := ""
+		if evt.Response != nil {
+			incompleteReason = evt.Response.IncompleteReason()
 		}
+		stopReason := claudeStopReason(incompleteReason, ctx.ToolIndex > 0 || ctx.CurrentToolID != "")
 		result = append(result, buildClaudeEvent("message_delta", map[string]interface{}{
 			"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
 			"usage": map[string]interface{}{"output_tokens": ctx.OutputTokens},
@@ -644,7 +738,7 @@ func OpenAI2StreamToClaude(event []byte, ctx *transformer.StreamContext) ([]byte
 
 // Helper functions
 
-func convertClaudeMessageToOpenAI2Items(content []interface{}, role string) []map[string]interface{} {
+func convertClaudeMessageToOpenAI2Items(content []interface{}, role string) ([]map[string]interface{}, error) {
 	var items []map[string]interface{}
 	var messageParts []map[string]interface{}
 	textType := "input_text"
@@ -675,9 +769,23 @@ func convertClaudeMessageToOpenAI2Items(content []interface{}, role string) []ma
 		case "text":
 			text, _ := m["text"].(string)
 			messageParts = append(messageParts, map[string]interface{}{"type": textType, "text": text})
+		case "image", "document":
+			media, err := mediaFromClaude(m)
+			if err != nil {
+				return nil, err
+			}
+			part, err := media.toOpenAI2()
+			if err != nil {
+				return nil, err
+			}
+			messageParts = append(messageParts, part)
 		case "thinking":
-			// Skip thinking blocks - they are Claude's internal reasoning
-			continue
+			// Only reasoning that originated from a Responses API backend can be
+			// replayed; other thinking blocks are Claude's internal reasoning
+			if item := openAI2ReasoningItem(m); item != nil && role == "assistant" {
+				flushMessage()
+				items = append(items, item)
+			}
 		case "tool_use":
 			flushMessage()
 			callID, _ := m["id"].(string)
@@ -701,23 +809,38 @@ func convertClaudeMessageToOpenAI2Items(content []interface{}, role string) []ma
 	}
 	flushMessage()
 
-	return items
+	return items, nil
 }
 
+// toolResultToString returns tool result content as text. Content that is
+// not only text blocks is sent as JSON.
 func toolResultToString(content interface{}) string {
 	switch v := content.(type) {
 	case string:
 		return v
-	default:
-		data, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Sprint(v)
+	case []interface{}:
+		if onlyTextBlocks(v) {
+			return extractToolResultContent(v)
 		}
-		return string(data)
 	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Sprint(content)
+	}
+	return string(data)
 }
 
-func convertOpenAI2InputToClaude(input interface{}) []map[string]interface{} {
+func onlyTextBlocks(blocks []interface{}) bool {
+	for _, block := range blocks {
+		m, ok := block.(map[string]interface{})
+		if !ok || m["type"] != "text" {
+			return false
+		}
+	}
+	return len(blocks) > 0
+}
+
+func convertOpenAI2InputToClaude(input interface{}) ([]map[string]interface{}, error) {
 	var messages []map[string]interface{}
 
 	switch v := input.(type) {
@@ -726,6 +849,7 @@ func convertOpenAI2InputToClaude(input interface{}) []map[string]interface{} {
 	case []interface{}:
 		var pendingToolUses []map[string]interface{}
 		var pendingToolResults []map[string]interface{}
+		var pendingThinking []map[string]interface{}
 
 		for _, item := range v {
 			itemMap, ok := item.(map[string]interface{})
@@ -733,7 +857,7 @@ func convertOpenAI2InputToClaude(input interface{}) []map[string]interface{} {
 				continue
 			}
 
-			itemType, _ := itemMap["type"].(string)
+			itemType := openAI2InputItemType(itemMap)
 			switch itemType {
 			case "message":
 				// Flush pending tool uses before user message
@@ -748,9 +872,23 @@ func convertOpenAI2InputToClaude(input interface{}) []map[string]interface{} {
 				}
 
 				role, _ := itemMap["role"].(string)
-				content := convertOpenAI2ContentToClaude(itemMap["content"], role)
+				content, err := convertOpenAI2ContentToClaude(itemMap["content"], role)
+				if err != nil {
+					return nil, err
+				}
+				if role == "assistant" && len(pendingThinking) > 0 {
+					content = prependThinkingBlocks(pendingThinking, content)
+				}
+				pendingThinking = nil
 				messages = append(messages, map[string]interface{}{"role": role, "content": content})
 
+			case "reasoning":
+				// Claude signatures round-tripped through encrypted_content are
+				// restored as thinking blocks on the next assistant turn
+				if block := claudeThinkingFromReasoningItem(itemMap); block != nil {
+					pendingThinking = append(pendingThinking, block)
+				}
+
 			case "function_call":
 				// Convert to Claude tool_use
 				callID, _ := itemMap["call_id"].(string)
@@ -763,6 +901,10 @@ func convertOpenAI2InputToClaude(input interface{}) []map[string]interface{} {
 				if err := json.Unmarshal([]byte(argsStr), &args); err != nil {
 					args = map[string]interface{}{}
 				}
+				if len(pendingToolUses) == 0 && len(pendingThinking) > 0 {
+					pendingToolUses = append(pendingToolUses, pendingThinking...)
+				}
+				pendingThinking = nil
 				pendingToolUses = append(pendingToolUses, map[string]interface{}{
 					"type": "tool_use", "id": callID, "name": name, "input": args,
 				})
@@ -790,13 +932,13 @@ func convertOpenAI2InputToClaude(input interface{}) []map[string]interface{} {
 			messages = append(messages, map[string]interface{}{"role": "user", "content": pendingToolResults})
 		}
 	}
-	return messages
+	return messages, nil
 }
 
-func convertOpenAI2ContentToClaude(content interface{}, role string) interface{} {
+func convertOpenAI2ContentToClaude(content interface{}, role string) (interface{}, error) {
 	arr, ok := content.([]interface{})
 	if !ok {
-		return content
+		return content, nil
 	}
 
 	var result []map[string]interface{}
@@ -808,13 +950,23 @@ func convertOpenAI2ContentToClaude(content interface{}, role string) interface{}
 		switch partMap["type"] {
 		case "input_text", "output_text":
 			result = append(result, map[string]interface{}{"type": "text", "text": partMap["text"]})
+		case "input_image", "input_file":
+			media, err := mediaFromOpenAI2(partMap)
+			if err != nil {
+				return nil, err
+			}
+			block, err := media.toClaude()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, block)
 		}
 	}
 
 	if len(result) == 1 {
 		if text, ok := result[0]["text"].(string); ok {
-			return text
+			return text, nil
 		}
 	}
-	return result
+	return result, nil
 }
@@ -163,7 +163,7 @@ func TestClaudeReqToOpenAI2PreservesToolChain(t *testing.T) {
 		]
 	}`
 
-	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1")
+	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI2 failed: %v", err)
 	}
@@ -262,7 +262,7 @@ func TestClaudeReqToOpenAI2MapsToolChoiceAnyToRequired(t *testing.T) {
 		"tool_choice": {"type":"any"}
 	}`
 
-	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1")
+	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI2 failed: %v", err)
 	}
@@ -292,7 +292,7 @@ func TestClaudeReqToOpenAI2MapsNamedToolChoice(t *testing.T) {
 		"tool_choice": {"type":"tool","name":"Write"}
 	}`
 
-	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1")
+	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI2 failed: %v", err)
 	}
@@ -319,7 +319,7 @@ func TestClaudeReqToOpenAI2DefaultsToolChoiceRequiredWhenToolsPresent(t *testing
 		"tools": [{"name":"Write","description":"Write file","input_schema":{"type":"object"}}]
 	}`
 
-	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1")
+	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI2 failed: %v", err)
 	}
@@ -345,7 +345,7 @@ func TestClaudeReqToOpenAI2DefaultsToolChoiceAutoAfterToolResult(t *testing.T) {
 		"tools": [{"name":"Read","description":"Read file","input_schema":{"type":"object"}}]
 	}`
 
-	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1")
+	reqBytes, err := ClaudeReqToOpenAI2([]byte(claudeReq), "gpt-4.1", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI2 failed: %v", err)
 	}
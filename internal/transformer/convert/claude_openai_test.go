@@ -29,7 +29,7 @@ func TestOpenAIRespToClaudeWithThinking(t *testing.T) {
 		}
 	}`
 
-	claudeRespBytes, err := OpenAIRespToClaude([]byte(openaiResp))
+	claudeRespBytes, err := OpenAIRespToClaude([]byte(openaiResp), transformer.Options{})
 	if err != nil {
 		t.Fatalf("OpenAIRespToClaude failed: %v", err)
 	}
@@ -86,7 +86,7 @@ func TestOpenAIRespToClaudeWithMultipleThinking(t *testing.T) {
 		}
 	}`
 
-	claudeRespBytes, err := OpenAIRespToClaude([]byte(openaiResp))
+	claudeRespBytes, err := OpenAIRespToClaude([]byte(openaiResp), transformer.Options{})
 	if err != nil {
 		t.Fatalf("OpenAIRespToClaude failed: %v", err)
 	}
@@ -142,7 +142,7 @@ func TestOpenAIStreamToClaudeWithThinking(t *testing.T) {
 
 	var allEvents []string
 	for _, chunk := range chunks {
-		events, err := OpenAIStreamToClaude([]byte(chunk), ctx)
+		events, err := OpenAIStreamToClaude([]byte(chunk), ctx, transformer.Options{})
 		if err != nil {
 			t.Fatalf("OpenAIStreamToClaude failed: %v", err)
 		}
@@ -168,7 +168,7 @@ func TestOpenAIStreamToClaudeUsageHasDelta(t *testing.T) {
 	ctx.ModelName = "claude-3-5-sonnet-20241022"
 
 	chunk := `data: {"id":"usage-1","object":"chat.completion.chunk","created":123,"model":"gpt-4","choices":[{"index":0,"delta":{}}],"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12}}`
-	result, err := OpenAIStreamToClaude([]byte(chunk), ctx)
+	result, err := OpenAIStreamToClaude([]byte(chunk), ctx, transformer.Options{})
 	if err != nil {
 		t.Fatalf("OpenAIStreamToClaude failed: %v", err)
 	}
@@ -211,7 +211,7 @@ func TestOpenAIStreamToClaudeWithThinkingSingleChunk(t *testing.T) {
 
 	chunk := `data: {"id":"1","choices":[{"index":0,"delta":{"content":"<think>Reasoning</think>Hello!"}}]}`
 
-	events, err := OpenAIStreamToClaude([]byte(chunk), ctx)
+	events, err := OpenAIStreamToClaude([]byte(chunk), ctx, transformer.Options{})
 	if err != nil {
 		t.Fatalf("OpenAIStreamToClaude failed: %v", err)
 	}
@@ -240,7 +240,7 @@ func TestOpenAIStreamToClaudeWithThinkingSplitTag(t *testing.T) {
 
 	var allEvents []string
 	for _, chunk := range chunks {
-		events, err := OpenAIStreamToClaude([]byte(chunk), ctx)
+		events, err := OpenAIStreamToClaude([]byte(chunk), ctx, transformer.Options{})
 		if err != nil {
 			t.Fatalf("OpenAIStreamToClaude failed: %v", err)
 		}
@@ -266,7 +266,7 @@ func TestOpenAIStreamToClaudeWithThinkingMissingCloseDone(t *testing.T) {
 
 	var allEvents []string
 	for _, chunk := range chunks {
-		events, err := OpenAIStreamToClaude([]byte(chunk), ctx)
+		events, err := OpenAIStreamToClaude([]byte(chunk), ctx, transformer.Options{})
 		if err != nil {
 			t.Fatalf("OpenAIStreamToClaude failed: %v", err)
 		}
@@ -312,7 +312,7 @@ func TestClaudeReqToOpenAIWithToolUseAndResult(t *testing.T) {
 		"max_tokens": 1024
 	}`
 
-	openaiReqBytes, err := ClaudeReqToOpenAI([]byte(claudeReq), "gpt-4")
+	openaiReqBytes, err := ClaudeReqToOpenAI([]byte(claudeReq), "gpt-4", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI failed: %v", err)
 	}
@@ -362,7 +362,7 @@ func TestClaudeReqToOpenAISkipsInvalidToolBlocks(t *testing.T) {
 		"max_tokens": 128
 	}`
 
-	openaiReqBytes, err := ClaudeReqToOpenAI([]byte(claudeReq), "gpt-4")
+	openaiReqBytes, err := ClaudeReqToOpenAI([]byte(claudeReq), "gpt-4", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI failed: %v", err)
 	}
@@ -408,7 +408,7 @@ func TestClaudeReqToOpenAIThinkingOnly(t *testing.T) {
 		"max_tokens": 1024
 	}`
 
-	openaiReqBytes, err := ClaudeReqToOpenAI([]byte(claudeReq), "gpt-4")
+	openaiReqBytes, err := ClaudeReqToOpenAI([]byte(claudeReq), "gpt-4", transformer.Options{})
 	if err != nil {
 		t.Fatalf("ClaudeReqToOpenAI failed: %v", err)
 	}
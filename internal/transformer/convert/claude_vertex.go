@@ -0,0 +1,38 @@
+package convert
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// VertexAnthropicVersion is the anthropic_version Vertex AI expects in request bodies
+const VertexAnthropicVersion = "vertex-2023-10-16"
+
+// ClaudeReqToVertex adapts a Claude Messages request for Vertex AI rawPredict.
+// Vertex takes the model from the URL, so it is removed; unlike Bedrock the
+// stream field stays in the body.
+func ClaudeReqToVertex(claudeReq []byte) ([]byte, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(claudeReq, &req); err != nil {
+		return nil, err
+	}
+
+	delete(req, "model")
+	if _, ok := req["anthropic_version"]; !ok {
+		req["anthropic_version"] = VertexAnthropicVersion
+	}
+	return json.Marshal(req)
+}
+
+// FilterVertexBetas keeps the anthropic-beta flags Vertex AI accepts. Vertex
+// supports the same flags as Bedrock and rejects requests naming others.
+func FilterVertexBetas(betaHeader string) string {
+	var betas []string
+	for _, beta := range strings.Split(betaHeader, ",") {
+		beta = strings.TrimSpace(beta)
+		if bedrockBetas[beta] {
+			betas = append(betas, beta)
+		}
+	}
+	return strings.Join(betas, ",")
+}
@@ -5,27 +5,27 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/lich0821/ccNexus/internal/logger"
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/schema"
 )
 
-// cleanSchemaForGemini removes fields not supported by Gemini API
-func cleanSchemaForGemini(schema interface{}) interface{} {
-	m, ok := schema.(map[string]interface{})
-	if !ok {
-		return schema
-	}
-	// Remove unsupported fields
-	delete(m, "additionalProperties")
-	delete(m, "$schema")
-	if props, ok := m["properties"].(map[string]interface{}); ok {
-		for k, v := range props {
-			props[k] = cleanSchemaForGemini(v)
-		}
+// normalizeToolSchema adapts a tool parameter schema to the target provider's
+// dialect and logs any constraints that could not be preserved
+func normalizeToolSchema(toolName string, params map[string]interface{}, profile schema.Profile) map[string]interface{} {
+	result := schema.Normalize(params, profile)
+	for _, issue := range result.Issues {
+		logger.Debug("[Schema] %s (%s): %s", toolName, profile, issue)
 	}
-	if items, ok := m["items"]; ok {
-		m["items"] = cleanSchemaForGemini(items)
+	return result.Schema
+}
+
+// openAISchemaProfile returns the OpenAI profile for a tool's strict flag
+func openAISchemaProfile(strict bool) schema.Profile {
+	if strict {
+		return schema.ProfileOpenAIStrict
 	}
-	return m
+	return schema.ProfileOpenAI
 }
 
 // parseSSE parses SSE event data
@@ -131,3 +131,189 @@ func extractSystemText(system interface{}) string {
 	}
 	return ""
 }
+
+// geminiCallIDs assigns tool-call IDs to Gemini function calls and pairs
+// function responses with them. Gemini clients only send IDs they received
+// from the proxy, so calls without one are matched by name in order.
+type geminiCallIDs struct {
+	pending map[string][]string // function name -> unanswered call IDs
+	count   int
+}
+
+func newGeminiCallIDs() *geminiCallIDs {
+	return &geminiCallIDs{pending: make(map[string][]string)}
+}
+
+// call returns the ID for a function call
+func (g *geminiCallIDs) call(fc *transformer.GeminiFunctionCall) string {
+	id := fc.ID
+	if id == "" {
+		id = fmt.Sprintf("call_gemini_%d", g.count)
+	}
+	g.count++
+	g.pending[fc.Name] = append(g.pending[fc.Name], id)
+	return id
+}
+
+// response returns the ID of the call a function response answers
+func (g *geminiCallIDs) response(fr *transformer.GeminiFunctionResponse) string {
+	queue := g.pending[fr.Name]
+	if fr.ID != "" {
+		for i, id := range queue {
+			if id == fr.ID {
+				g.pending[fr.Name] = append(queue[:i:i], queue[i+1:]...)
+				break
+			}
+		}
+		return fr.ID
+	}
+	if len(queue) > 0 {
+		g.pending[fr.Name] = queue[1:]
+		return queue[0]
+	}
+	return fmt.Sprintf("call_%s", fr.Name)
+}
+
+// geminiDeclarationSchema returns the parameter schema of a function declaration
+func geminiDeclarationSchema(fd transformer.GeminiFunctionDeclaration) map[string]interface{} {
+	if fd.Parameters == nil && fd.ParametersJSONSchema != nil {
+		return fd.ParametersJSONSchema
+	}
+	return fd.Parameters
+}
+
+// geminiFunctionResponseText flattens a Gemini function response to tool
+// result text. Gemini CLI wraps results as {"output": "..."}, the converters
+// as {"result": "..."}.
+func geminiFunctionResponseText(response map[string]interface{}) string {
+	if len(response) == 1 {
+		if output, ok := response["output"].(string); ok {
+			return output
+		}
+		if result, ok := response["result"].(string); ok {
+			return result
+		}
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Sprint(response)
+	}
+	return string(data)
+}
+
+// geminiFunctionResponse wraps tool result text as a Gemini function response.
+// A JSON object is the response itself, other text is wrapped as
+// {"result": "..."}.
+func geminiFunctionResponse(text string) map[string]interface{} {
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &response); err == nil && response != nil {
+		return response
+	}
+	return map[string]interface{}{"result": text}
+}
+
+// buildGeminiChunk builds a Gemini stream chunk. The final chunk carries the
+// finish reason and usage.
+func buildGeminiChunk(parts []map[string]interface{}, finishReason string, usage map[string]interface{}) []byte {
+	candidate := map[string]interface{}{
+		"content": map[string]interface{}{"role": "model", "parts": parts},
+		"index":   0,
+	}
+	if finishReason != "" {
+		candidate["finishReason"] = finishReason
+	}
+	chunk := map[string]interface{}{"candidates": []map[string]interface{}{candidate}}
+	if usage != nil {
+		chunk["usageMetadata"] = usage
+	}
+	data, _ := json.Marshal(chunk)
+	return []byte(fmt.Sprintf("data: %s\n\n", data))
+}
+
+// finishGeminiStream builds the final chunk of a Gemini client stream once
+func finishGeminiStream(ctx *transformer.StreamContext) []byte {
+	if ctx.FinishReasonSent {
+		return nil
+	}
+	ctx.FinishReasonSent = true
+	finishReason := ctx.PendingFinishReason
+	if finishReason == "" {
+		finishReason = "STOP"
+	}
+	return buildGeminiChunk([]map[string]interface{}{{"text": ""}}, finishReason, geminiUsageMetadata(ctx.InputTokens, ctx.OutputTokens))
+}
+
+func geminiUsageMetadata(inputTokens, outputTokens int) map[string]interface{} {
+	return map[string]interface{}{
+		"promptTokenCount":     inputTokens,
+		"candidatesTokenCount": outputTokens,
+		"totalTokenCount":      inputTokens + outputTokens,
+	}
+}
+
+// geminiFinishReason maps Claude stop reasons and OpenAI finish reasons to
+// Gemini. Tool calls finish with STOP in Gemini.
+func geminiFinishReason(reason string) string {
+	switch reason {
+	case "max_tokens", "length", "max_output_tokens":
+		return "MAX_TOKENS"
+	case "refusal", "content_filter":
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+// claudeStopReason maps OpenAI, Responses and Gemini finish reasons to a
+// Claude stop reason
+func claudeStopReason(reason string, toolUse bool) string {
+	switch reason {
+	case "max_tokens", "length", "max_output_tokens", "MAX_TOKENS":
+		return "max_tokens"
+	case "refusal", "content_filter", "SAFETY":
+		return "refusal"
+	}
+	if toolUse {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+// openAIFinishReason maps Claude, Responses and Gemini finish reasons to an
+// OpenAI finish reason
+func openAIFinishReason(reason string, toolCalls bool) string {
+	switch reason {
+	case "max_tokens", "length", "max_output_tokens", "MAX_TOKENS":
+		return "length"
+	case "refusal", "content_filter", "SAFETY":
+		return "content_filter"
+	}
+	if toolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// openAI2IncompleteReason returns the Responses incomplete_details reason for
+// a finish reason, or "" when the response completed
+func openAI2IncompleteReason(reason string) string {
+	switch reason {
+	case "max_tokens", "length", "max_output_tokens", "MAX_TOKENS":
+		return "max_output_tokens"
+	case "refusal", "content_filter", "SAFETY":
+		return "content_filter"
+	}
+	return ""
+}
+
+// setOpenAI2Status sets the status of a Responses response for a finish
+// reason and returns the matching terminal stream event type
+func setOpenAI2Status(resp map[string]interface{}, reason string) string {
+	if incomplete := openAI2IncompleteReason(reason); incomplete != "" {
+		resp["status"] = "incomplete"
+		resp["incomplete_details"] = map[string]interface{}{"reason": incomplete}
+		return "response.incomplete"
+	}
+	resp["status"] = "completed"
+	return "response.completed"
+}
@@ -0,0 +1,195 @@
+package convert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+const geminiToolTurnRequest = `{
+	"systemInstruction":{"parts":[{"text":"Be brief."}]},
+	"contents":[
+		{"role":"user","parts":[{"text":"Weather in Paris?"}]},
+		{"role":"model","parts":[{"functionCall":{"id":"fc_1","name":"weather","args":{"city":"Paris"}}}]},
+		{"role":"user","parts":[{"functionResponse":{"id":"fc_1","name":"weather","response":{"output":"Sunny"}}}]}
+	],
+	"tools":[{"functionDeclarations":[{"name":"weather","parametersJsonSchema":{"type":"object","properties":{"city":{"type":"string"}}}}]}]
+}`
+
+func TestGeminiReqToClaudeKeepsCallIDs(t *testing.T) {
+	out, err := GeminiReqToClaude([]byte(geminiToolTurnRequest), "claude", transformer.Options{})
+	if err != nil {
+		t.Fatalf("GeminiReqToClaude failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"id":"fc_1","input":{"city":"Paris"},"name":"weather","type":"tool_use"`, "function call should become tool_use with its id")
+	assertContains(t, result, `"content":"Sunny","tool_use_id":"fc_1","type":"tool_result"`, "function response should be unwrapped into tool_result text")
+	assertContains(t, result, `"input_schema":{"properties":{"city":{"type":"string"}},"type":"object"}`, "parametersJsonSchema should be used as input_schema")
+}
+
+func TestGeminiReqToClaudeGeneratesMissingCallIDs(t *testing.T) {
+	req := `{"contents":[
+		{"role":"model","parts":[{"functionCall":{"name":"weather","args":{"city":"Paris"}}},{"functionCall":{"name":"weather","args":{"city":"Rome"}}}]},
+		{"role":"user","parts":[{"functionResponse":{"name":"weather","response":{"output":"Sunny"}}},{"functionResponse":{"name":"weather","response":{"output":"Rainy"}}}]}
+	]}`
+
+	out, err := GeminiReqToClaude([]byte(req), "claude", transformer.Options{})
+	if err != nil {
+		t.Fatalf("GeminiReqToClaude failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"content":"Sunny","tool_use_id":"call_gemini_0"`, "first response should pair with first call")
+	assertContains(t, result, `"content":"Rainy","tool_use_id":"call_gemini_1"`, "second response should pair with second call")
+}
+
+func TestGeminiReqToOpenAIWithToolTurn(t *testing.T) {
+	out, err := GeminiReqToOpenAI([]byte(geminiToolTurnRequest), "gpt-4.1", transformer.Options{})
+	if err != nil {
+		t.Fatalf("GeminiReqToOpenAI failed: %v", err)
+	}
+
+	var payload struct {
+		Messages []map[string]interface{} `json:"messages"`
+		Tools    []interface{}            `json:"tools"`
+	}
+	if err := json.Unmarshal(out, &payload); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(payload.Messages) != 4 {
+		t.Fatalf("expected system, user, assistant and tool messages, got %v", payload.Messages)
+	}
+	if payload.Messages[0]["role"] != "system" || payload.Messages[0]["content"] != "Be brief." {
+		t.Fatalf("expected system message first, got %v", payload.Messages[0])
+	}
+	call := payload.Messages[2]["tool_calls"].([]interface{})[0].(map[string]interface{})
+	if call["id"] != "fc_1" || call["function"].(map[string]interface{})["arguments"] != `{"city":"Paris"}` {
+		t.Fatalf("unexpected tool call: %v", call)
+	}
+	if payload.Messages[3]["role"] != "tool" || payload.Messages[3]["tool_call_id"] != "fc_1" || payload.Messages[3]["content"] != "Sunny" {
+		t.Fatalf("unexpected tool message: %v", payload.Messages[3])
+	}
+	if len(payload.Tools) != 1 {
+		t.Fatalf("expected one tool, got %v", payload.Tools)
+	}
+}
+
+func TestGeminiReqToOpenAIStreamFlag(t *testing.T) {
+	out, err := GeminiReqToOpenAI([]byte(`{"stream":true,"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`), "gpt-4.1", transformer.Options{})
+	if err != nil {
+		t.Fatalf("GeminiReqToOpenAI failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"stream":true`, "stream flag should be forwarded")
+	assertContains(t, result, `"include_usage":true`, "usage should be requested for the final chunk")
+}
+
+func TestGeminiReqToOpenAI2WithToolTurn(t *testing.T) {
+	out, err := GeminiReqToOpenAI2([]byte(geminiToolTurnRequest), "gpt-5", transformer.Options{})
+	if err != nil {
+		t.Fatalf("GeminiReqToOpenAI2 failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"instructions":"Be brief."`, "system instruction should become instructions")
+	assertContains(t, result, `"arguments":"{\"city\":\"Paris\"}","call_id":"fc_1","name":"weather","type":"function_call"`, "function call should become a function_call item")
+	assertContains(t, result, `"call_id":"fc_1","output":"Sunny","type":"function_call_output"`, "function response should become a function_call_output item")
+}
+
+func TestClaudeRespToGeminiToolUse(t *testing.T) {
+	resp := `{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[
+		{"type":"text","text":"Checking."},
+		{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Paris"}}
+	],"stop_reason":"tool_use","usage":{"input_tokens":3,"output_tokens":4}}`
+
+	out, err := ClaudeRespToGemini([]byte(resp))
+	if err != nil {
+		t.Fatalf("ClaudeRespToGemini failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"functionCall":{"args":{"city":"Paris"},"id":"toolu_1","name":"weather"}`, "tool_use should keep its id")
+	assertContains(t, result, `"finishReason":"STOP"`, "tool use should finish with STOP")
+	assertContains(t, result, `"totalTokenCount":7`, "usage should be summed")
+}
+
+func TestClaudeStreamToGeminiToolCall(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	events := []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"input_tokens\":3}}}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Checking.\"}}\n\n",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"weather\",\"input\":{}}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"city\\\":\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"Paris\\\"}\"}}\n\n",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":5}}\n\n",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+	}
+
+	var out strings.Builder
+	for _, evt := range events {
+		chunk, err := ClaudeStreamToGemini([]byte(evt), ctx)
+		if err != nil {
+			t.Fatalf("ClaudeStreamToGemini failed: %v", err)
+		}
+		out.Write(chunk)
+	}
+	result := out.String()
+
+	assertContains(t, result, `"text":"Checking."`, "text delta should be streamed")
+	assertContains(t, result, `"functionCall":{"args":{"city":"Paris"},"id":"toolu_1","name":"weather"}`, "buffered tool call should be emitted whole")
+	assertContains(t, result, `"finishReason":"STOP"`, "final chunk should carry the finish reason")
+	assertContains(t, result, `"promptTokenCount":3`, "final chunk should carry usage")
+	assertNotContains(t, result, "[DONE]", "Gemini streams do not end with [DONE]")
+	if strings.Count(result, "finishReason") != 1 {
+		t.Fatalf("expected exactly one finish chunk, got %s", result)
+	}
+}
+
+func TestOpenAIStreamToGeminiToolCallAndUsage(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	events := []string{
+		`data: {"choices":[{"index":0,"delta":{"role":"assistant","content":"Checking."}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":"}}]}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{},"finish_reason":"length"}]}` + "\n\n",
+		`data: {"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":5,"total_tokens":8}}` + "\n\n",
+		"data: [DONE]\n\n",
+	}
+
+	var out strings.Builder
+	for _, evt := range events {
+		chunk, err := OpenAIStreamToGemini([]byte(evt), ctx, transformer.Options{})
+		if err != nil {
+			t.Fatalf("OpenAIStreamToGemini failed: %v", err)
+		}
+		out.Write(chunk)
+	}
+	result := out.String()
+
+	assertContains(t, result, `"text":"Checking."`, "content delta should be streamed")
+	assertContains(t, result, `"functionCall":{"args":{"city":"Paris"},"id":"call_1","name":"weather"}`, "tool call should be emitted once complete")
+	assertContains(t, result, `"finishReason":"MAX_TOKENS"`, "length should map to MAX_TOKENS")
+	assertContains(t, result, `"totalTokenCount":8`, "final chunk should carry usage")
+	assertNotContains(t, result, "[DONE]", "Gemini streams do not end with [DONE]")
+	if strings.Count(result, "finishReason") != 1 {
+		t.Fatalf("expected exactly one finish chunk, got %s", result)
+	}
+}
+
+func TestOpenAI2RespToGeminiFunctionCall(t *testing.T) {
+	resp := `{"id":"resp_1","status":"completed","model":"gpt-5","output":[
+		{"type":"reasoning","summary":[{"type":"summary_text","text":"Thinking."}]},
+		{"type":"function_call","call_id":"call_1","name":"weather","arguments":"{\"city\":\"Paris\"}"}
+	],"usage":{"input_tokens":3,"output_tokens":5}}`
+
+	out, err := OpenAI2RespToGemini([]byte(resp))
+	if err != nil {
+		t.Fatalf("OpenAI2RespToGemini failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"text":"Thinking.","thought":true`, "reasoning summary should become a thought part")
+	assertContains(t, result, `"functionCall":{"args":{"city":"Paris"},"id":"call_1","name":"weather"}`, "function call should keep call_id")
+	assertContains(t, result, `"candidatesTokenCount":5`, "usage should be mapped")
+}
@@ -0,0 +1,555 @@
+package convert
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+)
+
+// Inline payload limits enforced before forwarding, measured on decoded bytes.
+const (
+	claudeMaxImageBytes    = 5 * 1024 * 1024
+	claudeMaxDocumentBytes = 32 * 1024 * 1024
+	openAIMaxImageBytes    = 20 * 1024 * 1024
+	openAIMaxFileBytes     = 32 * 1024 * 1024
+	geminiMaxInlineBytes   = 20 * 1024 * 1024
+)
+
+// Target format names used in MediaError.
+const (
+	mediaTargetClaude  = "claude"
+	mediaTargetOpenAI  = "openai"
+	mediaTargetOpenAI2 = "openai2"
+	mediaTargetGemini  = "gemini"
+)
+
+type mediaKind string
+
+const (
+	mediaKindImage mediaKind = "image"
+	mediaKindFile  mediaKind = "file"
+	mediaKindAudio mediaKind = "audio"
+)
+
+// File ID namespaces; IDs issued by one provider cannot be resolved by another.
+const (
+	fileIDProviderAnthropic = "anthropic"
+	fileIDProviderOpenAI    = "openai"
+)
+
+// MediaError reports a content part that the target format cannot accept.
+type MediaError struct {
+	Target    string
+	MediaType string
+	Reason    string
+}
+
+func (e *MediaError) Error() string {
+	mediaType := e.MediaType
+	if mediaType == "" {
+		mediaType = "media"
+	}
+	return fmt.Sprintf("%s target cannot accept %s: %s", e.Target, mediaType, e.Reason)
+}
+
+// mediaPart is the format-neutral form of an image, document, file or audio part.
+// Exactly one of Data, URL or FileID carries the payload.
+type mediaPart struct {
+	Kind           mediaKind
+	MediaType      string
+	Data           string // base64 without the data: prefix
+	URL            string
+	FileID         string
+	FileIDProvider string
+	Filename       string
+	Detail         string
+}
+
+// decodedSize returns the byte size of the base64 payload.
+func (p *mediaPart) decodedSize() int {
+	data := strings.TrimRight(p.Data, "=")
+	return len(data) * 3 / 4
+}
+
+func (p *mediaPart) isText() bool {
+	return p.MediaType == "text/plain" && p.Data != ""
+}
+
+// text decodes a text/plain payload.
+func (p *mediaPart) text() (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(p.Data)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 text document: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func (p *mediaPart) dataURL() string {
+	return "data:" + p.MediaType + ";base64," + p.Data
+}
+
+func (p *mediaPart) errorf(target, format string, args ...interface{}) error {
+	return &MediaError{Target: target, MediaType: p.MediaType, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (p *mediaPart) checkSize(target string, limit int) error {
+	if p.Data == "" {
+		return nil
+	}
+	if size := p.decodedSize(); size > limit {
+		return p.errorf(target, "inline payload is %d bytes, limit is %d bytes", size, limit)
+	}
+	return nil
+}
+
+func (p *mediaPart) checkFileID(target, provider string) error {
+	if p.FileIDProvider != provider {
+		return p.errorf(target, "file_id %q was issued by %s and cannot be resolved", p.FileID, p.FileIDProvider)
+	}
+	return nil
+}
+
+// parseDataURL splits a data: URL into media type and base64 payload.
+func parseDataURL(url string) (mediaType, data string, ok bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", "", false
+	}
+	parts := strings.SplitN(url, ",", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	mediaType = strings.TrimPrefix(strings.Split(parts[0], ";")[0], "data:")
+	return mediaType, parts[1], true
+}
+
+// mediaTypeFromURL guesses a media type from the URL path extension.
+func mediaTypeFromURL(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	ext := strings.ToLower(path.Ext(url))
+	if ext == "" {
+		return ""
+	}
+	mediaType := mime.TypeByExtension(ext)
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return mediaType
+}
+
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+func kindForMediaType(mediaType string) mediaKind {
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return mediaKindImage
+	case strings.HasPrefix(mediaType, "audio/"):
+		return mediaKindAudio
+	default:
+		return mediaKindFile
+	}
+}
+
+func isCommonImageType(mediaType string) bool {
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Parsers
+
+// mediaFromClaude parses a Claude image or document block.
+func mediaFromClaude(block map[string]interface{}) (*mediaPart, error) {
+	blockType, _ := block["type"].(string)
+	kind := mediaKindImage
+	if blockType == "document" {
+		kind = mediaKindFile
+	}
+	source, _ := block["source"].(map[string]interface{})
+	if source == nil {
+		return nil, fmt.Errorf("claude %s block has no source", blockType)
+	}
+
+	part := &mediaPart{Kind: kind}
+	part.MediaType, _ = source["media_type"].(string)
+	if title, ok := block["title"].(string); ok {
+		part.Filename = title
+	}
+
+	switch source["type"] {
+	case "base64":
+		part.Data, _ = source["data"].(string)
+	case "url":
+		part.URL, _ = source["url"].(string)
+		if part.MediaType == "" {
+			part.MediaType = mediaTypeFromURL(part.URL)
+		}
+	case "file":
+		part.FileID, _ = source["file_id"].(string)
+		part.FileIDProvider = fileIDProviderAnthropic
+	case "text":
+		text, _ := source["data"].(string)
+		part.MediaType = "text/plain"
+		part.Data = base64.StdEncoding.EncodeToString([]byte(text))
+	default:
+		return nil, fmt.Errorf("unsupported claude %s source type %v", blockType, source["type"])
+	}
+	return part, nil
+}
+
+// mediaFromOpenAI parses an OpenAI Chat image_url, file or input_audio part.
+func mediaFromOpenAI(item map[string]interface{}) (*mediaPart, error) {
+	switch item["type"] {
+	case "image_url":
+		part := &mediaPart{Kind: mediaKindImage}
+		var url string
+		switch v := item["image_url"].(type) {
+		case string:
+			url = v
+		case map[string]interface{}:
+			url, _ = v["url"].(string)
+			part.Detail, _ = v["detail"].(string)
+		}
+		if mediaType, data, ok := parseDataURL(url); ok {
+			part.MediaType, part.Data = mediaType, data
+		} else {
+			part.URL = url
+			part.MediaType = mediaTypeFromURL(url)
+		}
+		return part, nil
+	case "file":
+		file, _ := item["file"].(map[string]interface{})
+		part := &mediaPart{Kind: mediaKindFile}
+		part.Filename, _ = file["filename"].(string)
+		if fileID, _ := file["file_id"].(string); fileID != "" {
+			part.FileID = fileID
+			part.FileIDProvider = fileIDProviderOpenAI
+			part.MediaType = mediaTypeFromURL(part.Filename)
+			return part, nil
+		}
+		fileData, _ := file["file_data"].(string)
+		if mediaType, data, ok := parseDataURL(fileData); ok {
+			part.MediaType, part.Data = mediaType, data
+		} else {
+			part.Data = fileData
+			part.MediaType = mediaTypeFromURL(part.Filename)
+		}
+		return part, nil
+	case "input_audio":
+		audio, _ := item["input_audio"].(map[string]interface{})
+		part := &mediaPart{Kind: mediaKindAudio}
+		part.Data, _ = audio["data"].(string)
+		format, _ := audio["format"].(string)
+		part.MediaType = "audio/" + format
+		return part, nil
+	}
+	return nil, fmt.Errorf("unsupported openai content part type %v", item["type"])
+}
+
+// mediaFromOpenAI2 parses a Responses API input_image or input_file part.
+func mediaFromOpenAI2(item map[string]interface{}) (*mediaPart, error) {
+	switch item["type"] {
+	case "input_image":
+		part := &mediaPart{Kind: mediaKindImage}
+		part.Detail, _ = item["detail"].(string)
+		if fileID, _ := item["file_id"].(string); fileID != "" {
+			part.FileID = fileID
+			part.FileIDProvider = fileIDProviderOpenAI
+			return part, nil
+		}
+		url, _ := item["image_url"].(string)
+		if mediaType, data, ok := parseDataURL(url); ok {
+			part.MediaType, part.Data = mediaType, data
+		} else {
+			part.URL = url
+			part.MediaType = mediaTypeFromURL(url)
+		}
+		return part, nil
+	case "input_file":
+		part := &mediaPart{Kind: mediaKindFile}
+		part.Filename, _ = item["filename"].(string)
+		if fileID, _ := item["file_id"].(string); fileID != "" {
+			part.FileID = fileID
+			part.FileIDProvider = fileIDProviderOpenAI
+			part.MediaType = mediaTypeFromURL(part.Filename)
+			return part, nil
+		}
+		if fileURL, _ := item["file_url"].(string); fileURL != "" {
+			part.URL = fileURL
+			part.MediaType = mediaTypeFromURL(fileURL)
+			return part, nil
+		}
+		fileData, _ := item["file_data"].(string)
+		if mediaType, data, ok := parseDataURL(fileData); ok {
+			part.MediaType, part.Data = mediaType, data
+		} else {
+			part.Data = fileData
+			part.MediaType = mediaTypeFromURL(part.Filename)
+		}
+		return part, nil
+	}
+	return nil, fmt.Errorf("unsupported openai2 content part type %v", item["type"])
+}
+
+// mediaFromGemini parses a Gemini inlineData or fileData payload.
+func mediaFromGemini(mimeType, data, fileURI string) *mediaPart {
+	part := &mediaPart{MediaType: mimeType, Kind: kindForMediaType(mimeType)}
+	if fileURI != "" {
+		part.URL = fileURI
+		if part.MediaType == "" {
+			part.MediaType = mediaTypeFromURL(fileURI)
+			part.Kind = kindForMediaType(part.MediaType)
+		}
+	} else {
+		part.Data = data
+	}
+	return part
+}
+
+// Emitters
+
+// toClaude builds a Claude content block for the media part.
+func (p *mediaPart) toClaude() (map[string]interface{}, error) {
+	if p.Kind == mediaKindAudio {
+		return nil, p.errorf(mediaTargetClaude, "audio input is not supported")
+	}
+	if p.FileID != "" {
+		if err := p.checkFileID(mediaTargetClaude, fileIDProviderAnthropic); err != nil {
+			return nil, err
+		}
+		blockType := "document"
+		if p.Kind == mediaKindImage {
+			blockType = "image"
+		}
+		return map[string]interface{}{
+			"type":   blockType,
+			"source": map[string]interface{}{"type": "file", "file_id": p.FileID},
+		}, nil
+	}
+
+	if p.Kind == mediaKindImage {
+		if p.URL != "" {
+			if !isHTTPURL(p.URL) {
+				return nil, p.errorf(mediaTargetClaude, "image URL %q is not an http(s) URL", p.URL)
+			}
+			return map[string]interface{}{
+				"type":   "image",
+				"source": map[string]interface{}{"type": "url", "url": p.URL},
+			}, nil
+		}
+		if !isCommonImageType(p.MediaType) {
+			return nil, p.errorf(mediaTargetClaude, "only jpeg, png, gif and webp images are supported")
+		}
+		if err := p.checkSize(mediaTargetClaude, claudeMaxImageBytes); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"type":   "image",
+			"source": map[string]interface{}{"type": "base64", "media_type": p.MediaType, "data": p.Data},
+		}, nil
+	}
+
+	var source map[string]interface{}
+	switch {
+	case p.isText():
+		text, err := p.text()
+		if err != nil {
+			return nil, err
+		}
+		source = map[string]interface{}{"type": "text", "media_type": "text/plain", "data": text}
+	case p.MediaType != "application/pdf":
+		return nil, p.errorf(mediaTargetClaude, "only PDF and plain text documents are supported")
+	case p.URL != "":
+		if !isHTTPURL(p.URL) {
+			return nil, p.errorf(mediaTargetClaude, "document URL %q is not an http(s) URL", p.URL)
+		}
+		source = map[string]interface{}{"type": "url", "url": p.URL}
+	default:
+		if err := p.checkSize(mediaTargetClaude, claudeMaxDocumentBytes); err != nil {
+			return nil, err
+		}
+		source = map[string]interface{}{"type": "base64", "media_type": p.MediaType, "data": p.Data}
+	}
+	block := map[string]interface{}{"type": "document", "source": source}
+	if p.Filename != "" {
+		block["title"] = p.Filename
+	}
+	return block, nil
+}
+
+// toOpenAI builds an OpenAI Chat content part for the media part.
+func (p *mediaPart) toOpenAI() (map[string]interface{}, error) {
+	if p.FileID != "" {
+		if err := p.checkFileID(mediaTargetOpenAI, fileIDProviderOpenAI); err != nil {
+			return nil, err
+		}
+		if p.Kind == mediaKindImage {
+			return nil, p.errorf(mediaTargetOpenAI, "image file_id references are not supported by Chat Completions")
+		}
+		file := map[string]interface{}{"file_id": p.FileID}
+		if p.Filename != "" {
+			file["filename"] = p.Filename
+		}
+		return map[string]interface{}{"type": "file", "file": file}, nil
+	}
+
+	switch p.Kind {
+	case mediaKindImage:
+		url := p.URL
+		if url == "" {
+			if !isCommonImageType(p.MediaType) {
+				return nil, p.errorf(mediaTargetOpenAI, "only jpeg, png, gif and webp images are supported")
+			}
+			if err := p.checkSize(mediaTargetOpenAI, openAIMaxImageBytes); err != nil {
+				return nil, err
+			}
+			url = p.dataURL()
+		} else if !isHTTPURL(url) {
+			return nil, p.errorf(mediaTargetOpenAI, "image URL %q is not an http(s) URL", url)
+		}
+		imageURL := map[string]interface{}{"url": url}
+		if p.Detail != "" {
+			imageURL["detail"] = p.Detail
+		}
+		return map[string]interface{}{"type": "image_url", "image_url": imageURL}, nil
+	case mediaKindAudio:
+		format := strings.TrimPrefix(p.MediaType, "audio/")
+		switch format {
+		case "mpeg":
+			format = "mp3"
+		case "x-wav":
+			format = "wav"
+		}
+		if (format != "wav" && format != "mp3") || p.Data == "" {
+			return nil, p.errorf(mediaTargetOpenAI, "only inline wav and mp3 audio is supported")
+		}
+		return map[string]interface{}{
+			"type":        "input_audio",
+			"input_audio": map[string]interface{}{"data": p.Data, "format": format},
+		}, nil
+	}
+
+	if p.isText() {
+		text, err := p.text()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "text", "text": text}, nil
+	}
+	if p.URL != "" {
+		return nil, p.errorf(mediaTargetOpenAI, "file URLs are not supported by Chat Completions")
+	}
+	if p.MediaType != "application/pdf" {
+		return nil, p.errorf(mediaTargetOpenAI, "only PDF files are supported")
+	}
+	if err := p.checkSize(mediaTargetOpenAI, openAIMaxFileBytes); err != nil {
+		return nil, err
+	}
+	file := map[string]interface{}{"file_data": p.dataURL()}
+	if p.Filename != "" {
+		file["filename"] = p.Filename
+	} else {
+		file["filename"] = "document.pdf"
+	}
+	return map[string]interface{}{"type": "file", "file": file}, nil
+}
+
+// toOpenAI2 builds a Responses API content part for the media part.
+func (p *mediaPart) toOpenAI2() (map[string]interface{}, error) {
+	if p.Kind == mediaKindAudio {
+		return nil, p.errorf(mediaTargetOpenAI2, "audio input is not supported")
+	}
+	if p.FileID != "" {
+		if err := p.checkFileID(mediaTargetOpenAI2, fileIDProviderOpenAI); err != nil {
+			return nil, err
+		}
+		if p.Kind == mediaKindImage {
+			part := map[string]interface{}{"type": "input_image", "file_id": p.FileID}
+			if p.Detail != "" {
+				part["detail"] = p.Detail
+			}
+			return part, nil
+		}
+		return map[string]interface{}{"type": "input_file", "file_id": p.FileID}, nil
+	}
+
+	if p.Kind == mediaKindImage {
+		url := p.URL
+		if url == "" {
+			if !isCommonImageType(p.MediaType) {
+				return nil, p.errorf(mediaTargetOpenAI2, "only jpeg, png, gif and webp images are supported")
+			}
+			if err := p.checkSize(mediaTargetOpenAI2, openAIMaxImageBytes); err != nil {
+				return nil, err
+			}
+			url = p.dataURL()
+		} else if !isHTTPURL(url) {
+			return nil, p.errorf(mediaTargetOpenAI2, "image URL %q is not an http(s) URL", url)
+		}
+		detail := p.Detail
+		if detail == "" {
+			detail = "auto"
+		}
+		return map[string]interface{}{"type": "input_image", "image_url": url, "detail": detail}, nil
+	}
+
+	if p.isText() {
+		text, err := p.text()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "input_text", "text": text}, nil
+	}
+	if p.URL != "" {
+		if !isHTTPURL(p.URL) {
+			return nil, p.errorf(mediaTargetOpenAI2, "file URL %q is not an http(s) URL", p.URL)
+		}
+		return map[string]interface{}{"type": "input_file", "file_url": p.URL}, nil
+	}
+	if p.MediaType != "application/pdf" {
+		return nil, p.errorf(mediaTargetOpenAI2, "only PDF files are supported")
+	}
+	if err := p.checkSize(mediaTargetOpenAI2, openAIMaxFileBytes); err != nil {
+		return nil, err
+	}
+	filename := p.Filename
+	if filename == "" {
+		filename = "document.pdf"
+	}
+	return map[string]interface{}{"type": "input_file", "filename": filename, "file_data": p.dataURL()}, nil
+}
+
+// toGemini builds a Gemini inlineData or fileData part for the media part.
+func (p *mediaPart) toGemini() (map[string]interface{}, error) {
+	if p.FileID != "" {
+		return nil, p.errorf(mediaTargetGemini, "file_id %q was issued by %s and cannot be resolved", p.FileID, p.FileIDProvider)
+	}
+	if p.MediaType == "" {
+		return nil, p.errorf(mediaTargetGemini, "media type is unknown and cannot be inferred")
+	}
+	switch strings.SplitN(p.MediaType, "/", 2)[0] {
+	case "image", "audio", "video", "text":
+	default:
+		if p.MediaType != "application/pdf" {
+			return nil, p.errorf(mediaTargetGemini, "unsupported media type")
+		}
+	}
+	if p.URL != "" {
+		return map[string]interface{}{
+			"fileData": map[string]interface{}{"mimeType": p.MediaType, "fileUri": p.URL},
+		}, nil
+	}
+	if err := p.checkSize(mediaTargetGemini, geminiMaxInlineBytes); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"inlineData": map[string]interface{}{"mimeType": p.MediaType, "data": p.Data},
+	}, nil
+}
@@ -0,0 +1,254 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+const (
+	testPNGData = "iVBORw0KGgo="
+	testPDFData = "JVBERi0xLjQ="
+)
+
+var (
+	claudeMediaRequest = `{
+		"model": "claude-sonnet-4",
+		"max_tokens": 1024,
+		"messages": [{
+			"role": "user",
+			"content": [
+				{"type": "text", "text": "describe"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "` + testPNGData + `"}},
+				{"type": "image", "source": {"type": "url", "url": "https://example.com/cat.jpg"}},
+				{"type": "document", "title": "spec.pdf", "source": {"type": "base64", "media_type": "application/pdf", "data": "` + testPDFData + `"}}
+			]
+		}]
+	}`
+
+	openAIMediaRequest = `{
+		"model": "gpt-4o",
+		"messages": [{
+			"role": "user",
+			"content": [
+				{"type": "text", "text": "describe"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,` + testPNGData + `"}},
+				{"type": "image_url", "image_url": {"url": "https://example.com/cat.jpg"}},
+				{"type": "file", "file": {"filename": "spec.pdf", "file_data": "data:application/pdf;base64,` + testPDFData + `"}}
+			]
+		}]
+	}`
+
+	openAI2MediaRequest = `{
+		"model": "gpt-4o",
+		"input": [{
+			"type": "message",
+			"role": "user",
+			"content": [
+				{"type": "input_text", "text": "describe"},
+				{"type": "input_image", "image_url": "data:image/png;base64,` + testPNGData + `"},
+				{"type": "input_image", "image_url": "https://example.com/cat.jpg"},
+				{"type": "input_file", "filename": "spec.pdf", "file_data": "data:application/pdf;base64,` + testPDFData + `"}
+			]
+		}]
+	}`
+
+	geminiMediaRequest = `{
+		"contents": [{
+			"role": "user",
+			"parts": [
+				{"text": "describe"},
+				{"inlineData": {"mimeType": "image/png", "data": "` + testPNGData + `"}},
+				{"fileData": {"mimeType": "application/pdf", "fileUri": "https://example.com/spec.pdf"}}
+			]
+		}]
+	}`
+)
+
+// Expected content for each target format.
+var (
+	claudeMediaGolden = `[
+		{"type": "text", "text": "describe"},
+		{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "` + testPNGData + `"}},
+		{"type": "image", "source": {"type": "url", "url": "https://example.com/cat.jpg"}},
+		{"type": "document", "title": "spec.pdf", "source": {"type": "base64", "media_type": "application/pdf", "data": "` + testPDFData + `"}}
+	]`
+
+	openAIMediaGolden = `[
+		{"type": "text", "text": "describe"},
+		{"type": "image_url", "image_url": {"url": "data:image/png;base64,` + testPNGData + `"}},
+		{"type": "image_url", "image_url": {"url": "https://example.com/cat.jpg"}},
+		{"type": "file", "file": {"filename": "spec.pdf", "file_data": "data:application/pdf;base64,` + testPDFData + `"}}
+	]`
+
+	openAI2MediaGolden = `[
+		{"type": "input_text", "text": "describe"},
+		{"type": "input_image", "image_url": "data:image/png;base64,` + testPNGData + `", "detail": "auto"},
+		{"type": "input_image", "image_url": "https://example.com/cat.jpg", "detail": "auto"},
+		{"type": "input_file", "filename": "spec.pdf", "file_data": "data:application/pdf;base64,` + testPDFData + `"}
+	]`
+
+	geminiMediaGolden = `[
+		{"text": "describe"},
+		{"inlineData": {"mimeType": "image/png", "data": "` + testPNGData + `"}},
+		{"fileData": {"mimeType": "image/jpeg", "fileUri": "https://example.com/cat.jpg"}},
+		{"inlineData": {"mimeType": "application/pdf", "data": "` + testPDFData + `"}}
+	]`
+)
+
+func TestMediaConversionGolden(t *testing.T) {
+	claudeContent := []string{"messages", "0", "content"}
+	openAIContent := []string{"messages", "0", "content"}
+	openAI2Content := []string{"input", "0", "content"}
+	geminiParts := []string{"contents", "0", "parts"}
+
+	tests := []struct {
+		name    string
+		convert func([]byte, string, transformer.Options) ([]byte, error)
+		request string
+		path    []string
+		golden  string
+	}{
+		{"claude to openai", ClaudeReqToOpenAI, claudeMediaRequest, openAIContent, openAIMediaGolden},
+		{"claude to openai2", ClaudeReqToOpenAI2, claudeMediaRequest, openAI2Content, openAI2MediaGolden},
+		{"claude to gemini", ClaudeReqToGemini, claudeMediaRequest, geminiParts, geminiMediaGolden},
+		{"openai to claude", OpenAIReqToClaude, openAIMediaRequest, claudeContent, claudeMediaGolden},
+		{"openai to openai2", OpenAIReqToOpenAI2, openAIMediaRequest, openAI2Content, openAI2MediaGolden},
+		{"openai to gemini", OpenAIReqToGemini, openAIMediaRequest, geminiParts, geminiMediaGolden},
+		{"openai2 to claude", OpenAI2ReqToClaude, openAI2MediaRequest, claudeContent, claudeMediaGolden},
+		{"openai2 to openai", OpenAI2ReqToOpenAI, openAI2MediaRequest, openAIContent, openAIMediaGolden},
+		{"openai2 to gemini", OpenAI2ReqToGemini, openAI2MediaRequest, geminiParts, geminiMediaGolden},
+		{"gemini to claude", GeminiReqToClaude, geminiMediaRequest, claudeContent, `[
+			{"type": "text", "text": "describe"},
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "` + testPNGData + `"}},
+			{"type": "document", "source": {"type": "url", "url": "https://example.com/spec.pdf"}}
+		]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.convert([]byte(tt.request), "target-model", transformer.Options{})
+			if err != nil {
+				t.Fatalf("convert failed: %v", err)
+			}
+			var payload interface{}
+			if err := json.Unmarshal(out, &payload); err != nil {
+				t.Fatalf("unmarshal output failed: %v", err)
+			}
+			got := lookupJSONPath(t, payload, tt.path)
+
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.golden), &want); err != nil {
+				t.Fatalf("unmarshal golden failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(want)
+				t.Fatalf("content mismatch\n got: %s\nwant: %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestMediaConversionErrors(t *testing.T) {
+	oversizedPNG := strings.Repeat("A", 7*1024*1024)
+
+	tests := []struct {
+		name    string
+		convert func([]byte, string, transformer.Options) ([]byte, error)
+		request string
+		reason  string
+	}{
+		{
+			name:    "claude pdf url to openai chat",
+			convert: ClaudeReqToOpenAI,
+			request: `{"messages":[{"role":"user","content":[{"type":"document","source":{"type":"url","url":"https://example.com/a.pdf"}}]}]}`,
+			reason:  "file URLs are not supported",
+		},
+		{
+			name:    "claude file id to gemini",
+			convert: ClaudeReqToGemini,
+			request: `{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"file","file_id":"file_011"}}]}]}`,
+			reason:  "cannot be resolved",
+		},
+		{
+			name:    "oversized image to claude",
+			convert: OpenAIReqToClaude,
+			request: `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + oversizedPNG + `"}}]}]}`,
+			reason:  "limit is 5242880 bytes",
+		},
+		{
+			name:    "openai audio to claude",
+			convert: OpenAIReqToClaude,
+			request: `{"messages":[{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"UklGRg==","format":"wav"}}]}]}`,
+			reason:  "audio input is not supported",
+		},
+		{
+			name:    "openai docx to gemini",
+			convert: OpenAIReqToGemini,
+			request: `{"messages":[{"role":"user","content":[{"type":"file","file":{"filename":"a.docx","file_data":"data:application/vnd.openxmlformats-officedocument.wordprocessingml.document;base64,UEsDBA=="}}]}]}`,
+			reason:  "unsupported media type",
+		},
+		{
+			name:    "openai2 file id to claude",
+			convert: OpenAI2ReqToClaude,
+			request: `{"input":[{"type":"message","role":"user","content":[{"type":"input_file","file_id":"file-abc"}]}]}`,
+			reason:  "issued by openai",
+		},
+		{
+			name:    "gemini gs uri to claude",
+			convert: GeminiReqToClaude,
+			request: `{"contents":[{"role":"user","parts":[{"fileData":{"mimeType":"image/png","fileUri":"gs://bucket/cat.png"}}]}]}`,
+			reason:  "not an http(s) URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.convert([]byte(tt.request), "target-model", transformer.Options{})
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			var mediaErr *MediaError
+			if !errors.As(err, &mediaErr) {
+				t.Fatalf("expected MediaError, got %T: %v", err, err)
+			}
+			if !strings.Contains(err.Error(), tt.reason) {
+				t.Fatalf("expected error to mention %q, got %v", tt.reason, err)
+			}
+		})
+	}
+}
+
+func TestClaudeTextDocumentToOpenAI2(t *testing.T) {
+	req := `{"messages":[{"role":"user","content":[{"type":"document","source":{"type":"text","media_type":"text/plain","data":"plain notes"}}]}]}`
+
+	out, err := ClaudeReqToOpenAI2([]byte(req), "gpt-4o", transformer.Options{})
+	if err != nil {
+		t.Fatalf("ClaudeReqToOpenAI2 failed: %v", err)
+	}
+	assertContains(t, string(out), `{"text":"plain notes","type":"input_text"}`, "text document should be inlined as input_text")
+}
+
+func lookupJSONPath(t *testing.T, v interface{}, path []string) interface{} {
+	t.Helper()
+	for _, key := range path {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			v = node[key]
+		case []interface{}:
+			idx := int(key[0] - '0')
+			if idx >= len(node) {
+				t.Fatalf("index %s out of range in %v", key, node)
+			}
+			v = node[idx]
+		default:
+			t.Fatalf("cannot descend into %T at %q", v, key)
+		}
+	}
+	return v
+}
@@ -6,10 +6,11 @@ import (
 	"strings"
 
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/schema"
 )
 
 // OpenAI2ReqToGemini converts OpenAI Responses API request to Gemini request
-func OpenAI2ReqToGemini(openai2Req []byte, model string) ([]byte, error) {
+func OpenAI2ReqToGemini(openai2Req []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.OpenAI2Request
 	if err := json.Unmarshal(openai2Req, &req); err != nil {
 		return nil, err
@@ -25,7 +26,10 @@ func OpenAI2ReqToGemini(openai2Req []byte, model string) ([]byte, error) {
 	}
 
 	// Convert input to contents
-	contents := convertOpenAI2InputToGeminiContents(req.Input)
+	contents, err := convertOpenAI2InputToGeminiContents(req.Input)
+	if err != nil {
+		return nil, err
+	}
 	geminiReq["contents"] = contents
 
 	// Generation config
@@ -36,6 +40,10 @@ func OpenAI2ReqToGemini(openai2Req []byte, model string) ([]byte, error) {
 	if req.Temperature != nil {
 		genConfig["temperature"] = *req.Temperature
 	}
+	if thinkingConfig := geminiThinkingConfig(reasoningFromOpenAI2(req.Reasoning), opts.Reasoning); thinkingConfig != nil {
+		genConfig["thinkingConfig"] = thinkingConfig
+	}
+	applyOutputFormatToGemini(genConfig, outputFormatFromOpenAI2(req.Text))
 	if len(genConfig) > 0 {
 		geminiReq["generationConfig"] = genConfig
 	}
@@ -62,23 +70,140 @@ func OpenAI2ReqToGemini(openai2Req []byte, model string) ([]byte, error) {
 			funcDecls = append(funcDecls, map[string]interface{}{
 				"name":        tool.Name,
 				"description": tool.Description,
-				"parameters":  cleanSchemaForGemini(params),
+				"parameters":  normalizeToolSchema(tool.Name, params, schema.ProfileGemini),
 			})
 		}
 		if len(funcDecls) > 0 {
 			geminiReq["tools"] = []map[string]interface{}{{"functionDeclarations": funcDecls}}
 			// Add toolConfig to enable function calling
-			geminiReq["toolConfig"] = map[string]interface{}{
-				"functionCallingConfig": map[string]interface{}{
-					"mode": "AUTO",
-				},
-			}
+			geminiReq["toolConfig"] = toolChoiceFromOpenAI(req.ToolChoice, req.ParallelToolCalls).geminiToolConfig()
 		}
 	}
 
 	return json.Marshal(geminiReq)
 }
 
+// GeminiReqToOpenAI2 converts Gemini request to OpenAI Responses API request
+func GeminiReqToOpenAI2(geminiReq []byte, model string, opts transformer.Options) ([]byte, error) {
+	var req transformer.GeminiRequest
+	if err := json.Unmarshal(geminiReq, &req); err != nil {
+		return nil, err
+	}
+
+	openai2Req := map[string]interface{}{
+		"model":  model,
+		"stream": req.Stream,
+	}
+
+	// Convert system instruction to instructions
+	if req.SystemInstruction != nil {
+		var systemParts []string
+		for _, part := range req.SystemInstruction.Parts {
+			if part.Text != "" {
+				systemParts = append(systemParts, part.Text)
+			}
+		}
+		if len(systemParts) > 0 {
+			openai2Req["instructions"] = strings.Join(systemParts, "\n")
+		}
+	}
+
+	// Convert contents to input items
+	input := make([]map[string]interface{}, 0)
+	callIDs := newGeminiCallIDs()
+	for _, content := range req.Contents {
+		role := "user"
+		textType := "input_text"
+		if content.Role == "model" {
+			role = "assistant"
+			textType = "output_text"
+		}
+
+		var messageParts []map[string]interface{}
+		flushMessage := func() {
+			if len(messageParts) == 0 {
+				return
+			}
+			input = append(input, map[string]interface{}{"type": "message", "role": role, "content": messageParts})
+			messageParts = nil
+		}
+
+		for _, part := range content.Parts {
+			switch {
+			case part.Thought:
+				continue
+			case part.Text != "":
+				messageParts = append(messageParts, map[string]interface{}{"type": textType, "text": part.Text})
+			case part.InlineData != nil || part.FileData != nil:
+				var media *mediaPart
+				if part.InlineData != nil {
+					media = mediaFromGemini(part.InlineData.MimeType, part.InlineData.Data, "")
+				} else {
+					media = mediaFromGemini(part.FileData.MimeType, "", part.FileData.FileURI)
+				}
+				converted, err := media.toOpenAI2()
+				if err != nil {
+					return nil, err
+				}
+				messageParts = append(messageParts, converted)
+			case part.FunctionCall != nil:
+				flushMessage()
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				if part.FunctionCall.Args == nil {
+					args = []byte("{}")
+				}
+				input = append(input, map[string]interface{}{
+					"type":      "function_call",
+					"call_id":   callIDs.call(part.FunctionCall),
+					"name":      part.FunctionCall.Name,
+					"arguments": string(args),
+				})
+			case part.FunctionResponse != nil:
+				flushMessage()
+				input = append(input, map[string]interface{}{
+					"type":    "function_call_output",
+					"call_id": callIDs.response(part.FunctionResponse),
+					"output":  geminiFunctionResponseText(part.FunctionResponse.Response),
+				})
+			}
+		}
+		flushMessage()
+	}
+	openai2Req["input"] = input
+
+	if req.GenerationConfig != nil && req.GenerationConfig.Temperature != nil {
+		openai2Req["temperature"] = *req.GenerationConfig.Temperature
+	}
+	if reasoning := openAI2Reasoning(reasoningFromGemini(req.GenerationConfig), opts.Reasoning); reasoning != nil {
+		openai2Req["reasoning"] = reasoning
+		openai2Req["include"] = []string{"reasoning.encrypted_content"}
+	}
+
+	// Convert tools
+	var tools []map[string]interface{}
+	for _, tool := range req.Tools {
+		for _, fd := range tool.FunctionDeclarations {
+			tools = append(tools, map[string]interface{}{
+				"type":        "function",
+				"name":        fd.Name,
+				"description": fd.Description,
+				"parameters":  normalizeToolSchema(fd.Name, geminiDeclarationSchema(fd), schema.ProfileOpenAI),
+			})
+		}
+	}
+	if len(tools) > 0 {
+		openai2Req["tools"] = tools
+		if choice := toolChoiceFromGemini(req.ToolConfig).openAI2(); choice != nil {
+			openai2Req["tool_choice"] = choice
+		}
+	}
+	if format := outputFormatFromGemini(req.GenerationConfig); format != nil {
+		openai2Req["text"] = openAI2TextFormat(format)
+	}
+
+	return json.Marshal(openai2Req)
+}
+
 // GeminiRespToOpenAI2 converts Gemini response to OpenAI Responses API response
 func GeminiRespToOpenAI2(geminiResp []byte) ([]byte, error) {
 	var resp transformer.GeminiResponse
@@ -86,13 +211,19 @@ func GeminiRespToOpenAI2(geminiResp []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	var reasoningItems []map[string]interface{}
 	var outputContent []map[string]interface{}
 	var functionCalls []map[string]interface{}
 
 	if len(resp.Candidates) > 0 {
 		candidate := resp.Candidates[0]
 		for _, part := range candidate.Content.Parts {
-			if part.Text != "" {
+			if part.Thought {
+				if part.Text != "" || part.ThoughtSignature != "" {
+					itemID := fmt.Sprintf("rs_gemini_%d", len(reasoningItems))
+					reasoningItems = append(reasoningItems, openAI2ReasoningOutput(itemID, part.Text, part.ThoughtSignature))
+				}
+			} else if part.Text != "" {
 				outputContent = append(outputContent, map[string]interface{}{
 					"type": "output_text",
 					"text": part.Text,
@@ -111,7 +242,7 @@ func GeminiRespToOpenAI2(geminiResp []byte) ([]byte, error) {
 		}
 	}
 
-	var output []map[string]interface{}
+	output := reasoningItems
 	if len(outputContent) > 0 {
 		output = append(output, map[string]interface{}{
 			"type":    "message",
@@ -133,9 +264,13 @@ func GeminiRespToOpenAI2(geminiResp []byte) ([]byte, error) {
 	openai2Resp := map[string]interface{}{
 		"id":     "gemini-resp",
 		"object": "response",
-		"status": "completed",
 		"output": output,
 	}
+	finishReason := ""
+	if len(resp.Candidates) > 0 {
+		finishReason = resp.Candidates[0].FinishReason
+	}
+	setOpenAI2Status(openai2Resp, finishReason)
 	if usage != nil {
 		openai2Resp["usage"] = usage
 	}
@@ -143,6 +278,59 @@ func GeminiRespToOpenAI2(geminiResp []byte) ([]byte, error) {
 	return json.Marshal(openai2Resp)
 }
 
+// OpenAI2RespToGemini converts OpenAI Responses API response to Gemini response
+func OpenAI2RespToGemini(openai2Resp []byte) ([]byte, error) {
+	var resp transformer.OpenAI2Response
+	if err := json.Unmarshal(openai2Resp, &resp); err != nil {
+		return nil, err
+	}
+
+	parts := make([]map[string]interface{}, 0)
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "reasoning":
+			var summary strings.Builder
+			for _, part := range item.Summary {
+				summary.WriteString(part.Text)
+			}
+			if summary.Len() > 0 {
+				parts = append(parts, map[string]interface{}{"text": summary.String(), "thought": true})
+			}
+		case "message":
+			for _, part := range item.Content {
+				if part.Type == "output_text" && part.Text != "" {
+					parts = append(parts, map[string]interface{}{"text": part.Text})
+				}
+			}
+		case "function_call":
+			args := map[string]interface{}{}
+			json.Unmarshal([]byte(item.Arguments), &args)
+			callID := item.CallID
+			if callID == "" {
+				callID = item.ID
+			}
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{"id": callID, "name": item.Name, "args": args},
+			})
+		}
+	}
+
+	finishReason := geminiFinishReason(resp.IncompleteReason())
+
+	geminiResp := map[string]interface{}{
+		"candidates": []map[string]interface{}{
+			{
+				"content":      map[string]interface{}{"role": "model", "parts": parts},
+				"finishReason": finishReason,
+				"index":        0,
+			},
+		},
+		"usageMetadata": geminiUsageMetadata(resp.Usage.InputTokens, resp.Usage.OutputTokens),
+	}
+
+	return json.Marshal(geminiResp)
+}
+
 // GeminiStreamToOpenAI2 converts Gemini stream chunk to OpenAI Responses stream event
 func GeminiStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte, error) {
 	_, jsonData := parseSSE(event)
@@ -213,7 +401,8 @@ func GeminiStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 
 	candidate := resp.Candidates[0]
 	for _, part := range candidate.Content.Parts {
-		if part.Text != "" {
+		// Thoughts are not streamed as output text
+		if part.Text != "" && !part.Thought {
 			if !ctx.ContentBlockStarted {
 				ctx.ContentBlockStarted = true
 				writeEvent(map[string]interface{}{
@@ -255,12 +444,13 @@ func GeminiStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 		if resp.UsageMetadata != nil && resp.UsageMetadata.TotalTokenCount > 0 {
 			totalTokens = resp.UsageMetadata.TotalTokenCount
 		}
+		response := map[string]interface{}{
+			"id": ctx.MessageID, "object": "response",
+			"usage": map[string]interface{}{"input_tokens": ctx.InputTokens, "output_tokens": ctx.OutputTokens, "total_tokens": totalTokens},
+		}
 		writeEvent(map[string]interface{}{
-			"type": "response.completed",
-			"response": map[string]interface{}{
-				"id": ctx.MessageID, "object": "response", "status": "completed",
-				"usage": map[string]interface{}{"input_tokens": ctx.InputTokens, "output_tokens": ctx.OutputTokens, "total_tokens": totalTokens},
-			},
+			"type":     setOpenAI2Status(response, candidate.FinishReason),
+			"response": response,
 		})
 		result.WriteString("data: [DONE]\n\n")
 	}
@@ -271,12 +461,12 @@ func GeminiStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 // OpenAI2StreamToGemini converts OpenAI Responses stream event to Gemini stream format
 func OpenAI2StreamToGemini(event []byte, ctx *transformer.StreamContext) ([]byte, error) {
 	_, jsonData := parseSSE(event)
-	if jsonData == "" || jsonData == "[DONE]" {
-		if jsonData == "[DONE]" {
-			return []byte("data: [DONE]\n\n"), nil
-		}
+	if jsonData == "" {
 		return nil, nil
 	}
+	if jsonData == "[DONE]" {
+		return finishGeminiStream(ctx), nil
+	}
 
 	var evt transformer.OpenAI2StreamEvent
 	if err := json.Unmarshal([]byte(jsonData), &evt); err != nil {
@@ -285,13 +475,10 @@ func OpenAI2StreamToGemini(event []byte, ctx *transformer.StreamContext) ([]byte
 
 	switch evt.Type {
 	case "response.output_text.delta":
-		chunk := map[string]interface{}{
-			"candidates": []map[string]interface{}{
-				{"content": map[string]interface{}{"role": "model", "parts": []map[string]interface{}{{"text": evt.Delta}}}},
-			},
-		}
-		d, _ := json.Marshal(chunk)
-		return []byte(fmt.Sprintf("data: %s\n\n", d)), nil
+		return buildGeminiChunk([]map[string]interface{}{{"text": evt.Delta}}, "", nil), nil
+
+	case "response.reasoning_summary_text.delta":
+		return buildGeminiChunk([]map[string]interface{}{{"text": evt.Delta, "thought": true}}, "", nil), nil
 
 	case "response.output_item.added":
 		if evt.Item != nil && evt.Item.Type == "function_call" {
@@ -311,26 +498,41 @@ func OpenAI2StreamToGemini(event []byte, ctx *transformer.StreamContext) ([]byte
 	case "response.output_item.done":
 		if evt.Item != nil && evt.Item.Type == "function_call" && ctx.ToolBlockStarted {
 			ctx.ToolBlockStarted = false
-			var args map[string]interface{}
-			json.Unmarshal([]byte(ctx.ToolArguments), &args)
-			chunk := map[string]interface{}{
-				"candidates": []map[string]interface{}{
-					{"content": map[string]interface{}{"role": "model", "parts": []map[string]interface{}{
-						{"functionCall": map[string]interface{}{"name": ctx.CurrentToolName, "args": args}},
-					}}},
-				},
+			arguments := ctx.ToolArguments
+			if evt.Item.Arguments != "" {
+				arguments = evt.Item.Arguments
 			}
-			d, _ := json.Marshal(chunk)
-			return []byte(fmt.Sprintf("data: %s\n\n", d)), nil
+			args := map[string]interface{}{}
+			json.Unmarshal([]byte(arguments), &args)
+			return buildGeminiChunk([]map[string]interface{}{{
+				"functionCall": map[string]interface{}{"id": ctx.CurrentToolID, "name": ctx.CurrentToolName, "args": args},
+			}}, "", nil), nil
 		}
 		return nil, nil
+
+	case "response.completed", "response.incomplete":
+		if evt.Response != nil {
+			if evt.Response.Usage.InputTokens > 0 {
+				ctx.InputTokens = evt.Response.Usage.InputTokens
+			}
+			if evt.Response.Usage.OutputTokens > 0 {
+				ctx.OutputTokens = evt.Response.Usage.OutputTokens
+			}
+		}
+		if evt.Response != nil {
+			ctx.PendingFinishReason = geminiFinishReason(evt.Response.IncompleteReason())
+		}
+		return finishGeminiStream(ctx), nil
+
+	case "response.failed", "error":
+		return nil, fmt.Errorf("upstream error: %s", jsonData)
 	}
 
 	return nil, nil
 }
 
 // Helper function
-func convertOpenAI2InputToGeminiContents(input interface{}) []map[string]interface{} {
+func convertOpenAI2InputToGeminiContents(input interface{}) ([]map[string]interface{}, error) {
 	var contents []map[string]interface{}
 
 	switch v := input.(type) {
@@ -350,7 +552,7 @@ func convertOpenAI2InputToGeminiContents(input interface{}) []map[string]interfa
 				continue
 			}
 
-			itemType, _ := itemMap["type"].(string)
+			itemType := openAI2InputItemType(itemMap)
 			switch itemType {
 			case "message":
 				// Flush pending function calls
@@ -368,7 +570,10 @@ func convertOpenAI2InputToGeminiContents(input interface{}) []map[string]interfa
 				if role == "assistant" {
 					role = "model"
 				}
-				parts := convertOpenAI2ContentToGeminiParts(itemMap["content"])
+				parts, err := convertOpenAI2ContentToGeminiParts(itemMap["content"])
+				if err != nil {
+					return nil, err
+				}
 				contents = append(contents, map[string]interface{}{"role": role, "parts": parts})
 
 			case "function_call":
@@ -396,7 +601,7 @@ func convertOpenAI2InputToGeminiContents(input interface{}) []map[string]interfa
 				name := callIDToName[callID]
 				output, _ := itemMap["output"].(string)
 				pendingFuncResponses = append(pendingFuncResponses, map[string]interface{}{
-					"functionResponse": map[string]interface{}{"name": name, "response": map[string]interface{}{"result": output}},
+					"functionResponse": map[string]interface{}{"name": name, "response": geminiFunctionResponse(output)},
 				})
 			}
 		}
@@ -410,18 +615,18 @@ func convertOpenAI2InputToGeminiContents(input interface{}) []map[string]interfa
 		}
 	}
 
-	return contents
+	return contents, nil
 }
 
-func convertOpenAI2ContentToGeminiParts(content interface{}) []map[string]interface{} {
+func convertOpenAI2ContentToGeminiParts(content interface{}) ([]map[string]interface{}, error) {
 	var parts []map[string]interface{}
 
 	arr, ok := content.([]interface{})
 	if !ok {
 		if str, ok := content.(string); ok {
-			return []map[string]interface{}{{"text": str}}
+			return []map[string]interface{}{{"text": str}}, nil
 		}
-		return parts
+		return parts, nil
 	}
 
 	for _, part := range arr {
@@ -432,9 +637,18 @@ func convertOpenAI2ContentToGeminiParts(content interface{}) []map[string]interf
 		switch partMap["type"] {
 		case "input_text", "output_text":
 			parts = append(parts, map[string]interface{}{"text": partMap["text"]})
+		case "input_image", "input_file":
+			media, err := mediaFromOpenAI2(partMap)
+			if err != nil {
+				return nil, err
+			}
+			geminiPart, err := media.toGemini()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, geminiPart)
 		}
 	}
 
-	return parts
+	return parts, nil
 }
-
@@ -6,10 +6,11 @@ import (
 	"strings"
 
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/schema"
 )
 
 // OpenAIReqToGemini converts OpenAI Chat request to Gemini request
-func OpenAIReqToGemini(openaiReq []byte, model string) ([]byte, error) {
+func OpenAIReqToGemini(openaiReq []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.OpenAIRequest
 	if err := json.Unmarshal(openaiReq, &req); err != nil {
 		return nil, err
@@ -40,7 +41,11 @@ func OpenAIReqToGemini(openaiReq []byte, model string) ([]byte, error) {
 		case string:
 			parts = append(parts, map[string]interface{}{"text": content})
 		case []interface{}:
-			parts = convertOpenAIContentToGeminiParts(content)
+			converted, err := convertOpenAIContentToGeminiParts(content)
+			if err != nil {
+				return nil, err
+			}
+			parts = converted
 		}
 
 		// Handle tool_calls
@@ -62,7 +67,7 @@ func OpenAIReqToGemini(openaiReq []byte, model string) ([]byte, error) {
 				{
 					"functionResponse": map[string]interface{}{
 						"name":     funcName,
-						"response": map[string]interface{}{"result": msg.Content},
+						"response": geminiFunctionResponse(extractToolResultContent(msg.Content)),
 					},
 				},
 			}
@@ -90,6 +95,10 @@ func OpenAIReqToGemini(openaiReq []byte, model string) ([]byte, error) {
 	if req.Temperature != nil {
 		genConfig["temperature"] = *req.Temperature
 	}
+	if thinkingConfig := geminiThinkingConfig(reasoningFromOpenAI(req), opts.Reasoning); thinkingConfig != nil {
+		genConfig["thinkingConfig"] = thinkingConfig
+	}
+	applyOutputFormatToGemini(genConfig, outputFormatFromOpenAI(req.ResponseFormat))
 	if len(genConfig) > 0 {
 		geminiReq["generationConfig"] = genConfig
 	}
@@ -102,24 +111,164 @@ func OpenAIReqToGemini(openaiReq []byte, model string) ([]byte, error) {
 				funcDecls = append(funcDecls, map[string]interface{}{
 					"name":        tool.Function.Name,
 					"description": tool.Function.Description,
-					"parameters":  cleanSchemaForGemini(tool.Function.Parameters),
+					"parameters":  normalizeToolSchema(tool.Function.Name, tool.Function.Parameters, schema.ProfileGemini),
 				})
 			}
 		}
 		if len(funcDecls) > 0 {
 			geminiReq["tools"] = []map[string]interface{}{{"functionDeclarations": funcDecls}}
 			// Add toolConfig to enable function calling
-			geminiReq["toolConfig"] = map[string]interface{}{
-				"functionCallingConfig": map[string]interface{}{
-					"mode": "AUTO",
-				},
-			}
+			geminiReq["toolConfig"] = toolChoiceFromOpenAI(req.ToolChoice, req.ParallelToolCalls).geminiToolConfig()
 		}
 	}
 
 	return json.Marshal(geminiReq)
 }
 
+// GeminiReqToOpenAI converts Gemini request to OpenAI Chat request
+func GeminiReqToOpenAI(geminiReq []byte, model string, opts transformer.Options) ([]byte, error) {
+	var req transformer.GeminiRequest
+	if err := json.Unmarshal(geminiReq, &req); err != nil {
+		return nil, err
+	}
+
+	var messages []transformer.OpenAIMessage
+
+	// Convert system instruction
+	if req.SystemInstruction != nil {
+		var systemParts []string
+		for _, part := range req.SystemInstruction.Parts {
+			if part.Text != "" {
+				systemParts = append(systemParts, part.Text)
+			}
+		}
+		if len(systemParts) > 0 {
+			messages = append(messages, transformer.OpenAIMessage{Role: "system", Content: strings.Join(systemParts, "\n")})
+		}
+	}
+
+	// Convert contents
+	callIDs := newGeminiCallIDs()
+	for _, content := range req.Contents {
+		role := "user"
+		if content.Role == "model" {
+			role = "assistant"
+		}
+
+		var textParts []string
+		var contentParts []map[string]interface{}
+		var toolCalls []transformer.OpenAIToolCall
+		var toolResults []transformer.OpenAIMessage
+		hasMedia := false
+
+		for _, part := range content.Parts {
+			switch {
+			case part.Thought:
+				// Thoughts are the model's internal reasoning and are not replayed
+				continue
+			case part.Text != "":
+				textParts = append(textParts, part.Text)
+				contentParts = append(contentParts, map[string]interface{}{"type": "text", "text": part.Text})
+			case part.InlineData != nil || part.FileData != nil:
+				var media *mediaPart
+				if part.InlineData != nil {
+					media = mediaFromGemini(part.InlineData.MimeType, part.InlineData.Data, "")
+				} else {
+					media = mediaFromGemini(part.FileData.MimeType, "", part.FileData.FileURI)
+				}
+				converted, err := media.toOpenAI()
+				if err != nil {
+					return nil, err
+				}
+				contentParts = append(contentParts, converted)
+				hasMedia = true
+			case part.FunctionCall != nil:
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				if part.FunctionCall.Args == nil {
+					args = []byte("{}")
+				}
+				toolCalls = append(toolCalls, transformer.OpenAIToolCall{
+					ID:   callIDs.call(part.FunctionCall),
+					Type: "function",
+					Function: struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					}{Name: part.FunctionCall.Name, Arguments: string(args)},
+				})
+			case part.FunctionResponse != nil:
+				toolResults = append(toolResults, transformer.OpenAIMessage{
+					Role:       "tool",
+					Content:    geminiFunctionResponseText(part.FunctionResponse.Response),
+					ToolCallID: callIDs.response(part.FunctionResponse),
+				})
+			}
+		}
+
+		// Tool results must directly follow the assistant message with the calls
+		messages = append(messages, toolResults...)
+		if len(textParts) > 0 || hasMedia || len(toolCalls) > 0 {
+			openaiMsg := transformer.OpenAIMessage{Role: role}
+			if hasMedia {
+				openaiMsg.Content = contentParts
+			} else if len(textParts) > 0 {
+				openaiMsg.Content = strings.Join(textParts, "")
+			}
+			openaiMsg.ToolCalls = toolCalls
+			messages = append(messages, openaiMsg)
+		}
+	}
+
+	openaiReq := transformer.OpenAIRequest{
+		Model:    model,
+		Messages: messages,
+		Stream:   req.Stream,
+	}
+
+	if cfg := req.GenerationConfig; cfg != nil {
+		if cfg.MaxOutputTokens != nil {
+			openaiReq.MaxCompletionTokens = *cfg.MaxOutputTokens
+		}
+		openaiReq.Temperature = cfg.Temperature
+	}
+	openaiReq.ReasoningEffort = openAIReasoningEffort(reasoningFromGemini(req.GenerationConfig), opts.Reasoning)
+
+	// Convert tools
+	for _, tool := range req.Tools {
+		for _, fd := range tool.FunctionDeclarations {
+			openaiReq.Tools = append(openaiReq.Tools, transformer.OpenAITool{
+				Type: "function",
+				Function: struct {
+					Name        string                 `json:"name"`
+					Description string                 `json:"description,omitempty"`
+					Parameters  map[string]interface{} `json:"parameters"`
+					Strict      bool                   `json:"strict,omitempty"`
+				}{
+					Name:        fd.Name,
+					Description: fd.Description,
+					Parameters:  normalizeToolSchema(fd.Name, geminiDeclarationSchema(fd), schema.ProfileOpenAI),
+				},
+			})
+		}
+	}
+	if len(openaiReq.Tools) > 0 {
+		openaiReq.ToolChoice = toolChoiceFromGemini(req.ToolConfig).openAI()
+	}
+	if format := outputFormatFromGemini(req.GenerationConfig); format != nil {
+		openaiReq.ResponseFormat = openAIResponseFormat(format)
+	}
+
+	// Enable usage tracking for streaming
+	if req.Stream {
+		openaiReq.StreamOptions = &transformer.StreamOptions{IncludeUsage: true}
+	}
+
+	body, err := json.Marshal(openaiReq)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyOpenAIRequestQuirks(body, opts)
+}
+
 // GeminiRespToOpenAI converts Gemini response to OpenAI Chat response
 func GeminiRespToOpenAI(geminiResp []byte, model string) ([]byte, error) {
 	var resp transformer.GeminiResponse
@@ -134,7 +283,8 @@ func GeminiRespToOpenAI(geminiResp []byte, model string) ([]byte, error) {
 	if len(resp.Candidates) > 0 {
 		candidate := resp.Candidates[0]
 		for _, part := range candidate.Content.Parts {
-			if part.Text != "" {
+			// Chat completions have no place for thoughts
+			if part.Text != "" && !part.Thought {
 				textContent += part.Text
 			}
 			if part.FunctionCall != nil {
@@ -147,9 +297,9 @@ func GeminiRespToOpenAI(geminiResp []byte, model string) ([]byte, error) {
 						"arguments": string(args),
 					},
 				})
-				finishReason = "tool_calls"
 			}
 		}
+		finishReason = openAIFinishReason(candidate.FinishReason, len(toolCalls) > 0)
 	}
 
 	message := map[string]interface{}{"role": "assistant", "content": textContent}
@@ -179,6 +329,60 @@ func GeminiRespToOpenAI(geminiResp []byte, model string) ([]byte, error) {
 	return json.Marshal(openaiResp)
 }
 
+// OpenAIRespToGemini converts OpenAI Chat response to Gemini response
+func OpenAIRespToGemini(openaiResp []byte, opts transformer.Options) ([]byte, error) {
+	var resp transformer.OpenAIResponse
+	if err := json.Unmarshal(normalizeOpenAIResponse(openaiResp, opts.Quirks), &resp); err != nil {
+		return nil, err
+	}
+
+	parts := make([]map[string]interface{}, 0)
+	finishReason := "STOP"
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if choice.Message.ReasoningContent != "" {
+			parts = append(parts, map[string]interface{}{"text": choice.Message.ReasoningContent, "thought": true})
+		}
+		blocks := []map[string]interface{}{{"type": "text", "text": choice.Message.Content}}
+		if opts.Quirks.ParseThinkTags() {
+			blocks = splitThinkTaggedText(choice.Message.Content)
+		} else if choice.Message.Content == "" {
+			blocks = nil
+		}
+		for _, block := range blocks {
+			if block["type"] == "thinking" {
+				parts = append(parts, map[string]interface{}{"text": block["thinking"], "thought": true})
+			} else {
+				parts = append(parts, map[string]interface{}{"text": block["text"]})
+			}
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			args := map[string]interface{}{}
+			json.Unmarshal([]byte(tc.Function.Arguments), &args)
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{"id": tc.ID, "name": tc.Function.Name, "args": args},
+			})
+		}
+		finishReason = geminiFinishReason(choice.FinishReason)
+	}
+
+	geminiResp := map[string]interface{}{
+		"candidates": []map[string]interface{}{
+			{
+				"content":      map[string]interface{}{"role": "model", "parts": parts},
+				"finishReason": finishReason,
+				"index":        0,
+			},
+		},
+		"usageMetadata": geminiUsageMetadata(resp.Usage.PromptTokens, resp.Usage.CompletionTokens),
+	}
+	if resp.Model != "" {
+		geminiResp["modelVersion"] = resp.Model
+	}
+
+	return json.Marshal(geminiResp)
+}
+
 // GeminiStreamToOpenAI converts Gemini stream chunk to OpenAI Chat stream chunk
 func GeminiStreamToOpenAI(event []byte, ctx *transformer.StreamContext, model string) ([]byte, error) {
 	_, jsonData := parseSSE(event)
@@ -206,7 +410,7 @@ func GeminiStreamToOpenAI(event []byte, ctx *transformer.StreamContext, model st
 	hasToolCall := false
 
 	for _, part := range candidate.Content.Parts {
-		if part.Text != "" {
+		if part.Text != "" && !part.Thought {
 			chunk, _ := buildOpenAIChunk("gemini-chunk", model, part.Text, nil, "")
 			result.Write(chunk)
 		}
@@ -232,10 +436,7 @@ func GeminiStreamToOpenAI(event []byte, ctx *transformer.StreamContext, model st
 
 	// Check for finish
 	if candidate.FinishReason != "" {
-		finishReason := "stop"
-		if hasToolCall || candidate.FinishReason == "TOOL_CODE" {
-			finishReason = "tool_calls"
-		}
+		finishReason := openAIFinishReason(candidate.FinishReason, hasToolCall || candidate.FinishReason == "TOOL_CODE")
 		usage := currentOpenAIUsage(ctx)
 		chunk, _ := buildOpenAIChunkWithUsage("gemini-chunk", model, "", nil, finishReason, usage)
 		result.Write(chunk)
@@ -245,41 +446,85 @@ func GeminiStreamToOpenAI(event []byte, ctx *transformer.StreamContext, model st
 	return []byte(result.String()), nil
 }
 
-// OpenAIStreamToGemini converts OpenAI Chat stream chunk to Gemini stream format
-func OpenAIStreamToGemini(event []byte, ctx *transformer.StreamContext) ([]byte, error) {
+// OpenAIStreamToGemini converts OpenAI Chat stream chunk to Gemini stream format.
+// Tool call arguments are buffered and emitted as whole function calls, and the
+// final chunk is held until usage arrives.
+func OpenAIStreamToGemini(event []byte, ctx *transformer.StreamContext, opts transformer.Options) ([]byte, error) {
 	_, jsonData := parseSSE(event)
-	if jsonData == "" || jsonData == "[DONE]" {
-		if jsonData == "[DONE]" {
-			return []byte("data: [DONE]\n\n"), nil
-		}
+	if jsonData == "" {
 		return nil, nil
 	}
+	if jsonData == "[DONE]" {
+		return append(flushOpenAIToolCallToGemini(ctx), finishGeminiStream(ctx)...), nil
+	}
 
 	var chunk transformer.OpenAIStreamChunk
-	if err := json.Unmarshal([]byte(jsonData), &chunk); err != nil {
+	if err := json.Unmarshal([]byte(normalizeOpenAIStreamChunk(jsonData, ctx, opts.Quirks)), &chunk); err != nil {
 		return nil, nil
 	}
 
-	if len(chunk.Choices) == 0 {
-		return nil, nil
+	var result []byte
+	if len(chunk.Choices) > 0 {
+		choice := chunk.Choices[0]
+		delta := choice.Delta
+		if delta.ReasoningContent != "" {
+			result = append(result, buildGeminiChunk([]map[string]interface{}{{"text": delta.ReasoningContent, "thought": true}}, "", nil)...)
+		}
+		if delta.Content != "" {
+			result = append(result, buildGeminiChunk([]map[string]interface{}{{"text": delta.Content}}, "", nil)...)
+		}
+		for _, tc := range delta.ToolCalls {
+			if tc.ID != "" || ctx.CurrentToolCall == nil {
+				result = append(result, flushOpenAIToolCallToGemini(ctx)...)
+				call := tc
+				ctx.CurrentToolCall = &call
+				ctx.ToolCallBuffer = ""
+			}
+			if tc.Function.Name != "" {
+				ctx.CurrentToolCall.Function.Name = tc.Function.Name
+			}
+			ctx.ToolCallBuffer += tc.Function.Arguments
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			result = append(result, flushOpenAIToolCallToGemini(ctx)...)
+			ctx.PendingFinishReason = geminiFinishReason(*choice.FinishReason)
+		}
 	}
 
-	delta := chunk.Choices[0].Delta
-	if delta.Content != "" {
-		geminiChunk := map[string]interface{}{
-			"candidates": []map[string]interface{}{
-				{"content": map[string]interface{}{"role": "model", "parts": []map[string]interface{}{{"text": delta.Content}}}},
-			},
+	if chunk.Usage != nil {
+		if chunk.Usage.PromptTokens > 0 {
+			ctx.InputTokens = chunk.Usage.PromptTokens
+		}
+		if chunk.Usage.CompletionTokens > 0 {
+			ctx.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		if ctx.PendingFinishReason != "" {
+			result = append(result, finishGeminiStream(ctx)...)
 		}
-		d, _ := json.Marshal(geminiChunk)
-		return []byte(fmt.Sprintf("data: %s\n\n", d)), nil
 	}
 
-	return nil, nil
+	return result, nil
+}
+
+// flushOpenAIToolCallToGemini emits the buffered OpenAI tool call as a Gemini function call
+func flushOpenAIToolCallToGemini(ctx *transformer.StreamContext) []byte {
+	if ctx.CurrentToolCall == nil {
+		return nil
+	}
+	call := ctx.CurrentToolCall
+	ctx.CurrentToolCall = nil
+	args := map[string]interface{}{}
+	if ctx.ToolCallBuffer != "" {
+		json.Unmarshal([]byte(ctx.ToolCallBuffer), &args)
+	}
+	ctx.ToolCallBuffer = ""
+	return buildGeminiChunk([]map[string]interface{}{{
+		"functionCall": map[string]interface{}{"id": call.ID, "name": call.Function.Name, "args": args},
+	}}, "", nil)
 }
 
 // Helper function
-func convertOpenAIContentToGeminiParts(content []interface{}) []map[string]interface{} {
+func convertOpenAIContentToGeminiParts(content []interface{}) ([]map[string]interface{}, error) {
 	var parts []map[string]interface{}
 	for _, item := range content {
 		m, ok := item.(map[string]interface{})
@@ -289,19 +534,17 @@ func convertOpenAIContentToGeminiParts(content []interface{}) []map[string]inter
 		switch m["type"] {
 		case "text":
 			parts = append(parts, map[string]interface{}{"text": m["text"]})
-		case "image_url":
-			if urlObj, ok := m["image_url"].(map[string]interface{}); ok {
-				if url, ok := urlObj["url"].(string); ok && strings.HasPrefix(url, "data:") {
-					urlParts := strings.SplitN(url, ",", 2)
-					if len(urlParts) == 2 {
-						mimeType := strings.TrimPrefix(strings.Split(urlParts[0], ";")[0], "data:")
-						parts = append(parts, map[string]interface{}{
-							"inlineData": map[string]interface{}{"mimeType": mimeType, "data": urlParts[1]},
-						})
-					}
-				}
+		case "image_url", "file", "input_audio":
+			media, err := mediaFromOpenAI(m)
+			if err != nil {
+				return nil, err
+			}
+			part, err := media.toGemini()
+			if err != nil {
+				return nil, err
 			}
+			parts = append(parts, part)
 		}
 	}
-	return parts
+	return parts, nil
 }
@@ -0,0 +1,152 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Gemini embedding methods
+const (
+	GeminiEmbedMethod      = ":embedContent"
+	GeminiBatchEmbedMethod = ":batchEmbedContents"
+)
+
+type geminiEmbedRequest struct {
+	Model                string             `json:"model,omitempty"`
+	Content              geminiEmbedContent `json:"content"`
+	OutputDimensionality int                `json:"outputDimensionality,omitempty"`
+}
+
+type geminiEmbedContent struct {
+	Parts []geminiEmbedPart `json:"parts"`
+}
+
+type geminiEmbedPart struct {
+	Text string `json:"text"`
+}
+
+// OpenAIEmbeddingsToGemini converts an OpenAI embeddings request. A single
+// string uses embedContent and a list of strings batchEmbedContents; the
+// returned method is appended to the model path. Token ID inputs have no
+// Gemini equivalent and are rejected.
+func OpenAIEmbeddingsToGemini(openaiReq []byte, model string) ([]byte, string, error) {
+	var req struct {
+		Input      interface{} `json:"input"`
+		Dimensions int         `json:"dimensions"`
+	}
+	if err := json.Unmarshal(openaiReq, &req); err != nil {
+		return nil, "", err
+	}
+
+	texts, batch, err := embeddingInputTexts(req.Input)
+	if err != nil {
+		return nil, "", err
+	}
+	if !batch {
+		body, err := json.Marshal(geminiEmbedRequest{Content: geminiTextContent(texts[0]), OutputDimensionality: req.Dimensions})
+		return body, GeminiEmbedMethod, err
+	}
+
+	requests := make([]geminiEmbedRequest, len(texts))
+	for i, text := range texts {
+		requests[i] = geminiEmbedRequest{
+			Model:                "models/" + model,
+			Content:              geminiTextContent(text),
+			OutputDimensionality: req.Dimensions,
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"requests": requests})
+	return body, GeminiBatchEmbedMethod, err
+}
+
+// EmbeddingInputTexts returns the texts of an OpenAI embeddings request
+func EmbeddingInputTexts(openaiReq []byte) []string {
+	var req struct {
+		Input interface{} `json:"input"`
+	}
+	if json.Unmarshal(openaiReq, &req) != nil {
+		return nil
+	}
+	texts, _, _ := embeddingInputTexts(req.Input)
+	return texts
+}
+
+func embeddingInputTexts(input interface{}) (texts []string, batch bool, err error) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, false, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, false, fmt.Errorf("input must not be empty")
+		}
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("gemini embeddings only accept string inputs")
+			}
+			texts = append(texts, text)
+		}
+		return texts, true, nil
+	default:
+		return nil, false, fmt.Errorf("input must be a string or a list of strings")
+	}
+}
+
+func geminiTextContent(text string) geminiEmbedContent {
+	return geminiEmbedContent{Parts: []geminiEmbedPart{{Text: text}}}
+}
+
+// GeminiEmbeddingsToOpenAI converts an embedContent or batchEmbedContents
+// response to an OpenAI embeddings list. Gemini reports no usage, so the
+// caller supplies an estimate. encodingFormat "base64" packs the vectors as
+// little-endian float32, as the OpenAI API does.
+func GeminiEmbeddingsToOpenAI(geminiResp []byte, model, encodingFormat string, promptTokens int) ([]byte, error) {
+	type values struct {
+		Values []float64 `json:"values"`
+	}
+	var resp struct {
+		Embedding  *values  `json:"embedding"`
+		Embeddings []values `json:"embeddings"`
+	}
+	if err := json.Unmarshal(geminiResp, &resp); err != nil {
+		return nil, err
+	}
+	embeddings := resp.Embeddings
+	if resp.Embedding != nil {
+		embeddings = []values{*resp.Embedding}
+	}
+
+	data := make([]map[string]interface{}, len(embeddings))
+	for i, e := range embeddings {
+		var vector interface{} = e.Values
+		if encodingFormat == "base64" {
+			vector = encodeEmbeddingBase64(e.Values)
+		}
+		data[i] = map[string]interface{}{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": vector,
+		}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  model,
+		"usage": map[string]int{
+			"prompt_tokens": promptTokens,
+			"total_tokens":  promptTokens,
+		},
+	})
+}
+
+func encodeEmbeddingBase64(vector []float64) string {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,385 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+const (
+	mediaTargetOllama  = "ollama"
+	ollamaCompletionID = "ollama-resp"
+)
+
+// ollamaMessage is a message of the Ollama /api/chat API
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaResponse is a non-streaming /api/chat response or one NDJSON line of a
+// streaming response
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// OpenAIReqToOllama converts an OpenAI Chat request to an Ollama /api/chat
+// request. Sampling settings move into options, and the endpoint's context
+// size and keep-alive are applied. Ollama streams by default, so stream is
+// always sent explicitly.
+func OpenAIReqToOllama(openaiReq []byte, opts transformer.Options) ([]byte, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(openaiReq, &req); err != nil {
+		return nil, err
+	}
+
+	messages, err := openAIMessagesToOllama(req["messages"])
+	if err != nil {
+		return nil, err
+	}
+	stream, _ := req["stream"].(bool)
+	out := map[string]interface{}{
+		"model":    req["model"],
+		"messages": messages,
+		"stream":   stream,
+	}
+	if tools, ok := req["tools"].([]interface{}); ok && len(tools) > 0 {
+		out["tools"] = tools
+	}
+
+	options := map[string]interface{}{}
+	for _, key := range []string{"temperature", "top_p", "seed", "frequency_penalty", "presence_penalty"} {
+		if v, ok := req[key]; ok && v != nil {
+			options[key] = v
+		}
+	}
+	if v, ok := req["max_completion_tokens"].(float64); ok && v > 0 {
+		options["num_predict"] = int(v)
+	} else if v, ok := req["max_tokens"].(float64); ok && v > 0 {
+		options["num_predict"] = int(v)
+	}
+	switch stop := req["stop"].(type) {
+	case string:
+		options["stop"] = []string{stop}
+	case []interface{}:
+		options["stop"] = stop
+	}
+	if opts.Ollama.NumCtx > 0 {
+		options["num_ctx"] = opts.Ollama.NumCtx
+	}
+	if len(options) > 0 {
+		out["options"] = options
+	}
+	if keepAlive := strings.TrimSpace(opts.Ollama.KeepAlive); keepAlive != "" {
+		out["keep_alive"] = keepAlive
+	}
+
+	if format := ollamaFormat(req["response_format"]); format != nil {
+		out["format"] = format
+	}
+	if effort, ok := req["reasoning_effort"].(string); ok && effort != "" {
+		out["think"] = effort != "none"
+	} else if enable, ok := req["enable_thinking"].(bool); ok && enable {
+		out["think"] = true
+	}
+
+	return json.Marshal(out)
+}
+
+// openAIMessagesToOllama flattens content parts into text plus base64 images
+// and names tool results after the call they answer
+func openAIMessagesToOllama(raw interface{}) ([]ollamaMessage, error) {
+	items, _ := raw.([]interface{})
+	toolNames := make(map[string]string)
+	messages := make([]ollamaMessage, 0, len(items))
+	for _, item := range items {
+		msg, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+		if role == "developer" {
+			role = "system"
+		}
+		out := ollamaMessage{Role: role}
+
+		switch content := msg["content"].(type) {
+		case string:
+			out.Content = content
+		case []interface{}:
+			var texts []string
+			for _, p := range content {
+				part, ok := p.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				switch part["type"] {
+				case "text":
+					text, _ := part["text"].(string)
+					texts = append(texts, text)
+				case "image_url":
+					var url string
+					switch v := part["image_url"].(type) {
+					case string:
+						url = v
+					case map[string]interface{}:
+						url, _ = v["url"].(string)
+					}
+					mediaType, data, ok := parseDataURL(url)
+					if !ok {
+						return nil, &MediaError{Target: mediaTargetOllama, MediaType: mediaTypeFromURL(url), Reason: "only inline base64 images are supported"}
+					}
+					if !strings.HasPrefix(mediaType, "image/") {
+						return nil, &MediaError{Target: mediaTargetOllama, MediaType: mediaType, Reason: "only images are supported"}
+					}
+					out.Images = append(out.Images, data)
+				default:
+					return nil, &MediaError{Target: mediaTargetOllama, MediaType: fmt.Sprint(part["type"]), Reason: "unsupported content part"}
+				}
+			}
+			out.Content = strings.Join(texts, "\n")
+		}
+
+		if reasoning, ok := msg["reasoning_content"].(string); ok {
+			out.Thinking = reasoning
+		}
+		if calls, ok := msg["tool_calls"].([]interface{}); ok {
+			for _, c := range calls {
+				call, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				fn, _ := call["function"].(map[string]interface{})
+				var tc ollamaToolCall
+				tc.Function.Name, _ = fn["name"].(string)
+				tc.Function.Arguments = map[string]interface{}{}
+				if args, ok := fn["arguments"].(string); ok && strings.TrimSpace(args) != "" {
+					json.Unmarshal([]byte(args), &tc.Function.Arguments)
+				}
+				if id, ok := call["id"].(string); ok {
+					toolNames[id] = tc.Function.Name
+				}
+				out.ToolCalls = append(out.ToolCalls, tc)
+			}
+		}
+		if role == "tool" {
+			id, _ := msg["tool_call_id"].(string)
+			out.ToolName = toolNames[id]
+		}
+		messages = append(messages, out)
+	}
+	return messages, nil
+}
+
+// ollamaFormat maps an OpenAI response_format to Ollama's format field
+func ollamaFormat(raw interface{}) interface{} {
+	format, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch format["type"] {
+	case "json_object":
+		return "json"
+	case "json_schema":
+		if schema, ok := format["json_schema"].(map[string]interface{}); ok {
+			return schema["schema"]
+		}
+	}
+	return nil
+}
+
+// OllamaRespToOpenAI converts a non-streaming /api/chat response to an OpenAI
+// Chat completion. Usage comes from prompt_eval_count and eval_count.
+func OllamaRespToOpenAI(ollamaResp []byte) ([]byte, error) {
+	var resp ollamaResponse
+	if err := json.Unmarshal(ollamaResp, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("upstream error: %s", resp.Error)
+	}
+
+	message := map[string]interface{}{
+		"role":    "assistant",
+		"content": resp.Message.Content,
+	}
+	if resp.Message.Thinking != "" {
+		message["reasoning_content"] = resp.Message.Thinking
+	}
+	if calls := ollamaToolCallsToOpenAI(resp.Message.ToolCalls, 0); len(calls) > 0 {
+		message["tool_calls"] = calls
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"id":      ollamaCompletionID,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   resp.Model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       message,
+			"finish_reason": ollamaFinishReason(resp.DoneReason, len(resp.Message.ToolCalls) > 0),
+		}},
+		"usage": ollamaUsage(resp),
+	})
+}
+
+// OllamaStreamState converts an NDJSON /api/chat stream into OpenAI Chat
+// stream chunks, one line at a time
+type OllamaStreamState struct {
+	created   int64
+	started   bool
+	toolCalls int
+	finished  bool
+}
+
+// NewOllamaStreamState creates the state for one streaming response
+func NewOllamaStreamState() *OllamaStreamState {
+	return &OllamaStreamState{created: time.Now().Unix()}
+}
+
+// Convert turns one NDJSON line into SSE "data:" events. The final line emits
+// the finish reason, a usage chunk and [DONE].
+func (s *OllamaStreamState) Convert(line []byte) []byte {
+	line = []byte(strings.TrimSpace(string(line)))
+	if len(line) == 0 || s.finished {
+		return nil
+	}
+	var chunk ollamaResponse
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return nil
+	}
+	if chunk.Error != "" {
+		return s.Error(chunk.Error)
+	}
+
+	var out []byte
+	delta := map[string]interface{}{}
+	if !s.started {
+		s.started = true
+		delta["role"] = "assistant"
+	}
+	if chunk.Message.Thinking != "" {
+		delta["reasoning_content"] = chunk.Message.Thinking
+	}
+	if chunk.Message.Content != "" {
+		delta["content"] = chunk.Message.Content
+	}
+	if calls := ollamaToolCallsToOpenAI(chunk.Message.ToolCalls, s.toolCalls); len(calls) > 0 {
+		for i, call := range calls {
+			call["index"] = s.toolCalls + i
+		}
+		s.toolCalls += len(calls)
+		delta["tool_calls"] = calls
+	}
+	if len(delta) > 0 {
+		out = append(out, s.chunk(chunk.Model, delta, nil, nil)...)
+	}
+
+	if chunk.Done {
+		s.finished = true
+		reason := ollamaFinishReason(chunk.DoneReason, s.toolCalls > 0)
+		out = append(out, s.chunk(chunk.Model, map[string]interface{}{}, &reason, nil)...)
+		out = append(out, s.chunk(chunk.Model, nil, nil, ollamaUsage(chunk))...)
+		out = append(out, []byte("data: [DONE]\n\n")...)
+	}
+	return out
+}
+
+// Error ends the stream with an error event
+func (s *OllamaStreamState) Error(message string) []byte {
+	s.finished = true
+	data, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]string{"type": "api_error", "message": message},
+	})
+	return []byte("data: " + string(data) + "\n\n")
+}
+
+func (s *OllamaStreamState) chunk(model string, delta map[string]interface{}, finishReason *string, usage map[string]int) []byte {
+	choices := []map[string]interface{}{}
+	if delta != nil {
+		choices = append(choices, map[string]interface{}{
+			"index":         0,
+			"delta":         delta,
+			"finish_reason": finishReason,
+		})
+	}
+	body := map[string]interface{}{
+		"id":      ollamaCompletionID,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   model,
+		"choices": choices,
+	}
+	if usage != nil {
+		body["usage"] = usage
+	}
+	data, _ := json.Marshal(body)
+	return []byte("data: " + string(data) + "\n\n")
+}
+
+// ollamaToolCallsToOpenAI assigns IDs to Ollama tool calls, which usually
+// arrive without one, and encodes their arguments as JSON strings. offset is
+// the number of calls already emitted in the response.
+func ollamaToolCallsToOpenAI(calls []ollamaToolCall, offset int) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(calls))
+	for i, call := range calls {
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_ollama_%d", offset+i)
+		}
+		args, _ := json.Marshal(call.Function.Arguments)
+		if call.Function.Arguments == nil {
+			args = []byte("{}")
+		}
+		tc := map[string]interface{}{
+			"id":   id,
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":      call.Function.Name,
+				"arguments": string(args),
+			},
+		}
+		out = append(out, tc)
+	}
+	return out
+}
+
+func ollamaFinishReason(doneReason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	if doneReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+func ollamaUsage(resp ollamaResponse) map[string]int {
+	return map[string]int{
+		"prompt_tokens":     resp.PromptEvalCount,
+		"completion_tokens": resp.EvalCount,
+		"total_tokens":      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
@@ -0,0 +1,125 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+func TestOpenAIReqToOllama(t *testing.T) {
+	req := `{"model":"qwen3","max_tokens":256,"temperature":0.2,"stop":"END","reasoning_effort":"high",
+		"response_format":{"type":"json_object"},
+		"messages":[
+			{"role":"developer","content":"be brief"},
+			{"role":"user","content":[{"type":"text","text":"what is this"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]},
+			{"role":"assistant","content":null,"reasoning_content":"look it up","tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":\"png\"}"}}]},
+			{"role":"tool","tool_call_id":"call_1","content":"an image"}
+		]}`
+	out, err := OpenAIReqToOllama([]byte(req), transformer.Options{Ollama: transformer.OllamaConfig{NumCtx: 32768, KeepAlive: "30m"}})
+	if err != nil {
+		t.Fatalf("OpenAIReqToOllama failed: %v", err)
+	}
+
+	var got struct {
+		Stream    *bool                  `json:"stream"`
+		KeepAlive string                 `json:"keep_alive"`
+		Think     bool                   `json:"think"`
+		Format    string                 `json:"format"`
+		Options   map[string]interface{} `json:"options"`
+		Messages  []ollamaMessage        `json:"messages"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output: %v", err)
+	}
+	if got.Stream == nil || *got.Stream {
+		t.Fatalf("expected explicit stream=false, got %s", out)
+	}
+	if got.KeepAlive != "30m" || !got.Think || got.Format != "json" {
+		t.Fatalf("unexpected top-level fields: %s", out)
+	}
+	if got.Options["num_ctx"] != float64(32768) || got.Options["num_predict"] != float64(256) || got.Options["temperature"] != 0.2 {
+		t.Fatalf("unexpected options: %v", got.Options)
+	}
+	if stop, _ := got.Options["stop"].([]interface{}); len(stop) != 1 || stop[0] != "END" {
+		t.Fatalf("expected stop list, got %v", got.Options["stop"])
+	}
+
+	if len(got.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(got.Messages))
+	}
+	if got.Messages[0].Role != "system" {
+		t.Fatalf("developer must become system, got %s", got.Messages[0].Role)
+	}
+	if user := got.Messages[1]; user.Content != "what is this" || len(user.Images) != 1 || user.Images[0] != "iVBORw0KGgo=" {
+		t.Fatalf("unexpected user message: %+v", user)
+	}
+	assistant := got.Messages[2]
+	if assistant.Thinking != "look it up" || len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Function.Arguments["q"] != "png" {
+		t.Fatalf("unexpected assistant message: %+v", assistant)
+	}
+	if got.Messages[3].ToolName != "lookup" {
+		t.Fatalf("tool result must name its tool, got %+v", got.Messages[3])
+	}
+}
+
+func TestOpenAIReqToOllamaRejectsRemoteImages(t *testing.T) {
+	req := `{"model":"llava","messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`
+	_, err := OpenAIReqToOllama([]byte(req), transformer.Options{})
+	var mediaErr *MediaError
+	if !errors.As(err, &mediaErr) || mediaErr.Target != mediaTargetOllama {
+		t.Fatalf("expected media error, got %v", err)
+	}
+}
+
+func TestOllamaRespToOpenAI(t *testing.T) {
+	resp := `{"model":"qwen3","message":{"role":"assistant","content":"","thinking":"hmm","tool_calls":[{"function":{"name":"lookup","arguments":{"q":"x"}}}]},"done":true,"done_reason":"stop","prompt_eval_count":21,"eval_count":7}`
+	out, err := OllamaRespToOpenAI([]byte(resp))
+	if err != nil {
+		t.Fatalf("OllamaRespToOpenAI failed: %v", err)
+	}
+	var got transformer.OpenAIResponse
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output: %v", err)
+	}
+	if got.Usage.PromptTokens != 21 || got.Usage.CompletionTokens != 7 || got.Usage.TotalTokens != 28 {
+		t.Fatalf("unexpected usage: %+v", got.Usage)
+	}
+	choice := got.Choices[0]
+	if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("unexpected choice: %+v", choice)
+	}
+	if call := choice.Message.ToolCalls[0]; call.ID != "call_ollama_0" || call.Function.Arguments != `{"q":"x"}` {
+		t.Fatalf("unexpected tool call: %+v", call)
+	}
+}
+
+func TestOllamaStreamState(t *testing.T) {
+	state := NewOllamaStreamState()
+	var out strings.Builder
+	for _, line := range []string{
+		`{"model":"qwen3","message":{"role":"assistant","content":"","thinking":"hm"},"done":false}`,
+		`{"model":"qwen3","message":{"role":"assistant","content":"Hel"},"done":false}`,
+		`{"model":"qwen3","message":{"role":"assistant","content":"lo"},"done":false}`,
+		`{"model":"qwen3","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":9,"eval_count":3}`,
+		`{"model":"qwen3","message":{"role":"assistant","content":"ignored"},"done":false}`,
+	} {
+		out.Write(state.Convert([]byte(line)))
+	}
+
+	events := strings.Split(strings.TrimSpace(out.String()), "\n\n")
+	if len(events) != 6 || events[5] != "data: [DONE]" {
+		t.Fatalf("unexpected events: %q", events)
+	}
+	if !strings.Contains(events[0], `"role":"assistant"`) || !strings.Contains(events[0], `"reasoning_content":"hm"`) {
+		t.Fatalf("first chunk must carry the role and thinking: %s", events[0])
+	}
+	if !strings.Contains(events[3], `"finish_reason":"length"`) {
+		t.Fatalf("expected length finish reason: %s", events[3])
+	}
+	if !strings.Contains(events[4], `"choices":[]`) || !strings.Contains(events[4], `"prompt_tokens":9`) || !strings.Contains(events[4], `"completion_tokens":3`) {
+		t.Fatalf("expected usage chunk: %s", events[4])
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // OpenAIReqToOpenAI2 converts OpenAI Chat request to OpenAI Responses request
-func OpenAIReqToOpenAI2(openaiReq []byte, model string) ([]byte, error) {
+func OpenAIReqToOpenAI2(openaiReq []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.OpenAIRequest
 	if err := json.Unmarshal(openaiReq, &req); err != nil {
 		return nil, err
@@ -29,21 +29,64 @@ func OpenAIReqToOpenAI2(openaiReq []byte, model string) ([]byte, error) {
 			continue
 		}
 
+		if msg.Role == "tool" {
+			input = append(input, map[string]interface{}{
+				"type":    "function_call_output",
+				"call_id": msg.ToolCallID,
+				"output":  extractToolResultContent(msg.Content),
+			})
+			continue
+		}
+
 		item := map[string]interface{}{"type": "message", "role": msg.Role}
 		var contentParts []map[string]interface{}
 
+		textType := "input_text"
+		if msg.Role == "assistant" {
+			textType = "output_text"
+		}
 		switch content := msg.Content.(type) {
 		case string:
-			textType := "input_text"
-			if msg.Role == "assistant" {
-				textType = "output_text"
-			}
 			contentParts = append(contentParts, map[string]interface{}{"type": textType, "text": content})
+		case []interface{}:
+			for _, part := range content {
+				m, ok := part.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				switch m["type"] {
+				case "text":
+					contentParts = append(contentParts, map[string]interface{}{"type": textType, "text": m["text"]})
+				case "image_url", "file", "input_audio":
+					media, err := mediaFromOpenAI(m)
+					if err != nil {
+						return nil, err
+					}
+					converted, err := media.toOpenAI2()
+					if err != nil {
+						return nil, err
+					}
+					contentParts = append(contentParts, converted)
+				}
+			}
+		}
+		if len(contentParts) > 0 || len(msg.ToolCalls) == 0 {
+			item["content"] = contentParts
+			input = append(input, item)
+		}
+		for _, call := range msg.ToolCalls {
+			input = append(input, map[string]interface{}{
+				"type":      "function_call",
+				"call_id":   call.ID,
+				"name":      call.Function.Name,
+				"arguments": call.Function.Arguments,
+			})
 		}
-		item["content"] = contentParts
-		input = append(input, item)
 	}
 	openai2Req["input"] = input
+	if reasoning := openAI2Reasoning(reasoningFromOpenAI(req), opts.Reasoning); reasoning != nil {
+		openai2Req["reasoning"] = reasoning
+	}
 	// TODO: max_output_tokens is standard OpenAI Responses API param but some
 	// third-party endpoints (e.g. SiliconFlow) don't support it. Skipping for compatibility.
 
@@ -51,12 +94,16 @@ func OpenAIReqToOpenAI2(openaiReq []byte, model string) ([]byte, error) {
 		var tools []map[string]interface{}
 		for _, tool := range req.Tools {
 			if tool.Type == "function" {
-				tools = append(tools, map[string]interface{}{
+				item := map[string]interface{}{
 					"type":        "function",
 					"name":        tool.Function.Name,
 					"description": tool.Function.Description,
-					"parameters":  tool.Function.Parameters,
-				})
+					"parameters":  normalizeToolSchema(tool.Function.Name, tool.Function.Parameters, openAISchemaProfile(tool.Function.Strict)),
+				}
+				if tool.Function.Strict {
+					item["strict"] = true
+				}
+				tools = append(tools, item)
 			}
 		}
 		openai2Req["tools"] = tools
@@ -70,12 +117,18 @@ func OpenAIReqToOpenAI2(openaiReq []byte, model string) ([]byte, error) {
 			openai2Req["tool_choice"] = "auto"
 		}
 	}
+	if req.ParallelToolCalls != nil {
+		openai2Req["parallel_tool_calls"] = *req.ParallelToolCalls
+	}
+	if format := outputFormatFromOpenAI(req.ResponseFormat); format != nil {
+		openai2Req["text"] = openAI2TextFormat(format)
+	}
 
 	return json.Marshal(openai2Req)
 }
 
 // OpenAI2ReqToOpenAI converts OpenAI Responses request to OpenAI Chat request
-func OpenAI2ReqToOpenAI(openai2Req []byte, model string) ([]byte, error) {
+func OpenAI2ReqToOpenAI(openai2Req []byte, model string, opts transformer.Options) ([]byte, error) {
 	var req transformer.OpenAI2Request
 	if err := json.Unmarshal(openai2Req, &req); err != nil {
 		return nil, err
@@ -99,7 +152,7 @@ func OpenAI2ReqToOpenAI(openai2Req []byte, model string) ([]byte, error) {
 				continue
 			}
 
-			itemType, _ := itemMap["type"].(string)
+			itemType := openAI2InputItemType(itemMap)
 			switch itemType {
 			case "message":
 				// Flush pending tool calls
@@ -111,8 +164,11 @@ func OpenAI2ReqToOpenAI(openai2Req []byte, model string) ([]byte, error) {
 				if role == "developer" {
 					role = "system"
 				}
-				text := extractOpenAI2Text(itemMap["content"])
-				messages = append(messages, transformer.OpenAIMessage{Role: role, Content: text})
+				content, err := convertOpenAI2ContentToOpenAI(itemMap["content"])
+				if err != nil {
+					return nil, err
+				}
+				messages = append(messages, transformer.OpenAIMessage{Role: role, Content: content})
 
 			case "function_call":
 				callID, _ := itemMap["call_id"].(string)
@@ -154,6 +210,7 @@ func OpenAI2ReqToOpenAI(openai2Req []byte, model string) ([]byte, error) {
 	if req.MaxOutputTokens > 0 {
 		openaiReq.MaxCompletionTokens = req.MaxOutputTokens
 	}
+	openaiReq.ReasoningEffort = openAIReasoningEffort(reasoningFromOpenAI2(req.Reasoning), opts.Reasoning)
 
 	if len(req.Tools) > 0 {
 		for _, tool := range req.Tools {
@@ -180,10 +237,12 @@ func OpenAI2ReqToOpenAI(openai2Req []byte, model string) ([]byte, error) {
 					Name        string                 `json:"name"`
 					Description string                 `json:"description,omitempty"`
 					Parameters  map[string]interface{} `json:"parameters"`
+					Strict      bool                   `json:"strict,omitempty"`
 				}{
 					Name:        tool.Name,
 					Description: tool.Description,
-					Parameters:  params,
+					Parameters:  normalizeToolSchema(tool.Name, params, openAISchemaProfile(tool.Strict)),
+					Strict:      tool.Strict,
 				},
 			})
 		}
@@ -192,8 +251,16 @@ func OpenAI2ReqToOpenAI(openai2Req []byte, model string) ([]byte, error) {
 	if req.ToolChoice != nil {
 		openaiReq.ToolChoice = mapOpenAI2ToolChoiceToOpenAI(req.ToolChoice)
 	}
+	openaiReq.ParallelToolCalls = req.ParallelToolCalls
+	if format := outputFormatFromOpenAI2(req.Text); format != nil {
+		openaiReq.ResponseFormat = openAIResponseFormat(format)
+	}
 
-	return json.Marshal(openaiReq)
+	body, err := json.Marshal(openaiReq)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyOpenAIRequestQuirks(body, opts)
 }
 
 func mapOpenAIToolChoiceToOpenAI2(toolChoice interface{}) interface{} {
@@ -252,16 +319,18 @@ func mapOpenAI2ToolChoiceToOpenAI(toolChoice interface{}) interface{} {
 }
 
 // OpenAIRespToOpenAI2 converts OpenAI Chat response to OpenAI Responses response
-func OpenAIRespToOpenAI2(openaiResp []byte) ([]byte, error) {
+func OpenAIRespToOpenAI2(openaiResp []byte, opts transformer.Options) ([]byte, error) {
 	var resp transformer.OpenAIResponse
-	if err := json.Unmarshal(openaiResp, &resp); err != nil {
+	if err := json.Unmarshal(normalizeOpenAIResponse(openaiResp, opts.Quirks), &resp); err != nil {
 		return nil, err
 	}
 
 	var output []map[string]interface{}
+	finishReason := ""
 
 	if len(resp.Choices) > 0 {
 		choice := resp.Choices[0]
+		finishReason = choice.FinishReason
 		if choice.Message.Content != "" {
 			output = append(output, map[string]interface{}{
 				"type": "message",
@@ -284,7 +353,6 @@ func OpenAIRespToOpenAI2(openaiResp []byte) ([]byte, error) {
 	openai2Resp := map[string]interface{}{
 		"id":     resp.ID,
 		"object": "response",
-		"status": "completed",
 		"output": output,
 		"usage": map[string]interface{}{
 			"input_tokens":  resp.Usage.PromptTokens,
@@ -292,6 +360,7 @@ func OpenAIRespToOpenAI2(openaiResp []byte) ([]byte, error) {
 			"total_tokens":  resp.Usage.TotalTokens,
 		},
 	}
+	setOpenAI2Status(openai2Resp, finishReason)
 
 	return json.Marshal(openai2Resp)
 }
@@ -331,10 +400,7 @@ func OpenAI2RespToOpenAI(openai2Resp []byte, model string) ([]byte, error) {
 		message["tool_calls"] = toolCalls
 	}
 
-	finishReason := "stop"
-	if len(toolCalls) > 0 {
-		finishReason = "tool_calls"
-	}
+	finishReason := openAIFinishReason(resp.IncompleteReason(), len(toolCalls) > 0)
 
 	openaiResp := map[string]interface{}{
 		"id":      resp.ID,
@@ -355,7 +421,7 @@ func OpenAI2RespToOpenAI(openai2Resp []byte, model string) ([]byte, error) {
 }
 
 // OpenAIStreamToOpenAI2 converts OpenAI Chat stream chunk to OpenAI Responses stream event
-func OpenAIStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte, error) {
+func OpenAIStreamToOpenAI2(event []byte, ctx *transformer.StreamContext, opts transformer.Options) ([]byte, error) {
 	_, jsonData := parseSSE(event)
 	if jsonData == "" || jsonData == "[DONE]" {
 		if jsonData == "[DONE]" && !ctx.FinishReasonSent {
@@ -395,9 +461,13 @@ func OpenAIStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 	}
 
 	var chunk transformer.OpenAIStreamChunk
-	if err := json.Unmarshal([]byte(jsonData), &chunk); err != nil {
+	if err := json.Unmarshal([]byte(normalizeOpenAIStreamChunk(jsonData, ctx, opts.Quirks)), &chunk); err != nil {
 		return nil, nil
 	}
+	if chunk.Usage != nil {
+		ctx.InputTokens = chunk.Usage.PromptTokens
+		ctx.OutputTokens = chunk.Usage.CompletionTokens
+	}
 
 	var result strings.Builder
 	writeEvent := func(evt map[string]interface{}) {
@@ -475,12 +545,13 @@ func OpenAIStreamToOpenAI2(event []byte, ctx *transformer.StreamContext) ([]byte
 					"item": map[string]interface{}{"type": "function_call", "call_id": ctx.CurrentToolID, "name": ctx.CurrentToolName, "arguments": ctx.ToolArguments, "status": "completed"},
 				})
 			}
+			response := map[string]interface{}{
+				"id": ctx.MessageID, "object": "response",
+				"usage": map[string]interface{}{"input_tokens": ctx.InputTokens, "output_tokens": ctx.OutputTokens, "total_tokens": ctx.InputTokens + ctx.OutputTokens},
+			}
 			writeEvent(map[string]interface{}{
-				"type": "response.completed",
-				"response": map[string]interface{}{
-					"id": ctx.MessageID, "object": "response", "status": "completed",
-					"usage": map[string]interface{}{"input_tokens": ctx.InputTokens, "output_tokens": ctx.OutputTokens, "total_tokens": ctx.InputTokens + ctx.OutputTokens},
-				},
+				"type":     setOpenAI2Status(response, *finishReason),
+				"response": response,
 			})
 			result.WriteString("data: [DONE]\n\n")
 			ctx.FinishReasonSent = true
@@ -543,7 +614,8 @@ func OpenAI2StreamToOpenAI(event []byte, ctx *transformer.StreamContext, model s
 		}
 		return nil, nil
 
-	case "response.completed":
+	case "response.completed", "response.incomplete":
+		incompleteReason := ""
 		if evt.Response != nil {
 			if evt.Response.Usage.InputTokens > 0 {
 				ctx.InputTokens = evt.Response.Usage.InputTokens
@@ -551,11 +623,9 @@ func OpenAI2StreamToOpenAI(event []byte, ctx *transformer.StreamContext, model s
 			if evt.Response.Usage.OutputTokens > 0 {
 				ctx.OutputTokens = evt.Response.Usage.OutputTokens
 			}
+			incompleteReason = evt.Response.IncompleteReason()
 		}
-		finishReason := "stop"
-		if ctx.CurrentToolID != "" {
-			finishReason = "tool_calls"
-		}
+		finishReason := openAIFinishReason(incompleteReason, ctx.CurrentToolID != "")
 		usage := map[string]interface{}{
 			"prompt_tokens":     ctx.InputTokens,
 			"completion_tokens": ctx.OutputTokens,
@@ -570,6 +640,16 @@ func OpenAI2StreamToOpenAI(event []byte, ctx *transformer.StreamContext, model s
 	return nil, nil
 }
 
+// openAI2InputItemType returns the type of a Responses API input item. The
+// short message form {"role": ..., "content": ...} carries no type.
+func openAI2InputItemType(item map[string]interface{}) string {
+	itemType, _ := item["type"].(string)
+	if itemType == "" && item["role"] != nil {
+		return "message"
+	}
+	return itemType
+}
+
 func extractOpenAI2Text(content interface{}) string {
 	arr, ok := content.([]interface{})
 	if !ok {
@@ -589,3 +669,44 @@ func extractOpenAI2Text(content interface{}) string {
 	}
 	return strings.Join(parts, "")
 }
+
+// convertOpenAI2ContentToOpenAI returns plain text for text-only content and
+// a Chat content part array when media parts are present.
+func convertOpenAI2ContentToOpenAI(content interface{}) (interface{}, error) {
+	arr, ok := content.([]interface{})
+	if !ok {
+		if str, ok := content.(string); ok {
+			return str, nil
+		}
+		return "", nil
+	}
+
+	var parts []map[string]interface{}
+	hasMedia := false
+	for _, part := range arr {
+		partMap, ok := part.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch partMap["type"] {
+		case "input_text", "output_text":
+			parts = append(parts, map[string]interface{}{"type": "text", "text": partMap["text"]})
+		case "input_image", "input_file":
+			media, err := mediaFromOpenAI2(partMap)
+			if err != nil {
+				return nil, err
+			}
+			converted, err := media.toOpenAI()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, converted)
+			hasMedia = true
+		}
+	}
+
+	if !hasMedia {
+		return extractOpenAI2Text(content), nil
+	}
+	return parts, nil
+}
@@ -15,7 +15,7 @@ func TestOpenAIReqToOpenAI2DefaultsToolChoiceAutoWhenToolsPresent(t *testing.T)
 		"tools":[{"type":"function","function":{"name":"Write","description":"Write file","parameters":{"type":"object"}}}]
 	}`
 
-	reqBytes, err := OpenAIReqToOpenAI2([]byte(openaiReq), "gpt-4.1")
+	reqBytes, err := OpenAIReqToOpenAI2([]byte(openaiReq), "gpt-4.1", transformer.Options{})
 	if err != nil {
 		t.Fatalf("OpenAIReqToOpenAI2 failed: %v", err)
 	}
@@ -104,3 +104,19 @@ func TestOpenAI2StreamToOpenAIIncludesUsageOnCompleted(t *testing.T) {
 		t.Fatalf("expected total_tokens=42, got %#v", usage["total_tokens"])
 	}
 }
+
+func TestOpenAIReqToOpenAI2KeepsStrictToolSchemaValid(t *testing.T) {
+	req := `{"messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{
+		"name":"search","strict":true,
+		"parameters":{"type":"object","properties":{"query":{"type":"string"},"limit":{"type":"integer","default":10}},"required":["query"]}
+	}}]}`
+
+	out, err := OpenAIReqToOpenAI2([]byte(req), "gpt-5", transformer.Options{})
+	if err != nil {
+		t.Fatalf("OpenAIReqToOpenAI2 failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"strict":true`, "strict flag should be preserved")
+	assertContains(t, result, `"limit":{"type":["integer","null"]}`, "optional property should become nullable")
+	assertContains(t, result, `"required":["limit","query"]`, "strict mode requires every property")
+}
@@ -0,0 +1,220 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+// ApplyOpenAIRequestQuirks adapts an OpenAI Chat request to the provider's
+// quirk profile: how reasoning is requested, the output token limit and
+// fields the provider rejects. Requests are returned unchanged without a
+// profile.
+func ApplyOpenAIRequestQuirks(body []byte, opts transformer.Options) ([]byte, error) {
+	q := opts.Quirks
+	if q.IsZero() {
+		return body, nil
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	effort, _ := req["reasoning_effort"].(string)
+	switch q.ReasoningRequest {
+	case transformer.ReasoningRequestEnableThinking:
+		delete(req, "reasoning_effort")
+		if effort != "" {
+			req["enable_thinking"] = true
+			req["thinking_budget"] = opts.Reasoning.BudgetForEffort(effort)
+		}
+	case transformer.ReasoningRequestThinking:
+		delete(req, "reasoning_effort")
+		if effort != "" {
+			req["thinking"] = map[string]interface{}{"type": "enabled"}
+		}
+	case transformer.ReasoningRequestReasoning:
+		delete(req, "reasoning_effort")
+		if effort != "" {
+			req["reasoning"] = map[string]interface{}{"effort": effort}
+		}
+	case transformer.ReasoningRequestNone:
+		delete(req, "reasoning_effort")
+	}
+
+	if q.Usage == transformer.UsageNone {
+		delete(req, "stream_options")
+	}
+
+	limit, field := 0.0, ""
+	for _, name := range []string{transformer.MaxTokensFieldCompletion, transformer.MaxTokensFieldLegacy} {
+		if value, ok := req[name].(float64); ok && value > 0 {
+			limit, field = value, name
+			break
+		}
+	}
+	if field != "" {
+		if q.MaxTokens > 0 && limit > float64(q.MaxTokens) {
+			limit = float64(q.MaxTokens)
+		}
+		if q.MaxTokensField != "" {
+			delete(req, transformer.MaxTokensFieldCompletion)
+			delete(req, transformer.MaxTokensFieldLegacy)
+			field = q.MaxTokensField
+		}
+		req[field] = int(limit)
+	}
+
+	for _, name := range q.DropFields {
+		delete(req, name)
+	}
+	return json.Marshal(req)
+}
+
+// rewritesOpenAIResponses reports whether responses need normalizing before
+// the converters read them
+func rewritesOpenAIResponses(q transformer.QuirkProfile) bool {
+	return q.Reasoning == transformer.ReasoningFieldReasoning || q.Reasoning == transformer.ReasoningNone ||
+		q.Usage == transformer.UsageChoice ||
+		q.ToolCalls == transformer.ToolCallsByIndex || q.ToolCalls == transformer.ToolCallsByID
+}
+
+// normalizeOpenAIResponse rewrites a non-streaming response of a quirky
+// provider into the shape the converters read: reasoning in
+// reasoning_content and usage at the top level
+func normalizeOpenAIResponse(resp []byte, q transformer.QuirkProfile) []byte {
+	if !rewritesOpenAIResponses(q) {
+		return resp
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(resp, &body); err != nil {
+		return resp
+	}
+	choices, _ := body["choices"].([]interface{})
+	for _, item := range choices {
+		choice, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if message, ok := choice["message"].(map[string]interface{}); ok {
+			normalizeOpenAIReasoning(message, q)
+		}
+		moveOpenAIChoiceUsage(body, choice, q)
+	}
+	normalized, err := json.Marshal(body)
+	if err != nil {
+		return resp
+	}
+	return normalized
+}
+
+// normalizeOpenAIStreamChunk is normalizeOpenAIResponse for stream chunks.
+// It also gives every streamed tool call a stable index and sends its id on
+// the first chunk only, which is what the converters expect.
+func normalizeOpenAIStreamChunk(jsonData string, ctx *transformer.StreamContext, q transformer.QuirkProfile) string {
+	if !rewritesOpenAIResponses(q) {
+		return jsonData
+	}
+	var chunk map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonData), &chunk); err != nil {
+		return jsonData
+	}
+	chunkID, _ := chunk["id"].(string)
+	choices, _ := chunk["choices"].([]interface{})
+	for _, item := range choices {
+		choice, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if delta, ok := choice["delta"].(map[string]interface{}); ok {
+			normalizeOpenAIReasoning(delta, q)
+			normalizeOpenAIToolCallDeltas(delta, chunkID, ctx, q)
+		}
+		moveOpenAIChoiceUsage(chunk, choice, q)
+	}
+	normalized, err := json.Marshal(chunk)
+	if err != nil {
+		return jsonData
+	}
+	return string(normalized)
+}
+
+func normalizeOpenAIReasoning(message map[string]interface{}, q transformer.QuirkProfile) {
+	switch q.Reasoning {
+	case transformer.ReasoningFieldReasoning:
+		if text, ok := message["reasoning"].(string); ok {
+			if existing, _ := message["reasoning_content"].(string); existing == "" {
+				message["reasoning_content"] = text
+			}
+		}
+		delete(message, "reasoning")
+	case transformer.ReasoningNone:
+		delete(message, "reasoning_content")
+		delete(message, "reasoning")
+	}
+}
+
+func moveOpenAIChoiceUsage(body, choice map[string]interface{}, q transformer.QuirkProfile) {
+	if q.Usage != transformer.UsageChoice {
+		return
+	}
+	usage, ok := choice["usage"]
+	if !ok {
+		return
+	}
+	delete(choice, "usage")
+	if body["usage"] == nil {
+		body["usage"] = usage
+	}
+}
+
+func normalizeOpenAIToolCallDeltas(delta map[string]interface{}, chunkID string, ctx *transformer.StreamContext, q transformer.QuirkProfile) {
+	calls, _ := delta["tool_calls"].([]interface{})
+	for position, item := range calls {
+		call, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := call["id"].(string)
+
+		switch q.ToolCalls {
+		case transformer.ToolCallsByIndex:
+			index := position
+			if value, ok := call["index"].(float64); ok {
+				index = int(value)
+			}
+			if ctx.UpstreamToolIDs == nil {
+				ctx.UpstreamToolIDs = make(map[int]string)
+			}
+			if _, known := ctx.UpstreamToolIDs[index]; known {
+				delete(call, "id")
+			} else {
+				if id == "" {
+					id = fmt.Sprintf("call_%s_%d", chunkID, index)
+				}
+				ctx.UpstreamToolIDs[index] = id
+				call["id"] = id
+			}
+			call["index"] = index
+		case transformer.ToolCallsByID:
+			if ctx.UpstreamToolIndexes == nil {
+				ctx.UpstreamToolIndexes = make(map[string]int)
+			}
+			if id == "" {
+				if len(ctx.UpstreamToolIndexes) > 0 {
+					call["index"] = len(ctx.UpstreamToolIndexes) - 1
+				}
+				continue
+			}
+			if index, known := ctx.UpstreamToolIndexes[id]; known {
+				delete(call, "id")
+				call["index"] = index
+				continue
+			}
+			index := len(ctx.UpstreamToolIndexes)
+			ctx.UpstreamToolIndexes[id] = index
+			call["index"] = index
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package convert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+func quirkOptions(t *testing.T, profile string) transformer.Options {
+	t.Helper()
+	q, ok := transformer.BuiltinQuirkProfile(profile)
+	if !ok {
+		t.Fatalf("unknown profile %s", profile)
+	}
+	return transformer.Options{Quirks: q}
+}
+
+func TestApplyOpenAIRequestQuirks(t *testing.T) {
+	clamped := transformer.QuirkProfile{MaxTokens: 1000, DropFields: []string{"user"}}
+	noUsage := transformer.QuirkProfile{Usage: transformer.UsageNone}
+
+	tests := []struct {
+		name string
+		opts transformer.Options
+		body string
+		want map[string]interface{}
+	}{
+		{
+			name: "no profile",
+			body: `{"model":"m","reasoning_effort":"high","max_completion_tokens":2048}`,
+			want: map[string]interface{}{"model": "m", "reasoning_effort": "high", "max_completion_tokens": float64(2048)},
+		},
+		{
+			name: "qwen",
+			opts: quirkOptions(t, "qwen"),
+			body: `{"model":"m","reasoning_effort":"low","max_completion_tokens":2048}`,
+			want: map[string]interface{}{"model": "m", "enable_thinking": true, "thinking_budget": float64(2048), "max_tokens": float64(2048)},
+		},
+		{
+			name: "glm",
+			opts: quirkOptions(t, "glm"),
+			body: `{"model":"m","reasoning_effort":"medium"}`,
+			want: map[string]interface{}{"model": "m", "thinking": map[string]interface{}{"type": "enabled"}},
+		},
+		{
+			name: "openrouter",
+			opts: quirkOptions(t, "openrouter"),
+			body: `{"model":"m","reasoning_effort":"high"}`,
+			want: map[string]interface{}{"model": "m", "reasoning": map[string]interface{}{"effort": "high"}},
+		},
+		{
+			name: "deepseek",
+			opts: quirkOptions(t, "deepseek"),
+			body: `{"model":"m","reasoning_effort":"high","max_tokens":100}`,
+			want: map[string]interface{}{"model": "m", "max_tokens": float64(100)},
+		},
+		{
+			name: "clamp and drop",
+			opts: transformer.Options{Quirks: clamped},
+			body: `{"model":"m","max_completion_tokens":8192,"user":"u"}`,
+			want: map[string]interface{}{"model": "m", "max_completion_tokens": float64(1000)},
+		},
+		{
+			name: "no usage",
+			opts: transformer.Options{Quirks: noUsage},
+			body: `{"model":"m","stream":true,"stream_options":{"include_usage":true}}`,
+			want: map[string]interface{}{"model": "m", "stream": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ApplyOpenAIRequestQuirks([]byte(tt.body), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Fatalf("request = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestOpenAIRespToClaudeReasoningField(t *testing.T) {
+	resp := `{"id":"r1","choices":[{"index":0,"message":{"role":"assistant","reasoning":"Let me think.","content":"Done."},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":4}}`
+
+	out, err := OpenAIRespToClaude([]byte(resp), quirkOptions(t, "openrouter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claudeResp struct {
+		Content []map[string]interface{} `json:"content"`
+	}
+	if err := json.Unmarshal(out, &claudeResp); err != nil {
+		t.Fatal(err)
+	}
+	if len(claudeResp.Content) != 2 || claudeResp.Content[0]["thinking"] != "Let me think." || claudeResp.Content[1]["text"] != "Done." {
+		t.Fatalf("content = %v", claudeResp.Content)
+	}
+}
+
+func TestOpenAIStreamToClaudeKeepsThinkTagsAsText(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	chunk := `data: {"id":"1","choices":[{"index":0,"delta":{"content":"<think>literal</think>"}}]}`
+
+	out, err := OpenAIStreamToClaude([]byte(chunk), ctx, quirkOptions(t, "deepseek"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := string(out)
+	if strings.Contains(events, `"type":"thinking"`) {
+		t.Fatalf("think tags should not start a thinking block: %s", events)
+	}
+	assertContains(t, events, `"text":"\u003cthink\u003eliteral\u003c/think\u003e"`, "Expected the tags to stay in the text")
+}
+
+func TestOpenAIStreamToClaudeToolCallsByIndex(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	chunks := []string{
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"arguments":"{\"city\":"}}]}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"arguments":"\"Paris\"}"}}]}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"name":"get_time","arguments":"{}"}}]}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+
+	var events strings.Builder
+	for _, chunk := range chunks {
+		out, err := OpenAIStreamToClaude([]byte(chunk), ctx, quirkOptions(t, "qwen"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		events.Write(out)
+	}
+	all := events.String()
+	if starts := strings.Count(all, `"type":"tool_use"`); starts != 2 {
+		t.Fatalf("tool_use blocks = %d, want 2: %s", starts, all)
+	}
+	assertContains(t, all, `"id":"call_c1_1"`, "Expected a generated id for the call without one")
+}
+
+func TestOpenAIStreamToOpenAI2ToolCallsByID(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	opts := transformer.Options{Quirks: transformer.QuirkProfile{ToolCalls: transformer.ToolCallsByID}}
+	chunks := []string{
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"a","arguments":"{}"}}]}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_b","type":"function","function":{"name":"b","arguments":"{}"}}]}}]}`,
+	}
+	for _, chunk := range chunks {
+		if _, err := OpenAIStreamToOpenAI2([]byte(chunk), ctx, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ctx.UpstreamToolIndexes["call_a"] != 0 || ctx.UpstreamToolIndexes["call_b"] != 1 {
+		t.Fatalf("indexes = %v, want distinct indexes per id", ctx.UpstreamToolIndexes)
+	}
+}
+
+func TestOpenAIStreamToClaudeChoiceUsage(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	chunk := `data: {"id":"1","choices":[{"index":0,"delta":{},"finish_reason":"stop","usage":{"prompt_tokens":11,"completion_tokens":22}}]}`
+
+	out, err := OpenAIStreamToClaude([]byte(chunk), ctx, quirkOptions(t, "kimi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, string(out), `"output_tokens":22`, "Expected usage from the choice")
+}
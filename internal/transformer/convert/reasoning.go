@@ -0,0 +1,298 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+const (
+	claudeMinThinkingBudget = 1024
+	claudeThinkingHeadroom  = 4096
+	geminiDynamicBudget     = -1
+
+	// openAIEncryptedReasoningPrefix marks Fernet tokens issued by OpenAI as
+	// reasoning encrypted_content. Signatures from other providers never use it.
+	openAIEncryptedReasoningPrefix = "gAAAAA"
+)
+
+// reasoningIntent is the provider-neutral form of a thinking/reasoning request
+type reasoningIntent struct {
+	Set     bool   // The source request expressed a preference
+	Enabled bool   // Thinking is enabled
+	Budget  int    // Thinking budget in tokens, 0 if expressed as effort
+	Effort  string // Reasoning effort, empty if expressed as budget
+	Dynamic bool   // Budget left to the model (Gemini -1)
+	Summary string // Responses API summary mode requested by the client
+}
+
+func (r reasoningIntent) effort(cfg transformer.ReasoningConfig) string {
+	if r.Effort != "" {
+		return r.Effort
+	}
+	if r.Dynamic || r.Budget <= 0 {
+		return transformer.ReasoningEffortMedium
+	}
+	return cfg.EffortForBudget(r.Budget)
+}
+
+func (r reasoningIntent) budget(cfg transformer.ReasoningConfig) int {
+	if r.Budget > 0 {
+		return r.Budget
+	}
+	return cfg.BudgetForEffort(r.effort(cfg))
+}
+
+func reasoningFromClaude(thinking interface{}) reasoningIntent {
+	m, ok := thinking.(map[string]interface{})
+	if !ok {
+		return reasoningIntent{}
+	}
+	switch m["type"] {
+	case "enabled":
+		budget, _ := m["budget_tokens"].(float64)
+		return reasoningIntent{Set: true, Enabled: true, Budget: int(budget)}
+	case "disabled":
+		return reasoningIntent{Set: true}
+	}
+	return reasoningIntent{}
+}
+
+func reasoningFromOpenAI(req transformer.OpenAIRequest) reasoningIntent {
+	if req.ReasoningEffort != "" {
+		return reasoningFromEffort(req.ReasoningEffort)
+	}
+	if req.EnableThinking {
+		return reasoningIntent{Set: true, Enabled: true}
+	}
+	return reasoningIntent{}
+}
+
+func reasoningFromOpenAI2(reasoning *transformer.OpenAI2Reasoning) reasoningIntent {
+	if reasoning == nil {
+		return reasoningIntent{}
+	}
+	intent := reasoningFromEffort(reasoning.Effort)
+	if reasoning.Effort == "" {
+		intent = reasoningIntent{Set: true, Enabled: true}
+	}
+	intent.Summary = reasoning.Summary
+	return intent
+}
+
+func reasoningFromEffort(effort string) reasoningIntent {
+	effort = strings.ToLower(strings.TrimSpace(effort))
+	if effort == "none" {
+		return reasoningIntent{Set: true}
+	}
+	return reasoningIntent{Set: true, Enabled: true, Effort: effort}
+}
+
+func reasoningFromGemini(config *transformer.GeminiGenerationConfig) reasoningIntent {
+	if config == nil || config.ThinkingConfig == nil || config.ThinkingConfig.ThinkingBudget == nil {
+		return reasoningIntent{}
+	}
+	budget := *config.ThinkingConfig.ThinkingBudget
+	switch {
+	case budget == 0:
+		return reasoningIntent{Set: true}
+	case budget < 0:
+		return reasoningIntent{Set: true, Enabled: true, Dynamic: true}
+	}
+	return reasoningIntent{Set: true, Enabled: true, Budget: budget}
+}
+
+// applyReasoningToClaude sets thinking on a Claude request map. Claude requires
+// max_tokens above the budget and rejects custom temperature while thinking.
+func applyReasoningToClaude(claudeReq map[string]interface{}, intent reasoningIntent, cfg transformer.ReasoningConfig) {
+	if !intent.Set {
+		return
+	}
+	if !intent.Enabled {
+		claudeReq["thinking"] = map[string]interface{}{"type": "disabled"}
+		return
+	}
+	budget := intent.budget(cfg)
+	if budget < claudeMinThinkingBudget {
+		budget = claudeMinThinkingBudget
+	}
+	if maxTokens, _ := claudeReq["max_tokens"].(int); maxTokens <= budget {
+		claudeReq["max_tokens"] = budget + claudeThinkingHeadroom
+	}
+	delete(claudeReq, "temperature")
+	claudeReq["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+}
+
+// openAIReasoningEffort returns the reasoning_effort for an OpenAI Chat request.
+// Disabled thinking is left to the model default since not all models accept "none".
+func openAIReasoningEffort(intent reasoningIntent, cfg transformer.ReasoningConfig) string {
+	if !intent.Enabled {
+		return ""
+	}
+	return intent.effort(cfg)
+}
+
+// openAI2Reasoning returns the reasoning object for a Responses API request
+func openAI2Reasoning(intent reasoningIntent, cfg transformer.ReasoningConfig) *transformer.OpenAI2Reasoning {
+	if !intent.Enabled {
+		return nil
+	}
+	summary := intent.Summary
+	if summary == "" {
+		summary = cfg.SummaryMode()
+	}
+	return &transformer.OpenAI2Reasoning{Effort: intent.effort(cfg), Summary: summary}
+}
+
+// geminiThinkingConfig returns the thinkingConfig for a Gemini request
+func geminiThinkingConfig(intent reasoningIntent, cfg transformer.ReasoningConfig) *transformer.GeminiThinkingConfig {
+	if !intent.Set {
+		return nil
+	}
+	budget := 0
+	if intent.Enabled {
+		budget = geminiDynamicBudget
+		if !intent.Dynamic {
+			budget = intent.budget(cfg)
+		}
+	}
+	return &transformer.GeminiThinkingConfig{ThinkingBudget: &budget, IncludeThoughts: intent.Enabled}
+}
+
+// isOpenAIEncryptedReasoning reports whether a thinking signature carries
+// OpenAI reasoning encrypted_content rather than a Claude signature
+func isOpenAIEncryptedReasoning(signature string) bool {
+	return strings.HasPrefix(signature, openAIEncryptedReasoningPrefix)
+}
+
+// reasoningSummaryText joins the summary parts of a Responses API reasoning item
+func reasoningSummaryText(summary []interface{}) string {
+	var parts []string
+	for _, s := range summary {
+		if m, ok := s.(map[string]interface{}); ok {
+			if text, _ := m["text"].(string); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// openAI2ReasoningItem builds a Responses API reasoning input item from a
+// Claude thinking block. Returns nil when the block carries no OpenAI payload.
+func openAI2ReasoningItem(block map[string]interface{}) map[string]interface{} {
+	signature, _ := block["signature"].(string)
+	if !isOpenAIEncryptedReasoning(signature) {
+		return nil
+	}
+	summary := []map[string]interface{}{}
+	if text, _ := block["thinking"].(string); text != "" {
+		summary = append(summary, map[string]interface{}{"type": "summary_text", "text": text})
+	}
+	return map[string]interface{}{
+		"type":              "reasoning",
+		"summary":           summary,
+		"encrypted_content": signature,
+	}
+}
+
+// claudeThinkingFromReasoningItem restores a Claude thinking block from a
+// Responses API reasoning item whose encrypted_content holds a Claude signature
+func claudeThinkingFromReasoningItem(item map[string]interface{}) map[string]interface{} {
+	signature, _ := item["encrypted_content"].(string)
+	if signature == "" || isOpenAIEncryptedReasoning(signature) {
+		return nil
+	}
+	summary, _ := item["summary"].([]interface{})
+	return map[string]interface{}{
+		"type":      "thinking",
+		"thinking":  reasoningSummaryText(summary),
+		"signature": signature,
+	}
+}
+
+// prependThinkingBlocks places thinking blocks ahead of converted Claude content
+func prependThinkingBlocks(thinking []map[string]interface{}, content interface{}) []map[string]interface{} {
+	blocks := append([]map[string]interface{}{}, thinking...)
+	switch v := content.(type) {
+	case string:
+		if v != "" {
+			blocks = append(blocks, map[string]interface{}{"type": "text", "text": v})
+		}
+	case []map[string]interface{}:
+		blocks = append(blocks, v...)
+	}
+	return blocks
+}
+
+// claudeThinkingFromOpenAI2Output converts a Responses API reasoning output
+// item to a Claude thinking block, carrying encrypted_content as the signature
+func claudeThinkingFromOpenAI2Output(item transformer.OpenAI2OutputItem) map[string]interface{} {
+	var parts []string
+	for _, s := range item.Summary {
+		if s.Text != "" {
+			parts = append(parts, s.Text)
+		}
+	}
+	if len(parts) == 0 && item.EncryptedContent == "" {
+		return nil
+	}
+	block := map[string]interface{}{
+		"type":     "thinking",
+		"thinking": strings.Join(parts, "\n\n"),
+	}
+	if item.EncryptedContent != "" {
+		block["signature"] = item.EncryptedContent
+	}
+	return block
+}
+
+// openAI2ReasoningOutput builds a Responses API reasoning output item from
+// Claude thinking. The signature is returned as encrypted_content so clients
+// running with store=false can send it back on the next turn.
+func openAI2ReasoningOutput(id, thinking, signature string) map[string]interface{} {
+	summary := []map[string]interface{}{}
+	if thinking != "" {
+		summary = append(summary, map[string]interface{}{"type": "summary_text", "text": thinking})
+	}
+	item := map[string]interface{}{
+		"type":    "reasoning",
+		"id":      id,
+		"summary": summary,
+	}
+	if signature != "" {
+		item["encrypted_content"] = signature
+	}
+	return item
+}
+
+// finishClaudeThinkingBlock closes the streamed thinking block for a finished
+// Responses API reasoning item, attaching encrypted_content as its signature.
+// A block is opened first when the item carried no summary text.
+func finishClaudeThinkingBlock(ctx *transformer.StreamContext, signature string) []byte {
+	var result []byte
+	if !ctx.ThinkingBlockStarted {
+		if signature == "" {
+			return nil
+		}
+		if ctx.ContentBlockStarted {
+			result = append(result, buildClaudeEvent("content_block_stop", map[string]interface{}{"index": ctx.ContentIndex})...)
+			ctx.ContentBlockStarted = false
+			ctx.ContentIndex++
+		}
+		ctx.ThinkingBlockStarted = true
+		ctx.ThinkingIndex = ctx.ContentIndex
+		ctx.ContentIndex++
+		result = append(result, buildClaudeEvent("content_block_start", map[string]interface{}{
+			"index": ctx.ThinkingIndex, "content_block": map[string]interface{}{"type": "thinking", "thinking": ""},
+		})...)
+	}
+	if signature != "" {
+		result = append(result, buildClaudeEvent("content_block_delta", map[string]interface{}{
+			"index": ctx.ThinkingIndex, "delta": map[string]interface{}{"type": "signature_delta", "signature": signature},
+		})...)
+	}
+	result = append(result, buildClaudeEvent("content_block_stop", map[string]interface{}{"index": ctx.ThinkingIndex})...)
+	ctx.ThinkingBlockStarted = false
+	return result
+}
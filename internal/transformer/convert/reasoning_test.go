@@ -0,0 +1,256 @@
+package convert
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+func TestReasoningParameterMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		convert func([]byte, string, transformer.Options) ([]byte, error)
+		request string
+		opts    transformer.Options
+		path    []string
+		want    string
+	}{
+		{
+			name:    "claude budget to openai effort",
+			convert: ClaudeReqToOpenAI,
+			request: `{"max_tokens":32000,"thinking":{"type":"enabled","budget_tokens":10000},"messages":[]}`,
+			path:    []string{"reasoning_effort"},
+			want:    `"medium"`,
+		},
+		{
+			name:    "claude budget uses endpoint thresholds",
+			convert: ClaudeReqToOpenAI,
+			request: `{"max_tokens":32000,"thinking":{"type":"enabled","budget_tokens":10000},"messages":[]}`,
+			opts:    transformer.Options{Reasoning: transformer.ReasoningConfig{LowMaxBudget: 2000, MediumMaxBudget: 8000}},
+			path:    []string{"reasoning_effort"},
+			want:    `"high"`,
+		},
+		{
+			name:    "claude budget to responses reasoning",
+			convert: ClaudeReqToOpenAI2,
+			request: `{"max_tokens":32000,"thinking":{"type":"enabled","budget_tokens":2048},"messages":[]}`,
+			path:    []string{"reasoning"},
+			want:    `{"effort":"low","summary":"auto"}`,
+		},
+		{
+			name:    "claude budget to gemini thinking config",
+			convert: ClaudeReqToGemini,
+			request: `{"max_tokens":32000,"thinking":{"type":"enabled","budget_tokens":6000},"messages":[]}`,
+			path:    []string{"generationConfig", "thinkingConfig"},
+			want:    `{"thinkingBudget":6000,"includeThoughts":true}`,
+		},
+		{
+			name:    "claude disabled thinking to gemini",
+			convert: ClaudeReqToGemini,
+			request: `{"thinking":{"type":"disabled"},"messages":[]}`,
+			path:    []string{"generationConfig", "thinkingConfig"},
+			want:    `{"thinkingBudget":0}`,
+		},
+		{
+			name:    "openai effort to claude budget",
+			convert: OpenAIReqToClaude,
+			request: `{"reasoning_effort":"high","max_tokens":4000,"temperature":0.2,"messages":[]}`,
+			path:    []string{"thinking"},
+			want:    `{"type":"enabled","budget_tokens":24576}`,
+		},
+		{
+			name:    "openai effort uses endpoint budget",
+			convert: OpenAIReqToClaude,
+			request: `{"reasoning_effort":"low","messages":[]}`,
+			opts:    transformer.Options{Reasoning: transformer.ReasoningConfig{LowBudget: 3000}},
+			path:    []string{"thinking"},
+			want:    `{"type":"enabled","budget_tokens":3000}`,
+		},
+		{
+			name:    "openai effort to responses",
+			convert: OpenAIReqToOpenAI2,
+			request: `{"reasoning_effort":"minimal","messages":[]}`,
+			opts:    transformer.Options{Reasoning: transformer.ReasoningConfig{Summary: "detailed"}},
+			path:    []string{"reasoning"},
+			want:    `{"effort":"minimal","summary":"detailed"}`,
+		},
+		{
+			name:    "responses effort to claude",
+			convert: OpenAI2ReqToClaude,
+			request: `{"reasoning":{"effort":"medium","summary":"auto"},"input":"hi"}`,
+			path:    []string{"thinking"},
+			want:    `{"type":"enabled","budget_tokens":8192}`,
+		},
+		{
+			name:    "responses effort to openai chat",
+			convert: OpenAI2ReqToOpenAI,
+			request: `{"reasoning":{"effort":"high"},"input":"hi"}`,
+			path:    []string{"reasoning_effort"},
+			want:    `"high"`,
+		},
+		{
+			name:    "responses effort to gemini",
+			convert: OpenAI2ReqToGemini,
+			request: `{"reasoning":{"effort":"low"},"input":"hi"}`,
+			path:    []string{"generationConfig", "thinkingConfig"},
+			want:    `{"thinkingBudget":2048,"includeThoughts":true}`,
+		},
+		{
+			name:    "gemini dynamic budget to claude",
+			convert: GeminiReqToClaude,
+			request: `{"contents":[],"generationConfig":{"thinkingConfig":{"thinkingBudget":-1}}}`,
+			path:    []string{"thinking"},
+			want:    `{"type":"enabled","budget_tokens":8192}`,
+		},
+		{
+			name:    "openai effort to gemini",
+			convert: OpenAIReqToGemini,
+			request: `{"reasoning_effort":"medium","messages":[]}`,
+			path:    []string{"generationConfig", "thinkingConfig"},
+			want:    `{"thinkingBudget":8192,"includeThoughts":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.convert([]byte(tt.request), "target-model", tt.opts)
+			if err != nil {
+				t.Fatalf("convert failed: %v", err)
+			}
+			var payload interface{}
+			if err := json.Unmarshal(out, &payload); err != nil {
+				t.Fatalf("unmarshal output failed: %v", err)
+			}
+			got := lookupJSONPath(t, payload, tt.path)
+
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("unmarshal want failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				t.Fatalf("reasoning mismatch\n got: %s\nwant: %s", gotJSON, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIReqToClaudeThinkingAdjustsMaxTokens(t *testing.T) {
+	out, err := OpenAIReqToClaude([]byte(`{"reasoning_effort":"high","max_tokens":4000,"temperature":0.2,"messages":[]}`), "claude", transformer.Options{})
+	if err != nil {
+		t.Fatalf("OpenAIReqToClaude failed: %v", err)
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if maxTokens := req["max_tokens"].(float64); maxTokens <= 24576 {
+		t.Fatalf("expected max_tokens above thinking budget, got %v", maxTokens)
+	}
+	if _, ok := req["temperature"]; ok {
+		t.Fatalf("expected temperature to be dropped when thinking is enabled")
+	}
+}
+
+func TestOpenAI2RespToClaudeMapsReasoningSummary(t *testing.T) {
+	resp := `{
+		"id": "resp_1",
+		"output": [
+			{"type": "reasoning", "id": "rs_1", "summary": [{"type": "summary_text", "text": "Step one"}, {"type": "summary_text", "text": "Step two"}], "encrypted_content": "gAAAAABenc"},
+			{"type": "message", "role": "assistant", "content": [{"type": "output_text", "text": "Answer"}]}
+		],
+		"usage": {"input_tokens": 1, "output_tokens": 2}
+	}`
+
+	out, err := OpenAI2RespToClaude([]byte(resp))
+	if err != nil {
+		t.Fatalf("OpenAI2RespToClaude failed: %v", err)
+	}
+	assertContains(t, string(out), `{"signature":"gAAAAABenc","thinking":"Step one\n\nStep two","type":"thinking"}`, "reasoning summary should become a thinking block")
+}
+
+func TestOpenAI2StreamToClaudeMapsReasoningSummary(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	events := []string{
+		`data: {"type":"response.created","response":{"id":"resp_1"}}`,
+		`data: {"type":"response.output_item.added","item":{"type":"reasoning","id":"rs_1"}}`,
+		`data: {"type":"response.reasoning_summary_text.delta","delta":"Thinking"}`,
+		`data: {"type":"response.output_item.done","item":{"type":"reasoning","id":"rs_1","encrypted_content":"gAAAAABenc"}}`,
+		`data: {"type":"response.output_text.delta","delta":"Answer"}`,
+		`data: {"type":"response.completed","response":{"id":"resp_1"}}`,
+	}
+
+	var out strings.Builder
+	for _, evt := range events {
+		chunk, err := OpenAI2StreamToClaude([]byte(evt), ctx)
+		if err != nil {
+			t.Fatalf("OpenAI2StreamToClaude failed: %v", err)
+		}
+		out.Write(chunk)
+	}
+	result := out.String()
+
+	assertContains(t, result, `"content_block":{"thinking":"","type":"thinking"},"index":0`, "thinking block should start at index 0")
+	assertContains(t, result, `"delta":{"thinking":"Thinking","type":"thinking_delta"}`, "summary delta should be forwarded")
+	assertContains(t, result, `"delta":{"signature":"gAAAAABenc","type":"signature_delta"}`, "encrypted content should become the signature")
+	assertContains(t, result, `"content_block":{"text":"","type":"text"},"index":1`, "text block should follow the thinking block")
+}
+
+func TestClaudeThinkingRoundTripThroughResponses(t *testing.T) {
+	claudeResp := `{"id":"msg_1","type":"message","role":"assistant","content":[
+		{"type":"thinking","thinking":"Let me check","signature":"EqQBClaudeSig"},
+		{"type":"tool_use","id":"toolu_1","name":"read","input":{"path":"a"}}
+	],"usage":{"input_tokens":1,"output_tokens":2}}`
+
+	out, err := ClaudeRespToOpenAI2([]byte(claudeResp))
+	if err != nil {
+		t.Fatalf("ClaudeRespToOpenAI2 failed: %v", err)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	output := resp["output"].([]interface{})
+	reasoning := output[0].(map[string]interface{})
+	if reasoning["type"] != "reasoning" || reasoning["encrypted_content"] != "EqQBClaudeSig" {
+		t.Fatalf("expected reasoning item carrying the signature, got %v", reasoning)
+	}
+
+	// Next turn: the Responses client replays the reasoning item with the call
+	nextReq, _ := json.Marshal(map[string]interface{}{
+		"input": []interface{}{
+			map[string]interface{}{"type": "message", "role": "user", "content": "read a"},
+			reasoning,
+			output[1],
+			map[string]interface{}{"type": "function_call_output", "call_id": "toolu_1", "output": "ok"},
+		},
+	})
+	claudeReq, err := OpenAI2ReqToClaude(nextReq, "claude", transformer.Options{})
+	if err != nil {
+		t.Fatalf("OpenAI2ReqToClaude failed: %v", err)
+	}
+	assertContains(t, string(claudeReq), `"content":[{"signature":"EqQBClaudeSig","thinking":"Let me check","type":"thinking"},{"id":"toolu_1"`, "thinking should precede tool_use in the assistant turn")
+}
+
+func TestClaudeReqToOpenAI2ReplaysEncryptedReasoning(t *testing.T) {
+	req := `{"thinking":{"type":"enabled","budget_tokens":4096},"messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":[
+			{"type":"thinking","thinking":"Plan","signature":"gAAAAABenc"},
+			{"type":"thinking","thinking":"Native","signature":"EqQBClaudeSig"},
+			{"type":"text","text":"Hello"}
+		]}
+	]}`
+
+	out, err := ClaudeReqToOpenAI2([]byte(req), "gpt-5", transformer.Options{})
+	if err != nil {
+		t.Fatalf("ClaudeReqToOpenAI2 failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `{"encrypted_content":"gAAAAABenc","summary":[{"text":"Plan","type":"summary_text"}],"type":"reasoning"}`, "OpenAI reasoning should be replayed")
+	assertNotContains(t, result, "EqQBClaudeSig", "Claude signatures must not be sent to Responses API")
+	assertContains(t, result, `"include":["reasoning.encrypted_content"]`, "encrypted reasoning should be requested")
+}
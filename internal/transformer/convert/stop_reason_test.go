@@ -0,0 +1,201 @@
+package convert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+func TestFinishReasonMapping(t *testing.T) {
+	cases := []struct {
+		reason     string
+		toolUse    bool
+		claude     string
+		openai     string
+		incomplete string
+	}{
+		{"", false, "end_turn", "stop", ""},
+		{"STOP", true, "tool_use", "tool_calls", ""},
+		{"max_tokens", false, "max_tokens", "length", "max_output_tokens"},
+		{"length", true, "max_tokens", "length", "max_output_tokens"},
+		{"MAX_TOKENS", false, "max_tokens", "length", "max_output_tokens"},
+		{"content_filter", false, "refusal", "content_filter", "content_filter"},
+		{"SAFETY", false, "refusal", "content_filter", "content_filter"},
+	}
+	for _, tc := range cases {
+		if got := claudeStopReason(tc.reason, tc.toolUse); got != tc.claude {
+			t.Errorf("claudeStopReason(%q, %v) = %q, want %q", tc.reason, tc.toolUse, got, tc.claude)
+		}
+		if got := openAIFinishReason(tc.reason, tc.toolUse); got != tc.openai {
+			t.Errorf("openAIFinishReason(%q, %v) = %q, want %q", tc.reason, tc.toolUse, got, tc.openai)
+		}
+		if got := openAI2IncompleteReason(tc.reason); got != tc.incomplete {
+			t.Errorf("openAI2IncompleteReason(%q) = %q, want %q", tc.reason, got, tc.incomplete)
+		}
+	}
+}
+
+func TestClaudeRespToOpenAI2ReportsIncomplete(t *testing.T) {
+	claudeResp := `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Hel"}],"stop_reason":"max_tokens","usage":{"input_tokens":3,"output_tokens":5}}`
+	out, err := ClaudeRespToOpenAI2([]byte(claudeResp))
+	if err != nil {
+		t.Fatalf("ClaudeRespToOpenAI2 failed: %v", err)
+	}
+	var resp transformer.OpenAI2Response
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "incomplete" || resp.IncompleteReason() != "max_output_tokens" {
+		t.Fatalf("expected an incomplete response for max_tokens, got %s", out)
+	}
+
+	// And back: an incomplete Responses answer stops on max_tokens
+	back, err := OpenAI2RespToClaude(out)
+	if err != nil {
+		t.Fatalf("OpenAI2RespToClaude failed: %v", err)
+	}
+	if !strings.Contains(string(back), `"stop_reason":"max_tokens"`) {
+		t.Fatalf("expected stop_reason max_tokens, got %s", back)
+	}
+}
+
+func TestClaudeStreamToOpenAI2EndsWithIncomplete(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	events := []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"input_tokens\":3}}}\n\n",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"},\"usage\":{\"output_tokens\":5}}\n\n",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+	}
+	var out strings.Builder
+	for _, event := range events {
+		chunk, err := ClaudeStreamToOpenAI2([]byte(event), ctx)
+		if err != nil {
+			t.Fatalf("ClaudeStreamToOpenAI2 failed: %v", err)
+		}
+		out.Write(chunk)
+	}
+	if !strings.Contains(out.String(), `"type":"response.incomplete"`) || strings.Contains(out.String(), `"type":"response.completed"`) {
+		t.Fatalf("expected the stream to end with response.incomplete, got:\n%s", out.String())
+	}
+}
+
+func TestOpenAI2StreamToClaudeHandlesIncomplete(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	chunks := []string{
+		`data: {"type":"response.created","response":{"id":"resp_1","object":"response","status":"in_progress"}}`,
+		`data: {"type":"response.output_text.delta","delta":"Hel"}`,
+		`data: {"type":"response.incomplete","response":{"id":"resp_1","object":"response","status":"incomplete","incomplete_details":{"reason":"max_output_tokens"}}}`,
+	}
+	var out strings.Builder
+	for _, chunk := range chunks {
+		events, err := OpenAI2StreamToClaude([]byte(chunk), ctx)
+		if err != nil {
+			t.Fatalf("OpenAI2StreamToClaude failed: %v", err)
+		}
+		out.Write(events)
+	}
+	if !strings.Contains(out.String(), `"stop_reason":"max_tokens"`) || !strings.Contains(out.String(), "message_stop") {
+		t.Fatalf("expected the message to stop on max_tokens, got:\n%s", out.String())
+	}
+}
+
+func TestGeminiRespToOpenAIMapsFinishReasonAndDropsThoughts(t *testing.T) {
+	geminiResp := `{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking","thought":true},{"text":"Answer"}]},"finishReason":"MAX_TOKENS"}]}`
+	out, err := GeminiRespToOpenAI([]byte(geminiResp), "gemini-2.5-pro")
+	if err != nil {
+		t.Fatalf("GeminiRespToOpenAI failed: %v", err)
+	}
+	if !strings.Contains(string(out), `"finish_reason":"length"`) {
+		t.Fatalf("expected finish_reason length, got %s", out)
+	}
+	if strings.Contains(string(out), "thinking") {
+		t.Fatalf("thoughts must not be returned as content, got %s", out)
+	}
+
+	out, err = GeminiRespToOpenAI2([]byte(geminiResp))
+	if err != nil {
+		t.Fatalf("GeminiRespToOpenAI2 failed: %v", err)
+	}
+	var resp transformer.OpenAI2Response
+	json.Unmarshal(out, &resp)
+	if len(resp.Output) != 2 || resp.Output[0].Type != "reasoning" || resp.IncompleteReason() != "max_output_tokens" {
+		t.Fatalf("expected a reasoning item and an incomplete response, got %s", out)
+	}
+}
+
+func TestToolResultToString(t *testing.T) {
+	cases := []struct {
+		content interface{}
+		want    string
+	}{
+		{"plain", "plain"},
+		{[]interface{}{map[string]interface{}{"type": "text", "text": "a"}, map[string]interface{}{"type": "text", "text": "b"}}, "a\nb"},
+		{[]interface{}{map[string]interface{}{"type": "image", "source": "x"}}, `[{"source":"x","type":"image"}]`},
+	}
+	for _, tc := range cases {
+		if got := toolResultToString(tc.content); got != tc.want {
+			t.Errorf("toolResultToString(%v) = %q, want %q", tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestGeminiFunctionResponseRoundTrip(t *testing.T) {
+	if got := geminiFunctionResponse(`{"temperature":21}`); got["temperature"] != float64(21) {
+		t.Fatalf("a JSON object must be the response itself, got %v", got)
+	}
+	wrapped := geminiFunctionResponse("sunny")
+	if wrapped["result"] != "sunny" || geminiFunctionResponseText(wrapped) != "sunny" {
+		t.Fatalf("text must round-trip through {\"result\": ...}, got %v", wrapped)
+	}
+
+	claudeReq := `{"model":"claude","max_tokens":100,"messages":[
+		{"role":"user","content":"weather?"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"city not found","is_error":true}]}]}`
+	geminiReq, err := ClaudeReqToGemini([]byte(claudeReq), "gemini-2.5-pro", transformer.Options{})
+	if err != nil {
+		t.Fatalf("ClaudeReqToGemini failed: %v", err)
+	}
+	if !strings.Contains(string(geminiReq), `"response":{"error":"city not found"}`) {
+		t.Fatalf("expected a failed call to be sent as {\"error\": ...}, got %s", geminiReq)
+	}
+
+	back, err := GeminiReqToClaude(geminiReq, "claude", transformer.Options{})
+	if err != nil {
+		t.Fatalf("GeminiReqToClaude failed: %v", err)
+	}
+	if !strings.Contains(string(back), `"is_error":true`) || !strings.Contains(string(back), `"content":"city not found"`) {
+		t.Fatalf("expected the error tool result to round-trip, got %s", back)
+	}
+}
+
+func TestOpenAIReqToOpenAI2ConvertsToolCalls(t *testing.T) {
+	openaiReq := `{"model":"gpt-4.1","messages":[
+		{"role":"user","content":"weather?"},
+		{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"call_1","content":"sunny"}]}`
+	out, err := OpenAIReqToOpenAI2([]byte(openaiReq), "gpt-4.1", transformer.Options{})
+	if err != nil {
+		t.Fatalf("OpenAIReqToOpenAI2 failed: %v", err)
+	}
+	var req struct {
+		Input []map[string]interface{} `json:"input"`
+	}
+	json.Unmarshal(out, &req)
+	if len(req.Input) != 3 || req.Input[1]["type"] != "function_call" || req.Input[2]["type"] != "function_call_output" || req.Input[2]["output"] != "sunny" {
+		t.Fatalf("expected message, function_call and function_call_output items, got %s", out)
+	}
+}
+
+func TestOpenAI2ShortMessageInput(t *testing.T) {
+	openai2Req := `{"model":"gpt-4.1","input":[{"role":"user","content":"hello"}]}`
+	out, err := OpenAI2ReqToOpenAI([]byte(openai2Req), "gpt-4.1", transformer.Options{})
+	if err != nil {
+		t.Fatalf("OpenAI2ReqToOpenAI failed: %v", err)
+	}
+	if !strings.Contains(string(out), `"role":"user"`) || !strings.Contains(string(out), "hello") {
+		t.Fatalf("expected the short message form to be converted, got %s", out)
+	}
+}
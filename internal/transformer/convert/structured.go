@@ -0,0 +1,458 @@
+package convert
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/schema"
+)
+
+// structuredOutputToolName is the synthetic tool used to emulate JSON schema
+// output on Claude. Calls to it are unwrapped into plain text responses.
+const structuredOutputToolName = "structured_output"
+
+const (
+	defaultOutputFormatName      = "response"
+	structuredOutputToolDesc     = "Respond with the final answer. The input must be the complete response as JSON matching the schema."
+	structuredOutputInstructions = "When you have the final answer, respond by calling the " + structuredOutputToolName + " tool with the complete response as its input."
+)
+
+// outputFormat is the provider-neutral form of a structured output request.
+// A nil *outputFormat means plain text output.
+type outputFormat struct {
+	Name        string
+	Description string
+	Schema      map[string]interface{} // nil for schemaless JSON mode
+	Strict      bool
+}
+
+func (f *outputFormat) name() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return defaultOutputFormatName
+}
+
+func (f *outputFormat) schemaOrObject() map[string]interface{} {
+	if f.Schema != nil {
+		return f.Schema
+	}
+	return map[string]interface{}{"type": "object"}
+}
+
+// outputFormatFromOpenAI reads a Chat Completions response_format
+func outputFormatFromOpenAI(responseFormat interface{}) *outputFormat {
+	m, ok := responseFormat.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch m["type"] {
+	case "json_object":
+		return &outputFormat{}
+	case "json_schema":
+		spec, _ := m["json_schema"].(map[string]interface{})
+		return outputFormatFromSpec(spec)
+	}
+	return nil
+}
+
+// outputFormatFromOpenAI2 reads a Responses API text.format
+func outputFormatFromOpenAI2(text *transformer.OpenAI2TextConfig) *outputFormat {
+	if text == nil {
+		return nil
+	}
+	switch text.Format["type"] {
+	case "json_object":
+		return &outputFormat{}
+	case "json_schema":
+		return outputFormatFromSpec(text.Format)
+	}
+	return nil
+}
+
+// outputFormatFromClaude reads a Claude output_format
+func outputFormatFromClaude(format interface{}) *outputFormat {
+	m, ok := format.(map[string]interface{})
+	if !ok || m["type"] != "json_schema" {
+		return nil
+	}
+	f := outputFormatFromSpec(m)
+	f.Strict = true
+	return f
+}
+
+// outputFormatFromGemini reads responseMimeType and responseSchema
+func outputFormatFromGemini(config *transformer.GeminiGenerationConfig) *outputFormat {
+	if config == nil || config.ResponseMimeType != "application/json" {
+		return nil
+	}
+	f := &outputFormat{Schema: config.ResponseJSONSchema}
+	if f.Schema == nil {
+		f.Schema = config.ResponseSchema
+	}
+	return f
+}
+
+func outputFormatFromSpec(spec map[string]interface{}) *outputFormat {
+	f := &outputFormat{}
+	if spec == nil {
+		return f
+	}
+	f.Name, _ = spec["name"].(string)
+	f.Description, _ = spec["description"].(string)
+	f.Schema, _ = spec["schema"].(map[string]interface{})
+	f.Strict, _ = spec["strict"].(bool)
+	return f
+}
+
+// openAIResponseFormat builds a Chat Completions response_format
+func openAIResponseFormat(f *outputFormat) map[string]interface{} {
+	if f.Schema == nil {
+		return map[string]interface{}{"type": "json_object"}
+	}
+	spec := map[string]interface{}{
+		"name":   f.name(),
+		"schema": normalizeToolSchema(f.name(), f.Schema, openAISchemaProfile(f.Strict)),
+	}
+	if f.Description != "" {
+		spec["description"] = f.Description
+	}
+	if f.Strict {
+		spec["strict"] = true
+	}
+	return map[string]interface{}{"type": "json_schema", "json_schema": spec}
+}
+
+// openAI2TextFormat builds a Responses API text config
+func openAI2TextFormat(f *outputFormat) map[string]interface{} {
+	if f.Schema == nil {
+		return map[string]interface{}{"format": map[string]interface{}{"type": "json_object"}}
+	}
+	format := map[string]interface{}{
+		"type":   "json_schema",
+		"name":   f.name(),
+		"schema": normalizeToolSchema(f.name(), f.Schema, openAISchemaProfile(f.Strict)),
+	}
+	if f.Description != "" {
+		format["description"] = f.Description
+	}
+	if f.Strict {
+		format["strict"] = true
+	}
+	return map[string]interface{}{"format": format}
+}
+
+// applyOutputFormatToGemini sets responseMimeType/responseSchema on a Gemini generationConfig
+func applyOutputFormatToGemini(genConfig map[string]interface{}, f *outputFormat) {
+	if f == nil {
+		return
+	}
+	genConfig["responseMimeType"] = "application/json"
+	if f.Schema != nil {
+		genConfig["responseSchema"] = normalizeToolSchema(f.name(), f.Schema, schema.ProfileGemini)
+	}
+}
+
+// applyOutputFormatToClaude emulates structured output with a synthetic tool
+// that the model must call. Claude rejects forced tool use while thinking, so
+// in that case the model is instructed to call the tool instead.
+func applyOutputFormatToClaude(claudeReq map[string]interface{}, f *outputFormat) {
+	if f == nil {
+		return
+	}
+	description := structuredOutputToolDesc
+	if f.Description != "" {
+		description = f.Description + "\n\n" + description
+	}
+	tools, _ := claudeReq["tools"].([]map[string]interface{})
+	hasClientTools := len(tools) > 0
+	claudeReq["tools"] = append(tools, map[string]interface{}{
+		"name":         structuredOutputToolName,
+		"description":  description,
+		"input_schema": normalizeToolSchema(f.name(), f.schemaOrObject(), schema.ProfileClaude),
+	})
+
+	existing, _ := claudeReq["tool_choice"].(map[string]interface{})
+	choice := map[string]interface{}{"type": "tool", "name": structuredOutputToolName}
+	if hasClientTools {
+		switch existing["type"] {
+		case "tool":
+			choice = existing
+		case "none":
+		default:
+			choice = map[string]interface{}{"type": "any"}
+		}
+	}
+	if thinking, _ := claudeReq["thinking"].(map[string]interface{}); thinking["type"] == "enabled" {
+		choice = map[string]interface{}{"type": "auto"}
+		appendClaudeSystemText(claudeReq, structuredOutputInstructions)
+	}
+	if disable, _ := existing["disable_parallel_tool_use"].(bool); disable {
+		choice["disable_parallel_tool_use"] = true
+	}
+	claudeReq["tool_choice"] = choice
+}
+
+// appendClaudeSystemText adds a paragraph to a Claude system prompt
+func appendClaudeSystemText(claudeReq map[string]interface{}, text string) {
+	switch system := claudeReq["system"].(type) {
+	case string:
+		if system != "" {
+			claudeReq["system"] = system + "\n\n" + text
+			return
+		}
+	case []interface{}:
+		claudeReq["system"] = append(system, map[string]interface{}{"type": "text", "text": text})
+		return
+	}
+	claudeReq["system"] = text
+}
+
+// unwrapStructuredOutputResponse turns a synthetic structured output tool call
+// in a Claude response into a text block holding the JSON
+func unwrapStructuredOutputResponse(claudeResp []byte) []byte {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(claudeResp, &resp); err != nil {
+		return claudeResp
+	}
+	content, ok := resp["content"].([]interface{})
+	if !ok {
+		return claudeResp
+	}
+
+	var structured map[string]interface{}
+	clientToolUse := false
+	for _, block := range content {
+		m, ok := block.(map[string]interface{})
+		if !ok || m["type"] != "tool_use" {
+			continue
+		}
+		if m["name"] == structuredOutputToolName && structured == nil {
+			structured = m
+		} else {
+			clientToolUse = true
+		}
+	}
+	if structured == nil {
+		return claudeResp
+	}
+
+	text, _ := json.Marshal(structured["input"])
+	var unwrapped []interface{}
+	for _, block := range content {
+		m, _ := block.(map[string]interface{})
+		switch {
+		case m == nil:
+			unwrapped = append(unwrapped, block)
+		case m["type"] == "text" && !clientToolUse:
+			// Preamble text would make the response invalid JSON
+		case m["type"] == "tool_use" && m["name"] == structuredOutputToolName:
+			unwrapped = append(unwrapped, map[string]interface{}{"type": "text", "text": string(text)})
+		default:
+			unwrapped = append(unwrapped, m)
+		}
+	}
+	resp["content"] = unwrapped
+	if resp["stop_reason"] == "tool_use" && !clientToolUse {
+		resp["stop_reason"] = "end_turn"
+	}
+
+	updated, err := json.Marshal(resp)
+	if err != nil {
+		return claudeResp
+	}
+	return updated
+}
+
+// unwrapStructuredOutputEvent rewrites Claude stream events of the synthetic
+// structured output tool into text events. Other events are returned unchanged.
+func unwrapStructuredOutputEvent(event []byte, ctx *transformer.StreamContext) []byte {
+	if ctx == nil || (!ctx.StructuredOutputSeen && !strings.Contains(string(event), "tool_use")) {
+		return event
+	}
+	eventType, jsonData := parseSSE(event)
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonData), &data); err != nil {
+		return event
+	}
+	index, _ := data["index"].(float64)
+
+	switch eventType {
+	case "content_block_start":
+		block, _ := data["content_block"].(map[string]interface{})
+		if block["type"] != "tool_use" {
+			return event
+		}
+		if block["name"] != structuredOutputToolName {
+			ctx.ClientToolUseSeen = true
+			return event
+		}
+		ctx.StructuredOutputSeen = true
+		ctx.StructuredOutputIndex = int(index)
+		data["content_block"] = map[string]interface{}{"type": "text", "text": ""}
+		return buildClaudeEvent(eventType, data)
+	case "content_block_delta":
+		if !ctx.StructuredOutputSeen || int(index) != ctx.StructuredOutputIndex {
+			return event
+		}
+		delta, _ := data["delta"].(map[string]interface{})
+		if delta["type"] != "input_json_delta" {
+			return event
+		}
+		partial, _ := delta["partial_json"].(string)
+		if partial == "" {
+			return nil
+		}
+		data["delta"] = map[string]interface{}{"type": "text_delta", "text": partial}
+		return buildClaudeEvent(eventType, data)
+	case "message_delta":
+		delta, _ := data["delta"].(map[string]interface{})
+		if ctx.StructuredOutputSeen && !ctx.ClientToolUseSeen && delta["stop_reason"] == "tool_use" {
+			delta["stop_reason"] = "end_turn"
+			return buildClaudeEvent(eventType, data)
+		}
+	}
+	return event
+}
+
+// toolChoiceIntent is the provider-neutral form of tool_choice
+type toolChoiceIntent struct {
+	Mode            string // "auto", "none", "required" or "tool"; "" when unspecified
+	Name            string // Tool name when Mode is "tool"
+	DisableParallel bool   // At most one tool call per turn
+}
+
+// toolChoiceFromOpenAI reads tool_choice and parallel_tool_calls from Chat
+// Completions or Responses API requests
+func toolChoiceFromOpenAI(toolChoice interface{}, parallel *bool) toolChoiceIntent {
+	intent := toolChoiceIntent{DisableParallel: parallel != nil && !*parallel}
+	switch tc := toolChoice.(type) {
+	case string:
+		switch tc {
+		case "auto", "none", "required":
+			intent.Mode = tc
+		}
+	case map[string]interface{}:
+		if tc["type"] != "function" {
+			break
+		}
+		name, _ := tc["name"].(string)
+		if fn, ok := tc["function"].(map[string]interface{}); ok {
+			name, _ = fn["name"].(string)
+		}
+		if name != "" {
+			intent.Mode = "tool"
+			intent.Name = name
+		}
+	}
+	return intent
+}
+
+// toolChoiceFromClaude reads a Claude tool_choice
+func toolChoiceFromClaude(toolChoice interface{}) toolChoiceIntent {
+	tc, ok := toolChoice.(map[string]interface{})
+	if !ok {
+		return toolChoiceIntent{}
+	}
+	intent := toolChoiceIntent{}
+	intent.DisableParallel, _ = tc["disable_parallel_tool_use"].(bool)
+	switch tc["type"] {
+	case "auto", "none":
+		intent.Mode, _ = tc["type"].(string)
+	case "any":
+		intent.Mode = "required"
+	case "tool":
+		if name, _ := tc["name"].(string); name != "" {
+			intent.Mode = "tool"
+			intent.Name = name
+		}
+	}
+	return intent
+}
+
+// toolChoiceFromGemini reads a Gemini toolConfig
+func toolChoiceFromGemini(config *transformer.GeminiToolConfig) toolChoiceIntent {
+	if config == nil || config.FunctionCallingConfig == nil {
+		return toolChoiceIntent{}
+	}
+	fc := config.FunctionCallingConfig
+	switch strings.ToUpper(fc.Mode) {
+	case "AUTO", "VALIDATED":
+		return toolChoiceIntent{Mode: "auto"}
+	case "NONE":
+		return toolChoiceIntent{Mode: "none"}
+	case "ANY":
+		if len(fc.AllowedFunctionNames) == 1 {
+			return toolChoiceIntent{Mode: "tool", Name: fc.AllowedFunctionNames[0]}
+		}
+		return toolChoiceIntent{Mode: "required"}
+	}
+	return toolChoiceIntent{}
+}
+
+// claude returns the Claude tool_choice, or nil when the default applies
+func (t toolChoiceIntent) claude() map[string]interface{} {
+	var choice map[string]interface{}
+	switch t.Mode {
+	case "auto", "none":
+		choice = map[string]interface{}{"type": t.Mode}
+	case "required":
+		choice = map[string]interface{}{"type": "any"}
+	case "tool":
+		choice = map[string]interface{}{"type": "tool", "name": t.Name}
+	default:
+		if !t.DisableParallel {
+			return nil
+		}
+		choice = map[string]interface{}{"type": "auto"}
+	}
+	if t.DisableParallel && t.Mode != "none" {
+		choice["disable_parallel_tool_use"] = true
+	}
+	return choice
+}
+
+// openAI returns the Chat Completions tool_choice, or nil when unspecified
+func (t toolChoiceIntent) openAI() interface{} {
+	switch t.Mode {
+	case "auto", "none", "required":
+		return t.Mode
+	case "tool":
+		return map[string]interface{}{"type": "function", "function": map[string]string{"name": t.Name}}
+	}
+	return nil
+}
+
+// openAI2 returns the Responses API tool_choice, or nil when unspecified
+func (t toolChoiceIntent) openAI2() interface{} {
+	if t.Mode == "tool" {
+		return map[string]interface{}{"type": "function", "name": t.Name}
+	}
+	return t.openAI()
+}
+
+// openAIParallelToolCalls returns parallel_tool_calls, or nil for the default
+func (t toolChoiceIntent) openAIParallelToolCalls() *bool {
+	if !t.DisableParallel {
+		return nil
+	}
+	parallel := false
+	return &parallel
+}
+
+// geminiToolConfig returns the Gemini toolConfig. Gemini has no parallel
+// call control, so DisableParallel is not represented.
+func (t toolChoiceIntent) geminiToolConfig() map[string]interface{} {
+	config := map[string]interface{}{"mode": "AUTO"}
+	switch t.Mode {
+	case "none":
+		config["mode"] = "NONE"
+	case "required":
+		config["mode"] = "ANY"
+	case "tool":
+		config["mode"] = "ANY"
+		config["allowedFunctionNames"] = []string{t.Name}
+	}
+	return map[string]interface{}{"functionCallingConfig": config}
+}
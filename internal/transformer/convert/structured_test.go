@@ -0,0 +1,227 @@
+package convert
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+const weatherTool = `{"type":"function","function":{"name":"weather","parameters":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}}`
+
+func TestToolChoiceAndOutputFormatMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		convert func([]byte, string, transformer.Options) ([]byte, error)
+		request string
+		path    []string
+		want    string
+	}{
+		{
+			name:    "openai parallel disabled to claude",
+			convert: OpenAIReqToClaude,
+			request: `{"messages":[],"tools":[` + weatherTool + `],"parallel_tool_calls":false}`,
+			path:    []string{"tool_choice"},
+			want:    `{"type":"auto","disable_parallel_tool_use":true}`,
+		},
+		{
+			name:    "openai named tool to claude",
+			convert: OpenAIReqToClaude,
+			request: `{"messages":[],"tools":[` + weatherTool + `],"tool_choice":{"type":"function","function":{"name":"weather"}}}`,
+			path:    []string{"tool_choice"},
+			want:    `{"type":"tool","name":"weather"}`,
+		},
+		{
+			name:    "responses required to claude",
+			convert: OpenAI2ReqToClaude,
+			request: `{"input":"hi","tools":[{"type":"function","name":"weather","parameters":{"type":"object","properties":{}}}],"tool_choice":"required"}`,
+			path:    []string{"tool_choice"},
+			want:    `{"type":"any"}`,
+		},
+		{
+			name:    "claude disable parallel to openai",
+			convert: ClaudeReqToOpenAI,
+			request: `{"messages":[],"tools":[{"name":"weather","input_schema":{"type":"object"}}],"tool_choice":{"type":"auto","disable_parallel_tool_use":true}}`,
+			path:    []string{"parallel_tool_calls"},
+			want:    `false`,
+		},
+		{
+			name:    "claude disable parallel to responses",
+			convert: ClaudeReqToOpenAI2,
+			request: `{"messages":[],"tools":[{"name":"weather","input_schema":{"type":"object"}}],"tool_choice":{"type":"any","disable_parallel_tool_use":true}}`,
+			path:    []string{"parallel_tool_calls"},
+			want:    `false`,
+		},
+		{
+			name:    "claude named tool to gemini",
+			convert: ClaudeReqToGemini,
+			request: `{"messages":[],"tools":[{"name":"weather","input_schema":{"type":"object"}}],"tool_choice":{"type":"tool","name":"weather"}}`,
+			path:    []string{"toolConfig"},
+			want:    `{"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["weather"]}}`,
+		},
+		{
+			name:    "openai none to gemini",
+			convert: OpenAIReqToGemini,
+			request: `{"messages":[],"tools":[` + weatherTool + `],"tool_choice":"none"}`,
+			path:    []string{"toolConfig"},
+			want:    `{"functionCallingConfig":{"mode":"NONE"}}`,
+		},
+		{
+			name:    "gemini single allowed function to claude",
+			convert: GeminiReqToClaude,
+			request: `{"contents":[],"tools":[{"functionDeclarations":[{"name":"weather","parameters":{"type":"OBJECT","properties":{"city":{"type":"STRING"}}}}]}],"toolConfig":{"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["weather"]}}}`,
+			path:    []string{"tool_choice"},
+			want:    `{"type":"tool","name":"weather"}`,
+		},
+		{
+			name:    "openai json schema to responses",
+			convert: OpenAIReqToOpenAI2,
+			request: `{"messages":[],"response_format":{"type":"json_schema","json_schema":{"name":"city","strict":true,"schema":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}}}}`,
+			path:    []string{"text"},
+			want:    `{"format":{"type":"json_schema","name":"city","strict":true,"schema":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"],"additionalProperties":false}}}`,
+		},
+		{
+			name:    "responses json object to openai",
+			convert: OpenAI2ReqToOpenAI,
+			request: `{"input":"hi","text":{"format":{"type":"json_object"}}}`,
+			path:    []string{"response_format"},
+			want:    `{"type":"json_object"}`,
+		},
+		{
+			name:    "claude output format to gemini",
+			convert: ClaudeReqToGemini,
+			request: `{"messages":[],"output_format":{"type":"json_schema","schema":{"type":"object","properties":{"name":{"type":"string"}},"additionalProperties":false}}}`,
+			path:    []string{"generationConfig"},
+			want:    `{"responseMimeType":"application/json","responseSchema":{"type":"object","properties":{"name":{"type":"string"}}}}`,
+		},
+		{
+			name:    "openai json schema to gemini",
+			convert: OpenAIReqToGemini,
+			request: `{"messages":[],"response_format":{"type":"json_schema","json_schema":{"name":"city","schema":{"type":"object","properties":{"name":{"type":["string","null"]}}}}}}`,
+			path:    []string{"generationConfig"},
+			want:    `{"responseMimeType":"application/json","responseSchema":{"type":"object","properties":{"name":{"type":"string","nullable":true}}}}`,
+		},
+		{
+			name:    "claude output format to openai",
+			convert: ClaudeReqToOpenAI,
+			request: `{"messages":[],"output_format":{"type":"json_schema","schema":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}}}`,
+			path:    []string{"response_format"},
+			want:    `{"type":"json_schema","json_schema":{"name":"response","strict":true,"schema":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"],"additionalProperties":false}}}`,
+		},
+		{
+			name:    "gemini response schema to claude",
+			convert: GeminiReqToClaude,
+			request: `{"contents":[],"generationConfig":{"responseMimeType":"application/json","responseSchema":{"type":"OBJECT","properties":{"name":{"type":"STRING"}}}}}`,
+			path:    []string{"tool_choice"},
+			want:    `{"type":"tool","name":"structured_output"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.convert([]byte(tt.request), "target-model", transformer.Options{})
+			if err != nil {
+				t.Fatalf("convert failed: %v", err)
+			}
+			var payload interface{}
+			if err := json.Unmarshal(out, &payload); err != nil {
+				t.Fatalf("unmarshal output failed: %v", err)
+			}
+			got := lookupJSONPath(t, payload, tt.path)
+
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("unmarshal want failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				t.Fatalf("mismatch\n got: %s\nwant: %s", gotJSON, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIReqToClaudeEmulatesJSONSchemaWithTool(t *testing.T) {
+	req := `{"messages":[{"role":"user","content":"Paris?"}],"response_format":{"type":"json_schema","json_schema":{
+		"name":"city","description":"City facts",
+		"schema":{"type":"object","properties":{"name":{"type":"string"},"population":{"type":"integer"}},"required":["name"]}
+	}}}`
+
+	out, err := OpenAIReqToClaude([]byte(req), "claude", transformer.Options{})
+	if err != nil {
+		t.Fatalf("OpenAIReqToClaude failed: %v", err)
+	}
+	var claudeReq map[string]interface{}
+	if err := json.Unmarshal(out, &claudeReq); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	tools := claudeReq["tools"].([]interface{})
+	tool := tools[0].(map[string]interface{})
+	if tool["name"] != structuredOutputToolName || !strings.HasPrefix(tool["description"].(string), "City facts") {
+		t.Fatalf("expected synthetic structured output tool, got %v", tool)
+	}
+	if tool["input_schema"].(map[string]interface{})["required"].([]interface{})[0] != "name" {
+		t.Fatalf("expected schema to be used as input_schema, got %v", tool["input_schema"])
+	}
+	choice := claudeReq["tool_choice"].(map[string]interface{})
+	if choice["type"] != "tool" || choice["name"] != structuredOutputToolName {
+		t.Fatalf("expected forced structured output tool, got %v", choice)
+	}
+}
+
+func TestOpenAIReqToClaudeStructuredOutputWithThinking(t *testing.T) {
+	req := `{"reasoning_effort":"low","messages":[{"role":"system","content":"Be brief."}],"response_format":{"type":"json_object"}}`
+
+	out, err := OpenAIReqToClaude([]byte(req), "claude", transformer.Options{})
+	if err != nil {
+		t.Fatalf("OpenAIReqToClaude failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"tool_choice":{"type":"auto"}`, "forced tool use is not allowed with thinking")
+	assertContains(t, result, "Be brief.\\n\\nWhen you have the final answer, respond by calling the structured_output tool", "model should be instructed to call the tool")
+}
+
+func TestClaudeRespToOpenAIUnwrapsStructuredOutput(t *testing.T) {
+	resp := `{"id":"msg_1","type":"message","role":"assistant","content":[
+		{"type":"tool_use","id":"toolu_1","name":"structured_output","input":{"name":"Paris"}}
+	],"stop_reason":"tool_use","usage":{"input_tokens":1,"output_tokens":2}}`
+
+	out, err := ClaudeRespToOpenAI([]byte(resp), "claude")
+	if err != nil {
+		t.Fatalf("ClaudeRespToOpenAI failed: %v", err)
+	}
+	result := string(out)
+	assertContains(t, result, `"content":"{\"name\":\"Paris\"}"`, "tool input should become the message content")
+	assertContains(t, result, `"finish_reason":"stop"`, "finish reason should not be tool_calls")
+	assertNotContains(t, result, "tool_calls", "synthetic tool call must not reach the client")
+}
+
+func TestClaudeStreamToOpenAI2UnwrapsStructuredOutput(t *testing.T) {
+	ctx := transformer.NewStreamContext()
+	events := []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"input_tokens\":1}}}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"structured_output\",\"input\":{}}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"name\\\":\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"Paris\\\"}\"}}\n\n",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":5}}\n\n",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+	}
+
+	var out strings.Builder
+	for _, evt := range events {
+		chunk, err := ClaudeStreamToOpenAI2([]byte(evt), ctx)
+		if err != nil {
+			t.Fatalf("ClaudeStreamToOpenAI2 failed: %v", err)
+		}
+		out.Write(chunk)
+	}
+	result := out.String()
+
+	assertContains(t, result, `"delta":"{\"name\":","output_index":0,"type":"response.output_text.delta"`, "streamed JSON should be emitted as output text")
+	assertContains(t, result, `"delta":"\"Paris\"}"`, "every JSON fragment should be forwarded")
+	assertNotContains(t, result, "function_call", "synthetic tool call must not reach the client")
+}
@@ -0,0 +1,17 @@
+package chat
+
+// BedrockTransformer transforms Codex Chat requests to AWS Bedrock, which uses the
+// Claude Messages format; the proxy moves the model into the URL and signs
+// the request
+type BedrockTransformer struct {
+	*ClaudeTransformer
+}
+
+// NewBedrockTransformer creates a new transformer
+func NewBedrockTransformer(model string) *BedrockTransformer {
+	return &BedrockTransformer{ClaudeTransformer: NewClaudeTransformer(model)}
+}
+
+func (t *BedrockTransformer) Name() string {
+	return "cx_chat_bedrock"
+}
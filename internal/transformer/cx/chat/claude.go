@@ -8,6 +8,7 @@ import (
 // ClaudeTransformer transforms Codex Chat requests to Claude format
 type ClaudeTransformer struct {
 	model string
+	opts  transformer.Options
 }
 
 // NewClaudeTransformer creates a new transformer
@@ -19,8 +20,13 @@ func (t *ClaudeTransformer) Name() string {
 	return "cx_chat_claude"
 }
 
+// SetOptions applies per-endpoint conversion options
+func (t *ClaudeTransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
 func (t *ClaudeTransformer) TransformRequest(req []byte) ([]byte, error) {
-	return convert.OpenAIReqToClaude(req, t.model)
+	return convert.OpenAIReqToClaude(req, t.model, t.opts)
 }
 
 func (t *ClaudeTransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
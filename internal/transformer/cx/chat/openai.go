@@ -2,11 +2,13 @@ package chat
 
 import (
 	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
 )
 
 // OpenAITransformer is a passthrough transformer for Codex Chat → OpenAI Chat
 type OpenAITransformer struct {
 	model string
+	opts  transformer.Options
 }
 
 // NewOpenAITransformer creates a new passthrough transformer
@@ -18,8 +20,15 @@ func (t *OpenAITransformer) Name() string {
 	return "cx_chat_openai"
 }
 
+// SetOptions applies per-endpoint conversion options
+func (t *OpenAITransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
+// TransformRequest passes the request through, adapted to the endpoint's
+// quirk profile
 func (t *OpenAITransformer) TransformRequest(req []byte) ([]byte, error) {
-	return req, nil
+	return convert.ApplyOpenAIRequestQuirks(req, t.opts)
 }
 
 func (t *OpenAITransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
@@ -8,6 +8,7 @@ import (
 // ClaudeTransformer transforms Codex Responses requests to Claude format
 type ClaudeTransformer struct {
 	model string
+	opts  transformer.Options
 }
 
 // NewClaudeTransformer creates a new transformer
@@ -19,8 +20,13 @@ func (t *ClaudeTransformer) Name() string {
 	return "cx_resp_claude"
 }
 
+// SetOptions applies per-endpoint conversion options
+func (t *ClaudeTransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
 func (t *ClaudeTransformer) TransformRequest(req []byte) ([]byte, error) {
-	return convert.OpenAI2ReqToClaude(req, t.model)
+	return convert.OpenAI2ReqToClaude(req, t.model, t.opts)
 }
 
 func (t *ClaudeTransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
@@ -8,6 +8,7 @@ import (
 // GeminiTransformer transforms Codex Responses requests to Gemini format
 type GeminiTransformer struct {
 	model string
+	opts  transformer.Options
 }
 
 // NewGeminiTransformer creates a new transformer
@@ -19,8 +20,13 @@ func (t *GeminiTransformer) Name() string {
 	return "cx_resp_gemini"
 }
 
+// SetOptions applies per-endpoint conversion options
+func (t *GeminiTransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
 func (t *GeminiTransformer) TransformRequest(req []byte) ([]byte, error) {
-	return convert.OpenAI2ReqToGemini(req, t.model)
+	return convert.OpenAI2ReqToGemini(req, t.model, t.opts)
 }
 
 func (t *GeminiTransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
@@ -8,6 +8,7 @@ import (
 // OpenAITransformer transforms Codex Responses requests to OpenAI Chat format
 type OpenAITransformer struct {
 	model string
+	opts  transformer.Options
 }
 
 // NewOpenAITransformer creates a new transformer
@@ -19,20 +20,25 @@ func (t *OpenAITransformer) Name() string {
 	return "cx_resp_openai"
 }
 
+// SetOptions applies per-endpoint conversion options
+func (t *OpenAITransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
 func (t *OpenAITransformer) TransformRequest(req []byte) ([]byte, error) {
-	return convert.OpenAI2ReqToOpenAI(req, t.model)
+	return convert.OpenAI2ReqToOpenAI(req, t.model, t.opts)
 }
 
 func (t *OpenAITransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
 	if isStreaming {
 		return nil, nil
 	}
-	return convert.OpenAIRespToOpenAI2(resp)
+	return convert.OpenAIRespToOpenAI2(resp, t.opts)
 }
 
 func (t *OpenAITransformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
 	if isStreaming {
-		return convert.OpenAIStreamToOpenAI2(resp, ctx)
+		return convert.OpenAIStreamToOpenAI2(resp, ctx, t.opts)
 	}
-	return convert.OpenAIRespToOpenAI2(resp)
+	return convert.OpenAIRespToOpenAI2(resp, t.opts)
 }
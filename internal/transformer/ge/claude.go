@@ -0,0 +1,44 @@
+package ge
+
+import (
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+)
+
+// ClaudeTransformer transforms Gemini CLI requests to Claude format
+type ClaudeTransformer struct {
+	model string
+	opts  transformer.Options
+}
+
+// NewClaudeTransformer creates a new transformer
+func NewClaudeTransformer(model string) *ClaudeTransformer {
+	return &ClaudeTransformer{model: model}
+}
+
+func (t *ClaudeTransformer) Name() string {
+	return "ge_claude"
+}
+
+// SetOptions applies per-endpoint conversion options
+func (t *ClaudeTransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
+func (t *ClaudeTransformer) TransformRequest(req []byte) ([]byte, error) {
+	return convert.GeminiReqToClaude(req, t.model, t.opts)
+}
+
+func (t *ClaudeTransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
+	if isStreaming {
+		return nil, nil
+	}
+	return convert.ClaudeRespToGemini(resp)
+}
+
+func (t *ClaudeTransformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
+	if isStreaming {
+		return convert.ClaudeStreamToGemini(resp, ctx)
+	}
+	return convert.ClaudeRespToGemini(resp)
+}
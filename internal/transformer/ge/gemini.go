@@ -0,0 +1,31 @@
+package ge
+
+import (
+	"github.com/lich0821/ccNexus/internal/transformer"
+)
+
+// GeminiTransformer is a passthrough transformer for Gemini CLI → Gemini
+type GeminiTransformer struct {
+	model string
+}
+
+// NewGeminiTransformer creates a new passthrough transformer
+func NewGeminiTransformer(model string) *GeminiTransformer {
+	return &GeminiTransformer{model: model}
+}
+
+func (t *GeminiTransformer) Name() string {
+	return "ge_gemini"
+}
+
+func (t *GeminiTransformer) TransformRequest(req []byte) ([]byte, error) {
+	return req, nil
+}
+
+func (t *GeminiTransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
+	return resp, nil
+}
+
+func (t *GeminiTransformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
+	return resp, nil
+}
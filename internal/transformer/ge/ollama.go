@@ -0,0 +1,60 @@
+package ge
+
+import (
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+)
+
+// OllamaTransformer transforms Gemini CLI requests to the native Ollama /api/chat
+// API. Requests go through the OpenAI Chat conversion first; the proxy turns
+// the NDJSON stream back into OpenAI Chat chunks before they reach this
+// transformer.
+type OllamaTransformer struct {
+	*OpenAITransformer
+	opts transformer.Options
+}
+
+// NewOllamaTransformer creates a new transformer
+func NewOllamaTransformer(model string) *OllamaTransformer {
+	return &OllamaTransformer{OpenAITransformer: NewOpenAITransformer(model)}
+}
+
+func (t *OllamaTransformer) Name() string {
+	return "ge_ollama"
+}
+
+// SetOptions applies per-endpoint conversion options
+func (t *OllamaTransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+	t.OpenAITransformer.SetOptions(opts)
+}
+
+func (t *OllamaTransformer) TransformRequest(req []byte) ([]byte, error) {
+	openaiReq, err := t.OpenAITransformer.TransformRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return convert.OpenAIReqToOllama(openaiReq, t.opts)
+}
+
+func (t *OllamaTransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
+	if isStreaming {
+		return t.OpenAITransformer.TransformResponse(resp, isStreaming)
+	}
+	openaiResp, err := convert.OllamaRespToOpenAI(resp)
+	if err != nil {
+		return nil, err
+	}
+	return t.OpenAITransformer.TransformResponse(openaiResp, false)
+}
+
+func (t *OllamaTransformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
+	if isStreaming {
+		return t.OpenAITransformer.TransformResponseWithContext(resp, isStreaming, ctx)
+	}
+	openaiResp, err := convert.OllamaRespToOpenAI(resp)
+	if err != nil {
+		return nil, err
+	}
+	return t.OpenAITransformer.TransformResponseWithContext(openaiResp, false, ctx)
+}
@@ -0,0 +1,44 @@
+package ge
+
+import (
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+)
+
+// OpenAITransformer transforms Gemini CLI requests to OpenAI Chat format
+type OpenAITransformer struct {
+	model string
+	opts  transformer.Options
+}
+
+// NewOpenAITransformer creates a new transformer
+func NewOpenAITransformer(model string) *OpenAITransformer {
+	return &OpenAITransformer{model: model}
+}
+
+func (t *OpenAITransformer) Name() string {
+	return "ge_openai"
+}
+
+// SetOptions applies per-endpoint conversion options
+func (t *OpenAITransformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
+func (t *OpenAITransformer) TransformRequest(req []byte) ([]byte, error) {
+	return convert.GeminiReqToOpenAI(req, t.model, t.opts)
+}
+
+func (t *OpenAITransformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
+	if isStreaming {
+		return nil, nil
+	}
+	return convert.OpenAIRespToGemini(resp, t.opts)
+}
+
+func (t *OpenAITransformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
+	if isStreaming {
+		return convert.OpenAIStreamToGemini(resp, ctx, t.opts)
+	}
+	return convert.OpenAIRespToGemini(resp, t.opts)
+}
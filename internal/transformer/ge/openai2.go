@@ -0,0 +1,44 @@
+package ge
+
+import (
+	"github.com/lich0821/ccNexus/internal/transformer"
+	"github.com/lich0821/ccNexus/internal/transformer/convert"
+)
+
+// OpenAI2Transformer transforms Gemini CLI requests to OpenAI Responses API format
+type OpenAI2Transformer struct {
+	model string
+	opts  transformer.Options
+}
+
+// NewOpenAI2Transformer creates a new transformer
+func NewOpenAI2Transformer(model string) *OpenAI2Transformer {
+	return &OpenAI2Transformer{model: model}
+}
+
+func (t *OpenAI2Transformer) Name() string {
+	return "ge_openai2"
+}
+
+// SetOptions applies per-endpoint conversion options
+func (t *OpenAI2Transformer) SetOptions(opts transformer.Options) {
+	t.opts = opts
+}
+
+func (t *OpenAI2Transformer) TransformRequest(req []byte) ([]byte, error) {
+	return convert.GeminiReqToOpenAI2(req, t.model, t.opts)
+}
+
+func (t *OpenAI2Transformer) TransformResponse(resp []byte, isStreaming bool) ([]byte, error) {
+	if isStreaming {
+		return nil, nil
+	}
+	return convert.OpenAI2RespToGemini(resp)
+}
+
+func (t *OpenAI2Transformer) TransformResponseWithContext(resp []byte, isStreaming bool, ctx *transformer.StreamContext) ([]byte, error) {
+	if isStreaming {
+		return convert.OpenAI2StreamToGemini(resp, ctx)
+	}
+	return convert.OpenAI2RespToGemini(resp)
+}
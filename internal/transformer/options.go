@@ -0,0 +1,112 @@
+package transformer
+
+import "strings"
+
+// Options carries per-endpoint conversion settings. The zero value uses defaults.
+type Options struct {
+	Reasoning ReasoningConfig
+	Ollama    OllamaConfig
+	Quirks    QuirkProfile // OpenAI-compatible provider deviations
+}
+
+// OllamaConfig holds Ollama request settings. Zero fields are left to the server.
+type OllamaConfig struct {
+	NumCtx    int    // Context window size sent as options.num_ctx
+	KeepAlive string // How long the model stays loaded, e.g. "5m" or "-1"
+}
+
+// Configurable is implemented by transformers that accept per-endpoint options
+type Configurable interface {
+	SetOptions(opts Options)
+}
+
+// Reasoning effort levels used by the OpenAI Chat and Responses APIs
+const (
+	ReasoningEffortMinimal = "minimal"
+	ReasoningEffortLow     = "low"
+	ReasoningEffortMedium  = "medium"
+	ReasoningEffortHigh    = "high"
+)
+
+// Default thresholds for mapping thinking budgets to reasoning effort
+const (
+	DefaultReasoningLowMaxBudget    = 4096
+	DefaultReasoningMediumMaxBudget = 16384
+	DefaultReasoningLowBudget       = 2048
+	DefaultReasoningMediumBudget    = 8192
+	DefaultReasoningHighBudget      = 24576
+	DefaultReasoningSummary         = "auto"
+)
+
+// ReasoningConfig controls how thinking budgets (Claude, Gemini) and reasoning
+// effort levels (OpenAI) are translated into each other. Zero fields use defaults.
+type ReasoningConfig struct {
+	LowMaxBudget    int    // Budgets up to this value map to "low"
+	MediumMaxBudget int    // Budgets up to this value map to "medium", above is "high"
+	LowBudget       int    // Budget used for "minimal" and "low" effort
+	MediumBudget    int    // Budget used for "medium" effort
+	HighBudget      int    // Budget used for "high" effort
+	Summary         string // Responses API reasoning.summary mode, "none" disables summaries
+}
+
+func (c ReasoningConfig) withDefaults() ReasoningConfig {
+	if c.LowMaxBudget <= 0 {
+		c.LowMaxBudget = DefaultReasoningLowMaxBudget
+	}
+	if c.MediumMaxBudget <= 0 {
+		c.MediumMaxBudget = DefaultReasoningMediumMaxBudget
+	}
+	if c.MediumMaxBudget <= c.LowMaxBudget {
+		c.MediumMaxBudget = c.LowMaxBudget * 4
+	}
+	if c.LowBudget <= 0 {
+		c.LowBudget = DefaultReasoningLowBudget
+	}
+	if c.MediumBudget <= 0 {
+		c.MediumBudget = DefaultReasoningMediumBudget
+	}
+	if c.HighBudget <= 0 {
+		c.HighBudget = DefaultReasoningHighBudget
+	}
+	if c.Summary == "" {
+		c.Summary = DefaultReasoningSummary
+	}
+	return c
+}
+
+// EffortForBudget returns the reasoning effort level for a thinking budget
+func (c ReasoningConfig) EffortForBudget(budget int) string {
+	c = c.withDefaults()
+	switch {
+	case budget <= c.LowMaxBudget:
+		return ReasoningEffortLow
+	case budget <= c.MediumMaxBudget:
+		return ReasoningEffortMedium
+	default:
+		return ReasoningEffortHigh
+	}
+}
+
+// BudgetForEffort returns the thinking budget for a reasoning effort level.
+// Unknown levels are treated as "medium".
+func (c ReasoningConfig) BudgetForEffort(effort string) int {
+	c = c.withDefaults()
+	switch strings.ToLower(strings.TrimSpace(effort)) {
+	case ReasoningEffortMinimal, ReasoningEffortLow:
+		return c.LowBudget
+	case ReasoningEffortHigh, "xhigh":
+		return c.HighBudget
+	default:
+		return c.MediumBudget
+	}
+}
+
+// SummaryMode returns the Responses API reasoning.summary value, or "" when
+// summaries are disabled
+func (c ReasoningConfig) SummaryMode() string {
+	c = c.withDefaults()
+	if c.Summary == "none" {
+		return ""
+	}
+	return c.Summary
+}
@@ -0,0 +1,160 @@
+package transformer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Where an OpenAI-compatible provider returns reasoning
+const (
+	ReasoningFieldContent   = "reasoning_content" // message.reasoning_content / delta.reasoning_content
+	ReasoningFieldReasoning = "reasoning"         // message.reasoning / delta.reasoning
+	ReasoningThinkTags      = "think_tags"        // <think>...</think> inside the content
+	ReasoningNone           = "none"              // No reasoning is returned
+)
+
+// How an OpenAI-compatible provider is asked for reasoning
+const (
+	ReasoningRequestEffort         = "reasoning_effort" // reasoning_effort: low / medium / high
+	ReasoningRequestEnableThinking = "enable_thinking"  // enable_thinking: true and thinking_budget
+	ReasoningRequestThinking       = "thinking"         // thinking: {"type": "enabled"}
+	ReasoningRequestReasoning      = "reasoning"        // reasoning: {"effort": ...}
+	ReasoningRequestNone           = "none"             // Reasoning cannot be requested
+)
+
+// How an OpenAI-compatible provider reports usage in streams
+const (
+	UsageStandard = "standard" // usage on a chunk, requested with stream_options.include_usage
+	UsageChoice   = "choice"   // usage inside choices[0] of the final chunk
+	UsageNone     = "none"     // No usage, stream_options is rejected; tokens are estimated
+)
+
+// How an OpenAI-compatible provider identifies streamed tool calls
+const (
+	ToolCallsStandard = "standard" // The first chunk of each call carries its id
+	ToolCallsByIndex  = "index"    // index identifies the call, ids may be missing or repeated
+	ToolCallsByID     = "id"       // id identifies the call, indexes may be wrong
+)
+
+// Output token limit fields
+const (
+	MaxTokensFieldCompletion = "max_completion_tokens"
+	MaxTokensFieldLegacy     = "max_tokens"
+)
+
+// QuirkProfile describes how an OpenAI-compatible provider deviates from the
+// OpenAI Chat API. Empty fields keep the default behavior, which reads
+// reasoning from reasoning_content as well as <think> tags.
+type QuirkProfile struct {
+	Reasoning        string   // Where reasoning is returned
+	ReasoningRequest string   // How reasoning is requested
+	Usage            string   // How streams report usage
+	ToolCalls        string   // How streamed tool calls are identified
+	MaxTokens        int      // Upper bound for the output token limit, 0 for none
+	MaxTokensField   string   // Field the output token limit is sent in
+	DropFields       []string // Top-level request fields the provider rejects
+}
+
+var builtinQuirkProfiles = map[string]QuirkProfile{
+	"deepseek": {
+		Reasoning:        ReasoningFieldContent,
+		ReasoningRequest: ReasoningRequestNone,
+		MaxTokensField:   MaxTokensFieldLegacy,
+	},
+	"qwen": {
+		Reasoning:        ReasoningFieldContent,
+		ReasoningRequest: ReasoningRequestEnableThinking,
+		ToolCalls:        ToolCallsByIndex,
+		MaxTokensField:   MaxTokensFieldLegacy,
+	},
+	"kimi": {
+		Reasoning:        ReasoningFieldContent,
+		ReasoningRequest: ReasoningRequestNone,
+		Usage:            UsageChoice,
+		MaxTokensField:   MaxTokensFieldLegacy,
+	},
+	"glm": {
+		Reasoning:        ReasoningFieldContent,
+		ReasoningRequest: ReasoningRequestThinking,
+		MaxTokensField:   MaxTokensFieldLegacy,
+	},
+	"minimax": {
+		Reasoning:        ReasoningThinkTags,
+		ReasoningRequest: ReasoningRequestNone,
+		MaxTokensField:   MaxTokensFieldLegacy,
+	},
+	"openrouter": {
+		Reasoning:        ReasoningFieldReasoning,
+		ReasoningRequest: ReasoningRequestReasoning,
+		MaxTokensField:   MaxTokensFieldLegacy,
+	},
+	"vllm": {
+		Reasoning:        ReasoningFieldContent,
+		ReasoningRequest: ReasoningRequestNone,
+		ToolCalls:        ToolCallsByIndex,
+		MaxTokensField:   MaxTokensFieldLegacy,
+	},
+}
+
+// BuiltinQuirkProfile returns a built-in profile by name
+func BuiltinQuirkProfile(name string) (QuirkProfile, bool) {
+	profile, ok := builtinQuirkProfiles[name]
+	if ok {
+		profile.DropFields = append([]string(nil), profile.DropFields...)
+	}
+	return profile, ok
+}
+
+// BuiltinQuirkProfileNames returns the names of the built-in profiles
+func BuiltinQuirkProfileNames() []string {
+	names := make([]string, 0, len(builtinQuirkProfiles))
+	for name := range builtinQuirkProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsZero reports whether the profile keeps every default
+func (q QuirkProfile) IsZero() bool {
+	return q.Reasoning == "" && q.ReasoningRequest == "" && q.Usage == "" && q.ToolCalls == "" &&
+		q.MaxTokens == 0 && q.MaxTokensField == "" && len(q.DropFields) == 0
+}
+
+// ParseThinkTags reports whether <think> tags in the content are reasoning
+func (q QuirkProfile) ParseThinkTags() bool {
+	return q.Reasoning == "" || q.Reasoning == ReasoningThinkTags
+}
+
+// Validate checks the profile's values
+func (q QuirkProfile) Validate() error {
+	checks := []struct {
+		field, value string
+		allowed      []string
+	}{
+		{"reasoning", q.Reasoning, []string{ReasoningFieldContent, ReasoningFieldReasoning, ReasoningThinkTags, ReasoningNone}},
+		{"reasoningRequest", q.ReasoningRequest, []string{ReasoningRequestEffort, ReasoningRequestEnableThinking, ReasoningRequestThinking, ReasoningRequestReasoning, ReasoningRequestNone}},
+		{"usage", q.Usage, []string{UsageStandard, UsageChoice, UsageNone}},
+		{"toolCalls", q.ToolCalls, []string{ToolCallsStandard, ToolCallsByIndex, ToolCallsByID}},
+		{"maxTokensField", q.MaxTokensField, []string{MaxTokensFieldCompletion, MaxTokensFieldLegacy}},
+	}
+	for _, check := range checks {
+		if check.value == "" {
+			continue
+		}
+		valid := false
+		for _, allowed := range check.allowed {
+			if check.value == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid %s %q, expected one of %v", check.field, check.value, check.allowed)
+		}
+	}
+	if q.MaxTokens < 0 {
+		return fmt.Errorf("maxTokens must not be negative")
+	}
+	return nil
+}
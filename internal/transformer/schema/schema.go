@@ -0,0 +1,634 @@
+// Package schema adapts JSON Schema tool definitions to the dialect accepted
+// by each upstream provider.
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile identifies the schema dialect of a target provider
+type Profile string
+
+const (
+	ProfileGemini       Profile = "gemini"        // OpenAPI 3.0 subset used by functionDeclarations
+	ProfileOpenAIStrict Profile = "openai_strict" // Structured outputs with strict: true
+	ProfileOpenAI       Profile = "openai"        // Function parameters without strict mode
+	ProfileClaude       Profile = "claude"        // Claude input_schema
+)
+
+// Issue describes a conversion that dropped or relaxed part of a schema
+type Issue struct {
+	Path    string // Location in the source schema, "" for the root
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// Result is the normalized schema and the lossy conversions applied to it
+type Result struct {
+	Schema map[string]interface{}
+	Issues []Issue
+}
+
+// geminiKeywords are the Schema fields accepted by Gemini functionDeclarations
+var geminiKeywords = keywordSet(
+	"type", "format", "title", "description", "nullable", "enum", "items",
+	"properties", "required", "minItems", "maxItems", "minProperties", "maxProperties",
+	"minLength", "maxLength", "pattern", "minimum", "maximum", "anyOf",
+	"propertyOrdering", "default", "example",
+)
+
+// openAIStrictKeywords are the keywords supported by OpenAI strict mode
+var openAIStrictKeywords = keywordSet(
+	"type", "description", "title", "properties", "required", "additionalProperties",
+	"items", "anyOf", "enum", "const", "pattern", "format", "minimum", "maximum",
+	"exclusiveMinimum", "exclusiveMaximum", "multipleOf", "minItems", "maxItems",
+)
+
+// metadataKeywords carry no validation semantics and are dropped silently
+var metadataKeywords = keywordSet(
+	"$schema", "$id", "$comment", "$defs", "definitions", "examples",
+	"deprecated", "readOnly", "writeOnly",
+)
+
+// geminiFormats lists the format values Gemini accepts per type
+var geminiFormats = map[string]map[string]bool{
+	"string":  keywordSet("enum", "date-time"),
+	"number":  keywordSet("float", "double"),
+	"integer": keywordSet("int32", "int64"),
+}
+
+func keywordSet(keys ...string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
+
+// Normalize converts a tool parameter schema for the given profile. The input
+// is not modified. A missing or non-object schema yields an empty object schema.
+func Normalize(schema interface{}, profile Profile) Result {
+	n := &normalizer{profile: profile}
+	root, _ := deepCopy(schema).(map[string]interface{})
+	if root == nil {
+		root = map[string]interface{}{}
+	}
+	n.defs = collectDefs(root)
+
+	out, _ := n.walk(root, "", nil).(map[string]interface{})
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	n.finishRoot(out)
+	return Result{Schema: out, Issues: n.issues}
+}
+
+type normalizer struct {
+	profile Profile
+	defs    map[string]interface{}
+	issues  []Issue
+}
+
+func (n *normalizer) report(path, format string, args ...interface{}) {
+	n.issues = append(n.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// inlinesRefs reports whether $ref must be resolved for this profile.
+// Claude and OpenAI accept local references as-is.
+func (n *normalizer) inlinesRefs() bool {
+	return n.profile == ProfileGemini || n.profile == ProfileOpenAIStrict
+}
+
+// restricted reports whether the profile only accepts a keyword subset
+func (n *normalizer) restricted() bool {
+	return n.profile == ProfileGemini || n.profile == ProfileOpenAIStrict
+}
+
+func collectDefs(root map[string]interface{}) map[string]interface{} {
+	defs := make(map[string]interface{})
+	for _, key := range []string{"definitions", "$defs"} {
+		if m, ok := root[key].(map[string]interface{}); ok {
+			for name, def := range m {
+				defs["#/"+key+"/"+name] = def
+			}
+		}
+	}
+	return defs
+}
+
+func (n *normalizer) walk(node interface{}, path string, refStack []string) interface{} {
+	switch v := node.(type) {
+	case bool:
+		// true accepts anything; false can only be approximated
+		if !v {
+			n.report(path, "false schema replaced by an unconstrained schema")
+		}
+		return map[string]interface{}{}
+	case map[string]interface{}:
+		return n.walkObject(v, path, refStack)
+	}
+	return node
+}
+
+func (n *normalizer) walkObject(m map[string]interface{}, path string, refStack []string) map[string]interface{} {
+	if ref, ok := m["$ref"].(string); ok && n.inlinesRefs() {
+		return n.inlineRef(m, ref, path, refStack)
+	}
+
+	n.normalizeType(m)
+	n.normalizeUnions(m, path, refStack)
+
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(props) {
+			props[name] = n.walk(props[name], joinPath(path, "properties", name), refStack)
+		}
+	}
+	if items, ok := m["items"]; ok {
+		if list, isList := items.([]interface{}); isList {
+			// Tuple form: keep the first item schema
+			if len(list) > 0 {
+				m["items"] = n.walk(list[0], joinPath(path, "items"), refStack)
+			} else {
+				delete(m, "items")
+			}
+			n.report(path, "tuple items reduced to a single item schema")
+		} else {
+			m["items"] = n.walk(items, joinPath(path, "items"), refStack)
+		}
+	}
+	if extra, ok := m["additionalProperties"].(map[string]interface{}); ok && !n.restricted() {
+		m["additionalProperties"] = n.walk(extra, joinPath(path, "additionalProperties"), refStack)
+	}
+	if !n.inlinesRefs() {
+		for _, key := range []string{"definitions", "$defs"} {
+			if defs, ok := m[key].(map[string]interface{}); ok {
+				for _, name := range sortedKeys(defs) {
+					defs[name] = n.walk(defs[name], joinPath(path, key, name), refStack)
+				}
+			}
+		}
+	}
+
+	switch n.profile {
+	case ProfileGemini:
+		n.applyGemini(m, path)
+	case ProfileOpenAIStrict:
+		n.applyOpenAIStrict(m, path)
+	default:
+		n.applyNullableAsType(m)
+	}
+	return m
+}
+
+// inlineRef replaces a local $ref with a copy of its target. Sibling keywords
+// such as description take precedence over the referenced schema.
+func (n *normalizer) inlineRef(m map[string]interface{}, ref, path string, refStack []string) map[string]interface{} {
+	for _, seen := range refStack {
+		if seen == ref {
+			n.report(path, "recursive $ref %s replaced by a generic object", ref)
+			return n.walkObject(map[string]interface{}{"type": "object"}, path, refStack)
+		}
+	}
+	target, ok := n.defs[ref]
+	if !ok {
+		n.report(path, "unresolved $ref %s replaced by a generic object", ref)
+		return n.walkObject(map[string]interface{}{"type": "object"}, path, refStack)
+	}
+	resolved, _ := deepCopy(target).(map[string]interface{})
+	if resolved == nil {
+		resolved = map[string]interface{}{}
+	}
+	for k, v := range m {
+		if k != "$ref" {
+			resolved[k] = v
+		}
+	}
+	return n.walkObject(resolved, path, append(refStack[:len(refStack):len(refStack)], ref))
+}
+
+// normalizeType lowercases Gemini-style type names and folds "null" out of
+// type arrays into nullable
+func (n *normalizer) normalizeType(m map[string]interface{}) {
+	switch t := m["type"].(type) {
+	case string:
+		m["type"] = strings.ToLower(t)
+	case []interface{}:
+		var types []interface{}
+		for _, item := range t {
+			s, _ := item.(string)
+			if strings.EqualFold(s, "null") {
+				m["nullable"] = true
+				continue
+			}
+			types = append(types, strings.ToLower(s))
+		}
+		switch len(types) {
+		case 0:
+			delete(m, "type")
+		case 1:
+			m["type"] = types[0]
+		default:
+			if n.profile == ProfileGemini {
+				// Gemini has no type arrays, express them as a union
+				variants := make([]interface{}, 0, len(types))
+				for _, typ := range types {
+					variants = append(variants, map[string]interface{}{"type": typ})
+				}
+				delete(m, "type")
+				m["anyOf"] = append(variants, toSlice(m["anyOf"])...)
+			} else {
+				m["type"] = types
+			}
+		}
+	}
+}
+
+// normalizeUnions rewrites anyOf/oneOf/allOf into forms the profile accepts.
+// Null variants become nullable and single-variant unions are collapsed.
+func (n *normalizer) normalizeUnions(m map[string]interface{}, path string, refStack []string) {
+	if allOf, ok := m["allOf"].([]interface{}); ok && n.restricted() {
+		delete(m, "allOf")
+		for i, sub := range allOf {
+			subMap, _ := n.walk(sub, joinPath(path, "allOf", fmt.Sprint(i)), refStack).(map[string]interface{})
+			n.mergeInto(m, subMap, path)
+		}
+	}
+
+	for _, key := range []string{"anyOf", "oneOf"} {
+		variants, ok := m[key].([]interface{})
+		if !ok {
+			continue
+		}
+		delete(m, key)
+
+		var kept []interface{}
+		for i, variant := range variants {
+			walked := n.walk(variant, joinPath(path, key, fmt.Sprint(i)), refStack)
+			vm, _ := walked.(map[string]interface{})
+			if isNullSchema(vm) {
+				m["nullable"] = true
+				continue
+			}
+			// Flatten nested unions that carry nothing else
+			if nested, ok := vm["anyOf"].([]interface{}); ok && len(vm) == 1 {
+				kept = append(kept, nested...)
+				continue
+			}
+			kept = append(kept, vm)
+		}
+
+		targetKey := key
+		if key == "oneOf" && n.restricted() {
+			targetKey = "anyOf"
+			if len(kept) > 1 {
+				n.report(path, "oneOf relaxed to anyOf")
+			}
+		}
+
+		switch len(kept) {
+		case 0:
+		case 1:
+			single, _ := kept[0].(map[string]interface{})
+			n.mergeInto(m, single, path)
+		default:
+			m[targetKey] = append(toSlice(m[targetKey]), kept...)
+		}
+	}
+}
+
+// mergeInto copies keywords of src into dst. Keywords already set on dst win,
+// except that object properties and required lists are combined.
+func (n *normalizer) mergeInto(dst, src map[string]interface{}, path string) {
+	for k, v := range src {
+		switch k {
+		case "properties":
+			props, _ := dst["properties"].(map[string]interface{})
+			if props == nil {
+				props = map[string]interface{}{}
+			}
+			if srcProps, ok := v.(map[string]interface{}); ok {
+				for name, p := range srcProps {
+					if _, exists := props[name]; !exists {
+						props[name] = p
+					}
+				}
+			}
+			dst["properties"] = props
+		case "required":
+			dst["required"] = mergeRequired(dst["required"], v)
+		case "type":
+			if existing, ok := dst["type"]; ok && fmt.Sprint(existing) != fmt.Sprint(v) {
+				n.report(path, "conflicting types %v and %v, kept %v", existing, v, existing)
+				continue
+			}
+			dst[k] = v
+		default:
+			if _, exists := dst[k]; !exists {
+				dst[k] = v
+			}
+		}
+	}
+}
+
+// applyGemini restricts a schema node to the Gemini OpenAPI subset
+func (n *normalizer) applyGemini(m map[string]interface{}, path string) {
+	if c, ok := m["const"]; ok {
+		delete(m, "const")
+		if s, isString := c.(string); isString {
+			m["enum"] = []interface{}{s}
+			if _, hasType := m["type"]; !hasType {
+				m["type"] = "string"
+			}
+		} else {
+			n.report(path, "non-string const %v dropped", c)
+		}
+	}
+	if enum, ok := m["enum"].([]interface{}); ok {
+		var values []interface{}
+		allStrings := true
+		for _, v := range enum {
+			if v == nil {
+				m["nullable"] = true
+				continue
+			}
+			if _, isString := v.(string); !isString {
+				allStrings = false
+			}
+			values = append(values, v)
+		}
+		if allStrings && len(values) > 0 {
+			m["enum"] = values
+			if t, _ := m["type"].(string); t != "" && t != "string" {
+				n.report(path, "enum on %s type dropped", t)
+				delete(m, "enum")
+			} else {
+				m["type"] = "string"
+			}
+		} else {
+			delete(m, "enum")
+			n.report(path, "non-string enum dropped")
+		}
+	}
+	if format, ok := m["format"].(string); ok {
+		t, _ := m["type"].(string)
+		if !geminiFormats[t][format] {
+			delete(m, "format")
+			n.report(path, "format %q dropped", format)
+		}
+	}
+	if props, ok := m["properties"].(map[string]interface{}); ok && len(props) == 0 {
+		// Gemini rejects OBJECT schemas with an empty properties map
+		delete(m, "properties")
+	}
+	n.filterRequired(m)
+	n.dropUnsupported(m, path, geminiKeywords)
+}
+
+// applyOpenAIStrict makes a schema node valid for OpenAI strict mode: every
+// object lists all properties as required, optional ones become nullable, and
+// additional properties are disallowed
+func (n *normalizer) applyOpenAIStrict(m map[string]interface{}, path string) {
+	if extra, ok := m["additionalProperties"]; ok {
+		if allowed, isBool := extra.(bool); !isBool || allowed {
+			n.report(path, "additionalProperties not allowed in strict mode")
+		}
+	}
+	if isObjectSchema(m) {
+		props, _ := m["properties"].(map[string]interface{})
+		if props == nil {
+			props = map[string]interface{}{}
+			m["properties"] = props
+		}
+		required := requiredSet(m["required"])
+		names := sortedKeys(props)
+		all := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			if !required[name] {
+				if pm, ok := props[name].(map[string]interface{}); ok {
+					makeNullable(pm)
+				}
+			}
+			all = append(all, name)
+		}
+		m["required"] = all
+		m["additionalProperties"] = false
+	}
+	n.applyNullableAsType(m)
+	n.dropUnsupported(m, path, openAIStrictKeywords)
+}
+
+// applyNullableAsType converts OpenAPI nullable into a JSON Schema null type
+func (n *normalizer) applyNullableAsType(m map[string]interface{}) {
+	nullable, ok := m["nullable"].(bool)
+	if !ok {
+		return
+	}
+	delete(m, "nullable")
+	if nullable {
+		makeNullable(m)
+	}
+}
+
+// finishRoot enforces that tool parameters are an object schema
+func (n *normalizer) finishRoot(root map[string]interface{}) {
+	if _, ok := root["type"]; !ok {
+		if _, hasUnion := root["anyOf"]; hasUnion {
+			n.report("", "root union is not supported for tool parameters")
+		}
+		root["type"] = "object"
+	}
+	if n.profile == ProfileOpenAIStrict {
+		delete(root, "anyOf")
+		if _, ok := root["properties"]; !ok {
+			root["properties"] = map[string]interface{}{}
+			root["required"] = []interface{}{}
+			root["additionalProperties"] = false
+		}
+	}
+	if n.profile == ProfileClaude || n.profile == ProfileOpenAI {
+		if _, ok := root["properties"]; !ok && root["type"] == "object" {
+			root["properties"] = map[string]interface{}{}
+		}
+	}
+}
+
+// filterRequired removes required entries that have no matching property
+func (n *normalizer) filterRequired(m map[string]interface{}) {
+	required, ok := m["required"].([]interface{})
+	if !ok {
+		return
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	var kept []interface{}
+	for _, r := range required {
+		name, _ := r.(string)
+		if _, exists := props[name]; exists {
+			kept = append(kept, name)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m, "required")
+		return
+	}
+	m["required"] = kept
+}
+
+func (n *normalizer) dropUnsupported(m map[string]interface{}, path string, allowed map[string]bool) {
+	for _, k := range sortedKeys(m) {
+		if allowed[k] {
+			continue
+		}
+		if !metadataKeywords[k] && !(k == "additionalProperties" && m[k] == false) {
+			n.report(path, "unsupported keyword %q dropped", k)
+		}
+		delete(m, k)
+	}
+}
+
+func makeNullable(m map[string]interface{}) {
+	switch t := m["type"].(type) {
+	case string:
+		if t != "null" {
+			m["type"] = []interface{}{t, "null"}
+		}
+		return
+	case []interface{}:
+		for _, item := range t {
+			if item == "null" {
+				return
+			}
+		}
+		m["type"] = append(t, "null")
+		return
+	}
+	if variants, ok := m["anyOf"].([]interface{}); ok {
+		for _, v := range variants {
+			if isNullSchema(toMap(v)) {
+				return
+			}
+		}
+		m["anyOf"] = append(variants, map[string]interface{}{"type": "null"})
+		return
+	}
+	if enum, ok := m["enum"].([]interface{}); ok {
+		for _, v := range enum {
+			if v == nil {
+				return
+			}
+		}
+		m["enum"] = append(enum, nil)
+	}
+}
+
+func isObjectSchema(m map[string]interface{}) bool {
+	switch t := m["type"].(type) {
+	case string:
+		return t == "object"
+	case []interface{}:
+		for _, item := range t {
+			if item == "object" {
+				return true
+			}
+		}
+	}
+	_, hasProps := m["properties"]
+	return hasProps && m["type"] == nil
+}
+
+func isNullSchema(m map[string]interface{}) bool {
+	if m == nil {
+		return false
+	}
+	t, _ := m["type"].(string)
+	return t == "null"
+}
+
+func requiredSet(v interface{}) map[string]bool {
+	set := make(map[string]bool)
+	for _, r := range toSlice(v) {
+		if name, ok := r.(string); ok {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+func mergeRequired(a, b interface{}) []interface{} {
+	seen := make(map[string]bool)
+	var out []interface{}
+	for _, list := range [][]interface{}{toSlice(a), toSlice(b)} {
+		for _, r := range list {
+			name, ok := r.(string)
+			if ok && !seen[name] {
+				seen[name] = true
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+func toSlice(v interface{}) []interface{} {
+	switch s := v.(type) {
+	case []interface{}:
+		return s
+	case []string:
+		out := make([]interface{}, len(s))
+		for i, item := range s {
+			out[i] = item
+		}
+		return out
+	}
+	return nil
+}
+
+func toMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// deepCopy copies JSON-like values so normalization never mutates the caller's schema
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopy(item)
+		}
+		return out
+	case []string:
+		return toSlice(val)
+	}
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(base string, parts ...string) string {
+	all := make([]string, 0, len(parts)+1)
+	if base != "" {
+		all = append(all, base)
+	}
+	return strings.Join(append(all, parts...), ".")
+}
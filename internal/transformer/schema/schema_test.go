@@ -0,0 +1,313 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tool schemas as published by common MCP servers
+const (
+	// @modelcontextprotocol/server-filesystem edit_file (zod-to-json-schema)
+	filesystemEditFile = `{
+		"type": "object",
+		"properties": {
+			"path": {"type": "string"},
+			"edits": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"oldText": {"type": "string", "description": "Text to search for - must match exactly"},
+						"newText": {"type": "string", "description": "Text to replace with"}
+					},
+					"required": ["oldText", "newText"],
+					"additionalProperties": false
+				}
+			},
+			"dryRun": {"type": "boolean", "default": false, "description": "Preview changes using git-style diff format"}
+		},
+		"required": ["path", "edits"],
+		"additionalProperties": false,
+		"$schema": "http://json-schema.org/draft-07/schema#"
+	}`
+
+	// FastMCP (pydantic) tool with a model argument and Optional fields
+	fastMCPSearch = `{
+		"$defs": {
+			"Filter": {
+				"properties": {
+					"field": {"title": "Field", "type": "string"},
+					"value": {"anyOf": [{"type": "string"}, {"type": "null"}], "default": null, "title": "Value"}
+				},
+				"required": ["field"],
+				"title": "Filter",
+				"type": "object"
+			}
+		},
+		"properties": {
+			"query": {"title": "Query", "type": "string"},
+			"filter": {"anyOf": [{"$ref": "#/$defs/Filter"}, {"type": "null"}], "default": null}
+		},
+		"required": ["query"],
+		"title": "searchArguments",
+		"type": "object"
+	}`
+
+	// github-mcp-server style arguments with formats, const and unions
+	githubListIssues = `{
+		"type": "object",
+		"properties": {
+			"repo": {"type": "string", "format": "uri"},
+			"since": {"type": "string", "format": "date-time"},
+			"state": {"type": "string", "enum": ["open", "closed", "all"]},
+			"kind": {"const": "issue"},
+			"perPage": {"type": "number", "minimum": 1, "maximum": 100},
+			"milestone": {"oneOf": [{"type": "string"}, {"type": "integer"}]}
+		},
+		"required": ["repo", "owner"]
+	}`
+
+	// Tree-shaped schema that references itself
+	recursiveNode = `{
+		"type": "object",
+		"properties": {"root": {"$ref": "#/definitions/Node"}},
+		"definitions": {
+			"Node": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"children": {"type": "array", "items": {"$ref": "#/definitions/Node"}}
+				}
+			}
+		}
+	}`
+
+	// Gemini functionDeclaration parameters (OpenAPI style)
+	geminiWeather = `{
+		"type": "OBJECT",
+		"properties": {
+			"city": {"type": "STRING"},
+			"unit": {"type": "STRING", "enum": ["c", "f"], "nullable": true}
+		},
+		"required": ["city"]
+	}`
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		input   string
+		want    string
+		issues  []string
+	}{
+		{
+			name:    "filesystem for gemini",
+			profile: ProfileGemini,
+			input:   filesystemEditFile,
+			want: `{
+				"type": "object",
+				"properties": {
+					"path": {"type": "string"},
+					"edits": {"type": "array", "items": {"type": "object", "properties": {
+						"oldText": {"type": "string", "description": "Text to search for - must match exactly"},
+						"newText": {"type": "string", "description": "Text to replace with"}
+					}, "required": ["oldText", "newText"]}},
+					"dryRun": {"type": "boolean", "default": false, "description": "Preview changes using git-style diff format"}
+				},
+				"required": ["path", "edits"]
+			}`,
+		},
+		{
+			name:    "filesystem for openai strict",
+			profile: ProfileOpenAIStrict,
+			input:   filesystemEditFile,
+			want: `{
+				"type": "object",
+				"properties": {
+					"path": {"type": "string"},
+					"edits": {"type": "array", "items": {"type": "object", "properties": {
+						"oldText": {"type": "string", "description": "Text to search for - must match exactly"},
+						"newText": {"type": "string", "description": "Text to replace with"}
+					}, "required": ["newText", "oldText"], "additionalProperties": false}},
+					"dryRun": {"type": ["boolean", "null"], "description": "Preview changes using git-style diff format"}
+				},
+				"required": ["dryRun", "edits", "path"],
+				"additionalProperties": false
+			}`,
+			issues: []string{`properties.dryRun: unsupported keyword "default" dropped`},
+		},
+		{
+			name:    "fastmcp refs for gemini",
+			profile: ProfileGemini,
+			input:   fastMCPSearch,
+			want: `{
+				"type": "object",
+				"title": "searchArguments",
+				"properties": {
+					"query": {"title": "Query", "type": "string"},
+					"filter": {
+						"type": "object", "title": "Filter", "nullable": true, "default": null,
+						"properties": {
+							"field": {"title": "Field", "type": "string"},
+							"value": {"type": "string", "nullable": true, "default": null, "title": "Value"}
+						},
+						"required": ["field"]
+					}
+				},
+				"required": ["query"]
+			}`,
+		},
+		{
+			name:    "fastmcp refs for openai strict",
+			profile: ProfileOpenAIStrict,
+			input:   fastMCPSearch,
+			want: `{
+				"type": "object",
+				"title": "searchArguments",
+				"properties": {
+					"query": {"title": "Query", "type": "string"},
+					"filter": {
+						"type": ["object", "null"], "title": "Filter",
+						"properties": {
+							"field": {"title": "Field", "type": "string"},
+							"value": {"type": ["string", "null"], "title": "Value"}
+						},
+						"required": ["field", "value"],
+						"additionalProperties": false
+					}
+				},
+				"required": ["filter", "query"],
+				"additionalProperties": false
+			}`,
+			issues: []string{
+				`properties.filter.anyOf.0.properties.value: unsupported keyword "default" dropped`,
+				`properties.filter: unsupported keyword "default" dropped`,
+			},
+		},
+		{
+			name:    "fastmcp keeps refs for claude",
+			profile: ProfileClaude,
+			input:   `{"$defs":{"Mode":{"type":"string","enum":["a","b"]}},"type":"object","properties":{"mode":{"$ref":"#/$defs/Mode"}}}`,
+			want:    `{"$defs":{"Mode":{"type":"string","enum":["a","b"]}},"type":"object","properties":{"mode":{"$ref":"#/$defs/Mode"}}}`,
+		},
+		{
+			name:    "github formats and unions for gemini",
+			profile: ProfileGemini,
+			input:   githubListIssues,
+			want: `{
+				"type": "object",
+				"properties": {
+					"repo": {"type": "string"},
+					"since": {"type": "string", "format": "date-time"},
+					"state": {"type": "string", "enum": ["open", "closed", "all"]},
+					"kind": {"type": "string", "enum": ["issue"]},
+					"perPage": {"type": "number", "minimum": 1, "maximum": 100},
+					"milestone": {"anyOf": [{"type": "string"}, {"type": "integer"}]}
+				},
+				"required": ["repo"]
+			}`,
+			issues: []string{
+				"properties.milestone: oneOf relaxed to anyOf",
+				`properties.repo: format "uri" dropped`,
+			},
+		},
+		{
+			name:    "recursive ref for gemini",
+			profile: ProfileGemini,
+			input:   recursiveNode,
+			want: `{
+				"type": "object",
+				"properties": {"root": {"type": "object", "properties": {
+					"name": {"type": "string"},
+					"children": {"type": "array", "items": {"type": "object"}}
+				}}}
+			}`,
+			issues: []string{"properties.root.properties.children.items: recursive $ref #/definitions/Node replaced by a generic object"},
+		},
+		{
+			name:    "gemini schema for claude",
+			profile: ProfileClaude,
+			input:   geminiWeather,
+			want: `{
+				"type": "object",
+				"properties": {
+					"city": {"type": "string"},
+					"unit": {"type": ["string", "null"], "enum": ["c", "f"]}
+				},
+				"required": ["city"]
+			}`,
+		},
+		{
+			name:    "free-form map for openai strict",
+			profile: ProfileOpenAIStrict,
+			input:   `{"type":"object","properties":{"env":{"type":"object","additionalProperties":{"type":"string"}}},"required":["env"]}`,
+			want:    `{"type":"object","properties":{"env":{"type":"object","properties":{},"required":[],"additionalProperties":false}},"required":["env"],"additionalProperties":false}`,
+			issues:  []string{"properties.env: additionalProperties not allowed in strict mode"},
+		},
+		{
+			name:    "missing schema for openai",
+			profile: ProfileOpenAI,
+			input:   `null`,
+			want:    `{"type":"object","properties":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input interface{}
+			if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+				t.Fatalf("invalid input: %v", err)
+			}
+			result := Normalize(input, tt.profile)
+
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("invalid want: %v", err)
+			}
+			got := roundTrip(t, result.Schema)
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(want)
+				t.Fatalf("schema mismatch\n got: %s\nwant: %s", gotJSON, wantJSON)
+			}
+
+			var issues []string
+			for _, issue := range result.Issues {
+				issues = append(issues, issue.String())
+			}
+			if strings.Join(issues, "\n") != strings.Join(tt.issues, "\n") {
+				t.Fatalf("issues mismatch\n got: %q\nwant: %q", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestNormalizeDoesNotModifyInput(t *testing.T) {
+	var input map[string]interface{}
+	if err := json.Unmarshal([]byte(fastMCPSearch), &input); err != nil {
+		t.Fatalf("invalid input: %v", err)
+	}
+	before, _ := json.Marshal(input)
+	Normalize(input, ProfileOpenAIStrict)
+	after, _ := json.Marshal(input)
+	if string(before) != string(after) {
+		t.Fatalf("input schema was modified\nbefore: %s\n after: %s", before, after)
+	}
+}
+
+// roundTrip normalizes Go slice types so results compare with decoded JSON
+func roundTrip(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	return out
+}
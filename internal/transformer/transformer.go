@@ -17,3 +17,9 @@ type Transformer interface {
 	// Name returns the transformer name
 	Name() string
 }
+
+// Wrapper is implemented by transformers that decorate another transformer,
+// such as plugins. Name reports the wrapped transformer's name.
+type Wrapper interface {
+	Unwrap() Transformer
+}
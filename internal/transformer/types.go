@@ -20,6 +20,7 @@ type OpenAITool struct {
 		Name        string                 `json:"name"`
 		Description string                 `json:"description,omitempty"`
 		Parameters  map[string]interface{} `json:"parameters"`
+		Strict      bool                   `json:"strict,omitempty"`
 	} `json:"function"`
 }
 
@@ -40,9 +41,12 @@ type OpenAIRequest struct {
 	Temperature         *float64        `json:"temperature,omitempty"`
 	Stream              bool            `json:"stream,omitempty"`
 	StreamOptions       *StreamOptions  `json:"stream_options,omitempty"`
-	EnableThinking      bool            `json:"enable_thinking,omitempty"` // For models that support reasoning/thinking
+	EnableThinking      bool            `json:"enable_thinking,omitempty"`  // For models that support reasoning/thinking
+	ReasoningEffort     string          `json:"reasoning_effort,omitempty"` // "minimal", "low", "medium", "high"
 	Tools               []OpenAITool    `json:"tools,omitempty"`
 	ToolChoice          interface{}     `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool           `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat      interface{}     `json:"response_format,omitempty"` // {"type":"json_schema","json_schema":{...}} or {"type":"json_object"}
 }
 
 // StreamOptions represents OpenAI stream options
@@ -59,9 +63,10 @@ type OpenAIResponse struct {
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
-			Role      string           `json:"role"`
-			Content   string           `json:"content"`
-			ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+			Role             string           `json:"role"`
+			Content          string           `json:"content"`
+			ReasoningContent string           `json:"reasoning_content,omitempty"` // For models with reasoning/thinking
+			ToolCalls        []OpenAIToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -106,15 +111,16 @@ type ClaudeMessage struct {
 
 // ClaudeRequest represents a Claude API request
 type ClaudeRequest struct {
-	Model       string          `json:"model"`
-	Messages    []ClaudeMessage `json:"messages"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
-	System      interface{}     `json:"system,omitempty"`   // Can be string or array of system messages
-	Thinking    interface{}     `json:"thinking,omitempty"` // Claude's thinking/extended thinking parameter
-	Tools       []ClaudeTool    `json:"tools,omitempty"`
-	ToolChoice  interface{}     `json:"tool_choice,omitempty"`
+	Model        string          `json:"model"`
+	Messages     []ClaudeMessage `json:"messages"`
+	MaxTokens    int             `json:"max_tokens,omitempty"`
+	Temperature  float64         `json:"temperature,omitempty"`
+	Stream       bool            `json:"stream,omitempty"`
+	System       interface{}     `json:"system,omitempty"`   // Can be string or array of system messages
+	Thinking     interface{}     `json:"thinking,omitempty"` // Claude's thinking/extended thinking parameter
+	Tools        []ClaudeTool    `json:"tools,omitempty"`
+	ToolChoice   interface{}     `json:"tool_choice,omitempty"`
+	OutputFormat interface{}     `json:"output_format,omitempty"` // Structured outputs: {"type":"json_schema","schema":{...}}
 }
 
 // ClaudeTool represents a tool definition in Claude format
@@ -192,7 +198,7 @@ type StreamContext struct {
 	CurrentToolCall      *OpenAIToolCall   // Current tool call being processed
 	ToolCallBuffer       string            // Buffer for accumulating tool call arguments
 	State                interface{}       // V3 architecture state (openai.StreamState)
-	ToolCallIDMap        map[string]string // tool_use_id -> function_name mapping for Gemini
+	ToolCallIDMap        map[string]string // tool_use_id / signature fingerprint -> producing provider
 	ToolCallCounter      int               // Counter for generating unique tool IDs
 	// Codex transformer fields
 	CurrentToolID   string // Current tool call ID being processed
@@ -202,6 +208,20 @@ type StreamContext struct {
 	InThinkingTag       bool   // Track if we are inside a <think> tag
 	ThinkingBuffer      string // Buffer for trailing partial tag detection
 	PendingThinkingText string // Buffered thinking text until closing tag arrives
+	// Reasoning/thinking accumulation for Responses API streams
+	ReasoningText      string // Accumulated thinking text for the current reasoning item
+	ReasoningSignature string // Accumulated thinking signature (encrypted_content)
+	// Structured output emulated through a synthetic Claude tool
+	StructuredOutputSeen  bool // The synthetic structured output tool was called
+	StructuredOutputIndex int  // Content block index of the synthetic tool call
+	ClientToolUseSeen     bool // A client-defined tool was called in the same message
+	// Gemini client streams report the finish reason together with usage
+	PendingFinishReason string // finish reason held until the final chunk or event
+	// Plugin stream state, returned by the plugin for the previous event
+	PluginState []byte
+	// Tool call identity for providers whose streamed ids or indexes are unreliable
+	UpstreamToolIDs     map[int]string // Tool call index -> id
+	UpstreamToolIndexes map[string]int // Tool call id -> index
 }
 
 // NewStreamContext creates a new stream context with default values
@@ -241,16 +261,32 @@ type GeminiPart struct {
 	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
 	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
 	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *GeminiBlob             `json:"inlineData,omitempty"`
+	FileData         *GeminiFileData         `json:"fileData,omitempty"`
+}
+
+// GeminiBlob represents inline base64 media in Gemini format
+type GeminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// GeminiFileData represents media referenced by URI in Gemini format
+type GeminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
 }
 
 // GeminiFunctionCall represents a function call in Gemini format
 type GeminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
 	Name string                 `json:"name"`
 	Args map[string]interface{} `json:"args"`
 }
 
 // GeminiFunctionResponse represents a function response in Gemini format
 type GeminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
 	Name     string                 `json:"name"`
 	Response map[string]interface{} `json:"response"`
 }
@@ -268,9 +304,10 @@ type GeminiTool struct {
 
 // GeminiFunctionDeclaration represents a function declaration in Gemini format
 type GeminiFunctionDeclaration struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description,omitempty"`
-	Parameters  map[string]interface{} `json:"parameters"`
+	Name                 string                 `json:"name"`
+	Description          string                 `json:"description,omitempty"`
+	Parameters           map[string]interface{} `json:"parameters"`
+	ParametersJSONSchema map[string]interface{} `json:"parametersJsonSchema,omitempty"` // Full JSON Schema alternative to parameters
 }
 
 // GeminiRequest represents a Gemini API request
@@ -278,14 +315,39 @@ type GeminiRequest struct {
 	Contents          []GeminiContent         `json:"contents"`
 	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
 	Tools             []GeminiTool            `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig       `json:"toolConfig,omitempty"`
 	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+	// Stream is not part of the Gemini API: Gemini clients select streaming by
+	// URL, and the proxy sets it when converting such requests to other formats
+	Stream bool `json:"stream,omitempty"`
+}
+
+// GeminiToolConfig represents function calling configuration in Gemini format
+type GeminiToolConfig struct {
+	FunctionCallingConfig *GeminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+// GeminiFunctionCallingConfig controls whether and which functions the model calls
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode,omitempty"` // "AUTO", "ANY", "NONE"
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 // GeminiGenerationConfig represents generation configuration in Gemini format
 type GeminiGenerationConfig struct {
-	Temperature     *float64 `json:"temperature,omitempty"`
-	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
-	StopSequences   []string `json:"stopSequences,omitempty"`
+	Temperature        *float64               `json:"temperature,omitempty"`
+	MaxOutputTokens    *int                   `json:"maxOutputTokens,omitempty"`
+	StopSequences      []string               `json:"stopSequences,omitempty"`
+	ThinkingConfig     *GeminiThinkingConfig  `json:"thinkingConfig,omitempty"`
+	ResponseMimeType   string                 `json:"responseMimeType,omitempty"`
+	ResponseSchema     map[string]interface{} `json:"responseSchema,omitempty"`     // OpenAPI subset
+	ResponseJSONSchema map[string]interface{} `json:"responseJsonSchema,omitempty"` // Full JSON Schema
+}
+
+// GeminiThinkingConfig represents thinking configuration in Gemini format
+type GeminiThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"` // 0 disables thinking, -1 is dynamic
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
 }
 
 // GeminiResponse represents a Gemini API response
@@ -350,18 +412,34 @@ type OpenAI2Tool struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      bool                   `json:"strict,omitempty"`
 }
 
 // OpenAI2Request represents an OpenAI Responses API request
 type OpenAI2Request struct {
-	Model           string        `json:"model"`
-	Input           interface{}   `json:"input"`                  // string or []OpenAI2InputItem
-	Instructions    string        `json:"instructions,omitempty"` // system prompt
-	Tools           []OpenAI2Tool `json:"tools,omitempty"`
-	ToolChoice      interface{}   `json:"tool_choice,omitempty"`
-	Stream          bool          `json:"stream,omitempty"`
-	MaxOutputTokens int           `json:"max_output_tokens,omitempty"`
-	Temperature     *float64      `json:"temperature,omitempty"`
+	Model             string             `json:"model"`
+	Input             interface{}        `json:"input"`                  // string or []OpenAI2InputItem
+	Instructions      string             `json:"instructions,omitempty"` // system prompt
+	Tools             []OpenAI2Tool      `json:"tools,omitempty"`
+	ToolChoice        interface{}        `json:"tool_choice,omitempty"`
+	Stream            bool               `json:"stream,omitempty"`
+	MaxOutputTokens   int                `json:"max_output_tokens,omitempty"`
+	Temperature       *float64           `json:"temperature,omitempty"`
+	Reasoning         *OpenAI2Reasoning  `json:"reasoning,omitempty"`
+	Include           []string           `json:"include,omitempty"`
+	ParallelToolCalls *bool              `json:"parallel_tool_calls,omitempty"`
+	Text              *OpenAI2TextConfig `json:"text,omitempty"`
+}
+
+// OpenAI2TextConfig represents text output configuration in Responses API
+type OpenAI2TextConfig struct {
+	Format map[string]interface{} `json:"format,omitempty"` // {"type":"json_schema","name":...,"schema":{...}}
+}
+
+// OpenAI2Reasoning represents reasoning configuration in Responses API
+type OpenAI2Reasoning struct {
+	Effort  string `json:"effort,omitempty"`  // "minimal", "low", "medium", "high"
+	Summary string `json:"summary,omitempty"` // "auto", "concise", "detailed"
 }
 
 // OpenAI2OutputItem represents an output item in Responses API response
@@ -374,19 +452,37 @@ type OpenAI2OutputItem struct {
 	Name      string `json:"name,omitempty"`
 	CallID    string `json:"call_id,omitempty"`
 	Arguments string `json:"arguments,omitempty"`
+	// Reasoning fields
+	Summary          []OpenAI2ContentPart `json:"summary,omitempty"`
+	EncryptedContent string               `json:"encrypted_content,omitempty"`
 }
 
 // OpenAI2Response represents an OpenAI Responses API response
 type OpenAI2Response struct {
 	ID     string              `json:"id"`
 	Object string              `json:"object"` // "response"
-	Status string              `json:"status"` // "completed", "failed", etc.
+	Status string              `json:"status"` // "completed", "incomplete", "failed", etc.
 	Output []OpenAI2OutputItem `json:"output"`
 	Usage  struct {
 		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
 		TotalTokens  int `json:"total_tokens"`
 	} `json:"usage"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"` // "max_output_tokens" or "content_filter"
+	} `json:"incomplete_details,omitempty"`
+}
+
+// IncompleteReason returns why the response stopped early, or "" when it
+// completed
+func (r *OpenAI2Response) IncompleteReason() string {
+	if r.Status != "incomplete" {
+		return ""
+	}
+	if r.IncompleteDetails != nil && r.IncompleteDetails.Reason != "" {
+		return r.IncompleteDetails.Reason
+	}
+	return "max_output_tokens"
 }
 
 // OpenAI2StreamEvent represents a streaming event from Responses API
@@ -398,4 +494,5 @@ type OpenAI2StreamEvent struct {
 	Item         *OpenAI2OutputItem  `json:"item,omitempty"`
 	Part         *OpenAI2ContentPart `json:"part,omitempty"`
 	Delta        string              `json:"delta,omitempty"` // Direct string for text delta
+	SummaryIndex int                 `json:"summary_index,omitempty"`
 }
@@ -0,0 +1,66 @@
+package vertex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	PublisherAnthropic = "anthropic"
+	PublisherGoogle    = "google"
+)
+
+var datedModelPattern = regexp.MustCompile(`^(.+)-(\d{8})$`)
+
+// IsClaudeModel reports whether a model is served by the Anthropic publisher
+func IsClaudeModel(model string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(model)), "claude")
+}
+
+// Publisher returns the Vertex AI publisher of a model
+func Publisher(model string) string {
+	if IsClaudeModel(model) {
+		return PublisherAnthropic
+	}
+	return PublisherGoogle
+}
+
+// ModelID resolves the Vertex AI model ID for a model name. Overrides win;
+// Anthropic snapshots use "@" before the date (claude-sonnet-4@20250514) and
+// Claude 3.5 Sonnet's second release is published as claude-3-5-sonnet-v2.
+func ModelID(model string, overrides map[string]string) string {
+	model = strings.TrimSpace(model)
+	if id, ok := overrides[model]; ok && strings.TrimSpace(id) != "" {
+		return strings.TrimSpace(id)
+	}
+	if !IsClaudeModel(model) || strings.Contains(model, "@") {
+		return model
+	}
+
+	// Claude Code marks extended context models with a suffix Vertex does not know
+	model = strings.TrimSuffix(model, "[1m]")
+	if model == "claude-3-5-sonnet-20241022" {
+		return "claude-3-5-sonnet-v2@20241022"
+	}
+	if m := datedModelPattern.FindStringSubmatch(model); m != nil {
+		return m[1] + "@" + m[2]
+	}
+	return model
+}
+
+// BaseURL returns the Vertex AI API host of a location
+func BaseURL(location string) string {
+	location = strings.TrimSpace(location)
+	if location == "" || location == "global" {
+		return "https://aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com", location)
+}
+
+// ModelPath returns the path of a publisher model method, e.g.
+// /v1/projects/p/locations/l/publishers/anthropic/models/m:streamRawPredict
+func ModelPath(project, location, publisher, modelID, method string) string {
+	return fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/%s/models/%s:%s",
+		project, location, publisher, modelID, method)
+}
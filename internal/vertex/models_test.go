@@ -0,0 +1,35 @@
+package vertex
+
+import "testing"
+
+func TestModelID(t *testing.T) {
+	tests := []struct {
+		model     string
+		overrides map[string]string
+		want      string
+	}{
+		{"claude-sonnet-4-20250514", nil, "claude-sonnet-4@20250514"},
+		{"claude-3-5-sonnet-20241022", nil, "claude-3-5-sonnet-v2@20241022"},
+		{"claude-opus-4-1-20250805[1m]", nil, "claude-opus-4-1@20250805"},
+		{"claude-sonnet-4-5", nil, "claude-sonnet-4-5"},
+		{"claude-3-haiku@20240307", nil, "claude-3-haiku@20240307"},
+		{"gemini-2.5-pro", nil, "gemini-2.5-pro"},
+		{"claude-sonnet-4-20250514", map[string]string{"claude-sonnet-4-20250514": "claude-sonnet-4@custom"}, "claude-sonnet-4@custom"},
+	}
+	for _, tt := range tests {
+		if got := ModelID(tt.model, tt.overrides); got != tt.want {
+			t.Errorf("ModelID(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestModelPath(t *testing.T) {
+	got := ModelPath("proj", "us-east5", Publisher("claude-sonnet-4"), "claude-sonnet-4@20250514", "streamRawPredict")
+	want := "/v1/projects/proj/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict"
+	if got != want {
+		t.Fatalf("ModelPath = %q, want %q", got, want)
+	}
+	if BaseURL("global") != "https://aiplatform.googleapis.com" || BaseURL("europe-west1") != "https://europe-west1-aiplatform.googleapis.com" {
+		t.Fatalf("unexpected base URLs %q %q", BaseURL("global"), BaseURL("europe-west1"))
+	}
+}
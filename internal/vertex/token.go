@@ -0,0 +1,218 @@
+package vertex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTokenURL is Google's OAuth 2.0 token endpoint
+	DefaultTokenURL = "https://oauth2.googleapis.com/token"
+	// CloudPlatformScope grants access to Vertex AI
+	CloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	assertionLifetime  = time.Hour
+	// refreshWindow matches the token pool: tokens are renewed two minutes early
+	refreshWindow = 2 * time.Minute
+)
+
+// ServiceAccount is the subset of a service-account JSON key used to mint tokens
+type ServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// LoadServiceAccount parses an inline service-account key, or reads it from
+// keyFile when no inline key is given
+func LoadServiceAccount(keyJSON, keyFile string) (*ServiceAccount, error) {
+	data := []byte(strings.TrimSpace(keyJSON))
+	if len(data) == 0 {
+		if strings.TrimSpace(keyFile) == "" {
+			return nil, fmt.Errorf("no service account key configured")
+		}
+		var err error
+		data, err = os.ReadFile(strings.TrimSpace(keyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account key: %w", err)
+		}
+	}
+
+	var sa ServiceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+	if sa.Type != "" && sa.Type != "service_account" {
+		return nil, fmt.Errorf("unsupported credential type: %s", sa.Type)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("service account key is missing client_email or private_key")
+	}
+	return &sa, nil
+}
+
+// Token is an OAuth access token and its expiry
+type Token struct {
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+// MintToken exchanges a JWT signed with the service account's private key for
+// an access token at tokenURL
+func MintToken(ctx context.Context, client *http.Client, sa *ServiceAccount, tokenURL string, now time.Time) (*Token, error) {
+	assertion, err := signAssertion(sa, tokenURL, now)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type": {jwtBearerGrantType},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create token request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed (%s): %w", tokenURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read token response failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("parse token response failed: %w", err)
+	}
+	if strings.TrimSpace(tokenResp.AccessToken) == "" {
+		return nil, fmt.Errorf("token response missing access_token")
+	}
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = assertionLifetime
+	}
+	return &Token{AccessToken: tokenResp.AccessToken, ExpiresAt: now.Add(expiresIn)}, nil
+}
+
+// signAssertion builds the RS256 JWT bearer assertion for the token request
+func signAssertion(sa *ServiceAccount, tokenURL string, now time.Time) (string, error) {
+	key, err := parsePrivateKey(sa.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if sa.PrivateKeyID != "" {
+		header["kid"] = sa.PrivateKeyID
+	}
+	claims := map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": CloudPlatformScope,
+		"aud":   tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(assertionLifetime).Unix(),
+	}
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign assertion failed: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parsePrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid service account private key")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("service account private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %w", err)
+	}
+	return key, nil
+}
+
+// TokenCache keeps one access token per service account and token URL and
+// mints a new one shortly before the current token expires
+type TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*Token
+}
+
+// NewTokenCache creates an empty token cache
+func NewTokenCache() *TokenCache {
+	return &TokenCache{tokens: make(map[string]*Token)}
+}
+
+// Token returns a valid access token for the service account, minting one when
+// the cached token is missing or about to expire
+func (c *TokenCache) Token(ctx context.Context, client *http.Client, sa *ServiceAccount, tokenURL string) (string, error) {
+	key := cacheKey(sa, tokenURL)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if token, ok := c.tokens[key]; ok && now.Add(refreshWindow).Before(token.ExpiresAt) {
+		return token.AccessToken, nil
+	}
+	token, err := MintToken(ctx, client, sa, tokenURL, now)
+	if err != nil {
+		return "", err
+	}
+	c.tokens[key] = token
+	return token.AccessToken, nil
+}
+
+// Invalidate drops the cached token so the next call mints a new one
+func (c *TokenCache) Invalidate(sa *ServiceAccount, tokenURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, cacheKey(sa, tokenURL))
+}
+
+func cacheKey(sa *ServiceAccount, tokenURL string) string {
+	return sa.ClientEmail + "|" + sa.PrivateKeyID + "|" + tokenURL
+}
@@ -0,0 +1,131 @@
+package vertex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestServiceAccount(t *testing.T) (*ServiceAccount, *rsa.PublicKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+	}
+	keyJSON, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "proj",
+		"private_key_id": "kid-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "svc@proj.iam.gserviceaccount.com",
+	})
+	sa, err := LoadServiceAccount(string(keyJSON), "")
+	if err != nil {
+		t.Fatalf("LoadServiceAccount failed: %v", err)
+	}
+	return sa, &key.PublicKey
+}
+
+// newTokenServer verifies the JWT bearer assertion and issues numbered tokens
+func newTokenServer(t *testing.T, pub *rsa.PublicKey, minted *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != jwtBearerGrantType {
+			t.Errorf("unexpected grant_type %q", r.PostForm.Get("grant_type"))
+		}
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Fatalf("malformed assertion")
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("assertion signature invalid: %v", err)
+		}
+		headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var header map[string]string
+		var claims map[string]interface{}
+		json.Unmarshal(headerJSON, &header)
+		json.Unmarshal(claimsJSON, &claims)
+		if header["alg"] != "RS256" || header["kid"] != "kid-1" {
+			t.Errorf("unexpected header %v", header)
+		}
+		if claims["iss"] != "svc@proj.iam.gserviceaccount.com" || claims["scope"] != CloudPlatformScope || claims["aud"] != "http://"+r.Host+"/token" {
+			t.Errorf("unexpected claims %v", claims)
+		}
+
+		n := atomic.AddInt32(minted, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3599,"token_type":"Bearer"}`, n)
+	}))
+}
+
+func TestMintToken(t *testing.T) {
+	sa, pub := newTestServiceAccount(t)
+	var minted int32
+	server := newTokenServer(t, pub, &minted)
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	token, err := MintToken(context.Background(), server.Client(), sa, server.URL+"/token", now)
+	if err != nil {
+		t.Fatalf("MintToken failed: %v", err)
+	}
+	if token.AccessToken != "token-1" || !token.ExpiresAt.Equal(now.Add(3599*time.Second)) {
+		t.Fatalf("unexpected token %+v", token)
+	}
+}
+
+func TestTokenCacheReusesUntilInvalidated(t *testing.T) {
+	sa, pub := newTestServiceAccount(t)
+	var minted int32
+	server := newTokenServer(t, pub, &minted)
+	defer server.Close()
+
+	cache := NewTokenCache()
+	tokenURL := server.URL + "/token"
+	for i := 0; i < 2; i++ {
+		token, err := cache.Token(context.Background(), server.Client(), sa, tokenURL)
+		if err != nil || token != "token-1" {
+			t.Fatalf("expected cached token-1, got %q (%v)", token, err)
+		}
+	}
+
+	cache.Invalidate(sa, tokenURL)
+	token, err := cache.Token(context.Background(), server.Client(), sa, tokenURL)
+	if err != nil || token != "token-2" {
+		t.Fatalf("expected token-2 after invalidation, got %q (%v)", token, err)
+	}
+}
+
+func TestTokenCacheRefreshesNearExpiry(t *testing.T) {
+	sa, pub := newTestServiceAccount(t)
+	var minted int32
+	server := newTokenServer(t, pub, &minted)
+	defer server.Close()
+
+	cache := NewTokenCache()
+	tokenURL := server.URL + "/token"
+	cache.tokens[cacheKey(sa, tokenURL)] = &Token{AccessToken: "stale", ExpiresAt: time.Now().Add(time.Minute)}
+	token, err := cache.Token(context.Background(), server.Client(), sa, tokenURL)
+	if err != nil || token != "token-1" {
+		t.Fatalf("expected a new token within the refresh window, got %q (%v)", token, err)
+	}
+}
@@ -0,0 +1,114 @@
+// Command quirks is an example ccNexus plugin for an OpenAI-compatible
+// provider with three quirks: it rejects some request fields, it caps
+// max_tokens, and its stream repeats the whole content so far in every chunk
+// instead of sending deltas.
+//
+// Build it as an executable:
+//
+//	go build -o quirks ./pluginsdk/examples/quirks
+//
+// or as a WASI module:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o quirks.wasm ./pluginsdk/examples/quirks
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lich0821/ccNexus/pluginsdk"
+)
+
+type config struct {
+	DropFields       []string `json:"dropFields"`       // Top-level request fields to remove
+	MaxTokens        int      `json:"maxTokens"`        // Upper bound for max_tokens, 0 for none
+	CumulativeDeltas bool     `json:"cumulativeDeltas"` // Convert cumulative stream content to deltas
+}
+
+var cfg config
+
+func main() {
+	plugin := &pluginsdk.Plugin{
+		Name:    "quirks",
+		Version: "1.0.0",
+		Init: func(raw json.RawMessage) error {
+			if len(raw) == 0 {
+				return nil
+			}
+			return json.Unmarshal(raw, &cfg)
+		},
+		TransformRequest: transformRequest,
+		TransformStreamEvent: func(ctx pluginsdk.CallContext, event string, state json.RawMessage) (string, json.RawMessage, error) {
+			if !cfg.CumulativeDeltas {
+				return event, state, nil
+			}
+			return toDeltas(event, state)
+		},
+	}
+	if err := pluginsdk.Serve(plugin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func transformRequest(ctx pluginsdk.CallContext, body json.RawMessage) (json.RawMessage, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	for _, field := range cfg.DropFields {
+		delete(req, field)
+	}
+	if maxTokens, ok := req["max_tokens"].(float64); ok && cfg.MaxTokens > 0 && int(maxTokens) > cfg.MaxTokens {
+		req["max_tokens"] = cfg.MaxTokens
+	}
+	return json.Marshal(req)
+}
+
+// toDeltas strips the content already sent from each chunk. The state holds
+// the content seen so far per choice index and lives as long as the stream.
+func toDeltas(event string, rawState json.RawMessage) (string, json.RawMessage, error) {
+	seen := map[string]string{}
+	if len(rawState) > 0 {
+		if err := json.Unmarshal(rawState, &seen); err != nil {
+			return "", nil, err
+		}
+	}
+
+	lines := strings.Split(event, "\n")
+	for i, line := range lines {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok || strings.TrimSpace(data) == "[DONE]" {
+			continue
+		}
+		var chunk map[string]interface{}
+		if json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk) != nil {
+			continue
+		}
+		choices, _ := chunk["choices"].([]interface{})
+		for _, item := range choices {
+			choice, _ := item.(map[string]interface{})
+			delta, _ := choice["delta"].(map[string]interface{})
+			content, ok := delta["content"].(string)
+			if !ok {
+				continue
+			}
+			key := fmt.Sprint(choice["index"])
+			delta["content"] = strings.TrimPrefix(content, seen[key])
+			seen[key] = content
+		}
+		rewritten, err := json.Marshal(chunk)
+		if err != nil {
+			return "", nil, err
+		}
+		lines[i] = "data: " + string(rewritten)
+	}
+
+	state, err := json.Marshal(seen)
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.Join(lines, "\n"), state, nil
+}
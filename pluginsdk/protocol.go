@@ -0,0 +1,113 @@
+// Package pluginsdk implements the ccNexus transformer plugin protocol.
+//
+// A plugin is an executable, or a WASI module, that reads newline-delimited
+// JSON-RPC 2.0 requests from stdin and writes one response line per request
+// to stdout. Logs go to stderr. ccNexus wraps the endpoint's built-in
+// transformer with the plugin: transform_request sees the request after it
+// was converted for the upstream, transform_response and
+// transform_stream_event see upstream responses before they are converted
+// back for the client.
+package pluginsdk
+
+import "encoding/json"
+
+// ProtocolVersion is the plugin protocol version sent on initialize
+const ProtocolVersion = 1
+
+// Protocol methods
+const (
+	MethodInitialize           = "initialize"
+	MethodTransformRequest     = "transform_request"
+	MethodTransformResponse    = "transform_response"
+	MethodTransformStreamEvent = "transform_stream_event"
+	MethodShutdown             = "shutdown" // Notification, no response is expected
+)
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request is a JSON-RPC request. Notifications have no ID.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InitializeParams is sent once after the plugin starts
+type InitializeParams struct {
+	ProtocolVersion int             `json:"protocolVersion"`
+	Name            string          `json:"name"`             // Name the plugin is configured under
+	Config          json.RawMessage `json:"config,omitempty"` // Plugin-specific configuration
+}
+
+// InitializeResult lists the transform methods the plugin implements. The
+// host does not call the others.
+type InitializeResult struct {
+	Name    string   `json:"name,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Methods []string `json:"methods"`
+}
+
+// CallContext describes the request being transformed
+type CallContext struct {
+	Endpoint    string `json:"endpoint"`    // Endpoint name
+	Model       string `json:"model"`       // Model sent upstream
+	Transformer string `json:"transformer"` // Built-in transformer the plugin wraps, e.g. cc_openai
+}
+
+// TransformRequestParams carries the upstream request body
+type TransformRequestParams struct {
+	CallContext
+	Body json.RawMessage `json:"body"`
+}
+
+// TransformResponseParams carries a non-streaming upstream response body
+type TransformResponseParams struct {
+	CallContext
+	Body json.RawMessage `json:"body"`
+}
+
+// BodyResult returns a transformed body
+type BodyResult struct {
+	Body json.RawMessage `json:"body"`
+}
+
+// TransformStreamEventParams carries one upstream SSE event, including its
+// "event:" and "data:" lines. State is what the plugin returned for the
+// previous event of the same stream, null for the first.
+type TransformStreamEventParams struct {
+	CallContext
+	Event string          `json:"event"`
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// TransformStreamEventResult returns the events to pass on, which may be
+// empty to drop the event or hold several events, and the stream state
+type TransformStreamEventResult struct {
+	Event string          `json:"event"`
+	State json.RawMessage `json:"state,omitempty"`
+}
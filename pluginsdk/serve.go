@@ -0,0 +1,144 @@
+package pluginsdk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Plugin implements the transforms. Nil functions are not advertised to the
+// host, which then leaves that part of the traffic untouched.
+type Plugin struct {
+	Name    string
+	Version string
+
+	// Init receives the plugin configuration from ccNexus
+	Init func(config json.RawMessage) error
+
+	TransformRequest     func(ctx CallContext, body json.RawMessage) (json.RawMessage, error)
+	TransformResponse    func(ctx CallContext, body json.RawMessage) (json.RawMessage, error)
+	TransformStreamEvent func(ctx CallContext, event string, state json.RawMessage) (string, json.RawMessage, error)
+}
+
+// Serve runs the plugin on stdin and stdout until stdin is closed or the
+// host sends shutdown
+func Serve(p *Plugin) error {
+	return ServeIO(p, os.Stdin, os.Stdout)
+}
+
+// ServeIO runs the plugin on the given streams
+func ServeIO(p *Plugin, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := encoder.Encode(Response{JSONRPC: "2.0", Error: &Error{Code: CodeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == MethodShutdown {
+			return nil
+		}
+		if req.ID == nil {
+			continue
+		}
+
+		resp := Response{JSONRPC: "2.0", ID: req.ID}
+		result, err := p.handle(req.Method, req.Params)
+		if err != nil {
+			rpcErr, ok := err.(*Error)
+			if !ok {
+				rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Result = nil
+			resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *Plugin) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch {
+	case method == MethodInitialize:
+		var init InitializeParams
+		if err := decodeParams(params, &init); err != nil {
+			return nil, err
+		}
+		if p.Init != nil {
+			if err := p.Init(init.Config); err != nil {
+				return nil, err
+			}
+		}
+		return InitializeResult{Name: p.Name, Version: p.Version, Methods: p.methods()}, nil
+	case method == MethodTransformRequest && p.TransformRequest != nil:
+		var call TransformRequestParams
+		if err := decodeParams(params, &call); err != nil {
+			return nil, err
+		}
+		body, err := p.TransformRequest(call.CallContext, call.Body)
+		return BodyResult{Body: body}, err
+	case method == MethodTransformResponse && p.TransformResponse != nil:
+		var call TransformResponseParams
+		if err := decodeParams(params, &call); err != nil {
+			return nil, err
+		}
+		body, err := p.TransformResponse(call.CallContext, call.Body)
+		return BodyResult{Body: body}, err
+	case method == MethodTransformStreamEvent && p.TransformStreamEvent != nil:
+		var call TransformStreamEventParams
+		if err := decodeParams(params, &call); err != nil {
+			return nil, err
+		}
+		event, state, err := p.TransformStreamEvent(call.CallContext, call.Event, call.State)
+		return TransformStreamEventResult{Event: event, State: state}, err
+	default:
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+	}
+}
+
+func (p *Plugin) methods() []string {
+	methods := []string{}
+	if p.TransformRequest != nil {
+		methods = append(methods, MethodTransformRequest)
+	}
+	if p.TransformResponse != nil {
+		methods = append(methods, MethodTransformResponse)
+	}
+	if p.TransformStreamEvent != nil {
+		methods = append(methods, MethodTransformStreamEvent)
+	}
+	return methods
+}
+
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}